@@ -0,0 +1,57 @@
+// Package aws connects to the ECS, EFS and EC2 APIs of the configured AWS regions.
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+)
+
+// Client holds the service clients of every enabled AWS region
+type Client struct {
+	regions map[string]*RegionClients
+}
+
+// RegionClients are the service clients of one AWS region
+type RegionClients struct {
+	ECS *ecs.Client
+	EFS *efs.Client
+	EC2 *ec2.Client // Only used to look up task public IPs
+}
+
+// NewClient creates clients for every enabled AWS region. Credentials come from
+// the default chain (AWS_ACCESS_KEY_ID, shared config, instance role, ...).
+// AWS_ENDPOINT_URL points every service at one endpoint, such as LocalStack.
+func NewClient(ctx context.Context, cfg *config.Config) (*Client, error) {
+	client := &Client{regions: make(map[string]*RegionClients)}
+
+	for _, region := range cfg.GetEnabledAWSRegions() {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region.Name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to load AWS configuration for region %s: %w", region.Name, err)
+		}
+
+		var endpoint *string
+		if cfg.AWS.Endpoint != "" {
+			endpoint = &cfg.AWS.Endpoint
+		}
+		client.regions[region.Name] = &RegionClients{
+			ECS: ecs.NewFromConfig(awsCfg, func(o *ecs.Options) { o.BaseEndpoint = endpoint }),
+			EFS: efs.NewFromConfig(awsCfg, func(o *efs.Options) { o.BaseEndpoint = endpoint }),
+			EC2: ec2.NewFromConfig(awsCfg, func(o *ec2.Options) { o.BaseEndpoint = endpoint }),
+		}
+	}
+
+	return client, nil
+}
+
+// Region returns the clients of an enabled region
+func (c *Client) Region(name string) (*RegionClients, bool) {
+	clients, ok := c.regions[name]
+	return clients, ok
+}
@@ -0,0 +1,72 @@
+package aws
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+)
+
+func TestNewClient_EndpointOverride(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.Header.Get("X-Amz-Target")+" "+r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	cfg := &config.Config{AWS: config.AWSConfig{
+		Endpoint: server.URL,
+		Regions: []config.AWSRegionConfig{
+			{Name: "us-east-1", Enabled: true},
+			{Name: "eu-west-1", Enabled: false},
+		},
+	}}
+	client, err := NewClient(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	if _, ok := client.Region("eu-west-1"); ok {
+		t.Error("disabled region should have no clients")
+	}
+	region, ok := client.Region("us-east-1")
+	if !ok {
+		t.Fatal("no clients for us-east-1")
+	}
+
+	if _, err := region.ECS.ListTasks(context.Background(), &ecs.ListTasksInput{}); err != nil {
+		t.Fatalf("ECS ListTasks() error = %v", err)
+	}
+	if _, err := region.EFS.DescribeAccessPoints(context.Background(), &efs.DescribeAccessPointsInput{}); err != nil {
+		t.Fatalf("EFS DescribeAccessPoints() error = %v", err)
+	}
+
+	want := []string{
+		"AmazonEC2ContainerServiceV20141113.ListTasks /",
+		" /2015-02-01/access-points",
+	}
+	if len(requests) != len(want) {
+		t.Fatalf("requests = %q, want %q", requests, want)
+	}
+	for i := range want {
+		if requests[i] != want[i] {
+			t.Errorf("request %d = %q, want %q", i, requests[i], want[i])
+		}
+	}
+}
@@ -33,6 +33,11 @@ type ContainerAppSpec struct {
 
 	// Agent configuration
 	AgentBaseURL string
+
+	// User environment variables and exposed ports. The first port is served by HTTP
+	// ingress; the others become additional TCP port mappings.
+	EnvVars []EnvVar
+	Ports   []ContainerPort
 }
 
 // ContainerAppResponse contains the created container app details
@@ -47,7 +52,7 @@ type ContainerAppResponse struct {
 // CreateContainerApp creates an Azure Container App for a workspace
 func (c *Client) CreateContainerApp(ctx context.Context, region, resourceGroup, environmentID string, spec ContainerAppSpec) (*ContainerAppResponse, error) {
 	// Initialize Container Apps client
-	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, nil)
+	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(region))
 	if err != nil {
 		return nil, fmt.Errorf("workspace %s: failed to create container apps client: %w", spec.WorkspaceID, err)
 	}
@@ -63,101 +68,15 @@ func (c *Client) CreateContainerApp(ctx context.Context, region, resourceGroup,
 	// Container App name (same naming convention as ACI)
 	appName := fmt.Sprintf("aca-%s", spec.WorkspaceID)
 
-	// Build secrets
-	var secrets []*armappcontainers.Secret
-	var envVars []*armappcontainers.EnvironmentVar
-
-	// Always-present environment variables
-	envVars = append(envVars,
-		&armappcontainers.EnvironmentVar{Name: to.Ptr("WORKSPACE_ID"), Value: to.Ptr(spec.WorkspaceID)},
-		&armappcontainers.EnvironmentVar{Name: to.Ptr("USER_ID"), Value: to.Ptr(spec.UserID)},
-		&armappcontainers.EnvironmentVar{Name: to.Ptr("WORKSPACE_DIR"), Value: to.Ptr("/home/dev8/workspace")},
-		&armappcontainers.EnvironmentVar{Name: to.Ptr("AGENT_ENABLED"), Value: to.Ptr("true")},
-		&armappcontainers.EnvironmentVar{Name: to.Ptr("MONITOR_INTERVAL"), Value: to.Ptr("30s")},
-	)
-
-	if spec.AgentBaseURL != "" {
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:  to.Ptr("AGENT_BASE_URL"),
-			Value: to.Ptr(spec.AgentBaseURL),
-		})
-	}
-
-	// Optional secrets and environment variables
-	if spec.GitHubToken != "" {
-		secrets = append(secrets, &armappcontainers.Secret{
-			Name:  to.Ptr("github-token"),
-			Value: to.Ptr(spec.GitHubToken),
-		})
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:      to.Ptr("GITHUB_TOKEN"),
-			SecretRef: to.Ptr("github-token"),
-		})
-	}
-
-	if spec.CodeServerPassword != "" {
-		secrets = append(secrets, &armappcontainers.Secret{
-			Name:  to.Ptr("code-server-password"),
-			Value: to.Ptr(spec.CodeServerPassword),
-		})
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:      to.Ptr("CODE_SERVER_PASSWORD"),
-			SecretRef: to.Ptr("code-server-password"),
-		})
-	}
-
-	if spec.SSHPublicKey != "" {
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:  to.Ptr("SSH_PUBLIC_KEY"),
-			Value: to.Ptr(spec.SSHPublicKey),
-		})
-	}
-
-	if spec.GitUserName != "" {
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:  to.Ptr("GIT_USER_NAME"),
-			Value: to.Ptr(spec.GitUserName),
-		})
-	}
-
-	if spec.GitUserEmail != "" {
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:  to.Ptr("GIT_USER_EMAIL"),
-			Value: to.Ptr(spec.GitUserEmail),
-		})
-	}
-
-	if spec.AnthropicAPIKey != "" {
-		secrets = append(secrets, &armappcontainers.Secret{
-			Name:  to.Ptr("anthropic-api-key"),
-			Value: to.Ptr(spec.AnthropicAPIKey),
-		})
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:      to.Ptr("ANTHROPIC_API_KEY"),
-			SecretRef: to.Ptr("anthropic-api-key"),
-		})
-	}
+	secrets, envVars := containerAppEnv(spec)
 
-	if spec.OpenAIAPIKey != "" {
-		secrets = append(secrets, &armappcontainers.Secret{
-			Name:  to.Ptr("openai-api-key"),
-			Value: to.Ptr(spec.OpenAIAPIKey),
-		})
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:      to.Ptr("OPENAI_API_KEY"),
-			SecretRef: to.Ptr("openai-api-key"),
-		})
+	ports := spec.Ports
+	if len(ports) == 0 {
+		ports = []ContainerPort{{Port: 8080, Protocol: "tcp", Public: true}}
 	}
-
-	if spec.GeminiAPIKey != "" {
-		secrets = append(secrets, &armappcontainers.Secret{
-			Name:  to.Ptr("gemini-api-key"),
-			Value: to.Ptr(spec.GeminiAPIKey),
-		})
-		envVars = append(envVars, &armappcontainers.EnvironmentVar{
-			Name:      to.Ptr("GEMINI_API_KEY"),
-			SecretRef: to.Ptr("gemini-api-key"),
-		})
+	portMappings, err := containerAppPortMappings(ports[1:])
+	if err != nil {
+		return nil, fmt.Errorf("workspace %s: %w", spec.WorkspaceID, err)
 	}
 
 	// Volume mounts (Azure Files)
@@ -194,8 +113,8 @@ func (c *Client) CreateContainerApp(ctx context.Context, region, resourceGroup,
 			Configuration: &armappcontainers.Configuration{
 				ActiveRevisionsMode: to.Ptr(armappcontainers.ActiveRevisionsModeSingle),
 				Ingress: &armappcontainers.Ingress{
-					External:      to.Ptr(true),
-					TargetPort:    to.Ptr(int32(8080)),
+					External:      to.Ptr(ports[0].Public),
+					TargetPort:    to.Ptr(ports[0].Port),
 					Transport:     to.Ptr(armappcontainers.IngressTransportMethodHTTP),
 					AllowInsecure: to.Ptr(false),
 					Traffic: []*armappcontainers.TrafficWeight{
@@ -251,6 +170,13 @@ func (c *Client) CreateContainerApp(ctx context.Context, region, resourceGroup,
 		return nil, fmt.Errorf("workspace %s: failed to create container app: %w", spec.WorkspaceID, err)
 	}
 
+	// The SDK's API version predates additional port mappings, so they are added by a second update
+	if len(portMappings) > 0 {
+		if err := c.setContainerAppPortMappings(ctx, resourceGroup, appName, portMappings); err != nil {
+			return nil, fmt.Errorf("workspace %s: %w", spec.WorkspaceID, err)
+		}
+	}
+
 	// Extract FQDN
 	fqdn := ""
 	latestRevision := ""
@@ -272,9 +198,122 @@ func (c *Client) CreateContainerApp(ctx context.Context, region, resourceGroup,
 	}, nil
 }
 
+// containerAppEnv builds the environment variables of a workspace container and the
+// app secrets that keys, tokens and secret user variables reference
+func containerAppEnv(spec ContainerAppSpec) ([]*armappcontainers.Secret, []*armappcontainers.EnvironmentVar) {
+	var secrets []*armappcontainers.Secret
+	var envVars []*armappcontainers.EnvironmentVar
+
+	// Always-present environment variables
+	envVars = append(envVars,
+		&armappcontainers.EnvironmentVar{Name: to.Ptr("WORKSPACE_ID"), Value: to.Ptr(spec.WorkspaceID)},
+		&armappcontainers.EnvironmentVar{Name: to.Ptr("USER_ID"), Value: to.Ptr(spec.UserID)},
+		&armappcontainers.EnvironmentVar{Name: to.Ptr("WORKSPACE_DIR"), Value: to.Ptr("/home/dev8/workspace")},
+		&armappcontainers.EnvironmentVar{Name: to.Ptr("AGENT_ENABLED"), Value: to.Ptr("true")},
+		&armappcontainers.EnvironmentVar{Name: to.Ptr("MONITOR_INTERVAL"), Value: to.Ptr("30s")},
+	)
+
+	if spec.AgentBaseURL != "" {
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:  to.Ptr("AGENT_BASE_URL"),
+			Value: to.Ptr(spec.AgentBaseURL),
+		})
+	}
+
+	// Optional secrets and environment variables
+	if spec.GitHubToken != "" {
+		secrets = append(secrets, &armappcontainers.Secret{
+			Name:  to.Ptr("github-token"),
+			Value: to.Ptr(spec.GitHubToken),
+		})
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:      to.Ptr("GITHUB_TOKEN"),
+			SecretRef: to.Ptr("github-token"),
+		})
+	}
+
+	if spec.CodeServerPassword != "" {
+		secrets = append(secrets, &armappcontainers.Secret{
+			Name:  to.Ptr("code-server-password"),
+			Value: to.Ptr(spec.CodeServerPassword),
+		})
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:      to.Ptr("CODE_SERVER_PASSWORD"),
+			SecretRef: to.Ptr("code-server-password"),
+		})
+	}
+
+	if spec.SSHPublicKey != "" {
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:  to.Ptr("SSH_PUBLIC_KEY"),
+			Value: to.Ptr(spec.SSHPublicKey),
+		})
+	}
+
+	if spec.GitUserName != "" {
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:  to.Ptr("GIT_USER_NAME"),
+			Value: to.Ptr(spec.GitUserName),
+		})
+	}
+
+	if spec.GitUserEmail != "" {
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:  to.Ptr("GIT_USER_EMAIL"),
+			Value: to.Ptr(spec.GitUserEmail),
+		})
+	}
+
+	if spec.AnthropicAPIKey != "" {
+		secrets = append(secrets, &armappcontainers.Secret{
+			Name:  to.Ptr("anthropic-api-key"),
+			Value: to.Ptr(spec.AnthropicAPIKey),
+		})
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:      to.Ptr("ANTHROPIC_API_KEY"),
+			SecretRef: to.Ptr("anthropic-api-key"),
+		})
+	}
+
+	if spec.OpenAIAPIKey != "" {
+		secrets = append(secrets, &armappcontainers.Secret{
+			Name:  to.Ptr("openai-api-key"),
+			Value: to.Ptr(spec.OpenAIAPIKey),
+		})
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:      to.Ptr("OPENAI_API_KEY"),
+			SecretRef: to.Ptr("openai-api-key"),
+		})
+	}
+
+	if spec.GeminiAPIKey != "" {
+		secrets = append(secrets, &armappcontainers.Secret{
+			Name:  to.Ptr("gemini-api-key"),
+			Value: to.Ptr(spec.GeminiAPIKey),
+		})
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{
+			Name:      to.Ptr("GEMINI_API_KEY"),
+			SecretRef: to.Ptr("gemini-api-key"),
+		})
+	}
+
+	// User environment variables; secrets are referenced like the ones above
+	for _, v := range spec.EnvVars {
+		if !v.Secret {
+			envVars = append(envVars, &armappcontainers.EnvironmentVar{Name: to.Ptr(v.Name), Value: to.Ptr(v.Value)})
+			continue
+		}
+		secretName := containerAppSecretName(v.Name)
+		secrets = append(secrets, &armappcontainers.Secret{Name: to.Ptr(secretName), Value: to.Ptr(v.Value)})
+		envVars = append(envVars, &armappcontainers.EnvironmentVar{Name: to.Ptr(v.Name), SecretRef: to.Ptr(secretName)})
+	}
+
+	return secrets, envVars
+}
+
 // GetContainerApp retrieves a container app
 func (c *Client) GetContainerApp(ctx context.Context, resourceGroup, appName string) (*armappcontainers.ContainerApp, error) {
-	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, nil)
+	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create container apps client: %w", err)
 	}
@@ -287,9 +326,29 @@ func (c *Client) GetContainerApp(ctx context.Context, resourceGroup, appName str
 	return &resp.ContainerApp, nil
 }
 
+// ListContainerApps lists all container apps in a resource group
+func (c *Client) ListContainerApps(ctx context.Context, resourceGroup string) ([]*armappcontainers.ContainerApp, error) {
+	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container apps client: %w", err)
+	}
+
+	var apps []*armappcontainers.ContainerApp
+	pager := client.NewListByResourceGroupPager(resourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list container apps in %s: %w", resourceGroup, err)
+		}
+		apps = append(apps, page.Value...)
+	}
+
+	return apps, nil
+}
+
 // DeleteContainerApp deletes a container app
 func (c *Client) DeleteContainerApp(ctx context.Context, resourceGroup, appName string) error {
-	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, nil)
+	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
 	if err != nil {
 		return fmt.Errorf("failed to create container apps client: %w", err)
 	}
@@ -311,7 +370,7 @@ func (c *Client) DeleteContainerApp(ctx context.Context, resourceGroup, appName
 // StopContainerApp stops a container app using the native Azure API
 // This immediately stops the container app (not scale-to-zero)
 func (c *Client) StopContainerApp(ctx context.Context, resourceGroup, appName string) error {
-	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, nil)
+	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
 	if err != nil {
 		return fmt.Errorf("failed to create container apps client: %w", err)
 	}
@@ -334,7 +393,7 @@ func (c *Client) StopContainerApp(ctx context.Context, resourceGroup, appName st
 // StartContainerApp starts a container app using the native Azure API
 // This immediately starts the stopped container app
 func (c *Client) StartContainerApp(ctx context.Context, resourceGroup, appName string) error {
-	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, nil)
+	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
 	if err != nil {
 		return fmt.Errorf("failed to create container apps client: %w", err)
 	}
@@ -354,19 +413,90 @@ func (c *Client) StartContainerApp(ctx context.Context, resourceGroup, appName s
 	return nil
 }
 
+// UpdateContainerAppResources changes the CPU and memory of a container app's workspace
+// container. In single revision mode this rolls a new revision that replaces the old one.
+func (c *Client) UpdateContainerAppResources(ctx context.Context, resourceGroup, appName string, cpuCores, memoryGB float64) error {
+	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
+	if err != nil {
+		return fmt.Errorf("failed to create container apps client: %w", err)
+	}
+
+	resp, err := client.Get(ctx, resourceGroup, appName, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get container app %s: %w", appName, err)
+	}
+
+	app := resp.ContainerApp
+	if app.Properties == nil || app.Properties.Template == nil || len(app.Properties.Template.Containers) == 0 {
+		return fmt.Errorf("container app %s has no containers", appName)
+	}
+	for _, container := range app.Properties.Template.Containers {
+		container.Resources = &armappcontainers.ContainerResources{
+			CPU:    to.Ptr(cpuCores),
+			Memory: to.Ptr(fmt.Sprintf("%.1fGi", memoryGB)),
+		}
+	}
+	// Secret values are not returned by Get; leaving them out of the patch keeps them unchanged
+	if app.Properties.Configuration != nil {
+		app.Properties.Configuration.Secrets = nil
+	}
+
+	poller, err := client.BeginUpdate(ctx, resourceGroup, appName, app, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin resize for container app %s: %w", appName, err)
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return fmt.Errorf("failed to resize container app %s: %w", appName, err)
+	}
+
+	return nil
+}
+
+// UpdateContainerAppEnv replaces the environment variables and secrets of a container
+// app's workspace container with those of spec. In single revision mode this rolls a
+// new revision that replaces the old one.
+func (c *Client) UpdateContainerAppEnv(ctx context.Context, resourceGroup, appName string, spec ContainerAppSpec) error {
+	client, err := armappcontainers.NewContainerAppsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
+	if err != nil {
+		return fmt.Errorf("failed to create container apps client: %w", err)
+	}
+
+	resp, err := client.Get(ctx, resourceGroup, appName, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get container app %s: %w", appName, err)
+	}
+
+	app := resp.ContainerApp
+	if app.Properties == nil || app.Properties.Template == nil || len(app.Properties.Template.Containers) == 0 {
+		return fmt.Errorf("container app %s has no containers", appName)
+	}
+	secrets, envVars := containerAppEnv(spec)
+	for _, container := range app.Properties.Template.Containers {
+		container.Env = envVars
+	}
+	if app.Properties.Configuration == nil {
+		app.Properties.Configuration = &armappcontainers.Configuration{}
+	}
+	app.Properties.Configuration.Secrets = secrets
+
+	poller, err := client.BeginUpdate(ctx, resourceGroup, appName, app, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin credential update for container app %s: %w", appName, err)
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return fmt.Errorf("failed to update credentials of container app %s: %w", appName, err)
+	}
+
+	return nil
+}
+
 // RegisterStorageWithEnvironment registers an Azure File Share with an ACA managed environment
 // This MUST be called before creating container apps that reference the storage
 func (c *Client) RegisterStorageWithEnvironment(ctx context.Context, resourceGroup, environmentID, fileShareName, storageAccountName string) error {
-	// Parse environment name from ID
-	// environmentID format: /subscriptions/{sub}/resourceGroups/{rg}/providers/Microsoft.App/managedEnvironments/{name}
-	envName := environmentID
-	if strings.Contains(environmentID, "/") {
-		parts := strings.Split(environmentID, "/")
-		envName = parts[len(parts)-1]
-	}
+	envName := managedEnvironmentName(environmentID)
 
 	// Initialize Managed Environments Storages client (dedicated client for storage operations)
-	storageClient, err := armappcontainers.NewManagedEnvironmentsStoragesClient(c.config.Azure.SubscriptionID, c.credential, nil)
+	storageClient, err := armappcontainers.NewManagedEnvironmentsStoragesClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
 	if err != nil {
 		return fmt.Errorf("failed to create managed environments storages client: %w", err)
 	}
@@ -400,9 +530,55 @@ func (c *Client) RegisterStorageWithEnvironment(ctx context.Context, resourceGro
 	return nil
 }
 
+// ListEnvironmentStorages returns the names of all storages registered with an ACA managed environment
+func (c *Client) ListEnvironmentStorages(ctx context.Context, resourceGroup, environmentID string) ([]string, error) {
+	storageClient, err := armappcontainers.NewManagedEnvironmentsStoragesClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create managed environments storages client: %w", err)
+	}
+
+	resp, err := storageClient.List(ctx, resourceGroup, managedEnvironmentName(environmentID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list environment storages: %w", err)
+	}
+
+	var names []string
+	for _, storage := range resp.Value {
+		if storage != nil && storage.Name != nil {
+			names = append(names, *storage.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// UnregisterStorageFromEnvironment removes a storage registration from an ACA managed environment
+func (c *Client) UnregisterStorageFromEnvironment(ctx context.Context, resourceGroup, environmentID, storageName string) error {
+	storageClient, err := armappcontainers.NewManagedEnvironmentsStoragesClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
+	if err != nil {
+		return fmt.Errorf("failed to create managed environments storages client: %w", err)
+	}
+
+	if _, err := storageClient.Delete(ctx, resourceGroup, managedEnvironmentName(environmentID), storageName, nil); err != nil {
+		return fmt.Errorf("storage %s: failed to unregister from environment: %w", storageName, err)
+	}
+
+	return nil
+}
+
+// managedEnvironmentName parses the environment name from an ACA environment ID
+// environmentID format: /subscriptions/{sub}/resourceGroups/{rg}/providers/Microsoft.App/managedEnvironments/{name}
+func managedEnvironmentName(environmentID string) string {
+	if strings.Contains(environmentID, "/") {
+		parts := strings.Split(environmentID, "/")
+		return parts[len(parts)-1]
+	}
+	return environmentID
+}
+
 // GetStorageAccountKey retrieves the primary key for a storage account
 func (c *Client) GetStorageAccountKey(ctx context.Context, resourceGroup, storageAccountName string) (string, error) {
-	storageClient, err := armstorage.NewAccountsClient(c.config.Azure.SubscriptionID, c.credential, nil)
+	storageClient, err := armstorage.NewAccountsClient(c.config.Azure.SubscriptionID, c.credential, c.armOptions(c.regionOf(resourceGroup)))
 	if err != nil {
 		return "", fmt.Errorf("failed to create storage client: %w", err)
 	}
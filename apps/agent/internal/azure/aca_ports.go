@@ -0,0 +1,134 @@
+package azure
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// containerAppsPortsAPIVersion is the first GA Container Apps API version with
+// additionalPortMappings; the armappcontainers v2 SDK uses an older one
+const containerAppsPortsAPIVersion = "2024-03-01"
+
+// maxAdditionalPortMappings is the Container Apps limit on additional ingress ports
+const maxAdditionalPortMappings = 5
+
+// ContainerAppPortMapping is an additional TCP port of a container app's ingress
+type ContainerAppPortMapping struct {
+	External    bool  `json:"external"`              // Reachable from outside the managed environment
+	TargetPort  int32 `json:"targetPort"`            // Port the container listens on
+	ExposedPort int32 `json:"exposedPort,omitempty"` // Port clients connect to; defaults to TargetPort
+}
+
+// containerAppPortMappings maps the ports after the HTTP ingress port to additional
+// port mappings, exposed on the same port number. Container Apps has no UDP ingress.
+func containerAppPortMappings(ports []ContainerPort) ([]ContainerAppPortMapping, error) {
+	if len(ports) > maxAdditionalPortMappings {
+		return nil, fmt.Errorf("container apps allow at most %d additional ports, got %d", maxAdditionalPortMappings, len(ports))
+	}
+
+	var mappings []ContainerAppPortMapping
+	for _, port := range ports {
+		if port.Protocol == "udp" {
+			return nil, fmt.Errorf("container apps cannot expose UDP port %d", port.Port)
+		}
+		mappings = append(mappings, ContainerAppPortMapping{External: port.Public, TargetPort: port.Port, ExposedPort: port.Port})
+	}
+	return mappings, nil
+}
+
+// containerAppSecretName derives a container app secret name (lower case alphanumerics
+// and '-') from an environment variable name. The "env-" prefix keeps user secrets apart
+// from the agent's own.
+func containerAppSecretName(envVar string) string {
+	return "env-" + strings.ReplaceAll(strings.ToLower(envVar), "_", "-")
+}
+
+// setContainerAppPortMappings replaces the additional port mappings of a container app
+func (c *Client) setContainerAppPortMappings(ctx context.Context, resourceGroup, appName string, mappings []ContainerAppPortMapping) error {
+	client, err := arm.NewClient("dev8-agent/azure", "v1.0.0", c.credential, c.armOptions(c.regionOf(resourceGroup)))
+	if err != nil {
+		return fmt.Errorf("failed to create resource manager client: %w", err)
+	}
+
+	req, err := runtime.NewRequest(ctx, http.MethodPatch, c.containerAppURL(client, resourceGroup, appName))
+	if err != nil {
+		return err
+	}
+	body := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"configuration": map[string]interface{}{
+				"ingress": map[string]interface{}{"additionalPortMappings": mappings},
+			},
+		},
+	}
+	if err := runtime.MarshalAsJSON(req, body); err != nil {
+		return err
+	}
+
+	resp, err := client.Pipeline().Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to set port mappings of container app %s: %w", appName, err)
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK, http.StatusAccepted) {
+		return fmt.Errorf("failed to set port mappings of container app %s: %w", appName, runtime.NewResponseError(resp))
+	}
+
+	poller, err := runtime.NewPoller[map[string]interface{}](resp, client.Pipeline(), nil)
+	if err != nil {
+		return err
+	}
+	if _, err := poller.PollUntilDone(ctx, nil); err != nil {
+		return fmt.Errorf("failed to set port mappings of container app %s: %w", appName, err)
+	}
+	return nil
+}
+
+// GetContainerAppPortMappings returns the additional port mappings of a container app
+func (c *Client) GetContainerAppPortMappings(ctx context.Context, resourceGroup, appName string) ([]ContainerAppPortMapping, error) {
+	client, err := arm.NewClient("dev8-agent/azure", "v1.0.0", c.credential, c.armOptions(c.regionOf(resourceGroup)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create resource manager client: %w", err)
+	}
+
+	req, err := runtime.NewRequest(ctx, http.MethodGet, c.containerAppURL(client, resourceGroup, appName))
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Pipeline().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get container app %s: %w", appName, err)
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return nil, fmt.Errorf("failed to get container app %s: %w", appName, runtime.NewResponseError(resp))
+	}
+
+	var app struct {
+		Properties struct {
+			Configuration struct {
+				Ingress struct {
+					AdditionalPortMappings []ContainerAppPortMapping `json:"additionalPortMappings"`
+				} `json:"ingress"`
+			} `json:"configuration"`
+		} `json:"properties"`
+	}
+	if err := runtime.UnmarshalAsJSON(resp, &app); err != nil {
+		return nil, err
+	}
+	return app.Properties.Configuration.Ingress.AdditionalPortMappings, nil
+}
+
+// containerAppURL returns the resource manager URL of a container app at the port mappings API version
+func (c *Client) containerAppURL(client *arm.Client, resourceGroup, appName string) string {
+	return fmt.Sprintf("%s/subscriptions/%s/resourceGroups/%s/providers/Microsoft.App/containerApps/%s?api-version=%s",
+		strings.TrimSuffix(client.Endpoint(), "/"),
+		url.PathEscape(c.config.Azure.SubscriptionID),
+		url.PathEscape(resourceGroup),
+		url.PathEscape(appName),
+		containerAppsPortsAPIVersion)
+}
@@ -0,0 +1,229 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/google/uuid"
+)
+
+// blobAPIVersion is the Blob service API version used for leases
+const blobAPIVersion = "2023-11-03"
+
+// storageScope is the token scope of every Storage data plane request
+const storageScope = "https://storage.azure.com/.default"
+
+// blobMetadataPrefix prefixes the headers carrying blob metadata
+const blobMetadataPrefix = "x-ms-meta-"
+
+// ErrLeaseHeld is returned by AcquireLease when another holder has the lease
+var ErrLeaseHeld = errors.New("blob lease is held by another client")
+
+// BlobLeaseClient takes leases on the blobs of one Blob container. A lease is
+// exclusive across every client of the container, which makes it a distributed lock.
+type BlobLeaseClient struct {
+	containerURL string
+	pipeline     runtime.Pipeline
+}
+
+// NewBlobLeaseClient creates a client for container in storage account accountName,
+// authenticated with DefaultAzureCredential like the resource manager clients.
+// The identity needs the Storage Blob Data Contributor role on the container. Its
+// calls retry through retrier and count against region's circuit breaker. retrier
+// may be nil.
+func NewBlobLeaseClient(accountName, container string, retrier *Retrier, region string) (*BlobLeaseClient, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+	return newBlobLeaseClient(fmt.Sprintf("https://%s.blob.core.windows.net/%s", accountName, container), cred, retrier.ClientOptions(region)), nil
+}
+
+func newBlobLeaseClient(containerURL string, cred azcore.TokenCredential, options azcore.ClientOptions) *BlobLeaseClient {
+	pipeline := runtime.NewPipeline("dev8-agent/locks", "v1.0.0", runtime.PipelineOptions{
+		PerRetry: []policy.Policy{runtime.NewBearerTokenPolicy(cred, []string{storageScope}, nil)},
+	}, &options)
+	return &BlobLeaseClient{containerURL: containerURL, pipeline: pipeline}
+}
+
+// EnsureContainer creates the container if it does not exist
+func (b *BlobLeaseClient) EnsureContainer(ctx context.Context) error {
+	req, err := b.newRequest(ctx, http.MethodPut, b.containerURL, "restype=container")
+	if err != nil {
+		return err
+	}
+
+	resp, err := b.pipeline.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to create lock container: %w", err)
+	}
+	if !runtime.HasStatusCode(resp, http.StatusCreated, http.StatusConflict) {
+		return fmt.Errorf("failed to create lock container: %w", runtime.NewResponseError(resp))
+	}
+	return nil
+}
+
+// AcquireLease creates blob if needed, takes a lease on it for duration (15-60s,
+// renewed with RenewLease) and stores metadata on it for other clients to read.
+// It returns the lease ID, or ErrLeaseHeld when another client has the lease.
+func (b *BlobLeaseClient) AcquireLease(ctx context.Context, blob string, duration time.Duration, metadata map[string]string) (string, error) {
+	if err := b.createBlob(ctx, blob); err != nil {
+		return "", err
+	}
+
+	req, err := b.newRequest(ctx, http.MethodPut, b.blobURL(blob), "comp=lease")
+	if err != nil {
+		return "", err
+	}
+	leaseID := uuid.NewString()
+	req.Raw().Header.Set("x-ms-lease-action", "acquire")
+	req.Raw().Header.Set("x-ms-lease-duration", strconv.Itoa(int(duration/time.Second)))
+	req.Raw().Header.Set("x-ms-proposed-lease-id", leaseID)
+
+	resp, err := b.pipeline.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to acquire lease on %s: %w", blob, err)
+	}
+	if runtime.HasStatusCode(resp, http.StatusConflict) {
+		return "", ErrLeaseHeld
+	}
+	if !runtime.HasStatusCode(resp, http.StatusCreated) {
+		return "", fmt.Errorf("failed to acquire lease on %s: %w", blob, runtime.NewResponseError(resp))
+	}
+
+	if err := b.setMetadata(ctx, blob, leaseID, metadata); err != nil {
+		_ = b.ReleaseLease(ctx, blob, leaseID)
+		return "", err
+	}
+	return leaseID, nil
+}
+
+// RenewLease extends a held lease by its duration
+func (b *BlobLeaseClient) RenewLease(ctx context.Context, blob, leaseID string) error {
+	return b.leaseAction(ctx, blob, leaseID, "renew", http.StatusOK)
+}
+
+// ReleaseLease gives a lease up so another client can acquire it
+func (b *BlobLeaseClient) ReleaseLease(ctx context.Context, blob, leaseID string) error {
+	return b.leaseAction(ctx, blob, leaseID, "release", http.StatusOK)
+}
+
+// Metadata returns the metadata of blob. IsNotFound reports a missing blob.
+func (b *BlobLeaseClient) Metadata(ctx context.Context, blob string) (map[string]string, error) {
+	req, err := b.newRequest(ctx, http.MethodHead, b.blobURL(blob), "")
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := b.pipeline.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metadata of %s: %w", blob, err)
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return nil, fmt.Errorf("failed to read metadata of %s: %w", blob, runtime.NewResponseError(resp))
+	}
+
+	metadata := make(map[string]string)
+	for name, values := range resp.Header {
+		if key, ok := strings.CutPrefix(strings.ToLower(name), blobMetadataPrefix); ok && len(values) > 0 {
+			metadata[key] = values[0]
+		}
+	}
+	return metadata, nil
+}
+
+// createBlob creates blob as an empty block blob unless it already exists
+func (b *BlobLeaseClient) createBlob(ctx context.Context, blob string) error {
+	req, err := b.newRequest(ctx, http.MethodPut, b.blobURL(blob), "")
+	if err != nil {
+		return err
+	}
+	req.Raw().Header.Set("x-ms-blob-type", "BlockBlob")
+	req.Raw().Header.Set("If-None-Match", "*")
+	req.Raw().ContentLength = 0
+
+	resp, err := b.pipeline.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to create lock blob %s: %w", blob, err)
+	}
+	// 409 and 412 mean the blob exists, possibly leased by someone else
+	if !runtime.HasStatusCode(resp, http.StatusCreated, http.StatusConflict, http.StatusPreconditionFailed) {
+		return fmt.Errorf("failed to create lock blob %s: %w", blob, runtime.NewResponseError(resp))
+	}
+	return nil
+}
+
+// setMetadata replaces the metadata of a blob leased with leaseID
+func (b *BlobLeaseClient) setMetadata(ctx context.Context, blob, leaseID string, metadata map[string]string) error {
+	req, err := b.newRequest(ctx, http.MethodPut, b.blobURL(blob), "comp=metadata")
+	if err != nil {
+		return err
+	}
+	req.Raw().Header.Set("x-ms-lease-id", leaseID)
+	for key, value := range metadata {
+		req.Raw().Header.Set(blobMetadataPrefix+key, value)
+	}
+
+	resp, err := b.pipeline.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to set metadata of %s: %w", blob, err)
+	}
+	if !runtime.HasStatusCode(resp, http.StatusOK) {
+		return fmt.Errorf("failed to set metadata of %s: %w", blob, runtime.NewResponseError(resp))
+	}
+	return nil
+}
+
+func (b *BlobLeaseClient) leaseAction(ctx context.Context, blob, leaseID, action string, want int) error {
+	req, err := b.newRequest(ctx, http.MethodPut, b.blobURL(blob), "comp=lease")
+	if err != nil {
+		return err
+	}
+	req.Raw().Header.Set("x-ms-lease-action", action)
+	req.Raw().Header.Set("x-ms-lease-id", leaseID)
+
+	resp, err := b.pipeline.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to %s lease on %s: %w", action, blob, err)
+	}
+	if !runtime.HasStatusCode(resp, want) {
+		return fmt.Errorf("failed to %s lease on %s: %w", action, blob, runtime.NewResponseError(resp))
+	}
+	return nil
+}
+
+// newRequest creates a Blob service request with the headers every request needs
+func (b *BlobLeaseClient) newRequest(ctx context.Context, method, endpoint, query string) (*policy.Request, error) {
+	if query != "" {
+		endpoint += "?" + query
+	}
+	req, err := runtime.NewRequest(ctx, method, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	req.Raw().Header.Set("x-ms-version", blobAPIVersion)
+	req.Raw().Header.Set("x-ms-date", time.Now().UTC().Format(http.TimeFormat))
+	return req, nil
+}
+
+// blobURL returns the data plane URL of blob
+func (b *BlobLeaseClient) blobURL(blob string) string {
+	return b.containerURL + "/" + url.PathEscape(blob)
+}
+
+// IsLeaseLost reports whether err means a lease expired or was taken over, so
+// renewing or releasing it is pointless
+func IsLeaseLost(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && (respErr.StatusCode == http.StatusConflict || respErr.StatusCode == http.StatusPreconditionFailed)
+}
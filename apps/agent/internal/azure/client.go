@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	armappcontainers "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
@@ -18,6 +19,7 @@ type Client struct {
 	credential azcore.TokenCredential
 	aciClients map[string]*armcontainerinstance.ContainerGroupsClient
 	acaClients map[string]*armappcontainers.ContainerAppsClient
+	retrier    *Retrier
 }
 
 // NewClient creates a new Azure client
@@ -37,6 +39,7 @@ func NewClient(cfg *config.Config) (*Client, error) {
 		credential: cred,
 		aciClients: make(map[string]*armcontainerinstance.ContainerGroupsClient),
 		acaClients: make(map[string]*armappcontainers.ContainerAppsClient),
+		retrier:    NewRetrier(cfg.Azure.Retry),
 	}
 
 	// Initialize clients based on deployment mode
@@ -66,7 +69,7 @@ func (c *Client) initACIClient(region string) error {
 	client, err := armcontainerinstance.NewContainerGroupsClient(
 		c.config.Azure.SubscriptionID,
 		c.credential,
-		nil,
+		c.armOptions(region),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create ACI client: %w", err)
@@ -85,7 +88,7 @@ func (c *Client) initACAClient(region string) error {
 	client, err := armappcontainers.NewContainerAppsClient(
 		c.config.Azure.SubscriptionID,
 		c.credential,
-		nil,
+		c.armOptions(region),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to create ACA client: %w", err)
@@ -95,6 +98,31 @@ func (c *Client) initACAClient(region string) error {
 	return nil
 }
 
+// Retrier returns the retrier shared by the client's calls, for other clients of the same
+// regions. A nil client has none.
+func (c *Client) Retrier() *Retrier {
+	if c == nil {
+		return nil
+	}
+	return c.retrier
+}
+
+// armOptions returns resource manager client options that retry and count failures against region
+func (c *Client) armOptions(region string) *arm.ClientOptions {
+	return c.retrier.armOptions(region)
+}
+
+// regionOf returns the region whose resource group is resourceGroup, for calls that only
+// name a resource group; unknown resource groups get a breaker of their own
+func (c *Client) regionOf(resourceGroup string) string {
+	for _, region := range c.config.Azure.Regions {
+		if region.ResourceGroupName == resourceGroup {
+			return region.Name
+		}
+	}
+	return resourceGroup
+}
+
 // GetACIClient returns the ACI client for the specified region
 func (c *Client) GetACIClient(region string) (*armcontainerinstance.ContainerGroupsClient, error) {
 	client, exists := c.aciClients[region]
@@ -194,6 +222,15 @@ func (c *Client) CreateContainerGroup(ctx context.Context, region, resourceGroup
 		})
 	}
 
+	// User environment variables
+	for _, v := range spec.EnvVars {
+		envVar := &armcontainerinstance.EnvironmentVariable{Name: to.Ptr(v.Name), Value: to.Ptr(v.Value)}
+		if v.Secret {
+			envVar = &armcontainerinstance.EnvironmentVariable{Name: to.Ptr(v.Name), SecureValue: to.Ptr(v.Value)}
+		}
+		envVars = append(envVars, envVar)
+	}
+
 	// Backup configuration (always enabled)
 	if spec.StorageAccountName != "" {
 		envVars = append(envVars,
@@ -204,6 +241,8 @@ func (c *Client) CreateContainerGroup(ctx context.Context, region, resourceGroup
 		)
 	}
 
+	containerPorts, publicPorts := containerGroupPorts(spec.Ports)
+
 	// Build container group configuration
 	containerGroup := armcontainerinstance.ContainerGroup{
 		Location: to.Ptr(region),
@@ -220,19 +259,15 @@ func (c *Client) CreateContainerGroup(ctx context.Context, region, resourceGroup
 								MemoryInGB: to.Ptr(float64(spec.MemoryGB)),
 							},
 						},
-						Ports: []*armcontainerinstance.ContainerPort{
-							{Port: to.Ptr(int32(8080)), Protocol: to.Ptr(armcontainerinstance.ContainerNetworkProtocolTCP)},
-						},
+						Ports:                containerPorts,
 						VolumeMounts:         volumeMounts,
 						EnvironmentVariables: envVars,
 					},
 				},
 			},
 			IPAddress: &armcontainerinstance.IPAddress{
-				Type: to.Ptr(armcontainerinstance.ContainerGroupIPAddressTypePublic),
-				Ports: []*armcontainerinstance.Port{
-					{Port: to.Ptr(int32(8080)), Protocol: to.Ptr(armcontainerinstance.ContainerGroupNetworkProtocolTCP)},
-				},
+				Type:         to.Ptr(armcontainerinstance.ContainerGroupIPAddressTypePublic),
+				Ports:        publicPorts,
 				DNSNameLabel: to.Ptr(spec.DNSNameLabel),
 			},
 			RestartPolicy: to.Ptr(armcontainerinstance.ContainerGroupRestartPolicyOnFailure),
@@ -271,6 +306,31 @@ func (c *Client) CreateContainerGroup(ctx context.Context, region, resourceGroup
 	return nil
 }
 
+// containerGroupPorts returns the ports opened in the container and the public ones
+// opened on the group's IP address. Private ports are only reachable within the group.
+// Without ports the container exposes code-server (8080) only.
+func containerGroupPorts(ports []ContainerPort) ([]*armcontainerinstance.ContainerPort, []*armcontainerinstance.Port) {
+	if len(ports) == 0 {
+		ports = []ContainerPort{{Port: 8080, Protocol: "tcp", Public: true}}
+	}
+
+	var containerPorts []*armcontainerinstance.ContainerPort
+	var publicPorts []*armcontainerinstance.Port
+	for _, port := range ports {
+		containerProtocol := armcontainerinstance.ContainerNetworkProtocolTCP
+		groupProtocol := armcontainerinstance.ContainerGroupNetworkProtocolTCP
+		if port.Protocol == "udp" {
+			containerProtocol = armcontainerinstance.ContainerNetworkProtocolUDP
+			groupProtocol = armcontainerinstance.ContainerGroupNetworkProtocolUDP
+		}
+		containerPorts = append(containerPorts, &armcontainerinstance.ContainerPort{Port: to.Ptr(port.Port), Protocol: to.Ptr(containerProtocol)})
+		if port.Public {
+			publicPorts = append(publicPorts, &armcontainerinstance.Port{Port: to.Ptr(port.Port), Protocol: to.Ptr(groupProtocol)})
+		}
+	}
+	return containerPorts, publicPorts
+}
+
 // GetContainerGroup retrieves an ACI container group
 func (c *Client) GetContainerGroup(ctx context.Context, region, resourceGroup, name string) (*armcontainerinstance.ContainerGroup, error) {
 	client, err := c.GetACIClient(region)
@@ -286,6 +346,26 @@ func (c *Client) GetContainerGroup(ctx context.Context, region, resourceGroup, n
 	return &resp.ContainerGroup, nil
 }
 
+// ListContainerGroups lists all ACI container groups in a resource group
+func (c *Client) ListContainerGroups(ctx context.Context, region, resourceGroup string) ([]*armcontainerinstance.ContainerGroup, error) {
+	client, err := c.GetACIClient(region)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []*armcontainerinstance.ContainerGroup
+	pager := client.NewListByResourceGroupPager(resourceGroup, nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list container groups in %s: %w", resourceGroup, err)
+		}
+		groups = append(groups, page.Value...)
+	}
+
+	return groups, nil
+}
+
 // DeleteContainerGroup deletes an ACI container group
 func (c *Client) DeleteContainerGroup(ctx context.Context, region, resourceGroup, name string) error {
 	client, err := c.GetACIClient(region)
@@ -366,4 +446,22 @@ type ContainerGroupSpec struct {
 	AnthropicAPIKey    string
 	OpenAIAPIKey       string
 	GeminiAPIKey       string
+
+	// User environment variables and exposed ports
+	EnvVars []EnvVar
+	Ports   []ContainerPort
+}
+
+// EnvVar is a user environment variable; secrets are passed as secure values
+type EnvVar struct {
+	Name   string
+	Value  string
+	Secret bool
+}
+
+// ContainerPort is a port the workspace container exposes
+type ContainerPort struct {
+	Port     int32
+	Protocol string // "tcp" or "udp"; HTTP ports are tcp
+	Public   bool   // Reachable from the internet, not only from within the container group or environment
 }
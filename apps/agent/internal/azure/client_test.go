@@ -34,6 +34,70 @@ func TestContainerGroupSpec(t *testing.T) {
 	}
 }
 
+func TestContainerGroupPorts(t *testing.T) {
+	containerPorts, publicPorts := containerGroupPorts(nil)
+	if len(containerPorts) != 1 || *containerPorts[0].Port != 8080 || len(publicPorts) != 1 {
+		t.Errorf("containerGroupPorts(nil) = %d container, %d public ports, want code-server only", len(containerPorts), len(publicPorts))
+	}
+
+	containerPorts, publicPorts = containerGroupPorts([]ContainerPort{
+		{Port: 8080, Protocol: "tcp", Public: true},
+		{Port: 5353, Protocol: "udp", Public: true},
+		{Port: 5432, Protocol: "tcp"},
+	})
+	if len(containerPorts) != 3 {
+		t.Errorf("containerGroupPorts() container ports = %d, want 3", len(containerPorts))
+	}
+	if len(publicPorts) != 2 || *publicPorts[1].Protocol != armcontainerinstance.ContainerGroupNetworkProtocolUDP {
+		t.Errorf("containerGroupPorts() public ports = %d, want 8080/tcp and 5353/udp", len(publicPorts))
+	}
+}
+
+func TestContainerAppPortMappings(t *testing.T) {
+	mappings, err := containerAppPortMappings([]ContainerPort{{Port: 2222, Protocol: "tcp", Public: true}, {Port: 5432, Protocol: "tcp"}})
+	if err != nil {
+		t.Fatalf("containerAppPortMappings() error = %v", err)
+	}
+	if len(mappings) != 2 || !mappings[0].External || mappings[1].External || mappings[1].ExposedPort != 5432 {
+		t.Errorf("containerAppPortMappings() = %+v", mappings)
+	}
+
+	if _, err := containerAppPortMappings([]ContainerPort{{Port: 5353, Protocol: "udp"}}); err == nil {
+		t.Error("containerAppPortMappings() with a UDP port error = nil")
+	}
+	if _, err := containerAppPortMappings(make([]ContainerPort, maxAdditionalPortMappings+1)); err == nil {
+		t.Error("containerAppPortMappings() above the limit error = nil")
+	}
+
+	if got := containerAppSecretName("DATABASE_URL"); got != "env-database-url" {
+		t.Errorf("containerAppSecretName() = %q, want env-database-url", got)
+	}
+}
+
+func TestContainerAppEnv(t *testing.T) {
+	secrets, envVars := containerAppEnv(ContainerAppSpec{
+		WorkspaceID:        "ws-1",
+		CodeServerPassword: "pw",
+		SSHPublicKey:       "ssh-ed25519 AAAA",
+		EnvVars:            []EnvVar{{Name: "API_TOKEN", Value: "t0k3n", Secret: true}},
+	})
+	if len(secrets) != 2 || *secrets[0].Name != "code-server-password" || *secrets[1].Value != "t0k3n" {
+		t.Errorf("containerAppEnv() secrets = %d, want the password and API_TOKEN", len(secrets))
+	}
+
+	refs := make(map[string]string)
+	for _, v := range envVars {
+		if v.SecretRef != nil {
+			refs[*v.Name] = *v.SecretRef
+		} else if *v.Name == "CODE_SERVER_PASSWORD" || *v.Name == "API_TOKEN" {
+			t.Errorf("%s has a plain value", *v.Name)
+		}
+	}
+	if refs["CODE_SERVER_PASSWORD"] != "code-server-password" || refs["API_TOKEN"] != "env-api-token" {
+		t.Errorf("containerAppEnv() secret refs = %v", refs)
+	}
+}
+
 func TestNewClient_InitializesACIClients(t *testing.T) {
 	// This test verifies that NewClient initializes ACI clients for enabled regions
 	// We can't fully test without Azure credentials, but we can test the structure
@@ -0,0 +1,164 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+)
+
+// ErrorKind classifies why an Azure call failed
+type ErrorKind string
+
+const (
+	ErrorThrottled     ErrorKind = "throttled"      // 429 or ServerBusy; retry after RetryAfter
+	ErrorQuotaExceeded ErrorKind = "quota-exceeded" // Subscription quota or regional capacity exhausted
+	ErrorNotFound      ErrorKind = "not-found"
+	ErrorConflict      ErrorKind = "conflict"          // The resource exists or is in a conflicting state
+	ErrorImagePull     ErrorKind = "image-pull-failed" // The container image could not be pulled
+	ErrorTransient     ErrorKind = "transient"         // Timeouts, 5xx, dropped connections, open circuit
+)
+
+// Error is a classified Azure error. It wraps the SDK error, whose message it keeps.
+type Error struct {
+	Kind       ErrorKind
+	StatusCode int           // HTTP status, 0 for transport errors
+	Code       string        // Azure error code, e.g. "ContainerGroupQuotaReached"
+	RetryAfter time.Duration // Server-requested wait before retrying, 0 if none
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the same call may succeed when retried later
+func (e *Error) Retryable() bool {
+	return e.Kind == ErrorThrottled || e.Kind == ErrorTransient
+}
+
+// quotaErrorCodes are error codes of exhausted quotas and regional capacity that do not
+// contain "Quota"
+var quotaErrorCodes = map[string]bool{
+	"SkuNotAvailable":                  true,
+	"ResourceNotAvailable":             true,
+	"ResourceRequestsNotAvailable":     true,
+	"MaxNumberOfRegionalEnvironments":  true,
+	"ManagedEnvironmentNotEnoughCores": true,
+}
+
+// imagePullErrorCodes are error codes of images that could not be pulled
+var imagePullErrorCodes = map[string]bool{
+	"InaccessibleImage":            true,
+	"RegistryErrorResponse":        true,
+	"ImageNotFound":                true,
+	"ContainerAppImagePullFailure": true,
+}
+
+// transientErrorCodes are retryable error codes returned with non-5xx statuses
+var transientErrorCodes = map[string]bool{
+	"AnotherOperationInProgress": true,
+	"OperationNotAllowedRetry":   true,
+}
+
+// Classify returns the classified Azure error in err's chain, classifying SDK response
+// errors and transport failures. ok is false for errors it cannot classify, including
+// cancelled contexts.
+func Classify(err error) (classified *Error, ok bool) {
+	if err == nil {
+		return nil, false
+	}
+	if errors.As(err, &classified) {
+		return classified, true
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, false
+	}
+
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		kind := classifyResponse(respErr.StatusCode, respErr.ErrorCode, err.Error())
+		if kind == "" {
+			return nil, false
+		}
+		classified = &Error{Kind: kind, StatusCode: respErr.StatusCode, Code: respErr.ErrorCode, Err: err}
+		if respErr.RawResponse != nil {
+			classified.RetryAfter = retryAfter(respErr.RawResponse.Header)
+		}
+		return classified, true
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return &Error{Kind: ErrorTransient, Err: err}, true
+	}
+	return nil, false
+}
+
+// KindOf returns the kind of a classified Azure error, or "" for other errors
+func KindOf(err error) ErrorKind {
+	if classified, ok := Classify(err); ok {
+		return classified.Kind
+	}
+	return ""
+}
+
+// classifyResponse classifies an error response by its error code, then its status
+func classifyResponse(status int, code, message string) ErrorKind {
+	lowerMessage := strings.ToLower(message)
+	switch {
+	case imagePullErrorCodes[code] || strings.Contains(lowerMessage, "failed to pull image") ||
+		strings.Contains(message, "ErrImagePull") || strings.Contains(message, "ImagePullBackOff"):
+		return ErrorImagePull
+	case strings.Contains(strings.ToLower(code), "quota") || quotaErrorCodes[code]:
+		return ErrorQuotaExceeded
+	case code == "ServerBusy" || status == http.StatusTooManyRequests:
+		return ErrorThrottled
+	case transientErrorCodes[code]:
+		return ErrorTransient
+	}
+
+	switch status {
+	case http.StatusNotFound:
+		return ErrorNotFound
+	case http.StatusConflict, http.StatusPreconditionFailed:
+		return ErrorConflict
+	case http.StatusRequestTimeout, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return ErrorTransient
+	}
+	return ""
+}
+
+// retryAfter parses the wait requested by retry-after-ms, x-ms-retry-after-ms or
+// Retry-After (seconds or an HTTP date)
+func retryAfter(header http.Header) time.Duration {
+	for _, name := range []string{"retry-after-ms", "x-ms-retry-after-ms"} {
+		if ms, err := strconv.Atoi(header.Get(name)); err == nil && ms > 0 {
+			return time.Duration(ms) * time.Millisecond
+		}
+	}
+
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(at); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+)
+
+// responseError builds the SDK error of a response with the given status, error code,
+// message and headers
+func responseError(status int, code, message string, header http.Header) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	body := fmt.Sprintf(`{"error":{"code":%q,"message":%q}}`, code, message)
+	req, _ := http.NewRequest(http.MethodPut, "https://management.azure.com/subscriptions/sub/resourceGroups/rg", nil)
+	return runtime.NewResponseError(&http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	})
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantOK    bool
+		wantKind  ErrorKind
+		retryable bool
+	}{
+		{
+			name:      "429",
+			err:       responseError(http.StatusTooManyRequests, "TooManyRequests", "slow down", nil),
+			wantOK:    true,
+			wantKind:  ErrorThrottled,
+			retryable: true,
+		},
+		{
+			name:      "server busy",
+			err:       responseError(http.StatusServiceUnavailable, "ServerBusy", "busy", nil),
+			wantOK:    true,
+			wantKind:  ErrorThrottled,
+			retryable: true,
+		},
+		{
+			name:     "quota code",
+			err:      responseError(http.StatusConflict, "ContainerGroupQuotaReached", "quota reached", nil),
+			wantOK:   true,
+			wantKind: ErrorQuotaExceeded,
+		},
+		{
+			name:     "regional capacity",
+			err:      responseError(http.StatusConflict, "SkuNotAvailable", "no capacity", nil),
+			wantOK:   true,
+			wantKind: ErrorQuotaExceeded,
+		},
+		{
+			name:     "image pull code",
+			err:      responseError(http.StatusBadRequest, "InaccessibleImage", "cannot pull", nil),
+			wantOK:   true,
+			wantKind: ErrorImagePull,
+		},
+		{
+			name:     "image pull message",
+			err:      responseError(http.StatusBadRequest, "ContainerAppOperationError", "Failed to pull image \"dev8/x\"", nil),
+			wantOK:   true,
+			wantKind: ErrorImagePull,
+		},
+		{
+			name:     "not found",
+			err:      responseError(http.StatusNotFound, "ResourceNotFound", "missing", nil),
+			wantOK:   true,
+			wantKind: ErrorNotFound,
+		},
+		{
+			name:     "conflict",
+			err:      responseError(http.StatusConflict, "Conflict", "exists", nil),
+			wantOK:   true,
+			wantKind: ErrorConflict,
+		},
+		{
+			name:      "operation in progress",
+			err:       responseError(http.StatusConflict, "AnotherOperationInProgress", "busy", nil),
+			wantOK:    true,
+			wantKind:  ErrorTransient,
+			retryable: true,
+		},
+		{
+			name:      "server error",
+			err:       responseError(http.StatusBadGateway, "", "", nil),
+			wantOK:    true,
+			wantKind:  ErrorTransient,
+			retryable: true,
+		},
+		{
+			name:      "wrapped transport error",
+			err:       fmt.Errorf("create share: %w", &net.OpError{Op: "dial", Err: errors.New("connection refused")}),
+			wantOK:    true,
+			wantKind:  ErrorTransient,
+			retryable: true,
+		},
+		{
+			name:      "already classified",
+			err:       fmt.Errorf("create app: %w", circuitOpenError("eastus", time.Second)),
+			wantOK:    true,
+			wantKind:  ErrorTransient,
+			retryable: true,
+		},
+		{
+			name:   "bad request",
+			err:    responseError(http.StatusBadRequest, "InvalidParameter", "bad cpu", nil),
+			wantOK: false,
+		},
+		{
+			name:   "cancelled",
+			err:    fmt.Errorf("create app: %w", context.Canceled),
+			wantOK: false,
+		},
+		{
+			name:   "plain error",
+			err:    errors.New("boom"),
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classified, ok := Classify(tt.err)
+			if ok != tt.wantOK {
+				t.Fatalf("Classify() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				if kind := KindOf(tt.err); kind != "" {
+					t.Errorf("KindOf() = %q, want empty", kind)
+				}
+				return
+			}
+			if classified.Kind != tt.wantKind {
+				t.Errorf("Classify() kind = %q, want %q", classified.Kind, tt.wantKind)
+			}
+			if classified.Retryable() != tt.retryable {
+				t.Errorf("Retryable() = %v, want %v", classified.Retryable(), tt.retryable)
+			}
+			if !errors.Is(classified, tt.err) && !errors.Is(tt.err, classified) {
+				t.Error("Classify() result does not wrap the original error")
+			}
+		})
+	}
+}
+
+func TestClassify_RetryAfter(t *testing.T) {
+	header := http.Header{}
+	header.Set("Retry-After", "12")
+	classified, ok := Classify(responseError(http.StatusTooManyRequests, "TooManyRequests", "slow down", header))
+	if !ok {
+		t.Fatal("Classify() ok = false")
+	}
+	if classified.RetryAfter != 12*time.Second {
+		t.Errorf("RetryAfter = %v, want 12s", classified.RetryAfter)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   time.Duration
+	}{
+		{name: "none", want: 0},
+		{name: "seconds", header: map[string]string{"Retry-After": "5"}, want: 5 * time.Second},
+		{name: "milliseconds first", header: map[string]string{"retry-after-ms": "250", "Retry-After": "5"}, want: 250 * time.Millisecond},
+		{name: "x-ms milliseconds", header: map[string]string{"x-ms-retry-after-ms": "1500"}, want: 1500 * time.Millisecond},
+		{name: "past date", header: map[string]string{"Retry-After": "Mon, 02 Jan 2006 15:04:05 GMT"}, want: 0},
+		{name: "invalid", header: map[string]string{"Retry-After": "soon"}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			for name, value := range tt.header {
+				header.Set(name, value)
+			}
+			if got := retryAfter(header); got != tt.want {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("future date", func(t *testing.T) {
+		header := http.Header{}
+		header.Set("Retry-After", time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+		if got := retryAfter(header); got <= 50*time.Second || got > time.Minute {
+			t.Errorf("retryAfter() = %v, want about 1m", got)
+		}
+	})
+}
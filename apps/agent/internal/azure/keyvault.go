@@ -0,0 +1,128 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+)
+
+// keyVaultRecoverTimeout bounds the wait for a soft-deleted secret to be recovered
+const keyVaultRecoverTimeout = 30 * time.Second
+
+// KeyVaultClient reads and writes the secrets of an Azure Key Vault
+type KeyVaultClient struct {
+	vaultURL string
+	client   *azsecrets.Client
+}
+
+// NewKeyVaultClient creates a client for the vault at vaultURL, authenticated
+// with DefaultAzureCredential like the resource manager clients. Its calls retry
+// through retrier and count against region's circuit breaker. retrier may be nil.
+func NewKeyVaultClient(vaultURL string, retrier *Retrier, region string) (*KeyVaultClient, error) {
+	cred, err := azidentity.NewDefaultAzureCredential(nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure credential: %w", err)
+	}
+	return newKeyVaultClient(vaultURL, cred, &azsecrets.ClientOptions{ClientOptions: retrier.ClientOptions(region)})
+}
+
+func newKeyVaultClient(vaultURL string, cred azcore.TokenCredential, options *azsecrets.ClientOptions) (*KeyVaultClient, error) {
+	vaultURL = strings.TrimSuffix(vaultURL, "/")
+	client, err := azsecrets.NewClient(vaultURL, cred, options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Key Vault client: %w", err)
+	}
+	return &KeyVaultClient{vaultURL: vaultURL, client: client}, nil
+}
+
+// SetSecret stores value as the new version of secret name and returns the
+// secret's versionless ID. A soft-deleted secret of the same name, left behind by
+// a deleted workspace whose ID is reused, is recovered first.
+func (k *KeyVaultClient) SetSecret(ctx context.Context, name, value, contentType string, tags map[string]string) (string, error) {
+	params := azsecrets.SetSecretParameters{
+		Value:       &value,
+		ContentType: &contentType,
+		Tags:        make(map[string]*string, len(tags)),
+	}
+	for key, tag := range tags {
+		params.Tags[key] = &tag
+	}
+
+	_, err := k.client.SetSecret(ctx, name, params, nil)
+	if isConflict(err) && k.isDeleted(ctx, name) {
+		if err := k.recoverSecret(ctx, name); err != nil {
+			return "", err
+		}
+		_, err = k.client.SetSecret(ctx, name, params, nil)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to set secret %s: %w", name, err)
+	}
+	return k.vaultURL + "/secrets/" + name, nil
+}
+
+// GetSecret returns the current value of secret name. IsNotFound reports a missing secret.
+func (k *KeyVaultClient) GetSecret(ctx context.Context, name string) (string, error) {
+	resp, err := k.client.GetSecret(ctx, name, "", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret %s: %w", name, err)
+	}
+	if resp.Value == nil {
+		return "", nil
+	}
+	return *resp.Value, nil
+}
+
+// DeleteSecret deletes secret name with all its versions. With soft-delete enabled
+// on the vault the secret stays recoverable for the vault's retention period, and
+// SetSecret recovers it if the name is used again. Deleting a missing secret is
+// not an error.
+func (k *KeyVaultClient) DeleteSecret(ctx context.Context, name string) error {
+	if _, err := k.client.DeleteSecret(ctx, name, nil); err != nil && !IsNotFound(err) {
+		return fmt.Errorf("failed to delete secret %s: %w", name, err)
+	}
+	return nil
+}
+
+// isDeleted reports whether secret name is soft-deleted
+func (k *KeyVaultClient) isDeleted(ctx context.Context, name string) bool {
+	_, err := k.client.GetDeletedSecret(ctx, name, nil)
+	return err == nil
+}
+
+// recoverSecret recovers soft-deleted secret name and waits until it can be
+// written, as recovery completes asynchronously
+func (k *KeyVaultClient) recoverSecret(ctx context.Context, name string) error {
+	if _, err := k.client.RecoverDeletedSecret(ctx, name, nil); err != nil {
+		return fmt.Errorf("failed to recover deleted secret %s: %w", name, err)
+	}
+
+	deadline := time.Now().Add(keyVaultRecoverTimeout)
+	for {
+		_, err := k.client.GetSecret(ctx, name, "", nil)
+		if err == nil {
+			return nil
+		}
+		if !IsNotFound(err) || time.Now().After(deadline) {
+			return fmt.Errorf("deleted secret %s was not recovered: %w", name, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// isConflict reports whether err is a 409 response
+func isConflict(err error) bool {
+	var respErr *azcore.ResponseError
+	return errors.As(err, &respErr) && respErr.StatusCode == http.StatusConflict
+}
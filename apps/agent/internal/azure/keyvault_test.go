@@ -0,0 +1,156 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+)
+
+const testVaultURL = "https://dev8.vault.azure.net"
+
+type fakeCredential struct{}
+
+func (fakeCredential) GetToken(ctx context.Context, opts policy.TokenRequestOptions) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+// fakeVault answers the Key Vault secret API from memory, soft-deleting like a
+// vault with soft-delete enabled
+type fakeVault struct {
+	mu      sync.Mutex
+	secrets map[string]string
+	deleted map[string]string
+}
+
+func newFakeVault() *fakeVault {
+	return &fakeVault{secrets: make(map[string]string), deleted: make(map[string]string)}
+}
+
+func (f *fakeVault) Do(req *http.Request) (*http.Response, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if req.Header.Get("Authorization") == "" {
+		header := http.Header{}
+		header.Set("WWW-Authenticate", `Bearer authorization="https://login.microsoftonline.com/tenant", resource="https://vault.azure.net"`)
+		return vaultResponse(req, http.StatusUnauthorized, header, nil), nil
+	}
+
+	path := strings.Trim(req.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	name := parts[1]
+	switch {
+	case parts[0] == "secrets" && req.Method == http.MethodPut:
+		if _, ok := f.deleted[name]; ok {
+			return vaultError(req, http.StatusConflict, "Conflict", "ObjectIsDeletedButRecoverable"), nil
+		}
+		var body struct {
+			Value string `json:"value"`
+		}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			return nil, err
+		}
+		f.secrets[name] = body.Value
+		return vaultSecret(req, name, body.Value), nil
+	case parts[0] == "secrets" && req.Method == http.MethodGet:
+		value, ok := f.secrets[name]
+		if !ok {
+			return vaultError(req, http.StatusNotFound, "SecretNotFound", ""), nil
+		}
+		return vaultSecret(req, name, value), nil
+	case parts[0] == "secrets" && req.Method == http.MethodDelete:
+		value, ok := f.secrets[name]
+		if !ok {
+			return vaultError(req, http.StatusNotFound, "SecretNotFound", ""), nil
+		}
+		delete(f.secrets, name)
+		f.deleted[name] = value
+		return vaultSecret(req, name, value), nil
+	case parts[0] == "deletedsecrets" && len(parts) == 3 && req.Method == http.MethodPost:
+		value, ok := f.deleted[name]
+		if !ok {
+			return vaultError(req, http.StatusNotFound, "SecretNotFound", ""), nil
+		}
+		delete(f.deleted, name)
+		f.secrets[name] = value
+		return vaultSecret(req, name, value), nil
+	case parts[0] == "deletedsecrets" && req.Method == http.MethodGet:
+		value, ok := f.deleted[name]
+		if !ok {
+			return vaultError(req, http.StatusNotFound, "SecretNotFound", ""), nil
+		}
+		return vaultSecret(req, name, value), nil
+	}
+	return nil, fmt.Errorf("unexpected request %s %s", req.Method, req.URL)
+}
+
+func vaultResponse(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("Content-Type", "application/json")
+	return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: header, Body: io.NopCloser(strings.NewReader(string(body))), Request: req}
+}
+
+func vaultSecret(req *http.Request, name, value string) *http.Response {
+	body, _ := json.Marshal(map[string]string{"value": value, "id": testVaultURL + "/secrets/" + name + "/1"})
+	return vaultResponse(req, http.StatusOK, nil, body)
+}
+
+func vaultError(req *http.Request, status int, code, innerCode string) *http.Response {
+	body, _ := json.Marshal(map[string]interface{}{
+		"error": map[string]interface{}{"code": code, "message": code, "innererror": map[string]string{"code": innerCode}},
+	})
+	return vaultResponse(req, status, nil, body)
+}
+
+func TestKeyVaultClient_DeleteThenRecreate(t *testing.T) {
+	ctx := context.Background()
+	vault := newFakeVault()
+	client, err := newKeyVaultClient(testVaultURL, fakeCredential{}, &azsecrets.ClientOptions{
+		ClientOptions:                        azcore.ClientOptions{Transport: vault},
+		DisableChallengeResourceVerification: true,
+	})
+	if err != nil {
+		t.Fatalf("newKeyVaultClient() error = %v", err)
+	}
+
+	id, err := client.SetSecret(ctx, "ws-1", "first", "application/json", map[string]string{"workspace-id": "1"})
+	if err != nil {
+		t.Fatalf("SetSecret() error = %v", err)
+	}
+	if id != testVaultURL+"/secrets/ws-1" {
+		t.Errorf("SetSecret() id = %q", id)
+	}
+	if err := client.DeleteSecret(ctx, "ws-1"); err != nil {
+		t.Fatalf("DeleteSecret() error = %v", err)
+	}
+	if _, err := client.GetSecret(ctx, "ws-1"); !IsNotFound(err) {
+		t.Fatalf("GetSecret() of a deleted secret error = %v, want not found", err)
+	}
+	if err := client.DeleteSecret(ctx, "ws-1"); err != nil {
+		t.Errorf("DeleteSecret() of a missing secret error = %v", err)
+	}
+
+	// The workspace ID is reused while the old secret is soft-deleted
+	if _, err := client.SetSecret(ctx, "ws-1", "second", "application/json", nil); err != nil {
+		t.Fatalf("SetSecret() after delete error = %v", err)
+	}
+	value, err := client.GetSecret(ctx, "ws-1")
+	if err != nil || value != "second" {
+		t.Errorf("GetSecret() = %q, %v, want the new value", value, err)
+	}
+	if len(vault.deleted) != 0 {
+		t.Errorf("deleted secrets = %v, want the old one recovered", vault.deleted)
+	}
+}
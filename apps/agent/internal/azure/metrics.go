@@ -0,0 +1,79 @@
+package azure
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	apiRequestDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "azure_api_request_duration_seconds",
+			Help:    "Duration of Azure API attempts, retries included separately",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"region", "resource", "method", "status"},
+	)
+
+	apiThrottledTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "azure_api_throttled_total",
+			Help: "Total number of Azure API attempts throttled with 429 or ServerBusy",
+		},
+		[]string{"region", "resource"},
+	)
+)
+
+// metricsPolicy runs below the SDK retry policy, so each attempt is timed and a
+// throttled attempt is counted even when its retry succeeds
+type metricsPolicy struct {
+	region string
+}
+
+func (p *metricsPolicy) Do(req *policy.Request) (*http.Response, error) {
+	resource := apiResource(req.Raw().URL)
+	start := time.Now()
+	resp, err := req.Next()
+
+	status := "error" // Transport failures have no status
+	if resp != nil {
+		status = strconv.Itoa(resp.StatusCode)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.Header.Get("x-ms-error-code") == "ServerBusy" {
+			apiThrottledTotal.WithLabelValues(p.region, resource).Inc()
+		}
+	}
+	apiRequestDuration.WithLabelValues(p.region, resource, req.Raw().Method, status).Observe(time.Since(start).Seconds())
+	return resp, err
+}
+
+// apiResource names what a request addresses without its resource names, keeping
+// metric labels bounded: the resource type of resource manager calls (e.g.
+// "Microsoft.App/containerApps") or the data-plane service
+func apiResource(u *url.URL) string {
+	path := u.Path
+	if i := strings.LastIndex(strings.ToLower(path), "/providers/"); i >= 0 {
+		parts := strings.SplitN(path[i+len("/providers/"):], "/", 3)
+		if len(parts) >= 2 && parts[1] != "" {
+			return parts[0] + "/" + parts[1]
+		}
+		return parts[0]
+	}
+
+	host := u.Hostname()
+	switch {
+	case strings.Contains(host, ".file.core."):
+		return "storage/file"
+	case strings.Contains(host, ".blob.core."):
+		return "storage/blob"
+	case strings.Contains(host, ".vault."):
+		return "keyvault"
+	}
+	return "other"
+}
@@ -0,0 +1,113 @@
+package azure
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestAPIResource(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://management.azure.com/subscriptions/sub/resourceGroups/rg/providers/Microsoft.ContainerInstance/containerGroups/aci-ws-1?api-version=2023-05-01", "Microsoft.ContainerInstance/containerGroups"},
+		{"https://management.azure.com/subscriptions/sub/resourceGroups/rg/providers/Microsoft.App/managedEnvironments/env/storages/fs-ws-1", "Microsoft.App/managedEnvironments"},
+		{"https://management.azure.com/subscriptions/sub/PROVIDERS/Microsoft.App", "Microsoft.App"},
+		{"https://account.file.core.windows.net/fs-ws-1?restype=share", "storage/file"},
+		{"https://account.blob.core.windows.net/locks/ws-1", "storage/blob"},
+		{"https://dev8.vault.azure.net/secrets/ws-1", "keyvault"},
+		{"https://management.azure.com/test", "other"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatalf("url.Parse(%q) error = %v", tt.url, err)
+		}
+		if got := apiResource(u); got != tt.want {
+			t.Errorf("apiResource(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestMetricsPolicy_CountsEveryAttempt(t *testing.T) {
+	r := testRetrier(3, 0)
+	transport := &fakeTransport{statuses: []int{http.StatusTooManyRequests, http.StatusOK}}
+
+	if _, err := send(t, r, "metricsregion", transport); err != nil {
+		t.Fatalf("send() error = %v", err)
+	}
+
+	if got := testutil.ToFloat64(apiThrottledTotal.WithLabelValues("metricsregion", "other")); got != 1 {
+		t.Errorf("throttled = %v, want 1", got)
+	}
+	for _, status := range []string{"429", "200"} {
+		var metric dto.Metric
+		observer := apiRequestDuration.WithLabelValues("metricsregion", "other", http.MethodGet, status)
+		if err := observer.(prometheus.Metric).Write(&metric); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if got := metric.GetHistogram().GetSampleCount(); got != 1 {
+			t.Errorf("attempts with status %s = %d, want 1", status, got)
+		}
+	}
+}
+
+// throttleOnce throttles the first authenticated request and passes on the rest
+type throttleOnce struct {
+	next      policy.Transporter
+	throttled bool
+}
+
+func (f *throttleOnce) Do(req *http.Request) (*http.Response, error) {
+	if f.throttled || req.Header.Get("Authorization") == "" {
+		return f.next.Do(req)
+	}
+	f.throttled = true
+	return &http.Response{StatusCode: http.StatusTooManyRequests, Status: http.StatusText(http.StatusTooManyRequests), Header: http.Header{}, Body: http.NoBody, Request: req}, nil
+}
+
+func TestMetricsPolicy_DataPlaneClients(t *testing.T) {
+	ctx := context.Background()
+	r := testRetrier(3, 0)
+
+	opts := r.ClientOptions("kvregion")
+	opts.Transport = &throttleOnce{next: newFakeVault()}
+	vault, err := newKeyVaultClient(testVaultURL, fakeCredential{}, &azsecrets.ClientOptions{
+		ClientOptions:                        opts,
+		DisableChallengeResourceVerification: true,
+	})
+	if err != nil {
+		t.Fatalf("newKeyVaultClient() error = %v", err)
+	}
+	if _, err := vault.SetSecret(ctx, "ws-1", "value", "text/plain", nil); err != nil {
+		t.Fatalf("SetSecret() after a throttled attempt error = %v", err)
+	}
+	if got := testutil.ToFloat64(apiThrottledTotal.WithLabelValues("kvregion", "keyvault")); got != 1 {
+		t.Errorf("throttled Key Vault attempts = %v, want 1", got)
+	}
+
+	opts = r.ClientOptions("blobregion")
+	opts.Transport = &fakeTransport{statuses: []int{http.StatusServiceUnavailable, http.StatusCreated}}
+	leases := newBlobLeaseClient("https://account.blob.core.windows.net/locks", fakeCredential{}, opts)
+	if err := leases.EnsureContainer(ctx); err != nil {
+		t.Fatalf("EnsureContainer() after a failed attempt error = %v", err)
+	}
+	for _, status := range []string{"503", "201"} {
+		var metric dto.Metric
+		observer := apiRequestDuration.WithLabelValues("blobregion", "storage/blob", http.MethodPut, status)
+		if err := observer.(prometheus.Metric).Write(&metric); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+		if got := metric.GetHistogram().GetSampleCount(); got != 1 {
+			t.Errorf("blob attempts with status %s = %d, want 1", status, got)
+		}
+	}
+}
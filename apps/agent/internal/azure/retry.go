@@ -0,0 +1,220 @@
+package azure
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/arm"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+)
+
+// ErrCircuitOpen is wrapped by the errors of calls rejected by an open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// retryStatusCodes are the responses retried with backoff
+var retryStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// BreakerState is the state of a region's circuit breaker
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Calls go through
+	BreakerOpen     BreakerState = "open"      // Calls are rejected until the cooldown ends
+	BreakerHalfOpen BreakerState = "half-open" // One probe call goes through; its result closes or reopens the circuit
+)
+
+// Retrier retries throttled and transient Azure calls with exponential backoff that
+// honors Retry-After, and keeps a circuit breaker per region: after a run of
+// consecutive failures the region's calls fail fast for a cooldown instead of
+// piling onto an unhealthy API. Clients get both through ClientOptions.
+type Retrier struct {
+	cfg config.AzureRetryConfig
+	now func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+type breaker struct {
+	failures  int // Consecutive failed attempts
+	openUntil time.Time
+	probing   bool // A half-open probe is in flight
+}
+
+// NewRetrier creates a retrier with the given settings
+func NewRetrier(cfg config.AzureRetryConfig) *Retrier {
+	return &Retrier{cfg: cfg, now: time.Now, breakers: make(map[string]*breaker)}
+}
+
+// ClientOptions returns SDK client options that retry through r, count every
+// attempt against region's circuit breaker and record it in the Azure API metrics.
+// A nil Retrier returns the SDK defaults.
+func (r *Retrier) ClientOptions(region string) azcore.ClientOptions {
+	if r == nil {
+		return azcore.ClientOptions{}
+	}
+	maxRetries := int32(r.cfg.MaxAttempts - 1)
+	if maxRetries <= 0 {
+		maxRetries = -1 // The SDK reads 0 as its default of 3
+	}
+	return azcore.ClientOptions{
+		Retry: policy.RetryOptions{
+			MaxRetries:    maxRetries,
+			RetryDelay:    r.cfg.BaseDelay,
+			MaxRetryDelay: r.cfg.MaxDelay, // A longer Retry-After fails the call instead of waiting
+			StatusCodes:   retryStatusCodes,
+		},
+		PerRetryPolicies: []policy.Policy{&breakerPolicy{retrier: r, region: region}, &metricsPolicy{region: region}},
+	}
+}
+
+// armOptions returns resource manager client options for region
+func (r *Retrier) armOptions(region string) *arm.ClientOptions {
+	return &arm.ClientOptions{ClientOptions: r.ClientOptions(region)}
+}
+
+// State returns the state of region's circuit breaker
+func (r *Retrier) State(region string) BreakerState {
+	if r == nil {
+		return BreakerClosed
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b, ok := r.breakers[region]
+	switch {
+	case !ok || b.openUntil.IsZero():
+		return BreakerClosed
+	case r.now().Before(b.openUntil):
+		return BreakerOpen
+	default:
+		return BreakerHalfOpen
+	}
+}
+
+// allow reports whether a call to region may go out, returning the error of a
+// rejected call
+func (r *Retrier) allow(region string) *Error {
+	if r.cfg.BreakerThreshold <= 0 {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.breakers[region]
+	if b == nil || b.openUntil.IsZero() {
+		return nil
+	}
+	now := r.now()
+	if now.Before(b.openUntil) {
+		return circuitOpenError(region, b.openUntil.Sub(now))
+	}
+	if b.probing {
+		return circuitOpenError(region, r.cfg.BreakerCooldown)
+	}
+	b.probing = true
+	return nil
+}
+
+// record counts the outcome of an attempt against region's circuit breaker
+func (r *Retrier) record(region string, failed bool) {
+	if r.cfg.BreakerThreshold <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.breakers[region]
+	if b == nil {
+		b = &breaker{}
+		r.breakers[region] = b
+	}
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.openUntil = time.Time{}
+		return
+	}
+	b.failures++
+	if b.failures >= r.cfg.BreakerThreshold || !b.openUntil.IsZero() {
+		// A failed half-open probe reopens the circuit straight away
+		b.openUntil = r.now().Add(r.cfg.BreakerCooldown)
+	}
+}
+
+// release ends a half-open probe without an outcome, letting the next call probe
+func (r *Retrier) release(region string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if b := r.breakers[region]; b != nil {
+		b.probing = false
+	}
+}
+
+func circuitOpenError(region string, wait time.Duration) *Error {
+	return &Error{
+		Kind:       ErrorTransient,
+		StatusCode: http.StatusServiceUnavailable,
+		Code:       "CircuitOpen",
+		RetryAfter: wait,
+		Err:        fmt.Errorf("azure region %s: %w after repeated failures; retry in %s", region, ErrCircuitOpen, wait.Round(time.Second)),
+	}
+}
+
+// breakerPolicy runs below the SDK retry policy, so it sees every attempt
+type breakerPolicy struct {
+	retrier *Retrier
+	region  string
+}
+
+func (p *breakerPolicy) Do(req *policy.Request) (*http.Response, error) {
+	if err := p.retrier.allow(p.region); err != nil {
+		return nil, nonRetriable{err}
+	}
+
+	resp, err := req.Next()
+	if req.Raw().Context().Err() != nil {
+		// The caller gave up, which says nothing about the region
+		p.retrier.release(p.region)
+		return resp, err
+	}
+	p.retrier.record(p.region, err != nil || isRetryStatus(resp.StatusCode))
+	return resp, err
+}
+
+func isRetryStatus(status int) bool {
+	for _, code := range retryStatusCodes {
+		if status == code {
+			return true
+		}
+	}
+	return false
+}
+
+// nonRetriable stops the SDK retry policy from retrying a rejected call
+type nonRetriable struct {
+	err *Error
+}
+
+func (n nonRetriable) Error() string {
+	return n.err.Error()
+}
+
+func (n nonRetriable) Unwrap() error {
+	return n.err
+}
+
+// NonRetriable marks the error for the SDK retry policy
+func (nonRetriable) NonRetriable() {}
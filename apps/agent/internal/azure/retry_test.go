@@ -0,0 +1,181 @@
+package azure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+)
+
+// fakeTransport answers requests with the given statuses in turn, repeating the last one
+type fakeTransport struct {
+	statuses []int
+	header   http.Header
+	calls    int
+}
+
+func (f *fakeTransport) Do(req *http.Request) (*http.Response, error) {
+	status := f.statuses[min(f.calls, len(f.statuses)-1)]
+	f.calls++
+	header := http.Header{}
+	for name, values := range f.header {
+		header[name] = values
+	}
+	return &http.Response{StatusCode: status, Status: http.StatusText(status), Header: header, Body: http.NoBody, Request: req}, nil
+}
+
+func testRetrier(maxAttempts, threshold int) *Retrier {
+	return NewRetrier(config.AzureRetryConfig{
+		MaxAttempts:      maxAttempts,
+		BaseDelay:        time.Millisecond,
+		MaxDelay:         10 * time.Millisecond,
+		BreakerThreshold: threshold,
+		BreakerCooldown:  time.Minute,
+	})
+}
+
+// send sends a GET through a pipeline built from r's options for region
+func send(t *testing.T, r *Retrier, region string, transport *fakeTransport) (*http.Response, error) {
+	t.Helper()
+	opts := r.ClientOptions(region)
+	opts.Transport = transport
+	pipeline := runtime.NewPipeline("test", "v1.0.0", runtime.PipelineOptions{}, &opts)
+
+	req, err := runtime.NewRequest(context.Background(), http.MethodGet, "https://management.azure.com/test")
+	if err != nil {
+		t.Fatalf("NewRequest() error = %v", err)
+	}
+	return pipeline.Do(req)
+}
+
+func TestRetrier_RetriesTransientFailures(t *testing.T) {
+	r := testRetrier(4, 10)
+	transport := &fakeTransport{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}}
+
+	resp, err := send(t, r, "eastus", transport)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+	if transport.calls != 3 {
+		t.Errorf("transport calls = %d, want 3", transport.calls)
+	}
+	if state := r.State("eastus"); state != BreakerClosed {
+		t.Errorf("State() = %s, want closed after a success", state)
+	}
+}
+
+func TestRetrier_StopsAfterMaxAttempts(t *testing.T) {
+	r := testRetrier(2, 10)
+	transport := &fakeTransport{statuses: []int{http.StatusInternalServerError}}
+
+	resp, err := send(t, r, "eastus", transport)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if resp.StatusCode != http.StatusInternalServerError || transport.calls != 2 {
+		t.Errorf("status = %d after %d calls, want 500 after 2", resp.StatusCode, transport.calls)
+	}
+}
+
+func TestRetrier_LongRetryAfterIsNotAwaited(t *testing.T) {
+	r := testRetrier(4, 10)
+	transport := &fakeTransport{statuses: []int{http.StatusTooManyRequests}, header: http.Header{"Retry-After": {"60"}}}
+
+	resp, err := send(t, r, "eastus", transport)
+	if err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if transport.calls != 1 {
+		t.Errorf("transport calls = %d, want 1 when Retry-After exceeds the maximum delay", transport.calls)
+	}
+	classified, ok := Classify(runtime.NewResponseError(resp))
+	if !ok || classified.Kind != ErrorThrottled || classified.RetryAfter != time.Minute {
+		t.Errorf("Classify() = %+v, %v; want throttled with a 1m RetryAfter", classified, ok)
+	}
+}
+
+func TestRetrier_CircuitBreaker(t *testing.T) {
+	r := testRetrier(1, 3)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	failing := &fakeTransport{statuses: []int{http.StatusServiceUnavailable}}
+	for i := 0; i < 3; i++ {
+		if _, err := send(t, r, "eastus", failing); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+	}
+	if state := r.State("eastus"); state != BreakerOpen {
+		t.Fatalf("State() = %s, want open after 3 failures", state)
+	}
+
+	// Open: calls fail fast without reaching Azure
+	_, err := send(t, r, "eastus", failing)
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Do() error = %v, want ErrCircuitOpen", err)
+	}
+	if failing.calls != 3 {
+		t.Errorf("transport calls = %d, want 3: an open circuit must not send requests", failing.calls)
+	}
+	classified, ok := Classify(err)
+	if !ok || classified.Kind != ErrorTransient || classified.RetryAfter != time.Minute {
+		t.Errorf("Classify() = %+v, %v; want transient with the cooldown as RetryAfter", classified, ok)
+	}
+
+	// Other regions are unaffected
+	healthy := &fakeTransport{statuses: []int{http.StatusOK}}
+	if _, err := send(t, r, "westeurope", healthy); err != nil {
+		t.Errorf("Do() in another region error = %v", err)
+	}
+
+	// Half-open: a failed probe reopens the circuit
+	now = now.Add(time.Minute)
+	if state := r.State("eastus"); state != BreakerHalfOpen {
+		t.Fatalf("State() = %s, want half-open after the cooldown", state)
+	}
+	if _, err := send(t, r, "eastus", failing); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if state := r.State("eastus"); state != BreakerOpen {
+		t.Fatalf("State() = %s, want open after a failed probe", state)
+	}
+
+	// Half-open: a successful probe closes it
+	now = now.Add(time.Minute)
+	if _, err := send(t, r, "eastus", healthy); err != nil {
+		t.Fatalf("Do() error = %v", err)
+	}
+	if state := r.State("eastus"); state != BreakerClosed {
+		t.Errorf("State() = %s, want closed after a successful probe", state)
+	}
+}
+
+func TestRetrier_BreakerDisabled(t *testing.T) {
+	r := testRetrier(1, 0)
+	failing := &fakeTransport{statuses: []int{http.StatusServiceUnavailable}}
+	for i := 0; i < 5; i++ {
+		if _, err := send(t, r, "eastus", failing); err != nil {
+			t.Fatalf("Do() error = %v", err)
+		}
+	}
+	if state := r.State("eastus"); state != BreakerClosed {
+		t.Errorf("State() = %s, want closed with the breaker disabled", state)
+	}
+}
+
+func TestRetrier_Nil(t *testing.T) {
+	var r *Retrier
+	if state := r.State("eastus"); state != BreakerClosed {
+		t.Errorf("State() = %s, want closed", state)
+	}
+	if opts := r.ClientOptions("eastus"); len(opts.PerRetryPolicies) != 0 {
+		t.Error("ClientOptions() of a nil Retrier should be the SDK defaults")
+	}
+}
@@ -6,8 +6,12 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/directory"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/file"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/fileerror"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/service"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azfile/share"
 )
@@ -19,8 +23,9 @@ type StorageClient struct {
 	accountKey    string
 }
 
-// NewStorageClient creates a new Azure Files storage client
-func NewStorageClient(accountName, accountKey string) (*StorageClient, error) {
+// NewStorageClient creates a new Azure Files storage client whose calls retry through
+// retrier and count against region's circuit breaker. retrier may be nil.
+func NewStorageClient(accountName, accountKey string, retrier *Retrier, region string) (*StorageClient, error) {
 	// Create service client using account name and key
 	serviceURL := fmt.Sprintf("https://%s.file.core.windows.net/", accountName)
 
@@ -31,7 +36,9 @@ func NewStorageClient(accountName, accountKey string) (*StorageClient, error) {
 	}
 
 	// Create service client
-	client, err := service.NewClientWithSharedKeyCredential(serviceURL, credential, nil)
+	client, err := service.NewClientWithSharedKeyCredential(serviceURL, credential, &service.ClientOptions{
+		ClientOptions: retrier.ClientOptions(region),
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create service client: %w", err)
 	}
@@ -57,11 +64,12 @@ func (s *StorageClient) CreateFileShare(ctx context.Context, shareName string, q
 	return nil
 }
 
-// DeleteFileShare deletes an Azure File share
+// DeleteFileShare deletes an Azure File share together with its snapshots
 func (s *StorageClient) DeleteFileShare(ctx context.Context, shareName string) error {
 	shareClient := s.serviceClient.NewShareClient(shareName)
 
-	_, err := shareClient.Delete(ctx, nil)
+	include := share.DeleteSnapshotsOptionTypeInclude
+	_, err := shareClient.Delete(ctx, &share.DeleteOptions{DeleteSnapshots: &include})
 	if err != nil {
 		return fmt.Errorf("failed to delete file share: %w", err)
 	}
@@ -69,6 +77,43 @@ func (s *StorageClient) DeleteFileShare(ctx context.Context, shareName string) e
 	return nil
 }
 
+// FileShareInfo describes a listed file share
+type FileShareInfo struct {
+	Name         string
+	QuotaGB      int32
+	LastModified time.Time
+}
+
+// ListFileShares lists file shares whose names start with prefix
+func (s *StorageClient) ListFileShares(ctx context.Context, prefix string) ([]FileShareInfo, error) {
+	var shares []FileShareInfo
+
+	pager := s.serviceClient.NewListSharesPager(&service.ListSharesOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list file shares: %w", err)
+		}
+		for _, item := range page.Shares {
+			if item == nil || item.Name == nil {
+				continue
+			}
+			info := FileShareInfo{Name: *item.Name}
+			if item.Properties != nil {
+				if item.Properties.Quota != nil {
+					info.QuotaGB = *item.Properties.Quota
+				}
+				if item.Properties.LastModified != nil {
+					info.LastModified = *item.Properties.LastModified
+				}
+			}
+			shares = append(shares, info)
+		}
+	}
+
+	return shares, nil
+}
+
 // FileShareExists checks if a file share exists
 func (s *StorageClient) FileShareExists(ctx context.Context, shareName string) (bool, error) {
 	shareClient := s.serviceClient.NewShareClient(shareName)
@@ -85,8 +130,8 @@ func (s *StorageClient) FileShareExists(ctx context.Context, shareName string) (
 	return true, nil
 }
 
-// GetFileShareProperties gets the properties of a file share
-func (s *StorageClient) GetFileShareProperties(ctx context.Context, shareName string) (map[string]interface{}, error) {
+// GetFileShareProperties gets the quota and last modification time of a file share
+func (s *StorageClient) GetFileShareProperties(ctx context.Context, shareName string) (*FileShareInfo, error) {
 	shareClient := s.serviceClient.NewShareClient(shareName)
 
 	resp, err := shareClient.GetProperties(ctx, nil)
@@ -94,12 +139,328 @@ func (s *StorageClient) GetFileShareProperties(ctx context.Context, shareName st
 		return nil, fmt.Errorf("failed to get file share properties: %w", err)
 	}
 
-	properties := map[string]interface{}{
-		"quota":        resp.Quota,
-		"lastModified": resp.LastModified,
+	info := &FileShareInfo{Name: shareName}
+	if resp.Quota != nil {
+		info.QuotaGB = *resp.Quota
+	}
+	if resp.LastModified != nil {
+		info.LastModified = *resp.LastModified
+	}
+	return info, nil
+}
+
+// SetFileShareQuota changes the quota of a file share. Shrinking below the stored data fails.
+func (s *StorageClient) SetFileShareQuota(ctx context.Context, shareName string, quotaGB int32) error {
+	shareClient := s.serviceClient.NewShareClient(shareName)
+
+	_, err := shareClient.SetProperties(ctx, &share.SetPropertiesOptions{
+		Quota: &quotaGB,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set file share quota: %w", err)
+	}
+
+	return nil
+}
+
+// GetFileShareUsage returns the bytes stored in a file share.
+// Azure refreshes share statistics periodically, so recent writes may not be counted yet.
+func (s *StorageClient) GetFileShareUsage(ctx context.Context, shareName string) (int64, error) {
+	shareClient := s.serviceClient.NewShareClient(shareName)
+
+	resp, err := shareClient.GetStatistics(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get file share usage: %w", err)
+	}
+	if resp.ShareUsageBytes == nil {
+		return 0, nil
+	}
+
+	return *resp.ShareUsageBytes, nil
+}
+
+// ShareSnapshotInfo describes a read-only point-in-time snapshot of a file share
+type ShareSnapshotInfo struct {
+	Snapshot  string // Opaque snapshot timestamp that identifies it, e.g. "2024-05-01T10:00:00.0000000Z"
+	CreatedAt time.Time
+	Metadata  map[string]string
+}
+
+// CreateShareSnapshot takes a snapshot of a file share
+func (s *StorageClient) CreateShareSnapshot(ctx context.Context, shareName string, metadata map[string]string) (*ShareSnapshotInfo, error) {
+	shareClient := s.serviceClient.NewShareClient(shareName)
+
+	meta := make(map[string]*string, len(metadata))
+	for key, value := range metadata {
+		meta[key] = &value
+	}
+
+	resp, err := shareClient.CreateSnapshot(ctx, &share.CreateSnapshotOptions{Metadata: meta})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share snapshot: %w", err)
+	}
+	if resp.Snapshot == nil {
+		return nil, fmt.Errorf("failed to create share snapshot: no snapshot returned")
+	}
+
+	return &ShareSnapshotInfo{
+		Snapshot:  *resp.Snapshot,
+		CreatedAt: snapshotTime(*resp.Snapshot),
+		Metadata:  metadata,
+	}, nil
+}
+
+// ListShareSnapshots lists the snapshots of a file share, oldest first
+func (s *StorageClient) ListShareSnapshots(ctx context.Context, shareName string) ([]ShareSnapshotInfo, error) {
+	var snapshots []ShareSnapshotInfo
+
+	pager := s.serviceClient.NewListSharesPager(&service.ListSharesOptions{
+		Prefix:  &shareName,
+		Include: service.ListSharesInclude{Snapshots: true, Metadata: true},
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list share snapshots: %w", err)
+		}
+		for _, item := range page.Shares {
+			// The prefix also matches longer share names and the base share itself
+			if item == nil || item.Name == nil || *item.Name != shareName || item.Snapshot == nil {
+				continue
+			}
+			info := ShareSnapshotInfo{
+				Snapshot:  *item.Snapshot,
+				CreatedAt: snapshotTime(*item.Snapshot),
+				Metadata:  make(map[string]string, len(item.Metadata)),
+			}
+			for key, value := range item.Metadata {
+				if value != nil {
+					info.Metadata[key] = *value
+				}
+			}
+			snapshots = append(snapshots, info)
+		}
+	}
+
+	return snapshots, nil
+}
+
+// DeleteShareSnapshot deletes one snapshot of a file share
+func (s *StorageClient) DeleteShareSnapshot(ctx context.Context, shareName, snapshot string) error {
+	shareClient := s.serviceClient.NewShareClient(shareName)
+
+	_, err := shareClient.Delete(ctx, &share.DeleteOptions{ShareSnapshot: &snapshot})
+	if err != nil {
+		return fmt.Errorf("failed to delete share snapshot: %w", err)
+	}
+
+	return nil
+}
+
+// CopyShareOptions controls CopyShare
+type CopyShareOptions struct {
+	// SourceSnapshot copies from a snapshot of the source share instead of the live share
+	SourceSnapshot string
+	// Mirror deletes destination files and directories that are not in the source
+	Mirror bool
+	// Progress is called after each directory with the number of files copied so far
+	Progress func(filesCopied int)
+}
+
+// copyPollInterval is how often CopyShare checks on pending server-side copies
+const copyPollInterval = 500 * time.Millisecond
+
+// CopyShare copies the files of srcShare into the existing dstShare, one directory at a time.
+// Files are copied server-side, so no data passes through the agent.
+func (s *StorageClient) CopyShare(ctx context.Context, srcShare, dstShare string, opts CopyShareOptions) error {
+	srcClient := s.serviceClient.NewShareClient(srcShare)
+	if opts.SourceSnapshot != "" {
+		// Clients derived from a snapshot client carry ?sharesnapshot= in their URLs,
+		// which makes both the listings and the copy sources read the snapshot
+		var err error
+		if srcClient, err = srcClient.WithSnapshot(opts.SourceSnapshot); err != nil {
+			return fmt.Errorf("failed to open share snapshot: %w", err)
+		}
+	}
+	dstClient := s.serviceClient.NewShareClient(dstShare)
+
+	type directoryPair struct {
+		src, dst *directory.Client
+	}
+	queue := []directoryPair{{src: srcClient.NewRootDirectoryClient(), dst: dstClient.NewRootDirectoryClient()}}
+	root := true
+	copied := 0
+
+	for len(queue) > 0 {
+		pair := queue[0]
+		queue = queue[1:]
+
+		if !root {
+			if _, err := pair.dst.Create(ctx, nil); err != nil && !fileerror.HasCode(err, fileerror.ResourceAlreadyExists) {
+				return fmt.Errorf("failed to create directory %s: %w", pair.dst.URL(), err)
+			}
+		}
+		root = false
+
+		subdirs, files, err := listDirectory(ctx, pair.src)
+		if err != nil {
+			return err
+		}
+
+		if opts.Mirror {
+			if err := removeExtraneous(ctx, pair.dst, subdirs, files); err != nil {
+				return err
+			}
+		}
+
+		var pending []*file.Client
+		for _, name := range files {
+			dstFile := pair.dst.NewFileClient(name)
+			resp, err := dstFile.StartCopyFromURL(ctx, pair.src.NewFileClient(name).URL(), nil)
+			if err != nil {
+				return fmt.Errorf("failed to copy file %s: %w", name, err)
+			}
+			if resp.CopyStatus != nil && *resp.CopyStatus == file.CopyStatusTypePending {
+				pending = append(pending, dstFile)
+			}
+		}
+		if err := waitForCopies(ctx, pending); err != nil {
+			return err
+		}
+
+		copied += len(files)
+		if opts.Progress != nil {
+			opts.Progress(copied)
+		}
+
+		for _, name := range subdirs {
+			queue = append(queue, directoryPair{src: pair.src.NewSubdirectoryClient(name), dst: pair.dst.NewSubdirectoryClient(name)})
+		}
+	}
+
+	return nil
+}
+
+// listDirectory returns the names of the subdirectories and files directly inside dir
+func listDirectory(ctx context.Context, dir *directory.Client) (subdirs, files []string, err error) {
+	pager := dir.NewListFilesAndDirectoriesPager(nil)
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list directory %s: %w", dir.URL(), err)
+		}
+		if page.Segment == nil {
+			continue
+		}
+		for _, item := range page.Segment.Directories {
+			if item != nil && item.Name != nil {
+				subdirs = append(subdirs, *item.Name)
+			}
+		}
+		for _, item := range page.Segment.Files {
+			if item != nil && item.Name != nil {
+				files = append(files, *item.Name)
+			}
+		}
+	}
+	return subdirs, files, nil
+}
+
+// removeExtraneous deletes the entries of dir that are not listed in keepDirs or keepFiles
+func removeExtraneous(ctx context.Context, dir *directory.Client, keepDirs, keepFiles []string) error {
+	subdirs, files, err := listDirectory(ctx, dir)
+	if isNotFoundError(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	keep := make(map[string]bool, len(keepDirs)+len(keepFiles))
+	for _, name := range keepDirs {
+		keep["d/"+name] = true
+	}
+	for _, name := range keepFiles {
+		keep["f/"+name] = true
+	}
+
+	for _, name := range files {
+		if keep["f/"+name] {
+			continue
+		}
+		if _, err := dir.NewFileClient(name).Delete(ctx, nil); err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("failed to delete file %s: %w", name, err)
+		}
+	}
+	for _, name := range subdirs {
+		if keep["d/"+name] {
+			continue
+		}
+		if err := deleteDirectoryTree(ctx, dir.NewSubdirectoryClient(name)); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// deleteDirectoryTree deletes a directory and everything in it; Azure only deletes empty directories
+func deleteDirectoryTree(ctx context.Context, dir *directory.Client) error {
+	if err := removeExtraneous(ctx, dir, nil, nil); err != nil {
+		return err
+	}
+	if _, err := dir.Delete(ctx, nil); err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("failed to delete directory %s: %w", dir.URL(), err)
+	}
+	return nil
+}
+
+// waitForCopies waits until every pending server-side copy has finished
+func waitForCopies(ctx context.Context, pending []*file.Client) error {
+	for len(pending) > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(copyPollInterval):
+		}
+
+		remaining := pending[:0]
+		for _, f := range pending {
+			props, err := f.GetProperties(ctx, nil)
+			if err != nil {
+				return fmt.Errorf("failed to check copy of %s: %w", f.URL(), err)
+			}
+			if props.CopyStatus == nil {
+				continue
+			}
+			switch *props.CopyStatus {
+			case file.CopyStatusTypePending:
+				remaining = append(remaining, f)
+			case file.CopyStatusTypeSuccess:
+			default:
+				description := ""
+				if props.CopyStatusDescription != nil {
+					description = *props.CopyStatusDescription
+				}
+				return fmt.Errorf("copy of %s %s: %s", f.URL(), *props.CopyStatus, description)
+			}
+		}
+		pending = remaining
+	}
+	return nil
+}
+
+// snapshotTime parses a share snapshot timestamp, returning the zero time if it is malformed
+func snapshotTime(snapshot string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, snapshot)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
 
-	return properties, nil
+// IsNotFound reports whether err means the requested Azure resource does not exist
+func IsNotFound(err error) bool {
+	return isNotFoundError(err)
 }
 
 // isNotFoundError checks if the error is a "not found" error
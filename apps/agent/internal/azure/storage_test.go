@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 )
@@ -32,7 +33,7 @@ func TestNewStorageClient(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client, err := NewStorageClient(tt.accountName, tt.accountKey)
+			client, err := NewStorageClient(tt.accountName, tt.accountKey, nil, "")
 			if (err != nil) != tt.wantErr {
 				t.Errorf("NewStorageClient() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -125,3 +126,20 @@ func TestStorageClientMethods(t *testing.T) {
 		_, _ = client.GetFileShareProperties(ctx, "test-share")
 	})
 }
+
+func TestSnapshotTime(t *testing.T) {
+	tests := []struct {
+		snapshot string
+		want     time.Time
+	}{
+		{snapshot: "2024-05-01T10:00:00.1234567Z", want: time.Date(2024, 5, 1, 10, 0, 0, 123456700, time.UTC)},
+		{snapshot: "2024-05-01T10:00:00Z", want: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{snapshot: "not-a-time", want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		if got := snapshotTime(tt.snapshot); !got.Equal(tt.want) {
+			t.Errorf("snapshotTime(%q) = %v, want %v", tt.snapshot, got, tt.want)
+		}
+	}
+}
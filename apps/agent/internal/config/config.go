@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -18,9 +21,34 @@ type Config struct {
 	// Database Configuration
 	DatabaseURL string // Optional - not used by Agent, kept for future
 
+	// Agent state (environment registry)
+	StateStore string // "bolt" (embedded on-disk database) or "memory"
+	StateDir   string // Directory holding the embedded database
+
 	// Azure Configuration
 	Azure AzureConfig
 
+	// AWS backend for workspaces created with cloudProvider "AWS"
+	AWS AWSConfig
+
+	// Local Docker Engine (AZURE_DEPLOYMENT_MODE=docker)
+	Docker DockerConfig
+
+	// Kubernetes cluster (AZURE_DEPLOYMENT_MODE=kubernetes)
+	Kubernetes KubernetesConfig
+
+	// Workspace volume storage
+	Volumes VolumeConfig
+
+	// Per-workspace secrets kept between starts
+	Secrets SecretStoreConfig
+
+	// Per-workspace operation locks
+	Locks LockConfig
+
+	// Plans limiting what each user or org may create and run
+	Policy PolicyConfig
+
 	// Container Image Configuration
 	ContainerImage     string
 	ContainerImageName string // Image name without registry (e.g., "dev8-workspace:latest")
@@ -29,6 +57,9 @@ type Config struct {
 	RegistryPassword   string
 	AgentBaseURL       string
 
+	// Workspace images selectable through baseImage
+	Images ImageCatalogConfig
+
 	// CORS Configuration
 	CORSAllowedOrigins []string
 
@@ -45,6 +76,241 @@ type Config struct {
 
 	// Timeouts
 	RequestTimeout time.Duration
+
+	// Asynchronous operations
+	OperationTimeout   time.Duration // Upper bound for a single create/start/stop/delete operation
+	OperationRetention time.Duration // How long finished operations remain queryable
+
+	// Responses kept for replay to requests retried with the same Idempotency-Key (0 disables)
+	IdempotencyTTL time.Duration
+
+	// Orphaned resource reconciler
+	Reconciler ReconcilerConfig
+
+	// Idle auto-stop
+	Idle IdleConfig
+
+	// Scheduled workspace starts and stops
+	Schedules ScheduleConfig
+
+	// Usage pricing and monthly budgets
+	Usage UsageConfig
+
+	// Workspace volume snapshots
+	Snapshots SnapshotConfig
+
+	// Lifecycle webhooks to the control plane
+	Webhooks WebhookConfig
+}
+
+// WebhookConfig controls delivery of lifecycle events to the control plane
+type WebhookConfig struct {
+	Endpoints   []WebhookEndpoint
+	MaxAttempts int           // Deliveries are dropped after this many failed attempts
+	Timeout     time.Duration // Per-attempt HTTP timeout
+}
+
+// WebhookEndpoint is a webhook receiver and the secret used to sign its payloads
+type WebhookEndpoint struct {
+	URL    string
+	Secret string
+}
+
+// DockerConfig holds the local Docker Engine settings used by the docker deployment mode
+type DockerConfig struct {
+	Host        string // Engine API address, e.g. unix:///var/run/docker.sock
+	PublishHost string // Hostname put in connection URLs for published ports
+}
+
+// KubernetesConfig holds the cluster settings used by the kubernetes deployment mode
+type KubernetesConfig struct {
+	Kubeconfig   string // Path to a kubeconfig file; empty uses the in-cluster service account
+	Context      string // Kubeconfig context; empty uses the current context
+	Namespace    string // Namespace holding every workspace
+	StorageClass string // Storage class for workspace PVCs; empty uses the cluster default
+	ServiceType  string // "ClusterIP" or "LoadBalancer"
+}
+
+// VolumeConfig selects where the per-workspace fs-{id} volumes live
+type VolumeConfig struct {
+	Backend  string // "azure" (Azure Files), "docker" (named volumes), "kubernetes" (PVCs) or "local" (host directories); empty picks the deployment mode's default
+	LocalDir string // Root directory of the local backend
+}
+
+// SecretStoreConfig selects where the secrets of each workspace are kept between starts
+type SecretStoreConfig struct {
+	Backend     string // "keyvault" (Azure Key Vault), "file" (encrypted local file) or "none"; empty picks keyvault when a vault is set, else file
+	KeyVaultURL string // Vault of the keyvault backend, e.g. https://dev8-secrets.vault.azure.net
+	FilePath    string // Secrets file of the file backend
+	FileKey     string // Base64 AES-256 key of the file backend; empty keeps a generated key next to the file
+}
+
+// LockConfig selects where the locks serializing each workspace's operations are held
+type LockConfig struct {
+	Backend        string        // "local" (in-process) or "blob" (Azure Blob leases, shared by every agent replica)
+	StorageAccount string        // Storage account of the blob backend; empty uses AZURE_STORAGE_ACCOUNT
+	Container      string        // Blob container holding one lock blob per workspace
+	LeaseDuration  time.Duration // Blob lease length, renewed while an operation runs
+}
+
+// PolicyConfig holds the tiers that workspace creates and starts are checked against.
+// Without tiers every request Validate accepts is allowed.
+type PolicyConfig struct {
+	File        string                // JSON file the tiers were loaded from
+	DefaultTier string                // Tier of requests and workspaces without one
+	Tiers       map[string]TierPolicy // Keyed by tier name
+}
+
+// TierPolicy is the limits of one plan. Zero limits and empty lists are unlimited.
+type TierPolicy struct {
+	Scope        string   `json:"scope"`        // "user" (default) counts a user's workspaces, "org" their org's
+	MaxRunning   int      `json:"maxRunning"`   // Concurrently running workspaces
+	MaxCPUCores  int      `json:"maxCpuCores"`  // Total vCPU of running workspaces
+	MaxMemoryGB  int      `json:"maxMemoryGB"`  // Total memory of running workspaces
+	MaxStorageGB int      `json:"maxStorageGB"` // Total storage of all workspaces, running or stopped
+	Regions      []string `json:"regions"`      // Allowed regions
+	Images       []string `json:"images"`       // Allowed catalogue images
+}
+
+// ImageCatalogConfig maps the logical image names requested as baseImage to images
+type ImageCatalogConfig struct {
+	Images           []ImageConfig
+	Default          string            // Image used by requests without a baseImage
+	RegionRegistries map[string]string // Per-region replacement of the default registry, e.g. a regional ACR or ECR
+}
+
+// ImageConfig is an entry of the image catalogue
+type ImageConfig struct {
+	Name       string // Logical name, e.g. "python"
+	Registry   string // Empty uses the default registry (the region's override, the ACR or REGISTRY_SERVER)
+	Repository string
+	Tag        string
+	Digest     string // Pins the image; takes precedence over Tag
+}
+
+// Reference returns the pullable image reference, prefixed with registry when the
+// image has no registry of its own
+func (i ImageConfig) Reference(registry string) string {
+	if i.Registry != "" {
+		registry = i.Registry
+	}
+	ref := i.Repository
+	if registry != "" {
+		ref = registry + "/" + ref
+	}
+	if i.Digest != "" {
+		return ref + "@" + i.Digest
+	}
+	if i.Tag != "" {
+		return ref + ":" + i.Tag
+	}
+	return ref + ":latest"
+}
+
+// ReconcilerConfig controls the background sweep for orphaned Azure resources
+type ReconcilerConfig struct {
+	Enabled     bool          // Run the sweep in the background
+	Interval    time.Duration // Time between background sweeps
+	Policy      string        // "report" (log and expose only) or "delete" (garbage-collect)
+	DryRun      bool          // With policy "delete", report what would be deleted without deleting
+	GracePeriod time.Duration // Resources younger than this are never considered orphaned
+}
+
+// IdleConfig controls automatic stopping of idle workspaces
+type IdleConfig struct {
+	Enabled        bool                     // Run the idle scanner
+	DefaultTimeout time.Duration            // Idle time before a workspace is stopped (0 disables)
+	WarningPeriod  time.Duration            // How long before stopping to warn (0 disables the warning stage)
+	ScanInterval   time.Duration            // Time between idle scans
+	TierTimeouts   map[string]time.Duration // Per user tier overrides (0 never stops)
+}
+
+// ScheduleConfig controls the scheduler running workspace start and stop schedules
+type ScheduleConfig struct {
+	Enabled       bool          // Run the scheduler
+	CheckInterval time.Duration // Time between checks for due runs
+	CatchUpWindow time.Duration // Runs missed by longer than this, e.g. while the agent was down, are skipped
+}
+
+// UsageConfig holds the rate card metered usage is priced with and the monthly
+// budgets of users. Budgets are in the rate card's currency; zero means none.
+type UsageConfig struct {
+	RateCardFile   string                  // JSON file the rate card was loaded from
+	Currency       string                  // Currency of rates and budgets
+	Rates          []UsageRate             // Matched by UsageRate.Matches
+	SoftBudget     float64                 // Monthly cost at which a user's running workspaces are warned
+	HardBudget     float64                 // Monthly cost at which they are stopped and starts are refused
+	TierBudgets    map[string]BudgetConfig // Per user tier overrides
+	BudgetInterval time.Duration           // Time between budget checks
+}
+
+// UsageRate prices usage in one region and deployment mode. "*" matches any.
+type UsageRate struct {
+	Region         string  `json:"region"`
+	Mode           string  `json:"mode"`           // "aci", "aca", "docker", "kubernetes" or "ecs"
+	VCPUHour       float64 `json:"vcpuHour"`       // Per vCPU and hour running
+	MemoryGBHour   float64 `json:"memoryGBHour"`   // Per GB of memory and hour running
+	StorageGBMonth float64 `json:"storageGBMonth"` // Per GB of provisioned share and 30 days
+}
+
+// BudgetConfig is a soft and hard monthly budget. Zero means none.
+type BudgetConfig struct {
+	Soft float64
+	Hard float64
+}
+
+// RateFor returns the rate of region and mode. An exact match wins over a match on
+// the region, which wins over a match on the mode, which wins over "*" for both.
+func (u UsageConfig) RateFor(region, mode string) (UsageRate, bool) {
+	best, bestScore := UsageRate{}, -1
+	for _, rate := range u.Rates {
+		score := 0
+		switch rate.Region {
+		case region:
+			score += 2
+		case "*":
+		default:
+			continue
+		}
+		switch rate.Mode {
+		case mode:
+			score++
+		case "*":
+		default:
+			continue
+		}
+		if score > bestScore {
+			best, bestScore = rate, score
+		}
+	}
+	return best, bestScore >= 0
+}
+
+// BudgetFor returns the monthly budget of a user tier
+func (u UsageConfig) BudgetFor(tier string) BudgetConfig {
+	if budget, ok := u.TierBudgets[tier]; ok && tier != "" {
+		return budget
+	}
+	return BudgetConfig{Soft: u.SoftBudget, Hard: u.HardBudget}
+}
+
+// HasBudgets reports whether any user has a budget
+func (u UsageConfig) HasBudgets() bool {
+	if u.SoftBudget > 0 || u.HardBudget > 0 {
+		return true
+	}
+	for _, budget := range u.TierBudgets {
+		if budget.Soft > 0 || budget.Hard > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// SnapshotConfig holds the default snapshot retention of workspaces without their own policy
+type SnapshotConfig struct {
+	MaxCount   int // Snapshots kept per workspace (0 keeps any number)
+	MaxAgeDays int // Snapshots older than this are deleted (0 keeps them forever)
 }
 
 // AzureConfig holds Azure-specific configuration
@@ -55,7 +321,7 @@ type AzureConfig struct {
 	StorageAccountKey  string
 	ContainerRegistry  string
 
-	// Deployment mode: "aci" or "aca"
+	// Deployment mode: "aci", "aca", "docker" (local Docker Engine) or "kubernetes" (any cluster); the last two need no Azure
 	DeploymentMode string
 
 	// Azure Container Apps configuration
@@ -64,6 +330,18 @@ type AzureConfig struct {
 	// Multi-region support
 	Regions       []RegionConfig
 	DefaultRegion string
+
+	// Retries of Azure API calls and the per-region circuit breaker
+	Retry AzureRetryConfig
+}
+
+// AzureRetryConfig controls how throttled and transient Azure API failures are retried
+type AzureRetryConfig struct {
+	MaxAttempts      int           // Attempts per call, including the first
+	BaseDelay        time.Duration // Backoff before the first retry, doubled on each retry
+	MaxDelay         time.Duration // Longest wait before a retry; a longer Retry-After fails the call
+	BreakerThreshold int           // Consecutive failed attempts that open a region's circuit (0 disables the breaker)
+	BreakerCooldown  time.Duration // How long an open circuit fails calls before letting a probe through
 }
 
 // RegionConfig holds region-specific configuration
@@ -75,12 +353,34 @@ type RegionConfig struct {
 	StorageAccount    string
 }
 
+// AWSConfig holds the AWS backend: ECS Fargate tasks on an EFS file system per region
+type AWSConfig struct {
+	Endpoint         string // Overrides every AWS service endpoint, e.g. http://localhost:4566 for LocalStack
+	ExecutionRoleARN string // ECS task execution role, needed to pull from ECR
+	TaskRoleARN      string // Role assumed by the workspace container (optional)
+	AssignPublicIP   bool   // Give tasks a public IP, used in connection URLs
+
+	Regions []AWSRegionConfig
+}
+
+// AWSRegionConfig holds the ECS cluster, network and EFS file system of an AWS region
+type AWSRegionConfig struct {
+	Name           string
+	Enabled        bool
+	Cluster        string
+	FileSystemID   string
+	Subnets        []string
+	SecurityGroups []string
+}
+
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	config := &Config{
 		Port:        getEnv("AGENT_PORT", "8080"),
 		Host:        getEnv("AGENT_HOST", "0.0.0.0"),
 		DatabaseURL: getEnv("DATABASE_URL", ""), // Optional, no error if empty
+		StateStore:  getEnv("STATE_STORE", "bolt"),
+		StateDir:    getEnv("STATE_DIR", "./data"),
 		Environment: getEnv("ENVIRONMENT", "development"),
 		LogLevel:    getEnv("LOG_LEVEL", "info"),
 
@@ -98,6 +398,121 @@ func Load() (*Config, error) {
 
 		// Timeouts
 		RequestTimeout: time.Duration(getEnvInt("REQUEST_TIMEOUT_SECONDS", 300)) * time.Second,
+
+		// Asynchronous operations
+		OperationTimeout:   time.Duration(getEnvInt("OPERATION_TIMEOUT_SECONDS", 900)) * time.Second,
+		OperationRetention: time.Duration(getEnvInt("OPERATION_RETENTION_MINUTES", 60)) * time.Minute,
+		IdempotencyTTL:     time.Duration(getEnvInt("IDEMPOTENCY_TTL_MINUTES", 1440)) * time.Minute,
+
+		// Orphaned resource reconciler
+		Reconciler: ReconcilerConfig{
+			Enabled:     getEnvBool("RECONCILER_ENABLED", false),
+			Interval:    time.Duration(getEnvInt("RECONCILER_INTERVAL_MINUTES", 60)) * time.Minute,
+			Policy:      getEnv("RECONCILER_POLICY", "report"),
+			DryRun:      getEnvBool("RECONCILER_DRY_RUN", true),
+			GracePeriod: time.Duration(getEnvInt("RECONCILER_GRACE_PERIOD_MINUTES", 60)) * time.Minute,
+		},
+
+		// Idle auto-stop
+		Idle: IdleConfig{
+			Enabled:        getEnvBool("IDLE_STOP_ENABLED", false),
+			DefaultTimeout: time.Duration(getEnvInt("IDLE_TIMEOUT_MINUTES", 60)) * time.Minute,
+			WarningPeriod:  time.Duration(getEnvInt("IDLE_WARNING_MINUTES", 0)) * time.Minute,
+			ScanInterval:   time.Duration(getEnvInt("IDLE_SCAN_INTERVAL_SECONDS", 60)) * time.Second,
+			TierTimeouts:   loadIdleTierTimeouts(),
+		},
+
+		// Scheduled starts and stops
+		Schedules: ScheduleConfig{
+			Enabled:       getEnvBool("SCHEDULER_ENABLED", true),
+			CheckInterval: time.Duration(getEnvInt("SCHEDULER_INTERVAL_SECONDS", 30)) * time.Second,
+			CatchUpWindow: time.Duration(getEnvInt("SCHEDULER_CATCH_UP_MINUTES", 720)) * time.Minute,
+		},
+
+		// Usage budgets; the rate card is loaded below
+		Usage: UsageConfig{
+			SoftBudget:     getEnvFloat("USAGE_MONTHLY_SOFT_BUDGET", 0),
+			HardBudget:     getEnvFloat("USAGE_MONTHLY_HARD_BUDGET", 0),
+			TierBudgets:    loadTierBudgets(),
+			BudgetInterval: time.Duration(getEnvInt("USAGE_BUDGET_INTERVAL_SECONDS", 300)) * time.Second,
+		},
+
+		// Workspace volume snapshots
+		Snapshots: SnapshotConfig{
+			MaxCount:   getEnvInt("SNAPSHOT_MAX_COUNT", 10),
+			MaxAgeDays: getEnvInt("SNAPSHOT_MAX_AGE_DAYS", 30),
+		},
+
+		// Local Docker Engine
+		Docker: DockerConfig{
+			Host:        getEnv("DOCKER_HOST", "unix:///var/run/docker.sock"),
+			PublishHost: getEnv("DOCKER_PUBLISH_HOST", "localhost"),
+		},
+
+		// Kubernetes cluster
+		Kubernetes: KubernetesConfig{
+			Kubeconfig:   getEnv("K8S_KUBECONFIG", os.Getenv("KUBECONFIG")),
+			Context:      getEnv("K8S_CONTEXT", ""),
+			Namespace:    getEnv("K8S_NAMESPACE", "dev8-workspaces"),
+			StorageClass: getEnv("K8S_STORAGE_CLASS", ""),
+			ServiceType:  getEnv("K8S_SERVICE_TYPE", "ClusterIP"),
+		},
+
+		// Workspace volumes
+		Volumes: VolumeConfig{
+			Backend:  getEnv("VOLUME_BACKEND", ""),
+			LocalDir: getEnv("VOLUME_LOCAL_DIR", ""),
+		},
+
+		// Workspace secrets
+		Secrets: SecretStoreConfig{
+			Backend:     getEnv("SECRET_STORE", ""),
+			KeyVaultURL: getEnv("AZURE_KEY_VAULT_URL", ""),
+			FilePath:    getEnv("SECRET_STORE_FILE", ""),
+			FileKey:     getEnv("SECRET_STORE_KEY", ""),
+		},
+
+		// Workspace operation locks
+		Locks: LockConfig{
+			Backend:        getEnv("LOCK_BACKEND", "local"),
+			StorageAccount: getEnv("LOCK_STORAGE_ACCOUNT", ""),
+			Container:      getEnv("LOCK_CONTAINER", "dev8-locks"),
+			LeaseDuration:  time.Duration(getEnvInt("LOCK_LEASE_SECONDS", 60)) * time.Second,
+		},
+
+		// Lifecycle webhooks
+		Webhooks: WebhookConfig{
+			Endpoints:   loadWebhookEndpoints(),
+			MaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 10),
+			Timeout:     time.Duration(getEnvInt("WEBHOOK_TIMEOUT_SECONDS", 10)) * time.Second,
+		},
+	}
+
+	// Local volumes live next to the agent state unless placed elsewhere
+	if config.Volumes.LocalDir == "" {
+		config.Volumes.LocalDir = filepath.Join(config.StateDir, "volumes")
+	}
+	if config.Secrets.FilePath == "" {
+		config.Secrets.FilePath = filepath.Join(config.StateDir, "secrets.json")
+	}
+
+	// Load the image catalogue
+	images, err := loadImageCatalog(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load image catalogue: %w", err)
+	}
+	config.Images = images
+
+	// Load the workspace policy
+	policy, err := loadPolicy()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policy: %w", err)
+	}
+	config.Policy = policy
+
+	// Load the usage rate card
+	if err := loadRateCard(&config.Usage); err != nil {
+		return nil, fmt.Errorf("failed to load rate card: %w", err)
 	}
 
 	// Load CORS configuration
@@ -112,6 +527,18 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to load Azure configuration: %w", err)
 	}
 	config.Azure = azureConfig
+	if config.Locks.StorageAccount == "" {
+		config.Locks.StorageAccount = config.Azure.StorageAccountName
+	}
+
+	// Load AWS configuration
+	config.AWS = AWSConfig{
+		Endpoint:         getEnv("AWS_ENDPOINT_URL", ""),
+		ExecutionRoleARN: getEnv("AWS_ECS_EXECUTION_ROLE_ARN", ""),
+		TaskRoleARN:      getEnv("AWS_ECS_TASK_ROLE_ARN", ""),
+		AssignPublicIP:   getEnvBool("AWS_ASSIGN_PUBLIC_IP", true),
+		Regions:          loadAWSRegions(),
+	}
 
 	// Validate configuration
 	if err := config.Validate(); err != nil {
@@ -130,8 +557,15 @@ func loadAzureConfig() (AzureConfig, error) {
 		StorageAccountKey:          getEnv("AZURE_STORAGE_KEY", ""),
 		ContainerRegistry:          getEnv("AZURE_CONTAINER_REGISTRY", ""),
 		DefaultRegion:              getEnv("AZURE_DEFAULT_REGION", "eastus"),
-		DeploymentMode:             getEnv("AZURE_DEPLOYMENT_MODE", "aci"), // "aci" or "aca"
+		DeploymentMode:             getEnv("AZURE_DEPLOYMENT_MODE", "aci"), // "aci", "aca", "docker" or "kubernetes"
 		ContainerAppsEnvironmentID: getEnv("AZURE_ACA_ENVIRONMENT_ID", ""),
+		Retry: AzureRetryConfig{
+			MaxAttempts:      getEnvInt("AZURE_RETRY_MAX_ATTEMPTS", 4),
+			BaseDelay:        time.Duration(getEnvInt("AZURE_RETRY_BASE_DELAY_MS", 800)) * time.Millisecond,
+			MaxDelay:         time.Duration(getEnvInt("AZURE_RETRY_MAX_DELAY_SECONDS", 60)) * time.Second,
+			BreakerThreshold: getEnvInt("AZURE_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:  time.Duration(getEnvInt("AZURE_BREAKER_COOLDOWN_SECONDS", 30)) * time.Second,
+		},
 	}
 
 	// Load multi-region configuration
@@ -202,6 +636,199 @@ func loadRegions() ([]RegionConfig, error) {
 	return regions, nil
 }
 
+// loadAWSRegions loads the AWS regions from environment variables
+func loadAWSRegions() []AWSRegionConfig {
+	// AWS_REGIONS format: "name:enabled:cluster:fileSystemId:subnet-a|subnet-b[:sg-1|sg-2],..."
+	// Example: "us-east-1:true:dev8:fs-0123456789abcdef0:subnet-0a|subnet-0b:sg-0c"
+	regionsEnv := getEnv("AWS_REGIONS", "")
+	if regionsEnv == "" {
+		return nil
+	}
+
+	var regions []AWSRegionConfig
+	for _, regionStr := range strings.Split(regionsEnv, ",") {
+		parts := strings.Split(strings.TrimSpace(regionStr), ":")
+		if len(parts) < 5 {
+			log.Printf("WARNING: Skipping malformed AWS region config (expected format 'name:enabled:cluster:fileSystemId:subnets[:securityGroups]'): %s", regionStr)
+			continue
+		}
+
+		enabled, err := strconv.ParseBool(parts[1])
+		if err != nil {
+			log.Printf("WARNING: Invalid boolean value for enabled flag in AWS region config '%s': %v - skipping region", regionStr, err)
+			continue
+		}
+
+		region := AWSRegionConfig{
+			Name:         parts[0],
+			Enabled:      enabled,
+			Cluster:      parts[2],
+			FileSystemID: parts[3],
+			Subnets:      splitList(parts[4], "|"),
+		}
+		if len(parts) > 5 {
+			region.SecurityGroups = splitList(parts[5], "|")
+		}
+
+		regions = append(regions, region)
+	}
+
+	return regions
+}
+
+// splitList splits s on sep, dropping empty items
+func splitList(s, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(s, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// loadImageCatalog loads the image catalogue from environment variables
+func loadImageCatalog(c *Config) (ImageCatalogConfig, error) {
+	// IMAGE_CATALOG format: comma-separated "name=reference"; references without a
+	// registry host are pulled from the default registry
+	// Example: "node=dev8-workspace:node,python=dev8-workspace:python,go=dev8-workspace@sha256:...,full=ghcr.io/dev8/full:1.4"
+	// IMAGE_REGION_REGISTRIES format: comma-separated "region=registry"
+	// Example: "westeurope=dev8weu.azurecr.io,us-east-1=123456789012.dkr.ecr.us-east-1.amazonaws.com"
+	catalog := ImageCatalogConfig{
+		Default:          getEnv("IMAGE_DEFAULT", "node"),
+		RegionRegistries: make(map[string]string),
+	}
+
+	catalogEnv := getEnv("IMAGE_CATALOG", "")
+	if catalogEnv == "" {
+		// A single "node" image keeps the CONTAINER_IMAGE / ACR setup working unchanged
+		ref := c.ContainerImage
+		if c.Azure.ContainerRegistry != "" {
+			ref = c.ContainerImageName
+		}
+		if ref != "" {
+			image, err := parseImageReference(ref)
+			if err != nil {
+				return catalog, err
+			}
+			image.Name = catalog.Default
+			catalog.Images = []ImageConfig{image}
+		}
+	}
+
+	seen := make(map[string]bool)
+
+	for _, entry := range splitList(catalogEnv, ",") {
+		name, ref, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return catalog, fmt.Errorf("IMAGE_CATALOG: malformed entry '%s' (expected 'name=reference')", entry)
+		}
+		image, err := parseImageReference(strings.TrimSpace(ref))
+		if err != nil {
+			return catalog, fmt.Errorf("IMAGE_CATALOG: image '%s': %w", name, err)
+		}
+		if seen[name] {
+			return catalog, fmt.Errorf("IMAGE_CATALOG: image '%s' is listed twice", name)
+		}
+		seen[name] = true
+		image.Name = name
+		catalog.Images = append(catalog.Images, image)
+	}
+
+	for _, entry := range splitList(getEnv("IMAGE_REGION_REGISTRIES", ""), ",") {
+		region, registry, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(region) == "" || strings.TrimSpace(registry) == "" {
+			log.Printf("WARNING: Skipping malformed image registry override (expected format 'region=registry'): %s", entry)
+			continue
+		}
+		catalog.RegionRegistries[strings.TrimSpace(region)] = strings.TrimSpace(registry)
+	}
+
+	return catalog, nil
+}
+
+// loadPolicy loads the workspace tiers from the JSON file named by POLICY_FILE:
+//
+//	{"defaultTier": "free", "tiers": {"free": {"maxRunning": 1, "maxCpuCores": 2, "regions": ["eastus"]}}}
+//
+// POLICY_DEFAULT_TIER overrides the file's default tier.
+func loadPolicy() (PolicyConfig, error) {
+	policy := PolicyConfig{File: getEnv("POLICY_FILE", "")}
+	if policy.File != "" {
+		data, err := os.ReadFile(policy.File)
+		if err != nil {
+			return policy, err
+		}
+		var file struct {
+			DefaultTier string                `json:"defaultTier"`
+			Tiers       map[string]TierPolicy `json:"tiers"`
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return policy, fmt.Errorf("%s: %w", policy.File, err)
+		}
+		policy.DefaultTier, policy.Tiers = file.DefaultTier, file.Tiers
+	}
+	policy.DefaultTier = getEnv("POLICY_DEFAULT_TIER", policy.DefaultTier)
+	return policy, nil
+}
+
+// loadRateCard loads the usage rates from the JSON file named by USAGE_RATE_CARD_FILE:
+//
+//	{"currency": "USD", "rates": [{"region": "*", "mode": "aci", "vcpuHour": 0.0405, "memoryGBHour": 0.0045, "storageGBMonth": 0.16}]}
+//
+// Without a rate card usage is metered but not priced.
+func loadRateCard(usage *UsageConfig) error {
+	usage.RateCardFile = getEnv("USAGE_RATE_CARD_FILE", "")
+	usage.Currency = "USD"
+	if usage.RateCardFile == "" {
+		return nil
+	}
+	data, err := os.ReadFile(usage.RateCardFile)
+	if err != nil {
+		return err
+	}
+	var file struct {
+		Currency string      `json:"currency"`
+		Rates    []UsageRate `json:"rates"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%s: %w", usage.RateCardFile, err)
+	}
+	if file.Currency != "" {
+		usage.Currency = file.Currency
+	}
+	usage.Rates = file.Rates
+	return nil
+}
+
+// parseImageReference splits an image reference such as
+// "myregistry.azurecr.io/dev8-workspace:1.2" or "dev8-workspace@sha256:..." into its parts.
+// As with docker, the first path component is a registry only if it looks like a host.
+func parseImageReference(ref string) (ImageConfig, error) {
+	var image ImageConfig
+	if ref == "" {
+		return image, fmt.Errorf("empty image reference")
+	}
+
+	if host, rest, ok := strings.Cut(ref, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		image.Registry = host
+		ref = rest
+	}
+	if repo, digest, ok := strings.Cut(ref, "@"); ok {
+		image.Repository, image.Digest = repo, digest
+	} else if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		image.Repository, image.Tag = ref[:i], ref[i+1:]
+	} else {
+		image.Repository = ref
+	}
+
+	if image.Repository == "" {
+		return image, fmt.Errorf("invalid image reference '%s'", ref)
+	}
+	return image, nil
+}
+
 // loadCORSAllowedOrigins loads CORS allowed origins from environment variables
 func loadCORSAllowedOrigins() []string {
 	// CORS_ALLOWED_ORIGINS format: comma-separated list of origins
@@ -224,15 +851,109 @@ func loadCORSAllowedOrigins() []string {
 	return trimmedOrigins
 }
 
+// loadTierBudgets loads per user tier monthly budgets from environment variables
+func loadTierBudgets() map[string]BudgetConfig {
+	// USAGE_BUDGET_TIERS format: "tier:soft:hard,..." - 0 means no budget
+	// Example: "free:5:10,pro:80:100,enterprise:0:0"
+	tiers := make(map[string]BudgetConfig)
+	tiersEnv := getEnv("USAGE_BUDGET_TIERS", "")
+	if tiersEnv == "" {
+		return tiers
+	}
+
+	for _, tierStr := range strings.Split(tiersEnv, ",") {
+		parts := strings.Split(strings.TrimSpace(tierStr), ":")
+		if len(parts) != 3 || parts[0] == "" {
+			log.Printf("WARNING: Skipping malformed budget tier config (expected format 'tier:soft:hard'): %s", tierStr)
+			continue
+		}
+
+		soft, softErr := strconv.ParseFloat(parts[1], 64)
+		hard, hardErr := strconv.ParseFloat(parts[2], 64)
+		if softErr != nil || hardErr != nil || soft < 0 || hard < 0 {
+			log.Printf("WARNING: Invalid budgets in budget tier config '%s' - skipping tier", tierStr)
+			continue
+		}
+
+		tiers[parts[0]] = BudgetConfig{Soft: soft, Hard: hard}
+	}
+
+	return tiers
+}
+
+// loadIdleTierTimeouts loads per user tier idle timeouts from environment variables
+func loadIdleTierTimeouts() map[string]time.Duration {
+	// IDLE_TIMEOUT_TIERS format: "tier:minutes,..." - 0 minutes never auto-stops
+	// Example: "free:30,pro:240,enterprise:0"
+	tiers := make(map[string]time.Duration)
+	tiersEnv := getEnv("IDLE_TIMEOUT_TIERS", "")
+	if tiersEnv == "" {
+		return tiers
+	}
+
+	for _, tierStr := range strings.Split(tiersEnv, ",") {
+		parts := strings.Split(strings.TrimSpace(tierStr), ":")
+		if len(parts) != 2 || parts[0] == "" {
+			log.Printf("WARNING: Skipping malformed idle tier config (expected format 'tier:minutes'): %s", tierStr)
+			continue
+		}
+
+		minutes, err := strconv.Atoi(parts[1])
+		if err != nil || minutes < 0 {
+			log.Printf("WARNING: Invalid minutes in idle tier config '%s' - skipping tier", tierStr)
+			continue
+		}
+
+		tiers[parts[0]] = time.Duration(minutes) * time.Minute
+	}
+
+	return tiers
+}
+
+// loadWebhookEndpoints loads webhook receivers from environment variables
+func loadWebhookEndpoints() []WebhookEndpoint {
+	// WEBHOOK_ENDPOINTS format: comma-separated "url" or "url|secret"
+	// Endpoints without an inline secret use WEBHOOK_SECRET.
+	// Example: "https://dev8.dev/api/agent/events|s3cr3t,https://audit.internal/hook"
+	endpointsEnv := getEnv("WEBHOOK_ENDPOINTS", "")
+	if endpointsEnv == "" {
+		return nil
+	}
+
+	defaultSecret := getEnv("WEBHOOK_SECRET", "")
+
+	var endpoints []WebhookEndpoint
+	for _, endpointStr := range strings.Split(endpointsEnv, ",") {
+		endpointStr = strings.TrimSpace(endpointStr)
+		if endpointStr == "" {
+			continue
+		}
+
+		endpoint := WebhookEndpoint{URL: endpointStr, Secret: defaultSecret}
+		if url, secret, ok := strings.Cut(endpointStr, "|"); ok {
+			endpoint.URL = strings.TrimSpace(url)
+			endpoint.Secret = strings.TrimSpace(secret)
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	return endpoints
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	if c.Port == "" {
 		return fmt.Errorf("AGENT_PORT is required")
 	}
 
-	// DATABASE_URL is now optional - Agent is stateless
+	// DATABASE_URL is optional - the agent keeps its own registry in StateDir
+
+	if c.StateStore != "bolt" && c.StateStore != "memory" {
+		return fmt.Errorf("STATE_STORE must be either 'bolt' or 'memory', got '%s'", c.StateStore)
+	}
 
-	if c.Azure.SubscriptionID == "" {
+	// Docker and Kubernetes modes run workspaces outside Azure and need no subscription
+	if c.Azure.SubscriptionID == "" && c.UsesAzure() {
 		return fmt.Errorf("AZURE_SUBSCRIPTION_ID is required")
 	}
 
@@ -253,9 +974,86 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("AGENT_BASE_URL is required")
 	}
 
+	if c.GetImage(c.Images.Default) == nil {
+		return fmt.Errorf("IMAGE_DEFAULT '%s' is not in IMAGE_CATALOG", c.Images.Default)
+	}
+
+	if err := c.validatePolicy(); err != nil {
+		return err
+	}
+
+	if c.Azure.Retry.MaxAttempts < 1 {
+		return fmt.Errorf("AZURE_RETRY_MAX_ATTEMPTS must be at least 1")
+	}
+	if c.Azure.Retry.BaseDelay <= 0 || c.Azure.Retry.MaxDelay < c.Azure.Retry.BaseDelay {
+		return fmt.Errorf("AZURE_RETRY_BASE_DELAY_MS must be positive and AZURE_RETRY_MAX_DELAY_SECONDS at least as long")
+	}
+	if c.Azure.Retry.BreakerThreshold < 0 || (c.Azure.Retry.BreakerThreshold > 0 && c.Azure.Retry.BreakerCooldown <= 0) {
+		return fmt.Errorf("AZURE_BREAKER_THRESHOLD must not be negative and AZURE_BREAKER_COOLDOWN_SECONDS must be positive")
+	}
+
+	if c.OperationTimeout <= 0 {
+		return fmt.Errorf("OPERATION_TIMEOUT_SECONDS must be positive")
+	}
+
+	if c.IdempotencyTTL < 0 {
+		return fmt.Errorf("IDEMPOTENCY_TTL_MINUTES must not be negative")
+	}
+
+	if c.Reconciler.Policy != "report" && c.Reconciler.Policy != "delete" {
+		return fmt.Errorf("RECONCILER_POLICY must be either 'report' or 'delete', got '%s'", c.Reconciler.Policy)
+	}
+
+	if c.Reconciler.Enabled && c.Reconciler.Interval <= 0 {
+		return fmt.Errorf("RECONCILER_INTERVAL_MINUTES must be positive when the reconciler is enabled")
+	}
+
+	if c.Idle.Enabled && c.Idle.ScanInterval <= 0 {
+		return fmt.Errorf("IDLE_SCAN_INTERVAL_SECONDS must be positive when idle auto-stop is enabled")
+	}
+
+	if c.Idle.DefaultTimeout < 0 || c.Idle.WarningPeriod < 0 {
+		return fmt.Errorf("IDLE_TIMEOUT_MINUTES and IDLE_WARNING_MINUTES must not be negative")
+	}
+
+	if c.Schedules.Enabled && (c.Schedules.CheckInterval <= 0 || c.Schedules.CheckInterval > time.Minute) {
+		return fmt.Errorf("SCHEDULER_INTERVAL_SECONDS must be between 1 and 60 when the scheduler is enabled")
+	}
+
+	if c.Schedules.CatchUpWindow < 0 {
+		return fmt.Errorf("SCHEDULER_CATCH_UP_MINUTES must not be negative")
+	}
+
+	if err := c.validateUsage(); err != nil {
+		return err
+	}
+
+	if c.Snapshots.MaxCount < 0 || c.Snapshots.MaxCount > 200 {
+		return fmt.Errorf("SNAPSHOT_MAX_COUNT must be between 0 and 200 (the Azure Files limit per share)")
+	}
+
+	if c.Snapshots.MaxAgeDays < 0 {
+		return fmt.Errorf("SNAPSHOT_MAX_AGE_DAYS must not be negative")
+	}
+
+	for _, endpoint := range c.Webhooks.Endpoints {
+		if !strings.HasPrefix(endpoint.URL, "http://") && !strings.HasPrefix(endpoint.URL, "https://") {
+			return fmt.Errorf("WEBHOOK_ENDPOINTS: invalid URL '%s'", endpoint.URL)
+		}
+		if endpoint.Secret == "" {
+			return fmt.Errorf("WEBHOOK_ENDPOINTS: no secret for '%s' (set one inline or WEBHOOK_SECRET)", endpoint.URL)
+		}
+	}
+
+	if len(c.Webhooks.Endpoints) > 0 && c.Webhooks.MaxAttempts < 1 {
+		return fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+
 	// Validate deployment mode
-	if c.Azure.DeploymentMode != "" && c.Azure.DeploymentMode != "aci" && c.Azure.DeploymentMode != "aca" {
-		return fmt.Errorf("AZURE_DEPLOYMENT_MODE must be either 'aci' or 'aca', got '%s'", c.Azure.DeploymentMode)
+	switch c.Azure.DeploymentMode {
+	case "", "aci", "aca", "docker", "kubernetes":
+	default:
+		return fmt.Errorf("AZURE_DEPLOYMENT_MODE must be 'aci', 'aca', 'docker' or 'kubernetes', got '%s'", c.Azure.DeploymentMode)
 	}
 
 	// If ACA mode is enabled, environment ID is required
@@ -263,9 +1061,111 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("AZURE_ACA_ENVIRONMENT_ID is required when AZURE_DEPLOYMENT_MODE is 'aca'")
 	}
 
+	if c.Azure.DeploymentMode == "kubernetes" {
+		if c.Kubernetes.Namespace == "" {
+			return fmt.Errorf("K8S_NAMESPACE is required when AZURE_DEPLOYMENT_MODE is 'kubernetes'")
+		}
+		if c.Kubernetes.ServiceType != "ClusterIP" && c.Kubernetes.ServiceType != "LoadBalancer" {
+			return fmt.Errorf("K8S_SERVICE_TYPE must be either 'ClusterIP' or 'LoadBalancer', got '%s'", c.Kubernetes.ServiceType)
+		}
+	}
+
+	// Azure containers can only mount Azure Files; local volumes need a local engine
+	switch c.Volumes.Backend {
+	case "":
+	case "azure":
+		if !c.UsesAzure() {
+			return fmt.Errorf("VOLUME_BACKEND 'azure' cannot be used when AZURE_DEPLOYMENT_MODE is '%s'", c.Azure.DeploymentMode)
+		}
+	case "docker", "local":
+		if c.Azure.DeploymentMode != "docker" {
+			return fmt.Errorf("VOLUME_BACKEND '%s' requires AZURE_DEPLOYMENT_MODE 'docker'", c.Volumes.Backend)
+		}
+	case "kubernetes":
+		if c.Azure.DeploymentMode != "kubernetes" {
+			return fmt.Errorf("VOLUME_BACKEND 'kubernetes' requires AZURE_DEPLOYMENT_MODE 'kubernetes'")
+		}
+	default:
+		return fmt.Errorf("VOLUME_BACKEND must be 'azure', 'docker', 'kubernetes' or 'local', got '%s'", c.Volumes.Backend)
+	}
+
+	switch c.SecretBackend() {
+	case "keyvault":
+		if !strings.HasPrefix(c.Secrets.KeyVaultURL, "https://") {
+			return fmt.Errorf("AZURE_KEY_VAULT_URL must be an https:// vault URL when SECRET_STORE is 'keyvault', got '%s'", c.Secrets.KeyVaultURL)
+		}
+	case "file":
+		if c.Secrets.FileKey != "" {
+			if key, err := base64.StdEncoding.DecodeString(c.Secrets.FileKey); err != nil || len(key) != 32 {
+				return fmt.Errorf("SECRET_STORE_KEY must be a base64-encoded 32-byte key")
+			}
+		}
+	case "none":
+	default:
+		return fmt.Errorf("SECRET_STORE must be 'keyvault', 'file' or 'none', got '%s'", c.Secrets.Backend)
+	}
+
+	switch c.Locks.Backend {
+	case "local":
+	case "blob":
+		if c.Locks.StorageAccount == "" {
+			return fmt.Errorf("LOCK_STORAGE_ACCOUNT or AZURE_STORAGE_ACCOUNT is required when LOCK_BACKEND is 'blob'")
+		}
+		if c.Locks.Container == "" {
+			return fmt.Errorf("LOCK_CONTAINER is required when LOCK_BACKEND is 'blob'")
+		}
+		// Blob leases last between 15 and 60 seconds
+		if c.Locks.LeaseDuration < 15*time.Second || c.Locks.LeaseDuration > 60*time.Second {
+			return fmt.Errorf("LOCK_LEASE_SECONDS must be between 15 and 60, got %d", int(c.Locks.LeaseDuration/time.Second))
+		}
+	default:
+		return fmt.Errorf("LOCK_BACKEND must be 'local' or 'blob', got '%s'", c.Locks.Backend)
+	}
+
+	if c.AWS.Endpoint != "" && !strings.HasPrefix(c.AWS.Endpoint, "http://") && !strings.HasPrefix(c.AWS.Endpoint, "https://") {
+		return fmt.Errorf("AWS_ENDPOINT_URL: invalid URL '%s'", c.AWS.Endpoint)
+	}
+
+	for _, region := range c.GetEnabledAWSRegions() {
+		if region.Cluster == "" || region.FileSystemID == "" || len(region.Subnets) == 0 {
+			return fmt.Errorf("AWS_REGIONS: region %s needs a cluster, a file system and at least one subnet", region.Name)
+		}
+	}
+
 	return nil
 }
 
+// UsesAzure reports whether workspaces run on Azure, as opposed to a local
+// Docker Engine or a Kubernetes cluster
+func (c *Config) UsesAzure() bool {
+	return c.Azure.DeploymentMode != "docker" && c.Azure.DeploymentMode != "kubernetes"
+}
+
+// VolumeBackend returns the configured volume backend, defaulting to named volumes
+// in docker mode, PVCs in kubernetes mode and Azure Files otherwise
+func (c *Config) VolumeBackend() string {
+	if c.Volumes.Backend != "" {
+		return c.Volumes.Backend
+	}
+	switch c.Azure.DeploymentMode {
+	case "docker", "kubernetes":
+		return c.Azure.DeploymentMode
+	}
+	return "azure"
+}
+
+// SecretBackend returns the configured secret store, defaulting to Key Vault when a
+// vault is set and to the encrypted local file otherwise
+func (c *Config) SecretBackend() string {
+	if c.Secrets.Backend != "" {
+		return c.Secrets.Backend
+	}
+	if c.Secrets.KeyVaultURL != "" {
+		return "keyvault"
+	}
+	return "file"
+}
+
 // GetRegion returns the region configuration for the given region name
 func (c *Config) GetRegion(name string) *RegionConfig {
 	for _, region := range c.Azure.Regions {
@@ -287,6 +1187,51 @@ func (c *Config) GetEnabledRegions() []RegionConfig {
 	return enabled
 }
 
+// GetImage returns the catalogue entry of the named image
+func (c *Config) GetImage(name string) *ImageConfig {
+	for _, image := range c.Images.Images {
+		if image.Name == name {
+			return &image
+		}
+	}
+	return nil
+}
+
+// ImageNames returns the names of the catalogue images in configuration order
+func (c *Config) ImageNames() []string {
+	names := make([]string, 0, len(c.Images.Images))
+	for _, image := range c.Images.Images {
+		names = append(names, image.Name)
+	}
+	return names
+}
+
+// UsesAWS reports whether workspaces can be created with cloudProvider "AWS"
+func (c *Config) UsesAWS() bool {
+	return len(c.GetEnabledAWSRegions()) > 0
+}
+
+// GetAWSRegion returns the configuration of an enabled AWS region
+func (c *Config) GetAWSRegion(name string) *AWSRegionConfig {
+	for _, region := range c.AWS.Regions {
+		if region.Name == name && region.Enabled {
+			return &region
+		}
+	}
+	return nil
+}
+
+// GetEnabledAWSRegions returns all enabled AWS regions
+func (c *Config) GetEnabledAWSRegions() []AWSRegionConfig {
+	var enabled []AWSRegionConfig
+	for _, region := range c.AWS.Regions {
+		if region.Enabled {
+			enabled = append(enabled, region)
+		}
+	}
+	return enabled
+}
+
 // getEnv gets an environment variable with a fallback default value
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -305,6 +1250,26 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvFloat gets a decimal environment variable with a fallback default value
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvBool gets a boolean environment variable with a fallback default value
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 // loadAPIKeys loads API keys from environment variables
 func loadAPIKeys() []string {
 	// API_KEYS format: comma-separated list of API keys
@@ -325,3 +1290,69 @@ func loadAPIKeys() []string {
 
 	return trimmedKeys
 }
+
+// validateUsage checks the rate card and budgets
+func (c *Config) validateUsage() error {
+	for i, rate := range c.Usage.Rates {
+		if rate.Region == "" || rate.Mode == "" {
+			return fmt.Errorf("rate card %s: rate %d needs a region and a mode (\"*\" matches any)", c.Usage.RateCardFile, i+1)
+		}
+		switch rate.Mode {
+		case "*", "aci", "aca", "docker", "kubernetes", "ecs":
+		default:
+			return fmt.Errorf("rate card %s: rate %d: unknown mode '%s'", c.Usage.RateCardFile, i+1, rate.Mode)
+		}
+		if rate.VCPUHour < 0 || rate.MemoryGBHour < 0 || rate.StorageGBMonth < 0 {
+			return fmt.Errorf("rate card %s: rate %d: prices must not be negative", c.Usage.RateCardFile, i+1)
+		}
+	}
+
+	budgets := map[string]BudgetConfig{"": {Soft: c.Usage.SoftBudget, Hard: c.Usage.HardBudget}}
+	for tier, budget := range c.Usage.TierBudgets {
+		budgets[tier] = budget
+	}
+	for tier, budget := range budgets {
+		name := "USAGE_MONTHLY_SOFT_BUDGET and USAGE_MONTHLY_HARD_BUDGET"
+		if tier != "" {
+			name = fmt.Sprintf("USAGE_BUDGET_TIERS tier '%s'", tier)
+		}
+		if budget.Soft < 0 || budget.Hard < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+		if budget.Soft > 0 && budget.Hard > 0 && budget.Soft > budget.Hard {
+			return fmt.Errorf("%s: the soft budget must not exceed the hard budget", name)
+		}
+	}
+
+	if c.Usage.HasBudgets() && c.Usage.BudgetInterval <= 0 {
+		return fmt.Errorf("USAGE_BUDGET_INTERVAL_SECONDS must be positive when budgets are set")
+	}
+	return nil
+}
+
+// validatePolicy checks the tiers loaded from POLICY_FILE
+func (c *Config) validatePolicy() error {
+	if len(c.Policy.Tiers) == 0 {
+		if c.Policy.File != "" {
+			return fmt.Errorf("POLICY_FILE %s defines no tiers", c.Policy.File)
+		}
+		return nil
+	}
+	if _, ok := c.Policy.Tiers[c.Policy.DefaultTier]; !ok {
+		return fmt.Errorf("policy default tier '%s' is not defined in %s", c.Policy.DefaultTier, c.Policy.File)
+	}
+	for name, tier := range c.Policy.Tiers {
+		if tier.Scope != "" && tier.Scope != "user" && tier.Scope != "org" {
+			return fmt.Errorf("policy tier '%s': scope must be 'user' or 'org', got '%s'", name, tier.Scope)
+		}
+		if tier.MaxRunning < 0 || tier.MaxCPUCores < 0 || tier.MaxMemoryGB < 0 || tier.MaxStorageGB < 0 {
+			return fmt.Errorf("policy tier '%s': limits must not be negative", name)
+		}
+		for _, image := range tier.Images {
+			if c.GetImage(image) == nil {
+				return fmt.Errorf("policy tier '%s': image '%s' is not in IMAGE_CATALOG", name, image)
+			}
+		}
+	}
+	return nil
+}
@@ -2,7 +2,9 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestLoad(t *testing.T) {
@@ -30,6 +32,222 @@ func TestLoad(t *testing.T) {
 			},
 			wantErr: false, // DATABASE_URL is now optional for stateless agent
 		},
+		{
+			name: "docker mode without subscription ID",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_DEPLOYMENT_MODE": "docker",
+			},
+			wantErr: false,
+		},
+		{
+			name: "docker mode with local volumes",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_DEPLOYMENT_MODE": "docker",
+				"VOLUME_BACKEND":        "local",
+			},
+			wantErr: false,
+		},
+		{
+			name: "local volumes outside docker mode",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"VOLUME_BACKEND":        "local",
+			},
+			wantErr: true,
+		},
+		{
+			name: "kubernetes mode without subscription ID",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_DEPLOYMENT_MODE": "kubernetes",
+			},
+			wantErr: false,
+		},
+		{
+			name: "kubernetes mode with unsupported service type",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_DEPLOYMENT_MODE": "kubernetes",
+				"K8S_SERVICE_TYPE":      "NodePort",
+			},
+			wantErr: true,
+		},
+		{
+			name: "AWS region",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"AWS_REGIONS":           "us-east-1:true:dev8:fs-0123:subnet-a|subnet-b:sg-1",
+				"AWS_ENDPOINT_URL":      "http://localhost:4566",
+			},
+			wantErr: false,
+		},
+		{
+			name: "AWS region without subnets",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"AWS_REGIONS":           "us-east-1:true:dev8:fs-0123:",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown deployment mode",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"AZURE_DEPLOYMENT_MODE": "podman",
+			},
+			wantErr: true,
+		},
+		{
+			name: "image catalogue",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"IMAGE_CATALOG":         "node=dev8-workspace:node,python=dev8-workspace:python",
+				"IMAGE_DEFAULT":         "python",
+			},
+			wantErr: false,
+		},
+		{
+			name: "default image not in catalogue",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"IMAGE_CATALOG":         "python=dev8-workspace:python",
+			},
+			wantErr: true,
+		},
+		{
+			name: "key vault secret store",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"AZURE_KEY_VAULT_URL":   "https://dev8-secrets.vault.azure.net",
+			},
+			wantErr: false,
+		},
+		{
+			name: "key vault secret store without vault",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"SECRET_STORE":          "keyvault",
+			},
+			wantErr: true,
+		},
+		{
+			name: "file secret store with short key",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"SECRET_STORE":          "file",
+				"SECRET_STORE_KEY":      "c2hvcnQ=",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown secret store",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"SECRET_STORE":          "vault",
+			},
+			wantErr: true,
+		},
+		{
+			name: "blob locks without storage account",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"LOCK_BACKEND":          "blob",
+			},
+			wantErr: true,
+		},
+		{
+			name: "blob locks in the default storage account",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"AZURE_STORAGE_ACCOUNT": "dev8storage",
+				"LOCK_BACKEND":          "blob",
+			},
+			wantErr: false,
+		},
+		{
+			name: "blob locks with too long lease",
+			envVars: map[string]string{
+				"AGENT_PORT":            "8080",
+				"AZURE_SUBSCRIPTION_ID": "test-sub-id",
+				"AZURE_STORAGE_ACCOUNT": "dev8storage",
+				"LOCK_BACKEND":          "blob",
+				"LOCK_LEASE_SECONDS":    "120",
+			},
+			wantErr: true,
+		},
+		{
+			name: "retry max delay below base delay",
+			envVars: map[string]string{
+				"AGENT_PORT":                    "8080",
+				"AZURE_SUBSCRIPTION_ID":         "test-sub-id",
+				"AZURE_RETRY_BASE_DELAY_MS":     "5000",
+				"AZURE_RETRY_MAX_DELAY_SECONDS": "1",
+			},
+			wantErr: true,
+		},
+		{
+			name: "breaker without cooldown",
+			envVars: map[string]string{
+				"AGENT_PORT":                     "8080",
+				"AZURE_SUBSCRIPTION_ID":          "test-sub-id",
+				"AZURE_BREAKER_COOLDOWN_SECONDS": "0",
+			},
+			wantErr: true,
+		},
+		{
+			name: "scheduler checking less than once a minute",
+			envVars: map[string]string{
+				"AGENT_PORT":                 "8080",
+				"AZURE_SUBSCRIPTION_ID":      "test-sub-id",
+				"SCHEDULER_INTERVAL_SECONDS": "300",
+			},
+			wantErr: true,
+		},
+		{
+			name: "scheduler disabled",
+			envVars: map[string]string{
+				"AGENT_PORT":                 "8080",
+				"AZURE_SUBSCRIPTION_ID":      "test-sub-id",
+				"SCHEDULER_ENABLED":          "false",
+				"SCHEDULER_INTERVAL_SECONDS": "0",
+			},
+			wantErr: false,
+		},
+		{
+			name: "soft budget above hard budget",
+			envVars: map[string]string{
+				"AGENT_PORT":                "8080",
+				"AZURE_SUBSCRIPTION_ID":     "test-sub-id",
+				"USAGE_MONTHLY_SOFT_BUDGET": "50",
+				"USAGE_MONTHLY_HARD_BUDGET": "20",
+			},
+			wantErr: true,
+		},
+		{
+			name: "budgets",
+			envVars: map[string]string{
+				"AGENT_PORT":                "8080",
+				"AZURE_SUBSCRIPTION_ID":     "test-sub-id",
+				"USAGE_MONTHLY_SOFT_BUDGET": "40",
+				"USAGE_MONTHLY_HARD_BUDGET": "50.5",
+				"USAGE_BUDGET_TIERS":        "free:5:10,enterprise:0:0",
+			},
+			wantErr: false,
+		},
 		{
 			name: "missing subscription ID",
 			envVars: map[string]string{
@@ -175,6 +393,35 @@ func TestLoadRegions(t *testing.T) {
 	}
 }
 
+func TestLoadAWSRegions(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("AWS_REGIONS", "us-east-1:true:dev8:fs-0123:subnet-a| subnet-b:sg-1, eu-west-1:false:dev8-eu:fs-4567:subnet-c, us-west-2:true")
+
+	regions := loadAWSRegions()
+	if len(regions) != 2 {
+		t.Fatalf("loadAWSRegions() returned %d regions, want 2 (malformed entry skipped)", len(regions))
+	}
+
+	east := regions[0]
+	if east.Name != "us-east-1" || !east.Enabled || east.Cluster != "dev8" || east.FileSystemID != "fs-0123" {
+		t.Errorf("us-east-1 = %+v", east)
+	}
+	if len(east.Subnets) != 2 || east.Subnets[1] != "subnet-b" {
+		t.Errorf("subnets = %v, want [subnet-a subnet-b]", east.Subnets)
+	}
+	if len(east.SecurityGroups) != 1 || east.SecurityGroups[0] != "sg-1" {
+		t.Errorf("security groups = %v, want [sg-1]", east.SecurityGroups)
+	}
+	if regions[1].Enabled || regions[1].SecurityGroups != nil {
+		t.Errorf("eu-west-1 = %+v, want disabled without security groups", regions[1])
+	}
+
+	cfg := &Config{AWS: AWSConfig{Regions: regions}}
+	if cfg.GetAWSRegion("us-east-1") == nil || cfg.GetAWSRegion("eu-west-1") != nil {
+		t.Error("GetAWSRegion() should only return enabled regions")
+	}
+}
+
 func TestLoadCORSAllowedOrigins(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -212,3 +459,249 @@ func TestLoadCORSAllowedOrigins(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadIdleTierTimeouts(t *testing.T) {
+	tests := []struct {
+		name     string
+		tiersEnv string
+		want     map[string]time.Duration
+	}{
+		{
+			name:     "empty",
+			tiersEnv: "",
+			want:     map[string]time.Duration{},
+		},
+		{
+			name:     "multiple tiers",
+			tiersEnv: "free:30, pro:240,enterprise:0",
+			want:     map[string]time.Duration{"free": 30 * time.Minute, "pro": 240 * time.Minute, "enterprise": 0},
+		},
+		{
+			name:     "malformed entries skipped",
+			tiersEnv: "free:30,pro,team:-5,:10,max:abc",
+			want:     map[string]time.Duration{"free": 30 * time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Clearenv()
+			if tt.tiersEnv != "" {
+				_ = os.Setenv("IDLE_TIMEOUT_TIERS", tt.tiersEnv)
+			}
+
+			got := loadIdleTierTimeouts()
+			if len(got) != len(tt.want) {
+				t.Fatalf("loadIdleTierTimeouts() = %v, want %v", got, tt.want)
+			}
+			for tier, timeout := range tt.want {
+				if got[tier] != timeout {
+					t.Errorf("loadIdleTierTimeouts()[%s] = %v, want %v", tier, got[tier], timeout)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadWebhookEndpoints(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("WEBHOOK_ENDPOINTS", "https://dev8.dev/hook|inline, https://audit.example/hook")
+	_ = os.Setenv("WEBHOOK_SECRET", "shared")
+
+	endpoints := loadWebhookEndpoints()
+	want := []WebhookEndpoint{
+		{URL: "https://dev8.dev/hook", Secret: "inline"},
+		{URL: "https://audit.example/hook", Secret: "shared"},
+	}
+
+	if len(endpoints) != len(want) {
+		t.Fatalf("loadWebhookEndpoints() = %v, want %v", endpoints, want)
+	}
+	for i := range want {
+		if endpoints[i] != want[i] {
+			t.Errorf("endpoint[%d] = %+v, want %+v", i, endpoints[i], want[i])
+		}
+	}
+}
+
+func TestLoadImageCatalog(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("IMAGE_CATALOG", "node=dev8-workspace:node, go=dev8-workspace@sha256:abc,full=ghcr.io/dev8/full:1.4,rust=localhost/rust")
+	_ = os.Setenv("IMAGE_REGION_REGISTRIES", "westeurope=dev8weu.azurecr.io,bogus")
+
+	catalog, err := loadImageCatalog(&Config{})
+	if err != nil {
+		t.Fatalf("loadImageCatalog() error = %v", err)
+	}
+	want := []ImageConfig{
+		{Name: "node", Repository: "dev8-workspace", Tag: "node"},
+		{Name: "go", Repository: "dev8-workspace", Digest: "sha256:abc"},
+		{Name: "full", Registry: "ghcr.io", Repository: "dev8/full", Tag: "1.4"},
+		{Name: "rust", Registry: "localhost", Repository: "rust"},
+	}
+	if len(catalog.Images) != len(want) {
+		t.Fatalf("images = %+v, want %+v", catalog.Images, want)
+	}
+	for i := range want {
+		if catalog.Images[i] != want[i] {
+			t.Errorf("images[%d] = %+v, want %+v", i, catalog.Images[i], want[i])
+		}
+	}
+	if len(catalog.RegionRegistries) != 1 || catalog.RegionRegistries["westeurope"] != "dev8weu.azurecr.io" {
+		t.Errorf("region registries = %v, want westeurope only", catalog.RegionRegistries)
+	}
+
+	_ = os.Setenv("IMAGE_CATALOG", "node=a:1,node=b:2")
+	if _, err := loadImageCatalog(&Config{}); err == nil {
+		t.Error("loadImageCatalog() with a duplicate name error = nil")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() (*Config, error) {
+		os.Clearenv()
+		_ = os.Setenv("AZURE_SUBSCRIPTION_ID", "test-sub-id")
+		_ = os.Setenv("POLICY_FILE", path)
+		return Load()
+	}
+
+	writePolicy(`{"defaultTier": "free", "tiers": {
+		"free": {"maxRunning": 1, "maxCpuCores": 2, "maxMemoryGB": 4, "maxStorageGB": 20, "regions": ["eastus"], "images": ["node"]},
+		"team": {"scope": "org", "maxRunning": 10}
+	}}`)
+	cfg, err := load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	free := cfg.Policy.Tiers["free"]
+	if cfg.Policy.DefaultTier != "free" || free.MaxRunning != 1 || free.MaxStorageGB != 20 || len(free.Regions) != 1 || cfg.Policy.Tiers["team"].Scope != "org" {
+		t.Errorf("policy = %+v", cfg.Policy)
+	}
+
+	invalid := map[string]string{
+		"unknown default tier": `{"defaultTier": "gold", "tiers": {"free": {}}}`,
+		"no tiers":             `{"defaultTier": "free"}`,
+		"invalid scope":        `{"defaultTier": "free", "tiers": {"free": {"scope": "team"}}}`,
+		"negative limit":       `{"defaultTier": "free", "tiers": {"free": {"maxRunning": -1}}}`,
+		"unknown image":        `{"defaultTier": "free", "tiers": {"free": {"images": ["cobol"]}}}`,
+		"malformed":            `{"tiers": [`,
+	}
+	for name, content := range invalid {
+		writePolicy(content)
+		if _, err := load(); err == nil {
+			t.Errorf("Load() with %s error = nil", name)
+		}
+	}
+}
+
+func TestLoadRateCard(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.json")
+	writeRates := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	load := func() (*Config, error) {
+		os.Clearenv()
+		_ = os.Setenv("AZURE_SUBSCRIPTION_ID", "test-sub-id")
+		_ = os.Setenv("USAGE_RATE_CARD_FILE", path)
+		return Load()
+	}
+
+	writeRates(`{"currency": "EUR", "rates": [
+		{"region": "*", "mode": "*", "vcpuHour": 0.05, "memoryGBHour": 0.005, "storageGBMonth": 0.1},
+		{"region": "*", "mode": "aca", "vcpuHour": 0.03},
+		{"region": "westeurope", "mode": "*", "vcpuHour": 0.04},
+		{"region": "westeurope", "mode": "aca", "vcpuHour": 0.02}
+	]}`)
+	cfg, err := load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Usage.Currency != "EUR" || len(cfg.Usage.Rates) != 4 {
+		t.Errorf("usage = %+v", cfg.Usage)
+	}
+
+	for _, tt := range []struct {
+		region, mode string
+		want         float64
+	}{
+		{"westeurope", "aca", 0.02},
+		{"westeurope", "aci", 0.04},
+		{"eastus", "aca", 0.03},
+		{"eastus", "aci", 0.05},
+	} {
+		if rate, ok := cfg.Usage.RateFor(tt.region, tt.mode); !ok || rate.VCPUHour != tt.want {
+			t.Errorf("RateFor(%s, %s) = %+v, %v, want vcpuHour %v", tt.region, tt.mode, rate, ok, tt.want)
+		}
+	}
+	if _, ok := (UsageConfig{}).RateFor("eastus", "aci"); ok {
+		t.Error("RateFor() without rates ok = true")
+	}
+
+	invalid := map[string]string{
+		"missing mode":   `{"rates": [{"region": "*"}]}`,
+		"unknown mode":   `{"rates": [{"region": "*", "mode": "vm"}]}`,
+		"negative price": `{"rates": [{"region": "*", "mode": "*", "vcpuHour": -1}]}`,
+		"malformed":      `{"rates": {`,
+	}
+	for name, content := range invalid {
+		writeRates(content)
+		if _, err := load(); err == nil {
+			t.Errorf("Load() with %s error = nil", name)
+		}
+	}
+}
+
+func TestUsageConfig_BudgetFor(t *testing.T) {
+	os.Clearenv()
+	_ = os.Setenv("USAGE_BUDGET_TIERS", "free:5:10, pro:80:100,broken:1,enterprise:0:0")
+	usage := UsageConfig{SoftBudget: 40, HardBudget: 50, TierBudgets: loadTierBudgets()}
+
+	if len(usage.TierBudgets) != 3 {
+		t.Fatalf("loadTierBudgets() = %v, want 3 tiers (malformed entry skipped)", usage.TierBudgets)
+	}
+	if got := usage.BudgetFor("pro"); got.Soft != 80 || got.Hard != 100 {
+		t.Errorf("BudgetFor(pro) = %+v", got)
+	}
+	if got := usage.BudgetFor("enterprise"); got.Soft != 0 || got.Hard != 0 {
+		t.Errorf("BudgetFor(enterprise) = %+v, want no budget", got)
+	}
+	if got := usage.BudgetFor("team"); got.Soft != 40 || got.Hard != 50 {
+		t.Errorf("BudgetFor(team) = %+v, want the default budget", got)
+	}
+	if (UsageConfig{TierBudgets: map[string]BudgetConfig{"enterprise": {}}}).HasBudgets() {
+		t.Error("HasBudgets() = true without any budget")
+	}
+}
+
+func TestImageConfig_Reference(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    ImageConfig
+		registry string
+		want     string
+	}{
+		{name: "default registry", image: ImageConfig{Repository: "dev8-workspace", Tag: "node"}, registry: "dev8.azurecr.io", want: "dev8.azurecr.io/dev8-workspace:node"},
+		{name: "docker hub", image: ImageConfig{Repository: "vaibhavsing/dev8-workspace", Tag: "latest"}, want: "vaibhavsing/dev8-workspace:latest"},
+		{name: "own registry wins", image: ImageConfig{Registry: "ghcr.io", Repository: "dev8/full", Tag: "1.4"}, registry: "dev8.azurecr.io", want: "ghcr.io/dev8/full:1.4"},
+		{name: "digest", image: ImageConfig{Repository: "dev8-workspace", Tag: "go", Digest: "sha256:abc"}, want: "dev8-workspace@sha256:abc"},
+		{name: "no tag", image: ImageConfig{Repository: "dev8-workspace"}, want: "dev8-workspace:latest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.image.Reference(tt.registry); got != tt.want {
+				t.Errorf("Reference(%q) = %v, want %v", tt.registry, got, tt.want)
+			}
+		})
+	}
+}
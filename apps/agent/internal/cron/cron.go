@@ -0,0 +1,203 @@
+// Package cron parses five-field cron expressions and computes when they run.
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// Schedules name IANA time zones; the agent image may ship without zoneinfo
+	_ "time/tzdata"
+)
+
+// Expression is a parsed cron expression:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields take *, numbers, ranges (1-5), lists (1,15) and steps (*/15, 9-17/2).
+// Months and weekdays may be named (JAN, MON); Sunday is 0 or 7. As in Vixie cron,
+// a time matches when it matches either day field if both are restricted.
+// The descriptors @yearly, @monthly, @weekly, @daily and @hourly are accepted too.
+type Expression struct {
+	minute, hour, dom, month, dow bitset
+	domAny, dowAny                bool // The day field was *, so only the other one restricts days
+}
+
+type bitset uint64
+
+func (b bitset) has(n int) bool {
+	return b&(1<<uint(n)) != 0
+}
+
+// field describes the values one cron field accepts
+type field struct {
+	name     string
+	min, max int
+	names    []string // Names of min, min+1, ...
+}
+
+var (
+	minuteField = field{name: "minute", min: 0, max: 59}
+	hourField   = field{name: "hour", min: 0, max: 23}
+	domField    = field{name: "day-of-month", min: 1, max: 31}
+	monthField  = field{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}}
+	dowField    = field{name: "day-of-week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// searchLimit bounds how far Next looks ahead for expressions that rarely or never match
+const searchLimit = 5 * 366 * 24 * time.Hour
+
+// Parse parses a cron expression
+func Parse(spec string) (*Expression, error) {
+	spec = strings.TrimSpace(spec)
+	if expanded, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields (minute hour day-of-month month day-of-week), got %d", spec, len(fields))
+	}
+
+	var e Expression
+	var err error
+	if e.minute, err = parseField(fields[0], minuteField); err != nil {
+		return nil, err
+	}
+	if e.hour, err = parseField(fields[1], hourField); err != nil {
+		return nil, err
+	}
+	if e.dom, err = parseField(fields[2], domField); err != nil {
+		return nil, err
+	}
+	if e.month, err = parseField(fields[3], monthField); err != nil {
+		return nil, err
+	}
+	if e.dow, err = parseField(fields[4], dowField); err != nil {
+		return nil, err
+	}
+	if e.dow.has(7) {
+		e.dow = e.dow&^(1<<7) | 1
+	}
+	e.domAny = strings.HasPrefix(fields[2], "*")
+	e.dowAny = strings.HasPrefix(fields[4], "*")
+	return &e, nil
+}
+
+// parseField parses a comma-separated list of ranges with optional steps
+func parseField(value string, f field) (bitset, error) {
+	var set bitset
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step %q in %s field %q", stepPart, f.name, value)
+			}
+			step = n
+		}
+
+		var low, high int
+		switch {
+		case rangePart == "*":
+			low, high = f.min, f.max
+			if f.max == 7 {
+				high = 6 // Sunday is covered by 0
+			}
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if low, err = f.value(lowPart); err != nil {
+				return 0, err
+			}
+			if high, err = f.value(highPart); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, f.name)
+			}
+		default:
+			var err error
+			if low, err = f.value(rangePart); err != nil {
+				return 0, err
+			}
+			high = low
+			if hasStep {
+				high = f.max // 5/15 means 5-max/15
+			}
+		}
+
+		for n := low; n <= high; n += step {
+			set |= 1 << uint(n)
+		}
+	}
+	return set, nil
+}
+
+// value parses a number or name of the field
+func (f field) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < f.min || n > f.max {
+		return 0, fmt.Errorf("%s value %q must be between %d and %d", f.name, s, f.min, f.max)
+	}
+	return n, nil
+}
+
+// Next returns the first time after t the expression matches, read in t's location.
+// Wall times skipped by a daylight saving change do not match. It returns the zero
+// time if the expression does not match within five years (e.g. "0 0 30 2 *").
+func (e *Expression) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(searchLimit)
+
+	// Start at the next whole minute
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		var next time.Time
+		switch {
+		case !e.month.has(int(t.Month())):
+			next = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !e.dayMatches(t):
+			next = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !e.hour.has(t.Hour()):
+			next = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !e.minute.has(t.Minute()):
+			next = t.Add(time.Minute)
+		default:
+			return t
+		}
+
+		// Normalising a wall time in a daylight saving change may not move forward
+		if !next.After(t) {
+			next = t.Add(time.Minute)
+		}
+		t = next
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether t's day matches the day-of-month and day-of-week fields
+func (e *Expression) dayMatches(t time.Time) bool {
+	dom := e.dom.has(t.Day())
+	dow := e.dow.has(int(t.Weekday()))
+	if e.domAny || e.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
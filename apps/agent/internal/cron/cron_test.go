@@ -0,0 +1,128 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"* * * FOO *",
+		"@reboot",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) error = nil, want an error", spec)
+		}
+	}
+}
+
+func TestExpression_Next(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Fatalf("LoadLocation() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{
+			name: "later the same day",
+			spec: "45 8 * * *",
+			from: time.Date(2025, 3, 3, 7, 10, 30, 0, time.UTC),
+			want: time.Date(2025, 3, 3, 8, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "strictly after",
+			spec: "45 8 * * *",
+			from: time.Date(2025, 3, 3, 8, 45, 0, 0, time.UTC),
+			want: time.Date(2025, 3, 4, 8, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "weekdays skip the weekend",
+			spec: "0 19 * * MON-FRI",
+			from: time.Date(2025, 3, 7, 19, 0, 0, 0, time.UTC), // Friday
+			want: time.Date(2025, 3, 10, 19, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "steps",
+			spec: "*/20 9-17/4 * * *",
+			from: time.Date(2025, 3, 3, 13, 41, 0, 0, time.UTC),
+			want: time.Date(2025, 3, 3, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "either day field",
+			spec: "0 0 15 * SUN",
+			from: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC), // Monday
+			want: time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "sunday as 7",
+			spec: "0 0 * * 7",
+			from: time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2025, 3, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "descriptor",
+			spec: "@yearly",
+			from: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "leap day",
+			spec: "0 12 29 FEB *",
+			from: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "time zone",
+			spec: "45 8 * * *",
+			from: time.Date(2025, 3, 3, 0, 0, 0, 0, kolkata),
+			want: time.Date(2025, 3, 3, 3, 15, 0, 0, time.UTC),
+		},
+		{
+			name: "wall time kept across daylight saving",
+			spec: "45 8 * * *",
+			from: time.Date(2025, 3, 8, 9, 0, 0, 0, newYork), // The clocks go forward on March 9
+			want: time.Date(2025, 3, 9, 12, 45, 0, 0, time.UTC),
+		},
+		{
+			name: "skipped wall time does not match",
+			spec: "30 2 * * *",
+			from: time.Date(2025, 3, 8, 3, 0, 0, 0, newYork),
+			want: time.Date(2025, 3, 10, 6, 30, 0, 0, time.UTC),
+		},
+		{
+			name: "never",
+			spec: "0 0 30 2 *",
+			from: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := Parse(tt.spec)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.spec, err)
+			}
+			if got := expr.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
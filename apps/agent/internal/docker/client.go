@@ -0,0 +1,457 @@
+// Package docker is a minimal Docker Engine API client used by the local
+// deployment mode. It speaks plain HTTP over the daemon's unix socket.
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiVersion is the oldest Engine API version offering everything used here (Docker 20.10+)
+const apiVersion = "v1.41"
+
+// ErrNotFound is returned when a container, volume or image does not exist
+var ErrNotFound = errors.New("not found")
+
+// IsNotFound reports whether err means the requested object does not exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// Client talks to a Docker Engine
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+}
+
+// NewClient creates a client for host, e.g. "unix:///var/run/docker.sock" or "tcp://127.0.0.1:2375"
+func NewClient(host string) (*Client, error) {
+	scheme, address, ok := strings.Cut(host, "://")
+	if !ok {
+		return nil, fmt.Errorf("invalid docker host %q (expected unix:// or tcp://)", host)
+	}
+
+	switch scheme {
+	case "unix":
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, "unix", address)
+			},
+		}
+		// The host part is ignored when dialing the socket
+		return &Client{httpClient: &http.Client{Transport: transport}, baseURL: "http://docker/" + apiVersion}, nil
+	case "tcp", "http":
+		return &Client{httpClient: &http.Client{}, baseURL: "http://" + address + "/" + apiVersion}, nil
+	default:
+		return nil, fmt.Errorf("unsupported docker host scheme %q", scheme)
+	}
+}
+
+// Ping checks that the daemon is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.do(ctx, http.MethodGet, "/_ping", nil, nil, nil)
+}
+
+// PortBinding is a host address a container port is published on.
+// An empty HostPort lets Docker pick a free port.
+type PortBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+// RestartPolicy controls whether Docker restarts an exited container
+type RestartPolicy struct {
+	Name string `json:"Name"`
+}
+
+// HostConfig is the subset of container host configuration the agent sets
+type HostConfig struct {
+	Binds         []string                 `json:"Binds,omitempty"`
+	ExtraHosts    []string                 `json:"ExtraHosts,omitempty"`
+	PortBindings  map[string][]PortBinding `json:"PortBindings,omitempty"`
+	NanoCPUs      int64                    `json:"NanoCpus,omitempty"`
+	Memory        int64                    `json:"Memory,omitempty"`
+	RestartPolicy RestartPolicy            `json:"RestartPolicy"`
+}
+
+// ContainerConfig is the subset of the container create request the agent sets
+type ContainerConfig struct {
+	Image        string              `json:"Image"`
+	Env          []string            `json:"Env,omitempty"`
+	Labels       map[string]string   `json:"Labels,omitempty"`
+	ExposedPorts map[string]struct{} `json:"ExposedPorts,omitempty"`
+	HostConfig   HostConfig          `json:"HostConfig"`
+}
+
+// Container describes an existing container
+type Container struct {
+	ID      string
+	Name    string
+	Running bool
+	Status  string            // created, running, exited, ...
+	Labels  map[string]string // Set at creation
+	Ports   map[int]int       // Container port -> published host port
+	Created time.Time
+}
+
+// CreateContainer creates (but does not start) a container and returns its ID
+func (c *Client) CreateContainer(ctx context.Context, name string, cfg ContainerConfig) (string, error) {
+	var resp struct {
+		ID string `json:"Id"`
+	}
+	query := url.Values{"name": {name}}
+	if err := c.do(ctx, http.MethodPost, "/containers/create", query, cfg, &resp); err != nil {
+		return "", fmt.Errorf("failed to create container %s: %w", name, err)
+	}
+	return resp.ID, nil
+}
+
+// StartContainer starts a container. Starting a running container is not an error.
+func (c *Client) StartContainer(ctx context.Context, name string) error {
+	if err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/start", nil, nil, nil); err != nil {
+		return fmt.Errorf("failed to start container %s: %w", name, err)
+	}
+	return nil
+}
+
+// StopContainer stops a container, killing it after timeout. Stopping a stopped container is not an error.
+func (c *Client) StopContainer(ctx context.Context, name string, timeout time.Duration) error {
+	query := url.Values{"t": {strconv.Itoa(int(timeout.Seconds()))}}
+	if err := c.do(ctx, http.MethodPost, "/containers/"+url.PathEscape(name)+"/stop", query, nil, nil); err != nil {
+		return fmt.Errorf("failed to stop container %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemoveContainer removes a container; force also removes it while running.
+// Its named volumes are kept.
+func (c *Client) RemoveContainer(ctx context.Context, name string, force bool) error {
+	query := url.Values{"force": {strconv.FormatBool(force)}}
+	if err := c.do(ctx, http.MethodDelete, "/containers/"+url.PathEscape(name), query, nil, nil); err != nil {
+		return fmt.Errorf("failed to remove container %s: %w", name, err)
+	}
+	return nil
+}
+
+// containerJSON is the part of the inspect response the agent reads
+type containerJSON struct {
+	ID      string `json:"Id"`
+	Name    string `json:"Name"`
+	Created string `json:"Created"`
+	State   struct {
+		Status  string `json:"Status"`
+		Running bool   `json:"Running"`
+	} `json:"State"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	NetworkSettings struct {
+		Ports map[string][]PortBinding `json:"Ports"`
+	} `json:"NetworkSettings"`
+}
+
+// InspectContainer returns a container's state and published ports
+func (c *Client) InspectContainer(ctx context.Context, name string) (*Container, error) {
+	var resp containerJSON
+	if err := c.do(ctx, http.MethodGet, "/containers/"+url.PathEscape(name)+"/json", nil, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to inspect container %s: %w", name, err)
+	}
+
+	container := &Container{
+		ID:      resp.ID,
+		Name:    strings.TrimPrefix(resp.Name, "/"),
+		Running: resp.State.Running,
+		Status:  resp.State.Status,
+		Labels:  resp.Config.Labels,
+		Ports:   make(map[int]int),
+	}
+	if created, err := time.Parse(time.RFC3339Nano, resp.Created); err == nil {
+		container.Created = created
+	}
+	for port, bindings := range resp.NetworkSettings.Ports {
+		containerPort, err := strconv.Atoi(strings.TrimSuffix(port, "/tcp"))
+		if err != nil || len(bindings) == 0 {
+			continue
+		}
+		if hostPort, err := strconv.Atoi(bindings[0].HostPort); err == nil {
+			container.Ports[containerPort] = hostPort
+		}
+	}
+
+	return container, nil
+}
+
+// ListContainers lists all containers (running or not) carrying label key=value
+func (c *Client) ListContainers(ctx context.Context, label string) ([]Container, error) {
+	filters, _ := json.Marshal(map[string][]string{"label": {label}})
+	query := url.Values{"all": {"true"}, "filters": {string(filters)}}
+
+	var resp []struct {
+		ID      string            `json:"Id"`
+		Names   []string          `json:"Names"`
+		State   string            `json:"State"`
+		Labels  map[string]string `json:"Labels"`
+		Created int64             `json:"Created"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/containers/json", query, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	containers := make([]Container, 0, len(resp))
+	for _, item := range resp {
+		container := Container{
+			ID:      item.ID,
+			Running: item.State == "running",
+			Status:  item.State,
+			Labels:  item.Labels,
+			Created: time.Unix(item.Created, 0),
+		}
+		if len(item.Names) > 0 {
+			container.Name = strings.TrimPrefix(item.Names[0], "/")
+		}
+		containers = append(containers, container)
+	}
+	return containers, nil
+}
+
+// Volume describes a named volume
+type Volume struct {
+	Name      string
+	Labels    map[string]string
+	CreatedAt time.Time
+}
+
+// CreateVolume creates a named local volume. Creating an existing volume is not an error.
+func (c *Client) CreateVolume(ctx context.Context, name string, labels map[string]string) error {
+	body := map[string]interface{}{"Name": name, "Driver": "local", "Labels": labels}
+	if err := c.do(ctx, http.MethodPost, "/volumes/create", nil, body, nil); err != nil {
+		return fmt.Errorf("failed to create volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// RemoveVolume deletes a named volume and its data. Removing a missing volume is not an error.
+func (c *Client) RemoveVolume(ctx context.Context, name string) error {
+	err := c.do(ctx, http.MethodDelete, "/volumes/"+url.PathEscape(name), nil, nil, nil)
+	if err != nil && !IsNotFound(err) {
+		return fmt.Errorf("failed to remove volume %s: %w", name, err)
+	}
+	return nil
+}
+
+// InspectVolume returns a named volume
+func (c *Client) InspectVolume(ctx context.Context, name string) (*Volume, error) {
+	var resp volumeJSON
+	if err := c.do(ctx, http.MethodGet, "/volumes/"+url.PathEscape(name), nil, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to inspect volume %s: %w", name, err)
+	}
+	volume := resp.toVolume()
+	return &volume, nil
+}
+
+// ListVolumes lists named volumes carrying label key=value
+func (c *Client) ListVolumes(ctx context.Context, label string) ([]Volume, error) {
+	filters, _ := json.Marshal(map[string][]string{"label": {label}})
+	query := url.Values{"filters": {string(filters)}}
+
+	var resp struct {
+		Volumes []volumeJSON `json:"Volumes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/volumes", query, nil, &resp); err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	volumes := make([]Volume, 0, len(resp.Volumes))
+	for _, item := range resp.Volumes {
+		volumes = append(volumes, item.toVolume())
+	}
+	return volumes, nil
+}
+
+// VolumeUsage returns the bytes stored in a named volume. The engine walks every
+// volume to answer, so this is slow on hosts with many large volumes.
+func (c *Client) VolumeUsage(ctx context.Context, name string) (int64, error) {
+	query := url.Values{"type": {"volume"}}
+
+	var resp struct {
+		Volumes []struct {
+			Name      string `json:"Name"`
+			UsageData struct {
+				Size int64 `json:"Size"`
+			} `json:"UsageData"`
+		} `json:"Volumes"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/system/df", query, nil, &resp); err != nil {
+		return 0, fmt.Errorf("failed to get disk usage: %w", err)
+	}
+
+	for _, volume := range resp.Volumes {
+		if volume.Name != name {
+			continue
+		}
+		// -1 means the engine has not computed the size
+		if volume.UsageData.Size < 0 {
+			return 0, nil
+		}
+		return volume.UsageData.Size, nil
+	}
+	return 0, fmt.Errorf("volume %s: %w", name, ErrNotFound)
+}
+
+type volumeJSON struct {
+	Name      string            `json:"Name"`
+	Labels    map[string]string `json:"Labels"`
+	CreatedAt string            `json:"CreatedAt"`
+}
+
+func (v volumeJSON) toVolume() Volume {
+	volume := Volume{Name: v.Name, Labels: v.Labels}
+	if created, err := time.Parse(time.RFC3339, v.CreatedAt); err == nil {
+		volume.CreatedAt = created
+	}
+	return volume
+}
+
+// RegistryAuth holds credentials for pulling from a private registry
+type RegistryAuth struct {
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	ServerAddress string `json:"serveraddress"`
+}
+
+// ImageExists reports whether image is available locally
+func (c *Client) ImageExists(ctx context.Context, image string) (bool, error) {
+	err := c.do(ctx, http.MethodGet, "/images/"+image+"/json", nil, nil, nil)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect image %s: %w", image, err)
+	}
+	return true, nil
+}
+
+// PullImage pulls image, blocking until the pull completes
+func (c *Client) PullImage(ctx context.Context, image string, auth *RegistryAuth) error {
+	ref, tag := image, "latest"
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		ref, tag = image[:i], image[i+1:]
+	}
+	query := url.Values{"fromImage": {ref}, "tag": {tag}}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/images/create", query, nil)
+	if err != nil {
+		return err
+	}
+	if auth != nil && auth.Username != "" {
+		encoded, err := json.Marshal(auth)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("X-Registry-Auth", base64.URLEncoding.EncodeToString(encoded))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", image, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkResponse(resp); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", image, err)
+	}
+
+	// The pull streams JSON progress messages; failures arrive as an "error" message
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &msg) == nil && msg.Error != "" {
+			return fmt.Errorf("failed to pull image %s: %s", image, msg.Error)
+		}
+	}
+	return scanner.Err()
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	target := c.baseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do sends a request and decodes a JSON response into out, if non-nil
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	req, err := c.newRequest(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// checkResponse converts Docker error responses into errors. 304 Not Modified
+// (already started/stopped) counts as success.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 || resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+
+	var apiErr struct {
+		Message string `json:"message"`
+	}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if json.Unmarshal(data, &apiErr) != nil || apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(data))
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %s", ErrNotFound, apiErr.Message)
+	}
+	return fmt.Errorf("docker API returned HTTP %d: %s", resp.StatusCode, apiErr.Message)
+}
@@ -0,0 +1,222 @@
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient("tcp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	return client
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		host    string
+		wantErr bool
+	}{
+		{host: "unix:///var/run/docker.sock"},
+		{host: "tcp://127.0.0.1:2375"},
+		{host: "/var/run/docker.sock", wantErr: true},
+		{host: "ssh://user@host", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewClient(tt.host); (err != nil) != tt.wantErr {
+			t.Errorf("NewClient(%q) error = %v, wantErr %v", tt.host, err, tt.wantErr)
+		}
+	}
+}
+
+func TestClient_CreateContainer(t *testing.T) {
+	var got ContainerConfig
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/"+apiVersion+"/containers/create" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if name := r.URL.Query().Get("name"); name != "docker-ws-1" {
+			t.Errorf("name = %q, want docker-ws-1", name)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"Id":"abc123"}`))
+	})
+
+	id, err := client.CreateContainer(context.Background(), "docker-ws-1", ContainerConfig{
+		Image:      "dev8-workspace:latest",
+		HostConfig: HostConfig{Binds: []string{"fs-ws-1:/home/dev8"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateContainer() error = %v", err)
+	}
+	if id != "abc123" {
+		t.Errorf("id = %q, want abc123", id)
+	}
+	if got.Image != "dev8-workspace:latest" || len(got.HostConfig.Binds) != 1 {
+		t.Errorf("request body = %+v", got)
+	}
+}
+
+func TestClient_InspectContainer(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{
+			"Id": "abc123",
+			"Name": "/docker-ws-1",
+			"Created": "2025-01-02T03:04:05.123456789Z",
+			"State": {"Status": "running", "Running": true},
+			"NetworkSettings": {"Ports": {
+				"8080/tcp": [{"HostIp": "127.0.0.1", "HostPort": "49153"}],
+				"2222/tcp": [{"HostIp": "127.0.0.1", "HostPort": "49154"}],
+				"9000/tcp": null
+			}}
+		}`))
+	})
+
+	container, err := client.InspectContainer(context.Background(), "docker-ws-1")
+	if err != nil {
+		t.Fatalf("InspectContainer() error = %v", err)
+	}
+	if container.Name != "docker-ws-1" || !container.Running {
+		t.Errorf("container = %+v", container)
+	}
+	if container.Ports[8080] != 49153 || container.Ports[2222] != 49154 {
+		t.Errorf("Ports = %v", container.Ports)
+	}
+	if _, ok := container.Ports[9000]; ok {
+		t.Error("unpublished port 9000 should not be mapped")
+	}
+	if container.Created.IsZero() {
+		t.Error("Created should be parsed")
+	}
+}
+
+func TestClient_Errors(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/start"):
+			w.WriteHeader(http.StatusNotModified) // Already running
+		case strings.HasPrefix(r.URL.Path, "/"+apiVersion+"/volumes/"):
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"get fs-ws-1: no such volume"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"message":"boom"}`))
+		}
+	})
+	ctx := context.Background()
+
+	if err := client.StartContainer(ctx, "docker-ws-1"); err != nil {
+		t.Errorf("StartContainer() on running container error = %v", err)
+	}
+	if _, err := client.InspectVolume(ctx, "fs-ws-1"); !IsNotFound(err) {
+		t.Errorf("InspectVolume() error = %v, want not found", err)
+	}
+	if err := client.RemoveVolume(ctx, "fs-ws-1"); err != nil {
+		t.Errorf("RemoveVolume() on missing volume error = %v", err)
+	}
+	err := client.StopContainer(ctx, "docker-ws-1", time.Second)
+	if err == nil || IsNotFound(err) || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("StopContainer() error = %v, want server error", err)
+	}
+}
+
+func TestClient_PullImage(t *testing.T) {
+	tests := []struct {
+		name     string
+		image    string
+		stream   string
+		wantFrom string
+		wantTag  string
+		wantErr  bool
+	}{
+		{
+			name:     "tagged image",
+			image:    "vaibhavsing/dev8-workspace:1.1",
+			stream:   `{"status":"Pulling"}` + "\n" + `{"status":"Done"}`,
+			wantFrom: "vaibhavsing/dev8-workspace",
+			wantTag:  "1.1",
+		},
+		{
+			name:     "registry with port and no tag",
+			image:    "localhost:5000/dev8-workspace",
+			stream:   `{"status":"Done"}`,
+			wantFrom: "localhost:5000/dev8-workspace",
+			wantTag:  "latest",
+		},
+		{
+			name:     "error in stream",
+			image:    "dev8-workspace:missing",
+			stream:   `{"status":"Pulling"}` + "\n" + `{"error":"manifest unknown"}`,
+			wantFrom: "dev8-workspace",
+			wantTag:  "missing",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if from := r.URL.Query().Get("fromImage"); from != tt.wantFrom {
+					t.Errorf("fromImage = %q, want %q", from, tt.wantFrom)
+				}
+				if tag := r.URL.Query().Get("tag"); tag != tt.wantTag {
+					t.Errorf("tag = %q, want %q", tag, tt.wantTag)
+				}
+				_, _ = w.Write([]byte(tt.stream))
+			})
+
+			err := client.PullImage(context.Background(), tt.image, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("PullImage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_VolumeUsage(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/"+apiVersion+"/system/df" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"Volumes": [
+			{"Name": "fs-ws-1", "UsageData": {"Size": 2048, "RefCount": 1}},
+			{"Name": "fs-ws-2", "UsageData": {"Size": -1, "RefCount": -1}}
+		]}`))
+	})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		want     int64
+		notFound bool
+	}{
+		{name: "fs-ws-1", want: 2048},
+		{name: "fs-ws-2", want: 0},
+		{name: "fs-ws-3", notFound: true},
+	}
+
+	for _, tt := range tests {
+		got, err := client.VolumeUsage(ctx, tt.name)
+		if tt.notFound {
+			if !IsNotFound(err) {
+				t.Errorf("VolumeUsage(%q) error = %v, want not found", tt.name, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("VolumeUsage(%q) = %d, %v, want %d", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/services"
+)
+
+// AdminHandler handles operator-only maintenance requests
+type AdminHandler struct {
+	reconciler *services.Reconciler
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(reconciler *services.Reconciler) *AdminHandler {
+	return &AdminHandler{
+		reconciler: reconciler,
+	}
+}
+
+// ListOrphans handles GET /api/v1/admin/orphans
+// Scans for orphaned resources without deleting anything.
+func (h *AdminHandler) ListOrphans(w http.ResponseWriter, r *http.Request) {
+	report := h.reconciler.Scan(r.Context())
+	respondWithSuccess(w, http.StatusOK, "Orphaned resources scanned", report)
+}
+
+// Reconcile handles POST /api/v1/admin/reconcile
+// Applies the configured policy; ?dryRun=true|false overrides RECONCILER_DRY_RUN.
+func (h *AdminHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	dryRun := h.reconciler.DryRunByDefault()
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			handleServiceError(w, models.ErrInvalidRequest(fmt.Sprintf("invalid dryRun value: %s", value)))
+			return
+		}
+		dryRun = parsed
+	}
+
+	report := h.reconciler.Reconcile(r.Context(), dryRun)
+	respondWithSuccess(w, http.StatusOK, "Reconciliation completed", report)
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// RotateCredentials handles POST /api/v1/environments/{id}/credentials/rotate
+func (h *EnvironmentHandler) RotateCredentials(w http.ResponseWriter, r *http.Request) {
+	// The body is optional; without one the password and SSH key are rotated
+	var req models.RotateCredentialsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+
+	envID := mux.Vars(r)["id"]
+	if req.WorkspaceID != "" && req.WorkspaceID != envID {
+		handleServiceError(w, models.ErrInvalidRequest("workspaceId in payload does not match route parameter"))
+		return
+	}
+	req.WorkspaceID = envID
+
+	op, err := h.service.RotateCredentialsAsync(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithOperation(w, "Credential rotation initiated", op)
+}
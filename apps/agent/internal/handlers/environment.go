@@ -2,9 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/services"
@@ -36,32 +40,63 @@ func (h *EnvironmentHandler) CreateEnvironment(w http.ResponseWriter, r *http.Re
 		req.UserID = "default-user"
 	}
 
-	env, err := h.service.CreateEnvironment(r.Context(), &req)
+	op, err := h.service.CreateEnvironmentAsync(r.Context(), &req)
 	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
 
-	respondWithSuccess(w, http.StatusCreated, "Workspace created successfully", map[string]interface{}{
-		"environment": env,
-		"message":     "Your development environment is ready to use",
-	})
+	respondWithOperation(w, "Workspace creation started", op)
 }
 
 // GetEnvironment handles GET /api/v1/environments/{id}
 func (h *EnvironmentHandler) GetEnvironment(w http.ResponseWriter, r *http.Request) {
-	// GetEnvironment is removed - Next.js is the source of truth
-	// Agent is stateless and doesn't store environment data
-	err := models.ErrInvalidRequest("Agent is stateless - query Next.js for environment details")
-	respondWithError(w, http.StatusNotImplemented, "Get Environment Not Supported", "This agent doesn't store state. Query Next.js API for environment details.", err)
+	envID := mux.Vars(r)["id"]
+
+	env, err := h.service.GetEnvironment(r.Context(), envID)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Workspace retrieved successfully", map[string]interface{}{
+		"environment": env,
+	})
 }
 
 // ListEnvironments handles GET /api/v1/environments
+// Supports ?userId=, ?region=, ?status=, ?page= and ?pageSize= query parameters.
 func (h *EnvironmentHandler) ListEnvironments(w http.ResponseWriter, r *http.Request) {
-	// ListEnvironments is removed - Next.js is the source of truth
-	// Agent is stateless and doesn't store environment data
-	err := models.ErrInvalidRequest("Agent is stateless - query Next.js for environment list")
-	respondWithError(w, http.StatusNotImplemented, "List Environments Not Supported", "This agent doesn't store state. Query Next.js API for environment list.", err)
+	query := r.URL.Query()
+
+	filter := services.EnvironmentFilter{
+		UserID: query.Get("userId"),
+		Region: query.Get("region"),
+		Status: models.EnvironmentStatus(strings.ToUpper(query.Get("status"))),
+	}
+
+	if filter.Status != "" && !filter.Status.IsValid() {
+		handleServiceError(w, models.ErrInvalidRequest(fmt.Sprintf("invalid status filter: %s", query.Get("status"))))
+		return
+	}
+
+	var err error
+	if filter.Page, err = parseIntParam(query.Get("page")); err != nil {
+		handleServiceError(w, models.ErrInvalidRequest("page must be a positive integer"))
+		return
+	}
+	if filter.PageSize, err = parseIntParam(query.Get("pageSize")); err != nil {
+		handleServiceError(w, models.ErrInvalidRequest("pageSize must be a positive integer"))
+		return
+	}
+
+	list, err := h.service.ListEnvironments(r.Context(), filter)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Workspaces retrieved successfully", list)
 }
 
 // StartEnvironment handles POST /api/v1/environments/start
@@ -77,16 +112,13 @@ func (h *EnvironmentHandler) StartEnvironment(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	env, err := h.service.StartEnvironment(r.Context(), &req)
+	op, err := h.service.StartEnvironmentAsync(r.Context(), &req)
 	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
 
-	respondWithSuccess(w, http.StatusOK, "Workspace started successfully", map[string]interface{}{
-		"environment": env,
-		"message":     "Your workspace is now running. All your files and settings have been preserved.",
-	})
+	respondWithOperation(w, "Workspace start initiated", op)
 }
 
 // StopEnvironment handles POST /api/v1/environments/stop
@@ -102,15 +134,60 @@ func (h *EnvironmentHandler) StopEnvironment(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	if err := h.service.StopEnvironment(r.Context(), req.WorkspaceID, req.CloudRegion); err != nil {
+	op, err := h.service.StopEnvironmentAsync(r.Context(), req.WorkspaceID, req.CloudRegion)
+	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
 
-	respondWithSuccess(w, http.StatusOK, "Workspace stopped successfully", map[string]interface{}{
-		"workspaceId": req.WorkspaceID,
-		"message":     "Workspace stopped and compute resources released. All your files are safely preserved. Restart anytime to resume work.",
-	})
+	respondWithOperation(w, "Workspace stop initiated", op)
+}
+
+// ResizeEnvironment handles PATCH /api/v1/environments/{id}
+func (h *EnvironmentHandler) ResizeEnvironment(w http.ResponseWriter, r *http.Request) {
+	var req models.ResizeEnvironmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+
+	envID := mux.Vars(r)["id"]
+	if req.WorkspaceID != "" && req.WorkspaceID != envID {
+		handleServiceError(w, models.ErrInvalidRequest("workspaceId in payload does not match route parameter"))
+		return
+	}
+	req.WorkspaceID = envID
+
+	op, err := h.service.ResizeEnvironmentAsync(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithOperation(w, "Workspace resize initiated", op)
+}
+
+// CloneEnvironment handles POST /api/v1/environments/{id}/clone
+func (h *EnvironmentHandler) CloneEnvironment(w http.ResponseWriter, r *http.Request) {
+	var req models.CloneEnvironmentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+	req.SourceWorkspaceID = mux.Vars(r)["id"]
+
+	// TODO: Extract user ID from authentication context
+	if req.UserID == "" {
+		req.UserID = "default-user"
+	}
+
+	op, err := h.service.CloneEnvironmentAsync(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithOperation(w, "Workspace clone started", op)
 }
 
 // ReportActivity handles POST /api/v1/environments/{id}/activity
@@ -154,19 +231,29 @@ func (h *EnvironmentHandler) DeleteEnvironment(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	if err := h.service.DeleteEnvironment(r.Context(), req.WorkspaceID, req.CloudRegion, req.Force); err != nil {
+	op, err := h.service.DeleteEnvironmentAsync(r.Context(), req.WorkspaceID, req.CloudRegion, req.Force)
+	if err != nil {
 		handleServiceError(w, err)
 		return
 	}
 
-	respondWithSuccess(w, http.StatusOK, "Workspace deleted permanently", map[string]interface{}{
-		"workspaceId": req.WorkspaceID,
-		"message":     "All data and resources have been permanently removed",
-	})
+	respondWithOperation(w, "Workspace deletion initiated", op)
 }
 
 // Helper functions
 
+// parseIntParam parses an optional positive integer query parameter (0 when absent)
+func parseIntParam(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("invalid integer parameter: %s", value)
+	}
+	return n, nil
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	response, err := json.Marshal(payload)
 	if err != nil {
@@ -198,21 +285,68 @@ func respondWithError(w http.ResponseWriter, code int, error string, message str
 	})
 }
 
+// respondWithOperation acknowledges an asynchronous operation with 202 Accepted
+// and points the caller at the operation resource to poll.
+func respondWithOperation(w http.ResponseWriter, message string, op *models.Operation) {
+	statusURL := fmt.Sprintf("/api/v1/operations/%s", op.ID)
+	w.Header().Set("Location", statusURL)
+	respondWithSuccess(w, http.StatusAccepted, message, map[string]interface{}{
+		"operationId": op.ID,
+		"workspaceId": op.WorkspaceID,
+		"statusUrl":   statusURL,
+		"operation":   op,
+	})
+}
+
 func handleServiceError(w http.ResponseWriter, err error) {
-	if appErr, ok := err.(*models.AppError); ok {
-		switch appErr.Code {
-		case "INVALID_REQUEST":
-			respondWithError(w, http.StatusBadRequest, "Invalid Request", appErr.Message, err)
-		case "NOT_FOUND":
-			respondWithError(w, http.StatusNotFound, "Resource Not Found", appErr.Message, err)
-		case "UNAUTHORIZED":
-			respondWithError(w, http.StatusUnauthorized, "Unauthorized", appErr.Message, err)
-		case "CONFLICT":
-			respondWithError(w, http.StatusConflict, "Conflict", appErr.Message, err)
-		default:
-			respondWithError(w, http.StatusInternalServerError, "Internal Server Error", "An unexpected error occurred. Please try again later.", err)
-		}
-	} else {
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) {
 		respondWithError(w, http.StatusInternalServerError, "Internal Server Error", "An unexpected error occurred. Please try again later.", err)
+		return
+	}
+
+	status, title := serviceErrorStatus(appErr.Code)
+	message := appErr.Message
+	if status == http.StatusInternalServerError {
+		message = "An unexpected error occurred. Please try again later."
+	}
+	if appErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+	}
+
+	log.Printf("❌ %s: %v", title, err)
+	respondWithJSON(w, status, models.ErrorResponse{
+		Success:   false,
+		Error:     title,
+		Message:   message,
+		Code:      fmt.Sprintf("ERR_%d", status),
+		Reason:    appErr.Reason,
+		Retryable: appErr.Retryable,
+	})
+}
+
+// serviceErrorStatus returns the HTTP status and title of an AppError code
+func serviceErrorStatus(code string) (int, string) {
+	switch code {
+	case "INVALID_REQUEST":
+		return http.StatusBadRequest, "Invalid Request"
+	case "NOT_FOUND":
+		return http.StatusNotFound, "Resource Not Found"
+	case "UNAUTHORIZED":
+		return http.StatusUnauthorized, "Unauthorized"
+	case "CONFLICT":
+		return http.StatusConflict, "Conflict"
+	case "THROTTLED":
+		return http.StatusTooManyRequests, "Too Many Requests"
+	case "QUOTA_EXCEEDED":
+		return http.StatusForbidden, "Quota Exceeded"
+	case "POLICY_VIOLATION":
+		return http.StatusForbidden, "Policy Violation"
+	case "IMAGE_PULL_FAILED":
+		return http.StatusBadGateway, "Image Pull Failed"
+	case "UNAVAILABLE":
+		return http.StatusServiceUnavailable, "Service Unavailable"
+	default:
+		return http.StatusInternalServerError, "Internal Server Error"
 	}
 }
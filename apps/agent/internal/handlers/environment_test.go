@@ -2,12 +2,17 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/services"
 	"github.com/gorilla/mux"
 )
 
@@ -80,9 +85,11 @@ func (e *testError) Error() string {
 
 func TestHandleServiceError(t *testing.T) {
 	tests := []struct {
-		name       string
-		err        error
-		wantStatus int
+		name           string
+		err            error
+		wantStatus     int
+		wantRetryAfter string
+		wantRetryable  bool
 	}{
 		{
 			name:       "invalid request error",
@@ -99,6 +106,46 @@ func TestHandleServiceError(t *testing.T) {
 			err:        &models.AppError{Code: "UNAUTHORIZED", Message: "unauthorized"},
 			wantStatus: http.StatusUnauthorized,
 		},
+		{
+			name:          "busy workspace",
+			err:           models.ErrBusy("workspace ws-1 is busy"),
+			wantStatus:    http.StatusConflict,
+			wantRetryable: true,
+		},
+		{
+			name:           "throttled",
+			err:            models.ErrThrottled("throttled", 1500*time.Millisecond),
+			wantStatus:     http.StatusTooManyRequests,
+			wantRetryAfter: "2",
+			wantRetryable:  true,
+		},
+		{
+			name:       "quota exceeded",
+			err:        models.ErrQuotaExceeded("quota exceeded"),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "policy violation",
+			err:        models.ErrPolicyViolation(models.PolicyMaxRunning, "tier 'free' allows 1 running workspaces"),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "image pull failed",
+			err:        models.ErrImagePull("image pull failed"),
+			wantStatus: http.StatusBadGateway,
+		},
+		{
+			name:           "unavailable",
+			err:            models.ErrUnavailable("circuit open", 30*time.Second),
+			wantStatus:     http.StatusServiceUnavailable,
+			wantRetryAfter: "30",
+			wantRetryable:  true,
+		},
+		{
+			name:       "wrapped app error",
+			err:        fmt.Errorf("workspace ws-1: %w", models.ErrNotFound("not found")),
+			wantStatus: http.StatusNotFound,
+		},
 		{
 			name:       "generic error",
 			err:        &testError{msg: "generic error"},
@@ -114,14 +161,23 @@ func TestHandleServiceError(t *testing.T) {
 			if w.Code != tt.wantStatus {
 				t.Errorf("handleServiceError() status = %v, want %v", w.Code, tt.wantStatus)
 			}
+			if got := w.Header().Get("Retry-After"); got != tt.wantRetryAfter {
+				t.Errorf("handleServiceError() Retry-After = %q, want %q", got, tt.wantRetryAfter)
+			}
+			var response models.ErrorResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if response.Retryable != tt.wantRetryable {
+				t.Errorf("handleServiceError() retryable = %v, want %v", response.Retryable, tt.wantRetryable)
+			}
 		})
 	}
 }
 
 // Need to import models package
 func TestEnvironmentHandler_Routes(t *testing.T) {
-	// Create a mock environment service (would need proper mocking in production)
-	handler := &EnvironmentHandler{}
+	handler := newTestEnvironmentHandler(t, services.NewMemoryEnvironmentStore())
 
 	tests := []struct {
 		name      string
@@ -174,6 +230,9 @@ func TestEnvironmentHandler_Routes(t *testing.T) {
 			// In a real test, you'd call the actual handler methods
 			if tt.method == "GET" && tt.path == "/api/v1/environments" {
 				handler.ListEnvironments(w, req)
+				if w.Code != http.StatusOK {
+					t.Errorf("ListEnvironments() status = %v, want %v", w.Code, http.StatusOK)
+				}
 			}
 		})
 	}
@@ -198,3 +257,170 @@ func TestRouteParameterExtraction(t *testing.T) {
 		t.Errorf("Route parameter extraction: got %v, want env-123", capturedID)
 	}
 }
+
+// newTestEnvironmentHandler builds a handler backed by store and no cloud clients
+func newTestEnvironmentHandler(t *testing.T, store services.EnvironmentStore) *EnvironmentHandler {
+	t.Helper()
+	services.RegisterProvider("fake", func(cfg *config.Config, clients services.ProviderClients) (services.ContainerProvider, error) {
+		return services.NewFakeProvider(), nil
+	})
+	cfg := &config.Config{Azure: config.AzureConfig{DeploymentMode: "fake"}}
+	service, err := services.NewEnvironmentService(cfg, nil, services.NewOperationManager(time.Second, time.Hour), store)
+	if err != nil {
+		t.Fatalf("NewEnvironmentService() error = %v", err)
+	}
+	return NewEnvironmentHandler(service)
+}
+
+func TestEnvironmentHandler_GetAndList(t *testing.T) {
+	store := services.NewMemoryEnvironmentStore()
+	ctx := context.Background()
+	now := time.Now()
+	for i, env := range []models.Environment{
+		{ID: "ws-1", UserID: "alice", CloudRegion: "eastus", Status: models.StatusRunning},
+		{ID: "ws-2", UserID: "alice", CloudRegion: "westus", Status: models.StatusStopped},
+		{ID: "ws-3", UserID: "bob", CloudRegion: "eastus", Status: models.StatusRunning},
+	} {
+		env.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		if err := store.Put(ctx, &env); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	handler := newTestEnvironmentHandler(t, store)
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/environments", handler.ListEnvironments).Methods("GET")
+	router.HandleFunc("/api/v1/environments/{id}", handler.GetEnvironment).Methods("GET")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantTotal  int
+	}{
+		{name: "get existing", path: "/api/v1/environments/ws-1", wantStatus: http.StatusOK},
+		{name: "get missing", path: "/api/v1/environments/ws-404", wantStatus: http.StatusNotFound},
+		{name: "list all", path: "/api/v1/environments", wantStatus: http.StatusOK, wantTotal: 3},
+		{name: "filter by user", path: "/api/v1/environments?userId=alice", wantStatus: http.StatusOK, wantTotal: 2},
+		{name: "filter by region and status", path: "/api/v1/environments?region=eastus&status=running", wantStatus: http.StatusOK, wantTotal: 2},
+		{name: "invalid status", path: "/api/v1/environments?status=bogus", wantStatus: http.StatusBadRequest},
+		{name: "invalid page", path: "/api/v1/environments?page=0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v (body: %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			if tt.wantTotal > 0 {
+				var response struct {
+					Data models.EnvironmentListResponse `json:"data"`
+				}
+				if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+					t.Fatalf("invalid JSON: %v", err)
+				}
+				if response.Data.Total != tt.wantTotal {
+					t.Errorf("total = %d, want %d", response.Data.Total, tt.wantTotal)
+				}
+			}
+		})
+	}
+}
+
+func TestEnvironmentHandler_GetUsage(t *testing.T) {
+	handler := newTestEnvironmentHandler(t, services.NewMemoryEnvironmentStore())
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/usage", handler.GetUsage).Methods("GET")
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantTo     time.Time
+	}{
+		{name: "current month", path: "/api/v1/usage?userId=alice", wantStatus: http.StatusOK},
+		{name: "dates include the last day", path: "/api/v1/usage?from=2025-03-01&to=2025-03-31", wantStatus: http.StatusOK, wantTo: time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "times", path: "/api/v1/usage?from=2025-03-01T00:00:00Z&to=2025-03-01T12:00:00%2B01:00", wantStatus: http.StatusOK, wantTo: time.Date(2025, 3, 1, 11, 0, 0, 0, time.UTC)},
+		{name: "malformed from", path: "/api/v1/usage?from=March", wantStatus: http.StatusBadRequest},
+		{name: "to before from", path: "/api/v1/usage?from=2025-03-02&to=2025-03-01T00:00:00Z", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %v, want %v (body: %s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if tt.wantTo.IsZero() {
+				return
+			}
+			var response struct {
+				Data struct {
+					Usage models.UsageReport `json:"usage"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+				t.Fatalf("invalid JSON: %v", err)
+			}
+			if !response.Data.Usage.To.Equal(tt.wantTo) {
+				t.Errorf("to = %s, want %s", response.Data.Usage.To, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestEnvironmentHandler_ResizeOverPolicy(t *testing.T) {
+	store := services.NewMemoryEnvironmentStore()
+	ctx := context.Background()
+	if err := store.Put(ctx, &models.Environment{
+		ID:          "ws-1",
+		UserID:      "alice",
+		CloudRegion: "eastus",
+		Status:      models.StatusRunning,
+		CPUCores:    1,
+		MemoryGB:    2,
+		StorageGB:   10,
+	}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	services.RegisterProvider("fake", func(cfg *config.Config, clients services.ProviderClients) (services.ContainerProvider, error) {
+		return services.NewFakeProvider(), nil
+	})
+	cfg := &config.Config{
+		Azure: config.AzureConfig{
+			DeploymentMode: "fake",
+			Regions:        []config.RegionConfig{{Name: "eastus", Location: "eastus", Enabled: true}},
+		},
+		Volumes: config.VolumeConfig{Backend: "local", LocalDir: t.TempDir()},
+		Policy: config.PolicyConfig{
+			DefaultTier: "free",
+			Tiers:       map[string]config.TierPolicy{"free": {MaxCPUCores: 2}},
+		},
+	}
+	service, err := services.NewEnvironmentService(cfg, nil, services.NewOperationManager(time.Second, time.Hour), store)
+	if err != nil {
+		t.Fatalf("NewEnvironmentService() error = %v", err)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/environments/{id}", NewEnvironmentHandler(service).ResizeEnvironment).Methods("PATCH")
+
+	req := httptest.NewRequest("PATCH", "/api/v1/environments/ws-1", bytes.NewBufferString(`{"cpuCores": 4}`))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %v, want %v (body: %s)", w.Code, http.StatusForbidden, w.Body.String())
+	}
+	if stored, _ := store.Get(ctx, "ws-1"); stored.CPUCores != 1 {
+		t.Errorf("stored CPU after a rejected resize = %d, want 1", stored.CPUCores)
+	}
+}
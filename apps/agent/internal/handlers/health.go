@@ -7,23 +7,37 @@ import (
 
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/docker"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/kube"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/logger"
+	"k8s.io/client-go/kubernetes"
 )
 
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	startTime   time.Time
-	azureClient *azure.Client
-	config      *config.Config
+	startTime    time.Time
+	azureClient  *azure.Client
+	dockerClient *docker.Client       // Set in docker mode instead of azureClient
+	kubeClient   kubernetes.Interface // Set in kubernetes mode instead of azureClient
+	config       *config.Config
 }
 
 // NewHealthHandler creates a new health handler
 func NewHealthHandler(azureClient *azure.Client, cfg *config.Config) *HealthHandler {
-	return &HealthHandler{
+	h := &HealthHandler{
 		startTime:   time.Now(),
 		azureClient: azureClient,
 		config:      cfg,
 	}
+	if cfg.Azure.DeploymentMode == "docker" {
+		// Config validation guarantees a usable host; a bad one surfaces as an unhealthy check
+		h.dockerClient, _ = docker.NewClient(cfg.Docker.Host)
+	}
+	if cfg.Azure.DeploymentMode == "kubernetes" {
+		// An unreachable cluster surfaces as an unhealthy check rather than a startup failure
+		h.kubeClient, _ = kube.NewClientset(cfg.Kubernetes.Kubeconfig, cfg.Kubernetes.Context)
+	}
+	return h
 }
 
 // HealthCheck handles GET /health with dependency checks
@@ -31,14 +45,14 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	uptime := time.Since(h.startTime)
 	ctx := r.Context()
 
-	// Check Azure connectivity
-	azureStatus := h.checkAzureConnectivity(ctx)
+	// Check Azure (or Docker Engine / Kubernetes cluster) connectivity
+	backend, backendStatus := h.checkBackend(ctx)
 
 	// Overall health status
 	overallStatus := "healthy"
 	statusCode := http.StatusOK
 
-	if !azureStatus {
+	if !backendStatus {
 		overallStatus = "degraded"
 		statusCode = http.StatusServiceUnavailable
 	}
@@ -49,8 +63,8 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		"service": "dev8-agent",
 		"version": "2.0.0",
 		"checks": map[string]any{
-			"azure": map[string]any{
-				"status": getStatusString(azureStatus),
+			backend: map[string]any{
+				"status": getStatusString(backendStatus),
 			},
 		},
 		"timestamp": time.Now().UTC().Format(time.RFC3339),
@@ -61,10 +75,9 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 func (h *HealthHandler) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	// Check Azure connectivity
-	azureReady := h.checkAzureConnectivity(ctx)
+	// Check Azure (or Docker Engine / Kubernetes cluster) connectivity
+	backend, ready := h.checkBackend(ctx)
 
-	ready := azureReady
 	statusCode := http.StatusOK
 	if !ready {
 		statusCode = http.StatusServiceUnavailable
@@ -73,7 +86,7 @@ func (h *HealthHandler) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
 	respondWithJSON(w, statusCode, map[string]any{
 		"status": getStatusString(ready),
 		"checks": map[string]any{
-			"azure": getStatusString(azureReady),
+			backend: getStatusString(ready),
 		},
 	})
 }
@@ -85,6 +98,43 @@ func (h *HealthHandler) LivenessCheck(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// checkBackend checks the deployment backend and returns its name for the checks map
+func (h *HealthHandler) checkBackend(ctx context.Context) (string, bool) {
+	switch h.config.Azure.DeploymentMode {
+	case "docker":
+		return "docker", h.checkDockerConnectivity(ctx)
+	case "kubernetes":
+		return "kubernetes", h.checkKubernetesConnectivity(ctx)
+	}
+	return "azure", h.checkAzureConnectivity(ctx)
+}
+
+// checkDockerConnectivity checks if the local Docker Engine is reachable
+func (h *HealthHandler) checkDockerConnectivity(ctx context.Context) bool {
+	if h.dockerClient == nil {
+		return false
+	}
+	if err := h.dockerClient.Ping(ctx); err != nil {
+		log := logger.FromContext(ctx)
+		log.Warn().Err(err).Msg("Docker connectivity check failed")
+		return false
+	}
+	return true
+}
+
+// checkKubernetesConnectivity checks if the cluster API server is reachable
+func (h *HealthHandler) checkKubernetesConnectivity(ctx context.Context) bool {
+	if h.kubeClient == nil {
+		return false
+	}
+	if _, err := h.kubeClient.Discovery().ServerVersion(); err != nil {
+		log := logger.FromContext(ctx)
+		log.Warn().Err(err).Msg("Kubernetes connectivity check failed")
+		return false
+	}
+	return true
+}
+
 // checkAzureConnectivity checks if Azure services are accessible
 func (h *HealthHandler) checkAzureConnectivity(ctx context.Context) bool {
 	// Try to check connectivity by querying a region
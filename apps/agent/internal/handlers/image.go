@@ -0,0 +1,16 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// ListImages handles GET /api/v1/images
+// The optional region query parameter returns the references pulled in that region.
+func (h *EnvironmentHandler) ListImages(w http.ResponseWriter, r *http.Request) {
+	images := h.service.ListImages(r.URL.Query().Get("region"))
+
+	respondWithSuccess(w, http.StatusOK, "Images retrieved successfully", map[string]interface{}{
+		"images": images,
+		"total":  len(images),
+	})
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/services"
+	"github.com/gorilla/mux"
+)
+
+// OperationHandler exposes the status of asynchronous lifecycle operations
+type OperationHandler struct {
+	operations *services.OperationManager
+}
+
+// NewOperationHandler creates a new operation handler
+func NewOperationHandler(operations *services.OperationManager) *OperationHandler {
+	return &OperationHandler{
+		operations: operations,
+	}
+}
+
+// GetOperation handles GET /api/v1/operations/{id}
+func (h *OperationHandler) GetOperation(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	op, err := h.operations.Get(id)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Operation retrieved successfully", op)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/services"
+	"github.com/gorilla/mux"
+)
+
+func TestOperationHandler_GetOperation(t *testing.T) {
+	operations := services.NewOperationManager(time.Second, time.Hour)
+	op := operations.Submit(models.OperationStop, "ws-123", func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/operations/{id}", NewOperationHandler(operations).GetOperation)
+
+	tests := []struct {
+		name       string
+		id         string
+		wantStatus int
+	}{
+		{name: "existing operation", id: op.ID, wantStatus: http.StatusOK},
+		{name: "unknown operation", id: "missing", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/operations/"+tt.id, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("GetOperation() status = %v, want %v", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRespondWithOperation(t *testing.T) {
+	op := &models.Operation{ID: "op-1", WorkspaceID: "ws-123", Phase: models.OperationPending}
+
+	w := httptest.NewRecorder()
+	respondWithOperation(w, "Workspace creation started", op)
+
+	if w.Code != http.StatusAccepted {
+		t.Errorf("respondWithOperation() status = %v, want %v", w.Code, http.StatusAccepted)
+	}
+	if loc := w.Header().Get("Location"); loc != "/api/v1/operations/op-1" {
+		t.Errorf("respondWithOperation() Location = %q", loc)
+	}
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if response.Data["operationId"] != "op-1" {
+		t.Errorf("operationId = %v, want op-1", response.Data["operationId"])
+	}
+}
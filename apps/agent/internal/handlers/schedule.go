@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// CreateSchedule handles POST /api/v1/environments/{id}/schedules
+func (h *EnvironmentHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req models.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+
+	schedule, err := h.service.CreateSchedule(r.Context(), mux.Vars(r)["id"], &req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusCreated, "Schedule created successfully", map[string]interface{}{
+		"schedule": schedule,
+	})
+}
+
+// ListSchedules handles GET /api/v1/environments/{id}/schedules
+func (h *EnvironmentHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	schedules, err := h.service.ListSchedules(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Schedules retrieved successfully", map[string]interface{}{
+		"schedules": schedules,
+		"total":     len(schedules),
+	})
+}
+
+// GetSchedule handles GET /api/v1/environments/{id}/schedules/{scheduleId}
+func (h *EnvironmentHandler) GetSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	schedule, err := h.service.GetSchedule(r.Context(), vars["id"], vars["scheduleId"])
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Schedule retrieved successfully", map[string]interface{}{
+		"schedule": schedule,
+	})
+}
+
+// UpdateSchedule handles PUT /api/v1/environments/{id}/schedules/{scheduleId}
+func (h *EnvironmentHandler) UpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req models.ScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	schedule, err := h.service.UpdateSchedule(r.Context(), vars["id"], vars["scheduleId"], &req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Schedule updated successfully", map[string]interface{}{
+		"schedule": schedule,
+	})
+}
+
+// DeleteSchedule handles DELETE /api/v1/environments/{id}/schedules/{scheduleId}
+func (h *EnvironmentHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.service.DeleteSchedule(r.Context(), vars["id"], vars["scheduleId"]); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Schedule deleted successfully", map[string]interface{}{
+		"workspaceId": vars["id"],
+		"scheduleId":  vars["scheduleId"],
+	})
+}
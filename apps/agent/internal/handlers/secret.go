@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// GetSecrets handles GET /api/v1/environments/{id}/secrets
+// Only the names of the saved secrets are returned, never their values.
+func (h *EnvironmentHandler) GetSecrets(w http.ResponseWriter, r *http.Request) {
+	info, err := h.service.GetSecrets(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Secrets retrieved successfully", map[string]interface{}{
+		"secrets": info,
+	})
+}
+
+// UpdateSecrets handles PATCH /api/v1/environments/{id}/secrets
+func (h *EnvironmentHandler) UpdateSecrets(w http.ResponseWriter, r *http.Request) {
+	var update models.WorkspaceSecrets
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+
+	info, err := h.service.UpdateSecrets(r.Context(), mux.Vars(r)["id"], &update)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Secrets updated successfully", map[string]interface{}{
+		"secrets": info,
+	})
+}
+
+// DeleteSecrets handles DELETE /api/v1/environments/{id}/secrets
+func (h *EnvironmentHandler) DeleteSecrets(w http.ResponseWriter, r *http.Request) {
+	envID := mux.Vars(r)["id"]
+	if err := h.service.DeleteSecrets(r.Context(), envID); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Secrets deleted successfully", map[string]interface{}{
+		"workspaceId": envID,
+	})
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/gorilla/mux"
+)
+
+// CreateSnapshot handles POST /api/v1/environments/{id}/snapshots
+func (h *EnvironmentHandler) CreateSnapshot(w http.ResponseWriter, r *http.Request) {
+	// The body is optional; it only carries a label
+	var req models.CreateSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+
+	envID := mux.Vars(r)["id"]
+	if req.WorkspaceID != "" && req.WorkspaceID != envID {
+		handleServiceError(w, models.ErrInvalidRequest("workspaceId in payload does not match route parameter"))
+		return
+	}
+	req.WorkspaceID = envID
+
+	snapshot, err := h.service.CreateSnapshot(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusCreated, "Snapshot created successfully", map[string]interface{}{
+		"snapshot": snapshot,
+	})
+}
+
+// ListSnapshots handles GET /api/v1/environments/{id}/snapshots
+func (h *EnvironmentHandler) ListSnapshots(w http.ResponseWriter, r *http.Request) {
+	snapshots, err := h.service.ListSnapshots(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Snapshots retrieved successfully", map[string]interface{}{
+		"snapshots": snapshots,
+		"total":     len(snapshots),
+	})
+}
+
+// DeleteSnapshot handles DELETE /api/v1/environments/{id}/snapshots/{snapshotId}
+func (h *EnvironmentHandler) DeleteSnapshot(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	if err := h.service.DeleteSnapshot(r.Context(), vars["id"], vars["snapshotId"]); err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Snapshot deleted successfully", map[string]interface{}{
+		"workspaceId": vars["id"],
+		"snapshotId":  vars["snapshotId"],
+	})
+}
+
+// RestoreSnapshot handles POST /api/v1/environments/{id}/snapshots/{snapshotId}/restore
+func (h *EnvironmentHandler) RestoreSnapshot(w http.ResponseWriter, r *http.Request) {
+	var req models.RestoreSnapshotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	req.WorkspaceID = vars["id"]
+	req.SnapshotID = vars["snapshotId"]
+
+	op, err := h.service.RestoreSnapshotAsync(r.Context(), &req)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithOperation(w, "Snapshot restore initiated", op)
+}
+
+// SetSnapshotRetention handles PUT /api/v1/environments/{id}/snapshot-retention
+// A body of null reverts the workspace to the agent default.
+func (h *EnvironmentHandler) SetSnapshotRetention(w http.ResponseWriter, r *http.Request) {
+	var retention *models.SnapshotRetention
+	if err := json.NewDecoder(r.Body).Decode(&retention); err != nil {
+		respondWithError(w, http.StatusBadRequest, "Invalid request body", "Please check your JSON payload", err)
+		return
+	}
+
+	env, err := h.service.SetSnapshotRetention(r.Context(), mux.Vars(r)["id"], retention)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Snapshot retention updated successfully", map[string]interface{}{
+		"environment": env,
+	})
+}
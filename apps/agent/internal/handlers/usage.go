@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// GetUsage handles GET /api/v1/usage
+// Supports ?userId=, ?from= and ?to= query parameters. from and to are RFC 3339
+// times or YYYY-MM-DD dates in UTC; a to date includes that whole day.
+func (h *EnvironmentHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	from, err := parseUsageTime(query.Get("from"), false)
+	if err != nil {
+		handleServiceError(w, models.ErrInvalidRequest(fmt.Sprintf("from: %v", err)))
+		return
+	}
+	to, err := parseUsageTime(query.Get("to"), true)
+	if err != nil {
+		handleServiceError(w, models.ErrInvalidRequest(fmt.Sprintf("to: %v", err)))
+		return
+	}
+
+	report, err := h.service.GetUsage(r.Context(), query.Get("userId"), from, to)
+	if err != nil {
+		handleServiceError(w, err)
+		return
+	}
+
+	respondWithSuccess(w, http.StatusOK, "Usage retrieved successfully", map[string]interface{}{
+		"usage": report,
+	})
+}
+
+// parseUsageTime parses an optional RFC 3339 time or YYYY-MM-DD date (zero when
+// absent). With endOfDay a date means the end of that day.
+func parseUsageTime(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a YYYY-MM-DD date", value)
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
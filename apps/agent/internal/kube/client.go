@@ -0,0 +1,36 @@
+// Package kube connects to the Kubernetes cluster used by the kubernetes deployment mode.
+package kube
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// NewClientset connects with the kubeconfig file at path, or with the pod's
+// service account when path is empty. An empty context uses the current one.
+func NewClientset(path, context string) (kubernetes.Interface, error) {
+	var (
+		restConfig *rest.Config
+		err        error
+	)
+	if path == "" {
+		restConfig, err = rest.InClusterConfig()
+	} else {
+		restConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			&clientcmd.ClientConfigLoadingRules{ExplicitPath: path},
+			&clientcmd.ConfigOverrides{CurrentContext: context},
+		).ClientConfig()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kubernetes configuration: %w", err)
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %w", err)
+	}
+	return clientset, nil
+}
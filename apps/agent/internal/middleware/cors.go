@@ -26,7 +26,7 @@ func CORSMiddleware(allowedOrigins []string) func(http.Handler) http.Handler {
 			// If no origins configured or not allowed, deny all (secure default)
 
 			// Set other CORS headers
-			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
 			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 			w.Header().Set("Access-Control-Max-Age", "3600")
 
@@ -0,0 +1,234 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/logger"
+)
+
+const (
+	// IdempotencyKeyHeader carries a client-chosen key that identifies one logical request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader is set on responses replayed from an earlier request
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	maxIdempotentBodyBytes  = 1 << 20 // Larger bodies are not buffered and the key is ignored
+)
+
+// replayedHeaders are the response headers stored with an idempotent response
+var replayedHeaders = []string{"Content-Type", "Location"}
+
+// Idempotency makes mutating requests safe to retry: a request carrying an
+// Idempotency-Key gets the stored response of the first request with that key instead
+// of running again. Responses are kept in memory for a TTL; server errors are not
+// kept, so a failed request can be retried.
+type Idempotency struct {
+	mu      sync.Mutex
+	entries map[string]*idempotentEntry
+	ttl     time.Duration
+	now     func() time.Time
+}
+
+type idempotentEntry struct {
+	fingerprint string // Method, path and body of the first request
+	done        bool   // False while the first request runs
+	status      int
+	header      http.Header
+	body        []byte
+	expires     time.Time
+}
+
+// NewIdempotency creates the middleware, keeping responses for ttl
+func NewIdempotency(ttl time.Duration) *Idempotency {
+	return &Idempotency{
+		entries: make(map[string]*idempotentEntry),
+		ttl:     ttl,
+		now:     time.Now,
+	}
+}
+
+// Middleware replays stored responses of POST, PUT, PATCH and DELETE requests
+func (i *Idempotency) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || !isMutatingMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			writeIdempotencyError(w, http.StatusBadRequest, "Invalid Idempotency-Key", "Idempotency-Key must be at most 255 characters.")
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxIdempotentBodyBytes+1))
+		if err != nil {
+			writeIdempotencyError(w, http.StatusBadRequest, "Invalid request body", "The request body could not be read.")
+			return
+		}
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		if len(body) > maxIdempotentBodyBytes {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Keys are scoped to the caller's credentials, so clients cannot see each other's responses
+		scope := sha256.Sum256([]byte(r.Header.Get("Authorization") + "\x00" + key))
+		entryKey := hex.EncodeToString(scope[:])
+		fingerprint := requestFingerprint(r, body)
+
+		entry, replay := i.begin(entryKey, fingerprint)
+		switch {
+		case replay && entry.fingerprint != fingerprint:
+			writeIdempotencyError(w, http.StatusUnprocessableEntity, "Idempotency-Key Reused",
+				"This Idempotency-Key was already used with a different request.")
+			return
+		case replay && !entry.done:
+			writeIdempotencyError(w, http.StatusConflict, "Request In Progress",
+				"A request with this Idempotency-Key is still being processed. Retry later.")
+			return
+		case replay:
+			log := logger.FromContext(r.Context())
+			log.Debug().
+				Str("method", r.Method).
+				Str("url", r.URL.String()).
+				Msg("Replaying idempotent response")
+			for name, values := range entry.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(entry.status)
+			_, _ = w.Write(entry.body)
+			return
+		}
+
+		recorder := &idempotentResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		finished := false
+		defer func() {
+			// A panicking handler must not hold the key forever
+			if !finished {
+				i.release(entryKey)
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+		finished = true
+		i.finish(entryKey, recorder)
+	})
+}
+
+// begin returns the entry for key, registering a new in-flight one if there is none.
+// replay is true when the entry belongs to an earlier request.
+func (i *Idempotency) begin(key, fingerprint string) (entry idempotentEntry, replay bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	i.pruneLocked(now)
+	if existing, ok := i.entries[key]; ok {
+		return *existing, true
+	}
+	i.entries[key] = &idempotentEntry{fingerprint: fingerprint, expires: now.Add(i.ttl)}
+	return idempotentEntry{}, false
+}
+
+// finish stores the response of the request holding key, or releases the key after a
+// server error so the request can be retried
+func (i *Idempotency) finish(key string, recorder *idempotentResponseWriter) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	entry, ok := i.entries[key]
+	if !ok {
+		return
+	}
+	if recorder.status >= http.StatusInternalServerError {
+		delete(i.entries, key)
+		return
+	}
+
+	entry.done = true
+	entry.status = recorder.status
+	entry.body = recorder.body.Bytes()
+	entry.header = make(http.Header)
+	for _, name := range replayedHeaders {
+		if values := recorder.Header().Values(name); len(values) > 0 {
+			entry.header[name] = values
+		}
+	}
+	entry.expires = i.now().Add(i.ttl)
+}
+
+// release forgets the key of a request that did not finish
+func (i *Idempotency) release(key string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	delete(i.entries, key)
+}
+
+// pruneLocked drops finished entries past their TTL; the caller holds i.mu
+func (i *Idempotency) pruneLocked(now time.Time) {
+	for key, entry := range i.entries {
+		if entry.done && now.After(entry.expires) {
+			delete(i.entries, key)
+		}
+	}
+}
+
+// idempotentResponseWriter passes the response through while recording it
+type idempotentResponseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rw *idempotentResponseWriter) WriteHeader(code int) {
+	if !rw.wroteHeader {
+		rw.status = code
+		rw.wroteHeader = true
+	}
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *idempotentResponseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+func requestFingerprint(r *http.Request, body []byte) string {
+	sum := sha256.New()
+	sum.Write([]byte(r.Method + " " + r.URL.RequestURI() + "\n"))
+	sum.Write(body)
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+func isMutatingMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func writeIdempotencyError(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	response := map[string]any{
+		"success": false,
+		"error":   title,
+		"message": message,
+		"code":    "ERR_" + strconv.Itoa(status),
+	}
+
+	_ = json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	status := http.StatusAccepted
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Location", "/api/v1/operations/op-1")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"operationId":"op-1"}`))
+	})
+	idempotency := NewIdempotency(time.Hour)
+	now := time.Now()
+	idempotency.now = func() time.Time { return now }
+	server := idempotency.Middleware(handler)
+
+	send := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/environments", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("key-1", `{"workspaceId":"ws-1"}`)
+	replay := send("key-1", `{"workspaceId":"ws-1"}`)
+	if calls != 1 {
+		t.Errorf("handler calls = %d, want 1", calls)
+	}
+	if replay.Code != http.StatusAccepted || replay.Body.String() != first.Body.String() ||
+		replay.Header().Get("Location") != "/api/v1/operations/op-1" || replay.Header().Get(IdempotentReplayedHeader) != "true" {
+		t.Errorf("replay = %d %q %v, want the first response", replay.Code, replay.Body.String(), replay.Header())
+	}
+
+	if w := send("key-1", `{"workspaceId":"ws-2"}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("reused key with another body = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if send("", `{"workspaceId":"ws-1"}`); calls != 2 {
+		t.Errorf("handler calls without a key = %d, want 2", calls)
+	}
+
+	// Entries expire after the TTL
+	now = now.Add(2 * time.Hour)
+	if send("key-1", `{"workspaceId":"ws-1"}`); calls != 3 {
+		t.Errorf("handler calls after TTL = %d, want 3", calls)
+	}
+
+	// Server errors are not stored, so the request can be retried
+	status = http.StatusInternalServerError
+	send("key-2", `{}`)
+	status = http.StatusAccepted
+	if w := send("key-2", `{}`); calls != 5 || w.Code != http.StatusAccepted {
+		t.Errorf("retry after a server error = %d with %d calls, want a new attempt", w.Code, calls)
+	}
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	idempotency := NewIdempotency(time.Hour)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	server := idempotency.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.WriteHeader(http.StatusAccepted)
+	}))
+
+	newRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/environments", strings.NewReader(`{}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		return req
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.ServeHTTP(httptest.NewRecorder(), newRequest())
+		close(done)
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, newRequest())
+	if w.Code != http.StatusConflict {
+		t.Errorf("concurrent retry = %d, want %d", w.Code, http.StatusConflict)
+	}
+	close(release)
+	<-done
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/gorilla/mux"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -78,10 +79,23 @@ func (rw *metricsResponseWriter) Write(b []byte) (int, error) {
 	return size, err
 }
 
-// MetricsMiddleware collects HTTP metrics
+// routeTemplate returns the path template of the route matched for r, e.g.
+// "/api/v1/environments/{id}", so workspace IDs don't become label values
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// MetricsMiddleware collects HTTP metrics, labelled by route template. Apply it with
+// Router.Use so the route is matched first.
 func MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		endpoint := routeTemplate(r)
 
 		// Increment active requests
 		activeRequests.Inc()
@@ -93,7 +107,7 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 		// Record request size
 		requestSize := float64(r.ContentLength)
 		if requestSize > 0 {
-			httpRequestSize.WithLabelValues(r.Method, r.URL.Path).Observe(requestSize)
+			httpRequestSize.WithLabelValues(r.Method, endpoint).Observe(requestSize)
 		}
 
 		// Process request
@@ -103,8 +117,8 @@ func MetricsMiddleware(next http.Handler) http.Handler {
 		duration := time.Since(start).Seconds()
 		statusCode := strconv.Itoa(mw.statusCode)
 
-		httpRequestsTotal.WithLabelValues(r.Method, r.URL.Path, statusCode).Inc()
-		httpRequestDuration.WithLabelValues(r.Method, r.URL.Path, statusCode).Observe(duration)
-		httpResponseSize.WithLabelValues(r.Method, r.URL.Path, statusCode).Observe(float64(mw.size))
+		httpRequestsTotal.WithLabelValues(r.Method, endpoint, statusCode).Inc()
+		httpRequestDuration.WithLabelValues(r.Method, endpoint, statusCode).Observe(duration)
+		httpResponseSize.WithLabelValues(r.Method, endpoint, statusCode).Observe(float64(mw.size))
 	})
 }
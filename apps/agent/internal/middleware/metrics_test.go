@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsMiddleware_LabelsByRouteTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware)
+	api := router.PathPrefix("/api/v1").Subrouter()
+	api.HandleFunc("/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}).Methods("POST")
+
+	for _, id := range []string{"ws-1", "ws-2", "ws-3"} {
+		req := httptest.NewRequest("POST", "/api/v1/metrics-test/"+id, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if got := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("POST", "/api/v1/metrics-test/{id}", "202")); got != 3 {
+		t.Errorf("requests for the route template = %v, want 3", got)
+	}
+	if got := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("POST", "/api/v1/metrics-test/ws-1", "202")); got != 0 {
+		t.Errorf("requests for the raw path = %v, want 0", got)
+	}
+}
+
+func TestMetricsMiddleware_Unmatched(t *testing.T) {
+	handler := MetricsMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/no/route/here", nil))
+
+	if got := testutil.ToFloat64(httpRequestsTotal.WithLabelValues("GET", "unmatched", "404")); got != 1 {
+		t.Errorf("unmatched requests = %v, want 1", got)
+	}
+}
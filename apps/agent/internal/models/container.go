@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// EnvVar is a user environment variable of a workspace container
+type EnvVar struct {
+	Name   string `json:"name"`
+	Value  string `json:"value,omitempty"`
+	Secret bool   `json:"secret,omitempty"` // Passed as a secure value and never stored or returned by the agent
+}
+
+// PortProtocol is the protocol of an exposed workspace port
+type PortProtocol string
+
+const (
+	PortProtocolHTTP PortProtocol = "http"
+	PortProtocolTCP  PortProtocol = "tcp"
+	PortProtocolUDP  PortProtocol = "udp"
+)
+
+// PortVisibility controls who can reach an exposed workspace port
+type PortVisibility string
+
+const (
+	PortPublic  PortVisibility = "public"  // Reachable from the internet
+	PortPrivate PortVisibility = "private" // Reachable only from inside the workspace's network
+)
+
+// PortSpec is a container port the workspace exposes in addition to code-server (8080),
+// SSH (2222) and the supervisor (9000)
+type PortSpec struct {
+	Port       int            `json:"port"`
+	Protocol   PortProtocol   `json:"protocol,omitempty"`   // Defaults to "http"
+	Visibility PortVisibility `json:"visibility,omitempty"` // Defaults to "public"
+}
+
+// Limits on user container settings. Azure Container Apps allows five additional
+// ports, two of which carry SSH and the supervisor.
+const (
+	MaxEnvVars = 50
+	MaxPorts   = 3
+)
+
+// envVarNamePattern matches POSIX environment variable names
+var envVarNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reservedEnvVars are set by the agent and cannot be overridden
+var reservedEnvVars = map[string]bool{
+	"WORKSPACE_ID": true, "USER_ID": true, "WORKSPACE_DIR": true,
+	"AGENT_BASE_URL": true, "AGENT_ENABLED": true, "MONITOR_INTERVAL": true, "LOG_FILE_PATH": true,
+	"GITHUB_TOKEN": true, "CODE_SERVER_PASSWORD": true, "SSH_PUBLIC_KEY": true,
+	"GIT_USER_NAME": true, "GIT_USER_EMAIL": true,
+	"ANTHROPIC_API_KEY": true, "OPENAI_API_KEY": true, "GEMINI_API_KEY": true,
+}
+
+// reservedPorts are the ports every workspace already exposes
+var reservedPorts = map[int]string{8080: "code-server", 2222: "SSH", 9000: "the supervisor"}
+
+func validateEnvVars(vars []EnvVar) error {
+	if len(vars) > MaxEnvVars {
+		return ErrInvalidRequest(fmt.Sprintf("at most %d envVars are allowed", MaxEnvVars))
+	}
+	seen := make(map[string]bool, len(vars))
+	for _, v := range vars {
+		if !envVarNamePattern.MatchString(v.Name) {
+			return ErrInvalidRequest(fmt.Sprintf("envVars: invalid name '%s'", v.Name))
+		}
+		if reservedEnvVars[v.Name] || strings.HasPrefix(v.Name, "BACKUP_") {
+			return ErrInvalidRequest(fmt.Sprintf("envVars: %s is set by the agent", v.Name))
+		}
+		if seen[v.Name] {
+			return ErrInvalidRequest(fmt.Sprintf("envVars: %s is listed twice", v.Name))
+		}
+		seen[v.Name] = true
+	}
+	return nil
+}
+
+// validatePorts validates the ports and fills in the default protocol and visibility
+func validatePorts(ports []PortSpec) error {
+	if len(ports) > MaxPorts {
+		return ErrInvalidRequest(fmt.Sprintf("at most %d ports are allowed", MaxPorts))
+	}
+	seen := make(map[int]bool, len(ports))
+	for i := range ports {
+		port := &ports[i]
+		if port.Port < 1 || port.Port > 65535 {
+			return ErrInvalidRequest(fmt.Sprintf("ports: %d is not a valid port", port.Port))
+		}
+		if name, ok := reservedPorts[port.Port]; ok {
+			return ErrInvalidRequest(fmt.Sprintf("ports: %d is already used by %s", port.Port, name))
+		}
+		if seen[port.Port] {
+			return ErrInvalidRequest(fmt.Sprintf("ports: %d is listed twice", port.Port))
+		}
+		seen[port.Port] = true
+
+		switch port.Protocol {
+		case "":
+			port.Protocol = PortProtocolHTTP
+		case PortProtocolHTTP, PortProtocolTCP, PortProtocolUDP:
+		default:
+			return ErrInvalidRequest(fmt.Sprintf("ports: protocol of %d must be http, tcp or udp", port.Port))
+		}
+		switch port.Visibility {
+		case "":
+			port.Visibility = PortPublic
+		case PortPublic, PortPrivate:
+		default:
+			return ErrInvalidRequest(fmt.Sprintf("ports: visibility of %d must be public or private", port.Port))
+		}
+	}
+	return nil
+}
+
+// StoredEnvVars returns vars without the values of secrets, for the environment record
+func StoredEnvVars(vars []EnvVar) []EnvVar {
+	if len(vars) == 0 {
+		return nil
+	}
+	stored := make([]EnvVar, len(vars))
+	for i, v := range vars {
+		if v.Secret {
+			v.Value = ""
+		}
+		stored[i] = v
+	}
+	return stored
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Credentials that can be rotated
+const (
+	CredentialCodeServerPassword = "codeServerPassword"
+	CredentialSSHKey             = "sshKey"
+	CredentialGitHubToken        = "githubToken"
+)
+
+// How rotated credentials reached the workspace
+const (
+	CredentialsRedeployed = "redeployed" // The running container was redeployed with them
+	CredentialsNextStart  = "next-start" // The workspace is stopped; they apply when it starts
+)
+
+// RotateCredentialsRequest represents a request to rotate workspace credentials
+type RotateCredentialsRequest struct {
+	WorkspaceID string `json:"workspaceId"` // Taken from the route
+
+	// Credentials to rotate; empty rotates the code-server password and the SSH key
+	Credentials []string `json:"credentials,omitempty"`
+
+	// New GitHub token, required to rotate githubToken. GitHub tokens are issued by
+	// GitHub, so the agent cannot generate one.
+	GitHubToken string `json:"githubToken,omitempty"`
+}
+
+// CredentialRotation is the result of a credential rotation. The SSH private key is
+// only returned here and is not kept by the agent.
+type CredentialRotation struct {
+	WorkspaceID    string               `json:"workspaceId"`
+	Rotated        []string             `json:"rotated"`
+	Applied        string               `json:"applied"` // redeployed or next-start
+	ConnectionURLs ConnectionURLs       `json:"connectionUrls"`
+	SSHPublicKey   string               `json:"sshPublicKey,omitempty"`
+	SSHPrivateKey  string               `json:"sshPrivateKey,omitempty"` // OpenSSH format
+	RotatedAt      map[string]time.Time `json:"rotatedAt"`               // Last rotation of every rotated credential
+}
+
+// Validate validates the request and fills in the default credentials
+func (r *RotateCredentialsRequest) Validate() error {
+	if r.WorkspaceID == "" {
+		return ErrInvalidRequest("workspaceId is required")
+	}
+	if len(r.Credentials) == 0 {
+		r.Credentials = []string{CredentialCodeServerPassword, CredentialSSHKey}
+		if r.GitHubToken != "" {
+			r.Credentials = append(r.Credentials, CredentialGitHubToken)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, credential := range r.Credentials {
+		switch credential {
+		case CredentialCodeServerPassword, CredentialSSHKey, CredentialGitHubToken:
+		default:
+			return ErrInvalidRequest(fmt.Sprintf("credentials: unknown credential %q (want %s, %s or %s)",
+				credential, CredentialCodeServerPassword, CredentialSSHKey, CredentialGitHubToken))
+		}
+		if seen[credential] {
+			return ErrInvalidRequest(fmt.Sprintf("credentials: %s is listed twice", credential))
+		}
+		seen[credential] = true
+	}
+
+	if seen[CredentialGitHubToken] && r.GitHubToken == "" {
+		return ErrInvalidRequest("githubToken is required to rotate the GitHub token")
+	}
+	if !seen[CredentialGitHubToken] && r.GitHubToken != "" {
+		return ErrInvalidRequest("githubToken is set but githubToken is not in credentials")
+	}
+	return nil
+}
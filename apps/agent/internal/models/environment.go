@@ -1,20 +1,35 @@
 package models
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // EnvironmentStatus represents the current status of an environment
 type EnvironmentStatus string
 
 const (
-	StatusCreating EnvironmentStatus = "CREATING"
-	StatusStarting EnvironmentStatus = "STARTING"
-	StatusRunning  EnvironmentStatus = "RUNNING"
-	StatusStopping EnvironmentStatus = "STOPPING"
-	StatusStopped  EnvironmentStatus = "STOPPED"
-	StatusError    EnvironmentStatus = "ERROR"
-	StatusDeleting EnvironmentStatus = "DELETING"
+	StatusCreating  EnvironmentStatus = "CREATING"
+	StatusStarting  EnvironmentStatus = "STARTING"
+	StatusRunning   EnvironmentStatus = "RUNNING"
+	StatusStopping  EnvironmentStatus = "STOPPING"
+	StatusStopped   EnvironmentStatus = "STOPPED"
+	StatusError     EnvironmentStatus = "ERROR"
+	StatusDeleting  EnvironmentStatus = "DELETING"
+	StatusResizing  EnvironmentStatus = "RESIZING"
+	StatusRestoring EnvironmentStatus = "RESTORING"
+	StatusRotating  EnvironmentStatus = "ROTATING"
 )
 
+// IsValid reports whether s is a known environment status
+func (s EnvironmentStatus) IsValid() bool {
+	switch s {
+	case StatusCreating, StatusStarting, StatusRunning, StatusStopping, StatusStopped, StatusError, StatusDeleting, StatusResizing, StatusRestoring, StatusRotating:
+		return true
+	}
+	return false
+}
+
 // CloudProvider represents supported cloud providers
 type CloudProvider string
 
@@ -24,6 +39,9 @@ const (
 	ProviderGCP   CloudProvider = "GCP"
 )
 
+// RegionAuto as a create's cloudRegion lets the agent choose among the enabled regions
+const RegionAuto = "auto"
+
 // ConnectionURLs contains all connection endpoints for the workspace
 type ConnectionURLs struct {
 	SSHURL             string `json:"sshUrl"`             // ssh://user@ws-{uuid}.region.azurecontainer.io:2222
@@ -31,15 +49,22 @@ type ConnectionURLs struct {
 	VSCodeDesktopURL   string `json:"vscodeDesktopUrl"`   // vscode-remote://ssh-remote+user@ws-{uuid}...:2222/home/dev8/workspace
 	SupervisorURL      string `json:"supervisorUrl"`      // http://ws-{uuid}.region.azurecontainer.io:9000
 	CodeServerPassword string `json:"codeServerPassword"` // Generated password for VS Code auth
+
+	// URLs of the workspace's own public ports, by container port (e.g. "http://ws-{uuid}...:3000")
+	Ports map[int]string `json:"ports,omitempty"`
 }
 
 // Environment represents a cloud development environment
 type Environment struct {
 	ID     string            `json:"id"` // Same as WorkspaceID (UUID from DB)
 	UserID string            `json:"userId"`
+	OrgID  string            `json:"orgId,omitempty"` // Org whose tier limits are shared by its users
 	Name   string            `json:"name"`
 	Status EnvironmentStatus `json:"status"`
 
+	// StatusMessage explains the current status, e.g. why provisioning failed
+	StatusMessage string `json:"statusMessage,omitempty"`
+
 	// Cloud Configuration
 	CloudProvider CloudProvider `json:"cloudProvider"`
 	CloudRegion   string        `json:"cloudRegion"`
@@ -56,13 +81,40 @@ type Environment struct {
 	AzureFileShare      string `json:"azureFileShare"`      // e.g., "fs-clxxx-yyyy-zzzz" (unified volume for home + workspace)
 	AzureFQDN           string `json:"azureFqdn"`           // e.g., "ws-clxxx-yyyy-zzzz.eastus.azurecontainer.io"
 
+	// User container settings; secret values are not kept
+	EnvVars []EnvVar   `json:"envVars,omitempty"`
+	Ports   []PortSpec `json:"ports,omitempty"`
+
+	// Saved secrets; the record only holds their reference in the secret store
+	SecretRef        string     `json:"secretRef,omitempty"`
+	SecretsUpdatedAt *time.Time `json:"secretsUpdatedAt,omitempty"`
+
+	// Last rotation of each credential, keyed like RotateCredentialsRequest.Credentials
+	CredentialsRotatedAt map[string]time.Time `json:"credentialsRotatedAt,omitempty"`
+
 	// Connection Information (all contain UUID)
 	ConnectionURLs ConnectionURLs `json:"connectionUrls"`
 
+	// Idle auto-stop
+	Tier                 string     `json:"tier,omitempty"`               // User tier, selects the policy limits and IDLE_TIMEOUT_TIERS overrides
+	IdleTimeoutMinutes   int        `json:"idleTimeoutMinutes,omitempty"` // 0 uses the tier or agent default
+	ActiveIDEConnections int        `json:"activeIdeConnections"`         // From the latest supervisor report
+	ActiveSSHConnections int        `json:"activeSshConnections"`         // From the latest supervisor report
+	IdleWarnedAt         *time.Time `json:"idleWarnedAt,omitempty"`       // Set when an idle warning is issued
+
+	// Monthly budget
+	BudgetWarnedAt *time.Time `json:"budgetWarnedAt,omitempty"` // Set when the soft budget warning is issued
+
+	// Volume snapshots
+	Snapshots         []SnapshotInfo     `json:"snapshots,omitempty"`         // Oldest first, as of the last snapshot call
+	SnapshotRetention *SnapshotRetention `json:"snapshotRetention,omitempty"` // nil uses the agent default
+	RestoredFrom      string             `json:"restoredFrom,omitempty"`      // {workspaceId}@{snapshotId} for workspaces restored to a new share
+	ClonedFrom        string             `json:"clonedFrom,omitempty"`        // Source workspace ID for cloned workspaces
+
 	// Timestamps
 	CreatedAt      time.Time `json:"createdAt"`
 	UpdatedAt      time.Time `json:"updatedAt"`
-	LastAccessedAt time.Time `json:"lastAccessedAt,omitempty"`
+	LastAccessedAt time.Time `json:"lastAccessedAt,omitempty"` // Last IDE/SSH activity
 }
 
 // CreateEnvironmentRequest represents a request to create a new environment
@@ -74,13 +126,32 @@ type CreateEnvironmentRequest struct {
 	UserID        string        `json:"userId"`
 	Name          string        `json:"name"`
 	CloudProvider CloudProvider `json:"cloudProvider"`
-	CloudRegion   string        `json:"cloudRegion"`
+	CloudRegion   string        `json:"cloudRegion"` // A region, or "auto" to let the agent choose
 	CPUCores      int           `json:"cpuCores"`
 	MemoryGB      int           `json:"memoryGB"`
 	StorageGB     int           `json:"storageGB"`
 	BaseImage     string        `json:"baseImage"`
 
-	// Optional per-workspace dynamic values
+	// Optional regions to choose from instead of cloudRegion, most preferred first
+	CloudRegions []string `json:"cloudRegions,omitempty"`
+	// Optional client-measured round trip to each region in milliseconds, favoured by "auto"
+	RegionLatencyMs map[string]int `json:"regionLatencyMs,omitempty"`
+
+	// Optional org of the user, for tiers limiting a whole org
+	OrgID string `json:"orgId,omitempty"`
+
+	// Optional tier (plan), selecting the policy limits and idle auto-stop settings
+	Tier               string `json:"tier,omitempty"`
+	IdleTimeoutMinutes int    `json:"idleTimeoutMinutes,omitempty"` // 0 uses the tier or agent default
+
+	// Optional snapshot retention; nil uses the agent default
+	SnapshotRetention *SnapshotRetention `json:"snapshotRetention,omitempty"`
+
+	// Optional user environment variables and exposed ports
+	EnvVars []EnvVar   `json:"envVars,omitempty"`
+	Ports   []PortSpec `json:"ports,omitempty"`
+
+	// Optional per-workspace dynamic values, saved in the secret store
 	GitHubToken        string `json:"githubToken,omitempty"`
 	CodeServerPassword string `json:"codeServerPassword,omitempty"`
 	SSHPublicKey       string `json:"sshPublicKey,omitempty"`
@@ -104,7 +175,7 @@ type StartEnvironmentRequest struct {
 	StorageGB int    `json:"storageGB"`
 	BaseImage string `json:"baseImage"`
 
-	// Optional per-workspace secrets
+	// Optional per-workspace secrets; values sent replace the saved ones
 	GitHubToken        string `json:"githubToken,omitempty"`
 	CodeServerPassword string `json:"codeServerPassword,omitempty"`
 	SSHPublicKey       string `json:"sshPublicKey,omitempty"`
@@ -145,6 +216,47 @@ type DeleteEnvironmentRequest struct {
 	Force       bool   `json:"force,omitempty"` // Force delete even if running
 }
 
+// ResizeEnvironmentRequest represents a request to change the size of an environment.
+// Zero fields keep their current value.
+type ResizeEnvironmentRequest struct {
+	WorkspaceID string `json:"workspaceId"` // Taken from the route
+
+	CPUCores  int `json:"cpuCores,omitempty"`
+	MemoryGB  int `json:"memoryGB,omitempty"`
+	StorageGB int `json:"storageGB,omitempty"`
+
+	// Optional per-workspace secrets; values sent replace the saved ones
+	GitHubToken        string `json:"githubToken,omitempty"`
+	CodeServerPassword string `json:"codeServerPassword,omitempty"`
+	SSHPublicKey       string `json:"sshPublicKey,omitempty"`
+	GitUserName        string `json:"gitUserName,omitempty"`
+	GitUserEmail       string `json:"gitUserEmail,omitempty"`
+	AnthropicAPIKey    string `json:"anthropicApiKey,omitempty"`
+	OpenAIAPIKey       string `json:"openaiApiKey,omitempty"`
+	GeminiAPIKey       string `json:"geminiApiKey,omitempty"`
+}
+
+// CloneEnvironmentRequest represents a request to copy a workspace's volume into a new workspace.
+// The new workspace has the source's size and image and is provisioned with the caller's secrets.
+type CloneEnvironmentRequest struct {
+	SourceWorkspaceID string `json:"sourceWorkspaceId"` // Taken from the route
+
+	WorkspaceID string `json:"workspaceId,omitempty"` // ID of the new workspace; generated when empty
+	UserID      string `json:"userId"`
+	Name        string `json:"name,omitempty"`  // Defaults to the source name with " (copy)"
+	Force       bool   `json:"force,omitempty"` // Clone a running source from a point-in-time snapshot
+
+	// Optional per-workspace secrets of the caller
+	GitHubToken        string `json:"githubToken,omitempty"`
+	CodeServerPassword string `json:"codeServerPassword,omitempty"`
+	SSHPublicKey       string `json:"sshPublicKey,omitempty"`
+	GitUserName        string `json:"gitUserName,omitempty"`
+	GitUserEmail       string `json:"gitUserEmail,omitempty"`
+	AnthropicAPIKey    string `json:"anthropicApiKey,omitempty"`
+	OpenAIAPIKey       string `json:"openaiApiKey,omitempty"`
+	GeminiAPIKey       string `json:"geminiApiKey,omitempty"`
+}
+
 // UpdateEnvironmentRequest represents a request to update an environment
 type UpdateEnvironmentRequest struct {
 	Name   string `json:"name,omitempty"`
@@ -220,8 +332,15 @@ func (r *CreateEnvironmentRequest) Validate() error {
 	if r.Name == "" {
 		return ErrInvalidRequest("name is required")
 	}
-	if r.CloudRegion == "" {
-		return ErrInvalidRequest("cloudRegion is required")
+	if err := r.validateRegions(); err != nil {
+		return err
+	}
+	switch r.CloudProvider {
+	case "":
+		r.CloudProvider = ProviderAzure
+	case ProviderAzure, ProviderAWS, ProviderGCP:
+	default:
+		return ErrInvalidRequest("cloudProvider must be AZURE, AWS or GCP")
 	}
 	if r.CPUCores < 1 || r.CPUCores > 4 {
 		return ErrInvalidRequest("cpuCores must be between 1 and 4")
@@ -232,12 +351,57 @@ func (r *CreateEnvironmentRequest) Validate() error {
 	if r.StorageGB < 10 || r.StorageGB > 100 {
 		return ErrInvalidRequest("storageGB must be between 10 and 100")
 	}
-	if r.BaseImage == "" {
-		r.BaseImage = "node" // Default to Node.js
+	if r.IdleTimeoutMinutes < 0 || r.IdleTimeoutMinutes > 7*24*60 {
+		return ErrInvalidRequest("idleTimeoutMinutes must be between 0 and 10080 (7 days)")
+	}
+	if r.SnapshotRetention != nil {
+		if err := r.SnapshotRetention.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateEnvVars(r.EnvVars); err != nil {
+		return err
+	}
+	if err := validatePorts(r.Ports); err != nil {
+		return err
+	}
+	return validateBaseImage(&r.BaseImage)
+}
+
+// validateRegions checks cloudRegion, cloudRegions and regionLatencyMs
+func (r *CreateEnvironmentRequest) validateRegions() error {
+	if len(r.CloudRegions) == 0 {
+		if r.CloudRegion == "" {
+			return ErrInvalidRequest("cloudRegion is required")
+		}
+	} else if r.CloudRegion != "" && r.CloudRegion != RegionAuto {
+		return ErrInvalidRequest("cloudRegion must be empty or \"auto\" when cloudRegions is set")
+	}
+
+	seen := make(map[string]bool, len(r.CloudRegions))
+	for _, region := range r.CloudRegions {
+		if region == "" || region == RegionAuto {
+			return ErrInvalidRequest("cloudRegions must name regions")
+		}
+		if seen[region] {
+			return ErrInvalidRequest(fmt.Sprintf("cloudRegions lists %s twice", region))
+		}
+		seen[region] = true
+	}
+	for region, ms := range r.RegionLatencyMs {
+		if ms < 0 {
+			return ErrInvalidRequest(fmt.Sprintf("regionLatencyMs of %s must not be negative", region))
+		}
 	}
 	return nil
 }
 
+// ChoosesRegion reports whether the agent picks the region of the create, from
+// cloudRegions or, for "auto", every enabled region
+func (r *CreateEnvironmentRequest) ChoosesRegion() bool {
+	return r.CloudRegion == RegionAuto || len(r.CloudRegions) > 0
+}
+
 // Validate validates the start environment request
 func (r *StartEnvironmentRequest) Validate() error {
 	if r.WorkspaceID == "" {
@@ -258,8 +422,42 @@ func (r *StartEnvironmentRequest) Validate() error {
 	if r.MemoryGB < 2 || r.MemoryGB > 16 {
 		return ErrInvalidRequest("memoryGB must be between 2 and 16")
 	}
-	if r.BaseImage == "" {
-		r.BaseImage = "node"
+	return validateBaseImage(&r.BaseImage)
+}
+
+// Validate validates the resize environment request
+func (r *ResizeEnvironmentRequest) Validate() error {
+	if r.WorkspaceID == "" {
+		return ErrInvalidRequest("workspaceId is required")
+	}
+	if r.CPUCores == 0 && r.MemoryGB == 0 && r.StorageGB == 0 {
+		return ErrInvalidRequest("at least one of cpuCores, memoryGB or storageGB is required")
+	}
+	if r.CPUCores != 0 && (r.CPUCores < 1 || r.CPUCores > 4) {
+		return ErrInvalidRequest("cpuCores must be between 1 and 4")
+	}
+	if r.MemoryGB != 0 && (r.MemoryGB < 2 || r.MemoryGB > 16) {
+		return ErrInvalidRequest("memoryGB must be between 2 and 16")
+	}
+	if r.StorageGB != 0 && (r.StorageGB < 10 || r.StorageGB > 100) {
+		return ErrInvalidRequest("storageGB must be between 10 and 100")
+	}
+	return nil
+}
+
+// Validate validates the clone environment request
+func (r *CloneEnvironmentRequest) Validate() error {
+	if r.SourceWorkspaceID == "" {
+		return ErrInvalidRequest("source workspace ID is required")
+	}
+	if r.WorkspaceID != "" && len(r.WorkspaceID) < 10 {
+		return ErrInvalidRequest("workspaceId must be a valid UUID")
+	}
+	if r.WorkspaceID == r.SourceWorkspaceID {
+		return ErrInvalidRequest("workspaceId must differ from the source workspace")
+	}
+	if r.UserID == "" {
+		return ErrInvalidRequest("userId is required")
 	}
 	return nil
 }
@@ -288,10 +486,12 @@ func (r *DeleteEnvironmentRequest) Validate() error {
 
 // ErrorResponse represents an error response
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
-	Message string `json:"message"`
-	Code    string `json:"code,omitempty"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error"`
+	Message   string `json:"message"`
+	Code      string `json:"code,omitempty"`
+	Reason    string `json:"reason,omitempty"` // Machine-readable cause, e.g. the policy limit exceeded
+	Retryable bool   `json:"retryable"`        // Whether the same request may succeed later
 }
 
 // SuccessResponse represents a successful operation response
@@ -303,8 +503,11 @@ type SuccessResponse struct {
 
 // Custom error types
 type AppError struct {
-	Message string
-	Code    string
+	Message    string
+	Code       string
+	Reason     string        // Machine-readable cause within Code, e.g. a PolicyReason
+	Retryable  bool          // The same request may succeed later
+	RetryAfter time.Duration // Suggested wait before retrying, 0 if unknown
 }
 
 func (e *AppError) Error() string {
@@ -331,3 +534,29 @@ func ErrUnauthorized(message string) error {
 func ErrConflict(message string) error {
 	return &AppError{Message: message, Code: "CONFLICT"}
 }
+
+// ErrBusy reports a conflict with an operation in flight, which goes away once it finishes
+func ErrBusy(message string) error {
+	return &AppError{Message: message, Code: "CONFLICT", Retryable: true}
+}
+
+func ErrThrottled(message string, retryAfter time.Duration) error {
+	return &AppError{Message: message, Code: "THROTTLED", Retryable: true, RetryAfter: retryAfter}
+}
+
+func ErrQuotaExceeded(message string) error {
+	return &AppError{Message: message, Code: "QUOTA_EXCEEDED"}
+}
+
+func ErrImagePull(message string) error {
+	return &AppError{Message: message, Code: "IMAGE_PULL_FAILED"}
+}
+
+// ErrPolicyViolation reports a request that the limits of the caller's tier do not allow
+func ErrPolicyViolation(reason PolicyReason, message string) error {
+	return &AppError{Message: message, Code: "POLICY_VIOLATION", Reason: string(reason)}
+}
+
+func ErrUnavailable(message string, retryAfter time.Duration) error {
+	return &AppError{Message: message, Code: "UNAVAILABLE", Retryable: true, RetryAfter: retryAfter}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"testing"
 	"time"
 )
@@ -24,6 +25,32 @@ func TestCreateEnvironmentRequest_Validate(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "unknown base image",
+			req: CreateEnvironmentRequest{
+				WorkspaceID: "550e8400-e29b-41d4-a716-446655440000",
+				Name:        "test-env",
+				CloudRegion: "eastus",
+				CPUCores:    2,
+				MemoryGB:    4,
+				StorageGB:   100,
+				BaseImage:   "cobol",
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown cloud provider",
+			req: CreateEnvironmentRequest{
+				WorkspaceID:   "550e8400-e29b-41d4-a716-446655440000",
+				Name:          "test-env",
+				CloudProvider: "DIGITALOCEAN",
+				CloudRegion:   "nyc1",
+				CPUCores:      2,
+				MemoryGB:      4,
+				StorageGB:     100,
+			},
+			wantErr: true,
+		},
 		{
 			name: "missing name",
 			req: CreateEnvironmentRequest{
@@ -88,6 +115,94 @@ func TestCreateEnvironmentRequest_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "negative idle timeout",
+			req: CreateEnvironmentRequest{
+				WorkspaceID:        "550e8400-e29b-41d4-a716-446655440000",
+				Name:               "test-env",
+				CloudRegion:        "eastus",
+				CPUCores:           2,
+				MemoryGB:           4,
+				StorageGB:          100,
+				IdleTimeoutMinutes: -1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "auto region",
+			req: CreateEnvironmentRequest{
+				WorkspaceID: "550e8400-e29b-41d4-a716-446655440000",
+				Name:        "test-env",
+				CloudRegion: RegionAuto,
+				CPUCores:    2,
+				MemoryGB:    4,
+				StorageGB:   100,
+			},
+			wantErr: false,
+		},
+		{
+			name: "preferred regions",
+			req: CreateEnvironmentRequest{
+				WorkspaceID:     "550e8400-e29b-41d4-a716-446655440000",
+				Name:            "test-env",
+				CloudRegions:    []string{"eastus", "westus"},
+				RegionLatencyMs: map[string]int{"eastus": 40},
+				CPUCores:        2,
+				MemoryGB:        4,
+				StorageGB:       100,
+			},
+			wantErr: false,
+		},
+		{
+			name: "preferred regions with a fixed region",
+			req: CreateEnvironmentRequest{
+				WorkspaceID:  "550e8400-e29b-41d4-a716-446655440000",
+				Name:         "test-env",
+				CloudRegion:  "eastus",
+				CloudRegions: []string{"westus"},
+				CPUCores:     2,
+				MemoryGB:     4,
+				StorageGB:    100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate preferred region",
+			req: CreateEnvironmentRequest{
+				WorkspaceID:  "550e8400-e29b-41d4-a716-446655440000",
+				Name:         "test-env",
+				CloudRegions: []string{"eastus", "eastus"},
+				CPUCores:     2,
+				MemoryGB:     4,
+				StorageGB:    100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative latency hint",
+			req: CreateEnvironmentRequest{
+				WorkspaceID:     "550e8400-e29b-41d4-a716-446655440000",
+				Name:            "test-env",
+				CloudRegion:     RegionAuto,
+				RegionLatencyMs: map[string]int{"eastus": -1},
+				CPUCores:        2,
+				MemoryGB:        4,
+				StorageGB:       100,
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing region",
+			req: CreateEnvironmentRequest{
+				WorkspaceID: "550e8400-e29b-41d4-a716-446655440000",
+				Name:        "test-env",
+
+				CPUCores:  2,
+				MemoryGB:  4,
+				StorageGB: 100,
+			},
+			wantErr: true,
+		},
 		{
 			name: "default base image",
 			req: CreateEnvironmentRequest{
@@ -119,6 +234,174 @@ func TestCreateEnvironmentRequest_Validate(t *testing.T) {
 	}
 }
 
+func TestSetImageCatalog(t *testing.T) {
+	SetImageCatalog([]string{"python", "go"}, "python")
+	t.Cleanup(func() { SetImageCatalog([]string{"node"}, "node") })
+
+	req := StartEnvironmentRequest{WorkspaceID: "ws-1", CloudRegion: "eastus", UserID: "user-1", Name: "test-env", CPUCores: 2, MemoryGB: 4}
+	if err := req.Validate(); err != nil || req.BaseImage != "python" {
+		t.Errorf("Validate() = %v with baseImage %q, want nil with the catalogue default", err, req.BaseImage)
+	}
+
+	req.BaseImage = "node"
+	if err := req.Validate(); err == nil {
+		t.Error("Validate() of an image no longer in the catalogue error = nil")
+	}
+}
+
+func TestValidateEnvVarsAndPorts(t *testing.T) {
+	tests := []struct {
+		name    string
+		vars    []EnvVar
+		ports   []PortSpec
+		wantErr bool
+	}{
+		{name: "valid", vars: []EnvVar{{Name: "DATABASE_URL", Value: "postgres://db"}, {Name: "API_TOKEN", Value: "t", Secret: true}}, ports: []PortSpec{{Port: 3000}, {Port: 5432, Protocol: PortProtocolTCP, Visibility: PortPrivate}}},
+		{name: "invalid name", vars: []EnvVar{{Name: "1FOO"}}, wantErr: true},
+		{name: "reserved name", vars: []EnvVar{{Name: "GITHUB_TOKEN", Value: "x"}}, wantErr: true},
+		{name: "reserved prefix", vars: []EnvVar{{Name: "BACKUP_ENABLED", Value: "false"}}, wantErr: true},
+		{name: "duplicate name", vars: []EnvVar{{Name: "FOO"}, {Name: "FOO"}}, wantErr: true},
+		{name: "reserved port", ports: []PortSpec{{Port: 8080}}, wantErr: true},
+		{name: "duplicate port", ports: []PortSpec{{Port: 3000}, {Port: 3000, Protocol: PortProtocolTCP}}, wantErr: true},
+		{name: "port out of range", ports: []PortSpec{{Port: 70000}}, wantErr: true},
+		{name: "unknown protocol", ports: []PortSpec{{Port: 3000, Protocol: "sctp"}}, wantErr: true},
+		{name: "unknown visibility", ports: []PortSpec{{Port: 3000, Visibility: "internal"}}, wantErr: true},
+		{name: "too many ports", ports: []PortSpec{{Port: 3000}, {Port: 3001}, {Port: 3002}, {Port: 3003}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := CreateEnvironmentRequest{
+				WorkspaceID: "550e8400-e29b-41d4-a716-446655440000",
+				Name:        "test-env",
+				CloudRegion: "eastus",
+				CPUCores:    2,
+				MemoryGB:    4,
+				StorageGB:   10,
+				EnvVars:     tt.vars,
+				Ports:       tt.ports,
+			}
+			if err := req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, port := range req.Ports {
+				if !tt.wantErr && (port.Protocol == "" || port.Visibility == "") {
+					t.Errorf("Validate() left port %d without defaults: %+v", port.Port, port)
+				}
+			}
+		})
+	}
+
+	stored := StoredEnvVars([]EnvVar{{Name: "FOO", Value: "bar"}, {Name: "TOKEN", Value: "s3cret", Secret: true}})
+	if stored[0].Value != "bar" || stored[1].Value != "" || !stored[1].Secret {
+		t.Errorf("StoredEnvVars() = %+v, want the secret value stripped", stored)
+	}
+}
+
+func TestResizeEnvironmentRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ResizeEnvironmentRequest
+		wantErr bool
+	}{
+		{name: "cpu only", req: ResizeEnvironmentRequest{WorkspaceID: "ws-1", CPUCores: 4}},
+		{name: "storage only", req: ResizeEnvironmentRequest{WorkspaceID: "ws-1", StorageGB: 50}},
+		{name: "missing workspace ID", req: ResizeEnvironmentRequest{CPUCores: 2}, wantErr: true},
+		{name: "nothing to change", req: ResizeEnvironmentRequest{WorkspaceID: "ws-1"}, wantErr: true},
+		{name: "too many cores", req: ResizeEnvironmentRequest{WorkspaceID: "ws-1", CPUCores: 8}, wantErr: true},
+		{name: "too little memory", req: ResizeEnvironmentRequest{WorkspaceID: "ws-1", MemoryGB: 1}, wantErr: true},
+		{name: "too much storage", req: ResizeEnvironmentRequest{WorkspaceID: "ws-1", StorageGB: 500}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCloneEnvironmentRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     CloneEnvironmentRequest
+		wantErr bool
+	}{
+		{name: "valid", req: CloneEnvironmentRequest{SourceWorkspaceID: "ws-1", WorkspaceID: "550e8400-e29b-41d4", UserID: "user-1"}},
+		{name: "missing source", req: CloneEnvironmentRequest{WorkspaceID: "550e8400-e29b-41d4", UserID: "user-1"}, wantErr: true},
+		{name: "clone onto itself", req: CloneEnvironmentRequest{SourceWorkspaceID: "550e8400-e29b-41d4", WorkspaceID: "550e8400-e29b-41d4", UserID: "user-1"}, wantErr: true},
+		{name: "short workspace ID", req: CloneEnvironmentRequest{SourceWorkspaceID: "ws-1", WorkspaceID: "ws-2", UserID: "user-1"}, wantErr: true},
+		{name: "missing user", req: CloneEnvironmentRequest{SourceWorkspaceID: "ws-1", WorkspaceID: "550e8400-e29b-41d4"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRestoreSnapshotRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      RestoreSnapshotRequest
+		wantMode RestoreMode
+		wantErr  bool
+	}{
+		{name: "defaults to in place", req: RestoreSnapshotRequest{WorkspaceID: "ws-1", SnapshotID: "snap"}, wantMode: RestoreInPlace},
+		{name: "new share", req: RestoreSnapshotRequest{WorkspaceID: "ws-1", SnapshotID: "snap", Mode: RestoreNewShare, TargetWorkspaceID: "550e8400-e29b-41d4"}, wantMode: RestoreNewShare},
+		{name: "missing snapshot", req: RestoreSnapshotRequest{WorkspaceID: "ws-1"}, wantErr: true},
+		{name: "unknown mode", req: RestoreSnapshotRequest{WorkspaceID: "ws-1", SnapshotID: "snap", Mode: "sideways"}, wantErr: true},
+		{name: "new share without target", req: RestoreSnapshotRequest{WorkspaceID: "ws-1", SnapshotID: "snap", Mode: RestoreNewShare}, wantErr: true},
+		{name: "target in place", req: RestoreSnapshotRequest{WorkspaceID: "ws-1", SnapshotID: "snap", TargetWorkspaceID: "550e8400-e29b-41d4"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && tt.req.Mode != tt.wantMode {
+				t.Errorf("Validate() mode = %s, want %s", tt.req.Mode, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestRotateCredentialsRequest_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     RotateCredentialsRequest
+		want    string
+		wantErr bool
+	}{
+		{name: "defaults", req: RotateCredentialsRequest{WorkspaceID: "ws-1"}, want: "codeServerPassword,sshKey"},
+		{name: "defaults with token", req: RotateCredentialsRequest{WorkspaceID: "ws-1", GitHubToken: "ghp_new"}, want: "codeServerPassword,sshKey,githubToken"},
+		{name: "token only", req: RotateCredentialsRequest{WorkspaceID: "ws-1", Credentials: []string{"githubToken"}, GitHubToken: "ghp_new"}, want: "githubToken"},
+		{name: "token without value", req: RotateCredentialsRequest{WorkspaceID: "ws-1", Credentials: []string{"githubToken"}}, wantErr: true},
+		{name: "value without token", req: RotateCredentialsRequest{WorkspaceID: "ws-1", Credentials: []string{"sshKey"}, GitHubToken: "ghp_new"}, wantErr: true},
+		{name: "unknown credential", req: RotateCredentialsRequest{WorkspaceID: "ws-1", Credentials: []string{"apiKey"}}, wantErr: true},
+		{name: "duplicate credential", req: RotateCredentialsRequest{WorkspaceID: "ws-1", Credentials: []string{"sshKey", "sshKey"}}, wantErr: true},
+		{name: "missing workspace", req: RotateCredentialsRequest{}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := strings.Join(tt.req.Credentials, ","); !tt.wantErr && got != tt.want {
+				t.Errorf("Validate() credentials = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestActivityReport_Normalize(t *testing.T) {
 	tests := []struct {
 		name              string
@@ -0,0 +1,61 @@
+package models
+
+import "sync"
+
+// Image is a workspace image of the catalogue, as listed by GET /api/v1/images
+type Image struct {
+	Name     string `json:"name"`              // Logical name requested as baseImage
+	Image    string `json:"image"`             // Reference pulled for the region
+	Registry string `json:"registry"`          // Registry the image is pulled from
+	Digest   string `json:"digest,omitempty"`  // Set when the image is pinned
+	Default  bool   `json:"default,omitempty"` // Used by requests without a baseImage
+}
+
+// imageCatalog holds the baseImage names accepted by request validation. The agent
+// sets it from its configuration at startup; until then only "node" is known.
+var imageCatalog = struct {
+	sync.RWMutex
+	names       map[string]bool
+	defaultName string
+}{
+	names:       map[string]bool{"node": true},
+	defaultName: "node",
+}
+
+// SetImageCatalog sets the image names accepted as baseImage and the default image
+func SetImageCatalog(names []string, defaultName string) {
+	known := make(map[string]bool, len(names))
+	for _, name := range names {
+		known[name] = true
+	}
+
+	imageCatalog.Lock()
+	defer imageCatalog.Unlock()
+	imageCatalog.names = known
+	imageCatalog.defaultName = defaultName
+}
+
+// IsKnownImage reports whether name is in the image catalogue
+func IsKnownImage(name string) bool {
+	imageCatalog.RLock()
+	defer imageCatalog.RUnlock()
+	return imageCatalog.names[name]
+}
+
+// DefaultImage returns the image used by requests without a baseImage
+func DefaultImage() string {
+	imageCatalog.RLock()
+	defer imageCatalog.RUnlock()
+	return imageCatalog.defaultName
+}
+
+// validateBaseImage defaults an empty baseImage and rejects names outside the catalogue
+func validateBaseImage(baseImage *string) error {
+	if *baseImage == "" {
+		*baseImage = DefaultImage()
+	}
+	if !IsKnownImage(*baseImage) {
+		return ErrInvalidRequest("baseImage '" + *baseImage + "' is not in the image catalogue (see GET /api/v1/images)")
+	}
+	return nil
+}
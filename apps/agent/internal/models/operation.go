@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// OperationType identifies the lifecycle action an operation performs
+type OperationType string
+
+const (
+	OperationCreate   OperationType = "create"
+	OperationStart    OperationType = "start"
+	OperationStop     OperationType = "stop"
+	OperationDelete   OperationType = "delete"
+	OperationResize   OperationType = "resize"
+	OperationRestore  OperationType = "restore"
+	OperationClone    OperationType = "clone"
+	OperationRotate   OperationType = "rotate-credentials"
+	OperationSnapshot OperationType = "snapshot" // Snapshot changes; these run synchronously
+	OperationSecrets  OperationType = "secrets"  // Saved secret changes; these run synchronously
+)
+
+// OperationPhase represents where an asynchronous operation is in its lifecycle
+type OperationPhase string
+
+const (
+	OperationPending   OperationPhase = "PENDING"
+	OperationRunning   OperationPhase = "RUNNING"
+	OperationSucceeded OperationPhase = "SUCCEEDED"
+	OperationFailed    OperationPhase = "FAILED"
+)
+
+// Operation tracks a long-running lifecycle action so callers can poll for
+// completion instead of holding the HTTP connection open.
+type Operation struct {
+	ID          string         `json:"id"`
+	Type        OperationType  `json:"type"`
+	WorkspaceID string         `json:"workspaceId"`
+	Phase       OperationPhase `json:"phase"`
+	Step        string         `json:"step,omitempty"` // Human-readable name of the current step
+	Progress    int            `json:"progress"`       // 0-100
+
+	Result interface{}     `json:"result,omitempty"`
+	Error  *OperationError `json:"error,omitempty"`
+
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+}
+
+// OperationError describes why an operation failed
+type OperationError struct {
+	Code              string `json:"code"`
+	Message           string `json:"message"`
+	Reason            string `json:"reason,omitempty"`
+	Retryable         bool   `json:"retryable"`
+	RetryAfterSeconds int    `json:"retryAfterSeconds,omitempty"`
+}
+
+// IsTerminal reports whether the operation has finished (successfully or not)
+func (o *Operation) IsTerminal() bool {
+	return o.Phase == OperationSucceeded || o.Phase == OperationFailed
+}
@@ -0,0 +1,15 @@
+package models
+
+// PolicyReason is the machine-readable reason a request was rejected by policy
+type PolicyReason string
+
+const (
+	PolicyUnknownTier      PolicyReason = "unknown-tier"       // The request names a tier that is not configured
+	PolicyRegionNotAllowed PolicyReason = "region-not-allowed" // The tier does not allow the region
+	PolicyImageNotAllowed  PolicyReason = "image-not-allowed"  // The tier does not allow the image
+	PolicyMaxRunning       PolicyReason = "max-running"        // Too many workspaces would be running
+	PolicyCPUQuota         PolicyReason = "cpu-quota"          // Running workspaces would exceed the vCPU total
+	PolicyMemoryQuota      PolicyReason = "memory-quota"       // Running workspaces would exceed the memory total
+	PolicyStorageQuota     PolicyReason = "storage-quota"      // All workspaces would exceed the storage total
+	PolicyBudgetExceeded   PolicyReason = "budget-exceeded"    // The user's cost this month reached the hard budget
+)
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// ResourceKind identifies a type of cloud resource the agent provisions
+type ResourceKind string
+
+const (
+	ResourceContainerGroup     ResourceKind = "container-group"     // ACI aci-{id}
+	ResourceContainerApp       ResourceKind = "container-app"       // ACA aca-{id}
+	ResourceDockerContainer    ResourceKind = "docker-container"    // Local Docker docker-{id}
+	ResourceKubernetesWorkload ResourceKind = "kubernetes-workload" // Kubernetes StatefulSet, Service and Secrets k8s-{id}
+	ResourceEnvironmentStorage ResourceKind = "environment-storage" // ACA managed-environment storage fs-{id}
+	ResourceFileShare          ResourceKind = "file-share"          // Azure Files fs-{id}
+)
+
+// ReconcileAction records what the reconciler did with an orphaned resource
+type ReconcileAction string
+
+const (
+	ActionReported     ReconcileAction = "reported"
+	ActionWouldDelete  ReconcileAction = "would-delete"
+	ActionDeleted      ReconcileAction = "deleted"
+	ActionDeleteFailed ReconcileAction = "delete-failed"
+)
+
+// OrphanedResource is a provisioned resource with no healthy workspace behind it
+type OrphanedResource struct {
+	Kind          ResourceKind    `json:"kind"`
+	Name          string          `json:"name"`
+	WorkspaceID   string          `json:"workspaceId"`
+	Region        string          `json:"region"`
+	ResourceGroup string          `json:"resourceGroup,omitempty"`
+	Reason        string          `json:"reason"`
+	Action        ReconcileAction `json:"action"`
+	Error         string          `json:"error,omitempty"`
+}
+
+// ReconcileReport summarises a single reconciler sweep
+type ReconcileReport struct {
+	Policy      string             `json:"policy"`
+	DryRun      bool               `json:"dryRun"`
+	Scanned     int                `json:"scanned"`
+	Orphans     []OrphanedResource `json:"orphans"`
+	Errors      []string           `json:"errors,omitempty"` // Regions or resource types that could not be listed
+	StartedAt   time.Time          `json:"startedAt"`
+	CompletedAt time.Time          `json:"completedAt"`
+}
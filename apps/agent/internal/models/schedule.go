@@ -0,0 +1,121 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/cron"
+)
+
+// maxSkipDates bounds the skip dates of one schedule
+const maxSkipDates = 366
+
+// ScheduleAction is what a schedule does to its workspace
+type ScheduleAction string
+
+const (
+	ScheduleStart ScheduleAction = "START"
+	ScheduleStop  ScheduleAction = "STOP"
+)
+
+// ScheduleResult is what happened at a schedule's last run
+type ScheduleResult string
+
+const (
+	ScheduleSubmitted  ScheduleResult = "submitted"  // The start or stop operation was submitted
+	ScheduleNoop       ScheduleResult = "no-op"      // The workspace was already running or stopped
+	ScheduleSuperseded ScheduleResult = "superseded" // A later run of another schedule of the workspace was due too
+	ScheduleMissed     ScheduleResult = "missed"     // The run was due longer ago than the catch-up window
+	ScheduleFailed     ScheduleResult = "failed"     // The start or stop was rejected; see lastError
+)
+
+// Schedule starts or stops a workspace at the times of a cron expression
+type Schedule struct {
+	ID          string         `json:"id"`
+	WorkspaceID string         `json:"workspaceId"`
+	Action      ScheduleAction `json:"action"`
+	Cron        string         `json:"cron"`                // minute hour day-of-month month day-of-week
+	Timezone    string         `json:"timezone"`            // IANA time zone the cron expression is read in
+	SkipDates   []string       `json:"skipDates,omitempty"` // YYYY-MM-DD dates in Timezone without runs, e.g. holidays
+	Enabled     bool           `json:"enabled"`
+
+	// Scheduler state
+	CheckedUntil    time.Time      `json:"checkedUntil"`              // Runs up to this time have been handled
+	NextRunAt       *time.Time     `json:"nextRunAt,omitempty"`       // nil when disabled or the expression never matches again
+	LastRunAt       *time.Time     `json:"lastRunAt,omitempty"`       // Scheduled time of the last run handled
+	LastResult      ScheduleResult `json:"lastResult,omitempty"`      // What happened at that run
+	LastError       string         `json:"lastError,omitempty"`       // Why it failed
+	LastOperationID string         `json:"lastOperationId,omitempty"` // Operation it submitted
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Expression parses the schedule's cron expression
+func (s *Schedule) Expression() (*cron.Expression, *time.Location, error) {
+	expr, err := cron.Parse(s.Cron)
+	if err != nil {
+		return nil, nil, err
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, nil, err
+	}
+	return expr, loc, nil
+}
+
+// Skips reports whether the schedule has no runs on the day of t, read in its time zone
+func (s *Schedule) Skips(t time.Time) bool {
+	return slices.Contains(s.SkipDates, t.Format(time.DateOnly))
+}
+
+// ScheduleRequest creates or replaces a workspace schedule
+type ScheduleRequest struct {
+	Action    ScheduleAction `json:"action"`
+	Cron      string         `json:"cron"`
+	Timezone  string         `json:"timezone,omitempty"` // Default UTC
+	SkipDates []string       `json:"skipDates,omitempty"`
+	Enabled   *bool          `json:"enabled,omitempty"` // Default true
+}
+
+// Validate validates the schedule request, normalising the action, time zone and skip dates
+func (r *ScheduleRequest) Validate() error {
+	r.Action = ScheduleAction(strings.ToUpper(string(r.Action)))
+	if r.Action != ScheduleStart && r.Action != ScheduleStop {
+		return ErrInvalidRequest("action must be START or STOP")
+	}
+
+	expr, err := cron.Parse(r.Cron)
+	if err != nil {
+		return ErrInvalidRequest(fmt.Sprintf("invalid cron: %v", err))
+	}
+	if expr.Next(time.Now()).IsZero() {
+		return ErrInvalidRequest(fmt.Sprintf("cron %q never runs", r.Cron))
+	}
+
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(r.Timezone); err != nil || r.Timezone == "Local" {
+		return ErrInvalidRequest(fmt.Sprintf("unknown timezone %q; use an IANA name such as Europe/Berlin", r.Timezone))
+	}
+
+	if len(r.SkipDates) > maxSkipDates {
+		return ErrInvalidRequest(fmt.Sprintf("at most %d skipDates are allowed", maxSkipDates))
+	}
+	for _, date := range r.SkipDates {
+		if _, err := time.Parse(time.DateOnly, date); err != nil {
+			return ErrInvalidRequest(fmt.Sprintf("skipDates must be YYYY-MM-DD dates, got %q", date))
+		}
+	}
+	slices.Sort(r.SkipDates)
+	r.SkipDates = slices.Compact(r.SkipDates)
+	return nil
+}
+
+// IsEnabled reports whether the requested schedule is enabled
+func (r *ScheduleRequest) IsEnabled() bool {
+	return r.Enabled == nil || *r.Enabled
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// WorkspaceSecrets are the per-workspace values a container gets on every start.
+// The agent saves them in its secret store when the workspace is created, so start,
+// resize and later requests only carry the values that changed.
+type WorkspaceSecrets struct {
+	GitHubToken        string `json:"githubToken,omitempty"`
+	CodeServerPassword string `json:"codeServerPassword,omitempty"`
+	SSHPublicKey       string `json:"sshPublicKey,omitempty"`
+	GitUserName        string `json:"gitUserName,omitempty"`
+	GitUserEmail       string `json:"gitUserEmail,omitempty"`
+	AnthropicAPIKey    string `json:"anthropicApiKey,omitempty"`
+	OpenAIAPIKey       string `json:"openaiApiKey,omitempty"`
+	GeminiAPIKey       string `json:"geminiApiKey,omitempty"`
+
+	// Values of the workspace's secret environment variables, by name
+	EnvVars map[string]string `json:"envVars,omitempty"`
+}
+
+// SecretsInfo describes the saved secrets of a workspace without their values
+type SecretsInfo struct {
+	WorkspaceID string     `json:"workspaceId"`
+	SecretRef   string     `json:"secretRef,omitempty"` // Reference in the agent's secret store
+	Keys        []string   `json:"keys"`                // Names of the saved values, e.g. "githubToken" or "envVars.API_TOKEN"
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// fields returns the named single-value secrets, for iteration
+func (s *WorkspaceSecrets) fields() map[string]*string {
+	return map[string]*string{
+		"githubToken":        &s.GitHubToken,
+		"codeServerPassword": &s.CodeServerPassword,
+		"sshPublicKey":       &s.SSHPublicKey,
+		"gitUserName":        &s.GitUserName,
+		"gitUserEmail":       &s.GitUserEmail,
+		"anthropicApiKey":    &s.AnthropicAPIKey,
+		"openaiApiKey":       &s.OpenAIAPIKey,
+		"geminiApiKey":       &s.GeminiAPIKey,
+	}
+}
+
+// IsEmpty reports whether no value is set
+func (s *WorkspaceSecrets) IsEmpty() bool {
+	return s == nil || len(s.Keys()) == 0
+}
+
+// Keys returns the sorted names of the values that are set
+func (s *WorkspaceSecrets) Keys() []string {
+	keys := []string{}
+	if s == nil {
+		return keys
+	}
+	for name, value := range s.fields() {
+		if *value != "" {
+			keys = append(keys, name)
+		}
+	}
+	for name, value := range s.EnvVars {
+		if value != "" {
+			keys = append(keys, "envVars."+name)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Merge copies the values set in update over s and reports whether anything changed
+func (s *WorkspaceSecrets) Merge(update *WorkspaceSecrets) bool {
+	if update == nil {
+		return false
+	}
+
+	changed := false
+	current := s.fields()
+	for name, value := range update.fields() {
+		if *value != "" && *current[name] != *value {
+			*current[name] = *value
+			changed = true
+		}
+	}
+	for name, value := range update.EnvVars {
+		if value == "" || s.EnvVars[name] == value {
+			continue
+		}
+		if s.EnvVars == nil {
+			s.EnvVars = make(map[string]string)
+		}
+		s.EnvVars[name] = value
+		changed = true
+	}
+	return changed
+}
+
+// secretEnvVarValues returns the values of the secret variables in vars, by name
+func secretEnvVarValues(vars []EnvVar) map[string]string {
+	var values map[string]string
+	for _, v := range vars {
+		if !v.Secret || v.Value == "" {
+			continue
+		}
+		if values == nil {
+			values = make(map[string]string)
+		}
+		values[v.Name] = v.Value
+	}
+	return values
+}
+
+// Secrets returns the secrets of the request, to be saved for the new workspace
+func (r *CreateEnvironmentRequest) Secrets() *WorkspaceSecrets {
+	return &WorkspaceSecrets{
+		GitHubToken:        r.GitHubToken,
+		CodeServerPassword: r.CodeServerPassword,
+		SSHPublicKey:       r.SSHPublicKey,
+		GitUserName:        r.GitUserName,
+		GitUserEmail:       r.GitUserEmail,
+		AnthropicAPIKey:    r.AnthropicAPIKey,
+		OpenAIAPIKey:       r.OpenAIAPIKey,
+		GeminiAPIKey:       r.GeminiAPIKey,
+		EnvVars:            secretEnvVarValues(r.EnvVars),
+	}
+}
+
+// Secrets returns the secrets sent with the request, which replace the saved ones
+func (r *StartEnvironmentRequest) Secrets() *WorkspaceSecrets {
+	return &WorkspaceSecrets{
+		GitHubToken:        r.GitHubToken,
+		CodeServerPassword: r.CodeServerPassword,
+		SSHPublicKey:       r.SSHPublicKey,
+		GitUserName:        r.GitUserName,
+		GitUserEmail:       r.GitUserEmail,
+		AnthropicAPIKey:    r.AnthropicAPIKey,
+		OpenAIAPIKey:       r.OpenAIAPIKey,
+		GeminiAPIKey:       r.GeminiAPIKey,
+	}
+}
+
+// Secrets returns the secrets sent with the request, which replace the saved ones
+func (r *ResizeEnvironmentRequest) Secrets() *WorkspaceSecrets {
+	return &WorkspaceSecrets{
+		GitHubToken:        r.GitHubToken,
+		CodeServerPassword: r.CodeServerPassword,
+		SSHPublicKey:       r.SSHPublicKey,
+		GitUserName:        r.GitUserName,
+		GitUserEmail:       r.GitUserEmail,
+		AnthropicAPIKey:    r.AnthropicAPIKey,
+		OpenAIAPIKey:       r.OpenAIAPIKey,
+		GeminiAPIKey:       r.GeminiAPIKey,
+	}
+}
+
+// Secrets returns the caller's secrets, to be saved for the new workspace
+func (r *CloneEnvironmentRequest) Secrets() *WorkspaceSecrets {
+	return &WorkspaceSecrets{
+		GitHubToken:        r.GitHubToken,
+		CodeServerPassword: r.CodeServerPassword,
+		SSHPublicKey:       r.SSHPublicKey,
+		GitUserName:        r.GitUserName,
+		GitUserEmail:       r.GitUserEmail,
+		AnthropicAPIKey:    r.AnthropicAPIKey,
+		OpenAIAPIKey:       r.OpenAIAPIKey,
+		GeminiAPIKey:       r.GeminiAPIKey,
+	}
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxSnapshotsPerShare is the Azure Files limit on snapshots of one share
+const maxSnapshotsPerShare = 200
+
+// SnapshotInfo describes a point-in-time snapshot of a workspace volume
+type SnapshotInfo struct {
+	ID        string    `json:"id"` // Assigned by the storage backend, e.g. "2024-05-01T10:00:00.0000000Z"
+	Label     string    `json:"label,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SnapshotRetention limits the snapshots kept for a workspace. Zero fields impose no limit.
+type SnapshotRetention struct {
+	MaxCount   int `json:"maxCount"`   // Oldest snapshots beyond this count are deleted
+	MaxAgeDays int `json:"maxAgeDays"` // Snapshots older than this are deleted
+}
+
+// Validate validates the retention policy
+func (r *SnapshotRetention) Validate() error {
+	if r.MaxCount < 0 || r.MaxCount > maxSnapshotsPerShare {
+		return ErrInvalidRequest(fmt.Sprintf("maxCount must be between 0 and %d", maxSnapshotsPerShare))
+	}
+	if r.MaxAgeDays < 0 || r.MaxAgeDays > 3650 {
+		return ErrInvalidRequest("maxAgeDays must be between 0 and 3650 (10 years)")
+	}
+	return nil
+}
+
+// CreateSnapshotRequest represents a request to snapshot a workspace volume
+type CreateSnapshotRequest struct {
+	WorkspaceID string `json:"workspaceId"` // Taken from the route
+	Label       string `json:"label,omitempty"`
+}
+
+// Validate validates the create snapshot request
+func (r *CreateSnapshotRequest) Validate() error {
+	if r.WorkspaceID == "" {
+		return ErrInvalidRequest("workspaceId is required")
+	}
+	if len(r.Label) > 64 {
+		return ErrInvalidRequest("label must be at most 64 characters")
+	}
+	// Labels are stored as Azure Files metadata, which only accepts printable ASCII
+	for _, c := range r.Label {
+		if c < ' ' || c > '~' {
+			return ErrInvalidRequest("label must only contain printable ASCII characters")
+		}
+	}
+	return nil
+}
+
+// RestoreMode selects where a snapshot is restored to
+type RestoreMode string
+
+const (
+	RestoreInPlace  RestoreMode = "in-place"  // Roll the workspace's own volume back
+	RestoreNewShare RestoreMode = "new-share" // Restore into the volume of a new, stopped workspace
+)
+
+// RestoreSnapshotRequest represents a request to restore a workspace snapshot
+type RestoreSnapshotRequest struct {
+	WorkspaceID string      `json:"workspaceId"` // Taken from the route
+	SnapshotID  string      `json:"snapshotId"`  // Taken from the route
+	Mode        RestoreMode `json:"mode"`        // Defaults to in-place
+
+	// For new-share restores: the workspace created from the snapshot
+	TargetWorkspaceID string `json:"targetWorkspaceId,omitempty"`
+	Name              string `json:"name,omitempty"` // Defaults to the source workspace name
+}
+
+// Validate validates the restore request
+func (r *RestoreSnapshotRequest) Validate() error {
+	if r.WorkspaceID == "" {
+		return ErrInvalidRequest("workspaceId is required")
+	}
+	if r.SnapshotID == "" {
+		return ErrInvalidRequest("snapshotId is required")
+	}
+	switch r.Mode {
+	case "":
+		r.Mode = RestoreInPlace
+	case RestoreInPlace, RestoreNewShare:
+	default:
+		return ErrInvalidRequest("mode must be in-place or new-share")
+	}
+
+	if r.Mode == RestoreNewShare {
+		if len(r.TargetWorkspaceID) < 10 {
+			return ErrInvalidRequest("targetWorkspaceId must be a valid UUID for new-share restores")
+		}
+		if r.TargetWorkspaceID == r.WorkspaceID {
+			return ErrInvalidRequest("targetWorkspaceId must differ from the source workspace")
+		}
+	} else if r.TargetWorkspaceID != "" {
+		return ErrInvalidRequest("targetWorkspaceId is only used by new-share restores")
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package models
+
+import "time"
+
+// UsageKind is what a usage record meters
+type UsageKind string
+
+const (
+	UsageCompute UsageKind = "compute" // A running container with CPUCores and MemoryGB
+	UsageStorage UsageKind = "storage" // A provisioned share of StorageGB
+)
+
+// UsageRecord is an interval during which a workspace used compute or storage of
+// one size. Records are closed, and new ones opened, when the size changes.
+type UsageRecord struct {
+	ID          string     `json:"id"`
+	WorkspaceID string     `json:"workspaceId"`
+	UserID      string     `json:"userId"`
+	Kind        UsageKind  `json:"kind"`
+	Region      string     `json:"region"`
+	Mode        string     `json:"mode"` // Deployment mode the workspace ran in, e.g. "aci" or "ecs"
+	CPUCores    int        `json:"cpuCores,omitempty"`
+	MemoryGB    int        `json:"memoryGB,omitempty"`
+	StorageGB   int        `json:"storageGB,omitempty"` // Provisioned share quota
+	StartedAt   time.Time  `json:"startedAt"`
+	EndedAt     *time.Time `json:"endedAt,omitempty"` // nil while the interval is open
+}
+
+// Overlap returns how much of the record lies between from and to, counting an
+// open record as lasting until now
+func (r *UsageRecord) Overlap(from, to, now time.Time) time.Duration {
+	end := now
+	if r.EndedAt != nil {
+		end = *r.EndedAt
+	}
+	start := r.StartedAt
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// UsageReport is the metered usage and its cost over a period
+type UsageReport struct {
+	UserID      string           `json:"userId,omitempty"` // Empty for all users
+	From        time.Time        `json:"from"`
+	To          time.Time        `json:"to"`
+	Currency    string           `json:"currency"`
+	ComputeCost float64          `json:"computeCost"`
+	StorageCost float64          `json:"storageCost"`
+	TotalCost   float64          `json:"totalCost"`
+	Workspaces  []WorkspaceUsage `json:"workspaces"`
+}
+
+// WorkspaceUsage is the usage and cost of one workspace in a usage report
+type WorkspaceUsage struct {
+	WorkspaceID   string  `json:"workspaceId"`
+	UserID        string  `json:"userId"`
+	Region        string  `json:"region"` // Of the latest record in the period
+	Mode          string  `json:"mode"`
+	RunningHours  float64 `json:"runningHours"`
+	VCPUHours     float64 `json:"vcpuHours"`
+	MemoryGBHours float64 `json:"memoryGBHours"`
+	StorageGBDays float64 `json:"storageGBDays"`
+	ComputeCost   float64 `json:"computeCost"`
+	StorageCost   float64 `json:"storageCost"`
+	TotalCost     float64 `json:"totalCost"`
+	Unpriced      bool    `json:"unpriced,omitempty"` // Some usage had no rate in the rate card
+}
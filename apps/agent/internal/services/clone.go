@@ -0,0 +1,294 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// CloneEnvironmentAsync validates the request and runs CloneEnvironment in the background.
+// The new workspace ID is assigned here so it can be tracked on the operation.
+func (s *EnvironmentService) CloneEnvironmentAsync(ctx context.Context, req *models.CloneEnvironmentRequest) (*models.Operation, error) {
+	if req.WorkspaceID == "" {
+		req.WorkspaceID = uuid.NewString()
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.checkCloneable(ctx, req); err != nil {
+		return nil, err
+	}
+
+	// The source is locked too, so it is not deleted or restored while it is copied
+	_, unlockSource, err := s.lockWorkspace(ctx, req.SourceWorkspaceID, models.OperationClone)
+	if err != nil {
+		return nil, err
+	}
+	op, err := s.submitLocked(ctx, models.OperationClone, req.WorkspaceID, func(ctx context.Context) (interface{}, error) {
+		defer unlockSource()
+		return s.CloneEnvironment(context.WithValue(ctx, heldLockKey{req.SourceWorkspaceID}, models.OperationClone), req)
+	})
+	if err != nil {
+		unlockSource()
+		return nil, err
+	}
+	return op, nil
+}
+
+// CloneEnvironment copies the volume of a workspace into a new workspace and provisions
+// a container for it with the caller's secrets. A running source is only cloned with
+// Force, from a point-in-time snapshot so files being written are copied consistently.
+// On failure everything created for the new workspace is removed.
+func (s *EnvironmentService) CloneEnvironment(ctx context.Context, req *models.CloneEnvironmentRequest) (*models.Environment, error) {
+	if req.WorkspaceID == "" {
+		req.WorkspaceID = uuid.NewString()
+	}
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, unlockSource, err := s.lockWorkspace(ctx, req.SourceWorkspaceID, models.OperationClone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockSource()
+	ctx, unlock, err := s.lockWorkspace(ctx, req.WorkspaceID, models.OperationClone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	source, err := s.checkCloneable(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	place := s.placementFor(source.CloudProvider, source.CloudRegion)
+	volumes := place.volumes
+	workspaceID := req.WorkspaceID
+	sourceShare := fmt.Sprintf("fs-%s", source.ID)
+	fileShareName := fmt.Sprintf("fs-%s", workspaceID)
+
+	name := req.Name
+	if name == "" {
+		name = source.Name + " (copy)"
+	}
+	quotaGB := volumeQuotaGB(source.StorageGB)
+	if props, err := volumes.VolumeProperties(ctx, sourceShare); err == nil && props.QuotaGB > quotaGB {
+		quotaGB = props.QuotaGB
+	}
+
+	// The clone runs under the tier of the source
+	release, err := s.policy.Admit(ctx, PolicyRequest{
+		WorkspaceID: workspaceID,
+		UserID:      req.UserID,
+		OrgID:       source.OrgID,
+		Tier:        source.Tier,
+		Region:      source.CloudRegion,
+		Image:       source.BaseImage,
+		CPUCores:    source.CPUCores,
+		MemoryGB:    source.MemoryGB,
+		StorageGB:   source.StorageGB,
+		NewStorage:  true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	log.Printf("🐑 Cloning workspace %s into %s", source.ID, workspaceID)
+
+	now := time.Now()
+	record := &models.Environment{
+		ID:                 workspaceID,
+		Name:               name,
+		UserID:             req.UserID,
+		Status:             models.StatusCreating,
+		CloudProvider:      source.CloudProvider,
+		CloudRegion:        source.CloudRegion,
+		CPUCores:           source.CPUCores,
+		MemoryGB:           source.MemoryGB,
+		StorageGB:          source.StorageGB,
+		BaseImage:          source.BaseImage,
+		AzureResourceGroup: place.resourceGroup,
+		AzureFileShare:     fileShareName,
+		OrgID:              source.OrgID,
+		Tier:               source.Tier,
+		IdleTimeoutMinutes: source.IdleTimeoutMinutes,
+		SnapshotRetention:  source.SnapshotRetention,
+		EnvVars:            source.EnvVars,
+		Ports:              source.Ports,
+		ClonedFrom:         source.ID,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	s.saveEnvironment(ctx, record)
+
+	containerCreated := false
+	cleanup := func(cause error) error {
+		// Clean up even if the operation was cancelled or timed out
+		ctx := context.WithoutCancel(ctx)
+		if containerCreated {
+			if err := place.containers.Delete(ctx, workspaceID, source.CloudRegion, place.resourceGroup); err != nil && !isContainerNotFound(err) {
+				log.Printf("Warning: workspace %s: failed to delete container of failed clone: %v", workspaceID, err)
+			}
+		}
+		if err := volumes.DeleteVolume(ctx, fileShareName); err != nil {
+			log.Printf("Warning: workspace %s: failed to delete volume of failed clone: %v", workspaceID, err)
+		}
+		s.deleteSavedSecrets(ctx, workspaceID)
+		s.usage.Close(ctx, workspaceID)
+		if err := s.store.Delete(ctx, workspaceID); err != nil {
+			log.Printf("Warning: workspace %s: failed to remove environment record: %v", workspaceID, err)
+		}
+		s.publish(ctx, webhook.EventFailed, workspaceID, map[string]interface{}{
+			"workspaceId": workspaceID,
+			"status":      models.StatusError,
+			"error":       toOperationError(cause),
+		})
+		return cause
+	}
+
+	// The clone gets the caller's secrets; those of the source stay with the source
+	secrets := req.Secrets()
+	if secrets.CodeServerPassword == "" {
+		secrets.CodeServerPassword = generatePassword()
+	}
+	if err := s.saveSecrets(ctx, record, secrets); err != nil {
+		return nil, cleanup(err)
+	}
+	s.saveEnvironment(ctx, record)
+
+	reportProgress(ctx, "creating-volume", 10)
+	if err := volumes.CreateVolume(ctx, fileShareName, quotaGB); err != nil {
+		return nil, cleanup(cloudError(fmt.Sprintf("workspace %s: failed to create volume", workspaceID), err))
+	}
+	if err := s.waitForFileShareAvailability(ctx, volumes, fileShareName, 30*time.Second); err != nil {
+		return nil, cleanup(cloudError(fmt.Sprintf("workspace %s: volume not available after creation", workspaceID), err))
+	}
+
+	reportProgress(ctx, "copying-files", 20)
+	if err := s.copyWorkspaceVolume(ctx, source, sourceShare, fileShareName); err != nil {
+		return nil, cleanup(cloudError(fmt.Sprintf("workspace %s: failed to copy volume of %s", workspaceID, source.ID), err))
+	}
+	log.Printf("✅ Volume %s copied to %s", sourceShare, fileShareName)
+
+	deploySpec := ContainerDeploymentSpec{
+		Image:              s.getContainerImage(source.BaseImage, source.CloudRegion),
+		CPUCores:           float64(source.CPUCores),
+		MemoryGB:           float64(source.MemoryGB),
+		FileShareName:      fileShareName,
+		LocalVolumePath:    localVolumePath(volumes, fileShareName),
+		StorageAccountName: place.storageAccount,
+		StorageAccountKey:  s.config.Azure.StorageAccountKey,
+		UserID:             req.UserID,
+		RegistryServer:     s.getRegistryServer(source.BaseImage, source.CloudRegion),
+		RegistryUsername:   s.config.RegistryUsername,
+		RegistryPassword:   s.config.RegistryPassword,
+		AgentBaseURL:       s.config.AgentBaseURL,
+		Ports:              source.Ports,
+	}
+	deploySpec.applySecrets(secrets, source.EnvVars)
+
+	reportProgress(ctx, "creating-container", 70)
+	containerCreated = true // A failed create may still leave a partial container behind
+	containerInfo, err := place.containers.Create(ctx, workspaceID, source.CloudRegion, place.resourceGroup, deploySpec)
+	if err != nil {
+		return nil, cleanup(cloudError(fmt.Sprintf("workspace %s: failed to create container", workspaceID), err))
+	}
+
+	if containerInfo == nil || containerInfo.FQDN == "" {
+		reportProgress(ctx, "waiting-for-fqdn", 85)
+		if info, err := s.waitForContainerFQDN(ctx, place, workspaceID, 30*time.Second); err != nil {
+			log.Printf("Warning: workspace %s: failed to get container details: %v", workspaceID, err)
+		} else {
+			containerInfo = info
+		}
+	}
+
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if containerInfo != nil {
+		env.AzureFQDN = containerInfo.FQDN
+	}
+	env.Status = models.StatusRunning
+	env.AzureContainerGroup = fmt.Sprintf("%s-%s", place.backendName(s.config), workspaceID)
+	env.ConnectionURLs = connectionURLsFor(containerInfo, secrets.CodeServerPassword, source.Ports)
+	env.UpdatedAt = time.Now()
+	env.LastAccessedAt = time.Now()
+	s.saveEnvironment(ctx, env)
+	s.publish(ctx, webhook.EventCreated, workspaceID, env)
+
+	log.Printf("✅ Workspace %s cloned from %s", workspaceID, source.ID)
+	return env, nil
+}
+
+// copyWorkspaceVolume copies the source volume into dst. A running source is copied
+// from a temporary snapshot, which is deleted afterwards.
+func (s *EnvironmentService) copyWorkspaceVolume(ctx context.Context, source *models.Environment, src, dst string) error {
+	volumes := s.placementFor(source.CloudProvider, source.CloudRegion).volumes
+	progress := filesCopiedProgress(ctx, "copying-files", 40)
+
+	snapshotter, ok := volumes.(VolumeSnapshotter)
+	if source.Status != models.StatusRunning || !ok {
+		return volumes.(VolumeCopier).CopyVolume(ctx, src, dst, progress)
+	}
+
+	snapshot, err := snapshotter.CreateSnapshot(ctx, src, "clone to "+dst)
+	if err != nil {
+		return fmt.Errorf("failed to snapshot source: %w", err)
+	}
+	defer func() {
+		if err := snapshotter.DeleteSnapshot(context.WithoutCancel(ctx), src, snapshot.ID); err != nil {
+			log.Printf("Warning: failed to delete clone snapshot %s of %s: %v", snapshot.ID, src, err)
+		}
+	}()
+	return snapshotter.RestoreSnapshot(ctx, src, snapshot.ID, dst, progress)
+}
+
+// checkCloneable returns the source workspace if it can be cloned into req.WorkspaceID
+func (s *EnvironmentService) checkCloneable(ctx context.Context, req *models.CloneEnvironmentRequest) (*models.Environment, error) {
+	source, err := s.store.Get(ctx, req.SourceWorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch source.Status {
+	case models.StatusStopped:
+	case models.StatusRunning:
+		if !req.Force {
+			return nil, models.ErrConflict(fmt.Sprintf("workspace %s is running and may be writing to its volume; stop it first or use force=true", source.ID))
+		}
+	default:
+		return nil, models.ErrConflict(fmt.Sprintf("workspace %s is %s; only running or stopped workspaces can be cloned", source.ID, source.Status))
+	}
+
+	place := s.placementFor(source.CloudProvider, source.CloudRegion)
+	if place == nil {
+		return nil, models.ErrNotFound(regionUnavailable(source.CloudProvider, source.CloudRegion))
+	}
+	if place.volumes == nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("volume store not found for region %s", source.CloudRegion))
+	}
+	if _, ok := place.volumes.(VolumeCopier); !ok {
+		return nil, models.ErrInvalidRequest(fmt.Sprintf("workspace %s: its volume backend does not support cloning", source.ID))
+	}
+
+	if _, err := s.store.Get(ctx, req.WorkspaceID); err == nil {
+		return nil, models.ErrConflict(fmt.Sprintf("workspace %s already exists", req.WorkspaceID))
+	}
+	exists, err := place.volumes.VolumeExists(ctx, fmt.Sprintf("fs-%s", req.WorkspaceID))
+	if err != nil {
+		return nil, cloudError(fmt.Sprintf("workspace %s: failed to check volume", req.WorkspaceID), err)
+	}
+	if exists {
+		return nil, models.ErrConflict(fmt.Sprintf("workspace %s already has a volume", req.WorkspaceID))
+	}
+	return source, nil
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+func TestEnvironmentService_Clone(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, volumes := newTestEnvironmentService(t, store)
+	const cloneID = "660e8400-e29b-41d4-a716-446655440000"
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "owner",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    8,
+		StorageGB:   10,
+		GitHubToken: "owner-token",
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(volumes.Path("fs-"+wsID), "workspace", "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(volumes.Path("fs-"+wsID), "workspace", "app", "main.go"), "package main")
+
+	// The source is running, so it may be writing
+	req := &models.CloneEnvironmentRequest{SourceWorkspaceID: wsID, WorkspaceID: cloneID, UserID: "teammate", GitHubToken: "teammate-token"}
+	_, err := service.CloneEnvironmentAsync(ctx, req)
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+		t.Fatalf("CloneEnvironmentAsync() of running source error = %v, want CONFLICT", err)
+	}
+
+	req.Force = true
+	env, err := service.CloneEnvironment(ctx, req)
+	if err != nil {
+		t.Fatalf("CloneEnvironment() error = %v", err)
+	}
+	if env.ID != cloneID || env.Status != models.StatusRunning || env.UserID != "teammate" || env.ClonedFrom != wsID || env.MemoryGB != 8 || env.Name != "test-env (copy)" {
+		t.Errorf("clone = %+v, want running copy of %s owned by teammate", env, wsID)
+	}
+	if data, _ := os.ReadFile(filepath.Join(volumes.Path("fs-"+cloneID), "workspace", "app", "main.go")); string(data) != "package main" {
+		t.Errorf("cloned main.go = %q, want %q", data, "package main")
+	}
+	if spec, _ := provider.Spec(cloneID); spec.GitHubToken != "teammate-token" || spec.FileShareName != "fs-"+cloneID {
+		t.Errorf("clone spec = %+v, want the teammate's token on the new volume", spec)
+	}
+	// The temporary snapshot used to copy the running source is gone
+	if snapshots, _ := volumes.ListSnapshots(ctx, "fs-"+wsID); len(snapshots) != 0 {
+		t.Errorf("source snapshots = %v, want none", snapshots)
+	}
+}
+
+func TestEnvironmentService_CloneCleansUpOnFailure(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, volumes := newTestEnvironmentService(t, store)
+	const cloneID = "660e8400-e29b-41d4-a716-446655440000"
+
+	if err := store.Put(ctx, &models.Environment{ID: wsID, Name: "test-env", CloudRegion: "eastus", Status: models.StatusStopped, CPUCores: 2, MemoryGB: 4, StorageGB: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := volumes.CreateVolume(ctx, "fs-"+wsID, 15); err != nil {
+		t.Fatal(err)
+	}
+
+	provider.FailOn(FakeOpCreate, errors.New("quota exceeded"))
+	_, err := service.CloneEnvironment(ctx, &models.CloneEnvironmentRequest{SourceWorkspaceID: wsID, WorkspaceID: cloneID, UserID: "teammate"})
+	if err == nil {
+		t.Fatal("CloneEnvironment() error = nil, want container failure")
+	}
+	if _, err := store.Get(ctx, cloneID); err == nil {
+		t.Error("clone record was not removed")
+	}
+	if exists, _ := volumes.VolumeExists(ctx, "fs-"+cloneID); exists {
+		t.Error("clone volume was not removed")
+	}
+	if env, _ := store.Get(ctx, wsID); env.Status != models.StatusStopped {
+		t.Errorf("source status = %s, want STOPPED", env.Status)
+	}
+}
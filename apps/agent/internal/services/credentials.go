@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+	"golang.org/x/crypto/ssh"
+)
+
+// RotateCredentialsAsync validates the request and runs RotateCredentials in the background
+func (s *EnvironmentService) RotateCredentialsAsync(ctx context.Context, req *models.RotateCredentialsRequest) (*models.Operation, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if s.secrets == nil {
+		return nil, models.ErrInvalidRequest("the agent has no secret store (SECRET_STORE=none), so rotated credentials cannot be kept")
+	}
+
+	env, err := s.store.Get(ctx, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRotatable(env); err != nil {
+		return nil, err
+	}
+	if s.placementFor(env.CloudProvider, env.CloudRegion) == nil {
+		return nil, models.ErrNotFound(regionUnavailable(env.CloudProvider, env.CloudRegion))
+	}
+
+	return s.submitLocked(ctx, models.OperationRotate, req.WorkspaceID, func(ctx context.Context) (interface{}, error) {
+		return s.RotateCredentials(ctx, req)
+	})
+}
+
+// RotateCredentials generates new credentials for a workspace and saves them. A running
+// container is redeployed with them, as a new revision where the provider supports it;
+// a stopped workspace gets them when it next starts.
+func (s *EnvironmentService) RotateCredentials(ctx context.Context, req *models.RotateCredentialsRequest) (*models.CredentialRotation, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, unlock, err := s.lockWorkspace(ctx, req.WorkspaceID, models.OperationRotate)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if s.secrets == nil {
+		return nil, models.ErrInvalidRequest("the agent has no secret store (SECRET_STORE=none), so rotated credentials cannot be kept")
+	}
+
+	env, err := s.store.Get(ctx, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRotatable(env); err != nil {
+		return nil, err
+	}
+	place := s.placementFor(env.CloudProvider, env.CloudRegion)
+	if place == nil {
+		return nil, models.ErrNotFound(regionUnavailable(env.CloudProvider, env.CloudRegion))
+	}
+
+	workspaceID := env.ID
+	previousStatus := env.Status
+	log.Printf("🔑 Rotating %s of workspace %s", strings.Join(req.Credentials, ", "), workspaceID)
+
+	secrets, err := s.loadSecrets(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+	rotation := &models.CredentialRotation{
+		WorkspaceID: workspaceID,
+		Rotated:     req.Credentials,
+		Applied:     models.CredentialsNextStart,
+		RotatedAt:   make(map[string]time.Time),
+	}
+	for _, credential := range req.Credentials {
+		switch credential {
+		case models.CredentialCodeServerPassword:
+			secrets.CodeServerPassword = generatePassword()
+		case models.CredentialSSHKey:
+			publicKey, privateKey, err := generateSSHKey("dev8-" + workspaceID)
+			if err != nil {
+				return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to generate SSH key: %v", workspaceID, err))
+			}
+			secrets.SSHPublicKey = publicKey
+			rotation.SSHPublicKey, rotation.SSHPrivateKey = publicKey, privateKey
+		case models.CredentialGitHubToken:
+			secrets.GitHubToken = req.GitHubToken
+		}
+	}
+
+	// Saved first: whatever happens to the container, the next start uses the new values
+	reportProgress(ctx, "saving-credentials", 10)
+	if err := s.saveSecrets(ctx, env, secrets); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if env.CredentialsRotatedAt == nil {
+		env.CredentialsRotatedAt = make(map[string]time.Time)
+	}
+	for _, credential := range req.Credentials {
+		env.CredentialsRotatedAt[credential] = now
+		rotation.RotatedAt[credential] = now
+	}
+	env.ConnectionURLs.CodeServerPassword = secrets.CodeServerPassword
+	env.UpdatedAt = now
+	s.saveEnvironment(ctx, env)
+
+	if previousStatus == models.StatusRunning {
+		s.setStatus(ctx, workspaceID, env.CloudRegion, models.StatusRotating)
+
+		reportProgress(ctx, "redeploying-container", 30)
+		fileShareName := fmt.Sprintf("fs-%s", workspaceID)
+		deploySpec := ContainerDeploymentSpec{
+			Image:              s.getContainerImage(env.BaseImage, env.CloudRegion),
+			CPUCores:           float64(env.CPUCores),
+			MemoryGB:           float64(env.MemoryGB),
+			FileShareName:      fileShareName,
+			LocalVolumePath:    localVolumePath(place.volumes, fileShareName),
+			StorageAccountName: place.storageAccount,
+			StorageAccountKey:  s.config.Azure.StorageAccountKey,
+			UserID:             env.UserID,
+			RegistryServer:     s.getRegistryServer(env.BaseImage, env.CloudRegion),
+			RegistryUsername:   s.config.RegistryUsername,
+			RegistryPassword:   s.config.RegistryPassword,
+			AgentBaseURL:       s.config.AgentBaseURL,
+			Ports:              env.Ports,
+		}
+		deploySpec.applySecrets(secrets, env.EnvVars)
+
+		containerInfo, err := updateCredentials(ctx, place, workspaceID, env.CloudRegion, deploySpec)
+		switch {
+		case isContainerNotFound(err):
+			log.Printf("Workspace %s has no container, new credentials apply at next start", workspaceID)
+		case err != nil:
+			return nil, s.failEnvironment(ctx, workspaceID, cloudError(fmt.Sprintf("workspace %s: failed to apply new credentials", workspaceID), err))
+		default:
+			rotation.Applied = models.CredentialsRedeployed
+			if containerInfo == nil || containerInfo.FQDN == "" {
+				reportProgress(ctx, "waiting-for-fqdn", 85)
+				if info, err := s.waitForContainerFQDN(ctx, place, workspaceID, 30*time.Second); err != nil {
+					log.Printf("Warning: workspace %s: failed to get container details: %v", workspaceID, err)
+				} else {
+					containerInfo = info
+				}
+			}
+			if containerInfo != nil && containerInfo.FQDN != "" {
+				env.AzureFQDN = containerInfo.FQDN
+				env.ConnectionURLs = connectionURLsFor(containerInfo, secrets.CodeServerPassword, env.Ports)
+			}
+		}
+	}
+
+	env.Status = previousStatus
+	env.StatusMessage = ""
+	env.UpdatedAt = time.Now()
+	s.saveEnvironment(ctx, env)
+	s.publish(ctx, webhook.EventRotated, workspaceID, map[string]interface{}{
+		"workspaceId": workspaceID,
+		"rotated":     rotation.Rotated,
+		"applied":     rotation.Applied,
+	})
+
+	rotation.ConnectionURLs = env.ConnectionURLs
+	log.Printf("✅ Credentials of workspace %s rotated (%s)", workspaceID, rotation.Applied)
+	return rotation, nil
+}
+
+// updateCredentials redeploys a workspace container with the credentials of spec,
+// falling back to Resize, which recreates the container from the full spec
+func updateCredentials(ctx context.Context, place *placement, workspaceID, region string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	if updater, ok := place.containers.(CredentialUpdater); ok {
+		return updater.UpdateCredentials(ctx, workspaceID, region, place.resourceGroup, spec)
+	}
+	return place.containers.Resize(ctx, workspaceID, region, place.resourceGroup, spec)
+}
+
+// checkRotatable only lets settled workspaces have their credentials rotated
+func checkRotatable(env *models.Environment) error {
+	if env.Status != models.StatusRunning && env.Status != models.StatusStopped {
+		return models.ErrConflict(fmt.Sprintf("workspace %s is %s; only running or stopped workspaces can rotate credentials", env.ID, env.Status))
+	}
+	return nil
+}
+
+// generatePassword returns a random 26-character password holding 128 bits
+func generatePassword() string {
+	return rand.Text()
+}
+
+// generateSSHKey returns a new ed25519 key pair as an authorized_keys line and an
+// OpenSSH private key
+func generateSSHKey(comment string) (string, string, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	sshPublicKey, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return "", "", err
+	}
+	block, err := ssh.MarshalPrivateKey(privateKey, comment)
+	if err != nil {
+		return "", "", err
+	}
+
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPublicKey))) + " " + comment
+	return authorizedKey, string(pem.EncodeToMemory(block)), nil
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateSSHKey(t *testing.T) {
+	publicKey, privateKey, err := generateSSHKey("dev8-ws-1")
+	if err != nil {
+		t.Fatalf("generateSSHKey() error = %v", err)
+	}
+	if !strings.HasPrefix(publicKey, "ssh-ed25519 ") || !strings.HasSuffix(publicKey, " dev8-ws-1") {
+		t.Errorf("public key = %q, want an ed25519 authorized_keys line", publicKey)
+	}
+
+	signer, err := ssh.ParsePrivateKey([]byte(privateKey))
+	if err != nil {
+		t.Fatalf("ParsePrivateKey() error = %v", err)
+	}
+	parsed, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil || string(parsed.Marshal()) != string(signer.PublicKey().Marshal()) {
+		t.Errorf("public key does not match the private key (%v)", err)
+	}
+
+	if a, b := generatePassword(), generatePassword(); len(a) < 24 || a == b {
+		t.Errorf("generatePassword() = %q, %q, want long distinct passwords", a, b)
+	}
+}
+
+func TestEnvironmentService_RotateCredentials(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, _ := newTestEnvironmentService(t, store)
+
+	if _, err := service.RotateCredentials(ctx, &models.RotateCredentialsRequest{WorkspaceID: wsID}); err == nil {
+		t.Error("RotateCredentials() without a secret store error = nil")
+	}
+
+	secrets, err := newFileSecrets(filepath.Join(t.TempDir(), "secrets.json"), "")
+	if err != nil {
+		t.Fatalf("newFileSecrets() error = %v", err)
+	}
+	service.SetSecretStore(secrets)
+
+	created, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+		GitHubToken: "ghp_one",
+	})
+	if err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	spec, _ := provider.Spec(wsID)
+	if spec.CodeServerPassword == "" || spec.CodeServerPassword != created.ConnectionURLs.CodeServerPassword {
+		t.Errorf("created with password %q, URLs say %q; want the same generated password", spec.CodeServerPassword, created.ConnectionURLs.CodeServerPassword)
+	}
+
+	// A running workspace is redeployed with the new password and key
+	rotation, err := service.RotateCredentials(ctx, &models.RotateCredentialsRequest{WorkspaceID: wsID})
+	if err != nil {
+		t.Fatalf("RotateCredentials() error = %v", err)
+	}
+	spec, _ = provider.Spec(wsID)
+	if rotation.Applied != models.CredentialsRedeployed {
+		t.Errorf("applied = %s, want %s", rotation.Applied, models.CredentialsRedeployed)
+	}
+	if spec.CodeServerPassword == created.ConnectionURLs.CodeServerPassword || spec.CodeServerPassword != rotation.ConnectionURLs.CodeServerPassword {
+		t.Errorf("redeployed password = %q, returned %q", spec.CodeServerPassword, rotation.ConnectionURLs.CodeServerPassword)
+	}
+	if spec.SSHPublicKey != rotation.SSHPublicKey || rotation.SSHPrivateKey == "" || spec.GitHubToken != "ghp_one" {
+		t.Errorf("redeployed key = %q, token = %q; want the new key and the saved token", spec.SSHPublicKey, spec.GitHubToken)
+	}
+	env, _ := store.Get(ctx, wsID)
+	if env.Status != models.StatusRunning || len(env.CredentialsRotatedAt) != 2 {
+		t.Errorf("after rotation status = %s, rotated at = %v", env.Status, env.CredentialsRotatedAt)
+	}
+
+	// A stopped workspace keeps them for the next start
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	rotation, err = service.RotateCredentials(ctx, &models.RotateCredentialsRequest{
+		WorkspaceID: wsID,
+		Credentials: []string{models.CredentialGitHubToken},
+		GitHubToken: "ghp_two",
+	})
+	if err != nil {
+		t.Fatalf("RotateCredentials() of a stopped workspace error = %v", err)
+	}
+	if rotation.Applied != models.CredentialsNextStart || rotation.SSHPrivateKey != "" {
+		t.Errorf("stopped rotation = %+v", rotation)
+	}
+	if _, err := service.StartEnvironment(ctx, &models.StartEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+	}); err != nil {
+		t.Fatalf("StartEnvironment() error = %v", err)
+	}
+	spec, _ = provider.Spec(wsID)
+	if spec.GitHubToken != "ghp_two" {
+		t.Errorf("started with token %q, want ghp_two", spec.GitHubToken)
+	}
+	env, _ = store.Get(ctx, wsID)
+	if len(env.CredentialsRotatedAt) != 3 {
+		t.Errorf("rotated at after start = %v, want all three credentials", env.CredentialsRotatedAt)
+	}
+}
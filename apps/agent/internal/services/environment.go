@@ -2,93 +2,413 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
-	"strings"
+	"slices"
 	"time"
 
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/aws"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/docker"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/kube"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+	"k8s.io/client-go/kubernetes"
 )
 
 // EnvironmentService handles environment lifecycle operations
 type EnvironmentService struct {
-	config             *config.Config
-	azureClient        *azure.Client
-	storageClients     map[string]*azure.StorageClient
-	deploymentStrategy *DeploymentStrategy
+	config       *config.Config
+	azureClient  *azure.Client
+	dockerClient *docker.Client         // Only set in docker mode
+	kubeClient   kubernetes.Interface   // Only set in kubernetes mode
+	volumes      map[string]VolumeStore // Keyed by region
+	containers   ContainerProvider
+	operations   *OperationManager
+	store        EnvironmentStore
+	events       EventPublisher
+	secrets      SecretStore // nil when secrets are not saved
+	locks        WorkspaceLocker
+	policy       *PolicyEngine
+	regions      *RegionSelector
+	schedules    ScheduleStore
+	usage        *UsageMeter
+
+	// AWS backend for cloudProvider "AWS", only set when AWS regions are configured
+	awsContainers ContainerProvider
+	awsVolumes    map[string]VolumeStore // Keyed by AWS region
+}
+
+// EventPublisher notifies the control plane about lifecycle changes
+type EventPublisher interface {
+	Publish(ctx context.Context, eventType, workspaceID string, data interface{}) error
 }
 
 // NewEnvironmentService creates a new environment service
-func NewEnvironmentService(cfg *config.Config, azureClient *azure.Client) (*EnvironmentService, error) {
-	// No database requirement - Agent is stateless
+func NewEnvironmentService(cfg *config.Config, azureClient *azure.Client, operations *OperationManager, store EnvironmentStore) (*EnvironmentService, error) {
+	// Next.js remains the source of truth; the store is the agent's own registry
+	// of what it has provisioned, where, and since when.
 	service := &EnvironmentService{
-		config:             cfg,
-		azureClient:        azureClient,
-		storageClients:     make(map[string]*azure.StorageClient),
-		deploymentStrategy: NewDeploymentStrategy(cfg, azureClient),
+		config:      cfg,
+		azureClient: azureClient,
+		operations:  operations,
+		store:       store,
+		events:      noopPublisher{},
+		locks:       NewLocalLocker(),
+		policy:      NewPolicyEngine(cfg.Policy, store),
+		regions:     NewRegionSelector(azureClient.Retrier()),
+		schedules:   NewMemoryScheduleStore(),
+		usage:       NewUsageMeter(cfg),
 	}
+	service.policy.SetUsageMeter(service.usage)
 
-	// Initialize storage clients for all regions
-	for _, region := range cfg.Azure.Regions {
-		if region.Enabled && region.StorageAccount != "" {
-			storageClient, err := azure.NewStorageClient(region.StorageAccount, cfg.Azure.StorageAccountKey)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create storage client for region %s: %w", region.Name, err)
-			}
-			service.storageClients[region.Name] = storageClient
+	if cfg.Azure.DeploymentMode == "docker" {
+		dockerClient, err := docker.NewClient(cfg.Docker.Host)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create docker client: %w", err)
+		}
+		service.dockerClient = dockerClient
+	}
+
+	if cfg.Azure.DeploymentMode == "kubernetes" {
+		kubeClient, err := kube.NewClientset(cfg.Kubernetes.Kubeconfig, cfg.Kubernetes.Context)
+		if err != nil {
+			return nil, err
+		}
+		service.kubeClient = kubeClient
+	}
+
+	clients := ProviderClients{Azure: azureClient, Docker: service.dockerClient, Kubernetes: service.kubeClient}
+	containers, err := NewContainerProvider(cfg, clients)
+	if err != nil {
+		return nil, err
+	}
+	service.containers = containers
+
+	volumes, err := newVolumeStores(cfg, clients)
+	if err != nil {
+		return nil, err
+	}
+	service.volumes = volumes
+
+	if cfg.UsesAWS() {
+		awsClient, err := aws.NewClient(context.Background(), cfg)
+		if err != nil {
+			return nil, err
+		}
+		service.awsContainers, service.awsVolumes, err = newAWSBackend(cfg, awsClient)
+		if err != nil {
+			return nil, err
 		}
 	}
 
 	return service, nil
 }
 
+// SetEventPublisher sends lifecycle events to publisher instead of discarding them
+func (s *EnvironmentService) SetEventPublisher(publisher EventPublisher) {
+	s.events = publisher
+}
+
 // Close releases service resources.
 func (s *EnvironmentService) Close() {
-	// Nothing to close - stateless!
+	// Nothing to close - the store is owned by the caller
+}
+
+// GetEnvironment returns the stored record for a workspace
+func (s *EnvironmentService) GetEnvironment(ctx context.Context, workspaceID string) (*models.Environment, error) {
+	return s.store.Get(ctx, workspaceID)
+}
+
+// ListEnvironments returns one page of stored workspaces matching the filter
+func (s *EnvironmentService) ListEnvironments(ctx context.Context, filter EnvironmentFilter) (*models.EnvironmentListResponse, error) {
+	filter.Normalize()
+
+	envs, total, err := s.store.List(ctx, filter)
+	if err != nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("failed to list environments: %v", err))
+	}
+
+	return &models.EnvironmentListResponse{
+		Environments: envs,
+		Total:        total,
+		Page:         filter.Page,
+		PageSize:     filter.PageSize,
+	}, nil
+}
+
+// CreateEnvironmentAsync validates the request and runs CreateEnvironment in the background.
+// Validation errors are returned immediately; provisioning errors are reported on the operation.
+func (s *EnvironmentService) CreateEnvironmentAsync(ctx context.Context, req *models.CreateEnvironmentRequest) (*models.Operation, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	places, err := s.createPlacements(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// A retried create joins the one in flight, or gets the workspace it created
+	if op := s.operations.Active(req.WorkspaceID, models.OperationCreate); op != nil {
+		return op, nil
+	}
+	existing, err := s.existingCreate(ctx, req, places)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return s.operations.Completed(models.OperationCreate, req.WorkspaceID, existing), nil
+	}
+
+	// Policy rejections are returned up front; the reservation lasts until the operation ends
+	release, err := s.policy.Admit(ctx, createPolicyRequest(req, places[0].region))
+	if err != nil {
+		return nil, err
+	}
+	op, err := s.submitLocked(ctx, models.OperationCreate, req.WorkspaceID, func(ctx context.Context) (interface{}, error) {
+		defer release()
+		return s.CreateEnvironment(ctx, req)
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return op, nil
+}
+
+// StartEnvironmentAsync runs StartEnvironment in the background
+func (s *EnvironmentService) StartEnvironmentAsync(ctx context.Context, req *models.StartEnvironmentRequest) (*models.Operation, error) {
+	provider := s.cloudProviderOf(ctx, req.WorkspaceID, req.CloudRegion)
+	if s.placementFor(provider, req.CloudRegion) == nil {
+		return nil, models.ErrNotFound(regionUnavailable(provider, req.CloudRegion))
+	}
+
+	existing, err := s.store.Get(ctx, req.WorkspaceID)
+	if err != nil {
+		existing = &models.Environment{ID: req.WorkspaceID}
+	}
+	release, err := s.policy.Admit(ctx, startPolicyRequest(req, existing))
+	if err != nil {
+		return nil, err
+	}
+	op, err := s.submitLocked(ctx, models.OperationStart, req.WorkspaceID, func(ctx context.Context) (interface{}, error) {
+		defer release()
+		return s.StartEnvironment(ctx, req)
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return op, nil
+}
+
+// StopEnvironmentAsync runs StopEnvironment in the background
+func (s *EnvironmentService) StopEnvironmentAsync(ctx context.Context, workspaceID, region string) (*models.Operation, error) {
+	provider := s.cloudProviderOf(ctx, workspaceID, region)
+	if s.placementFor(provider, region) == nil {
+		return nil, models.ErrNotFound(regionUnavailable(provider, region))
+	}
+
+	return s.submitLocked(ctx, models.OperationStop, workspaceID, func(ctx context.Context) (interface{}, error) {
+		if err := s.StopEnvironment(ctx, workspaceID, region); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"workspaceId": workspaceID, "status": models.StatusStopped}, nil
+	})
+}
+
+// DeleteEnvironmentAsync runs DeleteEnvironment in the background
+func (s *EnvironmentService) DeleteEnvironmentAsync(ctx context.Context, workspaceID, region string, force bool) (*models.Operation, error) {
+	provider := s.cloudProviderOf(ctx, workspaceID, region)
+	if s.placementFor(provider, region) == nil {
+		return nil, models.ErrNotFound(regionUnavailable(provider, region))
+	}
+
+	return s.submitLocked(ctx, models.OperationDelete, workspaceID, func(ctx context.Context) (interface{}, error) {
+		if err := s.DeleteEnvironment(ctx, workspaceID, region, force); err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"workspaceId": workspaceID, "deleted": true}, nil
+	})
 }
 
 // CreateEnvironment creates a new cloud development environment
-func (s *EnvironmentService) CreateEnvironment(ctx context.Context, req *models.CreateEnvironmentRequest) (*models.Environment, error) {
+func (s *EnvironmentService) CreateEnvironment(ctx context.Context, req *models.CreateEnvironmentRequest) (_ *models.Environment, err error) {
+	region := req.CloudRegion // The candidate region once provisioning starts
+	defer func() { s.observeOperation(models.OperationCreate, req.CloudProvider, region, err) }()
+
 	// CRITICAL: workspaceId (UUID) comes from Next.js (already created in DB)
 	if err := req.Validate(); err != nil {
 		return nil, err
 	}
+	ctx, unlock, err := s.lockWorkspace(ctx, req.WorkspaceID, models.OperationCreate)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 
-	// Validate region
-	regionConfig := s.config.GetRegion(req.CloudRegion)
-	if regionConfig == nil {
-		return nil, models.ErrInvalidRequest(fmt.Sprintf("region %s is not available", req.CloudRegion))
+	// Resolve the region, or the candidate regions when the agent chooses
+	places, err := s.createPlacements(req)
+	if err != nil {
+		return nil, err
 	}
+	region = places[0].region
 
-	// Get storage client for region
-	storageClient, ok := s.storageClients[req.CloudRegion]
-	if !ok {
-		return nil, models.ErrInternalServer(fmt.Sprintf("storage client not found for region %s", req.CloudRegion))
+	existing, err := s.existingCreate(ctx, req, places)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		log.Printf("Workspace %s already exists, returning it", req.WorkspaceID)
+		return existing, nil
 	}
 
+	// The reservation covers the workspace until its record counts it
+	release, err := s.policy.Admit(ctx, createPolicyRequest(req, places[0].region))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
 	// IMPORTANT: Use workspaceId for all Azure resource names
 	workspaceID := req.WorkspaceID // UUID from database (e.g., "clxxx-yyyy-zzzz")
 
-	log.Printf("🚀 Creating workspace %s (region: %s)", workspaceID, req.CloudRegion)
+	log.Printf("🚀 Creating workspace %s (region: %s)", workspaceID, places[0].region)
 	overallStartTime := time.Now()
 
 	// Azure resource names based on UUID and deployment mode
 	fileShareName := fmt.Sprintf("fs-%s", workspaceID) // fs-clxxx-yyyy-zzzz (unified volume)
 
-	resourceGroup := regionConfig.ResourceGroupName
-	if resourceGroup == "" {
-		resourceGroup = s.config.Azure.ResourceGroupName
+	// Register the workspace before touching Azure so half-provisioned resources are traceable
+	now := time.Now()
+	record := &models.Environment{
+		ID:                 workspaceID,
+		Name:               req.Name,
+		UserID:             req.UserID,
+		OrgID:              req.OrgID,
+		Status:             models.StatusCreating,
+		CloudProvider:      places[0].provider,
+		CloudRegion:        places[0].region,
+		CPUCores:           req.CPUCores,
+		MemoryGB:           req.MemoryGB,
+		StorageGB:          req.StorageGB,
+		BaseImage:          req.BaseImage,
+		AzureResourceGroup: places[0].resourceGroup,
+		AzureFileShare:     fileShareName,
+		Tier:               req.Tier,
+		IdleTimeoutMinutes: req.IdleTimeoutMinutes,
+		SnapshotRetention:  req.SnapshotRetention,
+		EnvVars:            models.StoredEnvVars(req.EnvVars),
+		Ports:              req.Ports,
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	// A generated password is saved too, so the container and the URLs agree
+	secrets := req.Secrets()
+	if secrets.CodeServerPassword == "" {
+		secrets.CodeServerPassword = generatePassword()
+	}
+	if err := s.saveSecrets(ctx, record, secrets); err != nil {
+		s.saveEnvironment(ctx, record)
+		return nil, s.failEnvironment(ctx, workspaceID, err)
+	}
+	s.saveEnvironment(ctx, record)
+
+	// Regions out of capacity hand the workspace to the next candidate, share and all
+	var place *placement
+	for i, candidate := range places {
+		if i > 0 {
+			log.Printf("⚠️  Workspace %s: region %s failed (%v), failing over to %s", workspaceID, places[i-1].region, err, candidate.region)
+			record.CloudRegion = candidate.region
+			record.AzureResourceGroup = candidate.resourceGroup
+			record.UpdatedAt = time.Now()
+			s.saveEnvironment(ctx, record)
+		}
+		region = candidate.region
+		err = s.provisionWorkspace(ctx, req, candidate, fileShareName, secrets)
+		s.regions.Record(candidate.region, err)
+		if err == nil {
+			place = candidate
+			break
+		}
+		if i == len(places)-1 || !regionFailure(err) {
+			return nil, s.failEnvironment(ctx, workspaceID, err)
+		}
+	}
+
+	// Wait for container to get FQDN
+	reportProgress(ctx, "waiting-for-fqdn", 85)
+	fqdnStart := time.Now()
+	containerInfo, fqdnErr := s.waitForContainerFQDN(ctx, place, workspaceID, 30*time.Second)
+	s.observePhase(phaseFQDNWait, place, fqdnStart)
+	if fqdnErr != nil {
+		log.Printf("Warning: workspace %s: failed to get container details: %v", workspaceID, fqdnErr)
 	}
 
-	// Log image source
-	containerImage := s.getContainerImage(req.BaseImage)
-	if s.config.Azure.ContainerRegistry != "" {
-		log.Printf("🐳 Using Azure Container Registry: %s", containerImage)
-	} else {
-		log.Printf("🐳 Using Docker Hub: %s", containerImage)
+	// Generate connection URLs
+	var fqdn string
+	if containerInfo != nil {
+		fqdn = containerInfo.FQDN
 	}
+	connectionURLs := connectionURLsFor(containerInfo, secrets.CodeServerPassword, req.Ports)
+
+	// Build environment response
+	env := &models.Environment{
+		ID:          workspaceID, // CRITICAL: Return the UUID from request
+		Name:        req.Name,
+		UserID:      req.UserID,
+		Status:      models.StatusRunning,
+		CloudRegion: place.region, // The region chosen, for "auto" and preference lists
+		CPUCores:    req.CPUCores,
+		MemoryGB:    req.MemoryGB,
+		StorageGB:   req.StorageGB,
+		BaseImage:   req.BaseImage,
+
+		// Azure resource identifiers (all based on UUID)
+		AzureResourceGroup:  place.resourceGroup,
+		AzureContainerGroup: fmt.Sprintf("%s-%s", place.backendName(s.config), workspaceID),
+		AzureFileShare:      fileShareName, // fs-clxxx-yyyy-zzzz
+		AzureFQDN:           fqdn,          // ws-clxxx-yyyy-zzzz.eastus.azurecontainer.io (or ACA FQDN)
+
+		// Connection URLs (contain UUID)
+		ConnectionURLs: connectionURLs,
+
+		OrgID:              req.OrgID,
+		Tier:               req.Tier,
+		IdleTimeoutMinutes: req.IdleTimeoutMinutes,
+		SnapshotRetention:  req.SnapshotRetention,
+		EnvVars:            models.StoredEnvVars(req.EnvVars),
+		Ports:              req.Ports,
+		SecretRef:          record.SecretRef,
+		SecretsUpdatedAt:   record.SecretsUpdatedAt,
+
+		CloudProvider:  place.provider,
+		CreatedAt:      now,
+		UpdatedAt:      time.Now(),
+		LastAccessedAt: time.Now(), // The idle clock starts when the workspace becomes reachable
+	}
+	s.saveEnvironment(ctx, env)
+	s.publish(ctx, webhook.EventCreated, workspaceID, env)
+
+	totalDuration := time.Since(overallStartTime)
+	log.Printf("⚡⚡⚡ WORKSPACE READY in %s (all operations ran concurrently!)", totalDuration)
+	log.Printf("✅ Workspace %s: %s", workspaceID, fqdn)
+
+	// Next.js will update its own workspace record from the operation result
+	return env, nil
+}
+
+// provisionWorkspace creates the file share and container of a new workspace in
+// place. A failed container takes its share with it, so the region is left clean.
+func (s *EnvironmentService) provisionWorkspace(ctx context.Context, req *models.CreateEnvironmentRequest, place *placement, fileShareName string, secrets *models.WorkspaceSecrets) error {
+	workspaceID := req.WorkspaceID
+	volumes := place.volumes
+
+	// Log image source
+	containerImage := s.getContainerImage(req.BaseImage, place.region)
+	log.Printf("🐳 Using %s image: %s", req.BaseImage, containerImage)
 
 	// ⚡⚡⚡ MAXIMUM CONCURRENCY: Start ALL operations in PARALLEL
 	log.Printf("⚡⚡⚡ Starting CONCURRENT creation (unified volume + container) for workspace %s...", workspaceID)
@@ -106,13 +426,16 @@ func (s *EnvironmentService) CreateEnvironment(ctx context.Context, req *models.
 	// Goroutine 1: Create unified file share (includes workspace + home subdirectories)
 	go func() {
 		// Safe conversion: validate StorageGB is non-negative and won't overflow
-		if req.StorageGB < 0 || req.StorageGB > (1<<31-1-5) {
+		if req.StorageGB < 0 || req.StorageGB > (1<<31-1-volumeHeadroomGB) {
 			volumeChan <- operationResult{name: "unified-volume", err: fmt.Errorf("workspace %s: invalid storage size: %d", workspaceID, req.StorageGB)}
 			return
 		}
-		totalQuotaGB := int32(req.StorageGB) + 5 // nolint:gosec // G115: validated above to prevent overflow
+		totalQuotaGB := volumeQuotaGB(req.StorageGB)
+		reportProgress(ctx, "creating-volume", 10)
 		log.Printf("📁 [1/2] Creating unified volume: %s (%dGB) - contains workspace/ and home/", fileShareName, totalQuotaGB)
-		err := storageClient.CreateFileShare(ctx, fileShareName, totalQuotaGB)
+		phaseStart := time.Now()
+		err := volumes.CreateVolume(ctx, fileShareName, totalQuotaGB)
+		s.observePhase(phaseFileShareCreate, place, phaseStart)
 		volumeChan <- operationResult{name: "unified-volume", err: err}
 	}()
 
@@ -121,14 +444,18 @@ func (s *EnvironmentService) CreateEnvironment(ctx context.Context, req *models.
 		// Wait for volume creation to complete FIRST
 		volResult := <-volumeChan
 		if volResult.err != nil {
-			// Volume creation failed, propagate error
-			aciChan <- operationResult{name: "container", err: fmt.Errorf("workspace %s: volume creation failed, skipping container creation: %w", workspaceID, volResult.err)}
+			// Volume creation failed, propagate its result and skip the container
+			aciChan <- volResult
 			return
 		}
 
 		// Volume created successfully, now verify it's fully propagated in Azure
 		// Poll for file share availability with exponential backoff
-		if err := s.waitForFileShareAvailability(ctx, storageClient, fileShareName, 30*time.Second); err != nil {
+		reportProgress(ctx, "waiting-for-volume", 25)
+		phaseStart := time.Now()
+		err := s.waitForFileShareAvailability(ctx, volumes, fileShareName, 30*time.Second)
+		s.observePhase(phaseSharePropagationWait, place, phaseStart)
+		if err != nil {
 			aciChan <- operationResult{name: "container", err: fmt.Errorf("workspace %s: file share not available after creation: %w", workspaceID, err)}
 			return
 		}
@@ -138,25 +465,23 @@ func (s *EnvironmentService) CreateEnvironment(ctx context.Context, req *models.
 			CPUCores:           float64(req.CPUCores),
 			MemoryGB:           float64(req.MemoryGB),
 			FileShareName:      fileShareName,
-			StorageAccountName: regionConfig.StorageAccount,
+			LocalVolumePath:    localVolumePath(volumes, fileShareName),
+			StorageAccountName: place.storageAccount,
 			StorageAccountKey:  s.config.Azure.StorageAccountKey,
 			UserID:             req.UserID,
-			RegistryServer:     s.getRegistryServer(),
+			RegistryServer:     s.getRegistryServer(req.BaseImage, place.region),
 			RegistryUsername:   s.config.RegistryUsername,
 			RegistryPassword:   s.config.RegistryPassword,
 			AgentBaseURL:       s.config.AgentBaseURL,
-			GitHubToken:        req.GitHubToken,
-			CodeServerPassword: req.CodeServerPassword,
-			SSHPublicKey:       req.SSHPublicKey,
-			GitUserName:        req.GitUserName,
-			GitUserEmail:       req.GitUserEmail,
-			AnthropicAPIKey:    req.AnthropicAPIKey,
-			OpenAIAPIKey:       req.OpenAIAPIKey,
-			GeminiAPIKey:       req.GeminiAPIKey,
-		}
-
-		log.Printf("📦 [2/2] Creating %s container for workspace %s", s.config.Azure.DeploymentMode, workspaceID)
-		_, err := s.deploymentStrategy.CreateContainer(ctx, workspaceID, req.CloudRegion, resourceGroup, deploySpec)
+			Ports:              req.Ports,
+		}
+		deploySpec.applySecrets(secrets, req.EnvVars)
+
+		reportProgress(ctx, "creating-container", 40)
+		log.Printf("📦 [2/2] Creating %s container for workspace %s", place.backendName(s.config), workspaceID)
+		phaseStart = time.Now()
+		_, err = place.containers.Create(ctx, workspaceID, place.region, place.resourceGroup, deploySpec)
+		s.observePhase(phaseContainerCreate, place, phaseStart)
 		aciChan <- operationResult{name: "container", err: err}
 	}()
 
@@ -168,236 +493,318 @@ func (s *EnvironmentService) CreateEnvironment(ctx context.Context, req *models.
 
 	// Check for errors (cleanup on failure)
 	if aciResult.err != nil {
-		// Check if error was from volume creation or container creation
-		if aciResult.name == "container" {
-			// Could be volume or container error - check message
-			errMsg := aciResult.err.Error()
-			if strings.Contains(errMsg, "volume creation failed") {
-				return nil, fmt.Errorf("workspace %s: failed to create unified file share: %w", workspaceID, aciResult.err)
-			}
-			// Container creation failed - cleanup file share
-			_ = storageClient.DeleteFileShare(ctx, fileShareName)
-			return nil, fmt.Errorf("workspace %s: failed to create container: %w", workspaceID, aciResult.err)
+		// The result names the step that failed
+		if aciResult.name == "unified-volume" {
+			return cloudError(fmt.Sprintf("workspace %s: failed to create unified file share in %s", workspaceID, place.region), aciResult.err)
 		}
+		// Container creation failed - cleanup file share
+		_ = volumes.DeleteVolume(ctx, fileShareName)
+		return cloudError(fmt.Sprintf("workspace %s: failed to create container in %s", workspaceID, place.region), aciResult.err)
 	}
 
-	// Wait for container to get FQDN
-	time.Sleep(3 * time.Second)
-
-	// Get container details
-	containerInfo, err := s.deploymentStrategy.GetContainer(ctx, workspaceID, req.CloudRegion, resourceGroup)
-	if err != nil {
-		log.Printf("Warning: workspace %s: failed to get container details: %v", workspaceID, err)
-	}
+	return nil
+}
 
-	// Generate connection URLs
-	var fqdn string
-	if containerInfo != nil {
-		fqdn = containerInfo.FQDN
+// existingCreate checks whether the workspace of a create request already exists in
+// one of its candidate regions. A repeat of the request that created it returns the
+// environment; any other collision, including a leftover volume, is a CONFLICT rather
+// than a failed provisioning attempt.
+func (s *EnvironmentService) existingCreate(ctx context.Context, req *models.CreateEnvironmentRequest, places []*placement) (*models.Environment, error) {
+	existing, err := s.store.Get(ctx, req.WorkspaceID)
+	var appErr *models.AppError
+	switch {
+	case err == nil:
+		inCandidate := slices.ContainsFunc(places, func(place *placement) bool { return place.region == existing.CloudRegion })
+		if existing.UserID != req.UserID || existing.CloudProvider != places[0].provider || !inCandidate ||
+			existing.CPUCores != req.CPUCores || existing.MemoryGB != req.MemoryGB || existing.StorageGB != req.StorageGB || existing.BaseImage != req.BaseImage {
+			return nil, models.ErrConflict(fmt.Sprintf("workspace %s already exists with different settings", req.WorkspaceID))
+		}
+		switch existing.Status {
+		case models.StatusCreating:
+			return nil, models.ErrConflict(fmt.Sprintf("workspace %s is already being created", req.WorkspaceID))
+		case models.StatusError, models.StatusDeleting:
+			return nil, models.ErrConflict(fmt.Sprintf("workspace %s already exists and is %s; delete it before creating it again", req.WorkspaceID, existing.Status))
+		}
+		return existing, nil
+	case !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND":
+		return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to read environment: %v", req.WorkspaceID, err))
 	}
-	connectionURLs := generateConnectionURLs(fqdn, "")
-
-	// Build environment response
-	env := &models.Environment{
-		ID:          workspaceID, // CRITICAL: Return the UUID from request
-		Name:        req.Name,
-		UserID:      req.UserID,
-		Status:      "running",
-		CloudRegion: req.CloudRegion,
-		CPUCores:    req.CPUCores,
-		MemoryGB:    req.MemoryGB,
-		StorageGB:   req.StorageGB,
-		BaseImage:   req.BaseImage,
-
-		// Azure resource identifiers (all based on UUID)
-		AzureResourceGroup:  resourceGroup,
-		AzureContainerGroup: fmt.Sprintf("%s-%s", s.config.Azure.DeploymentMode, workspaceID),
-		AzureFileShare:      fileShareName, // fs-clxxx-yyyy-zzzz
-		AzureFQDN:           fqdn,          // ws-clxxx-yyyy-zzzz.eastus.azurecontainer.io (or ACA FQDN)
-
-		// Connection URLs (contain UUID)
-		ConnectionURLs: connectionURLs,
 
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	fileShareName := fmt.Sprintf("fs-%s", req.WorkspaceID)
+	for _, place := range places {
+		exists, err := place.volumes.VolumeExists(ctx, fileShareName)
+		if err != nil {
+			return nil, cloudError(fmt.Sprintf("workspace %s: failed to check volume in %s", req.WorkspaceID, place.region), err)
+		}
+		if exists {
+			return nil, models.ErrConflict(fmt.Sprintf("workspace %s has no record but its volume %s exists in %s; delete the workspace before creating it again",
+				req.WorkspaceID, fileShareName, place.region))
+		}
 	}
-
-	totalDuration := time.Since(overallStartTime)
-	log.Printf("⚡⚡⚡ WORKSPACE READY in %s (all operations ran concurrently!)", totalDuration)
-	log.Printf("✅ Workspace %s: %s", workspaceID, fqdn)
-
-	// ❌ NO DATABASE OPERATIONS - Next.js will update the workspace with these details
-	return env, nil
+	return nil, nil
 }
 
 // StartEnvironment recreates container with existing volumes (fast restart)
-func (s *EnvironmentService) StartEnvironment(ctx context.Context, req *models.StartEnvironmentRequest) (*models.Environment, error) {
+func (s *EnvironmentService) StartEnvironment(ctx context.Context, req *models.StartEnvironmentRequest) (_ *models.Environment, err error) {
+	var provider models.CloudProvider
+	defer func() { s.observeOperation(models.OperationStart, provider, req.CloudRegion, err) }()
+
+	ctx, unlock, err := s.lockWorkspace(ctx, req.WorkspaceID, models.OperationStart)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
 	// Validate region
-	regionConfig := s.config.GetRegion(req.CloudRegion)
-	if regionConfig == nil {
-		return nil, models.ErrNotFound(fmt.Sprintf("region %s is not available", req.CloudRegion))
+	provider = s.cloudProviderOf(ctx, req.WorkspaceID, req.CloudRegion)
+	place := s.placementFor(provider, req.CloudRegion)
+	if place == nil {
+		return nil, models.ErrNotFound(regionUnavailable(provider, req.CloudRegion))
 	}
 
-	storageClient, ok := s.storageClients[req.CloudRegion]
-	if !ok {
-		return nil, models.ErrInternalServer(fmt.Sprintf("storage client not found for region %s", req.CloudRegion))
+	volumes := place.volumes
+	if volumes == nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("volume store not found for region %s", req.CloudRegion))
 	}
 
 	workspaceID := req.WorkspaceID
 	fileShareName := fmt.Sprintf("fs-%s", workspaceID)
-
-	resourceGroup := regionConfig.ResourceGroupName
-	if resourceGroup == "" {
-		resourceGroup = s.config.Azure.ResourceGroupName
-	}
+	resourceGroup := place.resourceGroup
 
 	log.Printf("🚀 Starting workspace %s (checking volume...)", workspaceID)
+	reportProgress(ctx, "checking-volume", 10)
 
 	// Verify unified volume exists
-	volumeExists, err := storageClient.FileShareExists(ctx, fileShareName)
+	volumeExists, err := volumes.VolumeExists(ctx, fileShareName)
 	if err != nil {
-		return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to check volume: %v", workspaceID, err))
+		return nil, cloudError(fmt.Sprintf("workspace %s: failed to check volume", workspaceID), err)
 	}
 	if !volumeExists {
 		return nil, models.ErrNotFound(fmt.Sprintf("workspace %s: unified volume not found: %s. Create environment first.", workspaceID, fileShareName))
 	}
 
+	// Env vars, ports, the tier and the secret reference are set at creation and kept in the record
+	existing, existingErr := s.store.Get(ctx, workspaceID)
+	if existingErr != nil {
+		existing = &models.Environment{ID: workspaceID}
+	}
+
+	release, err := s.policy.Admit(ctx, startPolicyRequest(req, existing))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	s.setStatus(ctx, workspaceID, req.CloudRegion, models.StatusStarting)
+
 	log.Printf("✅ Unified volume verified: %s", fileShareName)
 
 	// Start or restart container with existing volumes (fast!)
+	reportProgress(ctx, "starting-container", 30)
 	log.Printf("📦 Starting container instance with existing volumes...")
 
+	secrets, err := s.resolveSecrets(ctx, existing, req.Secrets())
+	if err != nil {
+		return nil, s.failEnvironment(ctx, workspaceID, err)
+	}
+
 	deploySpec := ContainerDeploymentSpec{
-		Image:              s.getContainerImage(req.BaseImage),
+		Image:              s.getContainerImage(req.BaseImage, req.CloudRegion),
 		CPUCores:           float64(req.CPUCores),
 		MemoryGB:           float64(req.MemoryGB),
 		FileShareName:      fileShareName,
-		StorageAccountName: regionConfig.StorageAccount,
+		LocalVolumePath:    localVolumePath(volumes, fileShareName),
+		StorageAccountName: place.storageAccount,
 		StorageAccountKey:  s.config.Azure.StorageAccountKey,
 		UserID:             req.UserID,
-		RegistryServer:     s.getRegistryServer(),
+		RegistryServer:     s.getRegistryServer(req.BaseImage, req.CloudRegion),
 		RegistryUsername:   s.config.RegistryUsername,
 		RegistryPassword:   s.config.RegistryPassword,
 		AgentBaseURL:       s.config.AgentBaseURL,
-		GitHubToken:        req.GitHubToken,
-		CodeServerPassword: req.CodeServerPassword,
-		SSHPublicKey:       req.SSHPublicKey,
-		GitUserName:        req.GitUserName,
-		GitUserEmail:       req.GitUserEmail,
-		AnthropicAPIKey:    req.AnthropicAPIKey,
-		OpenAIAPIKey:       req.OpenAIAPIKey,
-		GeminiAPIKey:       req.GeminiAPIKey,
+		Ports:              existing.Ports,
 	}
+	deploySpec.applySecrets(secrets, existing.EnvVars)
 
-	containerInfo, err := s.deploymentStrategy.StartContainer(ctx, workspaceID, req.CloudRegion, resourceGroup, deploySpec)
+	phaseStart := time.Now()
+	containerInfo, err := place.containers.Start(ctx, workspaceID, req.CloudRegion, resourceGroup, deploySpec)
+	s.observePhase(phaseContainerStart, place, phaseStart)
 	if err != nil {
-		return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to start container: %v", workspaceID, err))
+		return nil, s.failEnvironment(ctx, workspaceID, cloudError(fmt.Sprintf("workspace %s: failed to start container", workspaceID), err))
 	}
 
-	// Wait for FQDN
-	time.Sleep(3 * time.Second)
+	// Wait for FQDN (only needed when the provider didn't return one)
+	if containerInfo == nil || containerInfo.FQDN == "" {
+		reportProgress(ctx, "waiting-for-fqdn", 85)
+		phaseStart = time.Now()
+		info, err := s.waitForContainerFQDN(ctx, place, workspaceID, 30*time.Second)
+		s.observePhase(phaseFQDNWait, place, phaseStart)
+		if err != nil {
+			log.Printf("Warning: workspace %s: failed to get container details: %v", workspaceID, err)
+		} else {
+			containerInfo = info
+		}
+	}
 
 	var fqdn string
 	if containerInfo != nil {
 		fqdn = containerInfo.FQDN
 	}
 
-	connectionURLs := generateConnectionURLs(fqdn, req.CodeServerPassword)
+	connectionURLs := connectionURLsFor(containerInfo, secrets.CodeServerPassword, existing.Ports)
 
 	env := &models.Environment{
 		ID:                  workspaceID,
 		Name:                req.Name,
 		UserID:              req.UserID,
 		Status:              models.StatusRunning,
+		CloudProvider:       place.provider,
 		CloudRegion:         req.CloudRegion,
 		CPUCores:            req.CPUCores,
 		MemoryGB:            req.MemoryGB,
 		StorageGB:           req.StorageGB,
 		BaseImage:           req.BaseImage,
 		AzureResourceGroup:  resourceGroup,
-		AzureContainerGroup: fmt.Sprintf("%s-%s", s.config.Azure.DeploymentMode, workspaceID),
+		AzureContainerGroup: fmt.Sprintf("%s-%s", place.backendName(s.config), workspaceID),
 		AzureFileShare:      fileShareName,
 		AzureFQDN:           fqdn,
 		ConnectionURLs:      connectionURLs,
+		SecretRef:           existing.SecretRef,
+		SecretsUpdatedAt:    existing.SecretsUpdatedAt,
 		CreatedAt:           time.Now(),
 		UpdatedAt:           time.Now(),
+		LastAccessedAt:      time.Now(), // Restart the idle clock
+	}
+	if existingErr == nil {
+		env.CreatedAt = existing.CreatedAt
+		env.OrgID = existing.OrgID
+		env.Tier = existing.Tier
+		env.IdleTimeoutMinutes = existing.IdleTimeoutMinutes
+		env.Snapshots = existing.Snapshots
+		env.SnapshotRetention = existing.SnapshotRetention
+		env.RestoredFrom = existing.RestoredFrom
+		env.ClonedFrom = existing.ClonedFrom
+		env.EnvVars = existing.EnvVars
+		env.Ports = existing.Ports
+		env.CredentialsRotatedAt = existing.CredentialsRotatedAt
+		env.BudgetWarnedAt = existing.BudgetWarnedAt
 	}
+	s.saveEnvironment(ctx, env)
+	s.publish(ctx, webhook.EventStarted, workspaceID, env)
 
 	log.Printf("✅ Workspace %s started successfully (reused existing unified volume)", workspaceID)
 	return env, nil
 }
 
 // StopEnvironment deletes ACI instance but KEEPS volumes (cost optimization)
-func (s *EnvironmentService) StopEnvironment(ctx context.Context, workspaceID, region string) error {
-	regionConfig := s.config.GetRegion(region)
-	if regionConfig == nil {
-		return models.ErrNotFound(fmt.Sprintf("region %s is not available", region))
-	}
+func (s *EnvironmentService) StopEnvironment(ctx context.Context, workspaceID, region string) (err error) {
+	var provider models.CloudProvider
+	defer func() { s.observeOperation(models.OperationStop, provider, region, err) }()
 
-	resourceGroup := regionConfig.ResourceGroupName
-	if resourceGroup == "" {
-		resourceGroup = s.config.Azure.ResourceGroupName
+	ctx, unlock, err := s.lockWorkspace(ctx, workspaceID, models.OperationStop)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	provider = s.cloudProviderOf(ctx, workspaceID, region)
+	place := s.placementFor(provider, region)
+	if place == nil {
+		return models.ErrNotFound(regionUnavailable(provider, region))
 	}
+	resourceGroup := place.resourceGroup
 
 	log.Printf("🛑 Stopping workspace %s (releasing compute, preserving storage)", workspaceID)
 
 	// Check if container exists
-	_, err := s.deploymentStrategy.GetContainer(ctx, workspaceID, region, resourceGroup)
+	status, err := place.containers.Status(ctx, workspaceID, region, resourceGroup)
 	if err != nil {
+		return cloudError(fmt.Sprintf("workspace %s: failed to check container", workspaceID), err)
+	}
+	if status == ContainerNotFound {
 		return models.ErrNotFound(fmt.Sprintf("workspace %s: container not found. Already stopped?", workspaceID))
 	}
 
-	// Stop container instance - for ACI it deletes, for ACA it scales to zero
-	if err := s.deploymentStrategy.StopContainer(ctx, workspaceID, region, resourceGroup); err != nil {
-		return models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to stop container: %v", workspaceID, err))
+	// Stop container instance - ACI stops the group, ACA uses the native stop, Docker removes the container
+	reportProgress(ctx, "stopping-container", 30)
+	s.setStatus(ctx, workspaceID, region, models.StatusStopping)
+	if err := place.containers.Stop(ctx, workspaceID, region, resourceGroup); err != nil {
+		return s.failEnvironment(ctx, workspaceID, cloudError(fmt.Sprintf("workspace %s: failed to stop container", workspaceID), err))
 	}
+	s.setStatus(ctx, workspaceID, region, models.StatusStopped)
+	s.publish(ctx, webhook.EventStopped, workspaceID, map[string]interface{}{
+		"workspaceId": workspaceID,
+		"region":      region,
+		"status":      models.StatusStopped,
+	})
 
 	log.Printf("✅ Workspace %s stopped successfully (compute released, storage preserved for fast restart)", workspaceID)
 	return nil
 }
 
 // DeleteEnvironment permanently deletes environment and all resources
-func (s *EnvironmentService) DeleteEnvironment(ctx context.Context, workspaceID, region string, force bool) error {
-	regionConfig := s.config.GetRegion(region)
-	if regionConfig == nil {
-		return models.ErrNotFound(fmt.Sprintf("region %s is not available", region))
-	}
+func (s *EnvironmentService) DeleteEnvironment(ctx context.Context, workspaceID, region string, force bool) (err error) {
+	var provider models.CloudProvider
+	defer func() { s.observeOperation(models.OperationDelete, provider, region, err) }()
 
-	resourceGroup := regionConfig.ResourceGroupName
-	if resourceGroup == "" {
-		resourceGroup = s.config.Azure.ResourceGroupName
+	ctx, unlock, err := s.lockWorkspace(ctx, workspaceID, models.OperationDelete)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	provider = s.cloudProviderOf(ctx, workspaceID, region)
+	place := s.placementFor(provider, region)
+	if place == nil {
+		return models.ErrNotFound(regionUnavailable(provider, region))
 	}
+	resourceGroup := place.resourceGroup
 
 	fileShareName := fmt.Sprintf("fs-%s", workspaceID)
 
 	log.Printf("🗑️  Deleting workspace %s permanently", workspaceID)
 
 	// Check if container is running
-	container, err := s.deploymentStrategy.GetContainer(ctx, workspaceID, region, resourceGroup)
-	if err == nil && container != nil {
-		if !force {
-			return models.ErrInvalidRequest(fmt.Sprintf("workspace %s: still running. Stop it first or use force=true", workspaceID))
-		}
-		// Force delete - stop container first
-		log.Printf("⚠️  Force deleting running container for workspace %s", workspaceID)
-		if err := s.deploymentStrategy.DeleteContainer(ctx, workspaceID, region, resourceGroup); err != nil {
+	status, err := place.containers.Status(ctx, workspaceID, region, resourceGroup)
+	if err != nil {
+		log.Printf("Warning: workspace %s: failed to check container: %v", workspaceID, err)
+	}
+	if (status == ContainerRunning || status == ContainerPending) && !force {
+		return models.ErrInvalidRequest(fmt.Sprintf("workspace %s: still running. Stop it first or use force=true", workspaceID))
+	}
+	if status != ContainerNotFound {
+		// Stopped containers (ACI, ACA) still exist and are removed with the workspace
+		if status == ContainerRunning || status == ContainerPending {
+			log.Printf("⚠️  Force deleting running container for workspace %s", workspaceID)
+		}
+		if err := place.containers.Delete(ctx, workspaceID, region, resourceGroup); err != nil {
 			log.Printf("Warning: workspace %s: failed to delete container: %v", workspaceID, err)
 		}
 	}
 
 	// Delete unified file share (permanent data loss!)
-	storageClient, ok := s.storageClients[region]
-	if !ok {
-		return models.ErrInternalServer(fmt.Sprintf("workspace %s: storage client not found for region %s", workspaceID, region))
+	volumes := place.volumes
+	if volumes == nil {
+		return models.ErrInternalServer(fmt.Sprintf("workspace %s: volume store not found for region %s", workspaceID, region))
 	}
+	s.setStatus(ctx, workspaceID, region, models.StatusDeleting)
 
 	// Delete unified volume (contains both workspace/ and home/ subdirectories)
-	if err := storageClient.DeleteFileShare(ctx, fileShareName); err != nil {
+	reportProgress(ctx, "deleting-volume", 60)
+	if err := volumes.DeleteVolume(ctx, fileShareName); err != nil {
 		log.Printf("Warning: workspace %s: failed to delete unified file share %s: %v", workspaceID, fileShareName, err)
 	} else {
 		log.Printf("✅ Deleted unified volume: %s (workspace + home)", fileShareName)
 	}
 
+	s.deleteSavedSecrets(ctx, workspaceID)
+	s.deleteSchedules(ctx, workspaceID)
+	s.usage.Close(ctx, workspaceID)
+
+	if err := s.store.Delete(ctx, workspaceID); err != nil {
+		log.Printf("Warning: workspace %s: failed to remove environment record: %v", workspaceID, err)
+	}
+	s.publish(ctx, webhook.EventDeleted, workspaceID, map[string]interface{}{
+		"workspaceId": workspaceID,
+		"region":      region,
+	})
+
 	log.Printf("✅ Workspace %s permanently deleted (all data removed)", workspaceID)
 	return nil
 }
@@ -408,13 +815,173 @@ func (s *EnvironmentService) RecordActivity(ctx context.Context, report *models.
 		return models.ErrInvalidRequest("activity payload is required")
 	}
 
-	// Just log activity for MVP
-	// Later: forward to Next.js webhook
 	log.Printf("Activity recorded for environment %s: IDE=%d SSH=%d",
 		report.EnvironmentID,
 		report.Snapshot.ActiveIDE,
 		report.Snapshot.ActiveSSH)
 
+	s.publish(ctx, webhook.EventActivity, report.EnvironmentID, report)
+
+	// Open connections count as activity at report time; otherwise only
+	// the supervisor's own last-activity timestamps move the idle clock.
+	lastActivity := report.Snapshot.LastIDEActivity
+	if report.Snapshot.LastSSHActivity.After(lastActivity) {
+		lastActivity = report.Snapshot.LastSSHActivity
+	}
+	if report.Snapshot.ActiveIDE > 0 || report.Snapshot.ActiveSSH > 0 {
+		lastActivity = report.Timestamp
+	}
+
+	// Only the activity fields change, in one store update, so a report racing a
+	// stop or delete cannot write back the record it read
+	_, err := s.store.Update(ctx, report.EnvironmentID, func(env *models.Environment) bool {
+		if env.Status != models.StatusRunning {
+			return false
+		}
+		env.ActiveIDEConnections = report.Snapshot.ActiveIDE
+		env.ActiveSSHConnections = report.Snapshot.ActiveSSH
+		if lastActivity.After(env.LastAccessedAt) {
+			env.LastAccessedAt = lastActivity
+			env.IdleWarnedAt = nil
+		}
+		return true
+	})
+	var appErr *models.AppError
+	if err != nil && !(errors.As(err, &appErr) && appErr.Code == "NOT_FOUND") {
+		// Unknown workspaces (e.g. provisioned before the registry existed) have nothing to track
+		log.Printf("Warning: workspace %s: failed to record activity: %v", report.EnvironmentID, err)
+	}
+	return nil
+}
+
+// saveEnvironment writes env to the store. Store failures are logged rather than
+// returned so a registry hiccup never fails an Azure operation that already succeeded.
+func (s *EnvironmentService) saveEnvironment(ctx context.Context, env *models.Environment) {
+	if err := s.store.Put(ctx, env); err != nil {
+		log.Printf("Warning: workspace %s: failed to persist environment record: %v", env.ID, err)
+		return
+	}
+	s.usage.Observe(ctx, env)
+}
+
+// setStatus updates the stored status of a workspace, creating a minimal
+// record for workspaces provisioned before the registry existed.
+func (s *EnvironmentService) setStatus(ctx context.Context, workspaceID, region string, status models.EnvironmentStatus) {
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil {
+		env = &models.Environment{
+			ID:            workspaceID,
+			CloudProvider: s.cloudProviderOf(ctx, workspaceID, region),
+			CloudRegion:   region,
+			CreatedAt:     time.Now(),
+		}
+	}
+	env.Status = status
+	env.StatusMessage = ""
+	env.UpdatedAt = time.Now()
+	s.saveEnvironment(ctx, env)
+}
+
+// failEnvironment marks a workspace as ERROR with the failure reason and returns err unchanged
+func (s *EnvironmentService) failEnvironment(ctx context.Context, workspaceID string, err error) error {
+	if env, getErr := s.store.Get(ctx, workspaceID); getErr == nil {
+		env.Status = models.StatusError
+		env.StatusMessage = err.Error()
+		env.UpdatedAt = time.Now()
+		s.saveEnvironment(ctx, env)
+	}
+	s.publish(ctx, webhook.EventFailed, workspaceID, map[string]interface{}{
+		"workspaceId": workspaceID,
+		"status":      models.StatusError,
+		"error":       toOperationError(err),
+	})
+	return err
+}
+
+// placement is the backend a workspace runs on, with the settings of its region
+type placement struct {
+	provider       models.CloudProvider
+	region         string
+	resourceGroup  string // Azure only
+	storageAccount string // Azure only
+	containers     ContainerProvider
+	volumes        VolumeStore // nil when the region has no volume store
+}
+
+// backendName names the compute backend in logs and container names
+func (p *placement) backendName(cfg *config.Config) string {
+	if p.provider == models.ProviderAWS {
+		return "ecs"
+	}
+	return cfg.Azure.DeploymentMode
+}
+
+// placementFor resolves a region of a cloud provider, or returns nil if it is not available.
+// An empty provider is Azure, which also covers the docker and kubernetes deployment modes.
+func (s *EnvironmentService) placementFor(provider models.CloudProvider, region string) *placement {
+	switch provider {
+	case "", models.ProviderAzure:
+		regionConfig := s.config.GetRegion(region)
+		if regionConfig == nil {
+			return nil
+		}
+		resourceGroup := regionConfig.ResourceGroupName
+		if resourceGroup == "" {
+			resourceGroup = s.config.Azure.ResourceGroupName
+		}
+		return &placement{
+			provider:       models.ProviderAzure,
+			region:         region,
+			resourceGroup:  resourceGroup,
+			storageAccount: regionConfig.StorageAccount,
+			containers:     s.containers,
+			volumes:        s.volumes[region],
+		}
+	case models.ProviderAWS:
+		if s.awsContainers == nil || s.config.GetAWSRegion(region) == nil {
+			return nil
+		}
+		return &placement{
+			provider:   models.ProviderAWS,
+			region:     region,
+			containers: s.awsContainers,
+			volumes:    s.awsVolumes[region],
+		}
+	}
+	return nil
+}
+
+// cloudProviderOf returns the cloud provider of an existing workspace from its record,
+// falling back to the provider serving region for workspaces the registry doesn't know
+func (s *EnvironmentService) cloudProviderOf(ctx context.Context, workspaceID, region string) models.CloudProvider {
+	if env, err := s.store.Get(ctx, workspaceID); err == nil && env.CloudProvider != "" {
+		return env.CloudProvider
+	}
+	if s.config.GetRegion(region) == nil && s.config.GetAWSRegion(region) != nil {
+		return models.ProviderAWS
+	}
+	return models.ProviderAzure
+}
+
+// regionUnavailable is the error message for a region no configured backend serves
+func regionUnavailable(provider models.CloudProvider, region string) string {
+	if provider == "" || provider == models.ProviderAzure {
+		return fmt.Sprintf("region %s is not available", region)
+	}
+	return fmt.Sprintf("region %s is not available for cloud provider %s", region, provider)
+}
+
+// publish emits a lifecycle event. Failures are logged; the lifecycle call has already happened.
+func (s *EnvironmentService) publish(ctx context.Context, eventType, workspaceID string, data interface{}) {
+	if err := s.events.Publish(ctx, eventType, workspaceID, data); err != nil {
+		log.Printf("Warning: workspace %s: failed to publish %s event: %v", workspaceID, eventType, err)
+	}
+}
+
+// noopPublisher discards events when no webhooks are configured
+type noopPublisher struct{}
+
+func (noopPublisher) Publish(ctx context.Context, eventType, workspaceID string, data interface{}) error {
 	return nil
 }
 
@@ -427,7 +994,7 @@ func generateConnectionURLs(fqdn, password string) models.ConnectionURLs {
 
 	// Generate a secure password if not provided
 	if password == "" {
-		password = fmt.Sprintf("dev8-%d", time.Now().UnixNano()%100000)
+		password = generatePassword()
 	}
 
 	return models.ConnectionURLs{
@@ -439,32 +1006,131 @@ func generateConnectionURLs(fqdn, password string) models.ConnectionURLs {
 	}
 }
 
-func (s *EnvironmentService) getContainerImage(baseImage string) string {
-	// If ACR is configured, use it for faster image pulls
-	if s.config.Azure.ContainerRegistry != "" {
-		// Use ACR: dev8prodcr5xv5pu3m2xjli.azurecr.io/dev8-workspace:latest
-		return fmt.Sprintf("%s/%s", s.config.Azure.ContainerRegistry, s.config.ContainerImageName)
+// connectionURLsFor builds connection URLs for a container. Only ports the provider
+// reports as reachable get a URL; ports contains the workspace's own exposed ports.
+func connectionURLsFor(info *ContainerInfo, password string, ports []models.PortSpec) models.ConnectionURLs {
+	if info == nil {
+		return models.ConnectionURLs{}
+	}
+	if info.Ports == nil {
+		return generateConnectionURLs(info.FQDN, password)
 	}
+	return generatePortConnectionURLs(info, password, ports)
+}
 
-	// Fallback to Docker Hub or configured image
-	// baseImage parameter is ignored - can be used for future customization
-	return s.config.ContainerImage
+func generatePortConnectionURLs(info *ContainerInfo, password string, ports []models.PortSpec) models.ConnectionURLs {
+	host := info.FQDN
+	if host == "" {
+		return models.ConnectionURLs{}
+	}
+
+	if password == "" {
+		password = generatePassword()
+	}
+
+	urls := models.ConnectionURLs{CodeServerPassword: password}
+	if port, ok := info.Ports[portSSH]; ok {
+		urls.SSHURL = fmt.Sprintf("ssh://user@%s:%d", host, port)
+		urls.VSCodeDesktopURL = fmt.Sprintf("vscode-remote://ssh-remote+user@%s:%d/home/dev8/workspace", host, port)
+	}
+	if port, ok := info.Ports[portCodeServer]; ok {
+		switch {
+		case info.TLS && port == 443:
+			urls.VSCodeWebURL = fmt.Sprintf("https://%s", host)
+		case info.TLS:
+			urls.VSCodeWebURL = fmt.Sprintf("https://%s:%d", host, port)
+		default:
+			// Local code-server is served without TLS
+			urls.VSCodeWebURL = fmt.Sprintf("http://%s:%d", host, port)
+		}
+	}
+	if port, ok := info.Ports[portSupervisor]; ok {
+		urls.SupervisorURL = fmt.Sprintf("http://%s:%d", host, port)
+	}
+
+	for _, spec := range ports {
+		port, ok := info.Ports[spec.Port]
+		if !ok {
+			continue
+		}
+		if urls.Ports == nil {
+			urls.Ports = make(map[int]string)
+		}
+		urls.Ports[spec.Port] = fmt.Sprintf("%s://%s:%d", spec.Protocol, host, port)
+	}
+	return urls
 }
 
-// getRegistryServer returns the registry server to use
-func (s *EnvironmentService) getRegistryServer() string {
-	// If ACR is configured, use it
-	if s.config.Azure.ContainerRegistry != "" {
-		return s.config.Azure.ContainerRegistry
+// getContainerImage returns the reference of the catalogue image baseImage as pulled in region
+func (s *EnvironmentService) getContainerImage(baseImage, region string) string {
+	image := s.resolveImage(baseImage)
+	if image == nil {
+		// Without a catalogue every workspace gets the single configured image
+		if s.config.Azure.ContainerRegistry != "" {
+			return fmt.Sprintf("%s/%s", s.config.Azure.ContainerRegistry, s.config.ContainerImageName)
+		}
+		return s.config.ContainerImage
+	}
+	return image.Reference(s.defaultRegistry(region))
+}
+
+// getRegistryServer returns the registry the image is pulled from, for registry credentials
+func (s *EnvironmentService) getRegistryServer(baseImage, region string) string {
+	if image := s.resolveImage(baseImage); image != nil && image.Registry != "" {
+		return image.Registry
+	}
+	if registry := s.defaultRegistry(region); registry != "" {
+		return registry
 	}
 
 	// Fallback to configured registry (Docker Hub)
 	return s.config.RegistryServer
 }
 
-// waitForFileShareAvailability polls Azure to verify file share is fully propagated
-// Uses exponential backoff: 500ms, 1s, 2s, 4s, 8s, etc.
-func (s *EnvironmentService) waitForFileShareAvailability(ctx context.Context, storageClient *azure.StorageClient, fileShareName string, timeout time.Duration) error {
+// waitForContainerFQDN polls the deployment until the container reports an FQDN.
+// Replaces a fixed sleep so fast providers aren't penalised and slow ones get more time.
+func (s *EnvironmentService) waitForContainerFQDN(ctx context.Context, place *placement, workspaceID string, timeout time.Duration) (*ContainerInfo, error) {
+	deadline := time.Now().Add(timeout)
+	backoff := 500 * time.Millisecond
+
+	for {
+		info, err := place.containers.Get(ctx, workspaceID, place.region, place.resourceGroup)
+		if err == nil && info != nil && info.FQDN != "" {
+			return info, nil
+		}
+
+		if time.Now().Add(backoff).After(deadline) {
+			if err != nil {
+				return nil, err
+			}
+			// Return what we have; the caller treats a missing FQDN as non-fatal
+			return info, nil
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("context cancelled while waiting for container FQDN: %w", ctx.Err())
+		}
+
+		backoff *= 2
+		if backoff > 4*time.Second {
+			backoff = 4 * time.Second
+		}
+	}
+}
+
+// localVolumePath returns the host directory backing a volume when volumes are local directories
+func localVolumePath(volumes VolumeStore, name string) string {
+	if local, ok := volumes.(*localVolumes); ok {
+		return local.Path(name)
+	}
+	return ""
+}
+
+// waitForFileShareAvailability polls the volume store until a new volume is visible;
+// Azure Files propagates shares asynchronously. Uses exponential backoff: 500ms, 1s, 2s, 4s, 8s, etc.
+func (s *EnvironmentService) waitForFileShareAvailability(ctx context.Context, volumes VolumeStore, fileShareName string, timeout time.Duration) error {
 	startTime := time.Now()
 	attempt := 0
 	maxAttempts := 10
@@ -478,7 +1144,7 @@ func (s *EnvironmentService) waitForFileShareAvailability(ctx context.Context, s
 		}
 
 		// Check if file share exists and is accessible
-		exists, err := storageClient.FileShareExists(ctx, fileShareName)
+		exists, err := volumes.VolumeExists(ctx, fileShareName)
 		if err != nil {
 			log.Printf("⚠️  Attempt %d: Error checking file share: %v", attempt+1, err)
 		} else if exists {
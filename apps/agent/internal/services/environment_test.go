@@ -23,14 +23,6 @@ func TestGetContainerImage(t *testing.T) {
 			baseImage:          "node",
 			want:               "myregistry.azurecr.io/dev8-workspace:latest",
 		},
-		{
-			name:               "ACR configured - ignores baseImage parameter",
-			containerRegistry:  "myregistry.azurecr.io",
-			containerImageName: "dev8-workspace:latest",
-			containerImage:     "vaibhavsing/dev8-workspace:latest",
-			baseImage:          "python",
-			want:               "myregistry.azurecr.io/dev8-workspace:latest",
-		},
 		{
 			name:               "No ACR - uses Docker Hub fallback",
 			containerRegistry:  "",
@@ -53,10 +45,57 @@ func TestGetContainerImage(t *testing.T) {
 				},
 			}
 
-			got := service.getContainerImage(tt.baseImage)
+			got := service.getContainerImage(tt.baseImage, "eastus")
 			if got != tt.want {
 				t.Errorf("getContainerImage(%v) = %v, want %v", tt.baseImage, got, tt.want)
 			}
 		})
 	}
 }
+
+func TestGetContainerImage_Catalogue(t *testing.T) {
+	service := &EnvironmentService{
+		config: &config.Config{
+			Azure:          config.AzureConfig{ContainerRegistry: "myregistry.azurecr.io"},
+			RegistryServer: "index.docker.io",
+			Images: config.ImageCatalogConfig{
+				Images: []config.ImageConfig{
+					{Name: "node", Repository: "dev8-workspace", Tag: "node"},
+					{Name: "go", Repository: "dev8-workspace", Tag: "go", Digest: "sha256:abc"},
+					{Name: "full", Registry: "ghcr.io", Repository: "dev8/full", Tag: "1.4"},
+				},
+				Default:          "node",
+				RegionRegistries: map[string]string{"westeurope": "dev8weu.azurecr.io"},
+			},
+		},
+	}
+
+	tests := []struct {
+		baseImage    string
+		region       string
+		wantImage    string
+		wantRegistry string
+	}{
+		{baseImage: "node", region: "eastus", wantImage: "myregistry.azurecr.io/dev8-workspace:node", wantRegistry: "myregistry.azurecr.io"},
+		{baseImage: "go", region: "eastus", wantImage: "myregistry.azurecr.io/dev8-workspace@sha256:abc", wantRegistry: "myregistry.azurecr.io"},
+		{baseImage: "node", region: "westeurope", wantImage: "dev8weu.azurecr.io/dev8-workspace:node", wantRegistry: "dev8weu.azurecr.io"},
+		{baseImage: "full", region: "westeurope", wantImage: "ghcr.io/dev8/full:1.4", wantRegistry: "ghcr.io"},
+		{baseImage: "removed", region: "eastus", wantImage: "myregistry.azurecr.io/dev8-workspace:node", wantRegistry: "myregistry.azurecr.io"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.baseImage+"@"+tt.region, func(t *testing.T) {
+			if got := service.getContainerImage(tt.baseImage, tt.region); got != tt.wantImage {
+				t.Errorf("getContainerImage() = %v, want %v", got, tt.wantImage)
+			}
+			if got := service.getRegistryServer(tt.baseImage, tt.region); got != tt.wantRegistry {
+				t.Errorf("getRegistryServer() = %v, want %v", got, tt.wantRegistry)
+			}
+		})
+	}
+
+	images := service.ListImages("westeurope")
+	if len(images) != 3 || images[0].Name != "node" || !images[0].Default || images[1].Digest != "sha256:abc" || images[2].Image != "ghcr.io/dev8/full:1.4" {
+		t.Errorf("ListImages() = %+v", images)
+	}
+}
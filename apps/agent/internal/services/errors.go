@@ -0,0 +1,60 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// cloudError describes a failed cloud call as an AppError. Throttling, exhausted
+// quotas, failed image pulls and unavailable regions keep codes of their own, so
+// callers can tell them apart without matching messages; other failures are internal
+// errors.
+func cloudError(message string, err error) error {
+	message = fmt.Sprintf("%s: %v", message, err)
+
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return &models.AppError{Message: message, Code: appErr.Code, Reason: appErr.Reason, Retryable: appErr.Retryable, RetryAfter: appErr.RetryAfter}
+	}
+	if classified, ok := azure.Classify(err); ok {
+		return azureError(message, classified)
+	}
+	return models.ErrInternalServer(message)
+}
+
+// appError returns the AppError describing err: the one in its chain, one mapped from
+// a classified Azure error, or an internal error
+func appError(err error) *models.AppError {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	if classified, ok := azure.Classify(err); ok {
+		errors.As(azureError(err.Error(), classified), &appErr)
+		return appErr
+	}
+	return &models.AppError{Message: err.Error(), Code: "INTERNAL_SERVER_ERROR"}
+}
+
+// azureError maps a classified Azure error to the AppError with message
+func azureError(message string, classified *azure.Error) error {
+	switch classified.Kind {
+	case azure.ErrorThrottled:
+		return models.ErrThrottled(message, classified.RetryAfter)
+	case azure.ErrorQuotaExceeded:
+		return models.ErrQuotaExceeded(message)
+	case azure.ErrorImagePull:
+		return models.ErrImagePull(message)
+	case azure.ErrorTransient:
+		return models.ErrUnavailable(message, classified.RetryAfter)
+	case azure.ErrorNotFound:
+		return models.ErrNotFound(message)
+	case azure.ErrorConflict:
+		return models.ErrConflict(message)
+	default:
+		return models.ErrInternalServer(message)
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/runtime"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+func azureResponseError(status int, code string, header http.Header) error {
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set("x-ms-error-code", code)
+	req, _ := http.NewRequest(http.MethodPut, "https://management.azure.com/test", nil)
+	return runtime.NewResponseError(&http.Response{
+		StatusCode: status,
+		Status:     http.StatusText(status),
+		Header:     header,
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	})
+}
+
+func TestCloudError(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            error
+		wantCode       string
+		wantRetryable  bool
+		wantRetryAfter time.Duration
+	}{
+		{
+			name:           "throttled",
+			err:            azureResponseError(http.StatusTooManyRequests, "TooManyRequests", http.Header{"Retry-After": {"7"}}),
+			wantCode:       "THROTTLED",
+			wantRetryable:  true,
+			wantRetryAfter: 7 * time.Second,
+		},
+		{
+			name:     "quota exceeded",
+			err:      azureResponseError(http.StatusConflict, "ContainerGroupQuotaReached", nil),
+			wantCode: "QUOTA_EXCEEDED",
+		},
+		{
+			name:     "image pull",
+			err:      azureResponseError(http.StatusBadRequest, "InaccessibleImage", nil),
+			wantCode: "IMAGE_PULL_FAILED",
+		},
+		{
+			name:          "transient",
+			err:           fmt.Errorf("create share: %w", azureResponseError(http.StatusServiceUnavailable, "", nil)),
+			wantCode:      "UNAVAILABLE",
+			wantRetryable: true,
+		},
+		{
+			name:     "not found",
+			err:      azureResponseError(http.StatusNotFound, "ResourceNotFound", nil),
+			wantCode: "NOT_FOUND",
+		},
+		{
+			name:          "app error keeps its code",
+			err:           models.ErrBusy("workspace ws-1 is busy"),
+			wantCode:      "CONFLICT",
+			wantRetryable: true,
+		},
+		{
+			name:     "unclassified",
+			err:      errors.New("boom"),
+			wantCode: "INTERNAL_SERVER_ERROR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := cloudError("workspace ws-1: failed to create container", tt.err)
+			var appErr *models.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("cloudError() = %T, want *models.AppError", err)
+			}
+			if appErr.Code != tt.wantCode || appErr.Retryable != tt.wantRetryable || appErr.RetryAfter != tt.wantRetryAfter {
+				t.Errorf("cloudError() = %s retryable=%v retryAfter=%v, want %s retryable=%v retryAfter=%v",
+					appErr.Code, appErr.Retryable, appErr.RetryAfter, tt.wantCode, tt.wantRetryable, tt.wantRetryAfter)
+			}
+			if !strings.HasPrefix(appErr.Message, "workspace ws-1: failed to create container: ") {
+				t.Errorf("cloudError() message = %q, want the context prefix", appErr.Message)
+			}
+
+			// Operations report the same code
+			opErr := toOperationError(tt.err)
+			if opErr.Code != tt.wantCode || opErr.Retryable != tt.wantRetryable {
+				t.Errorf("toOperationError() = %+v, want code %s retryable=%v", opErr, tt.wantCode, tt.wantRetryable)
+			}
+		})
+	}
+}
+
+func TestToOperationError_RetryAfter(t *testing.T) {
+	opErr := toOperationError(models.ErrThrottled("throttled", 1500*time.Millisecond))
+	if opErr.RetryAfterSeconds != 2 {
+		t.Errorf("RetryAfterSeconds = %d, want 2 (rounded up)", opErr.RetryAfterSeconds)
+	}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+)
+
+// IdleScanner stops running workspaces that have had no IDE or SSH activity
+// for longer than their idle timeout, optionally warning first.
+type IdleScanner struct {
+	cfg   config.IdleConfig
+	store EnvironmentStore
+
+	// stop and publish are EnvironmentService methods, replaceable in tests
+	stop    func(ctx context.Context, workspaceID, region string) error
+	publish func(ctx context.Context, eventType, workspaceID string, data interface{})
+	now     func() time.Time
+}
+
+// NewIdleScanner creates an idle scanner for the workspaces managed by service
+func NewIdleScanner(service *EnvironmentService, cfg config.IdleConfig) *IdleScanner {
+	return &IdleScanner{
+		cfg:     cfg,
+		store:   service.store,
+		stop:    service.StopEnvironment,
+		publish: service.publish,
+		now:     time.Now,
+	}
+}
+
+// Run scans every interval until ctx is cancelled
+func (i *IdleScanner) Run(ctx context.Context) {
+	log.Printf("💤 Idle scanner started (default timeout=%s, warning=%s, interval=%s)", i.cfg.DefaultTimeout, i.cfg.WarningPeriod, i.cfg.ScanInterval)
+
+	ticker := time.NewTicker(i.cfg.ScanInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("💤 Idle scanner stopped")
+			return
+		case <-ticker.C:
+			if err := i.Scan(ctx); err != nil {
+				log.Printf("❌ Idle scan failed: %v", err)
+			}
+		}
+	}
+}
+
+// Scan checks every running workspace once, warning or stopping idle ones
+func (i *IdleScanner) Scan(ctx context.Context) error {
+	envs, err := listAllEnvironments(ctx, i.store, EnvironmentFilter{Status: models.StatusRunning})
+	if err != nil {
+		return fmt.Errorf("failed to list running environments: %w", err)
+	}
+
+	for idx := range envs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		i.check(ctx, &envs[idx])
+	}
+
+	return nil
+}
+
+func (i *IdleScanner) check(ctx context.Context, env *models.Environment) {
+	timeout := i.timeoutFor(env)
+	if timeout <= 0 {
+		return
+	}
+
+	now := i.now()
+	lastActivity := env.LastAccessedAt
+	if lastActivity.IsZero() {
+		lastActivity = env.UpdatedAt
+	}
+	idle := now.Sub(lastActivity)
+
+	// Warning stage: the workspace is stopped no sooner than WarningPeriod after the warning
+	if i.cfg.WarningPeriod > 0 {
+		warned := env.IdleWarnedAt != nil && env.IdleWarnedAt.After(lastActivity)
+		if !warned {
+			if idle >= timeout-i.cfg.WarningPeriod {
+				i.warn(ctx, env, now, timeout-idle)
+			}
+			return
+		}
+		if now.Sub(*env.IdleWarnedAt) < i.cfg.WarningPeriod {
+			return
+		}
+	}
+
+	if idle < timeout {
+		return
+	}
+
+	log.Printf("💤 Workspace %s idle for %s (timeout %s) - stopping", env.ID, idle.Round(time.Second), timeout)
+	if err := i.stop(ctx, env.ID, env.CloudRegion); err != nil {
+		log.Printf("❌ Failed to auto-stop idle workspace %s: %v", env.ID, err)
+		return
+	}
+
+	if stopped, err := i.store.Get(ctx, env.ID); err == nil {
+		stopped.StatusMessage = fmt.Sprintf("stopped after %s without IDE or SSH activity", timeout)
+		if err := i.store.Put(ctx, stopped); err != nil {
+			log.Printf("Warning: workspace %s: failed to persist environment record: %v", env.ID, err)
+		}
+	}
+}
+
+func (i *IdleScanner) warn(ctx context.Context, env *models.Environment, now time.Time, remaining time.Duration) {
+	if remaining < i.cfg.WarningPeriod {
+		remaining = i.cfg.WarningPeriod
+	}
+	log.Printf("⏰ Workspace %s is idle and will be stopped in %s unless activity resumes", env.ID, remaining.Round(time.Second))
+
+	// Re-read so a concurrent activity report is not overwritten
+	current, err := i.store.Get(ctx, env.ID)
+	if err != nil {
+		return
+	}
+	current.IdleWarnedAt = &now
+	if err := i.store.Put(ctx, current); err != nil {
+		log.Printf("Warning: workspace %s: failed to persist environment record: %v", env.ID, err)
+	}
+
+	i.publish(ctx, webhook.EventIdleWarning, env.ID, map[string]interface{}{
+		"workspaceId":    env.ID,
+		"lastActivityAt": env.LastAccessedAt,
+		"stopsAt":        now.Add(remaining),
+	})
+}
+
+// timeoutFor returns the idle timeout for env. A configured tier override
+// takes precedence over the workspace setting, which takes precedence over
+// the agent default. Zero means the workspace is never auto-stopped.
+func (i *IdleScanner) timeoutFor(env *models.Environment) time.Duration {
+	if timeout, ok := i.cfg.TierTimeouts[env.Tier]; ok && env.Tier != "" {
+		return timeout
+	}
+	if env.IdleTimeoutMinutes > 0 {
+		return time.Duration(env.IdleTimeoutMinutes) * time.Minute
+	}
+	return i.cfg.DefaultTimeout
+}
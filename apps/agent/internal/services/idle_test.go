@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+)
+
+// newTestIdleScanner returns a scanner over store that records stopped workspaces and published events
+func newTestIdleScanner(store EnvironmentStore, cfg config.IdleConfig, now time.Time) (*IdleScanner, *[]string, *[]string) {
+	var stopped, events []string
+	scanner := &IdleScanner{
+		cfg:   cfg,
+		store: store,
+		stop: func(ctx context.Context, workspaceID, region string) error {
+			stopped = append(stopped, workspaceID)
+			env, err := store.Get(ctx, workspaceID)
+			if err != nil {
+				return err
+			}
+			env.Status = models.StatusStopped
+			return store.Put(ctx, env)
+		},
+		publish: func(ctx context.Context, eventType, workspaceID string, data interface{}) {
+			events = append(events, eventType)
+		},
+		now: func() time.Time { return now },
+	}
+	return scanner, &stopped, &events
+}
+
+func TestIdleScanner_Scan(t *testing.T) {
+	now := time.Now()
+	minutesAgo := func(m int) time.Time { return now.Add(-time.Duration(m) * time.Minute) }
+
+	tests := []struct {
+		name        string
+		cfg         config.IdleConfig
+		env         models.Environment
+		wantStopped bool
+		wantWarned  bool
+	}{
+		{
+			name:        "idle past default timeout",
+			cfg:         config.IdleConfig{DefaultTimeout: time.Hour},
+			env:         models.Environment{Status: models.StatusRunning, LastAccessedAt: minutesAgo(61)},
+			wantStopped: true,
+		},
+		{
+			name: "recently active",
+			cfg:  config.IdleConfig{DefaultTimeout: time.Hour},
+			env:  models.Environment{Status: models.StatusRunning, LastAccessedAt: minutesAgo(10)},
+		},
+		{
+			name: "already stopped",
+			cfg:  config.IdleConfig{DefaultTimeout: time.Hour},
+			env:  models.Environment{Status: models.StatusStopped, LastAccessedAt: minutesAgo(600)},
+		},
+		{
+			name:        "per-workspace timeout",
+			cfg:         config.IdleConfig{DefaultTimeout: time.Hour},
+			env:         models.Environment{Status: models.StatusRunning, IdleTimeoutMinutes: 15, LastAccessedAt: minutesAgo(20)},
+			wantStopped: true,
+		},
+		{
+			name: "tier override beats workspace timeout",
+			cfg:  config.IdleConfig{DefaultTimeout: time.Hour, TierTimeouts: map[string]time.Duration{"pro": 4 * time.Hour}},
+			env:  models.Environment{Status: models.StatusRunning, Tier: "pro", IdleTimeoutMinutes: 15, LastAccessedAt: minutesAgo(120)},
+		},
+		{
+			name: "tier never stops",
+			cfg:  config.IdleConfig{DefaultTimeout: time.Hour, TierTimeouts: map[string]time.Duration{"enterprise": 0}},
+			env:  models.Environment{Status: models.StatusRunning, Tier: "enterprise", LastAccessedAt: minutesAgo(6000)},
+		},
+		{
+			name:       "warning before stop",
+			cfg:        config.IdleConfig{DefaultTimeout: time.Hour, WarningPeriod: 10 * time.Minute},
+			env:        models.Environment{Status: models.StatusRunning, LastAccessedAt: minutesAgo(55)},
+			wantWarned: true,
+		},
+		{
+			name:       "warning issued even when already past timeout",
+			cfg:        config.IdleConfig{DefaultTimeout: time.Hour, WarningPeriod: 10 * time.Minute},
+			env:        models.Environment{Status: models.StatusRunning, LastAccessedAt: minutesAgo(90)},
+			wantWarned: true,
+		},
+		{
+			name:        "stop after warning period",
+			cfg:         config.IdleConfig{DefaultTimeout: time.Hour, WarningPeriod: 10 * time.Minute},
+			env:         models.Environment{Status: models.StatusRunning, LastAccessedAt: minutesAgo(61), IdleWarnedAt: ptrTime(minutesAgo(11))},
+			wantStopped: true,
+			wantWarned:  true,
+		},
+		{
+			name:       "warning still pending",
+			cfg:        config.IdleConfig{DefaultTimeout: time.Hour, WarningPeriod: 10 * time.Minute},
+			env:        models.Environment{Status: models.StatusRunning, LastAccessedAt: minutesAgo(61), IdleWarnedAt: ptrTime(minutesAgo(5))},
+			wantWarned: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryEnvironmentStore()
+			tt.env.ID = "ws-1"
+			tt.env.CloudRegion = "eastus"
+			if err := store.Put(ctx, &tt.env); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			scanner, stopped, events := newTestIdleScanner(store, tt.cfg, now)
+			if err := scanner.Scan(ctx); err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+
+			if got := len(*stopped) == 1; got != tt.wantStopped {
+				t.Errorf("stopped = %v, want %v", got, tt.wantStopped)
+			}
+
+			env, _ := store.Get(ctx, "ws-1")
+			if got := env.IdleWarnedAt != nil; got != tt.wantWarned {
+				t.Errorf("warned = %v, want %v", got, tt.wantWarned)
+			}
+			warningPublished := len(*events) == 1 && (*events)[0] == webhook.EventIdleWarning
+			if wantPublished := tt.wantWarned && tt.env.IdleWarnedAt == nil; warningPublished != wantPublished {
+				t.Errorf("idle warning event published = %v, want %v (events %v)", warningPublished, wantPublished, *events)
+			}
+			if tt.wantStopped && env.StatusMessage == "" {
+				t.Errorf("expected a status message explaining the auto-stop")
+			}
+		})
+	}
+}
+
+func TestRecordActivity_MovesIdleClock(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	start := time.Now().Add(-time.Hour)
+	warned := start.Add(time.Minute)
+	if err := store.Put(ctx, &models.Environment{ID: "ws-1", Status: models.StatusRunning, LastAccessedAt: start, IdleWarnedAt: &warned}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	service, _, _ := newTestEnvironmentService(t, store)
+	publisher := &recordingPublisher{}
+	service.SetEventPublisher(publisher)
+
+	// No connections and no newer activity: the idle clock keeps running
+	report := &models.ActivityReport{EnvironmentID: "ws-1", Timestamp: time.Now()}
+	if err := service.RecordActivity(ctx, report); err != nil {
+		t.Fatalf("RecordActivity() error = %v", err)
+	}
+	env, _ := store.Get(ctx, "ws-1")
+	if !env.LastAccessedAt.Equal(start) || env.IdleWarnedAt == nil {
+		t.Errorf("idle report moved the clock: lastAccessedAt = %v, warned = %v", env.LastAccessedAt, env.IdleWarnedAt)
+	}
+
+	// An open connection counts as activity and clears the warning
+	report = &models.ActivityReport{EnvironmentID: "ws-1", Timestamp: time.Now(), Snapshot: models.ActivitySnapshot{ActiveSSH: 1}}
+	if err := service.RecordActivity(ctx, report); err != nil {
+		t.Fatalf("RecordActivity() error = %v", err)
+	}
+	env, _ = store.Get(ctx, "ws-1")
+	if !env.LastAccessedAt.Equal(report.Timestamp) || env.IdleWarnedAt != nil || env.ActiveSSHConnections != 1 {
+		t.Errorf("active report: lastAccessedAt = %v, warned = %v, ssh = %d", env.LastAccessedAt, env.IdleWarnedAt, env.ActiveSSHConnections)
+	}
+
+	if len(publisher.events) != 2 || publisher.events[0] != webhook.EventActivity {
+		t.Errorf("published events = %v, want two activity events", publisher.events)
+	}
+}
+
+func TestRecordActivity_LeavesStoppedWorkspaces(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	start := time.Now().Add(-time.Hour)
+	if err := store.Put(ctx, &models.Environment{ID: "ws-1", Status: models.StatusStopped, LastAccessedAt: start}); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	service, _, _ := newTestEnvironmentService(t, store)
+
+	// A report sent before the stop arrives after it
+	report := &models.ActivityReport{EnvironmentID: "ws-1", Timestamp: time.Now(), Snapshot: models.ActivitySnapshot{ActiveIDE: 1}}
+	if err := service.RecordActivity(ctx, report); err != nil {
+		t.Fatalf("RecordActivity() error = %v", err)
+	}
+	env, _ := store.Get(ctx, "ws-1")
+	if env.Status != models.StatusStopped || !env.LastAccessedAt.Equal(start) || env.ActiveIDEConnections != 0 {
+		t.Errorf("stopped workspace after a late report = %+v", env)
+	}
+
+	// Nor does a report bring back a deleted workspace
+	report.EnvironmentID = "ws-deleted"
+	if err := service.RecordActivity(ctx, report); err != nil {
+		t.Fatalf("RecordActivity() of a deleted workspace error = %v", err)
+	}
+	if _, err := store.Get(ctx, "ws-deleted"); err == nil {
+		t.Error("a late report recreated a deleted workspace")
+	}
+}
+
+// recordingPublisher captures published event types
+type recordingPublisher struct {
+	events []string
+}
+
+func (r *recordingPublisher) Publish(ctx context.Context, eventType, workspaceID string, data interface{}) error {
+	r.events = append(r.events, eventType)
+	return nil
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
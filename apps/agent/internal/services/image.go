@@ -0,0 +1,47 @@
+package services
+
+import (
+	"log"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// ListImages returns the image catalogue with the references pulled in region.
+// An empty region lists the references of the default registry.
+func (s *EnvironmentService) ListImages(region string) []models.Image {
+	images := make([]models.Image, 0, len(s.config.Images.Images))
+	for _, name := range s.config.ImageNames() {
+		image := s.config.GetImage(name)
+		images = append(images, models.Image{
+			Name:     name,
+			Image:    s.getContainerImage(name, region),
+			Registry: s.getRegistryServer(name, region),
+			Digest:   image.Digest,
+			Default:  name == s.config.Images.Default,
+		})
+	}
+	return images
+}
+
+// resolveImage returns the catalogue entry of baseImage. Workspaces whose image was
+// since removed from the catalogue get the default image; nil means no catalogue.
+func (s *EnvironmentService) resolveImage(baseImage string) *config.ImageConfig {
+	if image := s.config.GetImage(baseImage); image != nil {
+		return image
+	}
+	image := s.config.GetImage(s.config.Images.Default)
+	if image != nil && baseImage != "" {
+		log.Printf("Warning: image %q is not in the catalogue, using %q", baseImage, image.Name)
+	}
+	return image
+}
+
+// defaultRegistry returns the registry of catalogue images without one of their own:
+// the region's override, else the ACR if configured, else none (Docker Hub)
+func (s *EnvironmentService) defaultRegistry(region string) string {
+	if registry := s.config.Images.RegionRegistries[region]; registry != "" {
+		return registry
+	}
+	return s.config.Azure.ContainerRegistry
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// WorkspaceLocker serializes the lifecycle operations of each workspace, so a stop
+// cannot run between the volume check and the container start of a start.
+// Implementations must be safe for concurrent use.
+type WorkspaceLocker interface {
+	// Lock takes the lock of a workspace for operation op and returns the function
+	// releasing it. It does not wait: a held lock is reported as a *WorkspaceBusyError.
+	Lock(ctx context.Context, workspaceID string, op models.OperationType) (unlock func(), err error)
+}
+
+// WorkspaceBusyError reports that another operation holds the lock of a workspace
+type WorkspaceBusyError struct {
+	WorkspaceID string
+	Operation   models.OperationType // Empty when the holder is not known yet
+	Since       time.Time            // Zero when not known
+}
+
+func (e *WorkspaceBusyError) Error() string {
+	op := "another operation"
+	if e.Operation != "" {
+		op = fmt.Sprintf("a %s operation", e.Operation)
+	}
+	if e.Since.IsZero() {
+		return fmt.Sprintf("workspace %s is busy: %s is in progress", e.WorkspaceID, op)
+	}
+	return fmt.Sprintf("workspace %s is busy: %s is in progress (since %s)", e.WorkspaceID, op, e.Since.UTC().Format(time.RFC3339))
+}
+
+// NewWorkspaceLocker creates the configured locker: in-process locks, or Azure Blob
+// leases shared by every replica of the agent
+func NewWorkspaceLocker(ctx context.Context, cfg *config.Config) (WorkspaceLocker, error) {
+	switch cfg.Locks.Backend {
+	case "local":
+		return NewLocalLocker(), nil
+	case "blob":
+		// The lock container is not tied to a workspace region, so it has a circuit breaker of its own
+		client, err := azure.NewBlobLeaseClient(cfg.Locks.StorageAccount, cfg.Locks.Container, azure.NewRetrier(cfg.Azure.Retry), "locks")
+		if err != nil {
+			return nil, err
+		}
+		if err := client.EnsureContainer(ctx); err != nil {
+			return nil, err
+		}
+		return newBlobLocker(client, cfg.Locks.LeaseDuration), nil
+	default:
+		return nil, fmt.Errorf("invalid lock backend: %s", cfg.Locks.Backend)
+	}
+}
+
+// SetWorkspaceLocker replaces the workspace locker, e.g. with one shared by every replica
+func (s *EnvironmentService) SetWorkspaceLocker(locker WorkspaceLocker) {
+	s.locks = locker
+}
+
+// heldLockKey marks a context whose operation already holds a workspace lock
+type heldLockKey struct{ workspaceID string }
+
+// lockWorkspace takes the lock of a workspace for op and returns a context marking it
+// held, so the operation's own nested calls do not try to take it again. Lock
+// conflicts are returned as CONFLICT AppErrors naming the operation in flight.
+func (s *EnvironmentService) lockWorkspace(ctx context.Context, workspaceID string, op models.OperationType) (context.Context, func(), error) {
+	if s.locks == nil || ctx.Value(heldLockKey{workspaceID}) != nil {
+		return ctx, func() {}, nil
+	}
+
+	unlock, err := s.locks.Lock(ctx, workspaceID, op)
+	if err != nil {
+		return ctx, nil, s.lockError(err)
+	}
+	return context.WithValue(ctx, heldLockKey{workspaceID}, op), unlock, nil
+}
+
+// submitLocked takes the lock of a workspace, then runs fn as an operation holding it
+// until fn returns. A busy workspace is rejected before anything is submitted.
+func (s *EnvironmentService) submitLocked(ctx context.Context, opType models.OperationType, workspaceID string, fn OperationFunc) (*models.Operation, error) {
+	_, unlock, err := s.lockWorkspace(ctx, workspaceID, opType)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.operations.Submit(opType, workspaceID, func(ctx context.Context) (interface{}, error) {
+		defer unlock()
+		return fn(context.WithValue(ctx, heldLockKey{workspaceID}, opType))
+	}), nil
+}
+
+// lockError converts a locker error into the AppError returned to callers, naming
+// the operation ID when the holder runs on this agent
+func (s *EnvironmentService) lockError(err error) error {
+	busy, ok := err.(*WorkspaceBusyError)
+	if !ok {
+		return cloudError("failed to lock workspace", err)
+	}
+	if busy.Operation != "" && s.operations != nil {
+		if op := s.operations.Active(busy.WorkspaceID, busy.Operation); op != nil {
+			return models.ErrBusy(fmt.Sprintf("%s; poll operation %s and retry when it finishes", busy.Error(), op.ID))
+		}
+	}
+	return models.ErrBusy(busy.Error() + "; retry when it finishes")
+}
+
+// localLocker keeps workspace locks in memory. It only serializes operations within
+// one agent process.
+type localLocker struct {
+	mu   sync.Mutex
+	held map[string]WorkspaceBusyError
+	now  func() time.Time
+}
+
+// NewLocalLocker creates an in-process workspace locker
+func NewLocalLocker() WorkspaceLocker {
+	return &localLocker{held: make(map[string]WorkspaceBusyError), now: time.Now}
+}
+
+func (l *localLocker) Lock(ctx context.Context, workspaceID string, op models.OperationType) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if holder, ok := l.held[workspaceID]; ok {
+		return nil, &holder
+	}
+	l.held[workspaceID] = WorkspaceBusyError{WorkspaceID: workspaceID, Operation: op, Since: l.now()}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			delete(l.held, workspaceID)
+		})
+	}, nil
+}
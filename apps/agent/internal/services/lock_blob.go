@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// blobLocker holds workspace locks as leases on one blob per workspace, so every
+// replica of the agent sees the same locks. Leases are renewed while an operation
+// runs; a crashed replica's locks expire after one lease duration.
+type blobLocker struct {
+	client   *azure.BlobLeaseClient
+	duration time.Duration
+}
+
+func newBlobLocker(client *azure.BlobLeaseClient, duration time.Duration) *blobLocker {
+	return &blobLocker{client: client, duration: duration}
+}
+
+func (b *blobLocker) Lock(ctx context.Context, workspaceID string, op models.OperationType) (func(), error) {
+	blob := "ws-" + workspaceID
+	since := time.Now().UTC()
+	leaseID, err := b.client.AcquireLease(ctx, blob, b.duration, map[string]string{
+		"operation": string(op),
+		"since":     since.Format(time.RFC3339),
+	})
+	if errors.Is(err, azure.ErrLeaseHeld) {
+		return nil, b.holder(ctx, workspaceID, blob)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stop := make(chan struct{})
+	go b.renew(blob, leaseID, stop)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(stop)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := b.client.ReleaseLease(ctx, blob, leaseID); err != nil && !azure.IsLeaseLost(err) {
+				log.Printf("Warning: workspace %s: failed to release lock: %v", workspaceID, err)
+			}
+		})
+	}, nil
+}
+
+// renew keeps a lease alive until stop is closed
+func (b *blobLocker) renew(blob, leaseID string, stop <-chan struct{}) {
+	ticker := time.NewTicker(b.duration / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), b.duration/3)
+			err := b.client.RenewLease(ctx, blob, leaseID)
+			cancel()
+			if azure.IsLeaseLost(err) {
+				log.Printf("Warning: lock %s was lost; another operation may now run on the workspace", blob)
+				return
+			}
+			if err != nil {
+				log.Printf("Warning: failed to renew lock %s: %v", blob, err)
+			}
+		}
+	}
+}
+
+// holder describes the operation holding a workspace lock from the lock blob's metadata
+func (b *blobLocker) holder(ctx context.Context, workspaceID, blob string) *WorkspaceBusyError {
+	busy := &WorkspaceBusyError{WorkspaceID: workspaceID}
+	metadata, err := b.client.Metadata(ctx, blob)
+	if err != nil {
+		return busy
+	}
+	busy.Operation = models.OperationType(metadata["operation"])
+	busy.Since, _ = time.Parse(time.RFC3339, metadata["since"])
+	return busy
+}
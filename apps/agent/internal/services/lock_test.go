@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+func TestLocalLocker(t *testing.T) {
+	ctx := context.Background()
+	locker := NewLocalLocker()
+
+	unlock, err := locker.Lock(ctx, "ws-1", models.OperationStart)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+
+	_, err = locker.Lock(ctx, "ws-1", models.OperationStop)
+	var busy *WorkspaceBusyError
+	if !errors.As(err, &busy) || busy.Operation != models.OperationStart || busy.Since.IsZero() {
+		t.Errorf("Lock() of a held workspace error = %v, want the start holding it", err)
+	}
+	if other, err := locker.Lock(ctx, "ws-2", models.OperationStop); err != nil {
+		t.Errorf("Lock() of another workspace error = %v", err)
+	} else {
+		other()
+	}
+
+	unlock()
+	again, err := locker.Lock(ctx, "ws-1", models.OperationDelete)
+	if err != nil {
+		t.Fatalf("Lock() after unlock error = %v", err)
+	}
+	unlock() // Releasing twice must not release a later holder
+	if _, err := locker.Lock(ctx, "ws-1", models.OperationStop); err == nil {
+		t.Error("stale unlock released the lock of a later holder")
+	}
+	again()
+}
+
+func TestEnvironmentService_LockConflict(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, _, _ := newTestEnvironmentService(t, store)
+	secrets, err := newFileSecrets(filepath.Join(t.TempDir(), "secrets.json"), "")
+	if err != nil {
+		t.Fatalf("newFileSecrets() error = %v", err)
+	}
+	service.SetSecretStore(secrets)
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+
+	// A start in flight holds the workspace until it finishes
+	release := make(chan struct{})
+	started, err := service.submitLocked(ctx, models.OperationStart, wsID, func(ctx context.Context) (interface{}, error) {
+		<-release
+		// Nested lifecycle calls of the operation itself are not rejected
+		return nil, service.StopEnvironment(ctx, wsID, "eastus")
+	})
+	if err != nil {
+		t.Fatalf("submitLocked() error = %v", err)
+	}
+
+	conflicts := map[string]func() error{
+		"stop": func() error {
+			_, err := service.StopEnvironmentAsync(ctx, wsID, "eastus")
+			return err
+		},
+		"delete": func() error {
+			_, err := service.DeleteEnvironmentAsync(ctx, wsID, "eastus", true)
+			return err
+		},
+		"synchronous stop": func() error {
+			return service.StopEnvironment(ctx, wsID, "eastus")
+		},
+		"snapshot": func() error {
+			_, err := service.CreateSnapshot(ctx, &models.CreateSnapshotRequest{WorkspaceID: wsID})
+			return err
+		},
+		"snapshot delete": func() error {
+			return service.DeleteSnapshot(ctx, wsID, "snap-1")
+		},
+		"snapshot retention": func() error {
+			_, err := service.SetSnapshotRetention(ctx, wsID, &models.SnapshotRetention{MaxCount: 1})
+			return err
+		},
+		"secrets update": func() error {
+			_, err := service.UpdateSecrets(ctx, wsID, &models.WorkspaceSecrets{GitHubToken: "ghp_new"})
+			return err
+		},
+		"secrets delete": func() error {
+			return service.DeleteSecrets(ctx, wsID)
+		},
+	}
+	for name, run := range conflicts {
+		err := run()
+		var appErr *models.AppError
+		if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+			t.Errorf("%s during start error = %v, want CONFLICT", name, err)
+			continue
+		}
+		if !strings.Contains(appErr.Message, "start") || !strings.Contains(appErr.Message, started.ID) {
+			t.Errorf("%s during start message = %q, want the start operation named", name, appErr.Message)
+		}
+	}
+
+	close(release)
+	op := waitForOperation(t, service.operations, started.ID)
+	if op.Phase != models.OperationSucceeded {
+		t.Fatalf("locked operation = %s (%v), want SUCCEEDED", op.Phase, op.Error)
+	}
+	env, _ := store.Get(ctx, wsID)
+	if env.Status != models.StatusStopped {
+		t.Errorf("status = %s, want %s", env.Status, models.StatusStopped)
+	}
+
+	// A restore into a new workspace holds the target too
+	targetID := "660e8400-e29b-41d4-a716-446655440000"
+	unlockTarget, err := service.locks.Lock(ctx, targetID, models.OperationCreate)
+	if err != nil {
+		t.Fatalf("Lock() error = %v", err)
+	}
+	_, err = service.RestoreSnapshot(ctx, &models.RestoreSnapshotRequest{
+		WorkspaceID:       wsID,
+		SnapshotID:        "snap-1",
+		Mode:              models.RestoreNewShare,
+		TargetWorkspaceID: targetID,
+	})
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+		t.Errorf("restore into a locked target error = %v, want CONFLICT", err)
+	}
+	unlockTarget()
+	if _, err := store.Get(ctx, targetID); err == nil {
+		t.Error("restore into a locked target created its record")
+	}
+
+	if err := service.DeleteEnvironment(ctx, wsID, "eastus", false); err != nil {
+		t.Errorf("DeleteEnvironment() after the operation finished error = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Provisioning phases timed by workspacePhaseDuration
+const (
+	phaseFileShareCreate      = "file_share_create"
+	phaseSharePropagationWait = "share_propagation_wait"
+	phaseContainerCreate      = "container_create"
+	phaseContainerStart       = "container_start"
+	phaseFQDNWait             = "fqdn_wait"
+)
+
+var (
+	workspacePhaseDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "workspace_phase_duration_seconds",
+			Help:    "Duration of workspace provisioning phases, failed attempts included",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120, 300},
+		},
+		[]string{"phase", "region", "mode"},
+	)
+
+	workspaceOperationsTotal = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workspace_operations_total",
+			Help: "Total number of workspace lifecycle operations by outcome",
+		},
+		[]string{"operation", "region", "mode", "result", "error_class"},
+	)
+
+	workspacesDesc = prometheus.NewDesc(
+		"workspaces",
+		"Number of workspaces in the environment store by status",
+		[]string{"status", "region", "mode"}, nil,
+	)
+)
+
+// observePhase records how long a provisioning phase that began at start took in place
+func (s *EnvironmentService) observePhase(phase string, place *placement, start time.Time) {
+	workspacePhaseDuration.WithLabelValues(phase, place.region, place.backendName(s.config)).Observe(time.Since(start).Seconds())
+}
+
+// observeOperation counts the outcome of a lifecycle operation in region. Failures
+// are classed by the code of their AppError, e.g. "quota_exceeded".
+func (s *EnvironmentService) observeOperation(op models.OperationType, provider models.CloudProvider, region string, err error) {
+	mode := (&placement{provider: provider}).backendName(s.config)
+	result, class := "success", ""
+	if err != nil {
+		result, class = "failure", strings.ToLower(appError(err).Code)
+	}
+	workspaceOperationsTotal.WithLabelValues(string(op), region, mode, result, class).Inc()
+}
+
+// WorkspaceCollector reports the workspaces of an environment store by status as
+// gauges, counted when scraped so they survive restarts
+type WorkspaceCollector struct {
+	cfg   *config.Config
+	store EnvironmentStore
+}
+
+// NewWorkspaceCollector creates a collector counting the workspaces in store
+func NewWorkspaceCollector(cfg *config.Config, store EnvironmentStore) *WorkspaceCollector {
+	return &WorkspaceCollector{cfg: cfg, store: store}
+}
+
+// Describe implements prometheus.Collector
+func (c *WorkspaceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- workspacesDesc
+}
+
+// Collect implements prometheus.Collector. Running and stopped are always reported,
+// so dashboards see zero rather than no data.
+func (c *WorkspaceCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	envs, err := listAllEnvironments(ctx, c.store, EnvironmentFilter{})
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(workspacesDesc, err)
+		return
+	}
+
+	type key struct{ status, region, mode string }
+	counts := make(map[key]int)
+	for i := range envs {
+		env := &envs[i]
+		mode := (&placement{provider: env.CloudProvider}).backendName(c.cfg)
+		counts[key{string(env.Status), env.CloudRegion, mode}]++
+	}
+	zero := func(region, mode string) {
+		for _, status := range []models.EnvironmentStatus{models.StatusRunning, models.StatusStopped} {
+			if k := (key{string(status), region, mode}); counts[k] == 0 {
+				counts[k] = 0
+			}
+		}
+	}
+	for _, region := range c.cfg.GetEnabledRegions() {
+		zero(region.Name, c.cfg.Azure.DeploymentMode)
+	}
+	for _, region := range c.cfg.GetEnabledAWSRegions() {
+		zero(region.Name, "ecs")
+	}
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(workspacesDesc, prometheus.GaugeValue, float64(count), k.status, k.region, k.mode)
+	}
+}
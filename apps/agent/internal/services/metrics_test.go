@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// phaseCount returns how many times phase was observed in eastus
+func phaseCount(t *testing.T, phase string) uint64 {
+	t.Helper()
+	var metric dto.Metric
+	if err := workspacePhaseDuration.WithLabelValues(phase, "eastus", "aci").(prometheus.Metric).Write(&metric); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestEnvironmentService_Metrics(t *testing.T) {
+	ctx := context.Background()
+	service, provider, _ := newTestEnvironmentService(t, NewMemoryEnvironmentStore())
+
+	counter := func(op models.OperationType, result, class string) float64 {
+		return testutil.ToFloat64(workspaceOperationsTotal.WithLabelValues(string(op), "eastus", "aci", result, class))
+	}
+	phases := []string{phaseFileShareCreate, phaseSharePropagationWait, phaseContainerCreate, phaseFQDNWait}
+	phasesBefore := make(map[string]uint64)
+	for _, phase := range phases {
+		phasesBefore[phase] = phaseCount(t, phase)
+	}
+	createdBefore := counter(models.OperationCreate, "success", "")
+	quotaBefore := counter(models.OperationCreate, "failure", "quota_exceeded")
+	stoppedBefore := counter(models.OperationStop, "success", "")
+	notFoundBefore := counter(models.OperationStop, "failure", "not_found")
+
+	req := &models.CreateEnvironmentRequest{WorkspaceID: wsID, Name: "metrics", CloudRegion: "eastus", CPUCores: 2, MemoryGB: 4, StorageGB: 10}
+	if _, err := service.CreateEnvironment(ctx, req); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	if err := service.StopEnvironment(ctx, "770e8400-e29b-41d4-a716-446655440000", "eastus"); err == nil {
+		t.Fatal("StopEnvironment() of a missing workspace should fail")
+	}
+	provider.FailOn(FakeOpCreate, models.ErrQuotaExceeded("no capacity"))
+	failing := *req
+	failing.WorkspaceID = "660e8400-e29b-41d4-a716-446655440000"
+	if _, err := service.CreateEnvironment(ctx, &failing); err == nil {
+		t.Fatal("CreateEnvironment() should fail when the provider fails")
+	}
+
+	for _, tt := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"create successes", counter(models.OperationCreate, "success", ""), createdBefore + 1},
+		{"create quota failures", counter(models.OperationCreate, "failure", "quota_exceeded"), quotaBefore + 1},
+		{"stop successes", counter(models.OperationStop, "success", ""), stoppedBefore + 1},
+		{"stop not-found failures", counter(models.OperationStop, "failure", "not_found"), notFoundBefore + 1},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// The FQDN wait is skipped once the container fails, so it only ran for the first create
+	wantPhases := map[string]uint64{phaseFileShareCreate: 2, phaseSharePropagationWait: 2, phaseContainerCreate: 2, phaseFQDNWait: 1}
+	for _, phase := range phases {
+		if got := phaseCount(t, phase) - phasesBefore[phase]; got != wantPhases[phase] {
+			t.Errorf("%s observations = %d, want %d", phase, got, wantPhases[phase])
+		}
+	}
+}
+
+func TestWorkspaceCollector(t *testing.T) {
+	ctx := context.Background()
+	service, _, _ := newTestEnvironmentService(t, NewMemoryEnvironmentStore())
+	for id, status := range map[string]models.EnvironmentStatus{
+		"ws-1": models.StatusRunning,
+		"ws-2": models.StatusRunning,
+		"ws-3": models.StatusError,
+	} {
+		service.saveEnvironment(ctx, &models.Environment{ID: id, Status: status, CloudRegion: "eastus"})
+	}
+
+	// Stopped is reported as zero rather than left out
+	expected := `
+# HELP workspaces Number of workspaces in the environment store by status
+# TYPE workspaces gauge
+workspaces{mode="aci",region="eastus",status="ERROR"} 1
+workspaces{mode="aci",region="eastus",status="RUNNING"} 2
+workspaces{mode="aci",region="eastus",status="STOPPED"} 0
+`
+	collector := NewWorkspaceCollector(service.config, service.store)
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/google/uuid"
+)
+
+// OperationFunc performs the work of an asynchronous operation and returns its result
+type OperationFunc func(ctx context.Context) (interface{}, error)
+
+// OperationManager runs lifecycle operations in the background and keeps
+// their status around so clients can poll GET /api/v1/operations/{id}.
+type OperationManager struct {
+	mu         sync.RWMutex
+	operations map[string]*models.Operation
+
+	timeout   time.Duration // Upper bound for a single operation
+	retention time.Duration // How long finished operations stay queryable
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewOperationManager creates a new operation manager
+func NewOperationManager(timeout, retention time.Duration) *OperationManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OperationManager{
+		operations: make(map[string]*models.Operation),
+		timeout:    timeout,
+		retention:  retention,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Submit registers a new operation and runs fn in the background.
+// The returned operation is a snapshot taken before fn starts.
+func (m *OperationManager) Submit(opType models.OperationType, workspaceID string, fn OperationFunc) *models.Operation {
+	now := time.Now().UTC()
+	op := &models.Operation{
+		ID:          uuid.New().String(),
+		Type:        opType,
+		WorkspaceID: workspaceID,
+		Phase:       models.OperationPending,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	m.mu.Lock()
+	m.pruneLocked(now)
+	m.operations[op.ID] = op
+	snapshot := *op
+	m.mu.Unlock()
+
+	m.wg.Add(1)
+	go m.run(op.ID, fn)
+
+	return &snapshot
+}
+
+// Get returns a snapshot of the operation with the given ID
+func (m *OperationManager) Get(id string) (*models.Operation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	op, ok := m.operations[id]
+	if !ok {
+		return nil, models.ErrNotFound(fmt.Sprintf("operation %s not found", id))
+	}
+
+	snapshot := *op
+	return &snapshot, nil
+}
+
+// Active returns a snapshot of the unfinished operation of type opType on a workspace,
+// or nil if there is none
+func (m *OperationManager) Active(workspaceID string, opType models.OperationType) *models.Operation {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, op := range m.operations {
+		if op.WorkspaceID == workspaceID && op.Type == opType && !op.IsTerminal() {
+			snapshot := *op
+			return &snapshot
+		}
+	}
+	return nil
+}
+
+// Completed registers an operation that already succeeded with result, for requests
+// whose work was done by an earlier one
+func (m *OperationManager) Completed(opType models.OperationType, workspaceID string, result interface{}) *models.Operation {
+	now := time.Now().UTC()
+	op := &models.Operation{
+		ID:          uuid.New().String(),
+		Type:        opType,
+		WorkspaceID: workspaceID,
+		Phase:       models.OperationSucceeded,
+		Progress:    100,
+		Result:      result,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CompletedAt: &now,
+	}
+
+	m.mu.Lock()
+	m.pruneLocked(now)
+	m.operations[op.ID] = op
+	snapshot := *op
+	m.mu.Unlock()
+
+	return &snapshot
+}
+
+// Shutdown waits for in-flight operations to finish. If ctx expires first,
+// the remaining operations are cancelled.
+func (m *OperationManager) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		m.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		m.cancel()
+		return nil
+	case <-ctx.Done():
+		m.cancel()
+		<-done
+		return fmt.Errorf("operations cancelled before completion: %w", ctx.Err())
+	}
+}
+
+func (m *OperationManager) run(id string, fn OperationFunc) {
+	defer m.wg.Done()
+
+	ctx, cancel := context.WithTimeout(m.ctx, m.timeout)
+	defer cancel()
+
+	m.update(id, func(op *models.Operation) {
+		op.Phase = models.OperationRunning
+	})
+
+	ctx = context.WithValue(ctx, progressReporterKey{}, progressReporter(func(step string, percent int) {
+		m.update(id, func(op *models.Operation) {
+			op.Step = step
+			if percent > op.Progress {
+				op.Progress = percent
+			}
+		})
+	}))
+
+	result, err := fn(ctx)
+
+	m.update(id, func(op *models.Operation) {
+		completedAt := time.Now().UTC()
+		op.CompletedAt = &completedAt
+		if err != nil {
+			op.Phase = models.OperationFailed
+			op.Error = toOperationError(err)
+			log.Printf("❌ Operation %s (%s %s) failed: %v", op.ID, op.Type, op.WorkspaceID, err)
+			return
+		}
+		op.Phase = models.OperationSucceeded
+		op.Progress = 100
+		op.Result = result
+	})
+}
+
+func (m *OperationManager) update(id string, mutate func(op *models.Operation)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	op, ok := m.operations[id]
+	if !ok {
+		return
+	}
+	mutate(op)
+	op.UpdatedAt = time.Now().UTC()
+}
+
+// pruneLocked drops finished operations older than the retention window
+func (m *OperationManager) pruneLocked(now time.Time) {
+	for id, op := range m.operations {
+		if op.CompletedAt != nil && now.Sub(*op.CompletedAt) > m.retention {
+			delete(m.operations, id)
+		}
+	}
+}
+
+func toOperationError(err error) *models.OperationError {
+	appErr := appError(err)
+	return &models.OperationError{
+		Code:              appErr.Code,
+		Message:           appErr.Message,
+		Reason:            appErr.Reason,
+		Retryable:         appErr.Retryable,
+		RetryAfterSeconds: int(math.Ceil(appErr.RetryAfter.Seconds())),
+	}
+}
+
+type progressReporterKey struct{}
+
+type progressReporter func(step string, percent int)
+
+// reportProgress records progress on the operation running in ctx, if any.
+// It is a no-op for synchronous calls.
+func reportProgress(ctx context.Context, step string, percent int) {
+	if report, ok := ctx.Value(progressReporterKey{}).(progressReporter); ok {
+		report(step, percent)
+	}
+}
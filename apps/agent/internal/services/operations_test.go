@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// waitForOperation polls until the operation reaches a terminal phase
+func waitForOperation(t *testing.T, m *OperationManager, id string) *models.Operation {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		op, err := m.Get(id)
+		if err != nil {
+			t.Fatalf("Get(%s) error = %v", id, err)
+		}
+		if op.IsTerminal() {
+			return op
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("operation %s did not complete in time", id)
+	return nil
+}
+
+func TestOperationManager_Success(t *testing.T) {
+	m := NewOperationManager(time.Second, time.Hour)
+
+	op := m.Submit(models.OperationCreate, "ws-123", func(ctx context.Context) (interface{}, error) {
+		reportProgress(ctx, "creating-volume", 40)
+		return "done", nil
+	})
+
+	if op.Phase != models.OperationPending {
+		t.Errorf("Submit() phase = %v, want %v", op.Phase, models.OperationPending)
+	}
+
+	got := waitForOperation(t, m, op.ID)
+	if got.Phase != models.OperationSucceeded {
+		t.Errorf("phase = %v, want %v", got.Phase, models.OperationSucceeded)
+	}
+	if got.Progress != 100 {
+		t.Errorf("progress = %d, want 100", got.Progress)
+	}
+	if got.Step != "creating-volume" {
+		t.Errorf("step = %q, want creating-volume", got.Step)
+	}
+	if got.Result != "done" {
+		t.Errorf("result = %v, want done", got.Result)
+	}
+	if got.CompletedAt == nil {
+		t.Error("completedAt not set")
+	}
+}
+
+func TestOperationManager_Failure(t *testing.T) {
+	m := NewOperationManager(time.Second, time.Hour)
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+	}{
+		{name: "app error keeps code", err: models.ErrNotFound("volume missing"), wantCode: "NOT_FOUND"},
+		{name: "plain error is internal", err: errors.New("boom"), wantCode: "INTERNAL_SERVER_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := m.Submit(models.OperationStart, "ws-123", func(ctx context.Context) (interface{}, error) {
+				return nil, tt.err
+			})
+
+			got := waitForOperation(t, m, op.ID)
+			if got.Phase != models.OperationFailed {
+				t.Fatalf("phase = %v, want %v", got.Phase, models.OperationFailed)
+			}
+			if got.Error == nil || got.Error.Code != tt.wantCode {
+				t.Errorf("error = %+v, want code %s", got.Error, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestOperationManager_Timeout(t *testing.T) {
+	m := NewOperationManager(20*time.Millisecond, time.Hour)
+
+	op := m.Submit(models.OperationCreate, "ws-123", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	got := waitForOperation(t, m, op.ID)
+	if got.Phase != models.OperationFailed {
+		t.Errorf("phase = %v, want %v", got.Phase, models.OperationFailed)
+	}
+}
+
+func TestOperationManager_GetNotFound(t *testing.T) {
+	m := NewOperationManager(time.Second, time.Hour)
+
+	_, err := m.Get("missing")
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+		t.Errorf("Get() error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestOperationManager_PrunesExpired(t *testing.T) {
+	m := NewOperationManager(time.Second, time.Millisecond)
+
+	first := m.Submit(models.OperationStop, "ws-1", func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+	waitForOperation(t, m, first.ID)
+	time.Sleep(5 * time.Millisecond)
+
+	m.Submit(models.OperationStop, "ws-2", func(ctx context.Context) (interface{}, error) {
+		return nil, nil
+	})
+
+	if _, err := m.Get(first.ID); err == nil {
+		t.Error("expected expired operation to be pruned")
+	}
+}
+
+func TestOperationManager_Shutdown(t *testing.T) {
+	m := NewOperationManager(time.Minute, time.Hour)
+
+	started := make(chan struct{})
+	m.Submit(models.OperationCreate, "ws-123", func(ctx context.Context) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := m.Shutdown(ctx); err == nil {
+		t.Error("Shutdown() expected error when operations outlive the deadline")
+	}
+}
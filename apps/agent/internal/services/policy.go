@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// PolicyRequest describes a workspace about to be created or started
+type PolicyRequest struct {
+	WorkspaceID string
+	UserID      string
+	OrgID       string
+	Tier        string // Empty uses the default tier
+	Region      string
+	Image       string // Empty uses the catalogue default
+	CPUCores    int
+	MemoryGB    int
+	StorageGB   int
+	NewStorage  bool // The workspace's storage is new or grown and counts against the storage total
+	Stopped     bool // The workspace stays stopped, so only its storage is checked
+}
+
+// PolicyEngine checks workspace creates, starts and resizes against the limits of the owner's
+// tier. Usage is counted from the environment store, so it covers the workspaces of
+// this agent.
+type PolicyEngine struct {
+	cfg   config.PolicyConfig
+	store EnvironmentStore
+	usage *UsageMeter // Checks monthly budgets; nil checks none
+
+	mu       sync.Mutex
+	reserved map[string]policyUsage // Admitted workspaces, keyed by workspace ID
+}
+
+// policyUsage is what an admitted workspace counts against its owner's limits
+type policyUsage struct {
+	owner     string
+	running   bool
+	cpuCores  int
+	memoryGB  int
+	storageGB int // Only set for new storage; other workspaces' storage is counted from the store
+}
+
+// NewPolicyEngine creates a policy engine counting the workspaces in store
+func NewPolicyEngine(cfg config.PolicyConfig, store EnvironmentStore) *PolicyEngine {
+	return &PolicyEngine{cfg: cfg, store: store, reserved: make(map[string]policyUsage)}
+}
+
+// SetUsageMeter makes Admit refuse users over their hard monthly budget
+func (p *PolicyEngine) SetUsageMeter(meter *UsageMeter) {
+	p.usage = meter
+}
+
+// Admit checks req against its tier and reserves what it uses until release is
+// called, so concurrent requests of one owner cannot all slip under a limit.
+// Callers release once the workspace's record shows it running. Rejections are
+// POLICY_VIOLATION AppErrors whose Reason is a models.PolicyReason.
+func (p *PolicyEngine) Admit(ctx context.Context, req PolicyRequest) (release func(), err error) {
+	if p == nil {
+		return func() {}, nil
+	}
+	if err := p.usage.CheckBudget(ctx, req.UserID, req.Tier); err != nil {
+		return nil, err
+	}
+	if len(p.cfg.Tiers) == 0 {
+		return func() {}, nil
+	}
+
+	tierName, tier, ok := p.tier(req.Tier)
+	if !ok {
+		return nil, models.ErrPolicyViolation(models.PolicyUnknownTier, fmt.Sprintf("tier '%s' does not exist", tierName))
+	}
+	if len(tier.Regions) > 0 && !slices.Contains(tier.Regions, req.Region) {
+		return nil, models.ErrPolicyViolation(models.PolicyRegionNotAllowed, fmt.Sprintf("tier '%s' does not allow region %s", tierName, req.Region))
+	}
+	image := req.Image
+	if image == "" {
+		image = models.DefaultImage()
+	}
+	if len(tier.Images) > 0 && !slices.Contains(tier.Images, image) {
+		return nil, models.ErrPolicyViolation(models.PolicyImageNotAllowed, fmt.Sprintf("tier '%s' does not allow image %s", tierName, image))
+	}
+
+	owner, filter := policyOwner(tier, req)
+	envs, err := listAllEnvironments(ctx, p.store, filter)
+	if err != nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("failed to count workspaces for policy: %v", err))
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Count the owner's other workspaces; reserved ones are counted from their reservation
+	var running, cpuCores, memoryGB, storageGB int
+	for i := range envs {
+		env := &envs[i]
+		if env.ID == req.WorkspaceID || !filter.Matches(env) {
+			continue
+		}
+		reservation, reserved := p.reserved[env.ID]
+		if !reserved || reservation.storageGB == 0 {
+			storageGB += env.StorageGB
+		}
+		if !reserved && countsAsRunning(env.Status) {
+			running++
+			cpuCores += env.CPUCores
+			memoryGB += env.MemoryGB
+		}
+	}
+	for id, usage := range p.reserved {
+		if id == req.WorkspaceID || usage.owner != owner {
+			continue
+		}
+		if usage.running {
+			running++
+			cpuCores += usage.cpuCores
+			memoryGB += usage.memoryGB
+		}
+		storageGB += usage.storageGB
+	}
+
+	subject := fmt.Sprintf("tier '%s' of %s", tierName, owner)
+	compute := !req.Stopped
+	switch {
+	case compute && tier.MaxRunning > 0 && running+1 > tier.MaxRunning:
+		return nil, models.ErrPolicyViolation(models.PolicyMaxRunning,
+			fmt.Sprintf("%s allows %d running workspaces and %d are running; stop one first", subject, tier.MaxRunning, running))
+	case compute && tier.MaxCPUCores > 0 && cpuCores+req.CPUCores > tier.MaxCPUCores:
+		return nil, models.ErrPolicyViolation(models.PolicyCPUQuota,
+			fmt.Sprintf("%s allows %d vCPU in total; %d are in use and %d were requested", subject, tier.MaxCPUCores, cpuCores, req.CPUCores))
+	case compute && tier.MaxMemoryGB > 0 && memoryGB+req.MemoryGB > tier.MaxMemoryGB:
+		return nil, models.ErrPolicyViolation(models.PolicyMemoryQuota,
+			fmt.Sprintf("%s allows %dGB of memory in total; %dGB are in use and %dGB were requested", subject, tier.MaxMemoryGB, memoryGB, req.MemoryGB))
+	case req.NewStorage && tier.MaxStorageGB > 0 && storageGB+req.StorageGB > tier.MaxStorageGB:
+		return nil, models.ErrPolicyViolation(models.PolicyStorageQuota,
+			fmt.Sprintf("%s allows %dGB of storage in total; %dGB are in use and %dGB were requested", subject, tier.MaxStorageGB, storageGB, req.StorageGB))
+	}
+
+	usage := policyUsage{owner: owner, running: compute, cpuCores: req.CPUCores, memoryGB: req.MemoryGB}
+	if req.NewStorage {
+		usage.storageGB = req.StorageGB
+	}
+	p.reserved[req.WorkspaceID] = usage
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			p.mu.Lock()
+			defer p.mu.Unlock()
+			delete(p.reserved, req.WorkspaceID)
+		})
+	}, nil
+}
+
+// AllowedRegions returns the regions tier allows, in order. When it allows none of
+// them, or the tier does not exist, regions are returned unchanged for Admit to reject.
+func (p *PolicyEngine) AllowedRegions(tier string, regions []string) []string {
+	if p == nil {
+		return regions
+	}
+	_, policy, ok := p.tier(tier)
+	if !ok || len(policy.Regions) == 0 {
+		return regions
+	}
+
+	var allowed []string
+	for _, region := range regions {
+		if slices.Contains(policy.Regions, region) {
+			allowed = append(allowed, region)
+		}
+	}
+	if len(allowed) == 0 {
+		return regions
+	}
+	return allowed
+}
+
+// tier resolves a tier name, empty meaning the default tier
+func (p *PolicyEngine) tier(name string) (string, config.TierPolicy, bool) {
+	if name == "" {
+		name = p.cfg.DefaultTier
+	}
+	tier, ok := p.cfg.Tiers[name]
+	return name, tier, ok
+}
+
+// createPolicyRequest describes a create in region to the policy engine
+func createPolicyRequest(req *models.CreateEnvironmentRequest, region string) PolicyRequest {
+	return PolicyRequest{
+		WorkspaceID: req.WorkspaceID,
+		UserID:      req.UserID,
+		OrgID:       req.OrgID,
+		Tier:        req.Tier,
+		Region:      region,
+		Image:       req.BaseImage,
+		CPUCores:    req.CPUCores,
+		MemoryGB:    req.MemoryGB,
+		StorageGB:   req.StorageGB,
+		NewStorage:  true,
+	}
+}
+
+// startPolicyRequest describes a start to the policy engine. The workspace keeps the
+// org and tier it was created with.
+func startPolicyRequest(req *models.StartEnvironmentRequest, existing *models.Environment) PolicyRequest {
+	return PolicyRequest{
+		WorkspaceID: req.WorkspaceID,
+		UserID:      req.UserID,
+		OrgID:       existing.OrgID,
+		Tier:        existing.Tier,
+		Region:      req.CloudRegion,
+		Image:       req.BaseImage,
+		CPUCores:    req.CPUCores,
+		MemoryGB:    req.MemoryGB,
+	}
+}
+
+// resizePolicyRequest describes a resize of env to the policy engine. The workspace's
+// own record is not counted, so StorageGB is its new total and only a grown volume
+// counts as new storage; a stopped workspace stays stopped.
+func resizePolicyRequest(env *models.Environment, cpuCores, memoryGB, storageGB int) PolicyRequest {
+	return PolicyRequest{
+		WorkspaceID: env.ID,
+		UserID:      env.UserID,
+		OrgID:       env.OrgID,
+		Tier:        env.Tier,
+		Region:      env.CloudRegion,
+		Image:       env.BaseImage,
+		CPUCores:    cpuCores,
+		MemoryGB:    memoryGB,
+		StorageGB:   storageGB,
+		NewStorage:  storageGB > env.StorageGB,
+		Stopped:     env.Status == models.StatusStopped,
+	}
+}
+
+// policyOwner names the user or org whose workspaces share req's limits,
+// and the filter selecting their workspaces. Org tiers fall back to the user for
+// requests without an org.
+func policyOwner(tier config.TierPolicy, req PolicyRequest) (string, EnvironmentFilter) {
+	if tier.Scope == "org" && req.OrgID != "" {
+		return "org " + req.OrgID, EnvironmentFilter{OrgID: req.OrgID}
+	}
+	return "user " + req.UserID, EnvironmentFilter{UserID: req.UserID}
+}
+
+// countsAsRunning reports whether a workspace in status holds a container
+func countsAsRunning(status models.EnvironmentStatus) bool {
+	switch status {
+	case models.StatusCreating, models.StatusStarting, models.StatusRunning,
+		models.StatusStopping, models.StatusResizing, models.StatusRotating:
+		return true
+	default:
+		return false
+	}
+}
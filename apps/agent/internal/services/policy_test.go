@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+func testPolicy() config.PolicyConfig {
+	return config.PolicyConfig{
+		DefaultTier: "free",
+		Tiers: map[string]config.TierPolicy{
+			"free": {MaxRunning: 1, MaxCPUCores: 2, MaxMemoryGB: 4, MaxStorageGB: 30, Regions: []string{"eastus"}, Images: []string{"node"}},
+			"pro":  {MaxRunning: 3, MaxCPUCores: 6, MaxMemoryGB: 12},
+			"team": {Scope: "org", MaxRunning: 2},
+		},
+	}
+}
+
+// policyReason returns the reason of a POLICY_VIOLATION error, or "" for other errors
+func policyReason(err error) models.PolicyReason {
+	var appErr *models.AppError
+	if errors.As(err, &appErr) && appErr.Code == "POLICY_VIOLATION" {
+		return models.PolicyReason(appErr.Reason)
+	}
+	return ""
+}
+
+func TestPolicyEngine_Admit(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	for _, env := range []models.Environment{
+		{ID: "ws-running-1", UserID: "user-1", Status: models.StatusRunning, CPUCores: 1, MemoryGB: 2, StorageGB: 10},
+		{ID: "ws-stopped-1", UserID: "user-1", Status: models.StatusStopped, CPUCores: 2, MemoryGB: 4, StorageGB: 15},
+		{ID: "ws-running-2", UserID: "user-2", Status: models.StatusRunning, CPUCores: 4, MemoryGB: 8, StorageGB: 50},
+		{ID: "ws-org-a", UserID: "user-3", OrgID: "acme", Status: models.StatusRunning, CPUCores: 1, MemoryGB: 2, StorageGB: 10},
+		{ID: "ws-org-b", UserID: "user-4", OrgID: "acme", Status: models.StatusStarting, CPUCores: 1, MemoryGB: 2, StorageGB: 10},
+	} {
+		env := env
+		if err := store.Put(ctx, &env); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	engine := NewPolicyEngine(testPolicy(), store)
+
+	base := PolicyRequest{WorkspaceID: "ws-new", UserID: "user-9", Region: "eastus", Image: "node", CPUCores: 1, MemoryGB: 2, StorageGB: 10, NewStorage: true}
+	with := func(change func(*PolicyRequest)) PolicyRequest {
+		req := base
+		change(&req)
+		return req
+	}
+
+	tests := []struct {
+		name string
+		req  PolicyRequest
+		want models.PolicyReason
+	}{
+		{name: "within the default tier", req: base},
+		{name: "unknown tier", req: with(func(r *PolicyRequest) { r.Tier = "gold" }), want: models.PolicyUnknownTier},
+		{name: "region not allowed", req: with(func(r *PolicyRequest) { r.Region = "westeurope" }), want: models.PolicyRegionNotAllowed},
+		{name: "image not allowed", req: with(func(r *PolicyRequest) { r.Image = "python" }), want: models.PolicyImageNotAllowed},
+		{name: "tier without region limits", req: with(func(r *PolicyRequest) { r.Tier = "pro"; r.Region = "westeurope" })},
+		{name: "too much cpu", req: with(func(r *PolicyRequest) { r.CPUCores = 3 }), want: models.PolicyCPUQuota},
+		{name: "too much memory", req: with(func(r *PolicyRequest) { r.MemoryGB = 6 }), want: models.PolicyMemoryQuota},
+		{name: "running limit", req: with(func(r *PolicyRequest) { r.UserID = "user-1" }), want: models.PolicyMaxRunning},
+		{name: "starting the running workspace itself", req: with(func(r *PolicyRequest) { r.UserID = "user-1"; r.WorkspaceID = "ws-running-1" })},
+		{
+			name: "tier without a storage limit",
+			req:  with(func(r *PolicyRequest) { r.UserID = "user-1"; r.Tier = "pro"; r.StorageGB = 100 }),
+		},
+		{
+			name: "storage of stopped workspaces counts",
+			req:  with(func(r *PolicyRequest) { r.UserID = "user-1"; r.StorageGB = 20; r.WorkspaceID = "ws-running-1" }),
+			want: models.PolicyStorageQuota,
+		},
+		{
+			name: "starting does not add storage",
+			req: with(func(r *PolicyRequest) {
+				r.UserID = "user-1"
+				r.WorkspaceID = "ws-running-1"
+				r.StorageGB = 100
+				r.NewStorage = false
+			}),
+		},
+		{name: "other users are not counted", req: with(func(r *PolicyRequest) { r.UserID = "user-5" })},
+		{name: "org tier counts the org", req: with(func(r *PolicyRequest) { r.Tier = "team"; r.OrgID = "acme" }), want: models.PolicyMaxRunning},
+		{name: "org tier without an org counts the user", req: with(func(r *PolicyRequest) { r.Tier = "team" })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			release, err := engine.Admit(ctx, tt.req)
+			if got := policyReason(err); got != tt.want {
+				t.Fatalf("Admit() error = %v, want reason %q", err, tt.want)
+			}
+			if err == nil {
+				release()
+			}
+		})
+	}
+}
+
+func TestPolicyEngine_Reservations(t *testing.T) {
+	ctx := context.Background()
+	engine := NewPolicyEngine(testPolicy(), NewMemoryEnvironmentStore())
+	req := PolicyRequest{WorkspaceID: "ws-first", UserID: "user-1", Region: "eastus", Image: "node", CPUCores: 1, MemoryGB: 2, StorageGB: 10, NewStorage: true}
+
+	release, err := engine.Admit(ctx, req)
+	if err != nil {
+		t.Fatalf("Admit() error = %v", err)
+	}
+
+	// A concurrent create of the same user is held to the running limit
+	second := req
+	second.WorkspaceID = "ws-second"
+	if _, err := engine.Admit(ctx, second); policyReason(err) != models.PolicyMaxRunning {
+		t.Fatalf("second Admit() error = %v, want %s", err, models.PolicyMaxRunning)
+	}
+
+	release()
+	release() // Releasing twice is harmless
+	secondRelease, err := engine.Admit(ctx, second)
+	if err != nil {
+		t.Fatalf("Admit() after release error = %v", err)
+	}
+	secondRelease()
+}
+
+func TestPolicyEngine_NoTiers(t *testing.T) {
+	var nilEngine *PolicyEngine
+	for _, engine := range []*PolicyEngine{nilEngine, NewPolicyEngine(config.PolicyConfig{}, NewMemoryEnvironmentStore())} {
+		release, err := engine.Admit(context.Background(), PolicyRequest{WorkspaceID: "ws-1", Tier: "anything"})
+		if err != nil {
+			t.Fatalf("Admit() without tiers error = %v", err)
+		}
+		release()
+	}
+}
+
+func TestEnvironmentService_PolicyLimits(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, _ := newTestEnvironmentService(t, store)
+	service.policy = NewPolicyEngine(testPolicy(), store)
+
+	create := func(id string) error {
+		_, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+			WorkspaceID: id,
+			UserID:      "user-1",
+			Name:        "test-env",
+			CloudRegion: "eastus",
+			CPUCores:    1,
+			MemoryGB:    2,
+			StorageGB:   10,
+		})
+		return err
+	}
+
+	const first, second = "ws-policy-first", "ws-policy-second"
+	if err := create(first); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+
+	// The free tier runs one workspace at a time
+	err := create(second)
+	if policyReason(err) != models.PolicyMaxRunning {
+		t.Fatalf("second CreateEnvironment() error = %v, want %s", err, models.PolicyMaxRunning)
+	}
+	if _, ok := provider.Spec(second); ok {
+		t.Error("a rejected create deployed a container")
+	}
+	if _, err := store.Get(ctx, second); err == nil {
+		t.Error("a rejected create left an environment record")
+	}
+
+	if err := service.StopEnvironment(ctx, first, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	if err := create(second); err != nil {
+		t.Fatalf("CreateEnvironment() after stopping the first error = %v", err)
+	}
+
+	_, err = service.StartEnvironment(ctx, &models.StartEnvironmentRequest{
+		WorkspaceID: first,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    1,
+		MemoryGB:    2,
+	})
+	if policyReason(err) != models.PolicyMaxRunning {
+		t.Fatalf("StartEnvironment() error = %v, want %s", err, models.PolicyMaxRunning)
+	}
+	if stored, _ := store.Get(ctx, first); stored.Status != models.StatusStopped {
+		t.Errorf("stored status after a rejected start = %s, want STOPPED", stored.Status)
+	}
+}
+
+func TestEnvironmentService_PolicyResize(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, _ := newTestEnvironmentService(t, store)
+	service.policy = NewPolicyEngine(testPolicy(), store)
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    1,
+		MemoryGB:    2,
+		StorageGB:   10,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+
+	// The free tier allows 2 vCPU and 30GB of storage
+	_, err := service.ResizeEnvironment(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: wsID, CPUCores: 4})
+	if policyReason(err) != models.PolicyCPUQuota {
+		t.Fatalf("ResizeEnvironment() error = %v, want %s", err, models.PolicyCPUQuota)
+	}
+	if spec, _ := provider.Spec(wsID); spec.CPUCores != 1 {
+		t.Errorf("container CPU after a rejected resize = %v, want 1", spec.CPUCores)
+	}
+	_, err = service.ResizeEnvironmentAsync(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: wsID, StorageGB: 40})
+	if policyReason(err) != models.PolicyStorageQuota {
+		t.Fatalf("ResizeEnvironmentAsync() error = %v, want %s", err, models.PolicyStorageQuota)
+	}
+	if stored, _ := store.Get(ctx, wsID); stored.CPUCores != 1 || stored.StorageGB != 10 {
+		t.Errorf("stored size after rejected resizes = %d cores, %dGB", stored.CPUCores, stored.StorageGB)
+	}
+
+	// The workspace's own size is replaced, not added to
+	if _, err := service.ResizeEnvironment(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: wsID, CPUCores: 2, StorageGB: 30}); err != nil {
+		t.Fatalf("ResizeEnvironment() within the limits error = %v", err)
+	}
+
+	// A stopped workspace's storage is still checked
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	_, err = service.ResizeEnvironment(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: wsID, StorageGB: 40})
+	if policyReason(err) != models.PolicyStorageQuota {
+		t.Fatalf("ResizeEnvironment() of a stopped workspace error = %v, want %s", err, models.PolicyStorageQuota)
+	}
+}
+
+func TestEnvironmentService_PolicyRestoreToNewShare(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, _, volumes := newTestEnvironmentService(t, store)
+	service.policy = NewPolicyEngine(testPolicy(), store)
+	const targetID = "660e8400-e29b-41d4-a716-446655440000"
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    1,
+		MemoryGB:    2,
+		StorageGB:   20,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	snapshot, err := service.CreateSnapshot(ctx, &models.CreateSnapshotRequest{WorkspaceID: wsID})
+	if err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+
+	// A second 20GB volume exceeds the free tier's 30GB
+	_, err = service.RestoreSnapshot(ctx, &models.RestoreSnapshotRequest{
+		WorkspaceID:       wsID,
+		SnapshotID:        snapshot.ID,
+		Mode:              models.RestoreNewShare,
+		TargetWorkspaceID: targetID,
+	})
+	if policyReason(err) != models.PolicyStorageQuota {
+		t.Fatalf("RestoreSnapshot() error = %v, want %s", err, models.PolicyStorageQuota)
+	}
+	if _, err := store.Get(ctx, targetID); err == nil {
+		t.Error("a rejected restore left an environment record")
+	}
+	if _, err := os.Stat(volumes.Path("fs-" + targetID)); !os.IsNotExist(err) {
+		t.Errorf("a rejected restore left a volume: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/docker"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrContainerNotFound is returned by providers when a workspace has no container
+var ErrContainerNotFound = errors.New("container not found")
+
+// ContainerProvider runs workspace containers on one compute backend.
+// Every method addresses the container by workspace ID; providers derive
+// their own resource names from it.
+type ContainerProvider interface {
+	// Create creates and starts a new container for the workspace
+	Create(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error)
+	// Get returns container details, or an error wrapping ErrContainerNotFound
+	Get(ctx context.Context, workspaceID, region, resourceGroup string) (*ContainerInfo, error)
+	// Start starts a stopped container, creating it from spec if it no longer exists
+	Start(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error)
+	// Stop releases compute while keeping the workspace volume
+	Stop(ctx context.Context, workspaceID, region, resourceGroup string) error
+	// Resize applies the CPU and memory of spec, keeping the workspace volume. A running
+	// container is redeployed; a stopped one picks up the new size when it next starts.
+	Resize(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error)
+	// Delete removes the container
+	Delete(ctx context.Context, workspaceID, region, resourceGroup string) error
+	// Status reports the container state; a missing container is ContainerNotFound, not an error
+	Status(ctx context.Context, workspaceID, region, resourceGroup string) (ContainerStatus, error)
+}
+
+// CredentialUpdater is implemented by providers that can apply new keys and tokens to
+// a container without recreating it from scratch. Providers whose Resize redeploys a
+// running container from the full spec need not implement it.
+type CredentialUpdater interface {
+	// UpdateCredentials redeploys the workspace container with the secrets of spec
+	UpdateCredentials(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error)
+}
+
+// ContainerStatus is the provider-neutral state of a workspace container
+type ContainerStatus string
+
+const (
+	ContainerPending  ContainerStatus = "pending"
+	ContainerRunning  ContainerStatus = "running"
+	ContainerStopped  ContainerStatus = "stopped"
+	ContainerFailed   ContainerStatus = "failed"
+	ContainerNotFound ContainerStatus = "not-found"
+	ContainerUnknown  ContainerStatus = "unknown"
+)
+
+// Well-known ports of every workspace container
+const (
+	portCodeServer = 8080
+	portSSH        = 2222
+	portSupervisor = 9000
+)
+
+// ContainerInfo contains the result of a container creation
+type ContainerInfo struct {
+	Name string
+	FQDN string
+	ID   string
+
+	// Reachable container port -> port it is reached on at FQDN, e.g. a host port on a
+	// shared Docker host. Nil when the provider does not report ports; the well-known
+	// ports are then assumed to be reachable on FQDN.
+	Ports map[int]int
+	TLS   bool // code-server is reached over HTTPS
+}
+
+// ContainerDeploymentSpec contains the specification for deploying a container
+type ContainerDeploymentSpec struct {
+	Image              string
+	CPUCores           float64
+	MemoryGB           float64
+	FileShareName      string
+	LocalVolumePath    string // Host directory backing the volume, set only for the local volume backend
+	StorageAccountName string
+	StorageAccountKey  string
+	UserID             string
+
+	// Registry credentials
+	RegistryServer   string
+	RegistryUsername string
+	RegistryPassword string
+
+	// Environment variables
+	AgentBaseURL       string
+	GitHubToken        string
+	CodeServerPassword string
+	SSHPublicKey       string
+	GitUserName        string
+	GitUserEmail       string
+	AnthropicAPIKey    string
+	OpenAIAPIKey       string
+	GeminiAPIKey       string
+
+	// User environment variables and the user's exposed ports; providers add the well-known ports
+	EnvVars []models.EnvVar
+	Ports   []models.PortSpec
+}
+
+// workspaceContainerPorts returns the ports of a workspace container: code-server first,
+// then SSH, the supervisor and the user's ports
+func workspaceContainerPorts(user []models.PortSpec) []models.PortSpec {
+	ports := []models.PortSpec{
+		{Port: portCodeServer, Protocol: models.PortProtocolHTTP, Visibility: models.PortPublic},
+		{Port: portSSH, Protocol: models.PortProtocolTCP, Visibility: models.PortPublic},
+		{Port: portSupervisor, Protocol: models.PortProtocolHTTP, Visibility: models.PortPublic},
+	}
+	return append(ports, user...)
+}
+
+// applySecrets sets the workspace secrets on the spec, and vars as its environment
+// variables with the saved values of secret ones
+func (spec *ContainerDeploymentSpec) applySecrets(secrets *models.WorkspaceSecrets, vars []models.EnvVar) {
+	spec.GitHubToken = secrets.GitHubToken
+	spec.CodeServerPassword = secrets.CodeServerPassword
+	spec.SSHPublicKey = secrets.SSHPublicKey
+	spec.GitUserName = secrets.GitUserName
+	spec.GitUserEmail = secrets.GitUserEmail
+	spec.AnthropicAPIKey = secrets.AnthropicAPIKey
+	spec.OpenAIAPIKey = secrets.OpenAIAPIKey
+	spec.GeminiAPIKey = secrets.GeminiAPIKey
+	spec.EnvVars = deployableEnvVars(vars, secrets.EnvVars)
+}
+
+// deployableEnvVars fills in secret values read back from the environment record,
+// which does not keep them, from secretValues and drops secrets with no known value
+func deployableEnvVars(vars []models.EnvVar, secretValues map[string]string) []models.EnvVar {
+	var deployable []models.EnvVar
+	for _, v := range vars {
+		if v.Secret && v.Value == "" {
+			v.Value = secretValues[v.Name]
+		}
+		if v.Secret && v.Value == "" {
+			continue
+		}
+		deployable = append(deployable, v)
+	}
+	return deployable
+}
+
+// ProviderClients are the backend clients available to provider factories.
+// Clients not needed by the configured deployment mode are nil.
+type ProviderClients struct {
+	Azure      *azure.Client
+	Docker     *docker.Client
+	Kubernetes kubernetes.Interface
+}
+
+// ProviderFactory creates the container provider for a deployment mode
+type ProviderFactory func(cfg *config.Config, clients ProviderClients) (ContainerProvider, error)
+
+// providerFactories is the registry of deployment modes, keyed by AZURE_DEPLOYMENT_MODE
+var providerFactories = map[string]ProviderFactory{
+	"aci":        newACIProvider,
+	"aca":        newACAProvider,
+	"docker":     newDockerProvider,
+	"kubernetes": newKubernetesProvider,
+}
+
+// RegisterProvider adds or replaces the factory for a deployment mode.
+// It must be called before any EnvironmentService is created.
+func RegisterProvider(mode string, factory ProviderFactory) {
+	providerFactories[mode] = factory
+}
+
+// ProviderModes returns the registered deployment modes
+func ProviderModes() []string {
+	modes := make([]string, 0, len(providerFactories))
+	for mode := range providerFactories {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+// NewContainerProvider creates the provider for the configured deployment mode
+func NewContainerProvider(cfg *config.Config, clients ProviderClients) (ContainerProvider, error) {
+	mode := cfg.Azure.DeploymentMode
+	if mode == "" {
+		mode = "aci"
+	}
+
+	factory, ok := providerFactories[mode]
+	if !ok {
+		return nil, fmt.Errorf("invalid deployment mode: %s (registered: %v)", mode, ProviderModes())
+	}
+	return factory(cfg, clients)
+}
+
+// containerNotFound wraps ErrContainerNotFound with the provider's resource name
+func containerNotFound(name string) error {
+	return fmt.Errorf("%w: %s", ErrContainerNotFound, name)
+}
+
+// isContainerNotFound reports whether err means the workspace has no container
+func isContainerNotFound(err error) bool {
+	return errors.Is(err, ErrContainerNotFound)
+}
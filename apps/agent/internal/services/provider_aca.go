@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	armappcontainers "github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/appcontainers/armappcontainers/v2"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+)
+
+// acaProvider runs workspaces as Azure Container Apps (aca-{id}) in a managed environment
+type acaProvider struct {
+	client        *azure.Client
+	environmentID string
+}
+
+func newACAProvider(cfg *config.Config, clients ProviderClients) (ContainerProvider, error) {
+	if clients.Azure == nil {
+		return nil, fmt.Errorf("aca deployment mode requires an Azure client")
+	}
+	return &acaProvider{client: clients.Azure, environmentID: cfg.Azure.ContainerAppsEnvironmentID}, nil
+}
+
+// Create creates a container app
+func (p *acaProvider) Create(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+
+	if p.environmentID == "" {
+		return nil, fmt.Errorf("workspace %s: ACA environment ID not configured", workspaceID)
+	}
+
+	acaSpec := containerAppSpec(workspaceID, spec)
+
+	resp, err := p.client.CreateContainerApp(ctx, region, resourceGroup, p.environmentID, acaSpec)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get details, including the reachable ports
+	info, err := p.Get(ctx, workspaceID, region, resourceGroup)
+	if err != nil {
+		log.Printf("Warning: workspace %s: failed to get container details: %v", workspaceID, err)
+		return &ContainerInfo{Name: containerAppName, FQDN: resp.FQDN, ID: resp.ID}, nil
+	}
+	return info, nil
+}
+
+// Get gets container app details
+func (p *acaProvider) Get(ctx context.Context, workspaceID, region, resourceGroup string) (*ContainerInfo, error) {
+	app, err := p.getApp(ctx, workspaceID, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+	mappings, err := p.client.GetContainerAppPortMappings(ctx, resourceGroup, containerAppName)
+	if err != nil {
+		log.Printf("Warning: workspace %s: failed to get port mappings: %v", workspaceID, err)
+	}
+	return acaContainerInfo(workspaceID, app, mappings), nil
+}
+
+// Start starts a stopped container app, or creates it if it no longer exists
+func (p *acaProvider) Start(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+
+	// Check if container app exists
+	if _, err := p.getApp(ctx, workspaceID, resourceGroup); err != nil {
+		// Container app doesn't exist, need to create it
+		log.Printf("Container app %s not found, creating new one", containerAppName)
+		return p.Create(ctx, workspaceID, region, resourceGroup, spec)
+	}
+
+	log.Printf("Container app %s exists, starting it", containerAppName)
+	if err := p.client.StartContainerApp(ctx, resourceGroup, containerAppName); err != nil {
+		return nil, fmt.Errorf("failed to start container app: %w", err)
+	}
+
+	return p.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// Stop stops the container app using the native Stop API
+func (p *acaProvider) Stop(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+	return p.client.StopContainerApp(ctx, resourceGroup, containerAppName)
+}
+
+// Resize rolls a new revision with the new resources. The volume and secrets are kept.
+func (p *acaProvider) Resize(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+
+	if _, err := p.getApp(ctx, workspaceID, resourceGroup); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Rolling new revision of container app %s with %.1f cores and %.1f GB", containerAppName, spec.CPUCores, spec.MemoryGB)
+	if err := p.client.UpdateContainerAppResources(ctx, resourceGroup, containerAppName, spec.CPUCores, spec.MemoryGB); err != nil {
+		return nil, err
+	}
+	return p.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// UpdateCredentials rolls a new revision with the keys and tokens of spec
+func (p *acaProvider) UpdateCredentials(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+
+	if _, err := p.getApp(ctx, workspaceID, resourceGroup); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Rolling new revision of container app %s with new credentials", containerAppName)
+	if err := p.client.UpdateContainerAppEnv(ctx, resourceGroup, containerAppName, containerAppSpec(workspaceID, spec)); err != nil {
+		return nil, err
+	}
+	return p.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// Delete deletes the container app
+func (p *acaProvider) Delete(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+	return p.client.DeleteContainerApp(ctx, resourceGroup, containerAppName)
+}
+
+// Status maps the container app provisioning state. The management API does not
+// report whether a provisioned app is stopped, so a provisioned app counts as running.
+func (p *acaProvider) Status(ctx context.Context, workspaceID, region, resourceGroup string) (ContainerStatus, error) {
+	app, err := p.getApp(ctx, workspaceID, resourceGroup)
+	if err != nil {
+		if isContainerNotFound(err) {
+			return ContainerNotFound, nil
+		}
+		return ContainerUnknown, err
+	}
+	if app.Properties == nil || app.Properties.ProvisioningState == nil {
+		return ContainerUnknown, nil
+	}
+
+	switch *app.Properties.ProvisioningState {
+	case armappcontainers.ContainerAppProvisioningStateSucceeded:
+		return ContainerRunning, nil
+	case armappcontainers.ContainerAppProvisioningStateInProgress:
+		return ContainerPending, nil
+	case armappcontainers.ContainerAppProvisioningStateFailed, armappcontainers.ContainerAppProvisioningStateCanceled:
+		return ContainerFailed, nil
+	}
+	return ContainerUnknown, nil
+}
+
+// containerAppSpec converts a deployment spec for the container app aca-{id}
+func containerAppSpec(workspaceID string, spec ContainerDeploymentSpec) azure.ContainerAppSpec {
+	return azure.ContainerAppSpec{
+		WorkspaceID:        workspaceID,
+		UserID:             spec.UserID,
+		Name:               fmt.Sprintf("aca-%s", workspaceID),
+		Image:              spec.Image,
+		CPUCores:           spec.CPUCores,
+		MemoryGB:           spec.MemoryGB,
+		FileShareName:      spec.FileShareName,
+		StorageAccountName: spec.StorageAccountName,
+		GitHubToken:        spec.GitHubToken,
+		CodeServerPassword: spec.CodeServerPassword,
+		SSHPublicKey:       spec.SSHPublicKey,
+		GitUserName:        spec.GitUserName,
+		GitUserEmail:       spec.GitUserEmail,
+		AnthropicAPIKey:    spec.AnthropicAPIKey,
+		OpenAIAPIKey:       spec.OpenAIAPIKey,
+		GeminiAPIKey:       spec.GeminiAPIKey,
+		AgentBaseURL:       spec.AgentBaseURL,
+		EnvVars:            azureEnvVars(spec.EnvVars),
+		Ports:              azureContainerPorts(spec.Ports),
+	}
+}
+
+func (p *acaProvider) getApp(ctx context.Context, workspaceID, resourceGroup string) (*armappcontainers.ContainerApp, error) {
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+	app, err := p.client.GetContainerApp(ctx, resourceGroup, containerAppName)
+	if err != nil {
+		if azure.IsNotFound(err) {
+			return nil, containerNotFound(containerAppName)
+		}
+		return nil, err
+	}
+	return app, nil
+}
+
+// acaContainerInfo extracts the ingress FQDN and reachable ports from a container app.
+// External HTTP ingress serves its target port on 443; external additional port
+// mappings are reached on their exposed port.
+func acaContainerInfo(workspaceID string, app *armappcontainers.ContainerApp, mappings []azure.ContainerAppPortMapping) *ContainerInfo {
+	containerAppName := fmt.Sprintf("aca-%s", workspaceID)
+
+	var fqdn string
+	ports := make(map[int]int)
+	if app != nil &&
+		app.Properties != nil &&
+		app.Properties.Configuration != nil &&
+		app.Properties.Configuration.Ingress != nil {
+		ingress := app.Properties.Configuration.Ingress
+		if ingress.Fqdn != nil {
+			fqdn = *ingress.Fqdn
+		}
+		if ingress.External != nil && *ingress.External && ingress.TargetPort != nil {
+			ports[int(*ingress.TargetPort)] = 443
+		}
+	}
+	for _, mapping := range mappings {
+		if !mapping.External {
+			continue
+		}
+		exposed := mapping.ExposedPort
+		if exposed == 0 {
+			exposed = mapping.TargetPort
+		}
+		ports[int(mapping.TargetPort)] = int(exposed)
+	}
+
+	return &ContainerInfo{
+		Name:  containerAppName,
+		FQDN:  fqdn,
+		ID:    containerAppName,
+		Ports: ports,
+		TLS:   true,
+	}
+}
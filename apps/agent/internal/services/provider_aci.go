@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/containerinstance/armcontainerinstance/v2"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// aciProvider runs workspaces as Azure Container Instances container groups (aci-{id})
+type aciProvider struct {
+	client *azure.Client
+}
+
+func newACIProvider(cfg *config.Config, clients ProviderClients) (ContainerProvider, error) {
+	if clients.Azure == nil {
+		return nil, fmt.Errorf("aci deployment mode requires an Azure client")
+	}
+	return &aciProvider{client: clients.Azure}, nil
+}
+
+// Create creates a container group
+func (p *aciProvider) Create(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerGroupName := fmt.Sprintf("aci-%s", workspaceID)
+	dnsLabel := fmt.Sprintf("ws-%s", workspaceID)
+
+	aciSpec := azure.ContainerGroupSpec{
+		ContainerName:      "vscode-server",
+		Image:              spec.Image,
+		CPUCores:           int(spec.CPUCores),
+		MemoryGB:           int(spec.MemoryGB),
+		DNSNameLabel:       dnsLabel,
+		FileShareName:      spec.FileShareName,
+		StorageAccountName: spec.StorageAccountName,
+		StorageAccountKey:  spec.StorageAccountKey,
+		EnvironmentID:      workspaceID,
+		UserID:             spec.UserID,
+		RegistryServer:     spec.RegistryServer,
+		RegistryUsername:   spec.RegistryUsername,
+		RegistryPassword:   spec.RegistryPassword,
+		AgentBaseURL:       spec.AgentBaseURL,
+		GitHubToken:        spec.GitHubToken,
+		CodeServerPassword: spec.CodeServerPassword,
+		SSHPublicKey:       spec.SSHPublicKey,
+		GitUserName:        spec.GitUserName,
+		GitUserEmail:       spec.GitUserEmail,
+		AnthropicAPIKey:    spec.AnthropicAPIKey,
+		OpenAIAPIKey:       spec.OpenAIAPIKey,
+		GeminiAPIKey:       spec.GeminiAPIKey,
+		EnvVars:            azureEnvVars(spec.EnvVars),
+		Ports:              azureContainerPorts(spec.Ports),
+	}
+
+	if err := p.client.CreateContainerGroup(ctx, region, resourceGroup, containerGroupName, aciSpec); err != nil {
+		return nil, err
+	}
+
+	// Get details
+	info, err := p.Get(ctx, workspaceID, region, resourceGroup)
+	if err != nil {
+		log.Printf("Warning: workspace %s: failed to get container details: %v", workspaceID, err)
+		return &ContainerInfo{Name: containerGroupName, ID: containerGroupName}, nil
+	}
+	return info, nil
+}
+
+// Get gets container group details
+func (p *aciProvider) Get(ctx context.Context, workspaceID, region, resourceGroup string) (*ContainerInfo, error) {
+	group, err := p.getGroup(ctx, workspaceID, region, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	return aciContainerInfo(workspaceID, group), nil
+}
+
+// Start starts a stopped container group or creates a new one
+func (p *aciProvider) Start(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerGroupName := fmt.Sprintf("aci-%s", workspaceID)
+
+	// Check if container group exists
+	group, err := p.getGroup(ctx, workspaceID, region, resourceGroup)
+	if err != nil {
+		// Container doesn't exist, create a new one
+		log.Printf("Container group %s not found, creating new one", containerGroupName)
+		return p.Create(ctx, workspaceID, region, resourceGroup, spec)
+	}
+
+	// Container exists, start it
+	log.Printf("Container group %s exists, starting it", containerGroupName)
+	if err := p.client.StartContainerGroup(ctx, region, resourceGroup, containerGroupName); err != nil {
+		return nil, fmt.Errorf("failed to start container group: %w", err)
+	}
+
+	return aciContainerInfo(workspaceID, group), nil
+}
+
+// Stop stops the container group (keeps it in stopped state)
+func (p *aciProvider) Stop(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	containerGroupName := fmt.Sprintf("aci-%s", workspaceID)
+	return p.client.StopContainerGroup(ctx, region, resourceGroup, containerGroupName)
+}
+
+// Resize recreates the container group with the new resources; ACI cannot change the
+// resources of an existing group. A stopped group is deleted and recreated by Start.
+func (p *aciProvider) Resize(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerGroupName := fmt.Sprintf("aci-%s", workspaceID)
+
+	status, err := p.Status(ctx, workspaceID, region, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	if status == ContainerNotFound {
+		return nil, containerNotFound(containerGroupName)
+	}
+
+	log.Printf("Recreating container group %s with %.1f cores and %.1f GB", containerGroupName, spec.CPUCores, spec.MemoryGB)
+	if err := p.client.DeleteContainerGroup(ctx, region, resourceGroup, containerGroupName); err != nil {
+		return nil, fmt.Errorf("failed to delete container group for resize: %w", err)
+	}
+	if status == ContainerStopped {
+		return &ContainerInfo{Name: containerGroupName, ID: containerGroupName}, nil
+	}
+	return p.Create(ctx, workspaceID, region, resourceGroup, spec)
+}
+
+// Delete deletes the container group
+func (p *aciProvider) Delete(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	containerGroupName := fmt.Sprintf("aci-%s", workspaceID)
+	return p.client.DeleteContainerGroup(ctx, region, resourceGroup, containerGroupName)
+}
+
+// Status maps the container group instance state
+func (p *aciProvider) Status(ctx context.Context, workspaceID, region, resourceGroup string) (ContainerStatus, error) {
+	group, err := p.getGroup(ctx, workspaceID, region, resourceGroup)
+	if err != nil {
+		if isContainerNotFound(err) {
+			return ContainerNotFound, nil
+		}
+		return ContainerUnknown, err
+	}
+	if group.Properties == nil {
+		return ContainerUnknown, nil
+	}
+
+	if view := group.Properties.InstanceView; view != nil && view.State != nil {
+		switch strings.ToLower(*view.State) {
+		case "running":
+			return ContainerRunning, nil
+		case "stopped", "succeeded", "terminated":
+			return ContainerStopped, nil
+		case "pending", "creating", "starting", "restarting":
+			return ContainerPending, nil
+		case "failed":
+			return ContainerFailed, nil
+		}
+	}
+
+	if group.Properties.ProvisioningState != nil {
+		switch strings.ToLower(*group.Properties.ProvisioningState) {
+		case "failed":
+			return ContainerFailed, nil
+		case "pending", "creating", "updating", "repairing":
+			return ContainerPending, nil
+		}
+	}
+	return ContainerUnknown, nil
+}
+
+func (p *aciProvider) getGroup(ctx context.Context, workspaceID, region, resourceGroup string) (*armcontainerinstance.ContainerGroup, error) {
+	containerGroupName := fmt.Sprintf("aci-%s", workspaceID)
+	group, err := p.client.GetContainerGroup(ctx, region, resourceGroup, containerGroupName)
+	if err != nil {
+		if azure.IsNotFound(err) {
+			return nil, containerNotFound(containerGroupName)
+		}
+		return nil, err
+	}
+	return group, nil
+}
+
+// aciContainerInfo extracts the FQDN from a container group
+func aciContainerInfo(workspaceID string, group *armcontainerinstance.ContainerGroup) *ContainerInfo {
+	containerGroupName := fmt.Sprintf("aci-%s", workspaceID)
+
+	var fqdn string
+	if group != nil &&
+		group.Properties != nil &&
+		group.Properties.IPAddress != nil &&
+		group.Properties.IPAddress.Fqdn != nil {
+		fqdn = *group.Properties.IPAddress.Fqdn
+	}
+
+	// Only ports opened on the public IP address are reachable
+	ports := make(map[int]int)
+	if group != nil && group.Properties != nil && group.Properties.IPAddress != nil {
+		for _, port := range group.Properties.IPAddress.Ports {
+			if port.Port != nil {
+				ports[int(*port.Port)] = int(*port.Port)
+			}
+		}
+	}
+
+	return &ContainerInfo{
+		Name:  containerGroupName,
+		FQDN:  fqdn,
+		ID:    containerGroupName,
+		Ports: ports,
+		TLS:   true,
+	}
+}
+
+// azureContainerPorts returns the well-known and user ports in the form of the azure package
+func azureContainerPorts(user []models.PortSpec) []azure.ContainerPort {
+	var ports []azure.ContainerPort
+	for _, port := range workspaceContainerPorts(user) {
+		protocol := "tcp"
+		if port.Protocol == models.PortProtocolUDP {
+			protocol = "udp"
+		}
+		ports = append(ports, azure.ContainerPort{Port: int32(port.Port), Protocol: protocol, Public: port.Visibility == models.PortPublic})
+	}
+	return ports
+}
+
+// azureEnvVars returns the user environment variables in the form of the azure package
+func azureEnvVars(vars []models.EnvVar) []azure.EnvVar {
+	var envVars []azure.EnvVar
+	for _, v := range vars {
+		envVars = append(envVars, azure.EnvVar{Name: v.Name, Value: v.Value, Secret: v.Secret})
+	}
+	return envVars
+}
@@ -0,0 +1,561 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/aws"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// ecsAPI is the part of the ECS API used by the AWS provider
+type ecsAPI interface {
+	RegisterTaskDefinition(ctx context.Context, params *ecs.RegisterTaskDefinitionInput, optFns ...func(*ecs.Options)) (*ecs.RegisterTaskDefinitionOutput, error)
+	ListTaskDefinitions(ctx context.Context, params *ecs.ListTaskDefinitionsInput, optFns ...func(*ecs.Options)) (*ecs.ListTaskDefinitionsOutput, error)
+	DeregisterTaskDefinition(ctx context.Context, params *ecs.DeregisterTaskDefinitionInput, optFns ...func(*ecs.Options)) (*ecs.DeregisterTaskDefinitionOutput, error)
+	RunTask(ctx context.Context, params *ecs.RunTaskInput, optFns ...func(*ecs.Options)) (*ecs.RunTaskOutput, error)
+	ListTasks(ctx context.Context, params *ecs.ListTasksInput, optFns ...func(*ecs.Options)) (*ecs.ListTasksOutput, error)
+	DescribeTasks(ctx context.Context, params *ecs.DescribeTasksInput, optFns ...func(*ecs.Options)) (*ecs.DescribeTasksOutput, error)
+	StopTask(ctx context.Context, params *ecs.StopTaskInput, optFns ...func(*ecs.Options)) (*ecs.StopTaskOutput, error)
+}
+
+// ec2API looks up the public address of a task's network interface
+type ec2API interface {
+	DescribeNetworkInterfaces(ctx context.Context, params *ec2.DescribeNetworkInterfacesInput, optFns ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error)
+}
+
+// awsRegion is the ECS cluster, network and EFS file system of one AWS region
+type awsRegion struct {
+	config config.AWSRegionConfig
+	ecs    ecsAPI
+	efs    efsAPI
+	ec2    ec2API
+}
+
+// awsProvider runs each workspace as a Fargate task of the ecs-{id} task definition
+// family, with the workspace's EFS access point mounted at /home/dev8. Stopping a
+// workspace stops its task and keeps the task definition.
+type awsProvider struct {
+	config  config.AWSConfig
+	regions map[string]*awsRegion
+}
+
+// awsStopReason marks tasks stopped by the agent, as opposed to tasks that failed
+const awsStopReason = "Stopped by dev8-agent"
+
+// newAWSBackend creates the AWS provider and the EFS volume store of every enabled AWS region
+func newAWSBackend(cfg *config.Config, client *aws.Client) (ContainerProvider, map[string]VolumeStore, error) {
+	provider := &awsProvider{config: cfg.AWS, regions: make(map[string]*awsRegion)}
+	volumes := make(map[string]VolumeStore)
+
+	for _, region := range cfg.GetEnabledAWSRegions() {
+		clients, ok := client.Region(region.Name)
+		if !ok {
+			return nil, nil, fmt.Errorf("no AWS clients for region %s", region.Name)
+		}
+		provider.regions[region.Name] = &awsRegion{config: region, ecs: clients.ECS, efs: clients.EFS, ec2: clients.EC2}
+		volumes[region.Name] = &efsVolumes{client: clients.EFS, fileSystemID: region.FileSystemID}
+	}
+
+	return provider, volumes, nil
+}
+
+// Create registers the workspace task definition and runs a task from it
+func (p *awsProvider) Create(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	r, err := p.region(region)
+	if err != nil {
+		return nil, err
+	}
+	return p.launch(ctx, r, workspaceID, spec)
+}
+
+// Get returns the running task and its address. A stopped workspace has no task
+// and is reported without an address.
+func (p *awsProvider) Get(ctx context.Context, workspaceID, region, resourceGroup string) (*ContainerInfo, error) {
+	name := fmt.Sprintf("ecs-%s", workspaceID)
+
+	r, err := p.region(region)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := p.tasks(ctx, r, workspaceID, ecstypes.DesiredStatusRunning)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		revisions, err := p.taskDefinitions(ctx, r, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		if len(revisions) == 0 {
+			return nil, containerNotFound(name)
+		}
+		return &ContainerInfo{Name: name}, nil
+	}
+
+	task := tasks[0]
+	info := &ContainerInfo{Name: name, ID: deref(task.TaskArn)}
+	info.FQDN, err = p.taskAddress(ctx, r, task)
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// Start runs a new task with the current spec unless one is already running
+func (p *awsProvider) Start(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	r, err := p.region(region)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := p.tasks(ctx, r, workspaceID, ecstypes.DesiredStatusRunning)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) > 0 {
+		log.Printf("Task for ecs-%s already running", workspaceID)
+		return p.Get(ctx, workspaceID, region, resourceGroup)
+	}
+
+	// Registering a new revision picks up image, size and key changes
+	return p.launch(ctx, r, workspaceID, spec)
+}
+
+// Resize replaces a running task with one from a new task definition revision. A stopped
+// workspace has no task; Start registers a revision with the new size.
+func (p *awsProvider) Resize(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	name := fmt.Sprintf("ecs-%s", workspaceID)
+
+	r, err := p.region(region)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := p.tasks(ctx, r, workspaceID, ecstypes.DesiredStatusRunning)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		revisions, err := p.taskDefinitions(ctx, r, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		if len(revisions) == 0 {
+			return nil, containerNotFound(name)
+		}
+		return &ContainerInfo{Name: name}, nil
+	}
+
+	if err := p.stopTasks(ctx, r, workspaceID); err != nil {
+		return nil, err
+	}
+	return p.launch(ctx, r, workspaceID, spec)
+}
+
+// Stop stops the workspace's tasks, keeping the task definition and the access point
+func (p *awsProvider) Stop(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	r, err := p.region(region)
+	if err != nil {
+		return err
+	}
+
+	revisions, err := p.taskDefinitions(ctx, r, workspaceID)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		return containerNotFound(fmt.Sprintf("ecs-%s", workspaceID))
+	}
+	return p.stopTasks(ctx, r, workspaceID)
+}
+
+// Delete stops the workspace's tasks and deregisters its task definitions
+func (p *awsProvider) Delete(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	r, err := p.region(region)
+	if err != nil {
+		return err
+	}
+
+	if err := p.stopTasks(ctx, r, workspaceID); err != nil {
+		return err
+	}
+
+	revisions, err := p.taskDefinitions(ctx, r, workspaceID)
+	if err != nil {
+		return err
+	}
+	for _, arn := range revisions {
+		if _, err := r.ecs.DeregisterTaskDefinition(ctx, &ecs.DeregisterTaskDefinitionInput{TaskDefinition: &arn}); err != nil {
+			return fmt.Errorf("failed to deregister task definition %s: %w", arn, err)
+		}
+	}
+	return nil
+}
+
+// Status maps the ECS task lifecycle. Without a running task, a task that stopped
+// for any reason other than the agent stopping it means the workspace failed.
+func (p *awsProvider) Status(ctx context.Context, workspaceID, region, resourceGroup string) (ContainerStatus, error) {
+	r, err := p.region(region)
+	if err != nil {
+		return ContainerUnknown, err
+	}
+
+	tasks, err := p.tasks(ctx, r, workspaceID, ecstypes.DesiredStatusRunning)
+	if err != nil {
+		return ContainerUnknown, err
+	}
+	if len(tasks) > 0 {
+		switch deref(tasks[0].LastStatus) {
+		case "RUNNING":
+			return ContainerRunning, nil
+		case "PROVISIONING", "PENDING", "ACTIVATING":
+			return ContainerPending, nil
+		}
+		return ContainerStopped, nil
+	}
+
+	revisions, err := p.taskDefinitions(ctx, r, workspaceID)
+	if err != nil {
+		return ContainerUnknown, err
+	}
+	if len(revisions) == 0 {
+		return ContainerNotFound, nil
+	}
+
+	// ECS keeps stopped tasks for about an hour
+	stopped, err := p.tasks(ctx, r, workspaceID, ecstypes.DesiredStatusStopped)
+	if err != nil {
+		return ContainerUnknown, err
+	}
+	if len(stopped) > 0 && deref(stopped[0].StoppedReason) != awsStopReason {
+		return ContainerFailed, nil
+	}
+	return ContainerStopped, nil
+}
+
+func (p *awsProvider) region(name string) (*awsRegion, error) {
+	r, ok := p.regions[name]
+	if !ok {
+		return nil, fmt.Errorf("AWS region %s is not configured", name)
+	}
+	return r, nil
+}
+
+// launch registers a task definition revision for spec and runs one task from it
+func (p *awsProvider) launch(ctx context.Context, r *awsRegion, workspaceID string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	name := fmt.Sprintf("ecs-%s", workspaceID)
+
+	accessPoint, err := findAccessPoint(ctx, r.efs, r.config.FileSystemID, spec.FileShareName)
+	if err != nil {
+		return nil, fmt.Errorf("workspace volume %s: %w", spec.FileShareName, err)
+	}
+	if spec.RegistryUsername != "" {
+		log.Printf("Warning: %s: registry credentials are ignored on AWS; grant the execution role access to the registry", name)
+	}
+
+	definition, err := r.ecs.RegisterTaskDefinition(ctx, p.taskDefinition(r, workspaceID, spec, deref(accessPoint.AccessPointId)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register task definition %s: %w", name, err)
+	}
+
+	assignPublicIP := ecstypes.AssignPublicIpDisabled
+	if p.config.AssignPublicIP {
+		assignPublicIP = ecstypes.AssignPublicIpEnabled
+	}
+	_, secrets := awsEnvironment(workspaceID, spec)
+	out, err := r.ecs.RunTask(ctx, &ecs.RunTaskInput{
+		Cluster:        &r.config.Cluster,
+		TaskDefinition: definition.TaskDefinition.TaskDefinitionArn,
+		LaunchType:     ecstypes.LaunchTypeFargate,
+		StartedBy:      strPtr(managedByTag),
+		NetworkConfiguration: &ecstypes.NetworkConfiguration{AwsvpcConfiguration: &ecstypes.AwsVpcConfiguration{
+			Subnets:        r.config.Subnets,
+			SecurityGroups: r.config.SecurityGroups,
+			AssignPublicIp: assignPublicIP,
+		}},
+		// Keys and tokens are passed per task so they never land in a task definition
+		Overrides: &ecstypes.TaskOverride{ContainerOverrides: []ecstypes.ContainerOverride{
+			{Name: strPtr("workspace"), Environment: secrets},
+		}},
+		Tags: awsTags(workspaceID),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to run task %s: %w", name, err)
+	}
+	if len(out.Failures) > 0 {
+		return nil, fmt.Errorf("failed to run task %s: %s", name, deref(out.Failures[0].Reason))
+	}
+	if len(out.Tasks) == 0 {
+		return nil, fmt.Errorf("failed to run task %s: no task started", name)
+	}
+
+	return &ContainerInfo{Name: name, ID: deref(out.Tasks[0].TaskArn)}, nil
+}
+
+func (p *awsProvider) taskDefinition(r *awsRegion, workspaceID string, spec ContainerDeploymentSpec, accessPointID string) *ecs.RegisterTaskDefinitionInput {
+	cpu, memory := fargateSize(spec.CPUCores, spec.MemoryGB)
+	plain, _ := awsEnvironment(workspaceID, spec)
+
+	var portMappings []ecstypes.PortMapping
+	for _, port := range []int32{portCodeServer, portSSH, portSupervisor} {
+		portMappings = append(portMappings, ecstypes.PortMapping{ContainerPort: int32Ptr(port), Protocol: ecstypes.TransportProtocolTcp})
+	}
+
+	input := &ecs.RegisterTaskDefinitionInput{
+		Family:                  strPtr(fmt.Sprintf("ecs-%s", workspaceID)),
+		RequiresCompatibilities: []ecstypes.Compatibility{ecstypes.CompatibilityFargate},
+		NetworkMode:             ecstypes.NetworkModeAwsvpc,
+		Cpu:                     strPtr(strconv.Itoa(cpu)),
+		Memory:                  strPtr(strconv.Itoa(memory)),
+		ContainerDefinitions: []ecstypes.ContainerDefinition{{
+			Name:         strPtr("workspace"),
+			Image:        &spec.Image,
+			Essential:    boolPtr(true),
+			PortMappings: portMappings,
+			Environment:  plain,
+			MountPoints:  []ecstypes.MountPoint{{SourceVolume: strPtr("home"), ContainerPath: strPtr("/home/dev8")}},
+		}},
+		Volumes: []ecstypes.Volume{{
+			Name: strPtr("home"),
+			EfsVolumeConfiguration: &ecstypes.EFSVolumeConfiguration{
+				FileSystemId:      &r.config.FileSystemID,
+				TransitEncryption: ecstypes.EFSTransitEncryptionEnabled,
+				AuthorizationConfig: &ecstypes.EFSAuthorizationConfig{
+					AccessPointId: &accessPointID,
+				},
+			},
+		}},
+		Tags: awsTags(workspaceID),
+	}
+	if p.config.ExecutionRoleARN != "" {
+		input.ExecutionRoleArn = &p.config.ExecutionRoleARN
+	}
+	if p.config.TaskRoleARN != "" {
+		input.TaskRoleArn = &p.config.TaskRoleARN
+	}
+	return input
+}
+
+// tasks returns the workspace's tasks with the given desired status, newest first
+func (p *awsProvider) tasks(ctx context.Context, r *awsRegion, workspaceID string, status ecstypes.DesiredStatus) ([]ecstypes.Task, error) {
+	family := fmt.Sprintf("ecs-%s", workspaceID)
+
+	list, err := r.ecs.ListTasks(ctx, &ecs.ListTasksInput{
+		Cluster:       &r.config.Cluster,
+		Family:        &family,
+		DesiredStatus: status,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tasks of %s: %w", family, err)
+	}
+	if len(list.TaskArns) == 0 {
+		return nil, nil
+	}
+
+	described, err := r.ecs.DescribeTasks(ctx, &ecs.DescribeTasksInput{Cluster: &r.config.Cluster, Tasks: list.TaskArns})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe tasks of %s: %w", family, err)
+	}
+	tasks := described.Tasks
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].CreatedAt != nil && tasks[j].CreatedAt != nil && tasks[i].CreatedAt.After(*tasks[j].CreatedAt)
+	})
+	return tasks, nil
+}
+
+func (p *awsProvider) stopTasks(ctx context.Context, r *awsRegion, workspaceID string) error {
+	tasks, err := p.tasks(ctx, r, workspaceID, ecstypes.DesiredStatusRunning)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		_, err := r.ecs.StopTask(ctx, &ecs.StopTaskInput{
+			Cluster: &r.config.Cluster,
+			Task:    task.TaskArn,
+			Reason:  strPtr(awsStopReason),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to stop task %s: %w", deref(task.TaskArn), err)
+		}
+	}
+	return nil
+}
+
+// taskDefinitions returns the ARNs of the active revisions of the workspace's family
+func (p *awsProvider) taskDefinitions(ctx context.Context, r *awsRegion, workspaceID string) ([]string, error) {
+	family := fmt.Sprintf("ecs-%s", workspaceID)
+
+	var (
+		arns      []string
+		nextToken *string
+	)
+	for {
+		out, err := r.ecs.ListTaskDefinitions(ctx, &ecs.ListTaskDefinitionsInput{
+			FamilyPrefix: &family,
+			Status:       ecstypes.TaskDefinitionStatusActive,
+			NextToken:    nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list task definitions of %s: %w", family, err)
+		}
+		for _, arn := range out.TaskDefinitionArns {
+			// The prefix also matches longer family names
+			if taskDefinitionFamily(arn) == family {
+				arns = append(arns, arn)
+			}
+		}
+		if out.NextToken == nil || *out.NextToken == "" {
+			return arns, nil
+		}
+		nextToken = out.NextToken
+	}
+}
+
+// taskAddress returns the public DNS name or IP of the task's network interface, or
+// its private IP when tasks get no public IP. It is empty until the interface is attached.
+func (p *awsProvider) taskAddress(ctx context.Context, r *awsRegion, task ecstypes.Task) (string, error) {
+	var interfaceID, privateIP string
+	for _, attachment := range task.Attachments {
+		if deref(attachment.Type) != "ElasticNetworkInterface" {
+			continue
+		}
+		for _, detail := range attachment.Details {
+			switch deref(detail.Name) {
+			case "networkInterfaceId":
+				interfaceID = deref(detail.Value)
+			case "privateIPv4Address":
+				privateIP = deref(detail.Value)
+			}
+		}
+	}
+	if !p.config.AssignPublicIP || interfaceID == "" {
+		return privateIP, nil
+	}
+
+	out, err := r.ec2.DescribeNetworkInterfaces(ctx, &ec2.DescribeNetworkInterfacesInput{NetworkInterfaceIds: []string{interfaceID}})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe network interface %s: %w", interfaceID, err)
+	}
+	for _, eni := range out.NetworkInterfaces {
+		if eni.Association == nil {
+			continue
+		}
+		if name := deref(eni.Association.PublicDnsName); name != "" {
+			return name, nil
+		}
+		if ip := deref(eni.Association.PublicIp); ip != "" {
+			return ip, nil
+		}
+	}
+	return "", nil
+}
+
+// awsEnvironment splits the container environment into plain values, stored in the
+// task definition, and keys and tokens, passed as overrides when the task runs
+func awsEnvironment(workspaceID string, spec ContainerDeploymentSpec) (plain, secrets []ecstypes.KeyValuePair) {
+	pair := func(name, value string) ecstypes.KeyValuePair {
+		return ecstypes.KeyValuePair{Name: strPtr(name), Value: strPtr(value)}
+	}
+
+	plain = []ecstypes.KeyValuePair{
+		pair("WORKSPACE_ID", workspaceID),
+		pair("USER_ID", spec.UserID),
+		pair("WORKSPACE_DIR", "/home/dev8/workspace"),
+		pair("AGENT_BASE_URL", spec.AgentBaseURL),
+		pair("AGENT_ENABLED", "true"),
+		pair("MONITOR_INTERVAL", "30s"),
+		pair("LOG_FILE_PATH", "/var/log/supervisor.log"),
+	}
+	if spec.GitUserName != "" {
+		plain = append(plain, pair("GIT_USER_NAME", spec.GitUserName))
+	}
+	if spec.GitUserEmail != "" {
+		plain = append(plain, pair("GIT_USER_EMAIL", spec.GitUserEmail))
+	}
+
+	for _, v := range []struct{ name, value string }{
+		{"GITHUB_TOKEN", spec.GitHubToken},
+		{"CODE_SERVER_PASSWORD", spec.CodeServerPassword},
+		{"SSH_PUBLIC_KEY", spec.SSHPublicKey},
+		{"ANTHROPIC_API_KEY", spec.AnthropicAPIKey},
+		{"OPENAI_API_KEY", spec.OpenAIAPIKey},
+		{"GEMINI_API_KEY", spec.GeminiAPIKey},
+	} {
+		if v.value != "" {
+			secrets = append(secrets, pair(v.name, v.value))
+		}
+	}
+	for _, v := range spec.EnvVars {
+		if v.Secret {
+			secrets = append(secrets, pair(v.Name, v.Value))
+		} else {
+			plain = append(plain, pair(v.Name, v.Value))
+		}
+	}
+	return plain, secrets
+}
+
+func awsTags(workspaceID string) []ecstypes.Tag {
+	return []ecstypes.Tag{
+		{Key: strPtr("managed-by"), Value: strPtr(managedByTag)},
+		{Key: strPtr("workspace-id"), Value: &workspaceID},
+	}
+}
+
+// fargateSize picks the smallest Fargate CPU size (in CPU units) that fits the requested
+// cores and memory, and raises memory (in MiB) to that size's minimum
+func fargateSize(cpuCores, memoryGB float64) (cpu, memory int) {
+	sizes := []struct {
+		vcpu         float64
+		minGB, maxGB float64
+	}{
+		{vcpu: 1, minGB: 2, maxGB: 8},
+		{vcpu: 2, minGB: 4, maxGB: 16},
+		{vcpu: 4, minGB: 8, maxGB: 30},
+	}
+
+	size := sizes[len(sizes)-1]
+	for _, s := range sizes {
+		if s.vcpu >= cpuCores && s.maxGB >= memoryGB {
+			size = s
+			break
+		}
+	}
+	if memoryGB < size.minGB {
+		memoryGB = size.minGB
+	}
+	if memoryGB > size.maxGB {
+		memoryGB = size.maxGB
+	}
+	return int(size.vcpu * 1024), int(memoryGB * 1024)
+}
+
+// taskDefinitionFamily extracts the family from arn:aws:ecs:{region}:{account}:task-definition/{family}:{revision}
+func taskDefinitionFamily(arn string) string {
+	_, familyRevision, ok := strings.Cut(arn, "task-definition/")
+	if !ok {
+		return ""
+	}
+	family, _, _ := strings.Cut(familyRevision, ":")
+	return family
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	ecstypes "github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	efstypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeECS keeps task definitions and tasks in memory
+type fakeECS struct {
+	definitions map[string]*ecs.RegisterTaskDefinitionInput // ARN -> input, active revisions only
+	revisions   map[string]int
+	tasks       []*ecstypes.Task
+	runs        []*ecs.RunTaskInput
+}
+
+func newFakeECS() *fakeECS {
+	return &fakeECS{definitions: make(map[string]*ecs.RegisterTaskDefinitionInput), revisions: make(map[string]int)}
+}
+
+func (f *fakeECS) RegisterTaskDefinition(ctx context.Context, in *ecs.RegisterTaskDefinitionInput, _ ...func(*ecs.Options)) (*ecs.RegisterTaskDefinitionOutput, error) {
+	f.revisions[*in.Family]++
+	arn := fmt.Sprintf("arn:aws:ecs:us-east-1:123:task-definition/%s:%d", *in.Family, f.revisions[*in.Family])
+	f.definitions[arn] = in
+	return &ecs.RegisterTaskDefinitionOutput{TaskDefinition: &ecstypes.TaskDefinition{TaskDefinitionArn: &arn}}, nil
+}
+
+func (f *fakeECS) ListTaskDefinitions(ctx context.Context, in *ecs.ListTaskDefinitionsInput, _ ...func(*ecs.Options)) (*ecs.ListTaskDefinitionsOutput, error) {
+	out := &ecs.ListTaskDefinitionsOutput{}
+	for arn := range f.definitions {
+		if strings.HasPrefix(taskDefinitionFamily(arn), *in.FamilyPrefix) {
+			out.TaskDefinitionArns = append(out.TaskDefinitionArns, arn)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeECS) DeregisterTaskDefinition(ctx context.Context, in *ecs.DeregisterTaskDefinitionInput, _ ...func(*ecs.Options)) (*ecs.DeregisterTaskDefinitionOutput, error) {
+	delete(f.definitions, *in.TaskDefinition)
+	return &ecs.DeregisterTaskDefinitionOutput{}, nil
+}
+
+func (f *fakeECS) RunTask(ctx context.Context, in *ecs.RunTaskInput, _ ...func(*ecs.Options)) (*ecs.RunTaskOutput, error) {
+	f.runs = append(f.runs, in)
+	now := time.Now().Add(time.Duration(len(f.tasks)) * time.Second)
+	task := &ecstypes.Task{
+		TaskArn:           strPtr(fmt.Sprintf("arn:aws:ecs:us-east-1:123:task/dev8/%d", len(f.tasks))),
+		TaskDefinitionArn: in.TaskDefinition,
+		LastStatus:        strPtr("PROVISIONING"),
+		DesiredStatus:     strPtr("RUNNING"),
+		CreatedAt:         &now,
+		Attachments: []ecstypes.Attachment{{
+			Type: strPtr("ElasticNetworkInterface"),
+			Details: []ecstypes.KeyValuePair{
+				{Name: strPtr("networkInterfaceId"), Value: strPtr("eni-1")},
+				{Name: strPtr("privateIPv4Address"), Value: strPtr("10.0.0.5")},
+			},
+		}},
+	}
+	f.tasks = append(f.tasks, task)
+	return &ecs.RunTaskOutput{Tasks: []ecstypes.Task{*task}}, nil
+}
+
+func (f *fakeECS) ListTasks(ctx context.Context, in *ecs.ListTasksInput, _ ...func(*ecs.Options)) (*ecs.ListTasksOutput, error) {
+	out := &ecs.ListTasksOutput{}
+	for _, task := range f.tasks {
+		if taskDefinitionFamily(*task.TaskDefinitionArn) == *in.Family && *task.DesiredStatus == string(in.DesiredStatus) {
+			out.TaskArns = append(out.TaskArns, *task.TaskArn)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeECS) DescribeTasks(ctx context.Context, in *ecs.DescribeTasksInput, _ ...func(*ecs.Options)) (*ecs.DescribeTasksOutput, error) {
+	out := &ecs.DescribeTasksOutput{}
+	for _, arn := range in.Tasks {
+		for _, task := range f.tasks {
+			if *task.TaskArn == arn {
+				out.Tasks = append(out.Tasks, *task)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeECS) StopTask(ctx context.Context, in *ecs.StopTaskInput, _ ...func(*ecs.Options)) (*ecs.StopTaskOutput, error) {
+	for _, task := range f.tasks {
+		if *task.TaskArn == *in.Task {
+			f.stop(task, *in.Reason)
+		}
+	}
+	return &ecs.StopTaskOutput{}, nil
+}
+
+func (f *fakeECS) stop(task *ecstypes.Task, reason string) {
+	task.DesiredStatus = strPtr("STOPPED")
+	task.LastStatus = strPtr("STOPPED")
+	task.StoppedReason = &reason
+}
+
+// fakeEFS keeps access points in memory
+type fakeEFS struct {
+	accessPoints []efstypes.AccessPointDescription
+}
+
+// CreateAccessPoint returns the existing access point for a repeated client token,
+// or an IdempotentParameterMismatch when the request differs like EFS does
+func (f *fakeEFS) CreateAccessPoint(ctx context.Context, in *efs.CreateAccessPointInput, _ ...func(*efs.Options)) (*efs.CreateAccessPointOutput, error) {
+	for _, existing := range f.accessPoints {
+		if in.ClientToken == nil || existing.ClientToken == nil || *existing.ClientToken != *in.ClientToken {
+			continue
+		}
+		if fmt.Sprint(tagMap(existing.Tags)) != fmt.Sprint(tagMap(in.Tags)) {
+			return nil, &smithy.GenericAPIError{Code: "IdempotentParameterMismatch", Message: "client token reused with different parameters"}
+		}
+		return &efs.CreateAccessPointOutput{AccessPointId: existing.AccessPointId}, nil
+	}
+
+	accessPoint := efstypes.AccessPointDescription{
+		AccessPointId:  strPtr(fmt.Sprintf("fsap-%d", len(f.accessPoints))),
+		ClientToken:    in.ClientToken,
+		FileSystemId:   in.FileSystemId,
+		RootDirectory:  in.RootDirectory,
+		Tags:           in.Tags,
+		LifeCycleState: efstypes.LifeCycleStateAvailable,
+	}
+	for _, tag := range in.Tags {
+		if *tag.Key == "Name" {
+			accessPoint.Name = tag.Value
+		}
+	}
+	f.accessPoints = append(f.accessPoints, accessPoint)
+	return &efs.CreateAccessPointOutput{AccessPointId: accessPoint.AccessPointId}, nil
+}
+
+// DescribeAccessPoints returns one access point per page to exercise pagination
+func (f *fakeEFS) DescribeAccessPoints(ctx context.Context, in *efs.DescribeAccessPointsInput, _ ...func(*efs.Options)) (*efs.DescribeAccessPointsOutput, error) {
+	start := 0
+	if in.NextToken != nil {
+		_, _ = fmt.Sscan(*in.NextToken, &start)
+	}
+	if start >= len(f.accessPoints) {
+		return &efs.DescribeAccessPointsOutput{}, nil
+	}
+	out := &efs.DescribeAccessPointsOutput{AccessPoints: f.accessPoints[start : start+1]}
+	if start+1 < len(f.accessPoints) {
+		out.NextToken = strPtr(fmt.Sprint(start + 1))
+	}
+	return out, nil
+}
+
+func (f *fakeEFS) DeleteAccessPoint(ctx context.Context, in *efs.DeleteAccessPointInput, _ ...func(*efs.Options)) (*efs.DeleteAccessPointOutput, error) {
+	for i, accessPoint := range f.accessPoints {
+		if *accessPoint.AccessPointId == *in.AccessPointId {
+			f.accessPoints = append(f.accessPoints[:i], f.accessPoints[i+1:]...)
+			return &efs.DeleteAccessPointOutput{}, nil
+		}
+	}
+	return nil, &efstypes.AccessPointNotFound{}
+}
+
+func (f *fakeEFS) TagResource(ctx context.Context, in *efs.TagResourceInput, _ ...func(*efs.Options)) (*efs.TagResourceOutput, error) {
+	for i := range f.accessPoints {
+		if *f.accessPoints[i].AccessPointId != *in.ResourceId {
+			continue
+		}
+		for _, tag := range in.Tags {
+			replaced := false
+			for j := range f.accessPoints[i].Tags {
+				if *f.accessPoints[i].Tags[j].Key == *tag.Key {
+					f.accessPoints[i].Tags[j] = tag
+					replaced = true
+				}
+			}
+			if !replaced {
+				f.accessPoints[i].Tags = append(f.accessPoints[i].Tags, tag)
+			}
+		}
+	}
+	return &efs.TagResourceOutput{}, nil
+}
+
+func tagMap(tags []efstypes.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		m[*tag.Key] = *tag.Value
+	}
+	return m
+}
+
+type fakeEC2 struct{}
+
+func (fakeEC2) DescribeNetworkInterfaces(ctx context.Context, in *ec2.DescribeNetworkInterfacesInput, _ ...func(*ec2.Options)) (*ec2.DescribeNetworkInterfacesOutput, error) {
+	return &ec2.DescribeNetworkInterfacesOutput{NetworkInterfaces: []ec2types.NetworkInterface{{
+		NetworkInterfaceId: &in.NetworkInterfaceIds[0],
+		Association:        &ec2types.NetworkInterfaceAssociation{PublicIp: strPtr("203.0.113.7")},
+	}}}, nil
+}
+
+func TestAWSProvider_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	fakeECS, fakeEFS := newFakeECS(), &fakeEFS{}
+	region := config.AWSRegionConfig{Name: "us-east-1", Enabled: true, Cluster: "dev8", FileSystemID: "fs-1", Subnets: []string{"subnet-a"}}
+	provider := &awsProvider{
+		config:  config.AWSConfig{AssignPublicIP: true, ExecutionRoleARN: "arn:aws:iam::123:role/exec"},
+		regions: map[string]*awsRegion{"us-east-1": {config: region, ecs: fakeECS, efs: fakeEFS, ec2: fakeEC2{}}},
+	}
+	volumes := &efsVolumes{client: fakeEFS, fileSystemID: "fs-1"}
+	family := "ecs-" + wsID
+
+	spec := ContainerDeploymentSpec{Image: "dev8-workspace:latest", CPUCores: 3, MemoryGB: 4, FileShareName: "fs-" + wsID, GitHubToken: "ghp_token"}
+	if _, err := provider.Create(ctx, wsID, "us-east-1", "", spec); err == nil {
+		t.Fatal("Create() without a volume should fail")
+	}
+	if err := volumes.CreateVolume(ctx, "fs-other", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := volumes.CreateVolume(ctx, "fs-"+wsID, 15); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := provider.Create(ctx, wsID, "us-east-1", "", spec); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	definition := fakeECS.definitions["arn:aws:ecs:us-east-1:123:task-definition/"+family+":1"]
+	if definition == nil {
+		t.Fatalf("task definition not registered: %v", fakeECS.definitions)
+	}
+	if *definition.Cpu != "4096" || *definition.Memory != "8192" {
+		t.Errorf("task size = %s/%s, want 4096/8192", *definition.Cpu, *definition.Memory)
+	}
+	if ap := definition.Volumes[0].EfsVolumeConfiguration.AuthorizationConfig.AccessPointId; *ap != "fsap-1" {
+		t.Errorf("access point = %s, want fsap-1 (fs-{id})", *ap)
+	}
+	for _, env := range definition.ContainerDefinitions[0].Environment {
+		if *env.Name == "GITHUB_TOKEN" {
+			t.Error("GITHUB_TOKEN stored in the task definition")
+		}
+	}
+	run := fakeECS.runs[0]
+	if *run.Cluster != "dev8" || run.NetworkConfiguration.AwsvpcConfiguration.AssignPublicIp != ecstypes.AssignPublicIpEnabled {
+		t.Errorf("run task input = %+v", run)
+	}
+	if env := run.Overrides.ContainerOverrides[0].Environment; len(env) != 1 || *env[0].Value != "ghp_token" {
+		t.Errorf("task overrides = %+v, want GITHUB_TOKEN", env)
+	}
+
+	if status, _ := provider.Status(ctx, wsID, "us-east-1", ""); status != ContainerPending {
+		t.Errorf("status while provisioning = %s, want %s", status, ContainerPending)
+	}
+	fakeECS.tasks[0].LastStatus = strPtr("RUNNING")
+	if status, _ := provider.Status(ctx, wsID, "us-east-1", ""); status != ContainerRunning {
+		t.Errorf("status = %s, want %s", status, ContainerRunning)
+	}
+	info, err := provider.Get(ctx, wsID, "us-east-1", "")
+	if err != nil || info.FQDN != "203.0.113.7" {
+		t.Errorf("Get() = %+v, %v, want public IP", info, err)
+	}
+
+	if err := provider.Stop(ctx, wsID, "us-east-1", ""); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if status, _ := provider.Status(ctx, wsID, "us-east-1", ""); status != ContainerStopped {
+		t.Errorf("status after stop = %s, want %s", status, ContainerStopped)
+	}
+
+	if _, err := provider.Start(ctx, wsID, "us-east-1", "", spec); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if len(fakeECS.runs) != 2 || fakeECS.revisions[family] != 2 {
+		t.Errorf("Start() ran %d tasks from %d revisions, want 2 and 2", len(fakeECS.runs), fakeECS.revisions[family])
+	}
+
+	// A task that exits on its own is a failure
+	fakeECS.stop(fakeECS.tasks[1], "Essential container in task exited")
+	if status, _ := provider.Status(ctx, wsID, "us-east-1", ""); status != ContainerFailed {
+		t.Errorf("status after crash = %s, want %s", status, ContainerFailed)
+	}
+
+	if err := provider.Delete(ctx, wsID, "us-east-1", ""); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if status, _ := provider.Status(ctx, wsID, "us-east-1", ""); status != ContainerNotFound {
+		t.Errorf("status after delete = %s, want %s", status, ContainerNotFound)
+	}
+	if _, err := provider.Get(ctx, wsID, "us-east-1", ""); !isContainerNotFound(err) {
+		t.Errorf("Get() after delete error = %v, want not found", err)
+	}
+	if err := provider.Stop(ctx, wsID, "us-east-1", ""); !isContainerNotFound(err) {
+		t.Errorf("Stop() after delete error = %v, want not found", err)
+	}
+}
+
+func TestEFSVolumes(t *testing.T) {
+	ctx := context.Background()
+	volumes := &efsVolumes{client: &fakeEFS{}, fileSystemID: "fs-1"}
+
+	if err := volumes.CreateVolume(ctx, "fs-ws-1", 15); err != nil {
+		t.Fatalf("CreateVolume() error = %v", err)
+	}
+	if err := volumes.CreateVolume(ctx, "fs-ws-2", 5); err != nil {
+		t.Fatalf("CreateVolume() error = %v", err)
+	}
+
+	// A retry after the first create went through finds its access point
+	efsClient := volumes.client.(*fakeEFS)
+	for i, tag := range efsClient.accessPoints[1].Tags {
+		if *tag.Key == "created-at" {
+			efsClient.accessPoints[1].Tags[i].Value = strPtr("2025-01-01T00:00:00Z")
+		}
+	}
+	if err := volumes.CreateVolume(ctx, "fs-ws-2", 5); err != nil {
+		t.Fatalf("retried CreateVolume() error = %v", err)
+	}
+	if len(efsClient.accessPoints) != 2 {
+		t.Errorf("access points after a retried create = %d, want 2", len(efsClient.accessPoints))
+	}
+	if exists, err := volumes.VolumeExists(ctx, "fs-ws-2"); err != nil || !exists {
+		t.Errorf("VolumeExists() = %v, %v, want true", exists, err)
+	}
+	if err := volumes.ResizeVolume(ctx, "fs-ws-1", 30); err != nil {
+		t.Fatalf("ResizeVolume() error = %v", err)
+	}
+	props, err := volumes.VolumeProperties(ctx, "fs-ws-1")
+	if err != nil || props.QuotaGB != 30 || props.LastModified.IsZero() {
+		t.Errorf("VolumeProperties() = %+v, %v, want quota 30", props, err)
+	}
+	if listed, err := volumes.ListVolumes(ctx, "fs-"); err != nil || len(listed) != 2 {
+		t.Errorf("ListVolumes() = %+v, %v, want 2 volumes", listed, err)
+	}
+	if _, err := volumes.VolumeUsage(ctx, "fs-ws-1"); !errors.Is(err, ErrVolumeUsageUnavailable) {
+		t.Errorf("VolumeUsage() error = %v, want ErrVolumeUsageUnavailable", err)
+	}
+
+	if err := volumes.DeleteVolume(ctx, "fs-ws-1"); err != nil {
+		t.Fatalf("DeleteVolume() error = %v", err)
+	}
+	if err := volumes.DeleteVolume(ctx, "fs-ws-1"); err != nil {
+		t.Errorf("DeleteVolume() of missing volume error = %v", err)
+	}
+	if _, err := volumes.VolumeProperties(ctx, "fs-ws-1"); !errors.Is(err, ErrVolumeNotFound) {
+		t.Errorf("VolumeProperties() after delete error = %v, want ErrVolumeNotFound", err)
+	}
+}
+
+func TestFargateSize(t *testing.T) {
+	tests := []struct {
+		cpu, memory         float64
+		wantCPU, wantMemory int
+	}{
+		{cpu: 1, memory: 2, wantCPU: 1024, wantMemory: 2048},
+		{cpu: 1, memory: 12, wantCPU: 2048, wantMemory: 12288}, // 1 vCPU allows at most 8 GB
+		{cpu: 2, memory: 2, wantCPU: 2048, wantMemory: 4096},   // 2 vCPU needs at least 4 GB
+		{cpu: 3, memory: 4, wantCPU: 4096, wantMemory: 8192},   // No 3 vCPU size
+		{cpu: 4, memory: 16, wantCPU: 4096, wantMemory: 16384},
+	}
+
+	for _, tt := range tests {
+		cpu, memory := fargateSize(tt.cpu, tt.memory)
+		if cpu != tt.wantCPU || memory != tt.wantMemory {
+			t.Errorf("fargateSize(%v, %v) = %d, %d, want %d, %d", tt.cpu, tt.memory, cpu, memory, tt.wantCPU, tt.wantMemory)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/docker"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// dockerStopTimeout is how long a workspace container gets to shut down before it is killed
+const dockerStopTimeout = 30 * time.Second
+
+// dockerProvider runs workspaces as containers (docker-{id}) on the local Docker Engine
+type dockerProvider struct {
+	client      *docker.Client
+	publishHost string
+}
+
+func newDockerProvider(cfg *config.Config, clients ProviderClients) (ContainerProvider, error) {
+	if clients.Docker == nil {
+		return nil, fmt.Errorf("docker deployment mode requires a Docker client")
+	}
+	return &dockerProvider{client: clients.Docker, publishHost: cfg.Docker.PublishHost}, nil
+}
+
+// Create creates and starts a container on the local Docker Engine
+func (d *dockerProvider) Create(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerName := fmt.Sprintf("docker-%s", workspaceID)
+
+	if err := d.ensureImage(ctx, spec); err != nil {
+		return nil, err
+	}
+
+	// A named volume, or a host directory with the local volume backend
+	volumeSource := spec.FileShareName
+	if spec.LocalVolumePath != "" {
+		volumeSource = spec.LocalVolumePath
+	}
+
+	// Public TCP ports are published on the host; the Docker client reports no UDP bindings
+	exposedPorts := make(map[string]struct{})
+	portBindings := make(map[string][]docker.PortBinding)
+	for _, port := range workspaceContainerPorts(spec.Ports) {
+		if port.Visibility != models.PortPublic || port.Protocol == models.PortProtocolUDP {
+			continue
+		}
+		key := fmt.Sprintf("%d/tcp", port.Port)
+		exposedPorts[key] = struct{}{}
+		// Empty HostPort: Docker picks a free port, so workspaces never collide with each other or the agent
+		portBindings[key] = []docker.PortBinding{{HostIP: "127.0.0.1"}}
+	}
+
+	containerConfig := docker.ContainerConfig{
+		Image: spec.Image,
+		Env:   dockerEnv(workspaceID, spec),
+		Labels: map[string]string{
+			"managed-by":   managedByTag,
+			"workspace-id": workspaceID,
+			"user-id":      spec.UserID,
+		},
+		ExposedPorts: exposedPorts,
+		HostConfig: docker.HostConfig{
+			// Same layout as the Azure File share: everything persistent lives under /home/dev8
+			Binds:         []string{volumeSource + ":/home/dev8"},
+			ExtraHosts:    []string{"host.docker.internal:host-gateway"},
+			PortBindings:  portBindings,
+			NanoCPUs:      int64(spec.CPUCores * 1e9),
+			Memory:        int64(spec.MemoryGB * (1 << 30)),
+			RestartPolicy: docker.RestartPolicy{Name: "on-failure"},
+		},
+	}
+
+	if _, err := d.client.CreateContainer(ctx, containerName, containerConfig); err != nil {
+		return nil, err
+	}
+	if err := d.client.StartContainer(ctx, containerName); err != nil {
+		_ = d.client.RemoveContainer(ctx, containerName, true)
+		return nil, err
+	}
+
+	return d.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// ensureImage pulls the workspace image unless it is already available locally
+func (d *dockerProvider) ensureImage(ctx context.Context, spec ContainerDeploymentSpec) error {
+	exists, err := d.client.ImageExists(ctx, spec.Image)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	log.Printf("🐳 Pulling image %s", spec.Image)
+	var auth *docker.RegistryAuth
+	if spec.RegistryUsername != "" {
+		auth = &docker.RegistryAuth{
+			Username:      spec.RegistryUsername,
+			Password:      spec.RegistryPassword,
+			ServerAddress: spec.RegistryServer,
+		}
+	}
+	return d.client.PullImage(ctx, spec.Image, auth)
+}
+
+// Get gets container details, including the host ports it is published on
+func (d *dockerProvider) Get(ctx context.Context, workspaceID, region, resourceGroup string) (*ContainerInfo, error) {
+	containerName := fmt.Sprintf("docker-%s", workspaceID)
+
+	container, err := d.inspect(ctx, containerName)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ContainerInfo{
+		Name:  containerName,
+		ID:    container.ID,
+		Ports: container.Ports,
+	}
+	// Ports are only published while the container runs
+	if container.Running && len(container.Ports) > 0 {
+		info.FQDN = d.publishHost
+	}
+	return info, nil
+}
+
+// Delete removes the container. The volume is kept.
+func (d *dockerProvider) Delete(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	containerName := fmt.Sprintf("docker-%s", workspaceID)
+	return d.client.RemoveContainer(ctx, containerName, true)
+}
+
+// Stop stops and removes the container, keeping the volume.
+// Start recreates it, so a restart picks up the new spec (password, keys, image).
+func (d *dockerProvider) Stop(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	containerName := fmt.Sprintf("docker-%s", workspaceID)
+	if err := d.client.StopContainer(ctx, containerName, dockerStopTimeout); err != nil {
+		return err
+	}
+	return d.client.RemoveContainer(ctx, containerName, false)
+}
+
+// Resize recreates a running container with the new limits. Stopped workspaces have
+// no container, so Start already creates one at the new size.
+func (d *dockerProvider) Resize(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerName := fmt.Sprintf("docker-%s", workspaceID)
+
+	status, err := d.Status(ctx, workspaceID, region, resourceGroup)
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case ContainerNotFound:
+		return &ContainerInfo{Name: containerName}, nil
+	case ContainerStopped, ContainerFailed:
+		if err := d.client.RemoveContainer(ctx, containerName, true); err != nil {
+			return nil, err
+		}
+		return &ContainerInfo{Name: containerName}, nil
+	}
+
+	if err := d.Stop(ctx, workspaceID, region, resourceGroup); err != nil {
+		return nil, fmt.Errorf("failed to stop container for resize: %w", err)
+	}
+	return d.Create(ctx, workspaceID, region, resourceGroup, spec)
+}
+
+// Start starts an existing container or creates a new one on the existing volume
+func (d *dockerProvider) Start(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	containerName := fmt.Sprintf("docker-%s", workspaceID)
+
+	if _, err := d.inspect(ctx, containerName); err != nil {
+		if !isContainerNotFound(err) {
+			return nil, err
+		}
+		log.Printf("Container %s not found, creating new one", containerName)
+		return d.Create(ctx, workspaceID, region, resourceGroup, spec)
+	}
+
+	log.Printf("Container %s exists, starting it", containerName)
+	if err := d.client.StartContainer(ctx, containerName); err != nil {
+		return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+	return d.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// Status maps the container state
+func (d *dockerProvider) Status(ctx context.Context, workspaceID, region, resourceGroup string) (ContainerStatus, error) {
+	container, err := d.inspect(ctx, fmt.Sprintf("docker-%s", workspaceID))
+	if err != nil {
+		if isContainerNotFound(err) {
+			return ContainerNotFound, nil
+		}
+		return ContainerUnknown, err
+	}
+
+	switch container.Status {
+	case "running":
+		return ContainerRunning, nil
+	case "created", "restarting":
+		return ContainerPending, nil
+	case "exited", "paused":
+		return ContainerStopped, nil
+	case "dead":
+		return ContainerFailed, nil
+	}
+	return ContainerUnknown, nil
+}
+
+func (d *dockerProvider) inspect(ctx context.Context, containerName string) (*docker.Container, error) {
+	container, err := d.client.InspectContainer(ctx, containerName)
+	if err != nil {
+		if docker.IsNotFound(err) {
+			return nil, containerNotFound(containerName)
+		}
+		return nil, err
+	}
+	return container, nil
+}
+
+// dockerEnv builds the container environment, matching what ACI passes to the workspace
+func dockerEnv(workspaceID string, spec ContainerDeploymentSpec) []string {
+	env := []string{
+		"WORKSPACE_ID=" + workspaceID,
+		"USER_ID=" + spec.UserID,
+		"WORKSPACE_DIR=/home/dev8/workspace",
+		"AGENT_BASE_URL=" + dockerAgentURL(spec.AgentBaseURL),
+		"AGENT_ENABLED=true",
+		"MONITOR_INTERVAL=30s",
+		"LOG_FILE_PATH=/var/log/supervisor.log",
+	}
+
+	optional := []struct{ name, value string }{
+		{"GITHUB_TOKEN", spec.GitHubToken},
+		{"CODE_SERVER_PASSWORD", spec.CodeServerPassword},
+		{"SSH_PUBLIC_KEY", spec.SSHPublicKey},
+		{"GIT_USER_NAME", spec.GitUserName},
+		{"GIT_USER_EMAIL", spec.GitUserEmail},
+		{"ANTHROPIC_API_KEY", spec.AnthropicAPIKey},
+		{"OPENAI_API_KEY", spec.OpenAIAPIKey},
+		{"GEMINI_API_KEY", spec.GeminiAPIKey},
+	}
+	for _, v := range optional {
+		if v.value != "" {
+			env = append(env, v.name+"="+v.value)
+		}
+	}
+	for _, v := range spec.EnvVars {
+		env = append(env, v.Name+"="+v.Value)
+	}
+
+	return env
+}
+
+// dockerAgentURL rewrites a loopback agent URL so the workspace container can reach
+// the agent on the Docker host.
+func dockerAgentURL(agentURL string) string {
+	u, err := url.Parse(agentURL)
+	if err != nil {
+		return agentURL
+	}
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "0.0.0.0":
+		if port := u.Port(); port != "" {
+			u.Host = "host.docker.internal:" + port
+		} else {
+			u.Host = "host.docker.internal"
+		}
+		return u.String()
+	}
+	return agentURL
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+func TestConnectionURLsFor(t *testing.T) {
+	tests := []struct {
+		name          string
+		info          *ContainerInfo
+		wantWeb       string
+		wantSSH       string
+		wantSupervise string
+		ports         []models.PortSpec
+		wantPorts     map[int]string
+	}{
+		{
+			name: "no container",
+		},
+		{
+			name:          "azure FQDN",
+			info:          &ContainerInfo{FQDN: "ws-1.eastus.azurecontainer.io"},
+			wantWeb:       "https://ws-1.eastus.azurecontainer.io:8080",
+			wantSSH:       "ssh://user@ws-1.eastus.azurecontainer.io:2222",
+			wantSupervise: "http://ws-1.eastus.azurecontainer.io:9000",
+		},
+		{
+			name:          "docker published ports",
+			info:          &ContainerInfo{FQDN: "localhost", Ports: map[int]int{8080: 49153, 2222: 49154, 9000: 49155}},
+			wantWeb:       "http://localhost:49153",
+			wantSSH:       "ssh://user@localhost:49154",
+			wantSupervise: "http://localhost:49155",
+		},
+		{
+			name: "container app ingress",
+			info: &ContainerInfo{
+				FQDN:  "ws-1.eastus.azurecontainerapps.io",
+				Ports: map[int]int{8080: 443, 2222: 2222, 3000: 3000},
+				TLS:   true,
+			},
+			ports: []models.PortSpec{
+				{Port: 3000, Protocol: models.PortProtocolTCP},
+				{Port: 5432, Protocol: models.PortProtocolTCP, Visibility: models.PortPrivate},
+			},
+			wantWeb:   "https://ws-1.eastus.azurecontainerapps.io",
+			wantSSH:   "ssh://user@ws-1.eastus.azurecontainerapps.io:2222",
+			wantPorts: map[int]string{3000: "tcp://ws-1.eastus.azurecontainerapps.io:3000"},
+		},
+		{
+			name: "docker container not running",
+			info: &ContainerInfo{Ports: map[int]int{8080: 49153}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls := connectionURLsFor(tt.info, "secret", tt.ports)
+			if urls.VSCodeWebURL != tt.wantWeb {
+				t.Errorf("VSCodeWebURL = %q, want %q", urls.VSCodeWebURL, tt.wantWeb)
+			}
+			if urls.SSHURL != tt.wantSSH {
+				t.Errorf("SSHURL = %q, want %q", urls.SSHURL, tt.wantSSH)
+			}
+			if urls.SupervisorURL != tt.wantSupervise {
+				t.Errorf("SupervisorURL = %q, want %q", urls.SupervisorURL, tt.wantSupervise)
+			}
+			if len(urls.Ports) != len(tt.wantPorts) {
+				t.Errorf("Ports = %v, want %v", urls.Ports, tt.wantPorts)
+			}
+			for port, want := range tt.wantPorts {
+				if urls.Ports[port] != want {
+					t.Errorf("Ports[%d] = %q, want %q", port, urls.Ports[port], want)
+				}
+			}
+			if tt.wantSSH != "" && !strings.Contains(urls.VSCodeDesktopURL, strings.TrimPrefix(tt.wantSSH, "ssh://")) {
+				t.Errorf("VSCodeDesktopURL = %q, want host and port of %q", urls.VSCodeDesktopURL, tt.wantSSH)
+			}
+		})
+	}
+}
+
+func TestDockerAgentURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"http://localhost:8080", "http://host.docker.internal:8080"},
+		{"http://127.0.0.1:8080/api", "http://host.docker.internal:8080/api"},
+		{"http://localhost", "http://host.docker.internal"},
+		{"https://agent.dev8.dev", "https://agent.dev8.dev"},
+	}
+
+	for _, tt := range tests {
+		if got := dockerAgentURL(tt.in); got != tt.want {
+			t.Errorf("dockerAgentURL(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDockerEnv(t *testing.T) {
+	env := dockerEnv("ws-1", ContainerDeploymentSpec{
+		UserID:             "user-1",
+		AgentBaseURL:       "http://localhost:8080",
+		CodeServerPassword: "secret",
+	})
+
+	want := []string{
+		"WORKSPACE_ID=ws-1",
+		"USER_ID=user-1",
+		"AGENT_BASE_URL=http://host.docker.internal:8080",
+		"CODE_SERVER_PASSWORD=secret",
+	}
+	joined := strings.Join(env, "\n")
+	for _, v := range want {
+		if !strings.Contains(joined, v) {
+			t.Errorf("dockerEnv() missing %q in %v", v, env)
+		}
+	}
+	if strings.Contains(joined, "GITHUB_TOKEN=") {
+		t.Error("dockerEnv() should omit empty optional variables")
+	}
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Provider operations, used to inject failures into FakeProvider
+const (
+	FakeOpCreate = "create"
+	FakeOpStart  = "start"
+	FakeOpStop   = "stop"
+	FakeOpDelete = "delete"
+	FakeOpResize = "resize"
+)
+
+// FakeProvider is an in-memory ContainerProvider for tests and local UI work.
+// Containers are reachable at fake-{id}.local and never run anything.
+type FakeProvider struct {
+	mu         sync.Mutex
+	containers map[string]*fakeContainer
+	failures   map[string]error
+}
+
+type fakeContainer struct {
+	status ContainerStatus
+	spec   ContainerDeploymentSpec
+}
+
+// NewFakeProvider creates an empty fake provider
+func NewFakeProvider() *FakeProvider {
+	return &FakeProvider{
+		containers: make(map[string]*fakeContainer),
+		failures:   make(map[string]error),
+	}
+}
+
+// FailOn makes every subsequent call of op return err; a nil err clears the failure
+func (f *FakeProvider) FailOn(op string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err == nil {
+		delete(f.failures, op)
+		return
+	}
+	f.failures[op] = err
+}
+
+// FailOnIn makes every subsequent call of op in region return err; a nil err clears the failure
+func (f *FakeProvider) FailOnIn(op, region string, err error) {
+	f.FailOn(op+"@"+region, err)
+}
+
+// failure returns the injected failure of op in region
+func (f *FakeProvider) failure(op, region string) error {
+	if err := f.failures[op]; err != nil {
+		return err
+	}
+	return f.failures[op+"@"+region]
+}
+
+// Spec returns the spec a workspace container was last created or started with
+func (f *FakeProvider) Spec(workspaceID string) (ContainerDeploymentSpec, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	container, ok := f.containers[workspaceID]
+	if !ok {
+		return ContainerDeploymentSpec{}, false
+	}
+	return container.spec, true
+}
+
+// Create creates a running container
+func (f *FakeProvider) Create(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failure(FakeOpCreate, region); err != nil {
+		return nil, err
+	}
+	if _, ok := f.containers[workspaceID]; ok {
+		return nil, fmt.Errorf("container fake-%s already exists", workspaceID)
+	}
+
+	f.containers[workspaceID] = &fakeContainer{status: ContainerRunning, spec: spec}
+	return fakeContainerInfo(workspaceID), nil
+}
+
+// Get returns the container details
+func (f *FakeProvider) Get(ctx context.Context, workspaceID, region, resourceGroup string) (*ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.containers[workspaceID]; !ok {
+		return nil, containerNotFound("fake-" + workspaceID)
+	}
+	return fakeContainerInfo(workspaceID), nil
+}
+
+// Start starts a stopped container or creates a new one
+func (f *FakeProvider) Start(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failure(FakeOpStart, region); err != nil {
+		return nil, err
+	}
+
+	f.containers[workspaceID] = &fakeContainer{status: ContainerRunning, spec: spec}
+	return fakeContainerInfo(workspaceID), nil
+}
+
+// Stop marks the container stopped
+func (f *FakeProvider) Stop(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failure(FakeOpStop, region); err != nil {
+		return err
+	}
+
+	container, ok := f.containers[workspaceID]
+	if !ok {
+		return containerNotFound("fake-" + workspaceID)
+	}
+	container.status = ContainerStopped
+	return nil
+}
+
+// Resize records the new resources, keeping the container state
+func (f *FakeProvider) Resize(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failure(FakeOpResize, region); err != nil {
+		return nil, err
+	}
+
+	container, ok := f.containers[workspaceID]
+	if !ok {
+		return nil, containerNotFound("fake-" + workspaceID)
+	}
+	container.spec = spec
+	return fakeContainerInfo(workspaceID), nil
+}
+
+// Delete removes the container
+func (f *FakeProvider) Delete(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.failure(FakeOpDelete, region); err != nil {
+		return err
+	}
+
+	delete(f.containers, workspaceID)
+	return nil
+}
+
+// Status returns the container state
+func (f *FakeProvider) Status(ctx context.Context, workspaceID, region, resourceGroup string) (ContainerStatus, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	container, ok := f.containers[workspaceID]
+	if !ok {
+		return ContainerNotFound, nil
+	}
+	return container.status, nil
+}
+
+func fakeContainerInfo(workspaceID string) *ContainerInfo {
+	return &ContainerInfo{
+		Name: "fake-" + workspaceID,
+		FQDN: fmt.Sprintf("fake-%s.local", workspaceID),
+		ID:   "fake-" + workspaceID,
+	}
+}
@@ -0,0 +1,438 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+)
+
+// kubernetesProvider runs each workspace as a single-replica StatefulSet (k8s-{id})
+// with a Service and a Secret of the same name. The fs-{id} PVC holds /home/dev8.
+type kubernetesProvider struct {
+	client      kubernetes.Interface
+	namespace   string
+	serviceType corev1.ServiceType
+}
+
+// credentialsRotatedAnnotation on the pod template rolls the pod when credentials change
+const credentialsRotatedAnnotation = "dev8.dev/credentials-rotated-at"
+
+func newKubernetesProvider(cfg *config.Config, clients ProviderClients) (ContainerProvider, error) {
+	if clients.Kubernetes == nil {
+		return nil, fmt.Errorf("kubernetes deployment mode requires a Kubernetes client")
+	}
+	return &kubernetesProvider{
+		client:      clients.Kubernetes,
+		namespace:   cfg.Kubernetes.Namespace,
+		serviceType: corev1.ServiceType(cfg.Kubernetes.ServiceType),
+	}, nil
+}
+
+// Create creates the workspace Secret, Service and StatefulSet
+func (k *kubernetesProvider) Create(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	if err := k.applySecrets(ctx, workspaceID, spec); err != nil {
+		return nil, err
+	}
+
+	if _, err := k.client.CoreV1().Services(k.namespace).Create(ctx, k.service(workspaceID), metav1.CreateOptions{}); err != nil && !apierrors.IsAlreadyExists(err) {
+		return nil, fmt.Errorf("failed to create service %s: %w", name, err)
+	}
+
+	if _, err := k.client.AppsV1().StatefulSets(k.namespace).Create(ctx, k.statefulSet(workspaceID, spec), metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to create statefulset %s: %w", name, err)
+	}
+
+	return k.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// Get returns the StatefulSet details and the address of its Service
+func (k *kubernetesProvider) Get(ctx context.Context, workspaceID, region, resourceGroup string) (*ContainerInfo, error) {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	set, err := k.getStatefulSet(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	info := &ContainerInfo{Name: name, ID: string(set.UID)}
+
+	service, err := k.client.CoreV1().Services(k.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return info, nil
+		}
+		return nil, fmt.Errorf("failed to get service %s: %w", name, err)
+	}
+	info.FQDN = k.serviceAddress(service)
+	return info, nil
+}
+
+// serviceAddress returns the load balancer address, or the in-cluster DNS name for
+// ClusterIP services. A load balancer has no address until the cloud assigns one.
+func (k *kubernetesProvider) serviceAddress(service *corev1.Service) string {
+	if service.Spec.Type != corev1.ServiceTypeLoadBalancer {
+		return fmt.Sprintf("%s.%s.svc.cluster.local", service.Name, service.Namespace)
+	}
+	for _, ingress := range service.Status.LoadBalancer.Ingress {
+		if ingress.Hostname != "" {
+			return ingress.Hostname
+		}
+		if ingress.IP != "" {
+			return ingress.IP
+		}
+	}
+	return ""
+}
+
+// Start scales the StatefulSet back to one replica with the new spec, or creates it
+func (k *kubernetesProvider) Start(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	set, err := k.getStatefulSet(ctx, workspaceID)
+	if err != nil {
+		if !isContainerNotFound(err) {
+			return nil, err
+		}
+		log.Printf("StatefulSet %s not found, creating new one", name)
+		return k.Create(ctx, workspaceID, region, resourceGroup, spec)
+	}
+
+	log.Printf("StatefulSet %s exists, scaling it up", name)
+	if err := k.applySecrets(ctx, workspaceID, spec); err != nil {
+		return nil, err
+	}
+
+	// The pod is recreated on scale-up, so the new template (image, resources, keys) applies
+	desired := k.statefulSet(workspaceID, spec)
+	set.Spec.Template = desired.Spec.Template
+	set.Spec.Replicas = desired.Spec.Replicas
+	if _, err := k.client.AppsV1().StatefulSets(k.namespace).Update(ctx, set, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to start statefulset %s: %w", name, err)
+	}
+
+	return k.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// Resize updates the pod resources. A running pod is rolled; a stopped StatefulSet
+// keeps zero replicas and starts at the new size.
+func (k *kubernetesProvider) Resize(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	set, err := k.getStatefulSet(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := k.statefulSet(workspaceID, spec).Spec.Template.Spec.Containers[0].Resources
+	for i := range set.Spec.Template.Spec.Containers {
+		if set.Spec.Template.Spec.Containers[i].Name == "workspace" {
+			set.Spec.Template.Spec.Containers[i].Resources = desired
+		}
+	}
+	if _, err := k.client.AppsV1().StatefulSets(k.namespace).Update(ctx, set, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to resize statefulset %s: %w", name, err)
+	}
+	return k.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// UpdateCredentials replaces the workspace Secret and rolls the pod, which reads it
+// only at startup. A stopped StatefulSet picks the Secret up when it scales up.
+func (k *kubernetesProvider) UpdateCredentials(ctx context.Context, workspaceID, region, resourceGroup string, spec ContainerDeploymentSpec) (*ContainerInfo, error) {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	set, err := k.getStatefulSet(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := k.applySecrets(ctx, workspaceID, spec); err != nil {
+		return nil, err
+	}
+
+	if set.Spec.Template.Annotations == nil {
+		set.Spec.Template.Annotations = make(map[string]string)
+	}
+	set.Spec.Template.Annotations[credentialsRotatedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if _, err := k.client.AppsV1().StatefulSets(k.namespace).Update(ctx, set, metav1.UpdateOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to roll statefulset %s: %w", name, err)
+	}
+	return k.Get(ctx, workspaceID, region, resourceGroup)
+}
+
+// Stop scales the StatefulSet to zero, keeping the PVC, Service and Secret
+func (k *kubernetesProvider) Stop(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	set, err := k.getStatefulSet(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	set.Spec.Replicas = int32Ptr(0)
+	if _, err := k.client.AppsV1().StatefulSets(k.namespace).Update(ctx, set, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to stop statefulset %s: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes the StatefulSet, Service and Secrets. The PVC is deleted with the volume.
+func (k *kubernetesProvider) Delete(ctx context.Context, workspaceID, region, resourceGroup string) error {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	deletes := []struct {
+		kind string
+		name string
+		fn   func(context.Context, string, metav1.DeleteOptions) error
+	}{
+		{"statefulset", name, k.client.AppsV1().StatefulSets(k.namespace).Delete},
+		{"service", name, k.client.CoreV1().Services(k.namespace).Delete},
+		{"secret", name, k.client.CoreV1().Secrets(k.namespace).Delete},
+		{"secret", name + "-registry", k.client.CoreV1().Secrets(k.namespace).Delete},
+	}
+	for _, d := range deletes {
+		if err := d.fn(ctx, d.name, metav1.DeleteOptions{}); err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete %s %s: %w", d.kind, d.name, err)
+		}
+	}
+	return nil
+}
+
+// Status maps the StatefulSet replicas, reporting pods stuck on image or config errors as failed
+func (k *kubernetesProvider) Status(ctx context.Context, workspaceID, region, resourceGroup string) (ContainerStatus, error) {
+	set, err := k.getStatefulSet(ctx, workspaceID)
+	if err != nil {
+		if isContainerNotFound(err) {
+			return ContainerNotFound, nil
+		}
+		return ContainerUnknown, err
+	}
+
+	if set.Spec.Replicas != nil && *set.Spec.Replicas == 0 {
+		return ContainerStopped, nil
+	}
+	if set.Status.ReadyReplicas > 0 {
+		return ContainerRunning, nil
+	}
+
+	pods, err := k.client.CoreV1().Pods(k.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: metav1.FormatLabelSelector(set.Spec.Selector),
+	})
+	if err != nil {
+		return ContainerUnknown, fmt.Errorf("failed to list pods of %s: %w", set.Name, err)
+	}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Status.ContainerStatuses {
+			if container.State.Waiting == nil {
+				continue
+			}
+			switch container.State.Waiting.Reason {
+			case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CrashLoopBackOff", "CreateContainerConfigError":
+				return ContainerFailed, nil
+			}
+		}
+	}
+	return ContainerPending, nil
+}
+
+func (k *kubernetesProvider) getStatefulSet(ctx context.Context, workspaceID string) (*appsv1.StatefulSet, error) {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+	set, err := k.client.AppsV1().StatefulSets(k.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, containerNotFound(name)
+		}
+		return nil, fmt.Errorf("failed to get statefulset %s: %w", name, err)
+	}
+	return set, nil
+}
+
+// kubernetesLabels identify a workspace's objects. The user ID is an annotation
+// because it is not guaranteed to be a valid label value.
+func kubernetesLabels(workspaceID string) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name": "dev8-workspace",
+		"managed-by":             managedByTag,
+		"workspace-id":           workspaceID,
+	}
+}
+
+func (k *kubernetesProvider) objectMeta(name, workspaceID string, spec ContainerDeploymentSpec) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        name,
+		Namespace:   k.namespace,
+		Labels:      kubernetesLabels(workspaceID),
+		Annotations: map[string]string{"dev8.dev/user-id": spec.UserID},
+	}
+}
+
+// applySecrets creates or replaces the workspace Secret holding the keys and tokens
+// from spec, plus an image pull Secret when registry credentials are set
+func (k *kubernetesProvider) applySecrets(ctx context.Context, workspaceID string, spec ContainerDeploymentSpec) error {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	data := make(map[string][]byte)
+	for _, v := range []struct{ name, value string }{
+		{"GITHUB_TOKEN", spec.GitHubToken},
+		{"CODE_SERVER_PASSWORD", spec.CodeServerPassword},
+		{"SSH_PUBLIC_KEY", spec.SSHPublicKey},
+		{"ANTHROPIC_API_KEY", spec.AnthropicAPIKey},
+		{"OPENAI_API_KEY", spec.OpenAIAPIKey},
+		{"GEMINI_API_KEY", spec.GeminiAPIKey},
+	} {
+		if v.value != "" {
+			data[v.name] = []byte(v.value)
+		}
+	}
+	for _, v := range spec.EnvVars {
+		if v.Secret {
+			data[v.Name] = []byte(v.Value)
+		}
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: k.objectMeta(name, workspaceID, spec),
+		Type:       corev1.SecretTypeOpaque,
+		Data:       data,
+	}
+	if err := k.applySecret(ctx, secret); err != nil {
+		return err
+	}
+
+	if spec.RegistryUsername == "" {
+		return nil
+	}
+	dockerConfig, err := json.Marshal(map[string]interface{}{
+		"auths": map[string]interface{}{
+			spec.RegistryServer: map[string]string{
+				"username": spec.RegistryUsername,
+				"password": spec.RegistryPassword,
+				"auth":     base64.StdEncoding.EncodeToString([]byte(spec.RegistryUsername + ":" + spec.RegistryPassword)),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return k.applySecret(ctx, &corev1.Secret{
+		ObjectMeta: k.objectMeta(name+"-registry", workspaceID, spec),
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data:       map[string][]byte{corev1.DockerConfigJsonKey: dockerConfig},
+	})
+}
+
+func (k *kubernetesProvider) applySecret(ctx context.Context, secret *corev1.Secret) error {
+	secrets := k.client.CoreV1().Secrets(k.namespace)
+	_, err := secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply secret %s: %w", secret.Name, err)
+	}
+	return nil
+}
+
+func (k *kubernetesProvider) service(workspaceID string) *corev1.Service {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+
+	ports := []corev1.ServicePort{
+		{Name: "code-server", Port: portCodeServer, TargetPort: intstr.FromInt32(portCodeServer)},
+		{Name: "ssh", Port: portSSH, TargetPort: intstr.FromInt32(portSSH)},
+		{Name: "supervisor", Port: portSupervisor, TargetPort: intstr.FromInt32(portSupervisor)},
+	}
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: k.namespace, Labels: kubernetesLabels(workspaceID)},
+		Spec: corev1.ServiceSpec{
+			Type:     k.serviceType,
+			Selector: map[string]string{"workspace-id": workspaceID, "managed-by": managedByTag},
+			Ports:    ports,
+		},
+	}
+}
+
+func (k *kubernetesProvider) statefulSet(workspaceID string, spec ContainerDeploymentSpec) *appsv1.StatefulSet {
+	name := fmt.Sprintf("k8s-%s", workspaceID)
+	labels := kubernetesLabels(workspaceID)
+
+	resources := corev1.ResourceList{
+		corev1.ResourceCPU:    *resource.NewMilliQuantity(int64(spec.CPUCores*1000), resource.DecimalSI),
+		corev1.ResourceMemory: *resource.NewQuantity(int64(spec.MemoryGB*(1<<30)), resource.BinarySI),
+	}
+
+	env := []corev1.EnvVar{
+		{Name: "WORKSPACE_ID", Value: workspaceID},
+		{Name: "USER_ID", Value: spec.UserID},
+		{Name: "WORKSPACE_DIR", Value: "/home/dev8/workspace"},
+		{Name: "AGENT_BASE_URL", Value: spec.AgentBaseURL},
+		{Name: "AGENT_ENABLED", Value: "true"},
+		{Name: "MONITOR_INTERVAL", Value: "30s"},
+		{Name: "LOG_FILE_PATH", Value: "/var/log/supervisor.log"},
+	}
+	if spec.GitUserName != "" {
+		env = append(env, corev1.EnvVar{Name: "GIT_USER_NAME", Value: spec.GitUserName})
+	}
+	if spec.GitUserEmail != "" {
+		env = append(env, corev1.EnvVar{Name: "GIT_USER_EMAIL", Value: spec.GitUserEmail})
+	}
+	// Secret user variables come from the workspace Secret with the other keys
+	for _, v := range spec.EnvVars {
+		if !v.Secret {
+			env = append(env, corev1.EnvVar{Name: v.Name, Value: v.Value})
+		}
+	}
+
+	var pullSecrets []corev1.LocalObjectReference
+	if spec.RegistryUsername != "" {
+		pullSecrets = []corev1.LocalObjectReference{{Name: name + "-registry"}}
+	}
+
+	return &appsv1.StatefulSet{
+		ObjectMeta: k.objectMeta(name, workspaceID, spec),
+		Spec: appsv1.StatefulSetSpec{
+			Replicas:    int32Ptr(1),
+			ServiceName: name,
+			Selector:    &metav1.LabelSelector{MatchLabels: map[string]string{"workspace-id": workspaceID, "managed-by": managedByTag}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					ImagePullSecrets: pullSecrets,
+					Containers: []corev1.Container{{
+						Name:  "workspace",
+						Image: spec.Image,
+						Ports: []corev1.ContainerPort{
+							{Name: "code-server", ContainerPort: portCodeServer},
+							{Name: "ssh", ContainerPort: portSSH},
+							{Name: "supervisor", ContainerPort: portSupervisor},
+						},
+						Env: env,
+						EnvFrom: []corev1.EnvFromSource{{
+							SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: name}},
+						}},
+						Resources:    corev1.ResourceRequirements{Requests: resources, Limits: resources},
+						VolumeMounts: []corev1.VolumeMount{{Name: "home", MountPath: "/home/dev8"}},
+					}},
+					Volumes: []corev1.Volume{{
+						Name: "home",
+						VolumeSource: corev1.VolumeSource{
+							PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: spec.FileShareName},
+						},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func int32Ptr(v int32) *int32 {
+	return &v
+}
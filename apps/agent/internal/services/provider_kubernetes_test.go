@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestKubernetesProvider_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	provider := &kubernetesProvider{client: client, namespace: "dev8", serviceType: corev1.ServiceTypeClusterIP}
+	name := "k8s-" + wsID
+
+	spec := ContainerDeploymentSpec{
+		Image:            "dev8-workspace:latest",
+		CPUCores:         2,
+		MemoryGB:         4,
+		FileShareName:    "fs-" + wsID,
+		UserID:           "user@example.com",
+		GitHubToken:      "ghp_token",
+		RegistryServer:   "registry.example.com",
+		RegistryUsername: "puller",
+		RegistryPassword: "secret",
+	}
+	info, err := provider.Create(ctx, wsID, "eastus", "", spec)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if info.FQDN != name+".dev8.svc.cluster.local" {
+		t.Errorf("FQDN = %q, want cluster DNS name", info.FQDN)
+	}
+
+	set, err := client.AppsV1().StatefulSets("dev8").Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("statefulset not created: %v", err)
+	}
+	pod := set.Spec.Template.Spec
+	if claim := pod.Volumes[0].PersistentVolumeClaim; claim == nil || claim.ClaimName != "fs-"+wsID {
+		t.Errorf("volume = %+v, want PVC fs-{id}", pod.Volumes[0])
+	}
+	if cpu := pod.Containers[0].Resources.Limits[corev1.ResourceCPU]; cpu.MilliValue() != 2000 {
+		t.Errorf("cpu limit = %s, want 2", cpu.String())
+	}
+	if len(pod.ImagePullSecrets) != 1 || pod.ImagePullSecrets[0].Name != name+"-registry" {
+		t.Errorf("image pull secrets = %+v", pod.ImagePullSecrets)
+	}
+	secret, err := client.CoreV1().Secrets("dev8").Get(ctx, name, metav1.GetOptions{})
+	if err != nil || string(secret.Data["GITHUB_TOKEN"]) != "ghp_token" {
+		t.Errorf("workspace secret = %+v, %v", secret, err)
+	}
+	if _, ok := secret.Data["OPENAI_API_KEY"]; ok {
+		t.Error("empty keys should not be stored")
+	}
+
+	// No ready replica yet
+	if status, _ := provider.Status(ctx, wsID, "eastus", ""); status != ContainerPending {
+		t.Errorf("status before ready = %s, want %s", status, ContainerPending)
+	}
+	set.Status.ReadyReplicas = 1
+	if _, err := client.AppsV1().StatefulSets("dev8").UpdateStatus(ctx, set, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := provider.Status(ctx, wsID, "eastus", ""); status != ContainerRunning {
+		t.Errorf("status when ready = %s, want %s", status, ContainerRunning)
+	}
+
+	if err := provider.Stop(ctx, wsID, "eastus", ""); err != nil {
+		t.Fatalf("Stop() error = %v", err)
+	}
+	if status, _ := provider.Status(ctx, wsID, "eastus", ""); status != ContainerStopped {
+		t.Errorf("status after stop = %s, want %s", status, ContainerStopped)
+	}
+
+	spec.GitHubToken = "ghp_rotated"
+	if _, err := provider.Start(ctx, wsID, "eastus", "", spec); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	set, _ = client.AppsV1().StatefulSets("dev8").Get(ctx, name, metav1.GetOptions{})
+	if set.Spec.Replicas == nil || *set.Spec.Replicas != 1 {
+		t.Errorf("replicas after start = %v, want 1", set.Spec.Replicas)
+	}
+	secret, _ = client.CoreV1().Secrets("dev8").Get(ctx, name, metav1.GetOptions{})
+	if string(secret.Data["GITHUB_TOKEN"]) != "ghp_rotated" {
+		t.Errorf("GITHUB_TOKEN after start = %q, want the new token", secret.Data["GITHUB_TOKEN"])
+	}
+
+	spec.CodeServerPassword = "new-password"
+	if _, err := provider.UpdateCredentials(ctx, wsID, "eastus", "", spec); err != nil {
+		t.Fatalf("UpdateCredentials() error = %v", err)
+	}
+	secret, _ = client.CoreV1().Secrets("dev8").Get(ctx, name, metav1.GetOptions{})
+	if string(secret.Data["CODE_SERVER_PASSWORD"]) != "new-password" {
+		t.Errorf("CODE_SERVER_PASSWORD after update = %q, want the new password", secret.Data["CODE_SERVER_PASSWORD"])
+	}
+	set, _ = client.AppsV1().StatefulSets("dev8").Get(ctx, name, metav1.GetOptions{})
+	if set.Spec.Template.Annotations[credentialsRotatedAnnotation] == "" {
+		t.Error("pod template not annotated, so the pod is not rolled")
+	}
+
+	if err := provider.Delete(ctx, wsID, "eastus", ""); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if status, _ := provider.Status(ctx, wsID, "eastus", ""); status != ContainerNotFound {
+		t.Errorf("status after delete = %s, want %s", status, ContainerNotFound)
+	}
+	for _, secretName := range []string{name, name + "-registry"} {
+		if _, err := client.CoreV1().Secrets("dev8").Get(ctx, secretName, metav1.GetOptions{}); err == nil {
+			t.Errorf("secret %s survived delete", secretName)
+		}
+	}
+	if _, err := provider.Get(ctx, wsID, "eastus", ""); !isContainerNotFound(err) {
+		t.Errorf("Get() after delete error = %v, want not found", err)
+	}
+}
+
+func TestKubernetesProvider_FailedPod(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	provider := &kubernetesProvider{client: client, namespace: "dev8", serviceType: corev1.ServiceTypeLoadBalancer}
+
+	info, err := provider.Create(ctx, wsID, "eastus", "", ContainerDeploymentSpec{Image: "missing:latest", FileShareName: "fs-" + wsID})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if info.FQDN != "" {
+		t.Errorf("FQDN = %q, want empty until the load balancer is assigned", info.FQDN)
+	}
+
+	_, err = client.CoreV1().Pods("dev8").Create(ctx, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "k8s-" + wsID + "-0", Labels: kubernetesLabels(wsID)},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}},
+		}}},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := provider.Status(ctx, wsID, "eastus", ""); status != ContainerFailed {
+		t.Errorf("status = %s, want %s", status, ContainerFailed)
+	}
+}
+
+func TestKubernetesVolumes(t *testing.T) {
+	ctx := context.Background()
+	volumes := &kubernetesVolumes{client: fake.NewClientset(), namespace: "dev8", storageClass: "premium"}
+
+	if err := volumes.CreateVolume(ctx, "fs-ws-1", 15); err != nil {
+		t.Fatalf("CreateVolume() error = %v", err)
+	}
+	if exists, err := volumes.VolumeExists(ctx, "fs-ws-1"); err != nil || !exists {
+		t.Errorf("VolumeExists() = %v, %v, want true", exists, err)
+	}
+	if err := volumes.ResizeVolume(ctx, "fs-ws-1", 30); err != nil {
+		t.Fatalf("ResizeVolume() error = %v", err)
+	}
+	props, err := volumes.VolumeProperties(ctx, "fs-ws-1")
+	if err != nil || props.QuotaGB != 30 {
+		t.Errorf("VolumeProperties() = %+v, %v, want quota 30", props, err)
+	}
+	if listed, err := volumes.ListVolumes(ctx, "fs-"); err != nil || len(listed) != 1 {
+		t.Errorf("ListVolumes() = %+v, %v, want 1 volume", listed, err)
+	}
+
+	// Stopped workspaces have no pod mounting the PVC
+	if _, err := volumes.VolumeUsage(ctx, "fs-ws-1"); !errors.Is(err, ErrVolumeUsageUnavailable) {
+		t.Errorf("VolumeUsage() error = %v, want ErrVolumeUsageUnavailable", err)
+	}
+
+	if err := volumes.DeleteVolume(ctx, "fs-ws-1"); err != nil {
+		t.Fatalf("DeleteVolume() error = %v", err)
+	}
+	if err := volumes.DeleteVolume(ctx, "fs-ws-1"); err != nil {
+		t.Errorf("DeleteVolume() of missing volume error = %v", err)
+	}
+	if _, err := volumes.VolumeProperties(ctx, "fs-ws-1"); !errors.Is(err, ErrVolumeNotFound) {
+		t.Errorf("VolumeProperties() after delete error = %v, want ErrVolumeNotFound", err)
+	}
+}
+
+func TestPVCUsedBytes(t *testing.T) {
+	summary := []byte(`{"pods": [{"volume": [
+		{"name": "kube-api-access", "usedBytes": 12},
+		{"name": "home", "usedBytes": 4096, "pvcRef": {"name": "fs-ws-1", "namespace": "dev8"}},
+		{"name": "home", "pvcRef": {"name": "fs-ws-2", "namespace": "dev8"}}
+	]}]}`)
+
+	tests := []struct {
+		claim       string
+		want        int64
+		unavailable bool
+	}{
+		{claim: "fs-ws-1", want: 4096},
+		{claim: "fs-ws-2", unavailable: true},
+		{claim: "fs-ws-3", unavailable: true},
+	}
+
+	for _, tt := range tests {
+		got, err := pvcUsedBytes(summary, "dev8", tt.claim)
+		if tt.unavailable {
+			if !errors.Is(err, ErrVolumeUsageUnavailable) {
+				t.Errorf("pvcUsedBytes(%q) error = %v, want ErrVolumeUsageUnavailable", tt.claim, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("pvcUsedBytes(%q) = %d, %v, want %d", tt.claim, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,380 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+)
+
+// wsID is a workspace ID in the UUID form requests require
+const wsID = "550e8400-e29b-41d4-a716-446655440000"
+
+// newTestEnvironmentService returns a service backed by the fake provider and local volumes in region "eastus"
+func newTestEnvironmentService(t *testing.T, store EnvironmentStore) (*EnvironmentService, *FakeProvider, *localVolumes) {
+	t.Helper()
+	provider := NewFakeProvider()
+	volumes, err := newLocalVolumes(t.TempDir())
+	if err != nil {
+		t.Fatalf("newLocalVolumes() error = %v", err)
+	}
+	service := &EnvironmentService{
+		config: &config.Config{
+			ContainerImage: "dev8-workspace:latest",
+			AgentBaseURL:   "http://localhost:8080",
+			Azure: config.AzureConfig{
+				DeploymentMode: "aci",
+				Regions:        []config.RegionConfig{{Name: "eastus", Location: "eastus", Enabled: true, ResourceGroupName: "rg"}},
+			},
+		},
+		volumes:    map[string]VolumeStore{"eastus": volumes},
+		containers: provider,
+		operations: NewOperationManager(time.Minute, time.Hour),
+		store:      store,
+		events:     noopPublisher{},
+		locks:      NewLocalLocker(),
+		schedules:  NewMemoryScheduleStore(),
+	}
+	service.usage = NewUsageMeter(service.config)
+	return service, provider, volumes
+}
+
+func TestNewContainerProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		wantErr bool
+	}{
+		{name: "aci without Azure client", mode: "aci", wantErr: true},
+		{name: "empty mode defaults to aci", mode: "", wantErr: true},
+		{name: "docker without Docker client", mode: "docker", wantErr: true},
+		{name: "unknown mode", mode: "podman", wantErr: true},
+		{name: "registered mode", mode: "test-fake"},
+	}
+
+	RegisterProvider("test-fake", func(cfg *config.Config, clients ProviderClients) (ContainerProvider, error) {
+		return NewFakeProvider(), nil
+	})
+	defer delete(providerFactories, "test-fake")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Azure: config.AzureConfig{DeploymentMode: tt.mode}}
+			provider, err := NewContainerProvider(cfg, ProviderClients{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewContainerProvider(%q) error = %v, wantErr %v", tt.mode, err, tt.wantErr)
+			}
+			if !tt.wantErr && provider == nil {
+				t.Error("NewContainerProvider() returned nil provider")
+			}
+		})
+	}
+}
+
+func TestEnvironmentService_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, volumes := newTestEnvironmentService(t, store)
+	publisher := &recordingPublisher{}
+	service.SetEventPublisher(publisher)
+
+	req := &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+	}
+	env, err := service.CreateEnvironment(ctx, req)
+	if err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	if env.Status != models.StatusRunning || env.AzureFQDN != "fake-"+wsID+".local" {
+		t.Errorf("created env = %s at %q, want RUNNING at fake-{id}.local", env.Status, env.AzureFQDN)
+	}
+	if env.ConnectionURLs.SSHURL == "" {
+		t.Error("created env has no connection URLs")
+	}
+	if props, err := volumes.VolumeProperties(ctx, "fs-"+wsID); err != nil || props.QuotaGB != 15 {
+		t.Errorf("volume properties = %+v, %v, want quota 15", props, err)
+	}
+	if spec, ok := provider.Spec(wsID); !ok || spec.FileShareName != "fs-"+wsID || spec.LocalVolumePath != volumes.Path("fs-"+wsID) || spec.Image != "dev8-workspace:latest" {
+		t.Errorf("deployed spec = %+v", spec)
+	}
+
+	// A running workspace is only deleted with force
+	if err := service.DeleteEnvironment(ctx, wsID, "eastus", false); err == nil {
+		t.Fatal("DeleteEnvironment() of running workspace without force should fail")
+	}
+
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	if status, _ := provider.Status(ctx, wsID, "eastus", "rg"); status != ContainerStopped {
+		t.Errorf("container status after stop = %s, want %s", status, ContainerStopped)
+	}
+	if stored, _ := store.Get(ctx, wsID); stored.Status != models.StatusStopped {
+		t.Errorf("stored status after stop = %s, want STOPPED", stored.Status)
+	}
+
+	started, err := service.StartEnvironment(ctx, &models.StartEnvironmentRequest{
+		WorkspaceID:        wsID,
+		UserID:             "user-1",
+		Name:               "test-env",
+		CloudRegion:        "eastus",
+		CPUCores:           2,
+		MemoryGB:           4,
+		StorageGB:          10,
+		CodeServerPassword: "secret",
+	})
+	if err != nil {
+		t.Fatalf("StartEnvironment() error = %v", err)
+	}
+	if started.ConnectionURLs.CodeServerPassword != "secret" {
+		t.Errorf("password = %q, want secret", started.ConnectionURLs.CodeServerPassword)
+	}
+
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	// A stopped workspace is deleted without force, container included
+	if err := service.DeleteEnvironment(ctx, wsID, "eastus", false); err != nil {
+		t.Fatalf("DeleteEnvironment() error = %v", err)
+	}
+	if status, _ := provider.Status(ctx, wsID, "eastus", "rg"); status != ContainerNotFound {
+		t.Errorf("container status after delete = %s, want %s", status, ContainerNotFound)
+	}
+	if exists, _ := volumes.VolumeExists(ctx, "fs-"+wsID); exists {
+		t.Error("volume survived delete")
+	}
+	if _, err := store.Get(ctx, wsID); err == nil {
+		t.Error("environment record survived delete")
+	}
+
+	want := []string{webhook.EventCreated, webhook.EventStopped, webhook.EventStarted, webhook.EventStopped, webhook.EventDeleted}
+	if len(publisher.events) != len(want) {
+		t.Fatalf("events = %v, want %v", publisher.events, want)
+	}
+	for i := range want {
+		if publisher.events[i] != want[i] {
+			t.Errorf("event[%d] = %s, want %s", i, publisher.events[i], want[i])
+		}
+	}
+}
+
+func TestEnvironmentService_EnvVarsAndPorts(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, _ := newTestEnvironmentService(t, store)
+
+	req := &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+		EnvVars:     []models.EnvVar{{Name: "NODE_ENV", Value: "development"}, {Name: "API_TOKEN", Value: "s3cret", Secret: true}},
+		Ports:       []models.PortSpec{{Port: 3000, Protocol: models.PortProtocolHTTP, Visibility: models.PortPublic}},
+	}
+	if _, err := service.CreateEnvironment(ctx, req); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+
+	spec, _ := provider.Spec(wsID)
+	if len(spec.EnvVars) != 2 || spec.EnvVars[1].Value != "s3cret" || len(spec.Ports) != 1 || spec.Ports[0].Port != 3000 {
+		t.Errorf("deployed spec env vars = %+v, ports = %+v", spec.EnvVars, spec.Ports)
+	}
+	stored, _ := store.Get(ctx, wsID)
+	if len(stored.EnvVars) != 2 || stored.EnvVars[1].Value != "" || len(stored.Ports) != 1 {
+		t.Errorf("stored env vars = %+v, ports = %+v, want the secret value stripped", stored.EnvVars, stored.Ports)
+	}
+
+	// Without a secret store, a restart deploys only the plain variables
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	if _, err := service.StartEnvironment(ctx, &models.StartEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+	}); err != nil {
+		t.Fatalf("StartEnvironment() error = %v", err)
+	}
+	spec, _ = provider.Spec(wsID)
+	if len(spec.EnvVars) != 1 || spec.EnvVars[0].Name != "NODE_ENV" || len(spec.Ports) != 1 {
+		t.Errorf("restarted spec env vars = %+v, ports = %+v", spec.EnvVars, spec.Ports)
+	}
+}
+
+func TestEnvironmentService_CreateFailure(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, volumes := newTestEnvironmentService(t, store)
+	publisher := &recordingPublisher{}
+	service.SetEventPublisher(publisher)
+	provider.FailOn(FakeOpCreate, errors.New("quota exceeded"))
+
+	_, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+	})
+	if err == nil {
+		t.Fatal("CreateEnvironment() should fail when the provider fails")
+	}
+
+	// The file share is cleaned up and the record explains the failure
+	if exists, _ := volumes.VolumeExists(ctx, "fs-"+wsID); exists {
+		t.Error("volume not cleaned up after container failure")
+	}
+	env, getErr := store.Get(ctx, wsID)
+	if getErr != nil {
+		t.Fatalf("Get() error = %v", getErr)
+	}
+	if env.Status != models.StatusError || env.StatusMessage == "" {
+		t.Errorf("stored env = %s (%q), want ERROR with message", env.Status, env.StatusMessage)
+	}
+	if len(publisher.events) != 1 || publisher.events[0] != webhook.EventFailed {
+		t.Errorf("events = %v, want [%s]", publisher.events, webhook.EventFailed)
+	}
+}
+
+func TestEnvironmentService_DuplicateCreate(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, volumes := newTestEnvironmentService(t, store)
+	req := func() *models.CreateEnvironmentRequest {
+		return &models.CreateEnvironmentRequest{
+			WorkspaceID: wsID,
+			UserID:      "user-1",
+			Name:        "test-env",
+			CloudRegion: "eastus",
+			CPUCores:    2,
+			MemoryGB:    4,
+			StorageGB:   10,
+		}
+	}
+
+	created, err := service.CreateEnvironment(ctx, req())
+	if err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+
+	// A retry returns the workspace without provisioning anything
+	provider.FailOn(FakeOpCreate, errors.New("must not be called"))
+	again, err := service.CreateEnvironment(ctx, req())
+	if err != nil || again.ID != created.ID || again.AzureFQDN != created.AzureFQDN {
+		t.Errorf("repeated CreateEnvironment() = %+v, %v; want the existing workspace", again, err)
+	}
+	op, err := service.CreateEnvironmentAsync(ctx, req())
+	if err != nil || op.Phase != models.OperationSucceeded || op.Result.(*models.Environment).ID != wsID {
+		t.Errorf("repeated CreateEnvironmentAsync() = %+v, %v; want a succeeded operation", op, err)
+	}
+
+	var appErr *models.AppError
+	different := req()
+	different.CPUCores = 4
+	if _, err := service.CreateEnvironment(ctx, different); !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+		t.Errorf("CreateEnvironment() with other settings error = %v, want CONFLICT", err)
+	}
+
+	// A leftover volume without a record is a conflict, not a provisioning failure
+	if err := store.Delete(ctx, wsID); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := volumes.VolumeExists(ctx, "fs-"+wsID); !exists {
+		t.Fatal("volume missing after create")
+	}
+	if _, err := service.CreateEnvironmentAsync(ctx, req()); !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+		t.Errorf("CreateEnvironmentAsync() over a leftover volume error = %v, want CONFLICT", err)
+	}
+}
+
+func TestEnvironmentService_StopMissingContainer(t *testing.T) {
+	service, _, _ := newTestEnvironmentService(t, NewMemoryEnvironmentStore())
+
+	err := service.StopEnvironment(context.Background(), wsID, "eastus")
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+		t.Errorf("StopEnvironment() error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestEnvironmentService_AWSPlacement(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, azureProvider, _ := newTestEnvironmentService(t, store)
+
+	awsProvider := NewFakeProvider()
+	awsVolumes, err := newLocalVolumes(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	service.config.AWS.Regions = []config.AWSRegionConfig{{Name: "us-east-1", Enabled: true}}
+	service.awsContainers = awsProvider
+	service.awsVolumes = map[string]VolumeStore{"us-east-1": awsVolumes}
+
+	req := func(provider models.CloudProvider, region string) *models.CreateEnvironmentRequest {
+		return &models.CreateEnvironmentRequest{
+			WorkspaceID:   wsID,
+			Name:          "test-env",
+			CloudProvider: provider,
+			CloudRegion:   region,
+			CPUCores:      2,
+			MemoryGB:      4,
+			StorageGB:     10,
+		}
+	}
+
+	// Regions belong to one provider
+	for _, r := range []*models.CreateEnvironmentRequest{req(models.ProviderAWS, "eastus"), req(models.ProviderAzure, "us-east-1"), req(models.ProviderGCP, "us-east1")} {
+		if _, err := service.CreateEnvironmentAsync(ctx, r); err == nil {
+			t.Errorf("CreateEnvironmentAsync(%s, %s) should fail", r.CloudProvider, r.CloudRegion)
+		}
+	}
+
+	env, err := service.CreateEnvironment(ctx, req(models.ProviderAWS, "us-east-1"))
+	if err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	if env.CloudProvider != models.ProviderAWS || env.AzureContainerGroup != "ecs-"+wsID {
+		t.Errorf("env = %s %s, want AWS ecs-{id}", env.CloudProvider, env.AzureContainerGroup)
+	}
+	if exists, _ := awsVolumes.VolumeExists(ctx, "fs-"+wsID); !exists {
+		t.Error("volume not created in the AWS volume store")
+	}
+	if _, ok := awsProvider.Spec(wsID); !ok {
+		t.Error("container not created by the AWS provider")
+	}
+	if _, ok := azureProvider.Spec(wsID); ok {
+		t.Error("container created by the Azure provider")
+	}
+
+	// Later operations only carry the region and follow the stored record
+	if err := service.StopEnvironment(ctx, wsID, "us-east-1"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	if status, _ := awsProvider.Status(ctx, wsID, "us-east-1", ""); status != ContainerStopped {
+		t.Errorf("AWS container status after stop = %s, want %s", status, ContainerStopped)
+	}
+	if err := service.DeleteEnvironment(ctx, wsID, "us-east-1", false); err != nil {
+		t.Fatalf("DeleteEnvironment() error = %v", err)
+	}
+	if exists, _ := awsVolumes.VolumeExists(ctx, "fs-"+wsID); exists {
+		t.Error("volume survived delete")
+	}
+}
@@ -0,0 +1,455 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/docker"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// managedByTag marks resources created by the agent
+const managedByTag = "dev8-agent"
+
+// resourceNamePrefixes maps each resource kind to the prefix of its {prefix}{workspaceID} name
+var resourceNamePrefixes = map[models.ResourceKind]string{
+	models.ResourceContainerGroup:     "aci-",
+	models.ResourceContainerApp:       "aca-",
+	models.ResourceDockerContainer:    "docker-",
+	models.ResourceKubernetesWorkload: "k8s-",
+	models.ResourceEnvironmentStorage: "fs-",
+	models.ResourceFileShare:          "fs-",
+}
+
+// deleteOrder removes compute before the storage it mounts
+var deleteOrder = map[models.ResourceKind]int{
+	models.ResourceContainerGroup:     0,
+	models.ResourceContainerApp:       0,
+	models.ResourceDockerContainer:    0,
+	models.ResourceKubernetesWorkload: 0,
+	models.ResourceEnvironmentStorage: 1,
+	models.ResourceFileShare:          2,
+}
+
+// managedResource is a provisioned resource discovered during a sweep
+type managedResource struct {
+	kind          models.ResourceKind
+	name          string
+	workspaceID   string
+	region        string
+	resourceGroup string
+	createdAt     time.Time // Zero when the provider does not report it
+}
+
+// resourceInventory lists and deletes the resources the agent provisions
+type resourceInventory interface {
+	// list returns every managed resource plus errors for anything that could not be listed.
+	// sharesListed is false if file shares could not be listed in some region.
+	list(ctx context.Context) (resources []managedResource, sharesListed bool, errs []string)
+	delete(ctx context.Context, res managedResource) error
+}
+
+// Reconciler finds Azure resources left behind by failed or partially
+// completed lifecycle operations and reports or garbage-collects them.
+type Reconciler struct {
+	cfg       config.ReconcilerConfig
+	store     EnvironmentStore
+	inventory resourceInventory
+
+	mu sync.Mutex // Serializes sweeps
+}
+
+// NewReconciler creates a reconciler for the resources managed by service
+func NewReconciler(service *EnvironmentService, cfg config.ReconcilerConfig) *Reconciler {
+	return &Reconciler{
+		cfg:   cfg,
+		store: service.store,
+		inventory: &azureInventory{
+			config:       service.config,
+			azureClient:  service.azureClient,
+			dockerClient: service.dockerClient,
+			kubeClient:   service.kubeClient,
+			containers:   service.containers,
+			volumes:      service.volumes,
+		},
+	}
+}
+
+// DryRunByDefault reports whether sweeps run in dry-run mode unless told otherwise
+func (r *Reconciler) DryRunByDefault() bool {
+	return r.cfg.DryRun
+}
+
+// Scan lists orphaned resources without deleting anything, regardless of policy
+func (r *Reconciler) Scan(ctx context.Context) *models.ReconcileReport {
+	return r.sweep(ctx, "report", false)
+}
+
+// Reconcile applies the configured policy to orphaned resources.
+// With dryRun set, resources the policy would delete are only reported.
+func (r *Reconciler) Reconcile(ctx context.Context, dryRun bool) *models.ReconcileReport {
+	return r.sweep(ctx, r.cfg.Policy, dryRun)
+}
+
+// Run reconciles every interval until ctx is cancelled
+func (r *Reconciler) Run(ctx context.Context) {
+	log.Printf("🧹 Reconciler started (policy=%s, dryRun=%t, interval=%s)", r.cfg.Policy, r.cfg.DryRun, r.cfg.Interval)
+
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("🧹 Reconciler stopped")
+			return
+		case <-ticker.C:
+			r.Reconcile(ctx, r.cfg.DryRun)
+		}
+	}
+}
+
+func (r *Reconciler) sweep(ctx context.Context, policy string, dryRun bool) *models.ReconcileReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	report := &models.ReconcileReport{
+		Policy:    policy,
+		DryRun:    dryRun,
+		Orphans:   []models.OrphanedResource{},
+		StartedAt: time.Now().UTC(),
+	}
+
+	resources, sharesListed, errs := r.inventory.list(ctx)
+	report.Scanned = len(resources)
+	report.Errors = append(report.Errors, errs...)
+
+	records := make(map[string]*models.Environment)
+	for _, res := range resources {
+		if res.workspaceID == "" {
+			continue
+		}
+		if _, seen := records[res.workspaceID]; seen {
+			continue
+		}
+		env, err := r.store.Get(ctx, res.workspaceID)
+		var appErr *models.AppError
+		switch {
+		case err == nil:
+			records[res.workspaceID] = env
+		case errors.As(err, &appErr) && appErr.Code == "NOT_FOUND":
+			records[res.workspaceID] = nil
+		default:
+			// Leave the workspace out of records so its resources are skipped
+			report.Errors = append(report.Errors, fmt.Sprintf("workspace %s: failed to load record: %v", res.workspaceID, err))
+		}
+	}
+
+	orphans := classifyResources(resources, records, sharesListed, r.cfg.GracePeriod, time.Now())
+	sort.SliceStable(orphans, func(i, j int) bool {
+		return deleteOrder[orphans[i].kind] < deleteOrder[orphans[j].kind]
+	})
+
+	for _, orphan := range orphans {
+		entry := models.OrphanedResource{
+			Kind:          orphan.kind,
+			Name:          orphan.name,
+			WorkspaceID:   orphan.workspaceID,
+			Region:        orphan.region,
+			ResourceGroup: orphan.resourceGroup,
+			Reason:        orphan.reason,
+			Action:        models.ActionReported,
+		}
+
+		if policy == "delete" {
+			if dryRun {
+				entry.Action = models.ActionWouldDelete
+			} else if err := r.inventory.delete(ctx, orphan.managedResource); err != nil {
+				entry.Action = models.ActionDeleteFailed
+				entry.Error = err.Error()
+				log.Printf("❌ Reconciler: failed to delete %s %s: %v", orphan.kind, orphan.name, err)
+			} else {
+				entry.Action = models.ActionDeleted
+				log.Printf("🗑️  Reconciler: deleted %s %s (%s)", orphan.kind, orphan.name, orphan.reason)
+			}
+		}
+
+		report.Orphans = append(report.Orphans, entry)
+	}
+
+	report.CompletedAt = time.Now().UTC()
+	log.Printf("🧹 Reconciler sweep: scanned=%d orphans=%d errors=%d (policy=%s, dryRun=%t)",
+		report.Scanned, len(report.Orphans), len(report.Errors), policy, dryRun)
+
+	return report
+}
+
+// orphanCandidate is a managed resource together with why it is considered orphaned
+type orphanCandidate struct {
+	managedResource
+	reason string
+}
+
+// classifyResources picks out resources with no matching workspace or whose
+// workspace is only half-provisioned. records maps workspace IDs to their
+// stored record, or nil when none exists; workspaces missing from records
+// could not be looked up and are skipped.
+func classifyResources(resources []managedResource, records map[string]*models.Environment, sharesListed bool, grace time.Duration, now time.Time) []orphanCandidate {
+	withShare := make(map[string]bool)
+	for _, res := range resources {
+		if res.kind == models.ResourceFileShare {
+			withShare[res.workspaceID] = true
+		}
+	}
+
+	var orphans []orphanCandidate
+	for _, res := range resources {
+		if res.workspaceID == "" {
+			continue
+		}
+		if !res.createdAt.IsZero() && now.Sub(res.createdAt) < grace {
+			continue
+		}
+		env, known := records[res.workspaceID]
+		if !known {
+			continue
+		}
+		if reason := orphanReason(res, env, withShare[res.workspaceID] || !sharesListed, grace, now); reason != "" {
+			orphans = append(orphans, orphanCandidate{managedResource: res, reason: reason})
+		}
+	}
+
+	return orphans
+}
+
+func orphanReason(res managedResource, env *models.Environment, hasShare bool, grace time.Duration, now time.Time) string {
+	if env == nil {
+		return "no matching workspace"
+	}
+
+	// Give in-flight operations time to finish
+	if now.Sub(env.UpdatedAt) < grace {
+		return ""
+	}
+
+	switch {
+	case env.Status == models.StatusDeleting:
+		return "workspace deletion did not complete"
+	case env.AzureFQDN == "" && (env.Status == models.StatusCreating || env.Status == models.StatusError):
+		// The workspace never became reachable, so its volume holds no user data
+		return fmt.Sprintf("workspace never finished provisioning (status %s)", env.Status)
+	case res.kind != models.ResourceFileShare && !hasShare:
+		return "workspace has no file share"
+	}
+
+	return ""
+}
+
+// workspaceIDFromName extracts the workspace ID from a resource name following
+// the agent's naming scheme, or returns "" if name does not follow it.
+func workspaceIDFromName(kind models.ResourceKind, name string) string {
+	prefix := resourceNamePrefixes[kind]
+	if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+		return ""
+	}
+	return strings.TrimPrefix(name, prefix)
+}
+
+// isManaged reports whether a resource was created by the agent, either by tag or by name
+func isManaged(kind models.ResourceKind, name string, tags map[string]*string) bool {
+	if tag, ok := tags["managed-by"]; ok && tag != nil && *tag == managedByTag {
+		return true
+	}
+	return workspaceIDFromName(kind, name) != ""
+}
+
+// azureInventory lists managed resources across all enabled Azure regions
+// (or on the local Docker Engine or Kubernetes cluster in those modes)
+type azureInventory struct {
+	config       *config.Config
+	azureClient  *azure.Client
+	dockerClient *docker.Client
+	kubeClient   kubernetes.Interface
+	containers   ContainerProvider
+	volumes      map[string]VolumeStore
+}
+
+func (a *azureInventory) list(ctx context.Context) ([]managedResource, bool, []string) {
+	var (
+		resources    []managedResource
+		errs         []string
+		sharesListed = true
+		seen         = make(map[string]bool) // Regions may share resource groups and storage accounts
+	)
+
+	add := func(res managedResource, scope string) {
+		key := string(res.kind) + "/" + scope + "/" + res.name
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		resources = append(resources, res)
+	}
+
+	for _, region := range a.config.GetEnabledRegions() {
+		resourceGroup := region.ResourceGroupName
+		if resourceGroup == "" {
+			resourceGroup = a.config.Azure.ResourceGroupName
+		}
+
+		switch a.config.Azure.DeploymentMode {
+		case "kubernetes":
+			sets, err := a.kubeClient.AppsV1().StatefulSets(a.config.Kubernetes.Namespace).List(ctx, metav1.ListOptions{
+				LabelSelector: "managed-by=" + managedByTag,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("region %s: %v", region.Name, err))
+				break
+			}
+			for _, set := range sets.Items {
+				if workspaceIDFromName(models.ResourceKubernetesWorkload, set.Name) == "" {
+					continue
+				}
+				add(managedResource{
+					kind:        models.ResourceKubernetesWorkload,
+					name:        set.Name,
+					workspaceID: workspaceIDFromName(models.ResourceKubernetesWorkload, set.Name),
+					region:      region.Name,
+					createdAt:   set.CreationTimestamp.Time,
+				}, "")
+			}
+		case "docker":
+			containers, err := a.dockerClient.ListContainers(ctx, "managed-by="+managedByTag)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("region %s: %v", region.Name, err))
+			}
+			for _, container := range containers {
+				if workspaceIDFromName(models.ResourceDockerContainer, container.Name) == "" {
+					continue
+				}
+				add(managedResource{
+					kind:        models.ResourceDockerContainer,
+					name:        container.Name,
+					workspaceID: workspaceIDFromName(models.ResourceDockerContainer, container.Name),
+					region:      region.Name,
+					createdAt:   container.Created,
+				}, "")
+			}
+		case "aca":
+			apps, err := a.azureClient.ListContainerApps(ctx, resourceGroup)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("region %s: %v", region.Name, err))
+			}
+			for _, app := range apps {
+				if app == nil || app.Name == nil || !isManaged(models.ResourceContainerApp, *app.Name, app.Tags) {
+					continue
+				}
+				res := managedResource{
+					kind:          models.ResourceContainerApp,
+					name:          *app.Name,
+					workspaceID:   workspaceIDFromName(models.ResourceContainerApp, *app.Name),
+					region:        region.Name,
+					resourceGroup: resourceGroup,
+				}
+				if app.SystemData != nil && app.SystemData.CreatedAt != nil {
+					res.createdAt = *app.SystemData.CreatedAt
+				}
+				add(res, resourceGroup)
+			}
+
+			storages, err := a.azureClient.ListEnvironmentStorages(ctx, resourceGroup, a.config.Azure.ContainerAppsEnvironmentID)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("region %s: %v", region.Name, err))
+			}
+			for _, name := range storages {
+				if !isManaged(models.ResourceEnvironmentStorage, name, nil) {
+					continue
+				}
+				add(managedResource{
+					kind:          models.ResourceEnvironmentStorage,
+					name:          name,
+					workspaceID:   workspaceIDFromName(models.ResourceEnvironmentStorage, name),
+					region:        region.Name,
+					resourceGroup: resourceGroup,
+				}, a.config.Azure.ContainerAppsEnvironmentID)
+			}
+		default:
+			groups, err := a.azureClient.ListContainerGroups(ctx, region.Name, resourceGroup)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("region %s: %v", region.Name, err))
+			}
+			for _, group := range groups {
+				if group == nil || group.Name == nil || !isManaged(models.ResourceContainerGroup, *group.Name, group.Tags) {
+					continue
+				}
+				add(managedResource{
+					kind:          models.ResourceContainerGroup,
+					name:          *group.Name,
+					workspaceID:   workspaceIDFromName(models.ResourceContainerGroup, *group.Name),
+					region:        region.Name,
+					resourceGroup: resourceGroup,
+				}, resourceGroup)
+			}
+		}
+
+		volumes, ok := a.volumes[region.Name]
+		if !ok {
+			continue
+		}
+		shares, err := volumes.ListVolumes(ctx, resourceNamePrefixes[models.ResourceFileShare])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("region %s: %v", region.Name, err))
+			sharesListed = false
+			continue
+		}
+		shareScope := region.StorageAccount
+		if a.config.VolumeBackend() != "azure" {
+			shareScope = "" // One local store backs every region
+		}
+		for _, share := range shares {
+			// Share properties only change on create or resize, so LastModified approximates creation
+			add(managedResource{
+				kind:        models.ResourceFileShare,
+				name:        share.Name,
+				workspaceID: workspaceIDFromName(models.ResourceFileShare, share.Name),
+				region:      region.Name,
+				createdAt:   share.LastModified,
+			}, shareScope)
+		}
+	}
+
+	return resources, sharesListed, errs
+}
+
+func (a *azureInventory) delete(ctx context.Context, res managedResource) error {
+	switch res.kind {
+	case models.ResourceContainerGroup:
+		return a.azureClient.DeleteContainerGroup(ctx, res.region, res.resourceGroup, res.name)
+	case models.ResourceContainerApp:
+		return a.azureClient.DeleteContainerApp(ctx, res.resourceGroup, res.name)
+	case models.ResourceDockerContainer:
+		return a.dockerClient.RemoveContainer(ctx, res.name, true)
+	case models.ResourceKubernetesWorkload:
+		// The provider removes the Service and Secrets along with the StatefulSet
+		return a.containers.Delete(ctx, res.workspaceID, res.region, res.resourceGroup)
+	case models.ResourceEnvironmentStorage:
+		return a.azureClient.UnregisterStorageFromEnvironment(ctx, res.resourceGroup, a.config.Azure.ContainerAppsEnvironmentID, res.name)
+	case models.ResourceFileShare:
+		volumes, ok := a.volumes[res.region]
+		if !ok {
+			return fmt.Errorf("volume store not found for region %s", res.region)
+		}
+		return volumes.DeleteVolume(ctx, res.name)
+	}
+	return fmt.Errorf("unknown resource kind %s", res.kind)
+}
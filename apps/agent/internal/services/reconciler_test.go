@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// fakeInventory is an in-memory resourceInventory
+type fakeInventory struct {
+	resources    []managedResource
+	sharesListed bool
+	deleted      []string
+	failDelete   map[string]bool
+}
+
+func (f *fakeInventory) list(ctx context.Context) ([]managedResource, bool, []string) {
+	return f.resources, f.sharesListed, nil
+}
+
+func (f *fakeInventory) delete(ctx context.Context, res managedResource) error {
+	if f.failDelete[res.name] {
+		return errors.New("delete failed")
+	}
+	f.deleted = append(f.deleted, res.name)
+	return nil
+}
+
+func TestWorkspaceIDFromName(t *testing.T) {
+	tests := []struct {
+		kind models.ResourceKind
+		name string
+		want string
+	}{
+		{models.ResourceContainerGroup, "aci-ws-1", "ws-1"},
+		{models.ResourceContainerApp, "aca-ws-1", "ws-1"},
+		{models.ResourceKubernetesWorkload, "k8s-ws-1", "ws-1"},
+		{models.ResourceFileShare, "fs-ws-1", "ws-1"},
+		{models.ResourceEnvironmentStorage, "fs-ws-1", "ws-1"},
+		{models.ResourceContainerGroup, "aca-ws-1", ""},
+		{models.ResourceFileShare, "fs-", ""},
+		{models.ResourceFileShare, "backups", ""},
+	}
+
+	for _, tt := range tests {
+		if got := workspaceIDFromName(tt.kind, tt.name); got != tt.want {
+			t.Errorf("workspaceIDFromName(%s, %q) = %q, want %q", tt.kind, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestClassifyResources(t *testing.T) {
+	now := time.Now()
+	grace := time.Hour
+	old := now.Add(-2 * time.Hour)
+
+	container := func(id string) managedResource {
+		return managedResource{kind: models.ResourceContainerGroup, name: "aci-" + id, workspaceID: id}
+	}
+	share := func(id string) managedResource {
+		return managedResource{kind: models.ResourceFileShare, name: "fs-" + id, workspaceID: id, createdAt: old}
+	}
+
+	tests := []struct {
+		name         string
+		resources    []managedResource
+		records      map[string]*models.Environment
+		sharesListed bool
+		want         []string
+	}{
+		{
+			name:         "healthy workspace",
+			resources:    []managedResource{container("ws-1"), share("ws-1")},
+			records:      map[string]*models.Environment{"ws-1": {ID: "ws-1", Status: models.StatusRunning, AzureFQDN: "ws-1.example", UpdatedAt: old}},
+			sharesListed: true,
+		},
+		{
+			name:         "no matching workspace",
+			resources:    []managedResource{container("ws-1"), share("ws-1")},
+			records:      map[string]*models.Environment{"ws-1": nil},
+			sharesListed: true,
+			want:         []string{"aci-ws-1", "fs-ws-1"},
+		},
+		{
+			name:         "record lookup failed",
+			resources:    []managedResource{container("ws-1")},
+			records:      map[string]*models.Environment{},
+			sharesListed: true,
+		},
+		{
+			name:         "failed provisioning past grace period",
+			resources:    []managedResource{share("ws-1")},
+			records:      map[string]*models.Environment{"ws-1": {ID: "ws-1", Status: models.StatusError, UpdatedAt: old}},
+			sharesListed: true,
+			want:         []string{"fs-ws-1"},
+		},
+		{
+			name:         "failed provisioning within grace period",
+			resources:    []managedResource{share("ws-1")},
+			records:      map[string]*models.Environment{"ws-1": {ID: "ws-1", Status: models.StatusError, UpdatedAt: now}},
+			sharesListed: true,
+		},
+		{
+			name:         "failed start keeps data",
+			resources:    []managedResource{container("ws-1"), share("ws-1")},
+			records:      map[string]*models.Environment{"ws-1": {ID: "ws-1", Status: models.StatusError, AzureFQDN: "ws-1.example", UpdatedAt: old}},
+			sharesListed: true,
+		},
+		{
+			name:         "container without file share",
+			resources:    []managedResource{container("ws-1")},
+			records:      map[string]*models.Environment{"ws-1": {ID: "ws-1", Status: models.StatusRunning, AzureFQDN: "ws-1.example", UpdatedAt: old}},
+			sharesListed: true,
+			want:         []string{"aci-ws-1"},
+		},
+		{
+			name:         "file shares not listed",
+			resources:    []managedResource{container("ws-1")},
+			records:      map[string]*models.Environment{"ws-1": {ID: "ws-1", Status: models.StatusRunning, AzureFQDN: "ws-1.example", UpdatedAt: old}},
+			sharesListed: false,
+		},
+		{
+			name:         "recently created resource",
+			resources:    []managedResource{{kind: models.ResourceContainerApp, name: "aca-ws-1", workspaceID: "ws-1", createdAt: now}},
+			records:      map[string]*models.Environment{"ws-1": nil},
+			sharesListed: true,
+		},
+		{
+			name:         "stuck deletion",
+			resources:    []managedResource{share("ws-1")},
+			records:      map[string]*models.Environment{"ws-1": {ID: "ws-1", Status: models.StatusDeleting, AzureFQDN: "ws-1.example", UpdatedAt: old}},
+			sharesListed: true,
+			want:         []string{"fs-ws-1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyResources(tt.resources, tt.records, tt.sharesListed, grace, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("classifyResources() = %d orphans, want %d (%+v)", len(got), len(tt.want), got)
+			}
+			for i, orphan := range got {
+				if orphan.name != tt.want[i] {
+					t.Errorf("orphan[%d] = %s, want %s", i, orphan.name, tt.want[i])
+				}
+				if orphan.reason == "" {
+					t.Errorf("orphan[%d] has no reason", i)
+				}
+			}
+		})
+	}
+}
+
+func TestReconciler_Policy(t *testing.T) {
+	resources := []managedResource{
+		{kind: models.ResourceFileShare, name: "fs-gone", workspaceID: "gone"},
+		{kind: models.ResourceContainerGroup, name: "aci-gone", workspaceID: "gone"},
+		{kind: models.ResourceFileShare, name: "fs-live", workspaceID: "live"},
+	}
+
+	tests := []struct {
+		name        string
+		policy      string
+		dryRun      bool
+		failDelete  map[string]bool
+		wantAction  models.ReconcileAction
+		wantDeleted []string
+	}{
+		{name: "report policy", policy: "report", wantAction: models.ActionReported},
+		{name: "delete policy dry run", policy: "delete", dryRun: true, wantAction: models.ActionWouldDelete},
+		{name: "delete policy", policy: "delete", wantAction: models.ActionDeleted, wantDeleted: []string{"aci-gone", "fs-gone"}},
+		{name: "delete failure", policy: "delete", failDelete: map[string]bool{"aci-gone": true, "fs-gone": true}, wantAction: models.ActionDeleteFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryEnvironmentStore()
+			live := &models.Environment{ID: "live", Status: models.StatusStopped, AzureFQDN: "live.example", UpdatedAt: time.Now().Add(-2 * time.Hour)}
+			if err := store.Put(context.Background(), live); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			inventory := &fakeInventory{resources: resources, sharesListed: true, failDelete: tt.failDelete}
+			reconciler := &Reconciler{
+				cfg:       config.ReconcilerConfig{Policy: tt.policy, GracePeriod: time.Hour},
+				store:     store,
+				inventory: inventory,
+			}
+
+			report := reconciler.Reconcile(context.Background(), tt.dryRun)
+
+			if report.Scanned != len(resources) {
+				t.Errorf("Scanned = %d, want %d", report.Scanned, len(resources))
+			}
+			if len(report.Orphans) != 2 {
+				t.Fatalf("Orphans = %+v, want 2", report.Orphans)
+			}
+			// Compute is removed before the storage it mounts
+			if report.Orphans[0].Kind != models.ResourceContainerGroup {
+				t.Errorf("first orphan kind = %s, want %s", report.Orphans[0].Kind, models.ResourceContainerGroup)
+			}
+			for _, orphan := range report.Orphans {
+				if orphan.Action != tt.wantAction {
+					t.Errorf("%s action = %s, want %s", orphan.Name, orphan.Action, tt.wantAction)
+				}
+			}
+			if len(inventory.deleted) != len(tt.wantDeleted) {
+				t.Fatalf("deleted = %v, want %v", inventory.deleted, tt.wantDeleted)
+			}
+			for i, name := range tt.wantDeleted {
+				if inventory.deleted[i] != name {
+					t.Errorf("deleted[%d] = %s, want %s", i, inventory.deleted[i], name)
+				}
+			}
+		})
+	}
+}
+
+func TestReconciler_Kubernetes(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewClientset()
+	provider := &kubernetesProvider{client: client, namespace: "dev8", serviceType: corev1.ServiceTypeClusterIP}
+	volumes := &kubernetesVolumes{client: client, namespace: "dev8"}
+	for _, id := range []string{"live", "gone"} {
+		if err := volumes.CreateVolume(ctx, "fs-"+id, 10); err != nil {
+			t.Fatalf("CreateVolume() error = %v", err)
+		}
+		if _, err := provider.Create(ctx, id, "eastus", "", ContainerDeploymentSpec{Image: "dev8-workspace:latest", CPUCores: 1, MemoryGB: 2, FileShareName: "fs-" + id}); err != nil {
+			t.Fatalf("Create() error = %v", err)
+		}
+	}
+
+	store := NewMemoryEnvironmentStore()
+	live := &models.Environment{ID: "live", Status: models.StatusRunning, AzureFQDN: "k8s-live.dev8.svc.cluster.local", UpdatedAt: time.Now().Add(-2 * time.Hour)}
+	if err := store.Put(ctx, live); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	cfg := &config.Config{
+		Azure: config.AzureConfig{
+			DeploymentMode: "kubernetes",
+			Regions:        []config.RegionConfig{{Name: "eastus", Enabled: true}},
+		},
+		Kubernetes: config.KubernetesConfig{Namespace: "dev8"},
+		Volumes:    config.VolumeConfig{Backend: "kubernetes"},
+	}
+	reconciler := &Reconciler{
+		cfg:   config.ReconcilerConfig{Policy: "delete", GracePeriod: time.Hour},
+		store: store,
+		inventory: &azureInventory{
+			config:     cfg,
+			kubeClient: client,
+			containers: provider,
+			volumes:    map[string]VolumeStore{"eastus": volumes},
+		},
+	}
+
+	report := reconciler.Reconcile(ctx, false)
+
+	if len(report.Errors) != 0 {
+		t.Fatalf("Errors = %v", report.Errors)
+	}
+	if len(report.Orphans) != 2 {
+		t.Fatalf("Orphans = %+v, want the workload and volume of the missing workspace", report.Orphans)
+	}
+	if report.Orphans[0].Kind != models.ResourceKubernetesWorkload || report.Orphans[0].Name != "k8s-gone" {
+		t.Errorf("first orphan = %+v, want the k8s-gone workload", report.Orphans[0])
+	}
+	for _, orphan := range report.Orphans {
+		if orphan.WorkspaceID != "gone" || orphan.Action != models.ActionDeleted {
+			t.Errorf("orphan %s = %+v, want workspace gone deleted", orphan.Name, orphan)
+		}
+	}
+	if _, err := client.AppsV1().StatefulSets("dev8").Get(ctx, "k8s-gone", metav1.GetOptions{}); err == nil {
+		t.Error("orphaned statefulset k8s-gone was not deleted")
+	}
+	if _, err := client.AppsV1().StatefulSets("dev8").Get(ctx, "k8s-live", metav1.GetOptions{}); err != nil {
+		t.Errorf("statefulset of a live workspace was deleted: %v", err)
+	}
+}
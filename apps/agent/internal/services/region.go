@@ -0,0 +1,208 @@
+package services
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+const (
+	regionOutcomeWindow  = 30 * time.Minute // Create attempts older than this are forgotten
+	regionOutcomeLimit   = 20               // Create attempts kept per region
+	regionMinOutcomes    = 3                // Attempts needed before a region's failure rate counts
+	regionFailingRate    = 0.5              // Failure rate at which a preferred region is tried last
+	regionFailureBuckets = 10               // Failure rates closer than 1/regionFailureBuckets rank alike
+)
+
+// RegionSelector orders the candidate regions of a create. It ranks regions by the
+// state of their circuit breaker, the failure rate of recent creates and client
+// latency hints.
+type RegionSelector struct {
+	breaker func(region string) azure.BreakerState
+	now     func() time.Time
+
+	mu       sync.Mutex
+	outcomes map[string][]regionOutcome // Recent create attempts by region, oldest first
+}
+
+// regionOutcome is the result of one create attempt in a region
+type regionOutcome struct {
+	at     time.Time
+	failed bool
+}
+
+// NewRegionSelector creates a region selector reading circuit states from retrier.
+// Without a retrier every circuit counts as closed.
+func NewRegionSelector(retrier *azure.Retrier) *RegionSelector {
+	return &RegionSelector{breaker: retrier.State, now: time.Now, outcomes: make(map[string][]regionOutcome)}
+}
+
+// Record records the outcome of a create attempt in region. Only failures of the
+// region itself count against it; a bad image or a cancelled request is not recorded.
+func (r *RegionSelector) Record(region string, err error) {
+	if r == nil || (err != nil && !regionFailure(err)) {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outcomes := append(r.outcomes[region], regionOutcome{at: r.now(), failed: err != nil})
+	if len(outcomes) > regionOutcomeLimit {
+		outcomes = outcomes[len(outcomes)-regionOutcomeLimit:]
+	}
+	r.outcomes[region] = outcomes
+}
+
+// FailureRate returns the share of recent create attempts in region that failed, and
+// the number of attempts it is based on
+func (r *RegionSelector) FailureRate(region string) (rate float64, attempts int) {
+	if r == nil {
+		return 0, 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cutoff := r.now().Add(-regionOutcomeWindow)
+	var failed int
+	for _, outcome := range r.outcomes[region] {
+		if outcome.at.Before(cutoff) {
+			continue
+		}
+		attempts++
+		if outcome.failed {
+			failed++
+		}
+	}
+	if attempts == 0 {
+		return 0, 0
+	}
+	return float64(failed) / float64(attempts), attempts
+}
+
+// Order returns regions best first. A preference list keeps its order, except that
+// regions with an open circuit or failing most recent creates go last. Otherwise
+// regions are ranked by circuit state (closed, half-open, open), then failure rate,
+// then latencyMs, regions without a hint after those with one; ties keep their order.
+func (r *RegionSelector) Order(regions []string, preferred bool, latencyMs map[string]int) []string {
+	type rank struct {
+		region   string
+		health   int
+		failures int
+		latency  int
+	}
+
+	ranks := make([]rank, len(regions))
+	for i, region := range regions {
+		state := azure.BreakerClosed
+		if r != nil {
+			state = r.breaker(region)
+		}
+		rate, attempts := r.FailureRate(region)
+		if attempts < regionMinOutcomes {
+			rate = 0
+		}
+
+		ranks[i] = rank{region: region, latency: math.MaxInt}
+		if preferred {
+			if state == azure.BreakerOpen || rate >= regionFailingRate {
+				ranks[i].health = 1
+			}
+			continue
+		}
+		switch state {
+		case azure.BreakerHalfOpen:
+			ranks[i].health = 1
+		case azure.BreakerOpen:
+			ranks[i].health = 2
+		}
+		ranks[i].failures = int(rate * regionFailureBuckets)
+		if ms, ok := latencyMs[region]; ok {
+			ranks[i].latency = ms
+		}
+	}
+
+	slices.SortStableFunc(ranks, func(a, b rank) int {
+		return cmp.Or(cmp.Compare(a.health, b.health), cmp.Compare(a.failures, b.failures), cmp.Compare(a.latency, b.latency))
+	})
+	ordered := make([]string, len(ranks))
+	for i, rank := range ranks {
+		ordered[i] = rank.region
+	}
+	return ordered
+}
+
+// regionFailure reports whether err says the region cannot take the workspace right
+// now: exhausted quota or capacity, or an unavailable (or circuit-broken) region. A
+// create failing this way is retried in the next candidate region.
+func regionFailure(err error) bool {
+	switch appError(err).Code {
+	case "QUOTA_EXCEEDED", "UNAVAILABLE":
+		return true
+	default:
+		return false
+	}
+}
+
+// createPlacements resolves the regions a create may run in, best first. A single
+// cloudRegion is used as is. "auto" considers every enabled region of the provider and
+// cloudRegions the listed ones; regions that are not available, or that the tier does
+// not allow, are left out.
+func (s *EnvironmentService) createPlacements(req *models.CreateEnvironmentRequest) ([]*placement, error) {
+	if !req.ChoosesRegion() {
+		place := s.placementFor(req.CloudProvider, req.CloudRegion)
+		if place == nil {
+			return nil, models.ErrInvalidRequest(regionUnavailable(req.CloudProvider, req.CloudRegion))
+		}
+		if place.volumes == nil {
+			return nil, models.ErrInternalServer(fmt.Sprintf("volume store not found for region %s", req.CloudRegion))
+		}
+		return []*placement{place}, nil
+	}
+
+	candidates := req.CloudRegions
+	if len(candidates) == 0 {
+		candidates = s.enabledRegions(req.CloudProvider)
+	}
+	var available []string
+	for _, region := range candidates {
+		if place := s.placementFor(req.CloudProvider, region); place != nil && place.volumes != nil {
+			available = append(available, region)
+		} else if len(req.CloudRegions) > 0 {
+			log.Printf("Workspace %s: skipping preferred region %s: %s", req.WorkspaceID, region, regionUnavailable(req.CloudProvider, region))
+		}
+	}
+	if len(available) == 0 {
+		return nil, models.ErrInvalidRequest(fmt.Sprintf("no region is available for cloud provider %s", req.CloudProvider))
+	}
+
+	available = s.policy.AllowedRegions(req.Tier, available)
+	ordered := s.regions.Order(available, len(req.CloudRegions) > 0, req.RegionLatencyMs)
+	places := make([]*placement, len(ordered))
+	for i, region := range ordered {
+		places[i] = s.placementFor(req.CloudProvider, region)
+	}
+	return places, nil
+}
+
+// enabledRegions returns the names of the enabled regions of provider
+func (s *EnvironmentService) enabledRegions(provider models.CloudProvider) []string {
+	var names []string
+	switch provider {
+	case "", models.ProviderAzure:
+		for _, region := range s.config.GetEnabledRegions() {
+			names = append(names, region.Name)
+		}
+	case models.ProviderAWS:
+		for _, region := range s.config.GetEnabledAWSRegions() {
+			names = append(names, region.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+func TestRegionSelector_Order(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	selector := NewRegionSelector(nil)
+	selector.now = func() time.Time { return now }
+	selector.breaker = func(region string) azure.BreakerState {
+		switch region {
+		case "open":
+			return azure.BreakerOpen
+		case "half-open":
+			return azure.BreakerHalfOpen
+		}
+		return azure.BreakerClosed
+	}
+	quota := models.ErrQuotaExceeded("out of capacity")
+	for i := 0; i < 4; i++ {
+		selector.Record("failing", quota)
+		selector.Record("flaky", nil)
+	}
+	selector.Record("flaky", quota)
+	selector.Record("healthy", models.ErrImagePull("bad image")) // Not the region's fault
+
+	tests := []struct {
+		name      string
+		regions   []string
+		preferred bool
+		latencyMs map[string]int
+		want      []string
+	}{
+		{
+			name:    "circuit state first",
+			regions: []string{"open", "half-open", "healthy"},
+			want:    []string{"healthy", "half-open", "open"},
+		},
+		{
+			name:    "then failure rate",
+			regions: []string{"failing", "flaky", "healthy"},
+			want:    []string{"healthy", "flaky", "failing"},
+		},
+		{
+			name:      "then latency hints",
+			regions:   []string{"healthy", "far", "near"},
+			latencyMs: map[string]int{"far": 180, "near": 20},
+			want:      []string{"near", "far", "healthy"},
+		},
+		{
+			name:      "preference order kept for healthy regions",
+			regions:   []string{"failing", "far", "open", "near", "flaky"},
+			preferred: true,
+			latencyMs: map[string]int{"far": 180, "near": 20},
+			want:      []string{"far", "near", "flaky", "failing", "open"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := selector.Order(tt.regions, tt.preferred, tt.latencyMs); !slices.Equal(got, tt.want) {
+				t.Errorf("Order() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Failures age out of the window
+	now = now.Add(regionOutcomeWindow + time.Minute)
+	if rate, attempts := selector.FailureRate("failing"); rate != 0 || attempts != 0 {
+		t.Errorf("FailureRate() after the window = %v over %d attempts, want none", rate, attempts)
+	}
+}
+
+func TestRegionSelector_Nil(t *testing.T) {
+	var selector *RegionSelector
+	selector.Record("eastus", nil)
+	if got := selector.Order([]string{"b", "a"}, false, map[string]int{"a": 10}); !slices.Equal(got, []string{"a", "b"}) {
+		t.Errorf("Order() = %v, want [a b]", got)
+	}
+}
+
+// addTestRegion adds an enabled region backed by its own local volumes to service
+func addTestRegion(t *testing.T, service *EnvironmentService, name string) *localVolumes {
+	t.Helper()
+	volumes, err := newLocalVolumes(t.TempDir())
+	if err != nil {
+		t.Fatalf("newLocalVolumes() error = %v", err)
+	}
+	service.config.Azure.Regions = append(service.config.Azure.Regions,
+		config.RegionConfig{Name: name, Location: name, Enabled: true, ResourceGroupName: "rg-" + name})
+	service.volumes[name] = volumes
+	return volumes
+}
+
+func TestEnvironmentService_RegionFailover(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, eastVolumes := newTestEnvironmentService(t, store)
+	westVolumes := addTestRegion(t, service, "westus")
+	service.regions = NewRegionSelector(nil)
+
+	provider.FailOnIn(FakeOpCreate, "eastus", models.ErrQuotaExceeded("container group quota reached"))
+	env, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID:  wsID,
+		Name:         "test-env",
+		CloudRegions: []string{"eastus", "westus"},
+		CPUCores:     2,
+		MemoryGB:     4,
+		StorageGB:    10,
+	})
+	if err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	if env.CloudRegion != "westus" || env.AzureResourceGroup != "rg-westus" {
+		t.Errorf("env placed in %s (%s), want westus (rg-westus)", env.CloudRegion, env.AzureResourceGroup)
+	}
+	if exists, _ := eastVolumes.VolumeExists(ctx, "fs-"+wsID); exists {
+		t.Error("the file share of the failed region was not cleaned up")
+	}
+	if exists, _ := westVolumes.VolumeExists(ctx, "fs-"+wsID); !exists {
+		t.Error("no file share was created in the failover region")
+	}
+	if rate, attempts := service.regions.FailureRate("eastus"); rate != 1 || attempts != 1 {
+		t.Errorf("FailureRate(eastus) = %v over %d attempts, want 1 over 1", rate, attempts)
+	}
+
+	// A retry of the request returns the workspace where it landed
+	again, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID:  wsID,
+		Name:         "test-env",
+		CloudRegions: []string{"eastus", "westus"},
+		CPUCores:     2,
+		MemoryGB:     4,
+		StorageGB:    10,
+	})
+	if err != nil || again.CloudRegion != "westus" {
+		t.Errorf("repeated CreateEnvironment() = %v, %v; want the westus workspace", again, err)
+	}
+}
+
+func TestEnvironmentService_RegionFailoverStops(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		req  models.CreateEnvironmentRequest
+	}{
+		{
+			name: "not a capacity error",
+			err:  models.ErrImagePull("image not found"),
+			req:  models.CreateEnvironmentRequest{CloudRegion: models.RegionAuto},
+		},
+		{
+			name: "single region",
+			err:  models.ErrQuotaExceeded("container group quota reached"),
+			req:  models.CreateEnvironmentRequest{CloudRegion: "eastus"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryEnvironmentStore()
+			service, provider, _ := newTestEnvironmentService(t, store)
+			westVolumes := addTestRegion(t, service, "westus")
+			provider.FailOnIn(FakeOpCreate, "eastus", tt.err)
+
+			req := tt.req
+			req.WorkspaceID, req.Name, req.CPUCores, req.MemoryGB, req.StorageGB = wsID, "test-env", 2, 4, 10
+			if _, err := service.CreateEnvironment(ctx, &req); appError(err).Code != appError(tt.err).Code {
+				t.Fatalf("CreateEnvironment() error = %v, want %s", err, appError(tt.err).Code)
+			}
+			if exists, _ := westVolumes.VolumeExists(ctx, "fs-"+wsID); exists {
+				t.Error("the create failed over to westus")
+			}
+			if env, _ := store.Get(ctx, wsID); env.Status != models.StatusError || env.CloudRegion != "eastus" {
+				t.Errorf("stored env = %s in %s, want ERROR in eastus", env.Status, env.CloudRegion)
+			}
+		})
+	}
+}
+
+func TestEnvironmentService_CreatePlacements(t *testing.T) {
+	service, _, _ := newTestEnvironmentService(t, NewMemoryEnvironmentStore())
+	addTestRegion(t, service, "westus")
+	service.config.Azure.Regions = append(service.config.Azure.Regions, config.RegionConfig{Name: "northeurope", Enabled: false})
+	service.policy = NewPolicyEngine(config.PolicyConfig{
+		DefaultTier: "free",
+		Tiers:       map[string]config.TierPolicy{"free": {Regions: []string{"westus"}}, "pro": {}},
+	}, service.store)
+
+	tests := []struct {
+		name    string
+		req     models.CreateEnvironmentRequest
+		want    []string
+		wantErr bool
+	}{
+		{name: "single region", req: models.CreateEnvironmentRequest{CloudRegion: "eastus"}, want: []string{"eastus"}},
+		{name: "unknown region", req: models.CreateEnvironmentRequest{CloudRegion: "northeurope"}, wantErr: true},
+		{name: "auto uses enabled regions", req: models.CreateEnvironmentRequest{CloudRegion: models.RegionAuto, Tier: "pro"}, want: []string{"eastus", "westus"}},
+		{
+			name: "auto favours low latency",
+			req:  models.CreateEnvironmentRequest{CloudRegion: models.RegionAuto, Tier: "pro", RegionLatencyMs: map[string]int{"westus": 30, "eastus": 90}},
+			want: []string{"westus", "eastus"},
+		},
+		{name: "tier limits the candidates", req: models.CreateEnvironmentRequest{CloudRegion: models.RegionAuto}, want: []string{"westus"}},
+		{
+			name: "unavailable preferences are skipped",
+			req:  models.CreateEnvironmentRequest{CloudRegions: []string{"northeurope", "westus", "eastus"}, Tier: "pro"},
+			want: []string{"westus", "eastus"},
+		},
+		{name: "no preference available", req: models.CreateEnvironmentRequest{CloudRegions: []string{"northeurope"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			req.CloudProvider = models.ProviderAzure
+			places, err := service.createPlacements(&req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("createPlacements() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, place := range places {
+				got = append(got, place.region)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("createPlacements() regions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
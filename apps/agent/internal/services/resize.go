@@ -0,0 +1,227 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+)
+
+// volumeHeadroomGB is added to the requested storage when sizing a workspace volume
+const volumeHeadroomGB = 5
+
+// volumeQuotaGB returns the volume quota for a workspace with storageGB of storage.
+// Callers validate storageGB, which is at most 100.
+func volumeQuotaGB(storageGB int) int32 {
+	return int32(storageGB) + volumeHeadroomGB // nolint:gosec // G115: storageGB is validated
+}
+
+// ResizeEnvironmentAsync validates the request and runs ResizeEnvironment in the background
+func (s *EnvironmentService) ResizeEnvironmentAsync(ctx context.Context, req *models.ResizeEnvironmentRequest) (*models.Operation, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+
+	env, err := s.store.Get(ctx, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResizable(env); err != nil {
+		return nil, err
+	}
+	if s.placementFor(env.CloudProvider, env.CloudRegion) == nil {
+		return nil, models.ErrNotFound(regionUnavailable(env.CloudProvider, env.CloudRegion))
+	}
+
+	// Policy rejections are returned up front; the reservation lasts until the operation ends
+	cpuCores, memoryGB, storageGB := resizedSpec(env, req)
+	release, err := s.policy.Admit(ctx, resizePolicyRequest(env, cpuCores, memoryGB, storageGB))
+	if err != nil {
+		return nil, err
+	}
+	op, err := s.submitLocked(ctx, models.OperationResize, req.WorkspaceID, func(ctx context.Context) (interface{}, error) {
+		defer release()
+		return s.ResizeEnvironment(ctx, req)
+	})
+	if err != nil {
+		release()
+		return nil, err
+	}
+	return op, nil
+}
+
+// ResizeEnvironment changes the CPU, memory and storage of a workspace in place.
+// The volume quota is changed first, so a refused shrink leaves the container untouched;
+// the container is then redeployed on the same volume with the new resources.
+func (s *EnvironmentService) ResizeEnvironment(ctx context.Context, req *models.ResizeEnvironmentRequest) (*models.Environment, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, unlock, err := s.lockWorkspace(ctx, req.WorkspaceID, models.OperationResize)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	env, err := s.store.Get(ctx, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResizable(env); err != nil {
+		return nil, err
+	}
+
+	place := s.placementFor(env.CloudProvider, env.CloudRegion)
+	if place == nil {
+		return nil, models.ErrNotFound(regionUnavailable(env.CloudProvider, env.CloudRegion))
+	}
+	volumes := place.volumes
+	if volumes == nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("volume store not found for region %s", env.CloudRegion))
+	}
+
+	workspaceID := env.ID
+	fileShareName := fmt.Sprintf("fs-%s", workspaceID)
+	previousStatus := env.Status
+
+	cpuCores, memoryGB, storageGB := resizedSpec(env, req)
+
+	// The reservation covers the new size until the record shows it
+	release, err := s.policy.Admit(ctx, resizePolicyRequest(env, cpuCores, memoryGB, storageGB))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	log.Printf("📐 Resizing workspace %s: %d→%d cores, %d→%d GB memory, %d→%d GB storage",
+		workspaceID, env.CPUCores, cpuCores, env.MemoryGB, memoryGB, env.StorageGB, storageGB)
+
+	if storageGB < env.StorageGB {
+		reportProgress(ctx, "checking-volume-usage", 10)
+		if err := checkVolumeShrink(ctx, volumes, fileShareName, volumeQuotaGB(storageGB)); err != nil {
+			return nil, err
+		}
+	}
+
+	// Resolve secrets up front: a new container size recreates the container
+	secrets := &models.WorkspaceSecrets{}
+	if cpuCores != env.CPUCores || memoryGB != env.MemoryGB {
+		resolved, err := s.resolveSecrets(ctx, env, req.Secrets())
+		if err != nil {
+			return nil, err
+		}
+		secrets = resolved
+	}
+
+	s.setStatus(ctx, workspaceID, env.CloudRegion, models.StatusResizing)
+
+	if storageGB != env.StorageGB {
+		reportProgress(ctx, "resizing-volume", 20)
+		if err := volumes.ResizeVolume(ctx, fileShareName, volumeQuotaGB(storageGB)); err != nil {
+			s.setStatus(ctx, workspaceID, env.CloudRegion, previousStatus)
+			return nil, cloudError(fmt.Sprintf("workspace %s: failed to resize volume", workspaceID), err)
+		}
+		log.Printf("✅ Volume %s resized to %d GB", fileShareName, volumeQuotaGB(storageGB))
+	}
+
+	if cpuCores != env.CPUCores || memoryGB != env.MemoryGB {
+		reportProgress(ctx, "resizing-container", 40)
+		deploySpec := ContainerDeploymentSpec{
+			Image:              s.getContainerImage(env.BaseImage, env.CloudRegion),
+			CPUCores:           float64(cpuCores),
+			MemoryGB:           float64(memoryGB),
+			FileShareName:      fileShareName,
+			LocalVolumePath:    localVolumePath(volumes, fileShareName),
+			StorageAccountName: place.storageAccount,
+			StorageAccountKey:  s.config.Azure.StorageAccountKey,
+			UserID:             env.UserID,
+			RegistryServer:     s.getRegistryServer(env.BaseImage, env.CloudRegion),
+			RegistryUsername:   s.config.RegistryUsername,
+			RegistryPassword:   s.config.RegistryPassword,
+			AgentBaseURL:       s.config.AgentBaseURL,
+			Ports:              env.Ports,
+		}
+		deploySpec.applySecrets(secrets, env.EnvVars)
+
+		containerInfo, err := place.containers.Resize(ctx, workspaceID, env.CloudRegion, place.resourceGroup, deploySpec)
+		switch {
+		case isContainerNotFound(err):
+			// Nothing is deployed; the next start creates the container at the new size
+			log.Printf("Workspace %s has no container, recording new size only", workspaceID)
+		case err != nil:
+			return nil, s.failEnvironment(ctx, workspaceID, cloudError(fmt.Sprintf("workspace %s: failed to resize container", workspaceID), err))
+		case previousStatus == models.StatusRunning:
+			if containerInfo == nil || containerInfo.FQDN == "" {
+				reportProgress(ctx, "waiting-for-fqdn", 85)
+				if info, err := s.waitForContainerFQDN(ctx, place, workspaceID, 30*time.Second); err != nil {
+					log.Printf("Warning: workspace %s: failed to get container details: %v", workspaceID, err)
+				} else {
+					containerInfo = info
+				}
+			}
+			if containerInfo != nil && containerInfo.FQDN != "" {
+				password := secrets.CodeServerPassword
+				if password == "" {
+					password = env.ConnectionURLs.CodeServerPassword
+				}
+				env.AzureFQDN = containerInfo.FQDN
+				env.ConnectionURLs = connectionURLsFor(containerInfo, password, env.Ports)
+			}
+		}
+	}
+
+	env.CPUCores = cpuCores
+	env.MemoryGB = memoryGB
+	env.StorageGB = storageGB
+	env.Status = previousStatus
+	env.StatusMessage = ""
+	env.UpdatedAt = time.Now()
+	s.saveEnvironment(ctx, env)
+	s.publish(ctx, webhook.EventResized, workspaceID, env)
+
+	log.Printf("✅ Workspace %s resized", workspaceID)
+	return env, nil
+}
+
+// resizedSpec returns the CPU, memory and storage of env after req; zero fields keep their value
+func resizedSpec(env *models.Environment, req *models.ResizeEnvironmentRequest) (cpuCores, memoryGB, storageGB int) {
+	cpuCores, memoryGB, storageGB = env.CPUCores, env.MemoryGB, env.StorageGB
+	if req.CPUCores != 0 {
+		cpuCores = req.CPUCores
+	}
+	if req.MemoryGB != 0 {
+		memoryGB = req.MemoryGB
+	}
+	if req.StorageGB != 0 {
+		storageGB = req.StorageGB
+	}
+	return cpuCores, memoryGB, storageGB
+}
+
+// checkResizable only lets settled workspaces be resized
+func checkResizable(env *models.Environment) error {
+	if env.Status != models.StatusRunning && env.Status != models.StatusStopped {
+		return models.ErrConflict(fmt.Sprintf("workspace %s is %s; only running or stopped workspaces can be resized", env.ID, env.Status))
+	}
+	return nil
+}
+
+// checkVolumeShrink refuses to shrink a volume below the data it already holds
+func checkVolumeShrink(ctx context.Context, volumes VolumeStore, name string, quotaGB int32) error {
+	used, err := volumes.VolumeUsage(ctx, name)
+	if errors.Is(err, ErrVolumeUsageUnavailable) {
+		return models.ErrConflict(fmt.Sprintf("volume %s: usage cannot be measured right now, so it cannot be shrunk", name))
+	}
+	if err != nil {
+		return cloudError(fmt.Sprintf("volume %s: failed to measure usage", name), err)
+	}
+
+	if used > int64(quotaGB)<<30 {
+		return models.ErrInvalidRequest(fmt.Sprintf("volume %s holds %.1f GB, more than the requested quota of %d GB", name, float64(used)/(1<<30), quotaGB))
+	}
+	return nil
+}
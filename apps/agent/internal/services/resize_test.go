@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+)
+
+func TestEnvironmentService_Resize(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, volumes := newTestEnvironmentService(t, store)
+	publisher := &recordingPublisher{}
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	service.SetEventPublisher(publisher)
+
+	env, err := service.ResizeEnvironment(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: wsID, CPUCores: 4, StorageGB: 50})
+	if err != nil {
+		t.Fatalf("ResizeEnvironment() error = %v", err)
+	}
+	if env.CPUCores != 4 || env.MemoryGB != 4 || env.StorageGB != 50 || env.Status != models.StatusRunning {
+		t.Errorf("resized env = %d cores, %d GB, %d GB storage, %s; want 4, 4, 50, RUNNING", env.CPUCores, env.MemoryGB, env.StorageGB, env.Status)
+	}
+	if spec, _ := provider.Spec(wsID); spec.CPUCores != 4 || spec.MemoryGB != 4 || spec.FileShareName != "fs-"+wsID {
+		t.Errorf("deployed spec = %+v, want 4 cores and 4 GB on the same volume", spec)
+	}
+	if props, err := volumes.VolumeProperties(ctx, "fs-"+wsID); err != nil || props.QuotaGB != 55 {
+		t.Errorf("volume properties = %+v, %v, want quota 55", props, err)
+	}
+	if len(publisher.events) != 1 || publisher.events[0] != webhook.EventResized {
+		t.Errorf("events = %v, want [%s]", publisher.events, webhook.EventResized)
+	}
+
+	// Storage-only resizes leave the container alone
+	provider.FailOn(FakeOpResize, errors.New("should not be called"))
+	if _, err := service.ResizeEnvironment(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: wsID, StorageGB: 20}); err != nil {
+		t.Fatalf("ResizeEnvironment() storage only error = %v", err)
+	}
+	if props, _ := volumes.VolumeProperties(ctx, "fs-"+wsID); props.QuotaGB != 25 {
+		t.Errorf("quota after shrink = %d, want 25", props.QuotaGB)
+	}
+}
+
+func TestEnvironmentService_ResizeRefusesShrinkBelowUsage(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, _, volumes := newTestEnvironmentService(t, store)
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   50,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+
+	// A sparse file reports its full size, so the volume appears to hold 20 GB
+	file, err := os.Create(filepath.Join(volumes.Path("fs-"+wsID), "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := file.Truncate(20 << 30); err != nil {
+		t.Fatal(err)
+	}
+	_ = file.Close()
+
+	_, err = service.ResizeEnvironment(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: wsID, StorageGB: 10})
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "INVALID_REQUEST" {
+		t.Fatalf("ResizeEnvironment() error = %v, want INVALID_REQUEST", err)
+	}
+	if props, _ := volumes.VolumeProperties(ctx, "fs-"+wsID); props.QuotaGB != 55 {
+		t.Errorf("quota after refused shrink = %d, want 55", props.QuotaGB)
+	}
+	if env, _ := store.Get(ctx, wsID); env.Status != models.StatusRunning || env.StorageGB != 50 {
+		t.Errorf("stored env = %s with %d GB, want RUNNING with 50 GB", env.Status, env.StorageGB)
+	}
+}
+
+func TestEnvironmentService_ResizeRequiresSettledWorkspace(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, _, _ := newTestEnvironmentService(t, store)
+
+	if err := store.Put(ctx, &models.Environment{ID: wsID, CloudRegion: "eastus", Status: models.StatusCreating}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := service.ResizeEnvironmentAsync(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: wsID, CPUCores: 4})
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+		t.Errorf("ResizeEnvironmentAsync() error = %v, want CONFLICT", err)
+	}
+
+	_, err = service.ResizeEnvironmentAsync(ctx, &models.ResizeEnvironmentRequest{WorkspaceID: "missing-workspace", CPUCores: 4})
+	if !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+		t.Errorf("ResizeEnvironmentAsync() of unknown workspace error = %v, want NOT_FOUND", err)
+	}
+}
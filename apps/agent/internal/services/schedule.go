@@ -0,0 +1,370 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/google/uuid"
+)
+
+// maxSchedulesPerWorkspace bounds the schedules of one workspace
+const maxSchedulesPerWorkspace = 20
+
+// SetScheduleStore keeps schedules in store, e.g. one that survives restarts
+func (s *EnvironmentService) SetScheduleStore(store ScheduleStore) {
+	s.schedules = store
+}
+
+// CreateSchedule adds a start or stop schedule to a workspace. Its first run is the
+// first one after now; earlier times are not caught up on.
+func (s *EnvironmentService) CreateSchedule(ctx context.Context, workspaceID string, req *models.ScheduleRequest) (*models.Schedule, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.store.Get(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+	existing, err := s.schedules.List(ctx, workspaceID)
+	if err != nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to list schedules: %v", workspaceID, err))
+	}
+	if len(existing) >= maxSchedulesPerWorkspace {
+		return nil, models.ErrInvalidRequest(fmt.Sprintf("workspace %s already has %d schedules", workspaceID, maxSchedulesPerWorkspace))
+	}
+
+	now := time.Now()
+	schedule := &models.Schedule{
+		ID:          uuid.NewString(),
+		WorkspaceID: workspaceID,
+		CreatedAt:   now,
+	}
+	applyScheduleRequest(schedule, req, now)
+	if err := s.schedules.Put(ctx, schedule); err != nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to save schedule: %v", workspaceID, err))
+	}
+	log.Printf("🗓️  Workspace %s: %s schedule %s added (%s %s)", workspaceID, schedule.Action, schedule.ID, schedule.Cron, schedule.Timezone)
+	return schedule, nil
+}
+
+// ListSchedules returns the schedules of a workspace, oldest first
+func (s *EnvironmentService) ListSchedules(ctx context.Context, workspaceID string) ([]models.Schedule, error) {
+	if _, err := s.store.Get(ctx, workspaceID); err != nil {
+		return nil, err
+	}
+	schedules, err := s.schedules.List(ctx, workspaceID)
+	if err != nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to list schedules: %v", workspaceID, err))
+	}
+	if schedules == nil {
+		schedules = []models.Schedule{}
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns one schedule of a workspace
+func (s *EnvironmentService) GetSchedule(ctx context.Context, workspaceID, scheduleID string) (*models.Schedule, error) {
+	schedule, err := s.schedules.Get(ctx, scheduleID)
+	var appErr *models.AppError
+	switch {
+	case errors.As(err, &appErr) && appErr.Code == "NOT_FOUND":
+		return nil, models.ErrNotFound(fmt.Sprintf("workspace %s has no schedule %s", workspaceID, scheduleID))
+	case err != nil:
+		return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to read schedule %s: %v", workspaceID, scheduleID, err))
+	case schedule.WorkspaceID != workspaceID:
+		return nil, models.ErrNotFound(fmt.Sprintf("workspace %s has no schedule %s", workspaceID, scheduleID))
+	}
+	return schedule, nil
+}
+
+// UpdateSchedule replaces a schedule. Like a new schedule, it runs from the next
+// time after now.
+func (s *EnvironmentService) UpdateSchedule(ctx context.Context, workspaceID, scheduleID string, req *models.ScheduleRequest) (*models.Schedule, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	schedule, err := s.GetSchedule(ctx, workspaceID, scheduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	applyScheduleRequest(schedule, req, time.Now())
+	if err := s.schedules.Put(ctx, schedule); err != nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to save schedule: %v", workspaceID, err))
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule removes a schedule of a workspace
+func (s *EnvironmentService) DeleteSchedule(ctx context.Context, workspaceID, scheduleID string) error {
+	if _, err := s.GetSchedule(ctx, workspaceID, scheduleID); err != nil {
+		return err
+	}
+	if err := s.schedules.Delete(ctx, scheduleID); err != nil {
+		return models.ErrInternalServer(fmt.Sprintf("workspace %s: failed to delete schedule %s: %v", workspaceID, scheduleID, err))
+	}
+	return nil
+}
+
+// deleteSchedules removes the schedules of a workspace being deleted
+func (s *EnvironmentService) deleteSchedules(ctx context.Context, workspaceID string) {
+	schedules, err := s.schedules.List(ctx, workspaceID)
+	if err != nil {
+		log.Printf("Warning: workspace %s: failed to list schedules: %v", workspaceID, err)
+		return
+	}
+	for _, schedule := range schedules {
+		if err := s.schedules.Delete(ctx, schedule.ID); err != nil {
+			log.Printf("Warning: workspace %s: failed to delete schedule %s: %v", workspaceID, schedule.ID, err)
+		}
+	}
+}
+
+// applyScheduleRequest sets the fields of a validated request on schedule and
+// restarts its runs from now
+func applyScheduleRequest(schedule *models.Schedule, req *models.ScheduleRequest, now time.Time) {
+	schedule.Action = req.Action
+	schedule.Cron = req.Cron
+	schedule.Timezone = req.Timezone
+	schedule.SkipDates = req.SkipDates
+	schedule.Enabled = req.IsEnabled()
+	schedule.CheckedUntil = now
+	schedule.NextRunAt = nextScheduleRun(schedule, now)
+	schedule.UpdatedAt = now
+}
+
+// Scheduler starts and stops workspaces at the runs of their schedules, through the
+// same asynchronous operations as the API.
+//
+// Runs are handled once: each schedule remembers the time up to which it has been
+// checked, so a restart neither repeats nor loses runs. When several runs of a
+// workspace's schedules are due at one check, e.g. after the agent was down, only
+// the latest one is carried out, so the workspace ends up as its schedules say it
+// should be now; the others are recorded as superseded. A latest run older than the
+// catch-up window is recorded as missed instead.
+type Scheduler struct {
+	cfg       config.ScheduleConfig
+	schedules ScheduleStore
+	store     EnvironmentStore
+
+	// start and stop are EnvironmentService methods, replaceable in tests
+	start func(ctx context.Context, req *models.StartEnvironmentRequest) (*models.Operation, error)
+	stop  func(ctx context.Context, workspaceID, region string) (*models.Operation, error)
+	now   func() time.Time
+}
+
+// NewScheduler creates a scheduler for the workspaces managed by service
+func NewScheduler(service *EnvironmentService, cfg config.ScheduleConfig) *Scheduler {
+	return &Scheduler{
+		cfg:       cfg,
+		schedules: service.schedules,
+		store:     service.store,
+		start:     service.StartEnvironmentAsync,
+		stop:      service.StopEnvironmentAsync,
+		now:       time.Now,
+	}
+}
+
+// Run checks for due runs right away, catching up on any missed while the agent was
+// down, then every check interval until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	log.Printf("🗓️  Scheduler started (interval=%s, catch-up=%s)", s.cfg.CheckInterval, s.cfg.CatchUpWindow)
+
+	ticker := time.NewTicker(s.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Check(ctx); err != nil {
+			log.Printf("❌ Schedule check failed: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("🗓️  Scheduler stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduledRun is the latest due run of a schedule
+type scheduledRun struct {
+	schedule *models.Schedule
+	at       time.Time
+}
+
+// Check carries out the runs that became due since the last check
+func (s *Scheduler) Check(ctx context.Context) error {
+	now := s.now()
+	schedules, err := s.schedules.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list schedules: %w", err)
+	}
+
+	due := make(map[string][]scheduledRun)
+	for i := range schedules {
+		schedule := &schedules[i]
+		if !schedule.Enabled {
+			continue
+		}
+		if at, ok := latestScheduleRun(schedule, now); ok {
+			due[schedule.WorkspaceID] = append(due[schedule.WorkspaceID], scheduledRun{schedule: schedule, at: at})
+		}
+	}
+
+	workspaceIDs := make([]string, 0, len(due))
+	for workspaceID := range due {
+		workspaceIDs = append(workspaceIDs, workspaceID)
+	}
+	sort.Strings(workspaceIDs)
+
+	for _, workspaceID := range workspaceIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s.runWorkspace(ctx, due[workspaceID], now)
+	}
+	return nil
+}
+
+// runWorkspace carries out the latest of the due runs of one workspace's schedules
+// and records the outcome on each of them
+func (s *Scheduler) runWorkspace(ctx context.Context, runs []scheduledRun, now time.Time) {
+	// The latest run decides; at the same time a stop wins over a start
+	sort.SliceStable(runs, func(i, j int) bool {
+		if !runs[i].at.Equal(runs[j].at) {
+			return runs[i].at.After(runs[j].at)
+		}
+		return runs[i].schedule.Action == models.ScheduleStop && runs[j].schedule.Action != models.ScheduleStop
+	})
+
+	// Runs found by regular checks are never late enough to be missed
+	window := max(s.cfg.CatchUpWindow, 2*s.cfg.CheckInterval)
+
+	for i, run := range runs {
+		schedule := run.schedule
+		var result models.ScheduleResult
+		var operationID, lastError string
+		switch {
+		case now.Sub(run.at) > window:
+			result = models.ScheduleMissed
+			log.Printf("🗓️  Workspace %s: %s run of %s missed by %s", schedule.WorkspaceID, schedule.Action, run.at.Format(time.RFC3339), now.Sub(run.at).Round(time.Second))
+		case i > 0:
+			result = models.ScheduleSuperseded
+		default:
+			op, err := s.execute(ctx, schedule)
+			switch {
+			case err != nil:
+				result, lastError = models.ScheduleFailed, err.Error()
+				log.Printf("❌ Workspace %s: scheduled %s failed: %v", schedule.WorkspaceID, schedule.Action, err)
+			case op == nil:
+				result = models.ScheduleNoop
+			default:
+				result, operationID = models.ScheduleSubmitted, op.ID
+				log.Printf("🗓️  Workspace %s: scheduled %s submitted as operation %s", schedule.WorkspaceID, schedule.Action, op.ID)
+			}
+		}
+		s.record(ctx, schedule, run.at, now, result, operationID, lastError)
+	}
+}
+
+// execute starts or stops the workspace of schedule. A nil operation means the
+// workspace already was as the schedule wants it.
+func (s *Scheduler) execute(ctx context.Context, schedule *models.Schedule) (*models.Operation, error) {
+	env, err := s.store.Get(ctx, schedule.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch schedule.Action {
+	case models.ScheduleStart:
+		if env.Status == models.StatusRunning || env.Status == models.StatusStarting {
+			return nil, nil
+		}
+		req := startRequestFor(env)
+		if err := req.Validate(); err != nil {
+			return nil, err
+		}
+		return s.start(ctx, req)
+	case models.ScheduleStop:
+		if env.Status == models.StatusStopped || env.Status == models.StatusStopping {
+			return nil, nil
+		}
+		return s.stop(ctx, env.ID, env.CloudRegion)
+	}
+	return nil, models.ErrInvalidRequest(fmt.Sprintf("unknown schedule action %s", schedule.Action))
+}
+
+// record saves the outcome of a run. A schedule replaced since the check started
+// keeps the state the replacement gave it.
+func (s *Scheduler) record(ctx context.Context, checked *models.Schedule, at, now time.Time, result models.ScheduleResult, operationID, lastError string) {
+	schedule, err := s.schedules.Get(ctx, checked.ID)
+	if err != nil || !schedule.UpdatedAt.Equal(checked.UpdatedAt) {
+		return
+	}
+
+	schedule.CheckedUntil = now
+	schedule.LastRunAt = &at
+	schedule.LastResult = result
+	schedule.LastOperationID = operationID
+	schedule.LastError = lastError
+	schedule.NextRunAt = nextScheduleRun(schedule, now)
+	if err := s.schedules.Put(ctx, schedule); err != nil {
+		log.Printf("Warning: workspace %s: failed to save schedule %s: %v", schedule.WorkspaceID, schedule.ID, err)
+	}
+}
+
+// latestScheduleRun returns the latest run of schedule after its last check and no
+// later than now, leaving out skip dates
+func latestScheduleRun(schedule *models.Schedule, now time.Time) (time.Time, bool) {
+	expr, loc, err := schedule.Expression()
+	if err != nil {
+		log.Printf("Warning: workspace %s: schedule %s is invalid: %v", schedule.WorkspaceID, schedule.ID, err)
+		return time.Time{}, false
+	}
+
+	var latest time.Time
+	for at := expr.Next(schedule.CheckedUntil.In(loc)); !at.IsZero() && !at.After(now); at = expr.Next(at) {
+		if !schedule.Skips(at) {
+			latest = at
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// nextScheduleRun returns the first run of schedule after now, leaving out skip dates,
+// or nil if it is disabled or never runs again
+func nextScheduleRun(schedule *models.Schedule, now time.Time) *time.Time {
+	if !schedule.Enabled {
+		return nil
+	}
+	expr, loc, err := schedule.Expression()
+	if err != nil {
+		return nil
+	}
+	for at := expr.Next(now.In(loc)); !at.IsZero(); at = expr.Next(at) {
+		if !schedule.Skips(at) {
+			return &at
+		}
+	}
+	return nil
+}
+
+// startRequestFor builds the start request of a stored workspace. Saved secrets are
+// filled in by StartEnvironment.
+func startRequestFor(env *models.Environment) *models.StartEnvironmentRequest {
+	return &models.StartEnvironmentRequest{
+		WorkspaceID: env.ID,
+		CloudRegion: env.CloudRegion,
+		UserID:      env.UserID,
+		Name:        env.Name,
+		CPUCores:    env.CPUCores,
+		MemoryGB:    env.MemoryGB,
+		StorageGB:   env.StorageGB,
+		BaseImage:   env.BaseImage,
+	}
+}
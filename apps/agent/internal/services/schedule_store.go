@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var schedulesBucket = []byte("schedules")
+
+// ScheduleStore persists workspace start and stop schedules with the scheduler's
+// progress through them. Implementations must be safe for concurrent use.
+type ScheduleStore interface {
+	// Get returns the schedule with the given ID or a NOT_FOUND AppError
+	Get(ctx context.Context, id string) (*models.Schedule, error)
+	// Put creates or replaces a schedule
+	Put(ctx context.Context, schedule *models.Schedule) error
+	// Delete removes a schedule. Deleting a missing schedule is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns the schedules of a workspace, or every schedule for an empty
+	// workspaceID, oldest first
+	List(ctx context.Context, workspaceID string) ([]models.Schedule, error)
+}
+
+// sortSchedules orders schedules oldest first
+func sortSchedules(schedules []models.Schedule) {
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].CreatedAt.Equal(schedules[j].CreatedAt) {
+			return schedules[i].ID < schedules[j].ID
+		}
+		return schedules[i].CreatedAt.Before(schedules[j].CreatedAt)
+	})
+}
+
+// MemoryScheduleStore keeps schedules in memory. They are lost on restart.
+type MemoryScheduleStore struct {
+	mu        sync.RWMutex
+	schedules map[string]models.Schedule
+}
+
+// NewMemoryScheduleStore creates an empty in-memory schedule store
+func NewMemoryScheduleStore() *MemoryScheduleStore {
+	return &MemoryScheduleStore{schedules: make(map[string]models.Schedule)}
+}
+
+// Get returns the schedule with the given ID
+func (m *MemoryScheduleStore) Get(ctx context.Context, id string) (*models.Schedule, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	schedule, ok := m.schedules[id]
+	if !ok {
+		return nil, models.ErrNotFound(fmt.Sprintf("schedule %s not found", id))
+	}
+	schedule.SkipDates = append([]string(nil), schedule.SkipDates...)
+	return &schedule, nil
+}
+
+// Put creates or replaces a schedule
+func (m *MemoryScheduleStore) Put(ctx context.Context, schedule *models.Schedule) error {
+	if schedule == nil || schedule.ID == "" {
+		return models.ErrInvalidRequest("schedule id is required")
+	}
+	stored := *schedule
+	stored.SkipDates = append([]string(nil), schedule.SkipDates...)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schedules[schedule.ID] = stored
+	return nil
+}
+
+// Delete removes a schedule
+func (m *MemoryScheduleStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.schedules, id)
+	return nil
+}
+
+// List returns the schedules of a workspace, or all of them
+func (m *MemoryScheduleStore) List(ctx context.Context, workspaceID string) ([]models.Schedule, error) {
+	m.mu.RLock()
+	var schedules []models.Schedule
+	for _, schedule := range m.schedules {
+		if workspaceID == "" || schedule.WorkspaceID == workspaceID {
+			schedule.SkipDates = append([]string(nil), schedule.SkipDates...)
+			schedules = append(schedules, schedule)
+		}
+	}
+	m.mu.RUnlock()
+
+	sortSchedules(schedules)
+	return schedules, nil
+}
+
+// BoltScheduleStore persists schedules in the agent's embedded database so they,
+// and the runs already handled, survive restarts
+type BoltScheduleStore struct {
+	db *bolt.DB
+}
+
+// NewBoltScheduleStore creates a schedule store backed by db
+func NewBoltScheduleStore(db *bolt.DB) (*BoltScheduleStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(schedulesBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize schedules bucket: %w", err)
+	}
+
+	return &BoltScheduleStore{db: db}, nil
+}
+
+// Get returns the schedule with the given ID
+func (b *BoltScheduleStore) Get(ctx context.Context, id string) (*models.Schedule, error) {
+	var schedule *models.Schedule
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(schedulesBucket).Get([]byte(id))
+		if data == nil {
+			return models.ErrNotFound(fmt.Sprintf("schedule %s not found", id))
+		}
+		schedule = &models.Schedule{}
+		return json.Unmarshal(data, schedule)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Put creates or replaces a schedule
+func (b *BoltScheduleStore) Put(ctx context.Context, schedule *models.Schedule) error {
+	if schedule == nil || schedule.ID == "" {
+		return models.ErrInvalidRequest("schedule id is required")
+	}
+
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule %s: %w", schedule.ID, err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).Put([]byte(schedule.ID), data)
+	})
+}
+
+// Delete removes a schedule
+func (b *BoltScheduleStore) Delete(ctx context.Context, id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).Delete([]byte(id))
+	})
+}
+
+// List returns the schedules of a workspace, or all of them
+func (b *BoltScheduleStore) List(ctx context.Context, workspaceID string) ([]models.Schedule, error) {
+	var schedules []models.Schedule
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(schedulesBucket).ForEach(func(k, v []byte) error {
+			var schedule models.Schedule
+			if err := json.Unmarshal(v, &schedule); err != nil {
+				return fmt.Errorf("failed to unmarshal schedule %s: %w", k, err)
+			}
+			if workspaceID == "" || schedule.WorkspaceID == workspaceID {
+				schedules = append(schedules, schedule)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortSchedules(schedules)
+	return schedules, nil
+}
@@ -0,0 +1,322 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// newTestScheduler returns a scheduler over schedules and store that records the
+// started and stopped workspaces
+func newTestScheduler(schedules ScheduleStore, store EnvironmentStore, cfg config.ScheduleConfig, now time.Time) (*Scheduler, *[]string, *[]string) {
+	var started, stopped []string
+	scheduler := &Scheduler{
+		cfg:       cfg,
+		schedules: schedules,
+		store:     store,
+		start: func(ctx context.Context, req *models.StartEnvironmentRequest) (*models.Operation, error) {
+			started = append(started, req.WorkspaceID)
+			return &models.Operation{ID: fmt.Sprintf("op-start-%d", len(started))}, nil
+		},
+		stop: func(ctx context.Context, workspaceID, region string) (*models.Operation, error) {
+			stopped = append(stopped, workspaceID)
+			return &models.Operation{ID: fmt.Sprintf("op-stop-%d", len(stopped))}, nil
+		},
+		now: func() time.Time { return now },
+	}
+	return scheduler, &started, &stopped
+}
+
+// putScheduledWorkspace stores workspace ws-1 with the given status
+func putScheduledWorkspace(t *testing.T, store EnvironmentStore, status models.EnvironmentStatus) {
+	t.Helper()
+	env := &models.Environment{
+		ID:          "ws-1",
+		UserID:      "user-1",
+		Name:        "scheduled",
+		CloudRegion: "eastus",
+		Status:      status,
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   20,
+		BaseImage:   "node",
+	}
+	if err := store.Put(context.Background(), env); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+}
+
+func TestEnvironmentService_Schedules(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, _, _ := newTestEnvironmentService(t, store)
+	putScheduledWorkspace(t, store, models.StatusRunning)
+
+	req := &models.ScheduleRequest{Action: "start", Cron: "0 9 * * MON-FRI", Timezone: "Europe/Berlin", SkipDates: []string{"2025-12-25", "2025-12-24", "2025-12-25"}}
+	if _, err := service.CreateSchedule(ctx, "ws-missing", req); !isAppErrorCode(err, "NOT_FOUND") {
+		t.Fatalf("CreateSchedule() for a missing workspace error = %v, want NOT_FOUND", err)
+	}
+
+	created, err := service.CreateSchedule(ctx, "ws-1", req)
+	if err != nil {
+		t.Fatalf("CreateSchedule() error = %v", err)
+	}
+	if created.Action != models.ScheduleStart || !created.Enabled || len(created.SkipDates) != 2 {
+		t.Errorf("created schedule = %+v, want an enabled START schedule with 2 skip dates", created)
+	}
+	if created.NextRunAt == nil || created.NextRunAt.Before(time.Now()) {
+		t.Errorf("NextRunAt = %v, want a future run", created.NextRunAt)
+	}
+
+	if _, err := service.CreateSchedule(ctx, "ws-1", &models.ScheduleRequest{Action: "PAUSE", Cron: "* * * * *"}); !isAppErrorCode(err, "INVALID_REQUEST") {
+		t.Errorf("CreateSchedule() with an unknown action error = %v, want INVALID_REQUEST", err)
+	}
+
+	other := &models.Environment{ID: "ws-2", CloudRegion: "eastus"}
+	if err := store.Put(ctx, other); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if _, err := service.GetSchedule(ctx, "ws-2", created.ID); !isAppErrorCode(err, "NOT_FOUND") {
+		t.Errorf("GetSchedule() from another workspace error = %v, want NOT_FOUND", err)
+	}
+
+	disabled := false
+	updated, err := service.UpdateSchedule(ctx, "ws-1", created.ID, &models.ScheduleRequest{Action: "STOP", Cron: "0 18 * * *", Enabled: &disabled})
+	if err != nil {
+		t.Fatalf("UpdateSchedule() error = %v", err)
+	}
+	if updated.Action != models.ScheduleStop || updated.Timezone != "UTC" || updated.Enabled || updated.NextRunAt != nil || !updated.CreatedAt.Equal(created.CreatedAt) {
+		t.Errorf("updated schedule = %+v, want a disabled UTC STOP schedule without a next run", updated)
+	}
+
+	schedules, err := service.ListSchedules(ctx, "ws-1")
+	if err != nil || len(schedules) != 1 {
+		t.Fatalf("ListSchedules() = %d schedules, %v, want 1", len(schedules), err)
+	}
+
+	if err := service.DeleteSchedule(ctx, "ws-1", created.ID); err != nil {
+		t.Fatalf("DeleteSchedule() error = %v", err)
+	}
+	if _, err := service.GetSchedule(ctx, "ws-1", created.ID); !isAppErrorCode(err, "NOT_FOUND") {
+		t.Errorf("GetSchedule() after delete error = %v, want NOT_FOUND", err)
+	}
+}
+
+func TestScheduler_Check(t *testing.T) {
+	// Monday 09:05 UTC
+	now := time.Date(2025, 3, 10, 9, 5, 0, 0, time.UTC)
+	lastCheck := now.Add(-30 * time.Minute)
+	cfg := config.ScheduleConfig{CheckInterval: 30 * time.Second, CatchUpWindow: time.Hour}
+
+	tests := []struct {
+		name        string
+		status      models.EnvironmentStatus
+		disabled    bool
+		schedules   []models.Schedule
+		wantStarted int
+		wantStopped int
+		wantResults []models.ScheduleResult // Per schedule, "" when it had no due run
+	}{
+		{
+			name:        "start due",
+			status:      models.StatusStopped,
+			schedules:   []models.Schedule{{Action: models.ScheduleStart, Cron: "0 9 * * *"}},
+			wantStarted: 1,
+			wantResults: []models.ScheduleResult{models.ScheduleSubmitted},
+		},
+		{
+			name:        "not yet due",
+			status:      models.StatusStopped,
+			schedules:   []models.Schedule{{Action: models.ScheduleStart, Cron: "0 10 * * *"}},
+			wantResults: []models.ScheduleResult{""},
+		},
+		{
+			name:        "already running",
+			status:      models.StatusRunning,
+			schedules:   []models.Schedule{{Action: models.ScheduleStart, Cron: "0 9 * * *"}},
+			wantResults: []models.ScheduleResult{models.ScheduleNoop},
+		},
+		{
+			name:        "skip date",
+			status:      models.StatusStopped,
+			schedules:   []models.Schedule{{Action: models.ScheduleStart, Cron: "0 9 * * *", SkipDates: []string{"2025-03-10"}}},
+			wantResults: []models.ScheduleResult{""},
+		},
+		{
+			name:        "time zone",
+			status:      models.StatusStopped,
+			schedules:   []models.Schedule{{Action: models.ScheduleStart, Cron: "0 10 * * MON", Timezone: "Europe/Berlin"}},
+			wantStarted: 1,
+			wantResults: []models.ScheduleResult{models.ScheduleSubmitted},
+		},
+		{
+			name:   "latest run wins",
+			status: models.StatusRunning,
+			schedules: []models.Schedule{
+				{Action: models.ScheduleStart, Cron: "45 8 * * *"},
+				{Action: models.ScheduleStop, Cron: "0 9 * * *"},
+			},
+			wantStopped: 1,
+			wantResults: []models.ScheduleResult{models.ScheduleSuperseded, models.ScheduleSubmitted},
+		},
+		{
+			name:   "stop wins at the same time",
+			status: models.StatusRunning,
+			schedules: []models.Schedule{
+				{Action: models.ScheduleStart, Cron: "0 9 * * *"},
+				{Action: models.ScheduleStop, Cron: "0 9 * * *"},
+			},
+			wantStopped: 1,
+			wantResults: []models.ScheduleResult{models.ScheduleSuperseded, models.ScheduleSubmitted},
+		},
+		{
+			name:   "runs older than the catch-up window are missed",
+			status: models.StatusStopped,
+			schedules: []models.Schedule{
+				{Action: models.ScheduleStart, Cron: "0 7 * * *", CheckedUntil: now.Add(-6 * time.Hour)},
+			},
+			wantResults: []models.ScheduleResult{models.ScheduleMissed},
+		},
+		{
+			name:   "catch up after downtime",
+			status: models.StatusRunning,
+			schedules: []models.Schedule{
+				{Action: models.ScheduleStop, Cron: "0 18 * * *", CheckedUntil: now.Add(-48 * time.Hour)},
+				{Action: models.ScheduleStart, Cron: "30 8 * * *", CheckedUntil: now.Add(-48 * time.Hour)},
+			},
+			wantResults: []models.ScheduleResult{models.ScheduleMissed, models.ScheduleNoop},
+		},
+		{
+			name:        "disabled",
+			status:      models.StatusStopped,
+			disabled:    true,
+			schedules:   []models.Schedule{{Action: models.ScheduleStart, Cron: "0 9 * * *"}},
+			wantResults: []models.ScheduleResult{""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryEnvironmentStore()
+			putScheduledWorkspace(t, store, tt.status)
+
+			schedules := NewMemoryScheduleStore()
+			for i, schedule := range tt.schedules {
+				schedule.ID = fmt.Sprintf("sched-%d", i)
+				schedule.WorkspaceID = "ws-1"
+				schedule.Enabled = !tt.disabled
+				if schedule.Timezone == "" {
+					schedule.Timezone = "UTC"
+				}
+				if schedule.CheckedUntil.IsZero() {
+					schedule.CheckedUntil = lastCheck
+				}
+				schedule.CreatedAt = now.Add(time.Duration(i-10) * time.Hour)
+				if err := schedules.Put(ctx, &schedule); err != nil {
+					t.Fatalf("Put() error = %v", err)
+				}
+			}
+
+			scheduler, started, stopped := newTestScheduler(schedules, store, cfg, now)
+			if err := scheduler.Check(ctx); err != nil {
+				t.Fatalf("Check() error = %v", err)
+			}
+			if len(*started) != tt.wantStarted || len(*stopped) != tt.wantStopped {
+				t.Errorf("started %v, stopped %v, want %d starts and %d stops", *started, *stopped, tt.wantStarted, tt.wantStopped)
+			}
+
+			for i, want := range tt.wantResults {
+				schedule, err := schedules.Get(ctx, fmt.Sprintf("sched-%d", i))
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				if schedule.LastResult != want {
+					t.Errorf("schedule %d LastResult = %q, want %q", i, schedule.LastResult, want)
+				}
+				if want == "" {
+					continue
+				}
+				if !schedule.CheckedUntil.Equal(now) {
+					t.Errorf("schedule %d CheckedUntil = %s, want %s", i, schedule.CheckedUntil, now)
+				}
+				if want == models.ScheduleSubmitted && schedule.LastOperationID == "" {
+					t.Errorf("schedule %d has no operation ID", i)
+				}
+				if schedule.NextRunAt == nil || !schedule.NextRunAt.After(now) {
+					t.Errorf("schedule %d NextRunAt = %v, want a run after %s", i, schedule.NextRunAt, now)
+				}
+			}
+		})
+	}
+}
+
+func TestScheduler_CheckSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	db, err := OpenBoltDB(filepath.Join(t.TempDir(), "agent.db"))
+	if err != nil {
+		t.Fatalf("OpenBoltDB() error = %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	schedules, err := NewBoltScheduleStore(db)
+	if err != nil {
+		t.Fatalf("NewBoltScheduleStore() error = %v", err)
+	}
+
+	store := NewMemoryEnvironmentStore()
+	putScheduledWorkspace(t, store, models.StatusStopped)
+	created := time.Date(2025, 3, 10, 8, 0, 0, 0, time.UTC)
+	schedule := &models.Schedule{
+		ID:           "sched-1",
+		WorkspaceID:  "ws-1",
+		Action:       models.ScheduleStart,
+		Cron:         "0 9 * * *",
+		Timezone:     "UTC",
+		Enabled:      true,
+		CheckedUntil: created,
+		CreatedAt:    created,
+		UpdatedAt:    created,
+	}
+	if err := schedules.Put(ctx, schedule); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	cfg := config.ScheduleConfig{CheckInterval: 30 * time.Second, CatchUpWindow: time.Hour}
+	now := time.Date(2025, 3, 10, 9, 0, 30, 0, time.UTC)
+	scheduler, started, _ := newTestScheduler(schedules, store, cfg, now)
+	if err := scheduler.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(*started) != 1 {
+		t.Fatalf("started %v, want 1 start", *started)
+	}
+
+	// A new scheduler over the same store does not repeat the run
+	restarted, startedAgain, _ := newTestScheduler(schedules, store, cfg, now.Add(time.Minute))
+	if err := restarted.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if len(*startedAgain) != 0 {
+		t.Errorf("started %v after restart, want no start", *startedAgain)
+	}
+
+	got, err := schedules.Get(ctx, "sched-1")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got.LastResult != models.ScheduleSubmitted || got.LastOperationID != "op-start-1" {
+		t.Errorf("LastResult = %q, LastOperationID = %q, want submitted op-start-1", got.LastResult, got.LastOperationID)
+	}
+}
+
+// isAppErrorCode reports whether err is an AppError with the given code
+func isAppErrorCode(err error, code string) bool {
+	var appErr *models.AppError
+	return errors.As(err, &appErr) && appErr.Code == code
+}
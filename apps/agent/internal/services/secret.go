@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// SecretStore keeps the secrets of each workspace between starts, so the control
+// plane sends them once at create time. Implementations must be safe for concurrent use.
+type SecretStore interface {
+	// Put saves the secrets of a workspace, replacing earlier ones, and returns their reference
+	Put(ctx context.Context, workspaceID string, secrets *models.WorkspaceSecrets) (string, error)
+	// Get resolves a reference returned by Put, or returns a NOT_FOUND AppError
+	Get(ctx context.Context, ref string) (*models.WorkspaceSecrets, error)
+	// Delete removes the secrets behind ref. Deleting missing secrets is not an error.
+	Delete(ctx context.Context, ref string) error
+}
+
+// NewSecretStore creates the configured secret store. The "none" backend returns
+// nil: nothing is saved and every request must carry the secrets it needs.
+func NewSecretStore(cfg *config.Config) (SecretStore, error) {
+	switch cfg.SecretBackend() {
+	case "none":
+		return nil, nil
+	case "keyvault":
+		// The vault is not tied to a workspace region, so it has a circuit breaker of its own
+		client, err := azure.NewKeyVaultClient(cfg.Secrets.KeyVaultURL, azure.NewRetrier(cfg.Azure.Retry), "keyvault")
+		if err != nil {
+			return nil, err
+		}
+		return &keyVaultSecrets{client: client}, nil
+	case "file":
+		return newFileSecrets(cfg.Secrets.FilePath, cfg.Secrets.FileKey)
+	default:
+		return nil, fmt.Errorf("invalid secret store: %s", cfg.SecretBackend())
+	}
+}
+
+// SetSecretStore saves workspace secrets in store; nil disables saving
+func (s *EnvironmentService) SetSecretStore(store SecretStore) {
+	s.secrets = store
+}
+
+// GetSecrets lists the saved secrets of a workspace without their values
+func (s *EnvironmentService) GetSecrets(ctx context.Context, workspaceID string) (*models.SecretsInfo, error) {
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := s.loadSecrets(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+	return secretsInfo(env, secrets), nil
+}
+
+// UpdateSecrets replaces the saved secrets with the values set in update, e.g. to
+// rotate a token. Running containers get the new values when next recreated.
+func (s *EnvironmentService) UpdateSecrets(ctx context.Context, workspaceID string, update *models.WorkspaceSecrets) (*models.SecretsInfo, error) {
+	if s.secrets == nil {
+		return nil, models.ErrInvalidRequest("the agent has no secret store (SECRET_STORE=none)")
+	}
+	if update.IsEmpty() {
+		return nil, models.ErrInvalidRequest("at least one secret is required")
+	}
+	// A credential rotation saves secrets under the same lock, so neither overwrites the other
+	ctx, unlock, err := s.lockWorkspace(ctx, workspaceID, models.OperationSecrets)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	for name := range update.EnvVars {
+		if !hasSecretEnvVar(env, name) {
+			return nil, models.ErrInvalidRequest(fmt.Sprintf("envVars: %s is not a secret environment variable of workspace %s", name, workspaceID))
+		}
+	}
+
+	secrets, err := s.loadSecrets(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+	if secrets.Merge(update) {
+		if err := s.saveSecrets(ctx, env, secrets); err != nil {
+			return nil, err
+		}
+		env.UpdatedAt = time.Now()
+		s.saveEnvironment(ctx, env)
+		log.Printf("🔑 Secrets of workspace %s updated", workspaceID)
+	}
+	return secretsInfo(env, secrets), nil
+}
+
+// DeleteSecrets removes the saved secrets of a workspace
+func (s *EnvironmentService) DeleteSecrets(ctx context.Context, workspaceID string) error {
+	ctx, unlock, err := s.lockWorkspace(ctx, workspaceID, models.OperationSecrets)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	if env.SecretRef == "" {
+		return nil
+	}
+	if s.secrets == nil {
+		return models.ErrInvalidRequest("the agent has no secret store (SECRET_STORE=none)")
+	}
+	if err := s.secrets.Delete(ctx, env.SecretRef); err != nil {
+		return cloudError(fmt.Sprintf("workspace %s: failed to delete secrets", workspaceID), err)
+	}
+
+	env.SecretRef = ""
+	env.SecretsUpdatedAt = nil
+	env.UpdatedAt = time.Now()
+	s.saveEnvironment(ctx, env)
+	log.Printf("🔑 Secrets of workspace %s deleted", workspaceID)
+	return nil
+}
+
+// saveSecrets saves secrets for env and records their reference on it; the caller
+// persists env. Empty secrets are only saved over earlier ones.
+func (s *EnvironmentService) saveSecrets(ctx context.Context, env *models.Environment, secrets *models.WorkspaceSecrets) error {
+	if s.secrets == nil || (secrets.IsEmpty() && env.SecretRef == "") {
+		return nil
+	}
+
+	ref, err := s.secrets.Put(ctx, env.ID, secrets)
+	if err != nil {
+		return cloudError(fmt.Sprintf("workspace %s: failed to save secrets", env.ID), err)
+	}
+	now := time.Now()
+	env.SecretRef = ref
+	env.SecretsUpdatedAt = &now
+	return nil
+}
+
+// loadSecrets returns the saved secrets of env, empty when none are saved
+func (s *EnvironmentService) loadSecrets(ctx context.Context, env *models.Environment) (*models.WorkspaceSecrets, error) {
+	if s.secrets == nil || env.SecretRef == "" {
+		return &models.WorkspaceSecrets{}, nil
+	}
+
+	secrets, err := s.secrets.Get(ctx, env.SecretRef)
+	var appErr *models.AppError
+	switch {
+	case errors.As(err, &appErr) && appErr.Code == "NOT_FOUND":
+		log.Printf("Warning: workspace %s: saved secrets %s not found", env.ID, env.SecretRef)
+		return &models.WorkspaceSecrets{}, nil
+	case err != nil:
+		return nil, cloudError(fmt.Sprintf("workspace %s: failed to read secrets", env.ID), err)
+	}
+	return secrets, nil
+}
+
+// resolveSecrets returns the saved secrets of env with the values sent in a request
+// on top. Sent values are saved so later requests can leave them out; so is a
+// generated code-server password when none is known.
+func (s *EnvironmentService) resolveSecrets(ctx context.Context, env *models.Environment, sent *models.WorkspaceSecrets) (*models.WorkspaceSecrets, error) {
+	secrets, err := s.loadSecrets(ctx, env)
+	if err != nil {
+		return nil, err
+	}
+	changed := secrets.Merge(sent)
+	if secrets.CodeServerPassword == "" {
+		secrets.CodeServerPassword = generatePassword()
+		changed = true
+	}
+	if changed {
+		if err := s.saveSecrets(ctx, env, secrets); err != nil {
+			// The container still gets the values sent; only saving them failed
+			log.Printf("Warning: %v", err)
+		}
+	}
+	return secrets, nil
+}
+
+// deleteSavedSecrets removes the saved secrets of a workspace being deleted
+func (s *EnvironmentService) deleteSavedSecrets(ctx context.Context, workspaceID string) {
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil || env.SecretRef == "" || s.secrets == nil {
+		return
+	}
+	if err := s.secrets.Delete(ctx, env.SecretRef); err != nil {
+		log.Printf("Warning: workspace %s: failed to delete secrets %s: %v", workspaceID, env.SecretRef, err)
+		return
+	}
+	log.Printf("✅ Deleted secrets: %s", env.SecretRef)
+}
+
+// hasSecretEnvVar reports whether env declares name as a secret environment variable
+func hasSecretEnvVar(env *models.Environment, name string) bool {
+	for _, v := range env.EnvVars {
+		if v.Name == name {
+			return v.Secret
+		}
+	}
+	return false
+}
+
+func secretsInfo(env *models.Environment, secrets *models.WorkspaceSecrets) *models.SecretsInfo {
+	return &models.SecretsInfo{
+		WorkspaceID: env.ID,
+		SecretRef:   env.SecretRef,
+		Keys:        secrets.Keys(),
+		UpdatedAt:   env.SecretsUpdatedAt,
+	}
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// fileSecrets keeps workspace secrets in a local JSON file, each workspace's entry
+// sealed with AES-256-GCM. It is meant for development; the reference of an entry
+// is the workspace ID.
+type fileSecrets struct {
+	mu   sync.Mutex
+	path string
+	aead cipher.AEAD
+}
+
+// newFileSecrets opens the secrets file at path. An empty key uses the key file next
+// to it, generating one on first use.
+func newFileSecrets(path, key string) (*fileSecrets, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+
+	if key == "" {
+		var err error
+		if key, err = loadOrCreateSecretKey(path + ".key"); err != nil {
+			return nil, err
+		}
+	}
+	rawKey, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(rawKey) != 32 {
+		return nil, fmt.Errorf("secret store key must be a base64-encoded 32-byte key")
+	}
+
+	block, err := aes.NewCipher(rawKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSecrets{path: path, aead: aead}, nil
+}
+
+// loadOrCreateSecretKey reads the base64 key in path, writing a random one if the file does not exist
+func loadOrCreateSecretKey(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return string(data), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read secret store key: %w", err)
+	}
+
+	rawKey := make([]byte, 32)
+	if _, err := rand.Read(rawKey); err != nil {
+		return "", err
+	}
+	key := base64.StdEncoding.EncodeToString(rawKey)
+	if err := os.WriteFile(path, []byte(key), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write secret store key: %w", err)
+	}
+	return key, nil
+}
+
+// Put seals the secrets of a workspace into the file
+func (f *fileSecrets) Put(ctx context.Context, workspaceID string, secrets *models.WorkspaceSecrets) (string, error) {
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, f.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	// The workspace ID is authenticated with the entry, so entries cannot be swapped
+	sealed := f.aead.Seal(nonce, nonce, plaintext, []byte(workspaceID))
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := f.load()
+	if err != nil {
+		return "", err
+	}
+	entries[workspaceID] = base64.StdEncoding.EncodeToString(sealed)
+	if err := f.save(entries); err != nil {
+		return "", err
+	}
+	return workspaceID, nil
+}
+
+// Get opens the entry of a workspace
+func (f *fileSecrets) Get(ctx context.Context, ref string) (*models.WorkspaceSecrets, error) {
+	f.mu.Lock()
+	entries, err := f.load()
+	f.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := entries[ref]
+	if !ok {
+		return nil, models.ErrNotFound(fmt.Sprintf("secrets %s not found", ref))
+	}
+	sealed, err := base64.StdEncoding.DecodeString(entry)
+	if err != nil || len(sealed) < f.aead.NonceSize() {
+		return nil, fmt.Errorf("secrets %s are corrupt", ref)
+	}
+	nonce, ciphertext := sealed[:f.aead.NonceSize()], sealed[f.aead.NonceSize():]
+	plaintext, err := f.aead.Open(nil, nonce, ciphertext, []byte(ref))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secrets %s (wrong key?): %w", ref, err)
+	}
+
+	secrets := &models.WorkspaceSecrets{}
+	if err := json.Unmarshal(plaintext, secrets); err != nil {
+		return nil, fmt.Errorf("secrets %s are corrupt: %w", ref, err)
+	}
+	return secrets, nil
+}
+
+// Delete removes the entry of a workspace
+func (f *fileSecrets) Delete(ctx context.Context, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entries, err := f.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := entries[ref]; !ok {
+		return nil
+	}
+	delete(entries, ref)
+	return f.save(entries)
+}
+
+// load reads every sealed entry; the caller holds f.mu
+func (f *fileSecrets) load() (map[string]string, error) {
+	entries := make(map[string]string)
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return entries, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets file: %w", err)
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse secrets file %s: %w", f.path, err)
+	}
+	return entries, nil
+}
+
+// save replaces the file atomically; the caller holds f.mu
+func (f *fileSecrets) save(entries map[string]string) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to write secrets file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// keyVaultSecrets keeps the secrets of each workspace as one JSON-valued Key Vault
+// secret named ws-{id}, which is also the reference
+type keyVaultSecrets struct {
+	client *azure.KeyVaultClient
+}
+
+// Put writes the secrets as a new version of the workspace's secret
+func (k *keyVaultSecrets) Put(ctx context.Context, workspaceID string, secrets *models.WorkspaceSecrets) (string, error) {
+	value, err := json.Marshal(secrets)
+	if err != nil {
+		return "", err
+	}
+
+	name := keyVaultSecretName(workspaceID)
+	tags := map[string]string{"managed-by": "dev8-agent", "workspace-id": workspaceID}
+	if _, err := k.client.SetSecret(ctx, name, string(value), "application/json", tags); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// Get reads the current version of a workspace's secret
+func (k *keyVaultSecrets) Get(ctx context.Context, ref string) (*models.WorkspaceSecrets, error) {
+	value, err := k.client.GetSecret(ctx, ref)
+	if azure.IsNotFound(err) {
+		return nil, models.ErrNotFound(fmt.Sprintf("secret %s not found", ref))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := &models.WorkspaceSecrets{}
+	if err := json.Unmarshal([]byte(value), secrets); err != nil {
+		return nil, fmt.Errorf("secret %s is not a workspace secret: %w", ref, err)
+	}
+	return secrets, nil
+}
+
+// Delete deletes a workspace's secret with all its versions
+func (k *keyVaultSecrets) Delete(ctx context.Context, ref string) error {
+	return k.client.DeleteSecret(ctx, ref)
+}
+
+// keyVaultSecretName derives a secret name (alphanumerics and '-', at most 127
+// characters) from a workspace ID
+func keyVaultSecretName(workspaceID string) string {
+	name := "ws-" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' {
+			return r
+		}
+		return '-'
+	}, workspaceID)
+	if len(name) > 127 {
+		name = name[:127]
+	}
+	return name
+}
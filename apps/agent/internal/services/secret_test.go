@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+func TestFileSecrets(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "secrets.json")
+	store, err := newFileSecrets(path, "")
+	if err != nil {
+		t.Fatalf("newFileSecrets() error = %v", err)
+	}
+
+	ref, err := store.Put(ctx, "ws-1", &models.WorkspaceSecrets{GitHubToken: "ghp_secret", EnvVars: map[string]string{"API_TOKEN": "t0k3n"}})
+	if err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "ghp_secret") || strings.Contains(string(data), "t0k3n") {
+		t.Error("secrets file holds plaintext values")
+	}
+
+	// A second store with the generated key reads the same entries
+	reopened, err := newFileSecrets(path, "")
+	if err != nil {
+		t.Fatalf("newFileSecrets() reopen error = %v", err)
+	}
+	got, err := reopened.Get(ctx, ref)
+	if err != nil || got.GitHubToken != "ghp_secret" || got.EnvVars["API_TOKEN"] != "t0k3n" {
+		t.Errorf("Get() = %+v, %v", got, err)
+	}
+
+	otherKey, err := newFileSecrets(path, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	if err != nil {
+		t.Fatalf("newFileSecrets() with key error = %v", err)
+	}
+	if _, err := otherKey.Get(ctx, ref); err == nil {
+		t.Error("Get() with the wrong key error = nil")
+	}
+
+	if err := store.Delete(ctx, ref); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	var appErr *models.AppError
+	if _, err := store.Get(ctx, ref); !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+		t.Errorf("Get() after delete error = %v, want NOT_FOUND", err)
+	}
+	if err := store.Delete(ctx, ref); err != nil {
+		t.Errorf("Delete() of missing secrets error = %v", err)
+	}
+}
+
+func TestKeyVaultSecretName(t *testing.T) {
+	if got := keyVaultSecretName("550e8400-e29b-41d4-a716-446655440000"); got != "ws-550e8400-e29b-41d4-a716-446655440000" {
+		t.Errorf("keyVaultSecretName() = %q", got)
+	}
+	if got := keyVaultSecretName("ws_1.a"); got != "ws-ws-1-a" {
+		t.Errorf("keyVaultSecretName() = %q, want ws-ws-1-a", got)
+	}
+}
+
+func TestEnvironmentService_SavedSecrets(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, provider, _ := newTestEnvironmentService(t, store)
+	secrets, err := newFileSecrets(filepath.Join(t.TempDir(), "secrets.json"), "")
+	if err != nil {
+		t.Fatalf("newFileSecrets() error = %v", err)
+	}
+	service.SetSecretStore(secrets)
+
+	_, err = service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID:        wsID,
+		UserID:             "user-1",
+		Name:               "test-env",
+		CloudRegion:        "eastus",
+		CPUCores:           2,
+		MemoryGB:           4,
+		StorageGB:          10,
+		GitHubToken:        "ghp_one",
+		CodeServerPassword: "pw-one",
+		EnvVars:            []models.EnvVar{{Name: "API_TOKEN", Value: "t0k3n", Secret: true}},
+	})
+	if err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	env, _ := store.Get(ctx, wsID)
+	if env.SecretRef == "" || env.SecretsUpdatedAt == nil {
+		t.Fatalf("created env secret ref = %q, want saved secrets", env.SecretRef)
+	}
+
+	// A start without secrets deploys the saved ones; sent values replace them
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	started, err := service.StartEnvironment(ctx, &models.StartEnvironmentRequest{
+		WorkspaceID:        wsID,
+		UserID:             "user-1",
+		Name:               "test-env",
+		CloudRegion:        "eastus",
+		CPUCores:           2,
+		MemoryGB:           4,
+		CodeServerPassword: "pw-two",
+	})
+	if err != nil {
+		t.Fatalf("StartEnvironment() error = %v", err)
+	}
+	spec, _ := provider.Spec(wsID)
+	if spec.GitHubToken != "ghp_one" || spec.CodeServerPassword != "pw-two" || len(spec.EnvVars) != 1 || spec.EnvVars[0].Value != "t0k3n" {
+		t.Errorf("started spec = token %q, password %q, env vars %+v", spec.GitHubToken, spec.CodeServerPassword, spec.EnvVars)
+	}
+	if started.SecretRef != env.SecretRef || started.ConnectionURLs.CodeServerPassword != "pw-two" {
+		t.Errorf("started env secret ref = %q, password = %q", started.SecretRef, started.ConnectionURLs.CodeServerPassword)
+	}
+
+	info, err := service.UpdateSecrets(ctx, wsID, &models.WorkspaceSecrets{GitHubToken: "ghp_two", EnvVars: map[string]string{"API_TOKEN": "r0tated"}})
+	if err != nil {
+		t.Fatalf("UpdateSecrets() error = %v", err)
+	}
+	want := []string{"codeServerPassword", "envVars.API_TOKEN", "githubToken"}
+	if strings.Join(info.Keys, ",") != strings.Join(want, ",") {
+		t.Errorf("UpdateSecrets() keys = %v, want %v", info.Keys, want)
+	}
+	saved, _ := secrets.Get(ctx, env.SecretRef)
+	if saved.GitHubToken != "ghp_two" || saved.CodeServerPassword != "pw-two" || saved.EnvVars["API_TOKEN"] != "r0tated" {
+		t.Errorf("saved secrets after update = %+v", saved)
+	}
+	if _, err := service.UpdateSecrets(ctx, wsID, &models.WorkspaceSecrets{EnvVars: map[string]string{"NODE_ENV": "x"}}); err == nil {
+		t.Error("UpdateSecrets() of an undeclared env var error = nil")
+	}
+
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	if err := service.DeleteEnvironment(ctx, wsID, "eastus", false); err != nil {
+		t.Fatalf("DeleteEnvironment() error = %v", err)
+	}
+	if _, err := secrets.Get(ctx, env.SecretRef); err == nil {
+		t.Error("secrets survived delete")
+	}
+}
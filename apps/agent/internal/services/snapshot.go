@@ -0,0 +1,469 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+)
+
+// CreateSnapshot takes a point-in-time snapshot of a workspace volume and then
+// applies the workspace's retention policy
+func (s *EnvironmentService) CreateSnapshot(ctx context.Context, req *models.CreateSnapshotRequest) (*models.SnapshotInfo, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, unlock, err := s.lockWorkspace(ctx, req.WorkspaceID, models.OperationSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	env, err := s.store.Get(ctx, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if env.Status != models.StatusRunning && env.Status != models.StatusStopped {
+		return nil, models.ErrConflict(fmt.Sprintf("workspace %s is %s; only running or stopped workspaces can be snapshotted", env.ID, env.Status))
+	}
+	snapshotter, err := s.snapshotterFor(env)
+	if err != nil {
+		return nil, err
+	}
+
+	fileShareName := fmt.Sprintf("fs-%s", env.ID)
+	snapshot, err := snapshotter.CreateSnapshot(ctx, fileShareName, req.Label)
+	if errors.Is(err, ErrVolumeNotFound) {
+		return nil, models.ErrNotFound(fmt.Sprintf("workspace %s: volume %s not found", env.ID, fileShareName))
+	}
+	if err != nil {
+		return nil, cloudError(fmt.Sprintf("workspace %s: failed to create snapshot", env.ID), err)
+	}
+	log.Printf("📸 Snapshot %s taken of workspace %s", snapshot.ID, env.ID)
+
+	snapshots, err := pruneSnapshots(ctx, snapshotter, fileShareName, s.snapshotRetentionFor(env), time.Now())
+	if err != nil {
+		// The snapshot exists; retention is applied again on the next change
+		log.Printf("Warning: workspace %s: failed to apply snapshot retention: %v", env.ID, err)
+	} else {
+		s.recordSnapshots(ctx, env.ID, snapshots)
+	}
+
+	info := snapshotInfo(*snapshot)
+	s.publish(ctx, webhook.EventSnapshotted, env.ID, map[string]interface{}{
+		"workspaceId": env.ID,
+		"snapshot":    info,
+	})
+	return &info, nil
+}
+
+// ListSnapshots lists the snapshots of a workspace volume, oldest first
+func (s *EnvironmentService) ListSnapshots(ctx context.Context, workspaceID string) ([]models.SnapshotInfo, error) {
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	snapshotter, err := s.snapshotterFor(env)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots, err := snapshotter.ListSnapshots(ctx, fmt.Sprintf("fs-%s", workspaceID))
+	if err != nil {
+		return nil, cloudError(fmt.Sprintf("workspace %s: failed to list snapshots", workspaceID), err)
+	}
+	return s.recordSnapshots(ctx, workspaceID, snapshots), nil
+}
+
+// DeleteSnapshot deletes one snapshot of a workspace volume
+func (s *EnvironmentService) DeleteSnapshot(ctx context.Context, workspaceID, snapshotID string) error {
+	ctx, unlock, err := s.lockWorkspace(ctx, workspaceID, models.OperationSnapshot)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	snapshotter, err := s.snapshotterFor(env)
+	if err != nil {
+		return err
+	}
+
+	fileShareName := fmt.Sprintf("fs-%s", workspaceID)
+	if err := snapshotter.DeleteSnapshot(ctx, fileShareName, snapshotID); err != nil {
+		if errors.Is(err, ErrSnapshotNotFound) {
+			return models.ErrNotFound(fmt.Sprintf("workspace %s: snapshot %s not found", workspaceID, snapshotID))
+		}
+		return cloudError(fmt.Sprintf("workspace %s: failed to delete snapshot %s", workspaceID, snapshotID), err)
+	}
+	log.Printf("🗑️  Deleted snapshot %s of workspace %s", snapshotID, workspaceID)
+
+	if snapshots, err := snapshotter.ListSnapshots(ctx, fileShareName); err == nil {
+		s.recordSnapshots(ctx, workspaceID, snapshots)
+	}
+	return nil
+}
+
+// SetSnapshotRetention replaces the retention policy of a workspace and applies it immediately.
+// A nil policy reverts to the agent default.
+func (s *EnvironmentService) SetSnapshotRetention(ctx context.Context, workspaceID string, retention *models.SnapshotRetention) (*models.Environment, error) {
+	if retention != nil {
+		if err := retention.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	ctx, unlock, err := s.lockWorkspace(ctx, workspaceID, models.OperationSnapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	env, err := s.store.Get(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	snapshotter, err := s.snapshotterFor(env)
+	if err != nil {
+		return nil, err
+	}
+
+	env.SnapshotRetention = retention
+	env.UpdatedAt = time.Now()
+	s.saveEnvironment(ctx, env)
+
+	snapshots, err := pruneSnapshots(ctx, snapshotter, fmt.Sprintf("fs-%s", workspaceID), s.snapshotRetentionFor(env), time.Now())
+	if err != nil {
+		return nil, cloudError(fmt.Sprintf("workspace %s: failed to apply snapshot retention", workspaceID), err)
+	}
+	env.Snapshots = s.recordSnapshots(ctx, workspaceID, snapshots)
+	return env, nil
+}
+
+// RestoreSnapshotAsync validates the request and runs RestoreSnapshot in the background
+func (s *EnvironmentService) RestoreSnapshotAsync(ctx context.Context, req *models.RestoreSnapshotRequest) (*models.Operation, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := s.checkRestorable(ctx, req); err != nil {
+		return nil, err
+	}
+
+	return s.submitLocked(ctx, models.OperationRestore, req.WorkspaceID, func(ctx context.Context) (interface{}, error) {
+		return s.RestoreSnapshot(ctx, req)
+	})
+}
+
+// RestoreSnapshot restores a workspace snapshot. In-place restores roll the stopped
+// workspace's own volume back; new-share restores create a new stopped workspace
+// whose volume holds the snapshot, leaving the source untouched.
+// It returns the restored workspace.
+func (s *EnvironmentService) RestoreSnapshot(ctx context.Context, req *models.RestoreSnapshotRequest) (*models.Environment, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	ctx, unlock, err := s.lockWorkspace(ctx, req.WorkspaceID, models.OperationRestore)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	env, err := s.checkRestorable(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Mode == models.RestoreNewShare {
+		return s.restoreToNewShare(ctx, env, req)
+	}
+
+	place := s.placementFor(env.CloudProvider, env.CloudRegion)
+	snapshotter := place.volumes.(VolumeSnapshotter)
+	fileShareName := fmt.Sprintf("fs-%s", env.ID)
+
+	log.Printf("⏪ Restoring workspace %s in place from snapshot %s", env.ID, req.SnapshotID)
+	s.setStatus(ctx, env.ID, env.CloudRegion, models.StatusRestoring)
+
+	reportProgress(ctx, "restoring-files", 10)
+	err = snapshotter.RestoreSnapshot(ctx, fileShareName, req.SnapshotID, fileShareName, filesCopiedProgress(ctx, "restoring-files", 50))
+	if errors.Is(err, ErrSnapshotNotFound) {
+		// Nothing was copied yet
+		s.setStatus(ctx, env.ID, env.CloudRegion, models.StatusStopped)
+		return nil, models.ErrNotFound(fmt.Sprintf("workspace %s: snapshot %s not found", env.ID, req.SnapshotID))
+	}
+	if err != nil {
+		return nil, s.failEnvironment(ctx, env.ID, cloudError(fmt.Sprintf("workspace %s: failed to restore snapshot %s", env.ID, req.SnapshotID), err))
+	}
+
+	env, err = s.store.Get(ctx, env.ID)
+	if err != nil {
+		return nil, err
+	}
+	env.Status = models.StatusStopped
+	env.StatusMessage = ""
+	env.UpdatedAt = time.Now()
+	s.saveEnvironment(ctx, env)
+	s.publish(ctx, webhook.EventRestored, env.ID, map[string]interface{}{
+		"workspaceId": env.ID,
+		"snapshotId":  req.SnapshotID,
+		"mode":        req.Mode,
+	})
+
+	log.Printf("✅ Workspace %s restored from snapshot %s", env.ID, req.SnapshotID)
+	return env, nil
+}
+
+// restoreToNewShare creates the target workspace's volume from a snapshot of env,
+// holding the target's lock so two restores into one new ID cannot race. On failure
+// the partially restored volume and record are removed.
+func (s *EnvironmentService) restoreToNewShare(ctx context.Context, env *models.Environment, req *models.RestoreSnapshotRequest) (*models.Environment, error) {
+	ctx, unlock, err := s.lockWorkspace(ctx, req.TargetWorkspaceID, models.OperationRestore)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	// Another restore may have created the target since the request was checked
+	if _, err := s.checkRestorable(ctx, req); err != nil {
+		return nil, err
+	}
+
+	place := s.placementFor(env.CloudProvider, env.CloudRegion)
+	volumes := place.volumes
+	snapshotter := volumes.(VolumeSnapshotter)
+	sourceShare := fmt.Sprintf("fs-%s", env.ID)
+	targetID := req.TargetWorkspaceID
+	targetShare := fmt.Sprintf("fs-%s", targetID)
+
+	name := req.Name
+	if name == "" {
+		name = env.Name
+	}
+	quotaGB := volumeQuotaGB(env.StorageGB)
+	if props, err := volumes.VolumeProperties(ctx, sourceShare); err == nil && props.QuotaGB > quotaGB {
+		quotaGB = props.QuotaGB
+	}
+
+	// The new workspace runs under the tier of the source, like a clone, but is
+	// created stopped
+	release, err := s.policy.Admit(ctx, PolicyRequest{
+		WorkspaceID: targetID,
+		UserID:      env.UserID,
+		OrgID:       env.OrgID,
+		Tier:        env.Tier,
+		Region:      env.CloudRegion,
+		Image:       env.BaseImage,
+		CPUCores:    env.CPUCores,
+		MemoryGB:    env.MemoryGB,
+		StorageGB:   env.StorageGB,
+		NewStorage:  true,
+		Stopped:     true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	log.Printf("⏪ Restoring snapshot %s of workspace %s to new workspace %s", req.SnapshotID, env.ID, targetID)
+
+	now := time.Now()
+	target := &models.Environment{
+		ID:                 targetID,
+		UserID:             env.UserID,
+		Name:               name,
+		Status:             models.StatusRestoring,
+		CloudProvider:      env.CloudProvider,
+		CloudRegion:        env.CloudRegion,
+		CPUCores:           env.CPUCores,
+		MemoryGB:           env.MemoryGB,
+		StorageGB:          env.StorageGB,
+		BaseImage:          env.BaseImage,
+		AzureResourceGroup: env.AzureResourceGroup,
+		AzureFileShare:     targetShare,
+		OrgID:              env.OrgID,
+		Tier:               env.Tier,
+		IdleTimeoutMinutes: env.IdleTimeoutMinutes,
+		SnapshotRetention:  env.SnapshotRetention,
+		RestoredFrom:       fmt.Sprintf("%s@%s", env.ID, req.SnapshotID),
+		CreatedAt:          now,
+		UpdatedAt:          now,
+	}
+	s.saveEnvironment(ctx, target)
+
+	cleanup := func(cause error) error {
+		if err := volumes.DeleteVolume(context.WithoutCancel(ctx), targetShare); err != nil {
+			log.Printf("Warning: workspace %s: failed to delete partially restored volume %s: %v", targetID, targetShare, err)
+		}
+		s.usage.Close(context.WithoutCancel(ctx), targetID)
+		if err := s.store.Delete(context.WithoutCancel(ctx), targetID); err != nil {
+			log.Printf("Warning: workspace %s: failed to remove environment record: %v", targetID, err)
+		}
+		return cause
+	}
+
+	reportProgress(ctx, "creating-volume", 10)
+	if err := volumes.CreateVolume(ctx, targetShare, quotaGB); err != nil {
+		return nil, cleanup(cloudError(fmt.Sprintf("workspace %s: failed to create volume", targetID), err))
+	}
+	if err := s.waitForFileShareAvailability(ctx, volumes, targetShare, 30*time.Second); err != nil {
+		return nil, cleanup(cloudError(fmt.Sprintf("workspace %s: volume not available after creation", targetID), err))
+	}
+
+	reportProgress(ctx, "restoring-files", 20)
+	err = snapshotter.RestoreSnapshot(ctx, sourceShare, req.SnapshotID, targetShare, filesCopiedProgress(ctx, "restoring-files", 50))
+	if errors.Is(err, ErrSnapshotNotFound) {
+		return nil, cleanup(models.ErrNotFound(fmt.Sprintf("workspace %s: snapshot %s not found", env.ID, req.SnapshotID)))
+	}
+	if err != nil {
+		return nil, cleanup(cloudError(fmt.Sprintf("workspace %s: failed to restore snapshot %s", targetID, req.SnapshotID), err))
+	}
+
+	target.Status = models.StatusStopped
+	target.UpdatedAt = time.Now()
+	s.saveEnvironment(ctx, target)
+	s.publish(ctx, webhook.EventRestored, targetID, map[string]interface{}{
+		"workspaceId":       targetID,
+		"sourceWorkspaceId": env.ID,
+		"snapshotId":        req.SnapshotID,
+		"mode":              req.Mode,
+	})
+
+	log.Printf("✅ Workspace %s restored from snapshot %s of %s", targetID, req.SnapshotID, env.ID)
+	return target, nil
+}
+
+// checkRestorable returns the source workspace if the restore can start
+func (s *EnvironmentService) checkRestorable(ctx context.Context, req *models.RestoreSnapshotRequest) (*models.Environment, error) {
+	env, err := s.store.Get(ctx, req.WorkspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.snapshotterFor(env); err != nil {
+		return nil, err
+	}
+
+	if req.Mode == models.RestoreInPlace {
+		if env.Status != models.StatusStopped {
+			return nil, models.ErrConflict(fmt.Sprintf("workspace %s is %s; stop it before restoring in place", env.ID, env.Status))
+		}
+		return env, nil
+	}
+
+	if env.Status != models.StatusRunning && env.Status != models.StatusStopped {
+		return nil, models.ErrConflict(fmt.Sprintf("workspace %s is %s; only running or stopped workspaces can be restored from", env.ID, env.Status))
+	}
+	if _, err := s.store.Get(ctx, req.TargetWorkspaceID); err == nil {
+		return nil, models.ErrConflict(fmt.Sprintf("workspace %s already exists", req.TargetWorkspaceID))
+	}
+	place := s.placementFor(env.CloudProvider, env.CloudRegion)
+	exists, err := place.volumes.VolumeExists(ctx, fmt.Sprintf("fs-%s", req.TargetWorkspaceID))
+	if err != nil {
+		return nil, cloudError(fmt.Sprintf("workspace %s: failed to check volume", req.TargetWorkspaceID), err)
+	}
+	if exists {
+		return nil, models.ErrConflict(fmt.Sprintf("workspace %s already has a volume", req.TargetWorkspaceID))
+	}
+	return env, nil
+}
+
+// snapshotterFor returns the volume store of a workspace if it supports snapshots
+func (s *EnvironmentService) snapshotterFor(env *models.Environment) (VolumeSnapshotter, error) {
+	place := s.placementFor(env.CloudProvider, env.CloudRegion)
+	if place == nil {
+		return nil, models.ErrNotFound(regionUnavailable(env.CloudProvider, env.CloudRegion))
+	}
+	if place.volumes == nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("volume store not found for region %s", env.CloudRegion))
+	}
+	snapshotter, ok := place.volumes.(VolumeSnapshotter)
+	if !ok {
+		return nil, models.ErrInvalidRequest(fmt.Sprintf("workspace %s: its volume backend does not support snapshots", env.ID))
+	}
+	return snapshotter, nil
+}
+
+// snapshotRetentionFor returns the workspace's retention policy, or the agent default
+func (s *EnvironmentService) snapshotRetentionFor(env *models.Environment) models.SnapshotRetention {
+	if env.SnapshotRetention != nil {
+		return *env.SnapshotRetention
+	}
+	return models.SnapshotRetention{
+		MaxCount:   s.config.Snapshots.MaxCount,
+		MaxAgeDays: s.config.Snapshots.MaxAgeDays,
+	}
+}
+
+// recordSnapshots stores the current snapshot list on the workspace record and returns it.
+// Only the list is written, in one store update, so listings running alongside a
+// lifecycle operation never write back the status they read.
+func (s *EnvironmentService) recordSnapshots(ctx context.Context, workspaceID string, snapshots []VolumeSnapshot) []models.SnapshotInfo {
+	infos := make([]models.SnapshotInfo, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		infos = append(infos, snapshotInfo(snapshot))
+	}
+
+	_, err := s.store.Update(ctx, workspaceID, func(env *models.Environment) bool {
+		env.Snapshots = infos
+		return true
+	})
+	var appErr *models.AppError
+	if err != nil && !(errors.As(err, &appErr) && appErr.Code == "NOT_FOUND") {
+		log.Printf("Warning: workspace %s: failed to record snapshots: %v", workspaceID, err)
+	}
+	return infos
+}
+
+// pruneSnapshots deletes the snapshots of volume name that retention expires and returns the rest
+func pruneSnapshots(ctx context.Context, snapshotter VolumeSnapshotter, name string, retention models.SnapshotRetention, now time.Time) ([]VolumeSnapshot, error) {
+	snapshots, err := snapshotter.ListSnapshots(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	expired := expiredSnapshots(snapshots, retention, now)
+	kept := make([]VolumeSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if !expired[snapshot.ID] {
+			kept = append(kept, snapshot)
+			continue
+		}
+		if err := snapshotter.DeleteSnapshot(ctx, name, snapshot.ID); err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+			return nil, fmt.Errorf("failed to delete expired snapshot %s: %w", snapshot.ID, err)
+		}
+		log.Printf("🗑️  Snapshot %s of %s expired by retention policy", snapshot.ID, name)
+	}
+	return kept, nil
+}
+
+// expiredSnapshots returns the IDs of the snapshots, sorted oldest first, that retention no longer keeps
+func expiredSnapshots(snapshots []VolumeSnapshot, retention models.SnapshotRetention, now time.Time) map[string]bool {
+	expired := make(map[string]bool)
+	if retention.MaxAgeDays > 0 {
+		cutoff := now.Add(-time.Duration(retention.MaxAgeDays) * 24 * time.Hour)
+		for _, snapshot := range snapshots {
+			if snapshot.CreatedAt.Before(cutoff) {
+				expired[snapshot.ID] = true
+			}
+		}
+	}
+	if retention.MaxCount > 0 && len(snapshots) > retention.MaxCount {
+		for _, snapshot := range snapshots[:len(snapshots)-retention.MaxCount] {
+			expired[snapshot.ID] = true
+		}
+	}
+	return expired
+}
+
+// filesCopiedProgress reports the number of files copied so far as part of the operation step
+func filesCopiedProgress(ctx context.Context, step string, percent int) func(filesCopied int) {
+	return func(filesCopied int) {
+		reportProgress(ctx, fmt.Sprintf("%s (%d copied)", step, filesCopied), percent)
+	}
+}
+
+func snapshotInfo(snapshot VolumeSnapshot) models.SnapshotInfo {
+	return models.SnapshotInfo{ID: snapshot.ID, Label: snapshot.Label, CreatedAt: snapshot.CreatedAt}
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+)
+
+func TestEnvironmentService_SnapshotAndRestoreInPlace(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, _, volumes := newTestEnvironmentService(t, store)
+	publisher := &recordingPublisher{}
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	service.SetEventPublisher(publisher)
+
+	notes := filepath.Join(volumes.Path("fs-"+wsID), "notes.txt")
+	writeFile(t, notes, "good")
+
+	snapshot, err := service.CreateSnapshot(ctx, &models.CreateSnapshotRequest{WorkspaceID: wsID, Label: "before upgrade"})
+	if err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	if env, _ := store.Get(ctx, wsID); len(env.Snapshots) != 1 || env.Snapshots[0].ID != snapshot.ID || env.Snapshots[0].Label != "before upgrade" {
+		t.Errorf("stored snapshots = %+v, want [%s before upgrade]", env.Snapshots, snapshot.ID)
+	}
+
+	writeFile(t, notes, "broken")
+	writeFile(t, filepath.Join(volumes.Path("fs-"+wsID), "junk"), "junk")
+
+	// Rolling back in place needs the workspace stopped
+	_, err = service.RestoreSnapshotAsync(ctx, &models.RestoreSnapshotRequest{WorkspaceID: wsID, SnapshotID: snapshot.ID})
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+		t.Fatalf("RestoreSnapshotAsync() of running workspace error = %v, want CONFLICT", err)
+	}
+
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	env, err := service.RestoreSnapshot(ctx, &models.RestoreSnapshotRequest{WorkspaceID: wsID, SnapshotID: snapshot.ID})
+	if err != nil {
+		t.Fatalf("RestoreSnapshot() error = %v", err)
+	}
+	if env.Status != models.StatusStopped {
+		t.Errorf("restored status = %s, want STOPPED", env.Status)
+	}
+	if data, _ := os.ReadFile(notes); string(data) != "good" {
+		t.Errorf("notes.txt = %q, want %q", data, "good")
+	}
+	if _, err := os.Stat(filepath.Join(volumes.Path("fs-"+wsID), "junk")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("junk survived the restore: %v", err)
+	}
+
+	want := []string{webhook.EventSnapshotted, webhook.EventStopped, webhook.EventRestored}
+	if len(publisher.events) != len(want) {
+		t.Fatalf("events = %v, want %v", publisher.events, want)
+	}
+	for i := range want {
+		if publisher.events[i] != want[i] {
+			t.Errorf("events[%d] = %s, want %s", i, publisher.events[i], want[i])
+		}
+	}
+
+	if err := service.DeleteSnapshot(ctx, wsID, snapshot.ID); err != nil {
+		t.Fatalf("DeleteSnapshot() error = %v", err)
+	}
+	if err := service.DeleteSnapshot(ctx, wsID, snapshot.ID); !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+		t.Errorf("DeleteSnapshot() twice error = %v, want NOT_FOUND", err)
+	}
+	if snapshots, err := service.ListSnapshots(ctx, wsID); err != nil || len(snapshots) != 0 {
+		t.Errorf("ListSnapshots() = %v, %v, want none", snapshots, err)
+	}
+}
+
+func TestEnvironmentService_RestoreToNewShare(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, _, volumes := newTestEnvironmentService(t, store)
+	const targetID = "660e8400-e29b-41d4-a716-446655440000"
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	writeFile(t, filepath.Join(volumes.Path("fs-"+wsID), "notes.txt"), "v1")
+
+	snapshot, err := service.CreateSnapshot(ctx, &models.CreateSnapshotRequest{WorkspaceID: wsID})
+	if err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	writeFile(t, filepath.Join(volumes.Path("fs-"+wsID), "notes.txt"), "v2")
+
+	// The source keeps running; the new workspace gets the snapshot
+	target, err := service.RestoreSnapshot(ctx, &models.RestoreSnapshotRequest{
+		WorkspaceID:       wsID,
+		SnapshotID:        snapshot.ID,
+		Mode:              models.RestoreNewShare,
+		TargetWorkspaceID: targetID,
+	})
+	if err != nil {
+		t.Fatalf("RestoreSnapshot() error = %v", err)
+	}
+	if target.ID != targetID || target.Status != models.StatusStopped || target.UserID != "user-1" || target.RestoredFrom != wsID+"@"+snapshot.ID {
+		t.Errorf("restored workspace = %+v, want stopped copy of %s", target, wsID)
+	}
+
+	// Starting the restored workspace keeps its provenance
+	started, err := service.StartEnvironment(ctx, &models.StartEnvironmentRequest{
+		WorkspaceID: targetID,
+		CloudRegion: "eastus",
+		UserID:      "user-1",
+		Name:        target.Name,
+		CPUCores:    2,
+		MemoryGB:    4,
+	})
+	if err != nil {
+		t.Fatalf("StartEnvironment() error = %v", err)
+	}
+	if started.RestoredFrom != target.RestoredFrom {
+		t.Errorf("started RestoredFrom = %q, want %q", started.RestoredFrom, target.RestoredFrom)
+	}
+	if data, _ := os.ReadFile(filepath.Join(volumes.Path("fs-"+targetID), "notes.txt")); string(data) != "v1" {
+		t.Errorf("restored notes.txt = %q, want v1", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(volumes.Path("fs-"+wsID), "notes.txt")); string(data) != "v2" {
+		t.Errorf("source notes.txt = %q, want v2", data)
+	}
+
+	// The target now exists
+	_, err = service.RestoreSnapshotAsync(ctx, &models.RestoreSnapshotRequest{
+		WorkspaceID:       wsID,
+		SnapshotID:        snapshot.ID,
+		Mode:              models.RestoreNewShare,
+		TargetWorkspaceID: targetID,
+	})
+	var appErr *models.AppError
+	if !errors.As(err, &appErr) || appErr.Code != "CONFLICT" {
+		t.Errorf("RestoreSnapshotAsync() to existing workspace error = %v, want CONFLICT", err)
+	}
+
+	// A missing snapshot leaves nothing behind
+	const otherID = "770e8400-e29b-41d4-a716-446655440000"
+	_, err = service.RestoreSnapshot(ctx, &models.RestoreSnapshotRequest{
+		WorkspaceID:       wsID,
+		SnapshotID:        "2000-01-01T00:00:00.0000000Z",
+		Mode:              models.RestoreNewShare,
+		TargetWorkspaceID: otherID,
+	})
+	if !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+		t.Errorf("RestoreSnapshot() of missing snapshot error = %v, want NOT_FOUND", err)
+	}
+	if _, err := store.Get(ctx, otherID); err == nil {
+		t.Error("record of failed restore was not removed")
+	}
+	if exists, _ := volumes.VolumeExists(ctx, "fs-"+otherID); exists {
+		t.Error("volume of failed restore was not removed")
+	}
+}
+
+// racingStore runs afterGet after every read, standing in for a lifecycle
+// operation that saves the record while the reader is still working
+type racingStore struct {
+	EnvironmentStore
+	afterGet func()
+}
+
+func (r *racingStore) Get(ctx context.Context, id string) (*models.Environment, error) {
+	env, err := r.EnvironmentStore.Get(ctx, id)
+	if r.afterGet != nil {
+		r.afterGet()
+	}
+	return env, err
+}
+
+func TestEnvironmentService_ListSnapshotsKeepsConcurrentChanges(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryEnvironmentStore()
+	store := &racingStore{EnvironmentStore: memory}
+	service, _, _ := newTestEnvironmentService(t, store)
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "user-1",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    2,
+		MemoryGB:    4,
+		StorageGB:   10,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	if _, err := service.CreateSnapshot(ctx, &models.CreateSnapshotRequest{WorkspaceID: wsID}); err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	env, _ := memory.Get(ctx, wsID)
+	env.Status = models.StatusStarting
+	if err := memory.Put(ctx, env); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// A start saves the record after each read of the listing
+	var saves int
+	var lastFQDN string
+	store.afterGet = func() {
+		saves++
+		env, _ := memory.Get(ctx, wsID)
+		lastFQDN = fmt.Sprintf("start-%d.example", saves)
+		env.Status = models.StatusRunning
+		env.AzureFQDN = lastFQDN
+		_ = memory.Put(ctx, env)
+	}
+	snapshots, err := service.ListSnapshots(ctx, wsID)
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("ListSnapshots() = %v, %v, want one snapshot", snapshots, err)
+	}
+
+	env, _ = memory.Get(ctx, wsID)
+	if env.Status != models.StatusRunning || env.AzureFQDN != lastFQDN {
+		t.Errorf("record after listing = %s at %q, want the start's RUNNING record", env.Status, env.AzureFQDN)
+	}
+	if len(env.Snapshots) != 1 {
+		t.Errorf("recorded snapshots = %d, want 1", len(env.Snapshots))
+	}
+}
+
+func TestExpiredSnapshots(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	snapshots := []VolumeSnapshot{
+		{ID: "a", CreatedAt: now.Add(-40 * 24 * time.Hour)},
+		{ID: "b", CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: "c", CreatedAt: now.Add(-2 * 24 * time.Hour)},
+		{ID: "d", CreatedAt: now.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name      string
+		retention models.SnapshotRetention
+		want      []string
+	}{
+		{name: "unlimited", retention: models.SnapshotRetention{}},
+		{name: "max count", retention: models.SnapshotRetention{MaxCount: 2}, want: []string{"a", "b"}},
+		{name: "max age", retention: models.SnapshotRetention{MaxAgeDays: 30}, want: []string{"a"}},
+		{name: "both", retention: models.SnapshotRetention{MaxCount: 3, MaxAgeDays: 5}, want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expiredSnapshots(snapshots, tt.retention, now)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expiredSnapshots() = %v, want %v", got, tt.want)
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Errorf("expiredSnapshots() = %v, want %s expired", got, id)
+				}
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// EnvironmentStore persists the agent's view of every workspace it manages.
+// Implementations must be safe for concurrent use.
+type EnvironmentStore interface {
+	// Get returns the environment with the given ID or a NOT_FOUND AppError
+	Get(ctx context.Context, id string) (*models.Environment, error)
+	// Put creates or replaces an environment record
+	Put(ctx context.Context, env *models.Environment) error
+	// Update runs change on the current record with the given ID and saves the result
+	// atomically, or returns a NOT_FOUND AppError. change reports whether it modified
+	// the record; nothing is written when it returns false.
+	Update(ctx context.Context, id string, change func(env *models.Environment) bool) (*models.Environment, error)
+	// Delete removes an environment record. Deleting a missing record is not an error.
+	Delete(ctx context.Context, id string) error
+	// List returns one page of environments matching the filter and the total match count
+	List(ctx context.Context, filter EnvironmentFilter) ([]models.Environment, int, error)
+}
+
+// EnvironmentFilter narrows and paginates List results. Empty fields match everything.
+type EnvironmentFilter struct {
+	UserID   string
+	OrgID    string
+	Region   string
+	Status   models.EnvironmentStatus
+	Page     int // 1-based
+	PageSize int
+}
+
+// Normalize applies default and maximum pagination values
+func (f *EnvironmentFilter) Normalize() {
+	if f.Page < 1 {
+		f.Page = 1
+	}
+	if f.PageSize < 1 {
+		f.PageSize = defaultPageSize
+	}
+	if f.PageSize > maxPageSize {
+		f.PageSize = maxPageSize
+	}
+}
+
+// Matches reports whether env satisfies the filter
+func (f EnvironmentFilter) Matches(env *models.Environment) bool {
+	if f.UserID != "" && env.UserID != f.UserID {
+		return false
+	}
+	if f.OrgID != "" && env.OrgID != f.OrgID {
+		return false
+	}
+	if f.Region != "" && env.CloudRegion != f.Region {
+		return false
+	}
+	if f.Status != "" && env.Status != f.Status {
+		return false
+	}
+	return true
+}
+
+// paginate sorts matches newest first and returns the requested page
+func paginate(matches []models.Environment, filter EnvironmentFilter) []models.Environment {
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].ID < matches[j].ID
+		}
+		return matches[i].CreatedAt.After(matches[j].CreatedAt)
+	})
+
+	start := (filter.Page - 1) * filter.PageSize
+	if start >= len(matches) {
+		return []models.Environment{}
+	}
+	end := start + filter.PageSize
+	if end > len(matches) {
+		end = len(matches)
+	}
+	return matches[start:end]
+}
+
+// listAllEnvironments walks every page of store matching filter
+func listAllEnvironments(ctx context.Context, store EnvironmentStore, filter EnvironmentFilter) ([]models.Environment, error) {
+	filter.Page = 1
+	filter.PageSize = maxPageSize
+
+	var all []models.Environment
+	for {
+		page, total, err := store.List(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page...)
+		if len(page) == 0 || len(all) >= total {
+			return all, nil
+		}
+		filter.Page++
+	}
+}
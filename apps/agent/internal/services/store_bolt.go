@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var environmentsBucket = []byte("environments")
+
+// OpenBoltDB opens (or creates) the agent's embedded state database.
+// The same handle is shared by every bolt-backed store.
+func OpenBoltDB(path string) (*bolt.DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// BoltEnvironmentStore persists environments in an embedded BoltDB file so the
+// registry survives agent restarts.
+type BoltEnvironmentStore struct {
+	db *bolt.DB
+}
+
+// NewBoltEnvironmentStore creates a store backed by db
+func NewBoltEnvironmentStore(db *bolt.DB) (*BoltEnvironmentStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(environmentsBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize environments bucket: %w", err)
+	}
+
+	return &BoltEnvironmentStore{db: db}, nil
+}
+
+// Get returns the environment with the given ID
+func (b *BoltEnvironmentStore) Get(ctx context.Context, id string) (*models.Environment, error) {
+	var env *models.Environment
+	err := b.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(environmentsBucket).Get([]byte(id))
+		if data == nil {
+			return models.ErrNotFound(fmt.Sprintf("environment %s not found", id))
+		}
+		env = &models.Environment{}
+		return json.Unmarshal(data, env)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Put creates or replaces an environment record
+func (b *BoltEnvironmentStore) Put(ctx context.Context, env *models.Environment) error {
+	if env == nil || env.ID == "" {
+		return models.ErrInvalidRequest("environment id is required")
+	}
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("failed to marshal environment %s: %w", env.ID, err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(environmentsBucket).Put([]byte(env.ID), data)
+	})
+}
+
+// Update changes an environment record within one transaction
+func (b *BoltEnvironmentStore) Update(ctx context.Context, id string, change func(env *models.Environment) bool) (*models.Environment, error) {
+	var env *models.Environment
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(environmentsBucket)
+		data := bucket.Get([]byte(id))
+		if data == nil {
+			return models.ErrNotFound(fmt.Sprintf("environment %s not found", id))
+		}
+		env = &models.Environment{}
+		if err := json.Unmarshal(data, env); err != nil {
+			return err
+		}
+		if !change(env) {
+			return nil
+		}
+
+		data, err := json.Marshal(env)
+		if err != nil {
+			return fmt.Errorf("failed to marshal environment %s: %w", id, err)
+		}
+		return bucket.Put([]byte(id), data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// Delete removes an environment record
+func (b *BoltEnvironmentStore) Delete(ctx context.Context, id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(environmentsBucket).Delete([]byte(id))
+	})
+}
+
+// List returns one page of matching environments and the total match count
+func (b *BoltEnvironmentStore) List(ctx context.Context, filter EnvironmentFilter) ([]models.Environment, int, error) {
+	filter.Normalize()
+
+	var matches []models.Environment
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(environmentsBucket).ForEach(func(k, v []byte) error {
+			var env models.Environment
+			if err := json.Unmarshal(v, &env); err != nil {
+				return fmt.Errorf("failed to unmarshal environment %s: %w", k, err)
+			}
+			if filter.Matches(&env) {
+				matches = append(matches, env)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return paginate(matches, filter), len(matches), nil
+}
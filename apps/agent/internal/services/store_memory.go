@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// MemoryEnvironmentStore is an in-process EnvironmentStore, used in tests
+// and when the agent runs without a state directory.
+type MemoryEnvironmentStore struct {
+	mu           sync.RWMutex
+	environments map[string]models.Environment
+}
+
+// NewMemoryEnvironmentStore creates an empty in-memory store
+func NewMemoryEnvironmentStore() *MemoryEnvironmentStore {
+	return &MemoryEnvironmentStore{
+		environments: make(map[string]models.Environment),
+	}
+}
+
+// Get returns the environment with the given ID
+func (m *MemoryEnvironmentStore) Get(ctx context.Context, id string) (*models.Environment, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	env, ok := m.environments[id]
+	if !ok {
+		return nil, models.ErrNotFound(fmt.Sprintf("environment %s not found", id))
+	}
+	return &env, nil
+}
+
+// Put creates or replaces an environment record
+func (m *MemoryEnvironmentStore) Put(ctx context.Context, env *models.Environment) error {
+	if env == nil || env.ID == "" {
+		return models.ErrInvalidRequest("environment id is required")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.environments[env.ID] = *env
+	return nil
+}
+
+// Update changes an environment record in place under the store's lock
+func (m *MemoryEnvironmentStore) Update(ctx context.Context, id string, change func(env *models.Environment) bool) (*models.Environment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	env, ok := m.environments[id]
+	if !ok {
+		return nil, models.ErrNotFound(fmt.Sprintf("environment %s not found", id))
+	}
+	if change(&env) {
+		m.environments[id] = env
+	}
+	return &env, nil
+}
+
+// Delete removes an environment record
+func (m *MemoryEnvironmentStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.environments, id)
+	return nil
+}
+
+// List returns one page of matching environments and the total match count
+func (m *MemoryEnvironmentStore) List(ctx context.Context, filter EnvironmentFilter) ([]models.Environment, int, error) {
+	filter.Normalize()
+
+	m.mu.RLock()
+	var matches []models.Environment
+	for _, env := range m.environments {
+		if filter.Matches(&env) {
+			matches = append(matches, env)
+		}
+	}
+	m.mu.RUnlock()
+
+	return paginate(matches, filter), len(matches), nil
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+)
+
+// storeFactories returns every EnvironmentStore implementation so they share one contract test
+func storeFactories(t *testing.T) map[string]func() EnvironmentStore {
+	return map[string]func() EnvironmentStore{
+		"memory": func() EnvironmentStore { return NewMemoryEnvironmentStore() },
+		"bolt": func() EnvironmentStore {
+			db, err := OpenBoltDB(filepath.Join(t.TempDir(), "agent.db"))
+			if err != nil {
+				t.Fatalf("OpenBoltDB() error = %v", err)
+			}
+			t.Cleanup(func() { _ = db.Close() })
+
+			store, err := NewBoltEnvironmentStore(db)
+			if err != nil {
+				t.Fatalf("NewBoltEnvironmentStore() error = %v", err)
+			}
+			return store
+		},
+	}
+}
+
+func TestEnvironmentStore_CRUD(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+
+			env := &models.Environment{ID: "ws-1", UserID: "alice", Status: models.StatusCreating, CreatedAt: time.Now()}
+			if err := store.Put(ctx, env); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			got, err := store.Get(ctx, "ws-1")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got.UserID != "alice" || got.Status != models.StatusCreating {
+				t.Errorf("Get() = %+v", got)
+			}
+
+			env.Status = models.StatusRunning
+			if err := store.Put(ctx, env); err != nil {
+				t.Fatalf("Put() update error = %v", err)
+			}
+			got, _ = store.Get(ctx, "ws-1")
+			if got.Status != models.StatusRunning {
+				t.Errorf("Get() after update status = %v, want %v", got.Status, models.StatusRunning)
+			}
+
+			if err := store.Delete(ctx, "ws-1"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			_, err = store.Get(ctx, "ws-1")
+			var appErr *models.AppError
+			if !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+				t.Errorf("Get() after delete error = %v, want NOT_FOUND", err)
+			}
+
+			if err := store.Delete(ctx, "ws-1"); err != nil {
+				t.Errorf("Delete() of missing record error = %v", err)
+			}
+
+			if err := store.Put(ctx, &models.Environment{}); err == nil {
+				t.Error("Put() without ID expected error")
+			}
+		})
+	}
+}
+
+func TestEnvironmentStore_Update(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+
+			if err := store.Put(ctx, &models.Environment{ID: "ws-1", Status: models.StatusRunning}); err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+
+			updated, err := store.Update(ctx, "ws-1", func(env *models.Environment) bool {
+				env.ActiveSSHConnections = 2
+				return true
+			})
+			if err != nil || updated.ActiveSSHConnections != 2 {
+				t.Fatalf("Update() = %+v, %v", updated, err)
+			}
+			if got, _ := store.Get(ctx, "ws-1"); got.ActiveSSHConnections != 2 || got.Status != models.StatusRunning {
+				t.Errorf("Get() after Update() = %+v", got)
+			}
+
+			if _, err := store.Update(ctx, "ws-1", func(env *models.Environment) bool {
+				env.ActiveSSHConnections = 5
+				return false
+			}); err != nil {
+				t.Fatalf("Update() without changes error = %v", err)
+			}
+			if got, _ := store.Get(ctx, "ws-1"); got.ActiveSSHConnections != 2 {
+				t.Errorf("Update() that reported no change wrote %d connections", got.ActiveSSHConnections)
+			}
+
+			_, err = store.Update(ctx, "ws-404", func(env *models.Environment) bool { return true })
+			var appErr *models.AppError
+			if !errors.As(err, &appErr) || appErr.Code != "NOT_FOUND" {
+				t.Errorf("Update() of missing record error = %v, want NOT_FOUND", err)
+			}
+			if _, err := store.Get(ctx, "ws-404"); err == nil {
+				t.Error("Update() of missing record created it")
+			}
+		})
+	}
+}
+
+func TestEnvironmentStore_List(t *testing.T) {
+	for name, newStore := range storeFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			store := newStore()
+			ctx := context.Background()
+			base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+			fixtures := []models.Environment{
+				{ID: "ws-1", UserID: "alice", CloudRegion: "eastus", Status: models.StatusRunning},
+				{ID: "ws-2", UserID: "alice", CloudRegion: "westus", Status: models.StatusStopped},
+				{ID: "ws-3", UserID: "bob", CloudRegion: "eastus", Status: models.StatusRunning},
+				{ID: "ws-4", UserID: "alice", CloudRegion: "eastus", Status: models.StatusRunning},
+			}
+			for i := range fixtures {
+				fixtures[i].CreatedAt = base.Add(time.Duration(i) * time.Hour)
+				if err := store.Put(ctx, &fixtures[i]); err != nil {
+					t.Fatalf("Put() error = %v", err)
+				}
+			}
+
+			tests := []struct {
+				name      string
+				filter    EnvironmentFilter
+				wantIDs   []string
+				wantTotal int
+			}{
+				{name: "no filter newest first", filter: EnvironmentFilter{}, wantIDs: []string{"ws-4", "ws-3", "ws-2", "ws-1"}, wantTotal: 4},
+				{name: "by user", filter: EnvironmentFilter{UserID: "alice"}, wantIDs: []string{"ws-4", "ws-2", "ws-1"}, wantTotal: 3},
+				{name: "by region and status", filter: EnvironmentFilter{Region: "eastus", Status: models.StatusRunning}, wantIDs: []string{"ws-4", "ws-3", "ws-1"}, wantTotal: 3},
+				{name: "second page", filter: EnvironmentFilter{Page: 2, PageSize: 3}, wantIDs: []string{"ws-1"}, wantTotal: 4},
+				{name: "page past end", filter: EnvironmentFilter{Page: 5, PageSize: 3}, wantIDs: []string{}, wantTotal: 4},
+				{name: "no matches", filter: EnvironmentFilter{UserID: "carol"}, wantIDs: []string{}, wantTotal: 0},
+			}
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					got, total, err := store.List(ctx, tt.filter)
+					if err != nil {
+						t.Fatalf("List() error = %v", err)
+					}
+					if total != tt.wantTotal {
+						t.Errorf("List() total = %d, want %d", total, tt.wantTotal)
+					}
+					if len(got) != len(tt.wantIDs) {
+						t.Fatalf("List() returned %d items, want %d", len(got), len(tt.wantIDs))
+					}
+					for i, id := range tt.wantIDs {
+						if got[i].ID != id {
+							t.Errorf("List()[%d].ID = %s, want %s", i, got[i].ID, id)
+						}
+					}
+				})
+			}
+		})
+	}
+}
+
+func TestEnvironmentFilter_Normalize(t *testing.T) {
+	f := EnvironmentFilter{PageSize: 1000}
+	f.Normalize()
+	if f.Page != 1 || f.PageSize != maxPageSize {
+		t.Errorf("Normalize() = %+v, want page 1 and pageSize %d", f, maxPageSize)
+	}
+
+	f = EnvironmentFilter{}
+	f.Normalize()
+	if f.PageSize != defaultPageSize {
+		t.Errorf("Normalize() pageSize = %d, want %d", f.PageSize, defaultPageSize)
+	}
+}
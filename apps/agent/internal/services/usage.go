@@ -0,0 +1,417 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+	"github.com/google/uuid"
+)
+
+// maxUsagePeriod bounds the period of one usage report
+const maxUsagePeriod = 366 * 24 * time.Hour
+
+// UsageMeter records the compute and storage intervals of workspaces and prices
+// them with the rate card.
+//
+// Compute is metered while a workspace is RUNNING, storage from the first time it
+// is RUNNING or STOPPED until it is deleted. Records follow the workspace record:
+// every save closes the intervals that no longer match it, e.g. after a stop or a
+// resize, and opens the ones it lacks.
+type UsageMeter struct {
+	cfg   *config.Config
+	store UsageStore
+	now   func() time.Time
+
+	mu sync.Mutex // Serialises Observe so concurrent saves of a workspace open one record
+}
+
+// NewUsageMeter creates a meter keeping its records in memory
+func NewUsageMeter(cfg *config.Config) *UsageMeter {
+	return &UsageMeter{cfg: cfg, store: NewMemoryUsageStore(), now: time.Now}
+}
+
+// SetUsageStore keeps usage records in store, e.g. one that survives restarts
+func (s *EnvironmentService) SetUsageStore(store UsageStore) {
+	s.usage.store = store
+}
+
+// Observe brings the open usage records of env in line with its saved state
+func (m *UsageMeter) Observe(ctx context.Context, env *models.Environment) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	open, err := m.store.Open(ctx, env.ID)
+	if err != nil {
+		log.Printf("Warning: workspace %s: failed to read usage records: %v", env.ID, err)
+		return
+	}
+
+	now := m.now()
+	mode := m.modeOf(env)
+	wantCompute := env.Status == models.StatusRunning && env.CPUCores > 0 && env.MemoryGB > 0
+	storageGB := 0
+	if env.StorageGB > 0 {
+		storageGB = int(volumeQuotaGB(env.StorageGB))
+	}
+
+	var hasCompute, hasStorage, hadStorage bool
+	for i := range open {
+		record := &open[i]
+		keep := record.Region == env.CloudRegion && record.Mode == mode
+		switch record.Kind {
+		case models.UsageCompute:
+			keep = keep && wantCompute && record.CPUCores == env.CPUCores && record.MemoryGB == env.MemoryGB
+			hasCompute = hasCompute || keep
+		case models.UsageStorage:
+			hadStorage = true
+			keep = keep && record.StorageGB == storageGB
+			hasStorage = hasStorage || keep
+		}
+		if !keep {
+			m.end(ctx, record, now)
+		}
+	}
+
+	if wantCompute && !hasCompute {
+		m.start(ctx, &models.UsageRecord{
+			WorkspaceID: env.ID,
+			UserID:      env.UserID,
+			Kind:        models.UsageCompute,
+			Region:      env.CloudRegion,
+			Mode:        mode,
+			CPUCores:    env.CPUCores,
+			MemoryGB:    env.MemoryGB,
+			StartedAt:   now,
+		})
+	}
+
+	provisioned := hadStorage || env.Status == models.StatusRunning || env.Status == models.StatusStopped
+	if storageGB > 0 && !hasStorage && provisioned {
+		m.start(ctx, &models.UsageRecord{
+			WorkspaceID: env.ID,
+			UserID:      env.UserID,
+			Kind:        models.UsageStorage,
+			Region:      env.CloudRegion,
+			Mode:        mode,
+			StorageGB:   storageGB,
+			StartedAt:   now,
+		})
+	}
+}
+
+// Close ends the open usage records of a workspace being deleted
+func (m *UsageMeter) Close(ctx context.Context, workspaceID string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	open, err := m.store.Open(ctx, workspaceID)
+	if err != nil {
+		log.Printf("Warning: workspace %s: failed to read usage records: %v", workspaceID, err)
+		return
+	}
+	now := m.now()
+	for i := range open {
+		m.end(ctx, &open[i], now)
+	}
+}
+
+func (m *UsageMeter) start(ctx context.Context, record *models.UsageRecord) {
+	record.ID = uuid.NewString()
+	if err := m.store.Put(ctx, record); err != nil {
+		log.Printf("Warning: workspace %s: failed to save usage record: %v", record.WorkspaceID, err)
+	}
+}
+
+func (m *UsageMeter) end(ctx context.Context, record *models.UsageRecord, now time.Time) {
+	record.EndedAt = &now
+	if err := m.store.Put(ctx, record); err != nil {
+		log.Printf("Warning: workspace %s: failed to close usage record %s: %v", record.WorkspaceID, record.ID, err)
+	}
+}
+
+// modeOf returns the deployment mode env runs in, as named in the rate card
+func (m *UsageMeter) modeOf(env *models.Environment) string {
+	if env.CloudProvider == models.ProviderAWS {
+		return "ecs"
+	}
+	return m.cfg.Azure.DeploymentMode
+}
+
+// Report returns the usage and cost of a user, or of every user for an empty
+// userID, between from and to, broken down by workspace
+func (m *UsageMeter) Report(ctx context.Context, userID string, from, to time.Time) (*models.UsageReport, error) {
+	records, err := m.store.List(ctx, userID, from, to)
+	if err != nil {
+		return nil, models.ErrInternalServer(fmt.Sprintf("failed to list usage records: %v", err))
+	}
+
+	now := m.now()
+	byWorkspace := make(map[string]*models.WorkspaceUsage)
+	for i := range records {
+		record := &records[i]
+		hours := record.Overlap(from, to, now).Hours()
+		if hours == 0 {
+			continue
+		}
+
+		usage, ok := byWorkspace[record.WorkspaceID]
+		if !ok {
+			usage = &models.WorkspaceUsage{WorkspaceID: record.WorkspaceID, UserID: record.UserID}
+			byWorkspace[record.WorkspaceID] = usage
+		}
+		// Records are oldest first, so the latest one names the region
+		usage.Region, usage.Mode = record.Region, record.Mode
+
+		rate, priced := m.cfg.Usage.RateFor(record.Region, record.Mode)
+		usage.Unpriced = usage.Unpriced || !priced
+		switch record.Kind {
+		case models.UsageCompute:
+			vcpuHours := hours * float64(record.CPUCores)
+			memoryGBHours := hours * float64(record.MemoryGB)
+			usage.RunningHours += hours
+			usage.VCPUHours += vcpuHours
+			usage.MemoryGBHours += memoryGBHours
+			usage.ComputeCost += vcpuHours*rate.VCPUHour + memoryGBHours*rate.MemoryGBHour
+		case models.UsageStorage:
+			gbDays := hours / 24 * float64(record.StorageGB)
+			usage.StorageGBDays += gbDays
+			usage.StorageCost += gbDays * rate.StorageGBMonth / 30
+		}
+	}
+
+	report := &models.UsageReport{
+		UserID:     userID,
+		From:       from,
+		To:         to,
+		Currency:   m.cfg.Usage.Currency,
+		Workspaces: make([]models.WorkspaceUsage, 0, len(byWorkspace)),
+	}
+	for _, usage := range byWorkspace {
+		report.ComputeCost += usage.ComputeCost
+		report.StorageCost += usage.StorageCost
+
+		usage.TotalCost = roundUsage(usage.ComputeCost + usage.StorageCost)
+		usage.ComputeCost = roundUsage(usage.ComputeCost)
+		usage.StorageCost = roundUsage(usage.StorageCost)
+		usage.RunningHours = roundUsage(usage.RunningHours)
+		usage.VCPUHours = roundUsage(usage.VCPUHours)
+		usage.MemoryGBHours = roundUsage(usage.MemoryGBHours)
+		usage.StorageGBDays = roundUsage(usage.StorageGBDays)
+		report.Workspaces = append(report.Workspaces, *usage)
+	}
+	sort.Slice(report.Workspaces, func(i, j int) bool {
+		return report.Workspaces[i].WorkspaceID < report.Workspaces[j].WorkspaceID
+	})
+	report.TotalCost = roundUsage(report.ComputeCost + report.StorageCost)
+	report.ComputeCost = roundUsage(report.ComputeCost)
+	report.StorageCost = roundUsage(report.StorageCost)
+	return report, nil
+}
+
+// roundUsage rounds quantities and costs to four decimals
+func roundUsage(v float64) float64 {
+	return math.Round(v*1e4) / 1e4
+}
+
+// monthStart returns the start of the UTC calendar month of t, which budgets cover
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// Budget returns the monthly budget of a user tier, empty meaning the default tier
+func (m *UsageMeter) Budget(tier string) config.BudgetConfig {
+	if tier == "" {
+		tier = m.cfg.Policy.DefaultTier
+	}
+	return m.cfg.Usage.BudgetFor(tier)
+}
+
+// CheckBudget refuses to run more workspaces for a user whose cost this month has
+// reached the hard budget of their tier
+func (m *UsageMeter) CheckBudget(ctx context.Context, userID, tier string) error {
+	if m == nil || userID == "" {
+		return nil
+	}
+	budget := m.Budget(tier)
+	if budget.Hard <= 0 {
+		return nil
+	}
+
+	now := m.now()
+	report, err := m.Report(ctx, userID, monthStart(now), now)
+	if err != nil {
+		return err
+	}
+	if report.TotalCost >= budget.Hard {
+		return models.ErrPolicyViolation(models.PolicyBudgetExceeded,
+			fmt.Sprintf("user %s has used %.2f %s of the %.2f %s monthly budget; workspaces cannot run until next month",
+				userID, report.TotalCost, report.Currency, budget.Hard, report.Currency))
+	}
+	return nil
+}
+
+// GetUsage returns the usage and cost of a user, or of every user, between from
+// and to. A zero from is the start of the current month and a zero to is now.
+func (s *EnvironmentService) GetUsage(ctx context.Context, userID string, from, to time.Time) (*models.UsageReport, error) {
+	now := s.usage.now()
+	if from.IsZero() {
+		from = monthStart(now)
+	}
+	if to.IsZero() {
+		to = now
+	}
+	if !to.After(from) {
+		return nil, models.ErrInvalidRequest("to must be after from")
+	}
+	if to.Sub(from) > maxUsagePeriod {
+		return nil, models.ErrInvalidRequest(fmt.Sprintf("usage can be reported for at most %d days at a time", int(maxUsagePeriod.Hours()/24)))
+	}
+	return s.usage.Report(ctx, userID, from.UTC(), to.UTC())
+}
+
+// BudgetEnforcer warns running workspaces whose owner's cost this month reached the
+// soft budget of their tier, and stops those whose owner reached the hard budget.
+type BudgetEnforcer struct {
+	cfg   config.UsageConfig
+	usage *UsageMeter
+	store EnvironmentStore
+
+	// stop and publish are EnvironmentService methods, replaceable in tests
+	stop    func(ctx context.Context, workspaceID, region string) error
+	publish func(ctx context.Context, eventType, workspaceID string, data interface{})
+	now     func() time.Time
+}
+
+// NewBudgetEnforcer creates a budget enforcer for the workspaces managed by service
+func NewBudgetEnforcer(service *EnvironmentService) *BudgetEnforcer {
+	return &BudgetEnforcer{
+		cfg:     service.config.Usage,
+		usage:   service.usage,
+		store:   service.store,
+		stop:    service.StopEnvironment,
+		publish: service.publish,
+		now:     time.Now,
+	}
+}
+
+// Run checks budgets every interval until ctx is cancelled
+func (b *BudgetEnforcer) Run(ctx context.Context) {
+	log.Printf("💰 Budget enforcer started (soft=%.2f, hard=%.2f %s, interval=%s)", b.cfg.SoftBudget, b.cfg.HardBudget, b.cfg.Currency, b.cfg.BudgetInterval)
+
+	ticker := time.NewTicker(b.cfg.BudgetInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("💰 Budget enforcer stopped")
+			return
+		case <-ticker.C:
+			if err := b.Check(ctx); err != nil {
+				log.Printf("❌ Budget check failed: %v", err)
+			}
+		}
+	}
+}
+
+// Check compares the cost of every user with running workspaces against their budgets
+func (b *BudgetEnforcer) Check(ctx context.Context) error {
+	envs, err := listAllEnvironments(ctx, b.store, EnvironmentFilter{Status: models.StatusRunning})
+	if err != nil {
+		return fmt.Errorf("failed to list running environments: %w", err)
+	}
+	if len(envs) == 0 {
+		return nil
+	}
+
+	now := b.now()
+	month := monthStart(now)
+	report, err := b.usage.Report(ctx, "", month, now)
+	if err != nil {
+		return err
+	}
+	spent := make(map[string]float64)
+	for _, usage := range report.Workspaces {
+		spent[usage.UserID] += usage.TotalCost
+	}
+
+	for i := range envs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		env := &envs[i]
+		if env.UserID == "" {
+			continue
+		}
+		budget := b.usage.Budget(env.Tier)
+		cost := spent[env.UserID]
+		switch {
+		case budget.Hard > 0 && cost >= budget.Hard:
+			b.stopOverBudget(ctx, env, cost, budget)
+		case budget.Soft > 0 && cost >= budget.Soft && (env.BudgetWarnedAt == nil || env.BudgetWarnedAt.Before(month)):
+			b.warn(ctx, env, now, cost, budget)
+		}
+	}
+	return nil
+}
+
+func (b *BudgetEnforcer) stopOverBudget(ctx context.Context, env *models.Environment, cost float64, budget config.BudgetConfig) {
+	log.Printf("💰 User %s has used %.2f of the %.2f %s monthly budget - stopping workspace %s", env.UserID, cost, budget.Hard, b.cfg.Currency, env.ID)
+	if err := b.stop(ctx, env.ID, env.CloudRegion); err != nil {
+		log.Printf("❌ Failed to stop workspace %s over budget: %v", env.ID, err)
+		return
+	}
+
+	if stopped, err := b.store.Get(ctx, env.ID); err == nil {
+		stopped.StatusMessage = fmt.Sprintf("stopped after the monthly budget of %.2f %s was reached", budget.Hard, b.cfg.Currency)
+		if err := b.store.Put(ctx, stopped); err != nil {
+			log.Printf("Warning: workspace %s: failed to persist environment record: %v", env.ID, err)
+		}
+	}
+
+	b.publish(ctx, webhook.EventBudgetExceeded, env.ID, map[string]interface{}{
+		"workspaceId": env.ID,
+		"userId":      env.UserID,
+		"spent":       cost,
+		"hardBudget":  budget.Hard,
+		"currency":    b.cfg.Currency,
+	})
+}
+
+func (b *BudgetEnforcer) warn(ctx context.Context, env *models.Environment, now time.Time, cost float64, budget config.BudgetConfig) {
+	log.Printf("💰 User %s has used %.2f of the %.2f %s soft monthly budget - warning workspace %s", env.UserID, cost, budget.Soft, b.cfg.Currency, env.ID)
+
+	// Re-read so a concurrent update is not overwritten
+	current, err := b.store.Get(ctx, env.ID)
+	if err != nil {
+		return
+	}
+	current.BudgetWarnedAt = &now
+	if err := b.store.Put(ctx, current); err != nil {
+		log.Printf("Warning: workspace %s: failed to persist environment record: %v", env.ID, err)
+	}
+
+	b.publish(ctx, webhook.EventBudgetWarning, env.ID, map[string]interface{}{
+		"workspaceId": env.ID,
+		"userId":      env.UserID,
+		"spent":       cost,
+		"softBudget":  budget.Soft,
+		"hardBudget":  budget.Hard,
+		"currency":    b.cfg.Currency,
+	})
+}
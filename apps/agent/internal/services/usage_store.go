@@ -0,0 +1,186 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	bolt "go.etcd.io/bbolt"
+)
+
+var usageBucket = []byte("usage")
+
+// UsageStore persists metered usage records. Implementations must be safe for
+// concurrent use.
+type UsageStore interface {
+	// Put creates or replaces a record
+	Put(ctx context.Context, record *models.UsageRecord) error
+	// Open returns the records of a workspace that have not ended
+	Open(ctx context.Context, workspaceID string) ([]models.UsageRecord, error)
+	// List returns the records of a user, or of every user for an empty userID,
+	// that overlap from..to, oldest first. Open records overlap any later period.
+	List(ctx context.Context, userID string, from, to time.Time) ([]models.UsageRecord, error)
+}
+
+// usageOverlaps reports whether record belongs in a listing of userID over from..to
+func usageOverlaps(record *models.UsageRecord, userID string, from, to time.Time) bool {
+	if userID != "" && record.UserID != userID {
+		return false
+	}
+	return record.StartedAt.Before(to) && (record.EndedAt == nil || record.EndedAt.After(from))
+}
+
+// sortUsage orders records oldest first
+func sortUsage(records []models.UsageRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].StartedAt.Equal(records[j].StartedAt) {
+			return records[i].ID < records[j].ID
+		}
+		return records[i].StartedAt.Before(records[j].StartedAt)
+	})
+}
+
+// MemoryUsageStore keeps usage records in memory. They are lost on restart.
+type MemoryUsageStore struct {
+	mu      sync.RWMutex
+	records map[string]models.UsageRecord
+}
+
+// NewMemoryUsageStore creates an empty in-memory usage store
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{records: make(map[string]models.UsageRecord)}
+}
+
+// Put creates or replaces a record
+func (m *MemoryUsageStore) Put(ctx context.Context, record *models.UsageRecord) error {
+	if record == nil || record.ID == "" {
+		return models.ErrInvalidRequest("usage record id is required")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[record.ID] = *record
+	return nil
+}
+
+// Open returns the open records of a workspace
+func (m *MemoryUsageStore) Open(ctx context.Context, workspaceID string) ([]models.UsageRecord, error) {
+	m.mu.RLock()
+	var records []models.UsageRecord
+	for _, record := range m.records {
+		if record.WorkspaceID == workspaceID && record.EndedAt == nil {
+			records = append(records, record)
+		}
+	}
+	m.mu.RUnlock()
+
+	sortUsage(records)
+	return records, nil
+}
+
+// List returns the records of a user overlapping from..to
+func (m *MemoryUsageStore) List(ctx context.Context, userID string, from, to time.Time) ([]models.UsageRecord, error) {
+	m.mu.RLock()
+	var records []models.UsageRecord
+	for _, record := range m.records {
+		if usageOverlaps(&record, userID, from, to) {
+			records = append(records, record)
+		}
+	}
+	m.mu.RUnlock()
+
+	sortUsage(records)
+	return records, nil
+}
+
+// BoltUsageStore persists usage records in the agent's embedded database. Records
+// are keyed by workspace so the open records of one are found without a full scan.
+type BoltUsageStore struct {
+	db *bolt.DB
+}
+
+// NewBoltUsageStore creates a usage store backed by db
+func NewBoltUsageStore(db *bolt.DB) (*BoltUsageStore, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(usageBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize usage bucket: %w", err)
+	}
+
+	return &BoltUsageStore{db: db}, nil
+}
+
+// usageKey is the key of a record: its workspace ID, a slash and its own ID
+func usageKey(workspaceID, id string) []byte {
+	return []byte(workspaceID + "/" + id)
+}
+
+// Put creates or replaces a record
+func (b *BoltUsageStore) Put(ctx context.Context, record *models.UsageRecord) error {
+	if record == nil || record.ID == "" {
+		return models.ErrInvalidRequest("usage record id is required")
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal usage record %s: %w", record.ID, err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(usageBucket).Put(usageKey(record.WorkspaceID, record.ID), data)
+	})
+}
+
+// Open returns the open records of a workspace
+func (b *BoltUsageStore) Open(ctx context.Context, workspaceID string) ([]models.UsageRecord, error) {
+	var records []models.UsageRecord
+	prefix := usageKey(workspaceID, "")
+	err := b.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(usageBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			var record models.UsageRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("failed to unmarshal usage record %s: %w", k, err)
+			}
+			if record.EndedAt == nil {
+				records = append(records, record)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortUsage(records)
+	return records, nil
+}
+
+// List returns the records of a user overlapping from..to
+func (b *BoltUsageStore) List(ctx context.Context, userID string, from, to time.Time) ([]models.UsageRecord, error) {
+	var records []models.UsageRecord
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(usageBucket).ForEach(func(k, v []byte) error {
+			var record models.UsageRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return fmt.Errorf("failed to unmarshal usage record %s: %w", k, err)
+			}
+			if usageOverlaps(&record, userID, from, to) {
+				records = append(records, record)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sortUsage(records)
+	return records, nil
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
+)
+
+// testUsageConfig prices aci usage in eastus at round numbers
+func testUsageConfig() *config.Config {
+	return &config.Config{
+		Azure: config.AzureConfig{DeploymentMode: "aci"},
+		Usage: config.UsageConfig{
+			Currency: "USD",
+			Rates:    []config.UsageRate{{Region: "eastus", Mode: "aci", VCPUHour: 0.1, MemoryGBHour: 0.01, StorageGBMonth: 3}},
+		},
+	}
+}
+
+// usageFactories returns every UsageStore implementation so they share one contract test
+func usageFactories(t *testing.T) map[string]func() UsageStore {
+	return map[string]func() UsageStore{
+		"memory": func() UsageStore { return NewMemoryUsageStore() },
+		"bolt": func() UsageStore {
+			db, err := OpenBoltDB(filepath.Join(t.TempDir(), "agent.db"))
+			if err != nil {
+				t.Fatalf("OpenBoltDB() error = %v", err)
+			}
+			t.Cleanup(func() { _ = db.Close() })
+
+			store, err := NewBoltUsageStore(db)
+			if err != nil {
+				t.Fatalf("NewBoltUsageStore() error = %v", err)
+			}
+			return store
+		},
+	}
+}
+
+func TestUsageMeter_Observe(t *testing.T) {
+	for name, newStore := range usageFactories(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+			now := start
+			meter := NewUsageMeter(testUsageConfig())
+			meter.store = newStore()
+			meter.now = func() time.Time { return now }
+
+			env := &models.Environment{ID: "ws-1", UserID: "user-1", CloudRegion: "eastus", Status: models.StatusCreating, CPUCores: 2, MemoryGB: 4, StorageGB: 25}
+			observe := func(status models.EnvironmentStatus, advance time.Duration) {
+				now = now.Add(advance)
+				env.Status = status
+				meter.Observe(ctx, env)
+			}
+
+			observe(models.StatusCreating, 0)
+			observe(models.StatusRunning, time.Minute)  // 00:01 compute and storage start
+			observe(models.StatusRunning, time.Hour)    // Activity saves change nothing
+			env.CPUCores = 4                            // Resize
+			observe(models.StatusResizing, time.Hour)   // 02:01 compute ends
+			observe(models.StatusRunning, time.Minute)  // 02:02 compute restarts with 4 vCPU
+			observe(models.StatusStopped, 2*time.Hour)  // 04:02 compute ends
+			observe(models.StatusError, 10*time.Hour)   // A failed start keeps the share
+			now = now.Add(9*time.Hour + 59*time.Minute) // 1 day after the share was created
+			meter.Close(ctx, "ws-1")                    // Deleted
+
+			if open, err := meter.store.Open(ctx, "ws-1"); err != nil || len(open) != 0 {
+				t.Fatalf("Open() after Close = %d records, %v, want none", len(open), err)
+			}
+
+			report, err := meter.Report(ctx, "user-1", start, start.AddDate(0, 1, 0))
+			if err != nil {
+				t.Fatalf("Report() error = %v", err)
+			}
+			if len(report.Workspaces) != 1 {
+				t.Fatalf("Report() workspaces = %+v, want 1", report.Workspaces)
+			}
+			usage := report.Workspaces[0]
+			// 2h at 2 vCPU and 2h at 4 vCPU, both with 4GB; a 30GB share for a day
+			if usage.RunningHours != 4 || usage.VCPUHours != 12 || usage.MemoryGBHours != 16 || usage.StorageGBDays != 30 {
+				t.Errorf("usage = %+v, want 4 running hours, 12 vCPU hours, 16 GB hours and 30 GB days", usage)
+			}
+			if usage.ComputeCost != 1.36 || usage.StorageCost != 3 || report.TotalCost != 4.36 || usage.Unpriced {
+				t.Errorf("costs = %+v, total %v, want 1.36 compute and 3 storage", usage, report.TotalCost)
+			}
+
+			// Only the overlap with the period counts
+			report, err = meter.Report(ctx, "user-1", start.Add(time.Hour+time.Minute), start.Add(2*time.Hour+time.Minute))
+			if err != nil {
+				t.Fatalf("Report() error = %v", err)
+			}
+			if got := report.Workspaces[0].VCPUHours; got != 2 {
+				t.Errorf("VCPUHours in one hour = %v, want 2", got)
+			}
+
+			if report, _ := meter.Report(ctx, "user-2", start, start.AddDate(0, 1, 0)); len(report.Workspaces) != 0 {
+				t.Errorf("Report() for another user = %+v, want no workspaces", report.Workspaces)
+			}
+		})
+	}
+}
+
+func TestUsageMeter_ReportUnpriced(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	meter := NewUsageMeter(testUsageConfig())
+	meter.now = func() time.Time { return now }
+
+	record := &models.UsageRecord{ID: "u-1", WorkspaceID: "ws-1", UserID: "user-1", Kind: models.UsageCompute, Region: "westeurope", Mode: "aci", CPUCores: 1, MemoryGB: 2, StartedAt: now.Add(-time.Hour)}
+	if err := meter.store.Put(ctx, record); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	report, err := meter.Report(ctx, "", now.Add(-24*time.Hour), now)
+	if err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	if len(report.Workspaces) != 1 || !report.Workspaces[0].Unpriced || report.TotalCost != 0 || report.Workspaces[0].VCPUHours != 1 {
+		t.Errorf("Report() = %+v, want one unpriced workspace with 1 vCPU hour of open usage", report)
+	}
+}
+
+func TestEnvironmentService_GetUsage(t *testing.T) {
+	service, _, _ := newTestEnvironmentService(t, NewMemoryEnvironmentStore())
+	ctx := context.Background()
+
+	report, err := service.GetUsage(ctx, "user-1", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetUsage() error = %v", err)
+	}
+	if report.From.Day() != 1 || report.Workspaces == nil {
+		t.Errorf("GetUsage() = %+v, want the current month with an empty workspace list", report)
+	}
+
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	if _, err := service.GetUsage(ctx, "", from, from); !isAppErrorCode(err, "INVALID_REQUEST") {
+		t.Errorf("GetUsage() with an empty period error = %v, want INVALID_REQUEST", err)
+	}
+	if _, err := service.GetUsage(ctx, "", from, from.AddDate(2, 0, 0)); !isAppErrorCode(err, "INVALID_REQUEST") {
+		t.Errorf("GetUsage() over two years error = %v, want INVALID_REQUEST", err)
+	}
+}
+
+// spendUsage records a closed compute interval costing cost for userID this month
+func spendUsage(t *testing.T, meter *UsageMeter, userID string, cost float64) {
+	t.Helper()
+	end := meter.now()
+	record := &models.UsageRecord{
+		ID:          "spent-" + userID,
+		WorkspaceID: "old-" + userID,
+		UserID:      userID,
+		Kind:        models.UsageCompute,
+		Region:      "eastus",
+		Mode:        "aci",
+		CPUCores:    1,
+		StartedAt:   end.Add(-time.Duration(cost / 0.1 * float64(time.Hour))),
+		EndedAt:     &end,
+	}
+	if err := meter.store.Put(context.Background(), record); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+}
+
+func TestBudgetEnforcer_Check(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	cfg := testUsageConfig()
+	cfg.Usage.SoftBudget, cfg.Usage.HardBudget = 5, 10
+	cfg.Usage.TierBudgets = map[string]config.BudgetConfig{"enterprise": {}}
+
+	meter := NewUsageMeter(cfg)
+	meter.now = func() time.Time { return now }
+	spendUsage(t, meter, "frugal", 1)
+	spendUsage(t, meter, "busy", 6)
+	spendUsage(t, meter, "spender", 11)
+	spendUsage(t, meter, "corp", 50)
+
+	store := NewMemoryEnvironmentStore()
+	lastMonth := now.AddDate(0, -1, 0)
+	thisMonth := now.Add(-time.Hour)
+	for _, env := range []*models.Environment{
+		{ID: "ws-frugal", UserID: "frugal"},
+		{ID: "ws-busy", UserID: "busy"},
+		{ID: "ws-busy-warned", UserID: "busy", BudgetWarnedAt: &thisMonth},
+		{ID: "ws-busy-last-month", UserID: "busy", BudgetWarnedAt: &lastMonth},
+		{ID: "ws-spender", UserID: "spender"},
+		{ID: "ws-corp", UserID: "corp", Tier: "enterprise"},
+	} {
+		env.Status = models.StatusRunning
+		env.CloudRegion = "eastus"
+		if err := store.Put(ctx, env); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	var stopped, events []string
+	enforcer := &BudgetEnforcer{
+		cfg:   cfg.Usage,
+		usage: meter,
+		store: store,
+		stop: func(ctx context.Context, workspaceID, region string) error {
+			stopped = append(stopped, workspaceID)
+			env, err := store.Get(ctx, workspaceID)
+			if err != nil {
+				return err
+			}
+			env.Status = models.StatusStopped
+			return store.Put(ctx, env)
+		},
+		publish: func(ctx context.Context, eventType, workspaceID string, data interface{}) {
+			events = append(events, eventType+" "+workspaceID)
+		},
+		now: func() time.Time { return now },
+	}
+	if err := enforcer.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+
+	if len(stopped) != 1 || stopped[0] != "ws-spender" {
+		t.Errorf("stopped = %v, want [ws-spender]", stopped)
+	}
+	wantEvents := map[string]bool{
+		webhook.EventBudgetWarning + " ws-busy":            true,
+		webhook.EventBudgetWarning + " ws-busy-last-month": true,
+		webhook.EventBudgetExceeded + " ws-spender":        true,
+	}
+	if len(events) != len(wantEvents) {
+		t.Errorf("events = %v, want %d", events, len(wantEvents))
+	}
+	for _, event := range events {
+		if !wantEvents[event] {
+			t.Errorf("unexpected event %s", event)
+		}
+	}
+
+	if env, _ := store.Get(ctx, "ws-busy"); env.BudgetWarnedAt == nil || !env.BudgetWarnedAt.Equal(now) {
+		t.Errorf("ws-busy BudgetWarnedAt = %v, want %s", env.BudgetWarnedAt, now)
+	}
+	if env, _ := store.Get(ctx, "ws-spender"); env.StatusMessage == "" {
+		t.Error("expected a status message explaining the budget stop")
+	}
+}
+
+func TestPolicyEngine_AdmitOverBudget(t *testing.T) {
+	ctx := context.Background()
+	cfg := testUsageConfig()
+	cfg.Usage.HardBudget = 10
+	cfg.Usage.TierBudgets = map[string]config.BudgetConfig{"enterprise": {}}
+	meter := NewUsageMeter(cfg)
+	meter.now = func() time.Time { return time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC) }
+	spendUsage(t, meter, "spender", 12)
+
+	engine := NewPolicyEngine(config.PolicyConfig{}, NewMemoryEnvironmentStore())
+	engine.SetUsageMeter(meter)
+
+	_, err := engine.Admit(ctx, PolicyRequest{WorkspaceID: "ws-1", UserID: "spender"})
+	if !isAppErrorCode(err, "POLICY_VIOLATION") {
+		t.Fatalf("Admit() over the hard budget error = %v, want POLICY_VIOLATION", err)
+	}
+	for _, req := range []PolicyRequest{
+		{WorkspaceID: "ws-2", UserID: "frugal"},
+		{WorkspaceID: "ws-3", UserID: "spender", Tier: "enterprise"},
+	} {
+		release, err := engine.Admit(ctx, req)
+		if err != nil {
+			t.Errorf("Admit(%+v) error = %v", req, err)
+			continue
+		}
+		release()
+	}
+}
+
+func TestBudgetEnforcer_WarnsOncePerMonth(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEnvironmentStore()
+	service, _, _ := newTestEnvironmentService(t, store)
+	service.config.Usage = testUsageConfig().Usage
+	service.config.Usage.SoftBudget = 5
+	service.usage = NewUsageMeter(service.config)
+	publisher := &recordingPublisher{}
+	service.SetEventPublisher(publisher)
+
+	if _, err := service.CreateEnvironment(ctx, &models.CreateEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "busy",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    1,
+		MemoryGB:    2,
+		StorageGB:   10,
+	}); err != nil {
+		t.Fatalf("CreateEnvironment() error = %v", err)
+	}
+	spendUsage(t, service.usage, "busy", 6)
+	enforcer := NewBudgetEnforcer(service)
+	warnings := func() int {
+		count := 0
+		for _, event := range publisher.events {
+			if event == webhook.EventBudgetWarning {
+				count++
+			}
+		}
+		return count
+	}
+
+	if err := enforcer.Check(ctx); err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if got := warnings(); got != 1 {
+		t.Fatalf("warnings after the first check = %d, want 1", got)
+	}
+
+	// A restart keeps the record of the warning
+	if err := service.StopEnvironment(ctx, wsID, "eastus"); err != nil {
+		t.Fatalf("StopEnvironment() error = %v", err)
+	}
+	if _, err := service.StartEnvironment(ctx, &models.StartEnvironmentRequest{
+		WorkspaceID: wsID,
+		UserID:      "busy",
+		Name:        "test-env",
+		CloudRegion: "eastus",
+		CPUCores:    1,
+		MemoryGB:    2,
+	}); err != nil {
+		t.Fatalf("StartEnvironment() error = %v", err)
+	}
+	if err := enforcer.Check(ctx); err != nil {
+		t.Fatalf("second Check() error = %v", err)
+	}
+	if got := warnings(); got != 1 {
+		t.Errorf("warnings after a restart = %d, want 1", got)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+)
+
+// ErrVolumeNotFound is returned by volume stores when a volume does not exist
+var ErrVolumeNotFound = errors.New("volume not found")
+
+// ErrVolumeUsageUnavailable is returned when a backend cannot measure a volume right now
+var ErrVolumeUsageUnavailable = errors.New("volume usage not available")
+
+// VolumeStore manages the per-workspace fs-{id} volumes of one region.
+// The volume holds everything persistent and is mounted at /home/dev8.
+type VolumeStore interface {
+	// CreateVolume creates a volume with a quota in GiB
+	CreateVolume(ctx context.Context, name string, quotaGB int32) error
+	// DeleteVolume deletes a volume and its data; deleting a missing volume is not an error
+	DeleteVolume(ctx context.Context, name string) error
+	// VolumeExists reports whether a volume exists
+	VolumeExists(ctx context.Context, name string) (bool, error)
+	// VolumeProperties returns the volume details, or an error wrapping ErrVolumeNotFound
+	VolumeProperties(ctx context.Context, name string) (*VolumeInfo, error)
+	// ResizeVolume changes the quota of a volume
+	ResizeVolume(ctx context.Context, name string, quotaGB int32) error
+	// VolumeUsage returns the bytes stored in a volume, or an error wrapping
+	// ErrVolumeUsageUnavailable when the backend cannot measure it right now
+	VolumeUsage(ctx context.Context, name string) (int64, error)
+	// ListVolumes lists volumes whose names start with prefix
+	ListVolumes(ctx context.Context, prefix string) ([]VolumeInfo, error)
+}
+
+// ErrSnapshotNotFound is returned by volume snapshotters when a snapshot does not exist
+var ErrSnapshotNotFound = errors.New("snapshot not found")
+
+// VolumeSnapshotter is implemented by volume stores that can take point-in-time snapshots
+type VolumeSnapshotter interface {
+	// CreateSnapshot takes a read-only snapshot of a volume
+	CreateSnapshot(ctx context.Context, name, label string) (*VolumeSnapshot, error)
+	// ListSnapshots lists the snapshots of a volume, oldest first
+	ListSnapshots(ctx context.Context, name string) ([]VolumeSnapshot, error)
+	// DeleteSnapshot deletes a snapshot, or returns an error wrapping ErrSnapshotNotFound
+	DeleteSnapshot(ctx context.Context, name, snapshotID string) error
+	// RestoreSnapshot replaces the contents of the existing volume target with a snapshot
+	// of volume name. target may be name itself to roll the volume back in place.
+	RestoreSnapshot(ctx context.Context, name, snapshotID, target string, progress func(filesCopied int)) error
+}
+
+// VolumeCopier is implemented by volume stores that can copy one volume into another
+type VolumeCopier interface {
+	// CopyVolume copies the contents of volume src into the existing volume dst
+	CopyVolume(ctx context.Context, src, dst string, progress func(filesCopied int)) error
+}
+
+// VolumeSnapshot describes a snapshot of a workspace volume
+type VolumeSnapshot struct {
+	ID        string
+	Label     string
+	CreatedAt time.Time
+}
+
+// VolumeInfo describes a workspace volume
+type VolumeInfo struct {
+	Name         string
+	QuotaGB      int32 // 0 when the backend enforces no quota
+	LastModified time.Time
+}
+
+// newVolumeStores creates the volume store of every enabled region for the configured backend.
+// Docker, Kubernetes and local volumes do not depend on the region, so every region shares one store.
+func newVolumeStores(cfg *config.Config, clients ProviderClients) (map[string]VolumeStore, error) {
+	stores := make(map[string]VolumeStore)
+
+	var shared VolumeStore
+	switch cfg.VolumeBackend() {
+	case "docker":
+		if clients.Docker == nil {
+			return nil, fmt.Errorf("docker volume backend requires a Docker client")
+		}
+		shared = &dockerVolumes{client: clients.Docker}
+	case "kubernetes":
+		if clients.Kubernetes == nil {
+			return nil, fmt.Errorf("kubernetes volume backend requires a Kubernetes client")
+		}
+		shared = &kubernetesVolumes{
+			client:       clients.Kubernetes,
+			namespace:    cfg.Kubernetes.Namespace,
+			storageClass: cfg.Kubernetes.StorageClass,
+		}
+	case "local":
+		local, err := newLocalVolumes(cfg.Volumes.LocalDir)
+		if err != nil {
+			return nil, err
+		}
+		shared = local
+	case "azure":
+		var retrier *azure.Retrier
+		if clients.Azure != nil {
+			retrier = clients.Azure.Retrier()
+		}
+		for _, region := range cfg.GetEnabledRegions() {
+			if region.StorageAccount == "" {
+				continue
+			}
+			storageClient, err := azure.NewStorageClient(region.StorageAccount, cfg.Azure.StorageAccountKey, retrier, region.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create storage client for region %s: %w", region.Name, err)
+			}
+			stores[region.Name] = &azureVolumes{client: storageClient}
+		}
+		return stores, nil
+	default:
+		return nil, fmt.Errorf("invalid volume backend: %s", cfg.VolumeBackend())
+	}
+
+	for _, region := range cfg.GetEnabledRegions() {
+		stores[region.Name] = shared
+	}
+	return stores, nil
+}
+
+// snapshotNotFound wraps ErrSnapshotNotFound with the volume and snapshot
+func snapshotNotFound(name, snapshotID string) error {
+	return fmt.Errorf("%w: %s@%s", ErrSnapshotNotFound, name, snapshotID)
+}
+
+// volumeNotFound wraps ErrVolumeNotFound with the volume name
+func volumeNotFound(name string) error {
+	return fmt.Errorf("%w: %s", ErrVolumeNotFound, name)
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/efs"
+	efstypes "github.com/aws/aws-sdk-go-v2/service/efs/types"
+	"github.com/aws/smithy-go"
+)
+
+// efsAPI is the part of the EFS API used for workspace volumes
+type efsAPI interface {
+	CreateAccessPoint(ctx context.Context, params *efs.CreateAccessPointInput, optFns ...func(*efs.Options)) (*efs.CreateAccessPointOutput, error)
+	DescribeAccessPoints(ctx context.Context, params *efs.DescribeAccessPointsInput, optFns ...func(*efs.Options)) (*efs.DescribeAccessPointsOutput, error)
+	DeleteAccessPoint(ctx context.Context, params *efs.DeleteAccessPointInput, optFns ...func(*efs.Options)) (*efs.DeleteAccessPointOutput, error)
+	TagResource(ctx context.Context, params *efs.TagResourceInput, optFns ...func(*efs.Options)) (*efs.TagResourceOutput, error)
+}
+
+// efsVolumes stores workspace volumes as EFS access points rooted at /{name} on a
+// region's file system. EFS grows elastically, so quotas are recorded as a tag and
+// not enforced, and it reports no per-directory usage.
+//
+// Deleting an access point leaves its directory on the file system; the data is
+// unreachable from workspaces and must be purged by an EFS-side cleanup job.
+type efsVolumes struct {
+	client       efsAPI
+	fileSystemID string
+}
+
+// The workspace image runs as dev8 (1000:1000)
+const efsOwnerID = 1000
+
+func (v *efsVolumes) CreateVolume(ctx context.Context, name string, quotaGB int32) error {
+	uid := int64(efsOwnerID)
+	_, err := v.client.CreateAccessPoint(ctx, &efs.CreateAccessPointInput{
+		ClientToken:  &name, // Retries of this request by the SDK return the same access point
+		FileSystemId: &v.fileSystemID,
+		RootDirectory: &efstypes.RootDirectory{
+			Path:         strPtr("/" + name),
+			CreationInfo: &efstypes.CreationInfo{OwnerUid: &uid, OwnerGid: &uid, Permissions: strPtr("0755")},
+		},
+		Tags: []efstypes.Tag{
+			{Key: strPtr("Name"), Value: &name},
+			{Key: strPtr("managed-by"), Value: strPtr(managedByTag)},
+			{Key: strPtr("quota-gb"), Value: strPtr(strconv.Itoa(int(quotaGB)))},
+			{Key: strPtr("created-at"), Value: strPtr(time.Now().UTC().Format(time.RFC3339))},
+		},
+	})
+	if accessPointExists(err) {
+		// A later create of the same volume carries a different created-at tag, so EFS
+		// refuses it instead of returning the access point of the first one
+		if _, findErr := findAccessPoint(ctx, v.client, v.fileSystemID, name); findErr == nil {
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create EFS access point %s: %w", name, err)
+	}
+	return nil
+}
+
+// accessPointExists reports whether a create failed because an access point with its
+// client token exists already
+func accessPointExists(err error) bool {
+	var exists *efstypes.AccessPointAlreadyExists
+	if errors.As(err, &exists) {
+		return true
+	}
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "IdempotentParameterMismatch"
+}
+
+func (v *efsVolumes) DeleteVolume(ctx context.Context, name string) error {
+	accessPoint, err := findAccessPoint(ctx, v.client, v.fileSystemID, name)
+	if errors.Is(err, ErrVolumeNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, err := v.client.DeleteAccessPoint(ctx, &efs.DeleteAccessPointInput{AccessPointId: accessPoint.AccessPointId}); err != nil {
+		var notFound *efstypes.AccessPointNotFound
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete EFS access point %s: %w", name, err)
+	}
+	return nil
+}
+
+func (v *efsVolumes) VolumeExists(ctx context.Context, name string) (bool, error) {
+	_, err := findAccessPoint(ctx, v.client, v.fileSystemID, name)
+	if errors.Is(err, ErrVolumeNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (v *efsVolumes) VolumeProperties(ctx context.Context, name string) (*VolumeInfo, error) {
+	accessPoint, err := findAccessPoint(ctx, v.client, v.fileSystemID, name)
+	if err != nil {
+		return nil, err
+	}
+	return accessPointInfo(accessPoint), nil
+}
+
+func (v *efsVolumes) ResizeVolume(ctx context.Context, name string, quotaGB int32) error {
+	accessPoint, err := findAccessPoint(ctx, v.client, v.fileSystemID, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = v.client.TagResource(ctx, &efs.TagResourceInput{
+		ResourceId: accessPoint.AccessPointId,
+		Tags:       []efstypes.Tag{{Key: strPtr("quota-gb"), Value: strPtr(strconv.Itoa(int(quotaGB)))}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to resize EFS access point %s: %w", name, err)
+	}
+	return nil
+}
+
+func (v *efsVolumes) VolumeUsage(ctx context.Context, name string) (int64, error) {
+	if _, err := findAccessPoint(ctx, v.client, v.fileSystemID, name); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("%w: EFS reports usage per file system only", ErrVolumeUsageUnavailable)
+}
+
+func (v *efsVolumes) ListVolumes(ctx context.Context, prefix string) ([]VolumeInfo, error) {
+	accessPoints, err := listAccessPoints(ctx, v.client, v.fileSystemID)
+	if err != nil {
+		return nil, err
+	}
+
+	var volumes []VolumeInfo
+	for i := range accessPoints {
+		if accessPoints[i].Name != nil && strings.HasPrefix(*accessPoints[i].Name, prefix) {
+			volumes = append(volumes, *accessPointInfo(&accessPoints[i]))
+		}
+	}
+	return volumes, nil
+}
+
+// findAccessPoint returns the access point named name, or an error wrapping ErrVolumeNotFound
+func findAccessPoint(ctx context.Context, client efsAPI, fileSystemID, name string) (*efstypes.AccessPointDescription, error) {
+	accessPoints, err := listAccessPoints(ctx, client, fileSystemID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range accessPoints {
+		if accessPoints[i].Name != nil && *accessPoints[i].Name == name &&
+			accessPoints[i].LifeCycleState != efstypes.LifeCycleStateDeleting &&
+			accessPoints[i].LifeCycleState != efstypes.LifeCycleStateDeleted {
+			return &accessPoints[i], nil
+		}
+	}
+	return nil, volumeNotFound(name)
+}
+
+func listAccessPoints(ctx context.Context, client efsAPI, fileSystemID string) ([]efstypes.AccessPointDescription, error) {
+	var (
+		accessPoints []efstypes.AccessPointDescription
+		nextToken    *string
+	)
+	for {
+		out, err := client.DescribeAccessPoints(ctx, &efs.DescribeAccessPointsInput{
+			FileSystemId: &fileSystemID,
+			NextToken:    nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list EFS access points of %s: %w", fileSystemID, err)
+		}
+		accessPoints = append(accessPoints, out.AccessPoints...)
+		if out.NextToken == nil || *out.NextToken == "" {
+			return accessPoints, nil
+		}
+		nextToken = out.NextToken
+	}
+}
+
+// accessPointInfo reads the quota and creation time recorded in the access point's tags
+func accessPointInfo(accessPoint *efstypes.AccessPointDescription) *VolumeInfo {
+	info := &VolumeInfo{}
+	if accessPoint.Name != nil {
+		info.Name = *accessPoint.Name
+	}
+	for _, tag := range accessPoint.Tags {
+		if tag.Key == nil || tag.Value == nil {
+			continue
+		}
+		switch *tag.Key {
+		case "quota-gb":
+			if quota, err := strconv.ParseInt(*tag.Value, 10, 32); err == nil {
+				info.QuotaGB = int32(quota)
+			}
+		case "created-at":
+			if created, err := time.Parse(time.RFC3339, *tag.Value); err == nil {
+				info.LastModified = created
+			}
+		}
+	}
+	return info
+}
+
+func strPtr(s string) *string {
+	return &s
+}
@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"sort"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/azure"
+)
+
+// azureVolumes stores workspace volumes as Azure File shares in a region's storage account
+type azureVolumes struct {
+	client *azure.StorageClient
+}
+
+func (v *azureVolumes) CreateVolume(ctx context.Context, name string, quotaGB int32) error {
+	return v.client.CreateFileShare(ctx, name, quotaGB)
+}
+
+func (v *azureVolumes) DeleteVolume(ctx context.Context, name string) error {
+	err := v.client.DeleteFileShare(ctx, name)
+	if azure.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func (v *azureVolumes) VolumeExists(ctx context.Context, name string) (bool, error) {
+	return v.client.FileShareExists(ctx, name)
+}
+
+func (v *azureVolumes) VolumeProperties(ctx context.Context, name string) (*VolumeInfo, error) {
+	share, err := v.client.GetFileShareProperties(ctx, name)
+	if err != nil {
+		if azure.IsNotFound(err) {
+			return nil, volumeNotFound(name)
+		}
+		return nil, err
+	}
+	return &VolumeInfo{Name: share.Name, QuotaGB: share.QuotaGB, LastModified: share.LastModified}, nil
+}
+
+func (v *azureVolumes) ResizeVolume(ctx context.Context, name string, quotaGB int32) error {
+	err := v.client.SetFileShareQuota(ctx, name, quotaGB)
+	if azure.IsNotFound(err) {
+		return volumeNotFound(name)
+	}
+	return err
+}
+
+func (v *azureVolumes) VolumeUsage(ctx context.Context, name string) (int64, error) {
+	used, err := v.client.GetFileShareUsage(ctx, name)
+	if azure.IsNotFound(err) {
+		return 0, volumeNotFound(name)
+	}
+	return used, err
+}
+
+func (v *azureVolumes) ListVolumes(ctx context.Context, prefix string) ([]VolumeInfo, error) {
+	shares, err := v.client.ListFileShares(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	volumes := make([]VolumeInfo, 0, len(shares))
+	for _, share := range shares {
+		volumes = append(volumes, VolumeInfo{Name: share.Name, QuotaGB: share.QuotaGB, LastModified: share.LastModified})
+	}
+	return volumes, nil
+}
+
+// CopyVolume copies src into dst server-side, one directory at a time
+func (v *azureVolumes) CopyVolume(ctx context.Context, src, dst string, progress func(filesCopied int)) error {
+	err := v.client.CopyShare(ctx, src, dst, azure.CopyShareOptions{Progress: progress})
+	if azure.IsNotFound(err) {
+		return volumeNotFound(src)
+	}
+	return err
+}
+
+// snapshotLabelKey is the share snapshot metadata key holding the snapshot label
+const snapshotLabelKey = "label"
+
+func (v *azureVolumes) CreateSnapshot(ctx context.Context, name, label string) (*VolumeSnapshot, error) {
+	var metadata map[string]string
+	if label != "" {
+		metadata = map[string]string{snapshotLabelKey: label}
+	}
+	snapshot, err := v.client.CreateShareSnapshot(ctx, name, metadata)
+	if azure.IsNotFound(err) {
+		return nil, volumeNotFound(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &VolumeSnapshot{ID: snapshot.Snapshot, Label: label, CreatedAt: snapshot.CreatedAt}, nil
+}
+
+func (v *azureVolumes) ListSnapshots(ctx context.Context, name string) ([]VolumeSnapshot, error) {
+	shareSnapshots, err := v.client.ListShareSnapshots(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]VolumeSnapshot, 0, len(shareSnapshots))
+	for _, snapshot := range shareSnapshots {
+		snapshots = append(snapshots, VolumeSnapshot{ID: snapshot.Snapshot, Label: snapshot.Metadata[snapshotLabelKey], CreatedAt: snapshot.CreatedAt})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt) })
+	return snapshots, nil
+}
+
+func (v *azureVolumes) DeleteSnapshot(ctx context.Context, name, snapshotID string) error {
+	err := v.client.DeleteShareSnapshot(ctx, name, snapshotID)
+	if azure.IsNotFound(err) {
+		return snapshotNotFound(name, snapshotID)
+	}
+	return err
+}
+
+// RestoreSnapshot copies the snapshot over target server-side and removes files the snapshot does not have
+func (v *azureVolumes) RestoreSnapshot(ctx context.Context, name, snapshotID, target string, progress func(filesCopied int)) error {
+	if err := v.checkSnapshot(ctx, name, snapshotID); err != nil {
+		return err
+	}
+	return v.client.CopyShare(ctx, name, target, azure.CopyShareOptions{
+		SourceSnapshot: snapshotID,
+		Mirror:         true,
+		Progress:       progress,
+	})
+}
+
+// checkSnapshot returns an error wrapping ErrSnapshotNotFound unless the snapshot exists
+func (v *azureVolumes) checkSnapshot(ctx context.Context, name, snapshotID string) error {
+	snapshots, err := v.client.ListShareSnapshots(ctx, name)
+	if err != nil {
+		return err
+	}
+	for _, snapshot := range snapshots {
+		if snapshot.Snapshot == snapshotID {
+			return nil
+		}
+	}
+	return snapshotNotFound(name, snapshotID)
+}
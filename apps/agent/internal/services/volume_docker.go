@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/docker"
+)
+
+// dockerVolumes stores workspace volumes as named volumes on the local Docker Engine.
+// The local driver enforces no quota, so quotas are accepted and ignored.
+type dockerVolumes struct {
+	client *docker.Client
+}
+
+func (v *dockerVolumes) CreateVolume(ctx context.Context, name string, quotaGB int32) error {
+	return v.client.CreateVolume(ctx, name, map[string]string{"managed-by": managedByTag})
+}
+
+func (v *dockerVolumes) DeleteVolume(ctx context.Context, name string) error {
+	return v.client.RemoveVolume(ctx, name)
+}
+
+func (v *dockerVolumes) VolumeExists(ctx context.Context, name string) (bool, error) {
+	_, err := v.client.InspectVolume(ctx, name)
+	if docker.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (v *dockerVolumes) VolumeProperties(ctx context.Context, name string) (*VolumeInfo, error) {
+	volume, err := v.client.InspectVolume(ctx, name)
+	if err != nil {
+		if docker.IsNotFound(err) {
+			return nil, volumeNotFound(name)
+		}
+		return nil, err
+	}
+	return &VolumeInfo{Name: volume.Name, LastModified: volume.CreatedAt}, nil
+}
+
+// ResizeVolume only checks the volume exists; there is no quota to change
+func (v *dockerVolumes) ResizeVolume(ctx context.Context, name string, quotaGB int32) error {
+	_, err := v.VolumeProperties(ctx, name)
+	return err
+}
+
+func (v *dockerVolumes) VolumeUsage(ctx context.Context, name string) (int64, error) {
+	used, err := v.client.VolumeUsage(ctx, name)
+	if docker.IsNotFound(err) {
+		return 0, volumeNotFound(name)
+	}
+	return used, err
+}
+
+func (v *dockerVolumes) ListVolumes(ctx context.Context, prefix string) ([]VolumeInfo, error) {
+	volumes, err := v.client.ListVolumes(ctx, "managed-by="+managedByTag)
+	if err != nil {
+		return nil, err
+	}
+
+	var infos []VolumeInfo
+	for _, volume := range volumes {
+		if strings.HasPrefix(volume.Name, prefix) {
+			infos = append(infos, VolumeInfo{Name: volume.Name, LastModified: volume.CreatedAt})
+		}
+	}
+	return infos, nil
+}
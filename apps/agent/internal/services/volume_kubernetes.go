@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// kubernetesVolumes stores workspace volumes as ReadWriteOnce PVCs in the workspace
+// namespace. Resizing needs a storage class with allowVolumeExpansion.
+type kubernetesVolumes struct {
+	client       kubernetes.Interface
+	namespace    string
+	storageClass string
+}
+
+func (v *kubernetesVolumes) CreateVolume(ctx context.Context, name string, quotaGB int32) error {
+	claim := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: v.namespace,
+			Labels:    map[string]string{"managed-by": managedByTag},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: gibQuantity(quotaGB)},
+			},
+		},
+	}
+	if v.storageClass != "" {
+		claim.Spec.StorageClassName = &v.storageClass
+	}
+
+	if _, err := v.client.CoreV1().PersistentVolumeClaims(v.namespace).Create(ctx, claim, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create PVC %s: %w", name, err)
+	}
+	return nil
+}
+
+func (v *kubernetesVolumes) DeleteVolume(ctx context.Context, name string) error {
+	err := v.client.CoreV1().PersistentVolumeClaims(v.namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete PVC %s: %w", name, err)
+	}
+	return nil
+}
+
+func (v *kubernetesVolumes) VolumeExists(ctx context.Context, name string) (bool, error) {
+	_, err := v.getClaim(ctx, name)
+	if errors.Is(err, ErrVolumeNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (v *kubernetesVolumes) VolumeProperties(ctx context.Context, name string) (*VolumeInfo, error) {
+	claim, err := v.getClaim(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return claimInfo(claim), nil
+}
+
+func (v *kubernetesVolumes) ResizeVolume(ctx context.Context, name string, quotaGB int32) error {
+	claim, err := v.getClaim(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	if claim.Spec.Resources.Requests == nil {
+		claim.Spec.Resources.Requests = corev1.ResourceList{}
+	}
+	claim.Spec.Resources.Requests[corev1.ResourceStorage] = gibQuantity(quotaGB)
+	if _, err := v.client.CoreV1().PersistentVolumeClaims(v.namespace).Update(ctx, claim, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to resize PVC %s: %w", name, err)
+	}
+	return nil
+}
+
+// VolumeUsage reads the kubelet stats of the node running the pod that mounts the
+// PVC. A volume that is not mounted (stopped workspace) cannot be measured.
+func (v *kubernetesVolumes) VolumeUsage(ctx context.Context, name string) (int64, error) {
+	if _, err := v.getClaim(ctx, name); err != nil {
+		return 0, err
+	}
+
+	workspaceID := workspaceIDFromName(models.ResourceFileShare, name)
+	pods, err := v.client.CoreV1().Pods(v.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "workspace-id=" + workspaceID + ",managed-by=" + managedByTag,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list pods mounting %s: %w", name, err)
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		raw, err := v.client.CoreV1().RESTClient().Get().
+			AbsPath("/api/v1/nodes", pod.Spec.NodeName, "proxy", "stats", "summary").
+			DoRaw(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read kubelet stats for %s: %w", name, err)
+		}
+		return pvcUsedBytes(raw, v.namespace, name)
+	}
+	return 0, fmt.Errorf("%w: PVC %s is not mounted", ErrVolumeUsageUnavailable, name)
+}
+
+func (v *kubernetesVolumes) ListVolumes(ctx context.Context, prefix string) ([]VolumeInfo, error) {
+	claims, err := v.client.CoreV1().PersistentVolumeClaims(v.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "managed-by=" + managedByTag,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVCs: %w", err)
+	}
+
+	var volumes []VolumeInfo
+	for i := range claims.Items {
+		if strings.HasPrefix(claims.Items[i].Name, prefix) {
+			volumes = append(volumes, *claimInfo(&claims.Items[i]))
+		}
+	}
+	return volumes, nil
+}
+
+func (v *kubernetesVolumes) getClaim(ctx context.Context, name string) (*corev1.PersistentVolumeClaim, error) {
+	claim, err := v.client.CoreV1().PersistentVolumeClaims(v.namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, volumeNotFound(name)
+		}
+		return nil, fmt.Errorf("failed to get PVC %s: %w", name, err)
+	}
+	return claim, nil
+}
+
+// claimInfo reports the requested size. The creation time stands in for LastModified,
+// which the reconciler only uses as the volume's age.
+func claimInfo(claim *corev1.PersistentVolumeClaim) *VolumeInfo {
+	info := &VolumeInfo{Name: claim.Name, LastModified: claim.CreationTimestamp.Time}
+	if size, ok := claim.Spec.Resources.Requests[corev1.ResourceStorage]; ok {
+		info.QuotaGB = int32(size.Value() >> 30) // nolint:gosec // G115: PVC sizes are far below 2^61 bytes
+	}
+	return info
+}
+
+func gibQuantity(gb int32) resource.Quantity {
+	return *resource.NewQuantity(int64(gb)<<30, resource.BinarySI)
+}
+
+// pvcUsedBytes extracts a PVC's used bytes from a kubelet stats summary
+func pvcUsedBytes(summary []byte, namespace, claimName string) (int64, error) {
+	var stats struct {
+		Pods []struct {
+			Volumes []struct {
+				UsedBytes *int64 `json:"usedBytes"`
+				PVCRef    *struct {
+					Name      string `json:"name"`
+					Namespace string `json:"namespace"`
+				} `json:"pvcRef"`
+			} `json:"volume"`
+		} `json:"pods"`
+	}
+	if err := json.Unmarshal(summary, &stats); err != nil {
+		return 0, fmt.Errorf("invalid kubelet stats: %w", err)
+	}
+
+	for _, pod := range stats.Pods {
+		for _, volume := range pod.Volumes {
+			if volume.PVCRef == nil || volume.PVCRef.Name != claimName || volume.PVCRef.Namespace != namespace {
+				continue
+			}
+			if volume.UsedBytes == nil {
+				return 0, fmt.Errorf("%w: kubelet reports no usage for PVC %s", ErrVolumeUsageUnavailable, claimName)
+			}
+			return *volume.UsedBytes, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: PVC %s not in kubelet stats", ErrVolumeUsageUnavailable, claimName)
+}
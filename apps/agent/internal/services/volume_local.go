@@ -0,0 +1,472 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// localVolumes stores workspace volumes as directories under root, for local
+// development and tests. Each volume {name}/ has a {name}.json sidecar recording
+// its quota, which is reported but not enforced. Snapshots are full copies kept
+// under .snapshots/{name}/.
+type localVolumes struct {
+	mu   sync.Mutex
+	root string
+}
+
+// localVolumeMeta is the sidecar stored next to each volume directory
+type localVolumeMeta struct {
+	QuotaGB int32 `json:"quotaGB"`
+}
+
+// newLocalVolumes creates the store, creating root if needed
+func newLocalVolumes(root string) (*localVolumes, error) {
+	// Docker bind mounts need an absolute source
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, fmt.Errorf("invalid volume directory %s: %w", root, err)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create volume directory %s: %w", abs, err)
+	}
+	return &localVolumes{root: abs}, nil
+}
+
+// Path returns the host directory backing a volume
+func (v *localVolumes) Path(name string) string {
+	return filepath.Join(v.root, name)
+}
+
+func (v *localVolumes) CreateVolume(ctx context.Context, name string, quotaGB int32) error {
+	if err := checkLocalVolumeName(name); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// World-writable: the workspace user inside the container does not share the agent's UID
+	if err := os.Mkdir(v.Path(name), 0o777); err != nil && !errors.Is(err, fs.ErrExist) { // nolint:gosec // G301: local development only
+		return fmt.Errorf("failed to create volume %s: %w", name, err)
+	}
+	if err := os.Chmod(v.Path(name), 0o777); err != nil { // nolint:gosec // G302: Mkdir is subject to the umask
+		return fmt.Errorf("failed to create volume %s: %w", name, err)
+	}
+	return v.writeMeta(name, localVolumeMeta{QuotaGB: quotaGB})
+}
+
+func (v *localVolumes) DeleteVolume(ctx context.Context, name string) error {
+	if err := checkLocalVolumeName(name); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if err := os.RemoveAll(v.Path(name)); err != nil {
+		return fmt.Errorf("failed to delete volume %s: %w", name, err)
+	}
+	if err := os.Remove(v.metaPath(name)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete volume %s: %w", name, err)
+	}
+	if err := os.RemoveAll(v.snapshotRoot(name)); err != nil {
+		return fmt.Errorf("failed to delete volume %s snapshots: %w", name, err)
+	}
+	return nil
+}
+
+func (v *localVolumes) VolumeExists(ctx context.Context, name string) (bool, error) {
+	if err := checkLocalVolumeName(name); err != nil {
+		return false, err
+	}
+	info, err := os.Stat(v.Path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check volume %s: %w", name, err)
+	}
+	return info.IsDir(), nil
+}
+
+func (v *localVolumes) VolumeProperties(ctx context.Context, name string) (*VolumeInfo, error) {
+	if err := checkLocalVolumeName(name); err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.properties(name)
+}
+
+func (v *localVolumes) ResizeVolume(ctx context.Context, name string, quotaGB int32) error {
+	if err := checkLocalVolumeName(name); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, err := v.properties(name); err != nil {
+		return err
+	}
+	return v.writeMeta(name, localVolumeMeta{QuotaGB: quotaGB})
+}
+
+// VolumeUsage sums the size of the regular files in the volume
+func (v *localVolumes) VolumeUsage(ctx context.Context, name string) (int64, error) {
+	if err := checkLocalVolumeName(name); err != nil {
+		return 0, err
+	}
+
+	var used int64
+	err := filepath.WalkDir(v.Path(name), func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			used += info.Size()
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, volumeNotFound(name)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to measure volume %s: %w", name, err)
+	}
+	return used, nil
+}
+
+func (v *localVolumes) ListVolumes(ctx context.Context, prefix string) ([]VolumeInfo, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entries, err := os.ReadDir(v.root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+
+	var volumes []VolumeInfo
+	for _, entry := range entries {
+		if !entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := v.properties(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		volumes = append(volumes, *info)
+	}
+	return volumes, nil
+}
+
+// properties reads the volume directory and its sidecar. Callers hold mu.
+func (v *localVolumes) properties(name string) (*VolumeInfo, error) {
+	dir, err := os.Stat(v.Path(name))
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !dir.IsDir()) {
+		return nil, volumeNotFound(name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read volume %s: %w", name, err)
+	}
+
+	// A directory without a sidecar (created by hand) has no quota
+	info := &VolumeInfo{Name: name, LastModified: dir.ModTime()}
+	data, err := os.ReadFile(v.metaPath(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return info, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read volume %s: %w", name, err)
+	}
+
+	var meta localVolumeMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("volume %s: corrupt metadata: %w", name, err)
+	}
+	info.QuotaGB = meta.QuotaGB
+	if stat, err := os.Stat(v.metaPath(name)); err == nil {
+		// Like share properties, the sidecar only changes on create or resize
+		info.LastModified = stat.ModTime()
+	}
+	return info, nil
+}
+
+func (v *localVolumes) writeMeta(name string, meta localVolumeMeta) error {
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(v.metaPath(name), data, 0o600); err != nil {
+		return fmt.Errorf("failed to write volume %s metadata: %w", name, err)
+	}
+	return nil
+}
+
+func (v *localVolumes) metaPath(name string) string {
+	return filepath.Join(v.root, name+".json")
+}
+
+func (v *localVolumes) CopyVolume(ctx context.Context, src, dst string, progress func(filesCopied int)) error {
+	if err := checkLocalVolumeName(src); err != nil {
+		return err
+	}
+	if err := checkLocalVolumeName(dst); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, name := range []string{src, dst} {
+		if _, err := v.properties(name); err != nil {
+			return err
+		}
+	}
+	copied, err := copyLocalTree(ctx, v.Path(src), v.Path(dst))
+	if err != nil {
+		return fmt.Errorf("failed to copy volume %s to %s: %w", src, dst, err)
+	}
+	if progress != nil {
+		progress(copied)
+	}
+	return nil
+}
+
+// localSnapshotMeta is the sidecar stored next to each snapshot directory
+type localSnapshotMeta struct {
+	Label     string    `json:"label,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// localSnapshotIDFormat matches the timestamps Azure Files uses as snapshot IDs
+const localSnapshotIDFormat = "2006-01-02T15:04:05.0000000Z"
+
+func (v *localVolumes) CreateSnapshot(ctx context.Context, name, label string) (*VolumeSnapshot, error) {
+	if err := checkLocalVolumeName(name); err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, err := v.properties(name); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(v.snapshotRoot(name), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to snapshot volume %s: %w", name, err)
+	}
+
+	createdAt := time.Now().UTC()
+	id := createdAt.Format(localSnapshotIDFormat)
+	if _, err := os.Stat(v.snapshotPath(name, id)); err == nil {
+		return nil, fmt.Errorf("failed to snapshot volume %s: snapshot %s already exists", name, id)
+	}
+	if _, err := copyLocalTree(ctx, v.Path(name), v.snapshotPath(name, id)); err != nil {
+		_ = os.RemoveAll(v.snapshotPath(name, id))
+		return nil, fmt.Errorf("failed to snapshot volume %s: %w", name, err)
+	}
+
+	data, err := json.Marshal(localSnapshotMeta{Label: label, CreatedAt: createdAt})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(v.snapshotPath(name, id)+".json", data, 0o600); err != nil {
+		_ = os.RemoveAll(v.snapshotPath(name, id))
+		return nil, fmt.Errorf("failed to write snapshot %s metadata: %w", id, err)
+	}
+	return &VolumeSnapshot{ID: id, Label: label, CreatedAt: createdAt}, nil
+}
+
+func (v *localVolumes) ListSnapshots(ctx context.Context, name string) ([]VolumeSnapshot, error) {
+	if err := checkLocalVolumeName(name); err != nil {
+		return nil, err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	entries, err := os.ReadDir(v.snapshotRoot(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots of volume %s: %w", name, err)
+	}
+
+	var snapshots []VolumeSnapshot
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(v.snapshotPath(name, entry.Name()) + ".json")
+		if err != nil {
+			// Still being written, or left behind by a failed snapshot
+			continue
+		}
+		var meta localSnapshotMeta
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return nil, fmt.Errorf("snapshot %s: corrupt metadata: %w", entry.Name(), err)
+		}
+		snapshots = append(snapshots, VolumeSnapshot{ID: entry.Name(), Label: meta.Label, CreatedAt: meta.CreatedAt})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt) })
+	return snapshots, nil
+}
+
+func (v *localVolumes) DeleteSnapshot(ctx context.Context, name, snapshotID string) error {
+	if err := checkLocalSnapshot(name, snapshotID); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, err := os.Stat(v.snapshotPath(name, snapshotID)); errors.Is(err, fs.ErrNotExist) {
+		return snapshotNotFound(name, snapshotID)
+	}
+	if err := os.Remove(v.snapshotPath(name, snapshotID) + ".json"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete snapshot %s: %w", snapshotID, err)
+	}
+	if err := os.RemoveAll(v.snapshotPath(name, snapshotID)); err != nil {
+		return fmt.Errorf("failed to delete snapshot %s: %w", snapshotID, err)
+	}
+	return nil
+}
+
+func (v *localVolumes) RestoreSnapshot(ctx context.Context, name, snapshotID, target string, progress func(filesCopied int)) error {
+	if err := checkLocalSnapshot(name, snapshotID); err != nil {
+		return err
+	}
+	if err := checkLocalVolumeName(target); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, err := os.Stat(v.snapshotPath(name, snapshotID) + ".json"); errors.Is(err, fs.ErrNotExist) {
+		return snapshotNotFound(name, snapshotID)
+	}
+	if _, err := v.properties(target); err != nil {
+		return err
+	}
+
+	// Empty the volume but keep its directory, which may be bind-mounted
+	entries, err := os.ReadDir(v.Path(target))
+	if err != nil {
+		return fmt.Errorf("failed to restore volume %s: %w", target, err)
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(v.Path(target), entry.Name())); err != nil {
+			return fmt.Errorf("failed to restore volume %s: %w", target, err)
+		}
+	}
+
+	copied, err := copyLocalTree(ctx, v.snapshotPath(name, snapshotID), v.Path(target))
+	if err != nil {
+		return fmt.Errorf("failed to restore volume %s: %w", target, err)
+	}
+	if progress != nil {
+		progress(copied)
+	}
+	return nil
+}
+
+func (v *localVolumes) snapshotRoot(name string) string {
+	return filepath.Join(v.root, ".snapshots", name)
+}
+
+func (v *localVolumes) snapshotPath(name, snapshotID string) string {
+	return filepath.Join(v.snapshotRoot(name), snapshotID)
+}
+
+// checkLocalSnapshot rejects volume names and snapshot IDs that would escape the snapshot root
+func checkLocalSnapshot(name, snapshotID string) error {
+	if err := checkLocalVolumeName(name); err != nil {
+		return err
+	}
+	if err := checkLocalVolumeName(snapshotID); err != nil {
+		return snapshotNotFound(name, snapshotID)
+	}
+	return nil
+}
+
+// copyLocalTree copies the files, directories and symlinks under src to dst,
+// which may already exist, and returns the number of files copied
+func copyLocalTree(ctx context.Context, src, dst string) (int, error) {
+	copied := 0
+	err := filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case entry.IsDir():
+			if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+				return err
+			}
+			return os.Chmod(target, info.Mode().Perm())
+		case entry.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case entry.Type().IsRegular():
+			copied++
+			return copyLocalFile(path, target, info.Mode().Perm())
+		}
+		// Sockets, devices and pipes are not part of a snapshot
+		return nil
+	})
+	return copied, err
+}
+
+func copyLocalFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src) // nolint:gosec // G304: paths are under the volume root
+	if err != nil {
+		return err
+	}
+	defer func() { _ = in.Close() }()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm) // nolint:gosec // G304: paths are under the volume root
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// checkLocalVolumeName rejects names that would escape the volume root
+func checkLocalVolumeName(name string) error {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid volume name %q", name)
+	}
+	return nil
+}
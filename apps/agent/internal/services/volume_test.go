@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+)
+
+func TestNewVolumeStores(t *testing.T) {
+	regions := []config.RegionConfig{
+		{Name: "eastus", Enabled: true},
+		{Name: "westus", Enabled: true},
+		{Name: "northeurope", Enabled: false},
+	}
+
+	tests := []struct {
+		name        string
+		mode        string
+		backend     string
+		wantErr     bool
+		wantRegions int
+	}{
+		{name: "azure without storage accounts", mode: "aci", wantRegions: 0},
+		{name: "local", mode: "docker", backend: "local", wantRegions: 2},
+		{name: "docker without Docker client", mode: "docker", wantErr: true},
+		{name: "unknown backend", mode: "docker", backend: "nfs", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				Azure:   config.AzureConfig{DeploymentMode: tt.mode, Regions: regions},
+				Volumes: config.VolumeConfig{Backend: tt.backend, LocalDir: t.TempDir()},
+			}
+			stores, err := newVolumeStores(cfg, ProviderClients{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newVolumeStores() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(stores) != tt.wantRegions {
+				t.Errorf("stores for %d regions, want %d", len(stores), tt.wantRegions)
+			}
+		})
+	}
+}
+
+func TestLocalVolumes(t *testing.T) {
+	ctx := context.Background()
+	volumes, err := newLocalVolumes(t.TempDir())
+	if err != nil {
+		t.Fatalf("newLocalVolumes() error = %v", err)
+	}
+
+	if err := volumes.CreateVolume(ctx, "fs-ws-1", 15); err != nil {
+		t.Fatalf("CreateVolume() error = %v", err)
+	}
+	if err := volumes.CreateVolume(ctx, "fs-ws-2", 5); err != nil {
+		t.Fatalf("CreateVolume() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(volumes.Path("fs-ws-1"), "workspace"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(volumes.Path("fs-ws-1"), "workspace", "main.go"), make([]byte, 1000), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if exists, err := volumes.VolumeExists(ctx, "fs-ws-1"); err != nil || !exists {
+		t.Errorf("VolumeExists() = %v, %v, want true", exists, err)
+	}
+	if used, err := volumes.VolumeUsage(ctx, "fs-ws-1"); err != nil || used != 1000 {
+		t.Errorf("VolumeUsage() = %d, %v, want 1000", used, err)
+	}
+
+	if err := volumes.ResizeVolume(ctx, "fs-ws-1", 50); err != nil {
+		t.Fatalf("ResizeVolume() error = %v", err)
+	}
+	props, err := volumes.VolumeProperties(ctx, "fs-ws-1")
+	if err != nil || props.QuotaGB != 50 || props.LastModified.IsZero() {
+		t.Errorf("VolumeProperties() = %+v, %v, want quota 50", props, err)
+	}
+
+	listed, err := volumes.ListVolumes(ctx, "fs-")
+	if err != nil || len(listed) != 2 {
+		t.Errorf("ListVolumes() = %+v, %v, want 2 volumes", listed, err)
+	}
+
+	if err := volumes.DeleteVolume(ctx, "fs-ws-1"); err != nil {
+		t.Fatalf("DeleteVolume() error = %v", err)
+	}
+	if err := volumes.DeleteVolume(ctx, "fs-ws-1"); err != nil {
+		t.Errorf("DeleteVolume() of missing volume error = %v", err)
+	}
+	if exists, _ := volumes.VolumeExists(ctx, "fs-ws-1"); exists {
+		t.Error("volume survived delete")
+	}
+
+	// Missing volumes report ErrVolumeNotFound
+	if _, err := volumes.VolumeProperties(ctx, "fs-ws-1"); !errors.Is(err, ErrVolumeNotFound) {
+		t.Errorf("VolumeProperties() of missing volume error = %v, want ErrVolumeNotFound", err)
+	}
+	if err := volumes.ResizeVolume(ctx, "fs-ws-1", 10); !errors.Is(err, ErrVolumeNotFound) {
+		t.Errorf("ResizeVolume() of missing volume error = %v, want ErrVolumeNotFound", err)
+	}
+	if _, err := volumes.VolumeUsage(ctx, "fs-ws-1"); !errors.Is(err, ErrVolumeNotFound) {
+		t.Errorf("VolumeUsage() of missing volume error = %v, want ErrVolumeNotFound", err)
+	}
+
+	for _, name := range []string{"", "..", "../fs-ws-2", "a/b"} {
+		if err := volumes.CreateVolume(ctx, name, 1); err == nil {
+			t.Errorf("CreateVolume(%q) should be rejected", name)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+)
+
+const (
+	defaultBaseBackoff  = 5 * time.Second
+	defaultMaxBackoff   = 15 * time.Minute
+	defaultPollInterval = 5 * time.Second
+	deliveryBatchSize   = 50
+)
+
+// Dispatcher queues events in an outbox and delivers them to every configured
+// endpoint, retrying failures with exponential backoff.
+type Dispatcher struct {
+	source    string
+	endpoints []config.WebhookEndpoint
+	outbox    Outbox
+	client    *http.Client
+
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	pollInterval time.Duration
+
+	wake chan struct{}
+	now  func() time.Time
+}
+
+// NewDispatcher creates a dispatcher. source identifies this agent in the
+// CloudEvents "source" attribute.
+func NewDispatcher(cfg config.WebhookConfig, source string, outbox Outbox) *Dispatcher {
+	return &Dispatcher{
+		source:       source,
+		endpoints:    cfg.Endpoints,
+		outbox:       outbox,
+		client:       &http.Client{Timeout: cfg.Timeout},
+		maxAttempts:  cfg.MaxAttempts,
+		baseBackoff:  defaultBaseBackoff,
+		maxBackoff:   defaultMaxBackoff,
+		pollInterval: defaultPollInterval,
+		wake:         make(chan struct{}, 1),
+		now:          time.Now,
+	}
+}
+
+// Publish queues an event for every endpoint. It returns once the event is
+// in the outbox; delivery happens in Run.
+func (d *Dispatcher) Publish(ctx context.Context, eventType, subject string, data interface{}) error {
+	if len(d.endpoints) == 0 {
+		return nil
+	}
+
+	event, err := NewEvent(d.source, eventType, subject, data)
+	if err != nil {
+		return fmt.Errorf("failed to build %s event: %w", eventType, err)
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	now := d.now()
+	for i, endpoint := range d.endpoints {
+		delivery := &Delivery{
+			ID:            fmt.Sprintf("%s-%d", event.ID, i),
+			EventID:       event.ID,
+			Endpoint:      endpoint.URL,
+			Event:         body,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		if err := d.outbox.Put(ctx, delivery); err != nil {
+			return fmt.Errorf("failed to queue %s event for %s: %w", eventType, endpoint.URL, err)
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers queued events until ctx is cancelled. Deliveries left in a
+// persistent outbox are picked up again on the next start.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.DeliverDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// DeliverDue attempts every delivery whose retry time has come
+func (d *Dispatcher) DeliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		due, err := d.outbox.Due(ctx, d.now(), deliveryBatchSize)
+		if err != nil {
+			log.Printf("❌ Webhook outbox read failed: %v", err)
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+
+		for i := range due {
+			if ctx.Err() != nil {
+				return
+			}
+			d.attempt(ctx, &due[i])
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *Delivery) {
+	secret, ok := d.secretFor(delivery.Endpoint)
+	if !ok {
+		log.Printf("Warning: dropping webhook %s for unconfigured endpoint %s", delivery.EventID, delivery.Endpoint)
+		d.remove(ctx, delivery)
+		return
+	}
+
+	err := d.send(ctx, delivery, secret)
+	if err == nil {
+		d.remove(ctx, delivery)
+		return
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= d.maxAttempts {
+		log.Printf("❌ Giving up on webhook %s to %s after %d attempts: %v", delivery.EventID, delivery.Endpoint, delivery.Attempts, err)
+		d.remove(ctx, delivery)
+		return
+	}
+
+	delivery.NextAttemptAt = d.now().Add(d.backoff(delivery.Attempts))
+	log.Printf("Warning: webhook %s to %s failed (attempt %d/%d), retrying at %s: %v",
+		delivery.EventID, delivery.Endpoint, delivery.Attempts, d.maxAttempts, delivery.NextAttemptAt.Format(time.RFC3339), err)
+	if err := d.outbox.Put(ctx, delivery); err != nil {
+		log.Printf("❌ Failed to reschedule webhook %s: %v", delivery.EventID, err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery *Delivery, secret string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.Endpoint, bytes.NewReader(delivery.Event))
+	if err != nil {
+		return err
+	}
+
+	// Each attempt is signed with a fresh timestamp; the event ID stays the same
+	timestamp := d.now()
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, delivery.Event))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("endpoint returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (d *Dispatcher) remove(ctx context.Context, delivery *Delivery) {
+	if err := d.outbox.Delete(ctx, delivery.ID); err != nil {
+		log.Printf("❌ Failed to remove webhook %s from outbox: %v", delivery.EventID, err)
+	}
+}
+
+// backoff returns the delay before the given retry attempt
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.baseBackoff
+	for i := 1; i < attempts && delay < d.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > d.maxBackoff {
+		delay = d.maxBackoff
+	}
+	return delay
+}
+
+func (d *Dispatcher) secretFor(url string) (string, bool) {
+	for _, endpoint := range d.endpoints {
+		if endpoint.URL == url {
+			return endpoint.Secret, true
+		}
+	}
+	return "", false
+}
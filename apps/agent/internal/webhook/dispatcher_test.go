@@ -0,0 +1,187 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/config"
+	bolt "go.etcd.io/bbolt"
+)
+
+// receiver is a webhook endpoint that fails the first failures requests
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	events   []Event
+	errs     []error
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	if err := Verify("secret", r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, DefaultTolerance, time.Now()); err != nil {
+		rc.errs = append(rc.errs, err)
+	}
+
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		rc.errs = append(rc.errs, err)
+	}
+	if r.Header.Get(HeaderEventID) != event.ID {
+		rc.errs = append(rc.errs, io.ErrUnexpectedEOF)
+	}
+	rc.events = append(rc.events, event)
+}
+
+func newTestDispatcher(url string, outbox Outbox) *Dispatcher {
+	d := NewDispatcher(config.WebhookConfig{
+		Endpoints:   []config.WebhookEndpoint{{URL: url, Secret: "secret"}},
+		MaxAttempts: 3,
+		Timeout:     time.Second,
+	}, "http://agent.test", outbox)
+	d.baseBackoff = time.Millisecond
+	d.maxBackoff = time.Millisecond
+	return d
+}
+
+// deliverUntilEmpty keeps delivering until nothing is due, allowing for backoff
+func deliverUntilEmpty(t *testing.T, d *Dispatcher, outbox Outbox) {
+	t.Helper()
+	ctx := context.Background()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		d.DeliverDue(ctx)
+		pending, _ := outbox.Due(ctx, time.Now().Add(time.Hour), 0)
+		if len(pending) == 0 {
+			return
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	t.Fatal("outbox not drained")
+}
+
+func TestDispatcher_DeliversSignedEvents(t *testing.T) {
+	rc := &receiver{failures: 1}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	outbox := NewMemoryOutbox()
+	d := newTestDispatcher(server.URL, outbox)
+
+	if err := d.Publish(context.Background(), EventCreated, "ws-1", map[string]string{"status": "RUNNING"}); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	deliverUntilEmpty(t, d, outbox)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.errs) > 0 {
+		t.Fatalf("receiver errors: %v", rc.errs)
+	}
+	if len(rc.events) != 1 {
+		t.Fatalf("delivered %d events, want 1", len(rc.events))
+	}
+	event := rc.events[0]
+	if event.SpecVersion != "1.0" || event.Type != EventCreated || event.Subject != "ws-1" || event.Source != "http://agent.test" {
+		t.Errorf("event = %+v", event)
+	}
+}
+
+func TestDispatcher_GivesUpAfterMaxAttempts(t *testing.T) {
+	rc := &receiver{failures: 100}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	outbox := NewMemoryOutbox()
+	d := newTestDispatcher(server.URL, outbox)
+
+	if err := d.Publish(context.Background(), EventFailed, "ws-1", nil); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	deliverUntilEmpty(t, d, outbox)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if attempts := 100 - rc.failures; attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDispatcher_OutboxSurvivesRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.db")
+	ctx := context.Background()
+
+	// Publish while the endpoint is unreachable, then "restart"
+	db, err := bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open() error = %v", err)
+	}
+	outbox, err := NewBoltOutbox(db)
+	if err != nil {
+		t.Fatalf("NewBoltOutbox() error = %v", err)
+	}
+	if err := newTestDispatcher("http://127.0.0.1:1", outbox).Publish(ctx, EventStopped, "ws-1", nil); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	_ = db.Close()
+
+	rc := &receiver{}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	db, err = bolt.Open(path, 0o600, nil)
+	if err != nil {
+		t.Fatalf("bolt.Open() error = %v", err)
+	}
+	defer func() { _ = db.Close() }()
+	outbox, err = NewBoltOutbox(db)
+	if err != nil {
+		t.Fatalf("NewBoltOutbox() error = %v", err)
+	}
+
+	// Point the queued delivery at the now-running receiver
+	pending, _ := outbox.Due(ctx, time.Now(), 0)
+	if len(pending) != 1 {
+		t.Fatalf("pending after restart = %d, want 1", len(pending))
+	}
+	pending[0].Endpoint = server.URL
+	_ = outbox.Put(ctx, &pending[0])
+
+	d := newTestDispatcher(server.URL, outbox)
+	deliverUntilEmpty(t, d, outbox)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.events) != 1 || rc.events[0].Type != EventStopped {
+		t.Errorf("events after restart = %+v", rc.events)
+	}
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	d := NewDispatcher(config.WebhookConfig{MaxAttempts: 10}, "agent", NewMemoryOutbox())
+
+	want := []time.Duration{5 * time.Second, 10 * time.Second, 20 * time.Second, 40 * time.Second}
+	for i, expected := range want {
+		if got := d.backoff(i + 1); got != expected {
+			t.Errorf("backoff(%d) = %v, want %v", i+1, got, expected)
+		}
+	}
+	if got := d.backoff(50); got != defaultMaxBackoff {
+		t.Errorf("backoff(50) = %v, want %v", got, defaultMaxBackoff)
+	}
+}
@@ -0,0 +1,58 @@
+// Package webhook delivers signed lifecycle events to the control plane.
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types emitted by the agent
+const (
+	EventCreated     = "dev.dev8.workspace.created"
+	EventStarted     = "dev.dev8.workspace.started"
+	EventStopped     = "dev.dev8.workspace.stopped"
+	EventDeleted     = "dev.dev8.workspace.deleted"
+	EventResized     = "dev.dev8.workspace.resized"
+	EventSnapshotted = "dev.dev8.workspace.snapshot_created"
+	EventRestored    = "dev.dev8.workspace.restored"
+	EventRotated     = "dev.dev8.workspace.credentials_rotated"
+	EventFailed      = "dev.dev8.workspace.failed"
+	EventActivity    = "dev.dev8.workspace.activity"
+	EventIdleWarning = "dev.dev8.workspace.idle_warning"
+
+	EventBudgetWarning  = "dev.dev8.workspace.budget_warning"
+	EventBudgetExceeded = "dev.dev8.workspace.budget_exceeded"
+)
+
+// Event is a CloudEvents 1.0 envelope in structured JSON mode
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"` // Workspace ID
+	Time            time.Time       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data,omitempty"`
+}
+
+// NewEvent builds an event with a fresh ID and the current time
+func NewEvent(source, eventType, subject string, data interface{}) (*Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Event{
+		SpecVersion:     "1.0",
+		ID:              uuid.New().String(),
+		Source:          source,
+		Type:            eventType,
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            raw,
+	}, nil
+}
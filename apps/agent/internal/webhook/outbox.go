@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var outboxBucket = []byte("webhook_outbox")
+
+// Delivery is one event queued for one endpoint
+type Delivery struct {
+	ID            string          `json:"id"`
+	EventID       string          `json:"eventId"`
+	Endpoint      string          `json:"endpoint"` // Receiver URL; the secret is looked up at send time
+	Event         json.RawMessage `json:"event"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"nextAttemptAt"`
+	LastError     string          `json:"lastError,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+}
+
+// Outbox stores pending deliveries until they succeed or are given up on.
+// Implementations must be safe for concurrent use.
+type Outbox interface {
+	// Put creates or replaces a delivery
+	Put(ctx context.Context, d *Delivery) error
+	// Due returns up to limit deliveries whose next attempt is at or before now, oldest first
+	Due(ctx context.Context, now time.Time, limit int) ([]Delivery, error)
+	// Delete removes a delivery. Deleting a missing delivery is not an error.
+	Delete(ctx context.Context, id string) error
+}
+
+// dueDeliveries filters and orders deliveries for Due
+func dueDeliveries(all []Delivery, now time.Time, limit int) []Delivery {
+	var due []Delivery
+	for _, d := range all {
+		if !d.NextAttemptAt.After(now) {
+			due = append(due, d)
+		}
+	}
+
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].NextAttemptAt.Equal(due[j].NextAttemptAt) {
+			return due[i].CreatedAt.Before(due[j].CreatedAt)
+		}
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+	return due
+}
+
+// MemoryOutbox keeps deliveries in memory. Pending events are lost on restart.
+type MemoryOutbox struct {
+	mu         sync.RWMutex
+	deliveries map[string]Delivery
+}
+
+// NewMemoryOutbox creates an empty in-memory outbox
+func NewMemoryOutbox() *MemoryOutbox {
+	return &MemoryOutbox{
+		deliveries: make(map[string]Delivery),
+	}
+}
+
+// Put stores a delivery
+func (m *MemoryOutbox) Put(ctx context.Context, d *Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries[d.ID] = *d
+	return nil
+}
+
+// Due returns deliveries ready for another attempt
+func (m *MemoryOutbox) Due(ctx context.Context, now time.Time, limit int) ([]Delivery, error) {
+	m.mu.RLock()
+	all := make([]Delivery, 0, len(m.deliveries))
+	for _, d := range m.deliveries {
+		all = append(all, d)
+	}
+	m.mu.RUnlock()
+
+	return dueDeliveries(all, now, limit), nil
+}
+
+// Delete removes a delivery
+func (m *MemoryOutbox) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.deliveries, id)
+	return nil
+}
+
+// BoltOutbox persists deliveries in the agent's embedded database so queued
+// events survive a restart.
+type BoltOutbox struct {
+	db *bolt.DB
+}
+
+// NewBoltOutbox creates an outbox backed by db
+func NewBoltOutbox(db *bolt.DB) (*BoltOutbox, error) {
+	err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(outboxBucket)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize webhook outbox bucket: %w", err)
+	}
+
+	return &BoltOutbox{db: db}, nil
+}
+
+// Put stores a delivery
+func (b *BoltOutbox) Put(ctx context.Context, d *Delivery) error {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to encode delivery %s: %w", d.ID, err)
+	}
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Put([]byte(d.ID), data)
+	})
+}
+
+// Due returns deliveries ready for another attempt
+func (b *BoltOutbox) Due(ctx context.Context, now time.Time, limit int) ([]Delivery, error) {
+	var all []Delivery
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).ForEach(func(_, data []byte) error {
+			var d Delivery
+			if err := json.Unmarshal(data, &d); err != nil {
+				return err
+			}
+			all = append(all, d)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhook outbox: %w", err)
+	}
+
+	return dueDeliveries(all, now, limit), nil
+}
+
+// Delete removes a delivery
+func (b *BoltOutbox) Delete(ctx context.Context, id string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(outboxBucket).Delete([]byte(id))
+	})
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	HeaderEventID   = "X-Dev8-Event-Id"
+	HeaderTimestamp = "X-Dev8-Timestamp"
+	HeaderSignature = "X-Dev8-Signature"
+)
+
+// DefaultTolerance is how far a delivery timestamp may drift before receivers should reject it
+const DefaultTolerance = 5 * time.Minute
+
+// Sign returns the signature header value for body sent at timestamp.
+// The signed payload is "{unix timestamp}.{body}" so a captured request
+// cannot be replayed with a new timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return "v1=" + computeSignature(secret, timestamp.Unix(), body)
+}
+
+// Verify checks a delivery's timestamp and signature headers against body.
+// Receivers should additionally reject event IDs they have already processed.
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, tolerance time.Duration, now time.Time) error {
+	unix, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid timestamp header: %w", err)
+	}
+
+	age := now.Sub(time.Unix(unix, 0))
+	if age > tolerance || age < -tolerance {
+		return fmt.Errorf("timestamp outside tolerance of %s", tolerance)
+	}
+
+	expected := computeSignature(secret, unix, body)
+	for _, part := range strings.Split(signatureHeader, ",") {
+		if sig, ok := strings.CutPrefix(strings.TrimSpace(part), "v1="); ok {
+			if hmac.Equal([]byte(sig), []byte(expected)) {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("signature mismatch")
+}
+
+func computeSignature(secret string, unix int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(unix, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
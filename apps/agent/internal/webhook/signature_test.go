@@ -0,0 +1,40 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	body := []byte(`{"id":"evt-1"}`)
+	sentAt := time.Unix(1_700_000_000, 0)
+	signature := Sign("secret", sentAt, body)
+	timestamp := "1700000000"
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		now       time.Time
+		wantErr   bool
+	}{
+		{name: "valid", secret: "secret", timestamp: timestamp, signature: signature, body: body, now: sentAt.Add(time.Minute)},
+		{name: "multiple signatures", secret: "secret", timestamp: timestamp, signature: "v1=deadbeef, " + signature, body: body, now: sentAt},
+		{name: "wrong secret", secret: "other", timestamp: timestamp, signature: signature, body: body, now: sentAt, wantErr: true},
+		{name: "tampered body", secret: "secret", timestamp: timestamp, signature: signature, body: []byte(`{"id":"evt-2"}`), now: sentAt, wantErr: true},
+		{name: "replayed with new timestamp", secret: "secret", timestamp: "1700000100", signature: signature, body: body, now: sentAt, wantErr: true},
+		{name: "stale timestamp", secret: "secret", timestamp: timestamp, signature: signature, body: body, now: sentAt.Add(10 * time.Minute), wantErr: true},
+		{name: "invalid timestamp", secret: "secret", timestamp: "yesterday", signature: signature, body: body, now: sentAt, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, DefaultTolerance, tt.now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -13,9 +14,12 @@ import (
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/handlers"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/logger"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/middleware"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/models"
 	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/services"
+	"github.com/VAIBHAVSING/Dev8.dev/apps/agent/internal/webhook"
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
@@ -60,31 +64,146 @@ func main() {
 		log.Info().
 			Str("registry", "ACR").
 			Str("url", cfg.Azure.ContainerRegistry).
-			Str("image", cfg.Azure.ContainerRegistry+"/dev8-workspace:latest").
 			Msg("Container registry configuration")
 	} else {
 		log.Info().
 			Str("registry", "Docker Hub").
-			Str("image", cfg.ContainerImage).
 			Msg("Container registry configuration")
 	}
 
-	// Initialize Azure client
-	azureClient, err := azure.NewClient(cfg)
-	if err != nil {
-		log.Fatal().Err(err).Msg("Failed to create Azure client")
+	// Requests may only ask for images of the catalogue
+	models.SetImageCatalog(cfg.ImageNames(), cfg.Images.Default)
+	log.Info().
+		Strs("images", cfg.ImageNames()).
+		Str("default", cfg.Images.Default).
+		Int("region_registries", len(cfg.Images.RegionRegistries)).
+		Msg("Image catalogue configuration")
+
+	// Initialize Azure client (docker and kubernetes modes run workspaces elsewhere and need none)
+	var azureClient *azure.Client
+	switch cfg.Azure.DeploymentMode {
+	case "docker":
+		log.Info().Str("docker_host", cfg.Docker.Host).Msg("Docker deployment mode - workspaces run on the local Docker Engine")
+	case "kubernetes":
+		log.Info().Str("namespace", cfg.Kubernetes.Namespace).Msg("Kubernetes deployment mode - workspaces run as StatefulSets in the cluster")
+	default:
+		azureClient, err = azure.NewClient(cfg)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create Azure client")
+		}
+		log.Info().Msg("Azure client initialized successfully")
 	}
-	log.Info().Msg("Azure client initialized successfully")
+
+	// Initialize the environment registry, webhook outbox, schedules and usage records
+	var store services.EnvironmentStore
+	var outbox webhook.Outbox
+	var schedules services.ScheduleStore
+	var usage services.UsageStore
+	if cfg.StateStore == "memory" {
+		store = services.NewMemoryEnvironmentStore()
+		outbox = webhook.NewMemoryOutbox()
+		schedules = services.NewMemoryScheduleStore()
+		usage = services.NewMemoryUsageStore()
+		log.Warn().Msg("Using in-memory environment store - workspace records, queued webhooks, schedules and usage are lost on restart")
+	} else {
+		stateDB, err := services.OpenBoltDB(filepath.Join(cfg.StateDir, "agent.db"))
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to open state database")
+		}
+		defer func() { _ = stateDB.Close() }()
+
+		store, err = services.NewBoltEnvironmentStore(stateDB)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create environment store")
+		}
+		log.Info().Str("path", stateDB.Path()).Msg("Environment store initialized")
+
+		outbox, err = webhook.NewBoltOutbox(stateDB)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create webhook outbox")
+		}
+
+		schedules, err = services.NewBoltScheduleStore(stateDB)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create schedule store")
+		}
+
+		usage, err = services.NewBoltUsageStore(stateDB)
+		if err != nil {
+			log.Fatal().Err(err).Msg("Failed to create usage store")
+		}
+	}
+
+	// Background workers run until shutdown
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Initialize asynchronous operation tracking
+	operations := services.NewOperationManager(cfg.OperationTimeout, cfg.OperationRetention)
 
 	// Initialize environment service
-	envService, err := services.NewEnvironmentService(cfg, azureClient)
+	envService, err := services.NewEnvironmentService(cfg, azureClient, operations, store)
 	if err != nil {
 		log.Fatal().Err(err).Msg("Failed to create environment service")
 	}
-	log.Info().Msg("Environment service initialized (stateless)")
+	envService.SetScheduleStore(schedules)
+	envService.SetUsageStore(usage)
+	prometheus.MustRegister(services.NewWorkspaceCollector(cfg, store))
+	log.Info().Msg("Environment service initialized")
+
+	// Initialize the workspace secret store
+	secretStore, err := services.NewSecretStore(cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create secret store")
+	}
+	envService.SetSecretStore(secretStore)
+	if secretStore == nil {
+		log.Warn().Msg("Secret store disabled - start and resize requests must resend workspace secrets")
+	} else {
+		log.Info().Str("backend", cfg.SecretBackend()).Msg("Secret store initialized")
+	}
+
+	// Initialize per-workspace operation locks
+	locker, err := services.NewWorkspaceLocker(backgroundCtx, cfg)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to create workspace locker")
+	}
+	envService.SetWorkspaceLocker(locker)
+	log.Info().Str("backend", cfg.Locks.Backend).Msg("Workspace locks initialized")
+
+	// Initialize lifecycle webhooks
+	if len(cfg.Webhooks.Endpoints) > 0 {
+		dispatcher := webhook.NewDispatcher(cfg.Webhooks, cfg.AgentBaseURL, outbox)
+		envService.SetEventPublisher(dispatcher)
+		go dispatcher.Run(backgroundCtx)
+		log.Info().Int("endpoints", len(cfg.Webhooks.Endpoints)).Msg("Webhook dispatcher started")
+	}
+
+	// Initialize the orphaned resource reconciler
+	reconciler := services.NewReconciler(envService, cfg.Reconciler)
+	if cfg.Reconciler.Enabled {
+		go reconciler.Run(backgroundCtx)
+	}
+
+	// Initialize idle auto-stop
+	if cfg.Idle.Enabled {
+		go services.NewIdleScanner(envService, cfg.Idle).Run(backgroundCtx)
+	}
+
+	// Initialize scheduled starts and stops
+	if cfg.Schedules.Enabled {
+		go services.NewScheduler(envService, cfg.Schedules).Run(backgroundCtx)
+	}
+
+	// Initialize monthly budget enforcement
+	if cfg.Usage.HasBudgets() {
+		go services.NewBudgetEnforcer(envService).Run(backgroundCtx)
+	}
 
 	// Initialize handlers
 	envHandler := handlers.NewEnvironmentHandler(envService)
+	operationHandler := handlers.NewOperationHandler(operations)
+	adminHandler := handlers.NewAdminHandler(reconciler)
 	healthHandler := handlers.NewHealthHandler(azureClient, cfg)
 
 	// Setup router
@@ -113,17 +232,55 @@ func main() {
 
 	// API v1 routes with timeout middleware
 	api := router.PathPrefix("/api/v1").Subrouter()
+	if cfg.IdempotencyTTL > 0 {
+		api.Use(middleware.NewIdempotency(cfg.IdempotencyTTL).Middleware) // Replay retried mutations
+	}
 	api.Use(middleware.TimeoutMiddleware(cfg.RequestTimeout))
 
 	// Environment routes
 	api.HandleFunc("/environments", envHandler.CreateEnvironment).Methods("POST")
 	api.HandleFunc("/environments", envHandler.ListEnvironments).Methods("GET")
 	api.HandleFunc("/environments/{id}", envHandler.GetEnvironment).Methods("GET")
+	api.HandleFunc("/environments/{id}", envHandler.ResizeEnvironment).Methods("PATCH")
 	api.HandleFunc("/environments", envHandler.DeleteEnvironment).Methods("DELETE")
 	api.HandleFunc("/environments/start", envHandler.StartEnvironment).Methods("POST")
 	api.HandleFunc("/environments/stop", envHandler.StopEnvironment).Methods("POST")
+	api.HandleFunc("/environments/{id}/clone", envHandler.CloneEnvironment).Methods("POST")
 	api.HandleFunc("/environments/{id}/activity", envHandler.ReportActivity).Methods("POST")
 
+	// Secret routes
+	api.HandleFunc("/environments/{id}/secrets", envHandler.GetSecrets).Methods("GET")
+	api.HandleFunc("/environments/{id}/secrets", envHandler.UpdateSecrets).Methods("PATCH")
+	api.HandleFunc("/environments/{id}/secrets", envHandler.DeleteSecrets).Methods("DELETE")
+	api.HandleFunc("/environments/{id}/credentials/rotate", envHandler.RotateCredentials).Methods("POST")
+
+	// Snapshot routes
+	api.HandleFunc("/environments/{id}/snapshots", envHandler.CreateSnapshot).Methods("POST")
+	api.HandleFunc("/environments/{id}/snapshots", envHandler.ListSnapshots).Methods("GET")
+	api.HandleFunc("/environments/{id}/snapshots/{snapshotId}", envHandler.DeleteSnapshot).Methods("DELETE")
+	api.HandleFunc("/environments/{id}/snapshots/{snapshotId}/restore", envHandler.RestoreSnapshot).Methods("POST")
+	api.HandleFunc("/environments/{id}/snapshot-retention", envHandler.SetSnapshotRetention).Methods("PUT")
+
+	// Schedule routes
+	api.HandleFunc("/environments/{id}/schedules", envHandler.CreateSchedule).Methods("POST")
+	api.HandleFunc("/environments/{id}/schedules", envHandler.ListSchedules).Methods("GET")
+	api.HandleFunc("/environments/{id}/schedules/{scheduleId}", envHandler.GetSchedule).Methods("GET")
+	api.HandleFunc("/environments/{id}/schedules/{scheduleId}", envHandler.UpdateSchedule).Methods("PUT")
+	api.HandleFunc("/environments/{id}/schedules/{scheduleId}", envHandler.DeleteSchedule).Methods("DELETE")
+
+	// Image catalogue
+	api.HandleFunc("/images", envHandler.ListImages).Methods("GET")
+
+	// Usage and cost
+	api.HandleFunc("/usage", envHandler.GetUsage).Methods("GET")
+
+	// Operation routes (poll asynchronous lifecycle operations)
+	api.HandleFunc("/operations/{id}", operationHandler.GetOperation).Methods("GET")
+
+	// Admin routes
+	api.HandleFunc("/admin/orphans", adminHandler.ListOrphans).Methods("GET")
+	api.HandleFunc("/admin/reconcile", adminHandler.Reconcile).Methods("POST")
+
 	// Root route
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -188,5 +345,12 @@ func main() {
 		log.Error().Err(err).Msg("Server forced to shutdown")
 	}
 
+	// Let in-flight provisioning finish within the same shutdown window
+	if err := operations.Shutdown(ctx); err != nil {
+		log.Error().Err(err).Msg("Operations did not complete before shutdown")
+	}
+	stopBackground()
+	envService.Close()
+
 	log.Info().Msg("Server stopped gracefully")
 }